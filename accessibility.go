@@ -0,0 +1,96 @@
+package readline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// announcement is the editing state last announced in screen-reader mode.
+type announcement struct {
+	mode      string
+	candidate string
+	hint      string
+}
+
+// announce describes the changes of the editing state since the last call, in
+// screen-reader mode: the keymaps, the selected completion candidate and the
+// hint text. Announcements are passed to the announce hooks if there are any,
+// or else printed above the prompt, since screen readers cannot follow the
+// redisplays of the input line and helpers made with cursor movements.
+func (rl *Shell) announce() {
+	if !rl.Config.GetBool("screen-reader") {
+		return
+	}
+
+	var messages []string
+
+	if mode := rl.announcedMode(); mode != rl.announced.mode {
+		rl.announced.mode = mode
+		messages = append(messages, "mode: "+mode)
+	}
+
+	if candidate := rl.announcedCandidate(); candidate != rl.announced.candidate {
+		rl.announced.candidate = candidate
+		if candidate != "" {
+			messages = append(messages, "candidate: "+candidate)
+		}
+	}
+
+	if hint := strings.TrimSpace(color.Strip(rl.Hint.Text())); hint != rl.announced.hint {
+		rl.announced.hint = hint
+		if hint != "" {
+			messages = append(messages, "hint: "+hint)
+		}
+	}
+
+	for _, msg := range messages {
+		if rl.Hooks.announced(msg) {
+			continue
+		}
+
+		rl.editMutex.Lock()
+		rl.messages = append(rl.messages, msg)
+		rl.editMutex.Unlock()
+	}
+}
+
+// resetAnnouncements forgets the state last announced, except for the keymaps,
+// which are only announced when they change, not when a Readline call starts.
+func (rl *Shell) resetAnnouncements() {
+	rl.announced = announcement{mode: rl.announcedMode()}
+}
+
+// announcedMode returns the main keymap, the local one if any, and whether
+// the Emacs overwrite mode is enabled.
+func (rl *Shell) announcedMode() string {
+	mode := string(rl.Keymap.Main())
+	if local := rl.Keymap.Local(); local != "" {
+		mode += ", " + string(local)
+	}
+
+	if rl.Keymap.Overwrite() {
+		mode += ", overwrite"
+	}
+
+	return mode
+}
+
+// announcedCandidate returns the selected completion candidate (with
+// its description and its position among all candidates), if any.
+func (rl *Shell) announcedCandidate() string {
+	candidates, selected := rl.completer.Candidates()
+	if selected < 0 {
+		return ""
+	}
+
+	candidate := candidates[selected]
+	text := fmt.Sprintf("%s (%d of %d)", candidate.Value, selected+1, len(candidates))
+
+	if candidate.Description != "" {
+		text += ": " + color.Strip(candidate.Description)
+	}
+
+	return text
+}
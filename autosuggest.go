@@ -0,0 +1,118 @@
+package readline
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/internal/core"
+)
+
+// Autosuggestions complete the line with dimmed text (see the autosuggest-style
+// option), which can be accepted with autosuggest-accept (or by moving the cursor
+// forward at the end of the line, unless autosuggest-motion-accept is off), or in
+// part with autosuggest-accept-word and autosuggest-accept-line-to-cursor, and
+// hidden with autosuggest-clear until the line changes. They are provided by the
+// strategies listed in the autosuggest-strategy option, in order, the first one to
+// suggest something being used: history suggests the most recent history line
+// starting with the line (if history-autosuggest is on), preferably one whose
+// command has not been reported to fail (see ReportCommandResult), completion
+// suggests the text returned with completions (if completion-autosuggest is on),
+// and other names are those of suggesters added with AddSuggester.
+
+// AddSuggester adds an autosuggestion strategy named name: the suggest function is
+// passed the input line, and returns the text completing it, or an empty string if
+// it has nothing to suggest. It is only used when listed in the autosuggest-strategy
+// option (eg. set with rl.Config.Set("autosuggest-strategy", "history name")). A nil
+// function removes the strategy.
+func (rl *Shell) AddSuggester(name string, suggest func(line string) string) {
+	if suggest == nil {
+		delete(rl.suggesters, name)
+		return
+	}
+
+	if rl.suggesters == nil {
+		rl.suggesters = make(map[string]func(line string) string)
+	}
+
+	rl.suggesters[name] = suggest
+}
+
+// autosuggesting returns true if any autosuggestion strategy is enabled and
+// displayed, which they never are in low-bandwidth and screen-reader modes.
+func (rl *Shell) autosuggesting() bool {
+	if rl.Config.GetBool("low-bandwidth") || rl.Config.GetBool("screen-reader") {
+		return false
+	}
+
+	for _, strategy := range rl.autosuggestStrategies() {
+		if rl.autosuggestEnabled(strategy) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggested returns the line completed with its autosuggestion, if any.
+func (rl *Shell) suggested() core.Line {
+	return rl.suggestLine(rl.line)
+}
+
+// suggestLine returns the line completed with the suggestion of the first
+// enabled strategy that has one, or the line itself if none has.
+func (rl *Shell) suggestLine(line *core.Line) core.Line {
+	if !rl.autosuggesting() || line.Len() == 0 || string(*line) == rl.dismissed {
+		return *line
+	}
+
+	for _, strategy := range rl.autosuggestStrategies() {
+		if !rl.autosuggestEnabled(strategy) {
+			continue
+		}
+
+		switch strategy {
+		case "history":
+			if suggested := rl.History.Suggest(line); suggested.Len() > line.Len() {
+				return suggested
+			}
+		case "completion":
+			if suggestion := rl.completer.Suggestion(); suggestion != "" {
+				return core.Line(string(*line) + suggestion)
+			}
+		default:
+			if suggestion := rl.suggesters[strategy](string(*line)); suggestion != "" {
+				return core.Line(string(*line) + suggestion)
+			}
+		}
+	}
+
+	return *line
+}
+
+// motionAccepts returns true if autosuggestions are accepted by cursor movements
+// at the end of the line (forward-char, forward-word, end-of-line and their Vim
+// equivalents), as they are unless the autosuggest-motion-accept option is off.
+func (rl *Shell) motionAccepts() bool {
+	return rl.autosuggesting() && rl.Config.GetBool("autosuggest-motion-accept")
+}
+
+// autosuggestStrategies returns the strategies listed in the autosuggest-strategy
+// option, which can be separated by blanks or commas.
+func (rl *Shell) autosuggestStrategies() []string {
+	strategies := strings.Trim(rl.Config.GetString("autosuggest-strategy"), "\"")
+
+	return strings.FieldsFunc(strategies, func(char rune) bool {
+		return char == ' ' || char == '\t' || char == ','
+	})
+}
+
+// autosuggestEnabled returns true if an autosuggestion strategy can be used.
+func (rl *Shell) autosuggestEnabled(strategy string) bool {
+	switch strategy {
+	case "history":
+		return rl.Config.GetBool("history-autosuggest")
+	case "completion":
+		return rl.Config.GetBool("completion-autosuggest")
+	default:
+		return rl.suggesters[strategy] != nil
+	}
+}
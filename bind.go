@@ -0,0 +1,191 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+var (
+	// ErrUnknownKeymap is returned when binding keys in a keymap that does not exist.
+	ErrUnknownKeymap = errors.New("unknown keymap")
+
+	// ErrUnknownCommand is returned when binding keys to a command that does not exist.
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+// Commands returns the sorted names of all commands available to the shell,
+// that is, the builtin ones and any other registered by the application.
+func (rl *Shell) Commands() []string {
+	commands := make([]string, 0, len(rl.Keymap.Commands()))
+
+	for name := range rl.Keymap.Commands() {
+		commands = append(commands, name)
+	}
+
+	sort.Strings(commands)
+
+	return commands
+}
+
+// AddCommand registers a named command, which can then be bound to keys
+// in inputrc files or with Bind(), used in macros, or as a Vim motion in
+// operator-pending mode (vi-opp keymap), like any builtin command would.
+// The command is passed the shell, to access and modify the input line,
+// cursor, selection, hints and completions through their methods.
+// A builtin command of the same name is overridden.
+func (rl *Shell) AddCommand(name string, command func(rl *Shell)) {
+	rl.Keymap.Register(map[string]func(){
+		name: func() { command(rl) },
+	})
+}
+
+// Binds returns all the binds of a keymap (the current main one if empty),
+// mapping key sequences to the commands they are bound to. Both sequences and
+// macros are escaped in inputrc format (control keys being uppercase, as in
+// `\C-X\C-E`), with macros being enclosed in quotes.
+// Returns nil if the keymap does not exist.
+func (rl *Shell) Binds(keymap string) map[string]string {
+	binds := rl.Config.Binds[rl.keymapName(keymap)]
+	if binds == nil {
+		return nil
+	}
+
+	all := make(map[string]string, len(binds))
+
+	for seq, bind := range binds {
+		if bind.Macro {
+			all[inputrc.Escape(seq)] = "\"" + inputrc.EscapeMacro(bind.Action) + "\""
+		} else {
+			all[inputrc.Escape(seq)] = bind.Action
+		}
+	}
+
+	return all
+}
+
+// Bind binds a key sequence in a keymap (the current main one if empty) to a
+// command, overwriting any existing bind for this sequence. As in an inputrc file,
+// the sequence is escaped (eg. `\C-x\C-e`), and the command is either the name of
+// a command, or a macro (keys to be read as input) when enclosed in quotes.
+//
+// Besides the main keymaps (emacs, vi-insert, vi-command, etc), keys can be bound
+// in local keymaps, which are used on top of the main one when active: vi-opp
+// (Vim operator-pending mode, eg. motions after `d`), vi-visual (Vim visual mode),
+// menu-select (completion menu) and isearch (incremental search). Keys not bound
+// in a local keymap are looked up in the main keymap.
+func (rl *Shell) Bind(keymap, seq, command string) error {
+	keymap = rl.keymapName(keymap)
+
+	if rl.Config.Binds[keymap] == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKeymap, keymap)
+	}
+
+	bind := inputrc.Bind{Action: command}
+
+	if len(command) > 1 && strings.HasPrefix(command, "\"") && strings.HasSuffix(command, "\"") {
+		bind = inputrc.Bind{Action: inputrc.Unescape(command[1 : len(command)-1]), Macro: true}
+	} else if _, found := rl.Keymap.Commands()[command]; !found {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, command)
+	}
+
+	rl.Config.Binds[keymap][inputrc.Unescape(seq)] = bind
+
+	return nil
+}
+
+// BindFunc binds a key sequence (escaped in inputrc format) in a keymap (the
+// current main one if empty) to a function. The function is registered as a
+// command named after the keymap and the sequence, thus listed with all others.
+func (rl *Shell) BindFunc(keymap, seq string, command func()) error {
+	keymap = rl.keymapName(keymap)
+
+	if rl.Config.Binds[keymap] == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKeymap, keymap)
+	}
+
+	name := fmt.Sprintf("func %s %s", keymap, seq)
+
+	rl.Keymap.Register(map[string]func(){name: command})
+	rl.Config.Binds[keymap][inputrc.Unescape(seq)] = inputrc.Bind{Action: name}
+
+	return nil
+}
+
+// Unbind removes the bind of a key sequence (escaped in inputrc
+// format) in a keymap (the current main one if empty), if any.
+func (rl *Shell) Unbind(keymap, seq string) error {
+	keymap = rl.keymapName(keymap)
+
+	if rl.Config.Binds[keymap] == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownKeymap, keymap)
+	}
+
+	delete(rl.Config.Binds[keymap], inputrc.Unescape(seq))
+
+	return nil
+}
+
+// OnKey registers a function called with each key sequence read by the shell and
+// the command it resolved to, before running it. The command is the name of a command,
+// a quoted macro (as returned by Binds()), or empty if the keys are not bound.
+// If the function returns true, the keys are swallowed, and the command is not run.
+// Passing nil removes the function.
+func (rl *Shell) OnKey(hook func(keys []rune, resolved string) (swallow bool)) {
+	rl.keyHook = hook
+}
+
+// TraceKeys writes to the writer, for each key sequence read by the shell, the
+// sequence (escaped in inputrc format, after decoding of enhanced keyboard protocols)
+// and the command it resolved to, in which keymap. This is useful to diagnose terminal
+// and keymap issues: since the terminal is in raw mode, the writer should be a file.
+// Passing nil disables tracing.
+func (rl *Shell) TraceKeys(w io.Writer) {
+	rl.keyTrace = w
+}
+
+// keyEvent traces the keys matched in a keymap and the bind they resolved
+// to, passes them to the key hook, and returns true if it swallowed them.
+func (rl *Shell) keyEvent(keymap string, bind inputrc.Bind) (swallowed bool) {
+	if rl.keyHook == nil && rl.keyTrace == nil {
+		return false
+	}
+
+	keys := rl.Keys.Caller()
+
+	resolved := bind.Action
+	if bind.Macro {
+		resolved = "\"" + inputrc.EscapeMacro(bind.Action) + "\""
+	}
+
+	if rl.keyHook != nil {
+		swallowed = rl.keyHook(keys, resolved)
+	}
+
+	if rl.keyTrace != nil {
+		if resolved == "" {
+			resolved = "(undefined)"
+		}
+
+		if swallowed {
+			resolved += " (swallowed)"
+		}
+
+		fmt.Fprintf(rl.keyTrace, "%s: %s -> %s\n", keymap, inputrc.Escape(string(keys)), resolved)
+	}
+
+	return swallowed
+}
+
+// keymapName returns the keymap name, or the current main one if empty.
+func (rl *Shell) keymapName(keymap string) string {
+	if keymap == "" {
+		return string(rl.Keymap.Main())
+	}
+
+	return keymap
+}
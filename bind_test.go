@@ -0,0 +1,68 @@
+package readline
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/core"
+)
+
+func TestShell_KeyEvent(t *testing.T) {
+	shell := NewShell()
+
+	var (
+		events []string
+		trace  bytes.Buffer
+	)
+
+	// Unbound keys are swallowed by the hook.
+	shell.OnKey(func(keys []rune, resolved string) bool {
+		events = append(events, string(keys)+" -> "+resolved)
+		return resolved == ""
+	})
+	shell.TraceKeys(&trace)
+
+	tests := []struct {
+		keys      string
+		bind      inputrc.Bind
+		swallowed bool
+	}{
+		{keys: "\x01", bind: inputrc.Bind{Action: "beginning-of-line"}},
+		{keys: "\x18m", bind: inputrc.Bind{Action: "a\tb", Macro: true}},
+		{keys: "\x18\x1a", swallowed: true},
+	}
+
+	for _, test := range tests {
+		core.MatchedKeys(shell.Keys, []byte(test.keys))
+
+		if swallowed := shell.keyEvent("emacs", test.bind); swallowed != test.swallowed {
+			t.Errorf("keyEvent(%q) = %v, want %v", test.keys, swallowed, test.swallowed)
+		}
+
+		core.FlushUsed(shell.Keys)
+	}
+
+	want := []string{"\x01 -> beginning-of-line", "\x18m -> \"a\\tb\"", "\x18\x1a -> "}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("OnKey() events = %q, want %q", events, want)
+	}
+
+	wantTrace := `emacs: \C-A -> beginning-of-line
+emacs: \C-Xm -> "a\tb"
+emacs: \C-X\C-Z -> (undefined) (swallowed)
+`
+	if trace.String() != wantTrace {
+		t.Errorf("TraceKeys() = %q, want %q", trace.String(), wantTrace)
+	}
+
+	// Without hook nor tracer, keys are never swallowed.
+	shell.OnKey(nil)
+	shell.TraceKeys(nil)
+	trace.Reset()
+
+	if shell.keyEvent("emacs", inputrc.Bind{}) || trace.Len() > 0 {
+		t.Error("keyEvent() without hook swallowed keys or traced them")
+	}
+}
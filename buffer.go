@@ -0,0 +1,94 @@
+package readline
+
+import (
+	"github.com/reeflective/readline/internal/core"
+)
+
+// preloaded is an input line to edit, set outside of the shell loop.
+type preloaded struct {
+	line   string
+	cursor int
+}
+
+// SetBuffer sets the input line and cursor position to edit on the next call to
+// Readline (eg. to edit again a command that failed), or immediately if called
+// while the shell is reading input. If the cursor position is out of the line,
+// the cursor is placed at its end. It is safe to call from any goroutine.
+func (rl *Shell) SetBuffer(line string, cursor int) {
+	rl.editMutex.Lock()
+	rl.preload = &preloaded{line: line, cursor: cursor}
+	rl.inserts = nil
+	rl.editMutex.Unlock()
+
+	core.Wake(rl.Keys)
+}
+
+// Buffer returns the input line and cursor position to be edited on the next call
+// to Readline, if set with SetBuffer, or else the ones of the last call to Readline.
+// It should not be called while the shell is reading input.
+func (rl *Shell) Buffer() (line string, cursor int) {
+	rl.editMutex.Lock()
+	defer rl.editMutex.Unlock()
+
+	if rl.preload != nil {
+		return rl.preload.line, cursorIn(rl.preload.line, rl.preload.cursor)
+	}
+
+	return string(*rl.line), rl.cursor.Pos()
+}
+
+// InsertText inserts text at the cursor position, as if typed by the user, but
+// without interpreting it as keys. It is safe to call from any goroutine while
+// the shell is reading input (eg. to insert a file chosen in a file picker), in
+// which case the line is immediately redisplayed. Otherwise, the text is inserted
+// at the beginning of the next call to Readline.
+func (rl *Shell) InsertText(text string) {
+	if text == "" {
+		return
+	}
+
+	rl.editMutex.Lock()
+	rl.inserts = append(rl.inserts, text)
+	rl.editMutex.Unlock()
+
+	core.Wake(rl.Keys)
+}
+
+// applyEdits sets the preloaded input line if any, and inserts pending text.
+func (rl *Shell) applyEdits() {
+	rl.editMutex.Lock()
+	preload, inserts, reload := rl.preload, rl.inserts, rl.reload
+	rl.preload, rl.inserts, rl.reload = nil, nil, false
+	rl.editMutex.Unlock()
+
+	// Inputrc files changed while watched with WatchConfig.
+	if reload {
+		rl.reReadInitFile()
+	}
+
+	if preload == nil && len(inserts) == 0 {
+		return
+	}
+
+	rl.History.Save()
+
+	if preload != nil {
+		rl.line.Set([]rune(preload.line)...)
+		rl.cursor.Set(cursorIn(preload.line, preload.cursor))
+	}
+
+	for _, text := range inserts {
+		chars := []rune(text)
+		rl.line.Insert(rl.cursor.Pos(), chars...)
+		rl.cursor.Move(len(chars))
+	}
+}
+
+// cursorIn returns the cursor position in the line, or the end of the line.
+func cursorIn(line string, cursor int) int {
+	if length := len([]rune(line)); cursor < 0 || cursor > length {
+		return length
+	}
+
+	return cursor
+}
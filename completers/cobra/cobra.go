@@ -0,0 +1,283 @@
+// Package cobra provides a completer for the readline shell, adapting the completion
+// engine of Cobra command trees to the Completer signature. It is a module of its own,
+// so that programs using the shell without Cobra do not depend on it.
+//
+// Only Cobra command trees are supported: those described with carapace-spec, or
+// completed with carapace actions, need a completer of their own.
+package cobra
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/readline"
+)
+
+const (
+	activeHelpMarker = "_activeHelp_ "
+	flagsTag         = "flags"
+	commandsTag      = "commands"
+	valuesTag        = "values"
+	filesTag         = "files"
+)
+
+// Completer returns a completer for a Cobra command tree, suitable for the shell Completer
+// field. Completions are produced by Cobra's own completion engine (the hidden __complete
+// command), so that valid args, flag completion functions, descriptions and directives
+// (no space, no file completion, file extensions or directory filtering, keep order)
+// are all honored. Subcommands are tagged with the title of their command group.
+//
+// Since each completion executes the command tree, flags are reset to their default
+// values afterwards, the root command output/error writers are restored, and its
+// arguments are reset: Cobra has no way of getting them, so arguments set on the root
+// command with SetArgs must be set again before executing it (os.Args are used otherwise).
+func Completer(root *cobra.Command) func(line []rune, cursor int) readline.Completions {
+	return func(line []rune, cursor int) readline.Completions {
+		if cursor > len(line) {
+			cursor = len(line)
+		}
+
+		args, last := splitArgs(line, cursor)
+
+		cmd, _, err := root.Find(args)
+		if err != nil || cmd == nil {
+			cmd = root
+		}
+
+		out, err := runComplete(root, append(args, last))
+		if err != nil {
+			return readline.CompleteMessage("completion error: %s", err.Error())
+		}
+
+		comps, directive := parseCompletions(cmd, out)
+
+		// A flag=value word is completed on its value only.
+		if strings.HasPrefix(last, "-") && strings.Contains(last, "=") {
+			value := last[strings.Index(last, "=")+1:]
+			comps = comps.Replace(cursor-len([]rune(value)), cursor)
+			last = value
+		}
+
+		return applyDirective(comps, directive, last)
+	}
+}
+
+// splitArgs parses the line with shell syntax, and returns the words
+// before the last one being completed, and this last (maybe empty) word.
+func splitArgs(line []rune, cursor int) (args []string, last string) {
+	words, current := readline.SplitArgs(line, cursor)
+
+	return words[:current], words[current]
+}
+
+// runComplete runs the hidden completion command of the tree,
+// returns its output, and restores the state of the command tree.
+func runComplete(root *cobra.Command, args []string) (string, error) {
+	stdout, stderr := root.OutOrStdout(), root.ErrOrStderr()
+
+	buf := new(bytes.Buffer)
+	root.SetOut(buf)
+	root.SetErr(io.Discard)
+	root.SetArgs(append([]string{cobra.ShellCompRequestCmd}, args...))
+
+	defer func() {
+		root.SetOut(stdout)
+		root.SetErr(stderr)
+		root.SetArgs(nil)
+		resetFlags(root)
+	}()
+
+	err := root.Execute()
+
+	return buf.String(), err
+}
+
+// parseCompletions parses the output of the Cobra completion command into
+// completions, tagging subcommands with their group titles, and flags.
+func parseCompletions(cmd *cobra.Command, out string) (readline.Completions, cobra.ShellCompDirective) {
+	var directive cobra.ShellCompDirective
+
+	var values []readline.Completion
+
+	var messages []string
+
+	groups := make(map[string]string)
+	for _, group := range cmd.Groups() {
+		groups[group.ID] = group.Title
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, ":"):
+			if code, err := strconv.Atoi(line[1:]); err == nil {
+				directive = cobra.ShellCompDirective(code)
+			}
+
+		case strings.HasPrefix(line, activeHelpMarker):
+			messages = append(messages, strings.TrimPrefix(line, activeHelpMarker))
+
+		case line != "":
+			value, desc, _ := strings.Cut(line, "\t")
+			values = append(values, readline.Completion{
+				Value:       value,
+				Description: desc,
+				Tag:         candidateTag(cmd, groups, value),
+			})
+		}
+	}
+
+	comps := readline.CompleteRaw(values)
+
+	for _, msg := range messages {
+		comps = comps.Merge(readline.Message(msg))
+	}
+
+	return comps, directive
+}
+
+// candidateTag returns the tag of a completion candidate.
+func candidateTag(cmd *cobra.Command, groups map[string]string, value string) string {
+	if strings.HasPrefix(value, "-") {
+		return flagsTag
+	}
+
+	for _, sub := range cmd.Commands() {
+		if sub.Name() != value && !sub.HasAlias(value) {
+			continue
+		}
+
+		if title, found := groups[sub.GroupID]; found && title != "" {
+			return strings.TrimSuffix(title, ":")
+		}
+
+		return commandsTag
+	}
+
+	return valuesTag
+}
+
+// applyDirective applies a Cobra completion directive to the completions.
+func applyDirective(comps readline.Completions, directive cobra.ShellCompDirective, last string) readline.Completions {
+	if directive&cobra.ShellCompDirectiveError != 0 {
+		return readline.CompleteMessage("completion error")
+	}
+
+	switch {
+	case directive&cobra.ShellCompDirectiveFilterFileExt != 0:
+		var exts []string
+		comps.EachValue(func(comp readline.Completion) readline.Completion {
+			exts = append(exts, comp.Value)
+			return comp
+		})
+
+		comps = completeFiles(last, false, exts...)
+
+	case directive&cobra.ShellCompDirectiveFilterDirs != 0:
+		comps = completeFiles(last, true)
+
+	case directive&cobra.ShellCompDirectiveNoFileComp == 0 && isEmpty(comps):
+		comps = completeFiles(last, false)
+	}
+
+	if directive&cobra.ShellCompDirectiveNoSpace != 0 {
+		comps = comps.NoSpace()
+	}
+
+	if directive&cobra.ShellCompDirectiveKeepOrder != 0 {
+		comps = comps.NoSort()
+	}
+
+	return comps
+}
+
+// completeFiles completes the files and directories in the directory of the
+// current word, optionally only directories or files with some extensions.
+func completeFiles(word string, dirsOnly bool, exts ...string) readline.Completions {
+	dir, _ := filepath.Split(word)
+
+	path := dir
+	if path == "" {
+		path = "."
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return readline.Completions{}
+	}
+
+	var files []string
+
+	for _, entry := range entries {
+		name := dir + entry.Name()
+
+		switch {
+		case entry.IsDir():
+			files = append(files, name+"/")
+		case dirsOnly:
+		case len(exts) > 0 && !hasExtension(name, exts):
+		default:
+			files = append(files, name)
+		}
+	}
+
+	return readline.CompleteValues(files...).Tag(filesTag).NoSpace('/').LinkFiles()
+}
+
+func hasExtension(name string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.HasSuffix(name, "."+strings.TrimPrefix(ext, ".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isEmpty(comps readline.Completions) bool {
+	empty := true
+
+	comps.EachValue(func(comp readline.Completion) readline.Completion {
+		empty = false
+		return comp
+	})
+
+	return empty
+}
+
+// resetFlags resets all flags of the command tree to their default
+// values, since they are kept across executions of the tree.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			defaults := []string{}
+			if values := strings.Trim(flag.DefValue, "[]"); values != "" {
+				defaults = strings.Split(values, ",")
+			}
+
+			_ = slice.Replace(defaults)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+
+		flag.Changed = false
+	}
+
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
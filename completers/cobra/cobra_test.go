@@ -0,0 +1,192 @@
+package cobra
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/readline"
+)
+
+// newTree returns a command tree with grouped and ungrouped
+// subcommands, and flags completed with values or not.
+func newTree(ran *string) *cobra.Command {
+	root := &cobra.Command{Use: "app", Run: func(*cobra.Command, []string) { *ran = "app" }}
+	root.AddGroup(&cobra.Group{ID: "core", Title: "Core commands:"})
+
+	start := &cobra.Command{Use: "start", GroupID: "core", Run: func(*cobra.Command, []string) { *ran = "start" }}
+	start.Flags().String("mode", "fast", "start mode")
+	start.Flags().Int("count", 1, "number of runs")
+	start.Flags().StringSlice("tags", []string{"a"}, "tags")
+	_ = start.RegisterFlagCompletionFunc("mode", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+		return []string{"fast\tfastest mode", "slow"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	stop := &cobra.Command{
+		Use:     "stop",
+		GroupID: "core",
+		Run:     func(*cobra.Command, []string) { *ran = "stop" },
+		ValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveError
+		},
+	}
+
+	status := &cobra.Command{Use: "status", Run: func(*cobra.Command, []string) { *ran = "status" }}
+
+	root.AddCommand(start, stop, status)
+
+	return root
+}
+
+// complete returns the candidates completing the line, by value.
+func complete(root *cobra.Command, line string) map[string]readline.Completion {
+	comps := Completer(root)([]rune(line), len(line))
+	values := make(map[string]readline.Completion)
+
+	comps.EachValue(func(comp readline.Completion) readline.Completion {
+		values[comp.Value] = comp
+		return comp
+	})
+
+	return values
+}
+
+func keys(values map[string]readline.Completion) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestCompleter_Commands(t *testing.T) {
+	var ran string
+
+	values := complete(newTree(&ran), "st")
+
+	if got, want := keys(values), []string{"start", "status", "stop"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+
+	// Subcommands are tagged with the title of their group.
+	for value, tag := range map[string]string{"start": "Core commands", "stop": "Core commands", "status": commandsTag} {
+		if got := values[value].Tag; got != tag {
+			t.Errorf("candidate %q tag = %q, want %q", value, got, tag)
+		}
+	}
+
+	if ran != "" {
+		t.Errorf("completion ran the %q command", ran)
+	}
+}
+
+func TestCompleter_Flags(t *testing.T) {
+	var ran string
+
+	root := newTree(&ran)
+
+	values := complete(root, "start --m")
+	if got, want := keys(values), []string{"--mode"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+
+	if got := values["--mode"].Tag; got != flagsTag {
+		t.Errorf("flag tag = %q, want %q", got, flagsTag)
+	}
+
+	// Flag values are completed with their completion functions and descriptions.
+	values = complete(root, "start --mode ")
+	if got, want := keys(values), []string{"fast", "slow"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("flag candidates = %v, want %v", got, want)
+	}
+
+	if got := values["fast"].Description; got != "fastest mode" {
+		t.Errorf("flag value description = %q, want %q", got, "fastest mode")
+	}
+
+	// Values of flag=value words are completed the same
+	// (and filtered against the value by the shell).
+	values = complete(root, "start --mode=s")
+	if got, want := keys(values), []string{"fast", "slow"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flag=value candidates = %v, want %v", got, want)
+	}
+}
+
+func TestCompleter_Error(t *testing.T) {
+	var ran string
+
+	// Error directives discard all candidates.
+	if values := complete(newTree(&ran), "stop "); len(values) != 0 {
+		t.Errorf("candidates on error = %v, want none", keys(values))
+	}
+}
+
+func TestCompleter_RestoresTree(t *testing.T) {
+	var ran string
+
+	root := newTree(&ran)
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+
+	complete(root, "start --mode slow --count 3 --tags b,c --")
+
+	// Writers are restored, and nothing is written to them.
+	if root.OutOrStdout() != stdout || root.ErrOrStderr() != stderr {
+		t.Error("root command writers not restored after completion")
+	}
+
+	if stdout.Len() > 0 || stderr.Len() > 0 {
+		t.Errorf("completion wrote to the root command: %q, %q", stdout, stderr)
+	}
+
+	// Flags are reset to their default values.
+	start, _, _ := root.Find([]string{"start"})
+
+	for name, want := range map[string]string{"mode": "fast", "count": "1", "tags": "[a]"} {
+		flag := start.Flags().Lookup(name)
+		if flag.Value.String() != want || flag.Changed {
+			t.Errorf("flag %q = %q (changed: %v), want %q", name, flag.Value.String(), flag.Changed, want)
+		}
+	}
+
+	// Arguments are reset, so that executing the tree uses os.Args
+	// instead of running the completion command again.
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	os.Args = []string{"app", "stop"}
+
+	if err := root.Execute(); err != nil || ran != "stop" {
+		t.Errorf("Execute() after completion ran %q (error: %v), want %q", ran, err, "stop")
+	}
+}
+
+func Test_splitArgs(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantArgs []string
+		wantLast string
+	}{
+		{line: "", wantArgs: []string{}, wantLast: ""},
+		{line: "start --mo", wantArgs: []string{"start"}, wantLast: "--mo"},
+		{line: "start ", wantArgs: []string{"start"}, wantLast: ""},
+		{line: `start "two words`, wantArgs: []string{"start"}, wantLast: "two words"},
+	}
+
+	for _, test := range tests {
+		args, last := splitArgs([]rune(test.line), len(test.line))
+
+		if !reflect.DeepEqual(args, test.wantArgs) || last != test.wantLast {
+			t.Errorf("splitArgs(%q) = %q, %q, want %q, %q", test.line, args, last, test.wantArgs, test.wantLast)
+		}
+	}
+}
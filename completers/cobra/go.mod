@@ -0,0 +1,17 @@
+module github.com/reeflective/readline/completers/cobra
+
+go 1.21
+
+require (
+	github.com/reeflective/readline v1.0.15
+	github.com/spf13/cobra v1.7.0
+	github.com/spf13/pflag v1.0.5
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/rivo/uniseg v0.4.4 // indirect
+	golang.org/x/exp v0.0.0-20220827204233-334a2380cb91 // indirect
+	golang.org/x/sys v0.8.0 // indirect
+	golang.org/x/term v0.8.0 // indirect
+)
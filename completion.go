@@ -2,7 +2,10 @@ package readline
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
 
+	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/history"
@@ -12,6 +15,7 @@ import (
 func (rl *Shell) completionCommands() commands {
 	return map[string]func(){
 		"complete":               rl.completeWord,
+		"complete-prefix":        rl.completePrefix,
 		"possible-completions":   rl.possibleCompletions,
 		"insert-completions":     rl.insertCompletions,
 		"menu-complete":          rl.menuComplete,
@@ -23,6 +27,22 @@ func (rl *Shell) completionCommands() commands {
 		"accept-and-menu-complete": rl.acceptAndMenuComplete,
 		"vi-registers-complete":    rl.viRegistersComplete,
 		"menu-incremental-search":  rl.menuIncrementalSearch,
+
+		"menu-incremental-search-field": rl.menuIncrementalSearchField,
+
+		"old-menu-complete":             rl.menuComplete,
+		"complete-filename":             rl.completeFilename,
+		"possible-filename-completions": rl.possibleFilenameCompletions,
+		"complete-command":              rl.completeCommand,
+		"possible-command-completions":  rl.possibleCommandCompletions,
+		"complete-variable":             rl.completeVariable,
+		"possible-variable-completions": rl.possibleVariableCompletions,
+		"complete-username":             rl.completeUsername,
+		"possible-username-completions": rl.possibleUsernameCompletions,
+		"complete-hostname":             rl.completeHostname,
+		"possible-hostname-completions": rl.possibleHostnameCompletions,
+		"dabbrev-expand":                rl.dabbrevExpand,
+		"correct-command-word":          rl.correctCommandWord,
 	}
 }
 
@@ -30,23 +50,22 @@ func (rl *Shell) completionCommands() commands {
 // Commands ---------------------------------------------------------------------------
 //
 
-// Attempt completion on the current word.
-// Currently identitical to menu-complete.
+// Attempt completion on the current word. If all matches share a common
+// prefix longer than the current word, it is inserted first. Otherwise,
+// this is currently identitical to menu-complete.
 func (rl *Shell) completeWord() {
-	rl.History.SkipSave()
-
-	// This completion function should attempt to insert the first
-	// valid completion found, without printing the actual list.
-	if !rl.completer.IsActive() {
-		rl.startMenuComplete(rl.commandCompletion)
+	rl.completeWordWith(rl.commandCompletion)
+}
 
-		if rl.Config.GetBool("menu-complete-display-prefix") {
-			return
-		}
-	}
+// Insert the longest prefix common to all completions
+// of the current word, without opening the completion menu.
+func (rl *Shell) completePrefix() {
+	rl.History.Save()
 
-	rl.completer.Select(1, 0)
-	rl.completer.SkipDisplay()
+	rl.completer.Reset()
+	rl.completer.GenerateWith(rl.commandCompletion)
+	rl.completer.InsertCommonPrefix()
+	rl.completer.ClearMenu(true)
 }
 
 // List possible completions for the current word.
@@ -62,7 +81,7 @@ func (rl *Shell) insertCompletions() {
 
 	// Generate all possible completions
 	if !rl.completer.IsActive() {
-		rl.startMenuComplete(rl.commandCompletion)
+		rl.generateMenu(rl.commandCompletion)
 	}
 
 	// Insert each match, cancel insertion with preserving
@@ -77,6 +96,57 @@ func (rl *Shell) insertCompletions() {
 	rl.completer.ClearMenu(true)
 }
 
+// Attempt filename completion on the current word.
+func (rl *Shell) completeFilename() {
+	rl.completeWordWith(sourceCompletion(rl.filenameCompletion))
+}
+
+// List the possible filename completions of the current word.
+func (rl *Shell) possibleFilenameCompletions() {
+	rl.startMenuComplete(sourceCompletion(rl.filenameCompletion))
+}
+
+// Attempt completion on the current word, treating it as a command name
+// (an executable file in $PATH).
+func (rl *Shell) completeCommand() {
+	rl.completeWordWith(sourceCompletion(rl.commandNameCompletion))
+}
+
+// List the possible completions of the current word, treating it as a command name.
+func (rl *Shell) possibleCommandCompletions() {
+	rl.startMenuComplete(sourceCompletion(rl.commandNameCompletion))
+}
+
+// Attempt completion on the current word, treating it as an environment variable.
+func (rl *Shell) completeVariable() {
+	rl.completeWordWith(sourceCompletion(rl.variableCompletion))
+}
+
+// List the possible completions of the current word, treating it as an environment variable.
+func (rl *Shell) possibleVariableCompletions() {
+	rl.startMenuComplete(sourceCompletion(rl.variableCompletion))
+}
+
+// Attempt completion on the current word, treating it as a username (after a tilde).
+func (rl *Shell) completeUsername() {
+	rl.completeWordWith(sourceCompletion(rl.usernameCompletion))
+}
+
+// List the possible completions of the current word, treating it as a username.
+func (rl *Shell) possibleUsernameCompletions() {
+	rl.startMenuComplete(sourceCompletion(rl.usernameCompletion))
+}
+
+// Attempt completion on the current word, treating it as a hostname.
+func (rl *Shell) completeHostname() {
+	rl.completeWordWith(sourceCompletion(rl.hostnameCompletion))
+}
+
+// List the possible completions of the current word, treating it as a hostname.
+func (rl *Shell) possibleHostnameCompletions() {
+	rl.startMenuComplete(sourceCompletion(rl.hostnameCompletion))
+}
+
 // Like complete-word, except that menu completion is used.
 func (rl *Shell) menuComplete() {
 	rl.History.SkipSave()
@@ -86,8 +156,10 @@ func (rl *Shell) menuComplete() {
 	if !rl.completer.IsActive() {
 		rl.startMenuComplete(rl.commandCompletion)
 
-		// Immediately select only if not asked to display first.
+		// Immediately select only if not asked to display first,
+		// in which case we insert the common prefix of all matches.
 		if rl.Config.GetBool("menu-complete-display-prefix") {
+			rl.completer.InsertCommonPrefix()
 			return
 		}
 	}
@@ -102,7 +174,7 @@ func (rl *Shell) menuComplete() {
 func (rl *Shell) deleteCharOrList() {
 	switch {
 	case rl.cursor.Pos() < rl.line.Len():
-		rl.line.CutRune(rl.cursor.Pos())
+		rl.line.CutGrapheme(rl.cursor.Pos())
 	default:
 		rl.possibleCompletions()
 	}
@@ -185,26 +257,216 @@ func (rl *Shell) viRegistersComplete() {
 func (rl *Shell) menuIncrementalSearch() {
 	rl.History.SkipSave()
 
-	// Always regenerate the list of completions.
-	rl.completer.GenerateWith(rl.commandCompletion)
+	// Always regenerate the list of completions: those of the menu
+	// if one is open (eg. history-menu), or else the command ones.
+	completer := rl.commandCompletion
+	if cached := rl.completer.Cached(); cached != nil && rl.Keymap.Local() == keymap.MenuSelect {
+		completer = cached
+	}
+
+	rl.completer.GenerateWith(completer)
 	rl.completer.IsearchStart("completions", false, false)
 }
 
+// In incremental search mode, cycle through the candidate fields matched
+// by the search: values and descriptions, values only, or descriptions only.
+func (rl *Shell) menuIncrementalSearchField() {
+	rl.History.SkipSave()
+	rl.completer.IsearchCycleField()
+}
+
+//
+// Programmatic completion ------------------------------------------------------------
+//
+
+// CompleteNow generates completions for the current line and cursor position
+// and opens the completion menu, as the possible-completions command does.
+// If only one candidate is available, it is directly inserted in the line.
+// The menu is displayed on the next refresh of the shell, and never needs to
+// be confirmed by the user, regardless of the completion-query-items option.
+// Like the other methods below, it acts on the shell state directly,
+// and must be called from the shell goroutine (eg. in a bound command).
+func (rl *Shell) CompleteNow() {
+	rl.generateMenu(rl.commandCompletion)
+}
+
+// CompletionCandidates returns a snapshot of the candidates currently available
+// in the completion menu (empty if not completing), in their display order, and
+// the index of the one currently selected and inserted in the line (or -1).
+func (rl *Shell) CompletionCandidates() (candidates []Completion, selected int) {
+	if !rl.completer.IsActive() {
+		return nil, -1
+	}
+
+	return rl.completer.Candidates()
+}
+
+// CompletionSelect selects the candidate at index n in the list returned by
+// CompletionCandidates(), and inserts it in the line as menu selection does.
+// Returns false if no completions are active, or if the index is invalid.
+// In commands, only those of the menu-select keymap can select candidates:
+// other commands close the completion menu before running.
+func (rl *Shell) CompletionSelect(n int) bool {
+	if !rl.completer.IsActive() {
+		return false
+	}
+
+	return rl.completer.SelectIndex(n)
+}
+
+// CompletionAccept accepts the currently selected candidate in the
+// input line and closes the completion menu. Returns false if no
+// candidate was selected.
+func (rl *Shell) CompletionAccept() bool {
+	if !rl.completer.IsActive() || !rl.completer.IsInserting() {
+		return false
+	}
+
+	rl.completer.Reset()
+	rl.History.Save()
+
+	return true
+}
+
 //
 // Utilities --------------------------------------------------------------------------
 //
 
+// completeWordWith attempts completion on the current word with a completer, as
+// complete does: the common prefix of all matches is inserted if longer than the
+// word, and otherwise the next match is selected, without displaying the list.
+func (rl *Shell) completeWordWith(completer completion.Completer) {
+	rl.History.SkipSave()
+
+	// This completion function should attempt to insert the first
+	// valid completion found, without printing the actual list.
+	if !rl.completer.IsActive() {
+		rl.generateMenu(completer)
+
+		if rl.completer.InsertCommonPrefix() {
+			rl.completer.SkipDisplay()
+			return
+		}
+
+		if rl.Config.GetBool("menu-complete-display-prefix") {
+			return
+		}
+	}
+
+	rl.completer.Select(1, 0)
+	rl.completer.SkipDisplay()
+}
+
 // startMenuComplete generates a completion menu with completions
 // generated from a given completer, without selecting a candidate.
+// If there are more than completion-query-items candidates, the
+// user is asked to confirm their display first.
 func (rl *Shell) startMenuComplete(completer completion.Completer) {
+	rl.generateMenu(completer)
+	rl.queryCompletions()
+}
+
+// generateMenu generates a completion menu with completions from a completer,
+// as startMenuComplete does, but without asking to confirm their display.
+func (rl *Shell) generateMenu(completer completion.Completer) {
 	rl.History.SkipSave()
 
 	rl.Keymap.SetLocal(keymap.MenuSelect)
 	rl.completer.GenerateWith(completer)
 }
 
+// queryCompletions asks the user whether to display the completions, as bash does,
+// if there are more candidates than the completion-query-items option (unless it is
+// not positive). If not (or on escape), completion is cancelled.
+func (rl *Shell) queryCompletions() {
+	limit := rl.Config.GetInt("completion-query-items")
+	count := rl.completer.Matches()
+
+	if limit <= 0 || count <= limit || !rl.completer.IsActive() {
+		return
+	}
+
+	rl.completer.SkipDisplay()
+	defer rl.Hint.Reset()
+
+	for {
+		rl.Hint.Set(rl.styles.Hint + fmt.Sprintf("Display all %d possibilities? ", count) + color.Reset + "(y or n)")
+		rl.Display.Refresh()
+
+		key, isAbort := rl.Keys.ReadKey()
+		if isAbort {
+			rl.completer.ResetForce()
+			return
+		}
+
+		switch unicode.ToLower(key) {
+		case 'y', ' ':
+			rl.completer.ShowDisplay()
+			return
+		case 'n', inputrc.Backspace, inputrc.Delete, '\x03', '\x07': // Ctrl-C, Ctrl-G
+			rl.completer.ResetForce()
+			return
+		}
+	}
+}
+
+// sourceCompletion returns a completer using one of the builtin completion sources.
+func sourceCompletion(source func() Completions) completion.Completer {
+	return func() completion.Values {
+		comps := source()
+		return comps.convert()
+	}
+}
+
+// dumpCompletions returns candidates listed one per line and in their order,
+// only inserted at the cursor position when selected, with a title hint. This is
+// used to display the lists of the dump-* commands.
+func (rl *Shell) dumpCompletions(title string, vals []completion.Candidate) completion.Values {
+	comps := completion.AddRaw(vals)
+	comps.Sort["*"] = completion.SortNone
+	comps.ListLong["*"] = true
+	comps.ListOnly = true
+
+	// Nothing is to be replaced, so that all candidates are listed.
+	_, cursor := rl.completer.Line()
+	comps.Replace = true
+	comps.ReplaceStart, comps.ReplaceEnd = cursor.Pos(), cursor.Pos()
+
+	hint := rl.styles.Title + "(" + title + ")"
+
+	if len(vals) == 0 {
+		hint += " - empty -"
+	}
+
+	comps.Messages.Add(hint)
+
+	return comps
+}
+
+// quoteBinds returns the quoted key sequences bound
+// to a command, listing at most five of them.
+func quoteBinds(binds []string) string {
+	quoted := make([]string, 0, len(binds))
+
+	for _, bind := range binds[:min(len(binds), 5)] {
+		quoted = append(quoted, "\""+bind+"\"")
+	}
+
+	list := strings.Join(quoted, ", ")
+
+	if len(binds) > 5 {
+		list += " ..."
+	}
+
+	return list
+}
+
 // commandCompletion generates the completions for commands/args/flags.
 func (rl *Shell) commandCompletion() completion.Values {
+	if comps, found := rl.designatorCompletion(); found {
+		return comps
+	}
+
 	if rl.Completer == nil {
 		return completion.Values{}
 	}
@@ -215,6 +477,42 @@ func (rl *Shell) commandCompletion() completion.Values {
 	return comps.convert()
 }
 
+// designatorCompletion returns the history lines matching the event designator
+// being typed (a word starting with !), if the designator-completion option is on.
+func (rl *Shell) designatorCompletion() (completion.Values, bool) {
+	if !rl.Config.GetBool("designator-completion") {
+		return completion.Values{}, false
+	}
+
+	line, cursor := rl.completer.Line()
+	cpos := cursor.Pos()
+
+	bpos := cpos
+	for bpos > 0 && !unicode.IsSpace((*line)[bpos-1]) {
+		bpos--
+	}
+
+	word := string((*line)[bpos:cpos])
+	if !strings.HasPrefix(word, "!") {
+		return completion.Values{}, false
+	}
+
+	return history.CompleteDesignators(rl.History, word, bpos, cpos), true
+}
+
+// registerCompletion lists all registers and their contents
+// while the name of one is read, without replacing anything.
+func (rl *Shell) registerCompletion() completion.Values {
+	comps := rl.Buffers.Complete()
+	comps.ListOnly = true
+
+	_, cursor := rl.completer.Line()
+	comps.Replace = true
+	comps.ReplaceStart, comps.ReplaceEnd = cursor.Pos(), cursor.Pos()
+
+	return comps
+}
+
 // historyCompletion manages the various completion/isearch modes related
 // to history control. It can start the history completions, stop them, cycle
 // through sources if more than one, and adjust the completion/isearch behavior.
@@ -239,7 +537,7 @@ func (rl *Shell) historyCompletion(forward, filterLine, substring bool) {
 	default:
 		// Notify if we don't have history sources at all.
 		if rl.History.Current() == nil {
-			rl.Hint.SetTemporary(fmt.Sprintf("%s%s%s %s", color.Dim, color.FgRed, "No command history source", color.Reset))
+			rl.Hint.SetTemporary(fmt.Sprintf("%s%s %s", rl.styles.Error, "No command history source", color.Reset))
 			return
 		}
 
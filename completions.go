@@ -3,12 +3,27 @@ package readline
 import (
 	"fmt"
 
+	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/strutil"
+	"github.com/reeflective/readline/internal/term"
 )
 
 // Completion represents a completion candidate.
 type Completion = completion.Candidate
 
+// SortStrategy determines how the candidates of a completion group are sorted.
+type SortStrategy = completion.SortStrategy
+
+// Sort strategies for completion groups.
+const (
+	SortAlpha   = completion.SortAlpha   // Case-insensitive alphabetical order (default).
+	SortNone    = completion.SortNone    // Keep candidates in the order they were given.
+	SortNatural = completion.SortNatural // Alphabetical, but digit runs are compared numerically.
+	SortLength  = completion.SortLength  // Shortest candidates first.
+	SortScore   = completion.SortScore   // Best matches against the current prefix first.
+)
+
 // Completions holds all completions candidates and their associated data,
 // including usage strings, messages, and suffix matchers for autoremoval.
 // Some of those additional settings will apply to all contained candidates,
@@ -19,10 +34,14 @@ type Completions struct {
 	noSpace  completion.SuffixMatcher
 	usage    string
 	listLong map[string]bool
-	noSort   map[string]bool
+	sort     map[string]completion.SortStrategy
+	sortLess map[string]func(a, b Completion) bool
 	listSep  map[string]string
 	pad      map[string]bool
 	escapes  map[string]bool
+	previews map[string]func(value string) *Image
+	region   []int
+	suggest  string
 
 	// Initially this will be set to the part of the current word
 	// from the beginning of the word up to the position of the cursor.
@@ -120,6 +139,49 @@ func Message(msg string, args ...any) Completions {
 	return comps
 }
 
+// Hyperlink returns the text as a hyperlink to the URI, which terminals supporting
+// them (with OSC 8 sequences) make clickable. It can be used in completion messages,
+// displays and descriptions, in hints and prompts, without altering their layout.
+//
+//	CompleteMessage("see %s", Hyperlink("https://example.com/doc", "the docs"))
+func Hyperlink(uri, text string) string {
+	return color.Link(uri, text)
+}
+
+// FileURL returns the file:// URL of a path (relative to the working directory
+// of the process if not absolute), to be used as the URI of a hyperlink.
+func FileURL(path string) string {
+	return term.FileURL(path)
+}
+
+// SplitArgs parses the line up to the cursor with shell syntax, and returns
+// the arguments of the command being completed, with their quotes and escapes
+// removed, and the index of the argument under cursor (always the last one,
+// which is empty when starting a new word). The words of an unclosed command
+// substitution ($() or backticks) belong to the substituted command, only the
+// words after the last control operator (|, &&, ;, etc) are returned, and
+// redirections and their targets are not arguments (unless being completed).
+// This can be used by completers to obtain a correctly parsed argument vector.
+func SplitArgs(line []rune, cursor int) (args []string, current int) {
+	if cursor > len(line) {
+		cursor = len(line)
+	}
+
+	for _, word := range strutil.CommandArgs(line[:cursor]) {
+		args = append(args, word.Value)
+	}
+
+	return args, len(args) - 1
+}
+
+// ShellAcceptMultiline can be used as the AcceptMultiline function of the shell for
+// POSIX shell command lines: it returns false (continuing the line on a new one) if
+// the line has an unclosed quote, command substitution or bracket, a here-document
+// without its delimiter line, or if it ends with a backslash or with a |, || or &&.
+func ShellAcceptMultiline(line []rune) (accept bool) {
+	return !strutil.Incomplete(line)
+}
+
 // Suppress suppresses specific error messages using regular expressions.
 func (c Completions) Suppress(expr ...string) Completions {
 	if err := c.messages.Suppress(expr...); err != nil {
@@ -167,6 +229,28 @@ func (c Completions) Suffix(suffix string) Completions {
 	return c
 }
 
+// Replace sets the region of the input line (start and end offsets) to be replaced
+// by the inserted candidates, instead of the current word up to the cursor. This
+// allows to rewrite an entire flag=value pair, or to fix up earlier arguments.
+// If the region is invalid for the current line, it is ignored.
+//
+//	CompleteValues("--flag=value").Replace(10, 22)
+func (c Completions) Replace(start, end int) Completions {
+	c.region = []int{start, end}
+	return c
+}
+
+// Suggest sets an inline suggestion for the line, displayed dimmed after
+// it (like history autosuggestions) when the cursor is at the end of the
+// line, and accepted with forward-char or end-of-line (right arrow/end).
+// The suggestion is the text to append to the line, and might be anything
+// (like the remaining words of a command line inferred by the application).
+// Suggestions are only queried when the completion-autosuggest option is on.
+func (c Completions) Suggest(text string) Completions {
+	c.suggest = text
+	return c
+}
+
 // Usage sets the usage.
 func (c Completions) Usage(usage string, args ...any) Completions {
 	return c.UsageF(func() string {
@@ -239,6 +323,77 @@ func (c Completions) TagF(f func(value string) string) Completions {
 	return c
 }
 
+// IconF sets the icon displayed before each value, and its style, using a function.
+// Icons are aligned in their own column, and candidates without one are padded.
+//
+//	CompleteValues("dir/", "test.txt").IconF(func(value string) (icon, style string) {
+//		return "\uf07b", "34"
+//	})
+func (c Completions) IconF(f func(value string) (icon, style string)) Completions {
+	for index, v := range c.values {
+		c.values[index].Icon, c.values[index].IconStyle = f(v.Value)
+	}
+
+	return c
+}
+
+// AnnotateF sets the annotation displayed after each value (aligned to the
+// right of its column), and its style, using a function.
+//
+//	CompleteValues("dir/", "test.txt").AnnotateF(func(value string) (annotation, style string) {
+//		return "4.0K", "2"
+//	})
+func (c Completions) AnnotateF(f func(value string) (annotation, style string)) Completions {
+	for index, v := range c.values {
+		c.values[index].Annotation, c.values[index].AnnotationStyle = f(v.Value)
+	}
+
+	return c
+}
+
+// InsertF sets the text inserted in place of each value using a function.
+// The returned text may contain several shell words, and a cursor marker
+// (%|%) at which the cursor will be placed once the candidate is inserted.
+//
+//	CompleteValues("--flag").InsertF(func(value string) string {
+//		return value + "=%|%"
+//	})
+func (c Completions) InsertF(f func(value string) string) Completions {
+	for index, v := range c.values {
+		c.values[index].Insert = f(v.Value)
+	}
+
+	return c
+}
+
+// LinkF makes the display of each candidate a hyperlink to the URI returned
+// by the function for its value, if not empty. Inserted values are unchanged.
+//
+//	CompleteValues("v1.2.0").LinkF(func(value string) string {
+//		return "https://example.com/releases/" + value
+//	})
+func (c Completions) LinkF(f func(value string) string) Completions {
+	for index, v := range c.values {
+		display := v.Display
+		if display == "" {
+			display = v.Value
+		}
+
+		c.values[index].Display = color.Link(f(v.Value), display)
+	}
+
+	return c
+}
+
+// LinkFiles makes the display of each candidate a hyperlink to the file it
+// completes (its value is a path, relative to the working directory if not
+// absolute), so that file completions can be opened from the terminal.
+//
+//	CompleteValues("go.mod", "main.go").LinkFiles()
+func (c Completions) LinkFiles() Completions {
+	return c.LinkF(FileURL)
+}
+
 // DisplayList forces the completions to be list below each other as a list.
 // A series of tags can be passed to restrict this to these tags. If empty,
 // will be applied to all completions.
@@ -294,16 +449,73 @@ func (c Completions) ListSeparator(seps ...string) Completions {
 // A series of tags can be passed to restrict this to these tags. If empty, will be
 // applied to all completions.
 func (c Completions) NoSort(tags ...string) Completions {
-	if c.noSort == nil {
-		c.noSort = make(map[string]bool)
+	return c.SortBy(SortNone, tags...)
+}
+
+// SortBy sets the strategy used to sort the completions: alphabetical (default),
+// natural, by length, by match score, or none. A series of tags can be passed to
+// restrict this to these tags. If empty, will be applied to all completions.
+//
+//	CompleteValues("file10", "file2").SortBy(readline.SortNatural)
+func (c Completions) SortBy(strategy SortStrategy, tags ...string) Completions {
+	if c.sort == nil {
+		c.sort = make(map[string]completion.SortStrategy)
+	}
+
+	if len(tags) == 0 {
+		tags = []string{"*"}
+	}
+
+	for _, tag := range tags {
+		c.sort[tag] = strategy
+	}
+
+	return c
+}
+
+// SortF sorts the completions with a function reporting whether the candidate
+// a must sort before b. A series of tags can be passed to restrict this to these
+// tags. If empty, will be applied to all completions.
+//
+//	CompleteValues("a", "bbb", "cc").SortF(func(a, b Completion) bool {
+//		return len(a.Value) > len(b.Value)
+//	})
+func (c Completions) SortF(less func(a, b Completion) bool, tags ...string) Completions {
+	if c.sortLess == nil {
+		c.sortLess = make(map[string]func(a, b Completion) bool)
 	}
 
 	if len(tags) == 0 {
-		c.noSort["*"] = true
+		tags = []string{"*"}
 	}
 
 	for _, tag := range tags {
-		c.noSort[tag] = true
+		c.sortLess[tag] = less
+	}
+
+	return c
+}
+
+// PreviewF sets a function returning the preview of candidates (eg. the thumbnail
+// of an image file), displayed in the hint section while they are selected. A series
+// of tags can be passed to restrict this to these tags. If empty, will be applied to
+// all completions. Images are displayed if the terminal supports an image protocol,
+// or else their alternative text.
+//
+//	CompleteValues("cat.png", "dog.png").PreviewF(func(value string) *Image {
+//		return &Image{Image: thumbnail(value), Cols: 20, Rows: 8, Alt: value}
+//	})
+func (c Completions) PreviewF(f func(value string) *Image, tags ...string) Completions {
+	if c.previews == nil {
+		c.previews = make(map[string]func(value string) *Image)
+	}
+
+	if len(tags) == 0 {
+		tags = []string{"*"}
+	}
+
+	for _, tag := range tags {
+		c.previews[tag] = f
 	}
 
 	return c
@@ -406,6 +618,14 @@ func (c *Completions) merge(other Completions) {
 		c.usage = other.usage
 	}
 
+	if other.region != nil {
+		c.region = other.region
+	}
+
+	if other.suggest != "" {
+		c.suggest = other.suggest
+	}
+
 	c.noSpace.Merge(other.noSpace)
 	c.messages.Merge(other.messages)
 
@@ -415,9 +635,33 @@ func (c *Completions) merge(other Completions) {
 		}
 	}
 
-	for tag := range other.noSort {
-		if _, found := c.noSort[tag]; !found {
-			c.noSort[tag] = true
+	for tag, strategy := range other.sort {
+		if c.sort == nil {
+			c.sort = make(map[string]completion.SortStrategy)
+		}
+
+		if _, found := c.sort[tag]; !found {
+			c.sort[tag] = strategy
+		}
+	}
+
+	for tag, less := range other.sortLess {
+		if c.sortLess == nil {
+			c.sortLess = make(map[string]func(a, b Completion) bool)
+		}
+
+		if _, found := c.sortLess[tag]; !found {
+			c.sortLess[tag] = less
+		}
+	}
+
+	for tag, preview := range other.previews {
+		if c.previews == nil {
+			c.previews = make(map[string]func(value string) *Image)
+		}
+
+		if _, found := c.previews[tag]; !found {
+			c.previews[tag] = preview
 		}
 	}
 
@@ -441,13 +685,26 @@ func (c *Completions) convert() completion.Values {
 	comps.NoSpace = c.noSpace
 	comps.Usage = c.usage
 	comps.ListLong = c.listLong
-	comps.NoSort = c.noSort
+	for tag, strategy := range c.sort {
+		comps.Sort[tag] = strategy
+	}
+
+	for tag, less := range c.sortLess {
+		comps.SortLess[tag] = less
+	}
 	comps.ListSep = c.listSep
 	comps.Pad = c.pad
 	comps.Escapes = c.escapes
+	comps.Previews = c.previews
 
 	comps.PREFIX = c.PREFIX
 	comps.SUFFIX = c.SUFFIX
+	comps.Suggest = c.suggest
+
+	if c.region != nil {
+		comps.Replace = true
+		comps.ReplaceStart, comps.ReplaceEnd = c.region[0], c.region[1]
+	}
 
 	return comps
 }
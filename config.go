@@ -0,0 +1,110 @@
+package readline
+
+import (
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/keymap"
+)
+
+// ReloadConfig parses the user inputrc files again (with the shell inputrc options),
+// and applies their binds and variables, switching the editing mode if it changed.
+// The input line being edited is kept as is. This is what the re-read-init-file
+// command (bound to C-x C-r in Emacs mode) does.
+// It should not be called while the shell is reading input: use WatchConfig to
+// reload the configuration from other goroutines.
+func (rl *Shell) ReloadConfig() error {
+	main := rl.Keymap.Main()
+
+	if err := rl.Keymap.ReloadConfig(rl.Opts...); err != nil {
+		return err
+	}
+
+	defer rl.Keymap.UpdateCursor()
+
+	if newMain := rl.Keymap.Main(); newMain != main {
+		switch newMain {
+		case keymap.Emacs, keymap.EmacsStandard, keymap.EmacsMeta, keymap.EmacsCtrlX:
+			rl.emacsEditingMode()
+		case keymap.Vi, keymap.ViCommand, keymap.ViMove:
+			rl.viCommandMode()
+		case keymap.ViInsert:
+			rl.viInsertMode()
+		}
+	}
+
+	return nil
+}
+
+// WatchConfig checks the user inputrc files for changes at each interval, and
+// reloads the configuration when one of them is modified, created or removed:
+// immediately if the shell is reading input (as with re-read-init-file), or else
+// at the beginning of the next call to Readline. The returned function stops
+// watching the files.
+func (rl *Shell) WatchConfig(interval time.Duration) (stop func()) {
+	current, _ := user.Current()
+	files := inputrc.UserFiles(current)
+	last := modTimes(files)
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			times := modTimes(files)
+			if sameTimes(times, last) {
+				continue
+			}
+
+			last = times
+
+			rl.editMutex.Lock()
+			rl.reload = true
+			rl.editMutex.Unlock()
+
+			core.Wake(rl.Keys)
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+// modTimes returns the modification times of files, zero for missing ones.
+func modTimes(files []string) []time.Time {
+	times := make([]time.Time, len(files))
+
+	for i, name := range files {
+		if info, err := os.Stat(name); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+
+	return times
+}
+
+func sameTimes(times, others []time.Time) bool {
+	for i := range times {
+		if !times[i].Equal(others[i]) {
+			return false
+		}
+	}
+
+	return true
+}
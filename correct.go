@@ -0,0 +1,160 @@
+package readline
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// SetCorrector sets a function returning the words that command names can be
+// corrected to (eg. the commands, builtins and aliases of the host shell). When
+// the correct-command option is on, accepting a line whose command word is not
+// one of them, but close to some (a typo), asks for the closest to be used
+// instead: y uses it, n accepts the line as is, and e (or escape) goes back to
+// editing it. The correct-command-word command offers all close words in the
+// completion menu instead. Set to nil to remove it.
+func (rl *Shell) SetCorrector(dictionary func() []string) {
+	rl.corrector = dictionary
+}
+
+// Offer the words of the corrector dictionary close to the command word
+// under the cursor in the completion menu, or replace it if there is only one.
+// The bell is rung if the word is correct, or if no corrections are found.
+func (rl *Shell) correctCommandWord() {
+	word, ok := rl.commandWord(rl.cursor.Pos())
+	if !ok {
+		rl.bell.Ring()
+		return
+	}
+
+	corrections := rl.corrections(word.Value)
+	if len(corrections) == 0 {
+		rl.bell.Ring()
+		return
+	}
+
+	vals := make([]completion.Candidate, 0, len(corrections))
+	for _, correction := range corrections {
+		vals = append(vals, completion.Candidate{Value: correction, Tag: "corrections"})
+	}
+
+	rl.startMenuComplete(func() completion.Values {
+		comps := completion.AddRaw(vals)
+		comps.Sort["*"] = completion.SortNone
+		comps.Replace, comps.ReplaceStart, comps.ReplaceEnd = true, word.Start, word.End
+		comps.NoFilter = true
+
+		return comps
+	})
+}
+
+// correctAccepted checks the command word of the accepted line, when the
+// correct-command option is on, and asks for its correction if it has one.
+// It returns false if the user wants to keep editing the line.
+func (rl *Shell) correctAccepted() bool {
+	if !rl.Config.GetBool("correct-command") {
+		return true
+	}
+
+	word, ok := rl.commandWord(0)
+	if !ok {
+		return true
+	}
+
+	corrections := rl.corrections(word.Value)
+	if len(corrections) == 0 {
+		return true
+	}
+
+	defer rl.Hint.Reset()
+
+	for {
+		rl.Hint.Set(rl.styles.Isearch + "correct " + color.Reset + color.Bold + word.Value + color.Reset +
+			rl.styles.Isearch + " to " + color.Reset + color.Bold + corrections[0] + color.Reset + " (y, n, e)")
+		rl.Display.Refresh()
+
+		key, isAbort := rl.Keys.ReadKey()
+		if isAbort {
+			return false
+		}
+
+		switch unicode.ToLower(key) {
+		case 'y':
+			rl.History.Save()
+			rl.line.InsertBetween(word.Start, word.End, []rune(corrections[0])...)
+			rl.cursor.Set(rl.line.Len())
+
+			return true
+		case 'n':
+			return true
+		case 'e':
+			return false
+		}
+	}
+}
+
+// commandWord returns the command word of the command in which pos is, if it is
+// written literally (without quotes or escapes, and neither a path nor an assignment).
+func (rl *Shell) commandWord(pos int) (word strutil.Token, ok bool) {
+	line := *rl.line
+	args := strutil.CommandArgs(line[:strutil.CommandEnd(line, pos)])
+
+	if len(args) == 0 || args[0].Kind != strutil.TokenWord {
+		return word, false
+	}
+
+	word = args[0]
+
+	if word.Value == "" || word.Value != string(line[word.Start:word.End]) || strings.ContainsAny(word.Value, "/=$`") {
+		return word, false
+	}
+
+	return word, true
+}
+
+// corrections returns the words of the corrector dictionary close to a misspelt
+// word, from the closest, or nothing if there is no corrector or if the word is in
+// the dictionary. Words at most one edit away are close, or two for words longer
+// than four characters.
+func (rl *Shell) corrections(word string) []string {
+	if rl.corrector == nil {
+		return nil
+	}
+
+	maxDistance := 1
+	if utf8.RuneCountInString(word) > 4 {
+		maxDistance = 2
+	}
+
+	distances := make(map[string]int)
+
+	for _, candidate := range rl.corrector() {
+		if candidate == word {
+			return nil
+		}
+
+		if distance := strutil.Distance(word, candidate); distance <= maxDistance {
+			distances[candidate] = distance
+		}
+	}
+
+	corrections := make([]string, 0, len(distances))
+	for candidate := range distances {
+		corrections = append(corrections, candidate)
+	}
+
+	sort.Slice(corrections, func(i, j int) bool {
+		if distances[corrections[i]] != distances[corrections[j]] {
+			return distances[corrections[i]] < distances[corrections[j]]
+		}
+
+		return corrections[i] < corrections[j]
+	})
+
+	return corrections
+}
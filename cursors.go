@@ -0,0 +1,89 @@
+package readline
+
+import "strings"
+
+// multiCursorCommands are the editing commands applied at all cursors when
+// additional cursors are set (movement commands only move the main cursor).
+var multiCursorCommands = map[string]bool{
+	"self-insert":              true,
+	"tab-insert":               true,
+	"delete-char":              true,
+	"end-of-file":              true,
+	"backward-delete-char":     true,
+	"vi-delete":                true,
+	"vi-backward-delete-char":  true,
+	"kill-word":                true,
+	"backward-kill-word":       true,
+	"unix-word-rubout":         true,
+	"shell-kill-word":          true,
+	"shell-backward-kill-word": true,
+	"delete-word":              true,
+	"yank":                     true,
+	"transpose-chars":          true,
+	"up-case-word":             true,
+	"down-case-word":           true,
+	"capitalize-word":          true,
+	"vi-change-case":           true,
+}
+
+// Place additional cursors at the beginning of all occurrences, in the line,
+// of the selected text (or of the word under the cursor if nothing is selected).
+// The main cursor is placed at the beginning of the occurrence it was in.
+func (rl *Shell) selectAllOccurrences() {
+	if rl.line.Len() == 0 {
+		return
+	}
+
+	bpos, epos := rl.selection.Pos()
+	if bpos == -1 || epos == -1 {
+		bpos, epos = rl.line.SelectWord(rl.cursor.Pos())
+		epos++
+	}
+
+	rl.selection.Reset()
+
+	if bpos >= epos || epos > rl.line.Len() {
+		return
+	}
+
+	target := string((*rl.line)[bpos:epos])
+	line := string(*rl.line)
+
+	var cursors []int
+
+	for offset := 0; offset < len(line); {
+		index := strings.Index(line[offset:], target)
+		if index == -1 {
+			break
+		}
+
+		pos := len([]rune(line[:offset+index]))
+		cursors = append(cursors, pos)
+		offset += index + len(target)
+	}
+
+	rl.cursor.Set(bpos)
+	rl.cursor.SetCursors(cursors...)
+}
+
+// Add a cursor at the current position, at which editing commands
+// will also be applied once the main cursor is moved elsewhere.
+func (rl *Shell) addCursor() {
+	rl.cursor.AddCursor(rl.cursor.Pos())
+}
+
+// Remove all additional cursors, leaving only the main one.
+func (rl *Shell) removeCursors() {
+	rl.cursor.ResetCursors()
+}
+
+// runAtCursors runs an editing command at the main cursor and at all additional ones.
+// All changes made by the command at all cursors are a single undo step.
+func (rl *Shell) runAtCursors(command func()) {
+	rl.History.Save()
+
+	rl.cursor.Apply(func() {
+		rl.History.SkipSave()
+		command()
+	})
+}
@@ -0,0 +1,131 @@
+package readline
+
+import (
+	"strings"
+	"unicode"
+)
+
+// dabbrev is the state of successive dabbrev-expand calls.
+type dabbrev struct {
+	start   int      // Position of the expanded word in the line.
+	prefix  string   // The word typed before the first expansion.
+	words   []string // Expansions found, nearest first.
+	current int      // Index of the inserted expansion, or -1 for the prefix.
+}
+
+// Expand the word before the cursor to the nearest word starting with it, found
+// in the input buffer (first before the cursor, then after it) or in the history
+// (from the most recent entry), without using the completer. Successive calls
+// cycle through the other words found, and the bell is rung once all have been
+// inserted, restoring the original word before starting over.
+func (rl *Shell) dabbrevExpand() {
+	if rl.History.Last().Action != "dabbrev-expand" || !rl.dabbrevInserted() {
+		start := rl.cursor.Pos()
+		for start > 0 && isDabbrevChar((*rl.line)[start-1]) {
+			start--
+		}
+
+		prefix := string((*rl.line)[start:rl.cursor.Pos()])
+		if prefix == "" {
+			rl.bell.Ring()
+			return
+		}
+
+		rl.dabbrev = dabbrev{
+			start:   start,
+			prefix:  prefix,
+			words:   rl.dabbrevWords(prefix, start),
+			current: -1,
+		}
+	}
+
+	rl.dabbrev.current++
+
+	word := rl.dabbrev.prefix
+	if rl.dabbrev.current < len(rl.dabbrev.words) {
+		word = rl.dabbrev.words[rl.dabbrev.current]
+	} else {
+		rl.dabbrev.current = -1
+		rl.bell.Ring()
+	}
+
+	rl.line.Cut(rl.dabbrev.start, rl.cursor.Pos())
+	rl.cursor.Set(rl.dabbrev.start)
+	rl.cursor.InsertAt([]rune(word)...)
+}
+
+// dabbrevInserted returns true if the word last inserted
+// by dabbrev-expand is still right behind the cursor.
+func (rl *Shell) dabbrevInserted() bool {
+	word := rl.dabbrev.prefix
+	if rl.dabbrev.current >= 0 && rl.dabbrev.current < len(rl.dabbrev.words) {
+		word = rl.dabbrev.words[rl.dabbrev.current]
+	}
+
+	start, end := rl.dabbrev.start, rl.cursor.Pos()
+
+	return start >= 0 && end <= rl.line.Len() && start <= end && string((*rl.line)[start:end]) == word
+}
+
+// dabbrevWords returns the distinct words starting with (and longer than) prefix,
+// in the line (excluding the one being expanded at start) from the nearest one
+// before the cursor, then after it, and in the history from the last entry.
+func (rl *Shell) dabbrevWords(prefix string, start int) []string {
+	var words []string
+
+	seen := map[string]bool{prefix: true}
+
+	add := func(word string) {
+		if strings.HasPrefix(word, prefix) && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+
+	before, after := dabbrevSplit(string((*rl.line)[:start])), dabbrevSplit(string((*rl.line)[start:]))
+
+	for i := len(before) - 1; i >= 0; i-- {
+		add(before[i])
+	}
+
+	// The first word after start is the one being expanded.
+	if len(after) > 0 {
+		after = after[1:]
+	}
+
+	for _, word := range after {
+		add(word)
+	}
+
+	history := rl.History.Current()
+	if history == nil {
+		return words
+	}
+
+	for i := history.Len() - 1; i >= 0; i-- {
+		line, err := history.GetLine(i)
+		if err != nil {
+			continue
+		}
+
+		fields := dabbrevSplit(line)
+		for j := len(fields) - 1; j >= 0; j-- {
+			add(fields[j])
+		}
+	}
+
+	return words
+}
+
+// dabbrevSplit splits text into words made of dabbrev characters.
+func dabbrevSplit(text string) []string {
+	return strings.FieldsFunc(text, func(char rune) bool {
+		return !isDabbrevChar(char)
+	})
+}
+
+// isDabbrevChar returns true if the character is part of words expanded by
+// dabbrev-expand: letters, digits, and characters used in paths or options.
+func isDabbrevChar(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || strings.ContainsRune("_-./", char)
+}
@@ -1,17 +1,20 @@
 package readline
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"sort"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/rivo/uniseg"
 
 	"github.com/reeflective/readline/inputrc"
-	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/editor"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
@@ -37,6 +40,8 @@ func (rl *Shell) standardCommands() commands {
 		// Moving
 		"forward-char":         rl.forwardChar,
 		"backward-char":        rl.backwardChar,
+		"forward-byte":         rl.forwardChar,
+		"backward-byte":        rl.backwardChar,
 		"forward-word":         rl.forwardWord,
 		"backward-word":        rl.backwardWord,
 		"shell-forward-word":   rl.forwardShellWord,
@@ -47,7 +52,9 @@ func (rl *Shell) standardCommands() commands {
 		"next-screen-line":     rl.downLine,
 		"clear-screen":         rl.clearScreen,
 		"clear-display":        rl.clearDisplay,
+		"terminal-suspend":     rl.terminalSuspend,
 		"redraw-current-line":  rl.Display.Refresh,
+		"toggle-line-numbers":  rl.toggleLineNumbers,
 
 		// Changing text
 		"end-of-file":                  rl.endOfFile,
@@ -63,38 +70,50 @@ func (rl *Shell) standardCommands() commands {
 		"shell-transpose-words":        rl.shellTransposeWords,
 		"down-case-word":               rl.downCaseWord,
 		"up-case-word":                 rl.upCaseWord,
+		"downcase-word":                rl.downCaseWord,
+		"upcase-word":                  rl.upCaseWord,
 		"capitalize-word":              rl.capitalizeWord,
 		"overwrite-mode":               rl.overwriteMode,
 		"delete-horizontal-whitespace": rl.deleteHorizontalWhitespace,
-
-		"delete-word":      rl.deleteWord,
-		"quote-region":     rl.quoteRegion,
-		"quote-line":       rl.quoteLine,
-		"keyword-increase": rl.keywordIncrease,
-		"keyword-decrease": rl.keywordDecrease,
+		"delete-horizontal-space":      rl.deleteHorizontalWhitespace,
+
+		"delete-word":            rl.deleteWord,
+		"quote-region":           rl.quoteRegion,
+		"quote-line":             rl.quoteLine,
+		"keyword-increase":       rl.keywordIncrease,
+		"keyword-decrease":       rl.keywordDecrease,
+		"insert-digraph":         rl.insertDigraph,
+		"insert-unicode":         rl.insertUnicode,
+		"select-all-occurrences": rl.selectAllOccurrences,
+		"add-cursor":             rl.addCursor,
+		"remove-cursors":         rl.removeCursors,
 
 		// Killing & yanking
-		"kill-line":           rl.killLine,
-		"backward-kill-line":  rl.backwardKillLine,
-		"unix-line-discard":   rl.backwardKillLine,
-		"kill-whole-line":     rl.killWholeLine,
-		"kill-word":           rl.killWord,
-		"backward-kill-word":  rl.backwardKillWord,
-		"unix-word-rubout":    rl.backwardKillWord,
-		"kill-region":         rl.killRegion,
-		"copy-region-as-kill": rl.copyRegionAsKill,
-		"copy-backward-word":  rl.copyBackwardWord,
-		"copy-forward-word":   rl.copyForwardWord,
-		"yank":                rl.yank,
-		"yank-pop":            rl.yankPop,
+		"kill-line":            rl.killLine,
+		"backward-kill-line":   rl.backwardKillLine,
+		"unix-line-discard":    rl.backwardKillLine,
+		"kill-whole-line":      rl.killWholeLine,
+		"kill-word":            rl.killWord,
+		"backward-kill-word":   rl.backwardKillWord,
+		"unix-word-rubout":     rl.unixWordRubout,
+		"unix-filename-rubout": rl.unixFilenameRubout,
+		"kill-region":          rl.killRegion,
+		"copy-region-as-kill":  rl.copyRegionAsKill,
+		"copy-backward-word":   rl.copyBackwardWord,
+		"copy-forward-word":    rl.copyForwardWord,
+		"yank":                 rl.yank,
+		"yank-pop":             rl.yankPop,
+		"list-kill-ring":       rl.listKillRing,
 
 		"kill-buffer":              rl.killBuffer,
 		"shell-kill-word":          rl.shellKillWord,
 		"shell-backward-kill-word": rl.shellBackwardKillWord,
+		"copy-prev-word":           rl.copyPrevWord,
 		"copy-prev-shell-word":     rl.copyPrevShellWord,
 
 		// Numeric arguments
-		"digit-argument": rl.digitArgument,
+		"digit-argument":     rl.digitArgument,
+		"universal-argument": rl.universalArgument,
 
 		// Macros
 		"start-kbd-macro":      rl.startKeyboardMacro,
@@ -104,6 +123,8 @@ func (rl *Shell) standardCommands() commands {
 
 		"macro-toggle-record": rl.macroToggleRecord,
 		"macro-run":           rl.macroRun,
+		"tour-next-step":      rl.tourNextStep,
+		"tour-stop":           rl.tourStop,
 
 		// Miscellaneous
 		"re-read-init-file":         rl.reReadInitFile,
@@ -121,12 +142,17 @@ func (rl *Shell) standardCommands() commands {
 		"dump-variables":            rl.dumpVariables,
 		"dump-macros":               rl.dumpMacros,
 		"magic-space":               rl.magicSpace,
+		"tilde-expand":              rl.tildeExpand,
+		"replace-in-line":           rl.replaceInLine,
 		"edit-and-execute-command":  rl.editAndExecuteCommand,
 		"edit-command-line":         rl.editCommandLine,
 
-		"redo":                rl.redo,
-		"select-keyword-next": rl.selectKeywordNext,
-		"select-keyword-prev": rl.selectKeywordPrev,
+		"redo":                 rl.redo,
+		"undo-all":             rl.undoAll,
+		"select-keyword-next":  rl.selectKeywordNext,
+		"select-keyword-prev":  rl.selectKeywordPrev,
+		"low-bandwidth-toggle": rl.lowBandwidthToggle,
+		"mouse-event":          rl.mouseEvent,
 	}
 
 	return widgets
@@ -163,7 +189,7 @@ func (rl *Shell) forwardChar() {
 	startPos := rl.cursor.Pos()
 
 	// Only exception where we actually don't forward a character.
-	if rl.Config.GetBool("history-autosuggest") && rl.cursor.Pos() >= rl.line.Len()-1 {
+	if rl.motionAccepts() && rl.cursor.Pos() >= rl.line.Len()-1 {
 		rl.autosuggestAccept()
 	}
 
@@ -190,31 +216,31 @@ func (rl *Shell) backwardChar() {
 	}
 }
 
-// Move to the beginning of the next word. The editor’s idea
-// of a word is any sequence of alphanumeric characters.
+// Move forward to the end of the next word. The editor’s idea
+// of a word is given by the word-style option.
 func (rl *Shell) forwardWord() {
 	rl.History.SkipSave()
 	vii := rl.Iterations.Get()
+	style := rl.wordStyle()
 
 	for i := 1; i <= vii; i++ {
 		// When we have an autosuggested history and if we are at the end
 		// of the line, insert the next word from this suggested line.
 		rl.insertAutosuggestPartial(true)
 
-		forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
-		rl.cursor.Move(forward + 1)
+		rl.cursor.Set(rl.line.WordEnd(style, rl.cursor.Pos()))
 	}
 }
 
-// Move to the beginning of the current or previousword. The editor’s
-// idea of a word is any sequence of alphanumeric characters.
+// Move to the beginning of the current or previous word. The
+// editor’s idea of a word is given by the word-style option.
 func (rl *Shell) backwardWord() {
 	rl.History.SkipSave()
-
 	vii := rl.Iterations.Get()
+	style := rl.wordStyle()
+
 	for i := 1; i <= vii; i++ {
-		backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-		rl.cursor.Move(backward)
+		rl.cursor.Set(rl.line.WordStart(style, rl.cursor.Pos()))
 	}
 }
 
@@ -265,11 +291,16 @@ func (rl *Shell) beginningOfLine() {
 
 // Move to the end of the line. If already at the end
 // of the line, move to the end of the next line, if any.
+// If a line is auto-suggested, accept it.
 func (rl *Shell) endOfLine() {
 	rl.History.SkipSave()
 	// If in Vim command mode, cursor
 	// will be brought back once later.
 	rl.cursor.EndOfLineAppend()
+
+	if rl.motionAccepts() {
+		rl.autosuggestAccept()
+	}
 }
 
 // Move up one line if the current buffer has more than one line.
@@ -289,8 +320,8 @@ func (rl *Shell) downLine() {
 func (rl *Shell) clearScreen() {
 	rl.History.SkipSave()
 
-	fmt.Print(term.CursorTopLeft)
-	fmt.Print(term.ClearScreen)
+	fmt.Fprint(rl.term, term.CursorTopLeft)
+	fmt.Fprint(rl.term, term.ClearScreen)
 
 	rl.Display.PrintPrimaryPrompt()
 }
@@ -300,12 +331,55 @@ func (rl *Shell) clearScreen() {
 func (rl *Shell) clearDisplay() {
 	rl.History.SkipSave()
 
-	fmt.Print(term.CursorTopLeft)
-	fmt.Print(term.ClearDisplay)
+	fmt.Fprint(rl.term, term.CursorTopLeft)
+	fmt.Fprint(rl.term, term.ClearDisplay)
 
 	rl.Display.PrintPrimaryPrompt()
 }
 
+// Toggle the display of line numbers before the lines of multiline buffers.
+// With a numeric argument, toggle relative line numbers instead: they are
+// then displayed, and numbered relatively to the cursor line.
+func (rl *Shell) toggleLineNumbers() {
+	rl.History.SkipSave()
+
+	if !rl.Iterations.IsSet() {
+		rl.Config.Set("line-numbers", !rl.Config.GetBool("line-numbers"))
+		return
+	}
+
+	rl.Iterations.Reset()
+	rl.Config.Set("line-numbers", true)
+	rl.Config.Set("relative-line-numbers", !rl.Config.GetBool("relative-line-numbers"))
+}
+
+// Suspend the shell process, as Ctrl-Z does for other programs. The input line
+// is left as is, and once the process is continued, the prompt and the input
+// line are redisplayed below it, with the cursor where it was.
+func (rl *Shell) terminalSuspend() {
+	rl.History.SkipSave()
+
+	rl.Display.AcceptLine()
+	rl.Keymap.RestoreCursor()
+	rl.leaveTerminal()
+	rl.Hooks.suspended(false)
+
+	suspendErr := term.Suspend()
+
+	rl.Hooks.suspended(true)
+	err := rl.enterTerminal()
+
+	rl.Keymap.PrintCursor(rl.Keymap.Main())
+	rl.Display.PrintPrimaryPrompt()
+
+	switch {
+	case err != nil:
+		rl.Hint.SetTemporary(rl.styles.Error + err.Error())
+	case suspendErr != nil:
+		rl.Hint.SetTemporary(rl.styles.Error + suspendErr.Error())
+	}
+}
+
 //
 // Changing Text --------------------------------------------------------
 //
@@ -318,8 +392,7 @@ func (rl *Shell) clearDisplay() {
 func (rl *Shell) endOfFile() {
 	switch rl.line.Len() {
 	case 0:
-		rl.Display.AcceptLine()
-		rl.History.Accept(false, false, io.EOF)
+		rl.terminate(rl.eof, ErrEOF)
 	default:
 		rl.deleteChar()
 	}
@@ -333,7 +406,7 @@ func (rl *Shell) deleteChar() {
 
 	// Delete the chars in the line anyway
 	for i := 1; i <= vii; i++ {
-		rl.line.CutRune(rl.cursor.Pos())
+		rl.line.CutGrapheme(rl.cursor.Pos())
 	}
 }
 
@@ -360,21 +433,38 @@ func (rl *Shell) backwardDeleteChar() {
 
 	vii := rl.Iterations.Get()
 
+	// In overwrite mode, deleted characters are replaced with spaces,
+	// unless they were at the end of the line.
+	if rl.Keymap.Overwrite() {
+		atEnd := rl.cursor.Pos() == rl.line.Len()
+
+		for i := 1; i <= vii && rl.cursor.Pos() > 0; i++ {
+			rl.cursor.Dec()
+			rl.line.CutGrapheme(rl.cursor.Pos())
+
+			if !atEnd {
+				rl.line.Insert(rl.cursor.Pos(), ' ')
+			}
+		}
+
+		return
+	}
+
 	switch vii {
 	case 1:
 		// Handle removal of autopairs characters.
-		if rl.Config.GetBool("autopairs") {
-			completion.AutopairDelete(rl.line, rl.cursor)
+		if pairs := rl.autopairs(); pairs != "" {
+			completion.AutopairDelete(pairs, rl.line, rl.cursor)
 		}
 
 		// And then delete the character under cursor.
 		rl.cursor.Dec()
-		rl.line.CutRune(rl.cursor.Pos())
+		rl.line.CutGrapheme(rl.cursor.Pos())
 
 	default:
 		for i := 1; i <= vii; i++ {
 			rl.cursor.Dec()
-			rl.line.CutRune(rl.cursor.Pos())
+			rl.line.CutGrapheme(rl.cursor.Pos())
 		}
 	}
 }
@@ -400,12 +490,101 @@ func (rl *Shell) quotedInsert() {
 	defer done()
 
 	key, _ := rl.Keys.ReadKey()
+	if core.Closed(rl.Keys) || core.Cancelled(rl.Keys) {
+		return
+	}
 
 	quoted, _ := strutil.Quote(key)
 
 	rl.cursor.InsertAt(quoted...)
 }
 
+// Read two characters, and insert the character for which they are
+// an RFC 1345 digraph (eg. `e'` for é, `a*` for α, `Eu` for €), or
+// the second character if they are not. Escape cancels the insertion.
+func (rl *Shell) insertDigraph() {
+	rl.History.SkipSave()
+	rl.completer.TrimSuffix()
+
+	done := rl.Keymap.PendingCursor()
+	defer done()
+	defer rl.Hint.Reset()
+
+	// Preview the pending digraph while reading its characters.
+	rl.Hint.Set(rl.styles.Hint + "digraph: ")
+	rl.Display.Refresh()
+
+	first, isAbort := rl.Keys.ReadKey()
+	if isAbort {
+		return
+	}
+
+	rl.Hint.Set(rl.styles.Hint + "digraph: " + string(first))
+	rl.Display.Refresh()
+
+	second, isAbort := rl.Keys.ReadKey()
+	if isAbort {
+		return
+	}
+
+	char, found := strutil.Digraph(first, second)
+	if !found {
+		char = second
+	}
+
+	rl.cursor.InsertAt(char)
+}
+
+// Read a unicode code point in hexadecimal (up to 6 digits, ended
+// by any other key), and insert the corresponding character.
+// Escape cancels the insertion.
+func (rl *Shell) insertUnicode() {
+	rl.History.SkipSave()
+	rl.completer.TrimSuffix()
+
+	done := rl.Keymap.PendingCursor()
+	defer done()
+	defer rl.Hint.Reset()
+
+	var digits []rune
+
+	for len(digits) < 6 {
+		// Preview the code point and its character, if valid.
+		preview := rl.styles.Hint + "unicode: U+" + string(digits)
+		if char, valid := codePoint(digits); valid && unicode.IsPrint(char) {
+			preview += " " + string(char)
+		}
+
+		rl.Hint.Set(preview)
+		rl.Display.Refresh()
+
+		key, isAbort := rl.Keys.ReadKey()
+		if isAbort {
+			return
+		}
+
+		if !unicode.Is(unicode.ASCII_Hex_Digit, key) {
+			break
+		}
+
+		digits = append(digits, key)
+	}
+
+	if char, valid := codePoint(digits); valid {
+		rl.cursor.InsertAt(char)
+	}
+}
+
+// codePoint returns the character of a hexadecimal code point.
+func codePoint(digits []rune) (char rune, valid bool) {
+	point, err := strconv.ParseUint(string(digits), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return rune(point), utf8.ValidRune(rune(point))
+}
+
 // Insert a tab character.
 func (rl *Shell) tabInsert() {
 	rl.History.SkipSave()
@@ -417,17 +596,23 @@ func (rl *Shell) tabInsert() {
 func (rl *Shell) selfInsert() {
 	rl.History.SkipSave()
 
+	key := rl.Keys.Caller()
+
+	// Digits typed after universal-argument are part of the numeric argument.
+	if len(key) == 1 && rl.Iterations.Accepts(key[0]) {
+		rl.Iterations.Add(string(key))
+		return
+	}
+
 	// Handle suffix-autoremoval for inserted completions.
 	rl.completer.TrimSuffix()
 
-	key := rl.Keys.Caller()
-
 	// Handle autopair insertion (for the closer only)
 	searching, _, _ := rl.completer.NonIncrementallySearching()
 	isearch := rl.Keymap.Local() == keymap.Isearch
 
-	if !searching && !isearch && rl.Config.GetBool("autopairs") {
-		if jump := completion.AutopairInsertOrJump(key[0], rl.line, rl.cursor); jump {
+	if pairs := rl.autopairs(); !searching && !isearch && pairs != "" {
+		if jump := completion.AutopairInsertOrJump(key[0], pairs, rl.line, rl.cursor); jump {
 			return
 		}
 	}
@@ -442,9 +627,24 @@ func (rl *Shell) selfInsert() {
 		quoted, length = strutil.Quote(key[0])
 	}
 
-	rl.cursor.InsertAt(quoted...)
-	rl.cursor.Move(-1 * len(quoted))
-	rl.cursor.Move(length)
+	// In Emacs mode, a numeric argument inserts the character several times.
+	times := 1
+	if rl.Keymap.IsEmacs() {
+		times = rl.Iterations.Get()
+	}
+
+	overwrite := rl.Keymap.Overwrite()
+
+	for i := 0; i < times; i++ {
+		// In overwrite mode, the character replaces the one under the cursor.
+		if overwrite && rl.cursor.Pos() < rl.line.Len() {
+			rl.line.CutGrapheme(rl.cursor.Pos())
+		}
+
+		rl.cursor.InsertAt(quoted...)
+		rl.cursor.Move(-1 * len(quoted))
+		rl.cursor.Move(length)
+	}
 }
 
 func (rl *Shell) bracketedPasteBegin() {
@@ -481,59 +681,46 @@ func (rl *Shell) transposeChars() {
 // Drag the word before point past the word after point,
 // moving point over that word as well.  If point is at the
 // end of the line, this transposes the last two words on the
-// line. If a numeric argument is given, the word to transpose
-// is chosen backward.
+// line. With a numeric argument, the word before point is
+// dragged past as many words.
 func (rl *Shell) transposeWords() {
 	rl.History.Save()
 
-	startPos := rl.cursor.Pos()
-	rl.cursor.ToFirstNonSpace(true)
-	rl.cursor.CheckCommand()
+	vii := max(rl.Iterations.Get(), 1)
+	style := rl.wordStyle()
 
-	// Save the current word and move the cursor to its beginning
-	rl.viSelectInWord()
-	rl.selection.Visual(false)
-	toTranspose, tbpos, tepos, _ := rl.selection.Pop()
+	// Find the two words.
+	w2end := rl.cursor.Pos()
+	for i := 0; i < vii; i++ {
+		w2end = rl.line.WordEnd(style, w2end)
+	}
 
-	// Then move some number of words.
-	// Either use words backward (if we are at end of line) or forward.
-	rl.cursor.Set(tbpos)
+	w2beg := rl.line.WordStart(style, w2end)
 
-	if tepos >= rl.line.Len()-1 || rl.Iterations.IsSet() {
-		rl.backwardWord()
-	} else {
-		rl.viForwardWord()
+	w1beg := w2beg
+	for i := 0; i < vii; i++ {
+		w1beg = rl.line.WordStart(style, w1beg)
 	}
 
-	// Save the word to transpose with
-	rl.viSelectInWord()
-	rl.selection.Visual(false)
-	transposeWith, wbpos, wepos, _ := rl.selection.Pop()
+	w1end := rl.line.WordEnd(style, w1beg)
 
-	// We might be on the first word of the line,
-	// in which case we don't do anything.
-	if tbpos == 0 {
-		rl.cursor.Set(startPos)
+	// There might not be two words to transpose.
+	if w1beg == w2beg || w2beg < w1end {
+		rl.bell.Ring()
 		return
 	}
 
-	// If we went forward rather than backward, swap everything.
-	if wbpos > tbpos {
-		wbpos, tbpos = tbpos, wbpos
-		wepos, tepos = tepos, wepos
-		transposeWith, toTranspose = toTranspose, transposeWith
-	}
+	line := *rl.line
 
-	// Assemble the newline
-	begin := string((*rl.line)[:wbpos])
-	newLine := append([]rune(begin), []rune(toTranspose)...)
-	newLine = append(newLine, (*rl.line)[wepos:tbpos]...)
-	newLine = append(newLine, []rune(transposeWith)...)
-	newLine = append(newLine, (*rl.line)[tepos:]...)
-	rl.line.Set(newLine...)
+	transposed := make([]rune, 0, line.Len())
+	transposed = append(transposed, line[:w1beg]...)
+	transposed = append(transposed, line[w2beg:w2end]...)
+	transposed = append(transposed, line[w1end:w2beg]...)
+	transposed = append(transposed, line[w1beg:w1end]...)
+	transposed = append(transposed, line[w2end:]...)
 
-	// And replace the cursor
-	rl.cursor.Set(tepos)
+	rl.line.Set(transposed...)
+	rl.cursor.Set(w2end)
 }
 
 // Drag the shell word before point past the shell word after point,
@@ -579,143 +766,61 @@ func (rl *Shell) shellTransposeWords() {
 // Lowercase the current (or following) word. With a negative argument,
 // lowercase the previous word, but do not move point.
 func (rl *Shell) downCaseWord() {
-	rl.History.Save()
-
-	startPos := rl.cursor.Pos()
-
-	// Save the current word
-	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(backward)
-
-	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(forward)
-
-	rl.selection.ReplaceWith(unicode.ToLower)
-	rl.cursor.Set(startPos)
+	rl.changeWordsCase(func(_ bool, char rune) rune { return unicode.ToLower(char) })
 }
 
 // Uppercase the current (or following) word.  With a negative argument,
 // uppercase the previous word, but do not move point.
 func (rl *Shell) upCaseWord() {
-	rl.History.Save()
-
-	startPos := rl.cursor.Pos()
-
-	// Save the current word
-	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(backward)
-
-	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(forward)
-
-	rl.selection.ReplaceWith(unicode.ToUpper)
-	rl.cursor.Set(startPos)
+	rl.changeWordsCase(func(_ bool, char rune) rune { return unicode.ToUpper(char) })
 }
 
 // Capitalize the current (or following) word.  With a negative argument,
 // capitalize the previous word, but do not move point.
 func (rl *Shell) capitalizeWord() {
-	if rl.line.Len() == 0 {
-		return
-	}
-
-	rl.History.Save()
-
-	startPos := rl.cursor.Pos()
-
-	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(backward)
+	rl.changeWordsCase(func(first bool, char rune) rune {
+		if first {
+			return unicode.ToUpper(char)
+		}
 
-	letter := rl.cursor.Char()
-	rl.cursor.ReplaceWith(unicode.ToUpper(letter))
-	rl.cursor.Set(startPos)
+		return unicode.ToLower(char)
+	})
 }
 
-// Toggle overwrite mode. In overwrite mode, characters bound to
-// self-insert replace the text at point rather than pushing the
-// text to the right.  Characters bound to backward-delete-char
-// replace the character before point with a space.
+// Toggle overwrite mode. With an explicit positive numeric argument, switches
+// to overwrite mode. With an explicit non-positive numeric argument, switches to
+// insert mode. In overwrite mode, characters bound to self-insert replace the
+// text at point rather than pushing the text to the right. Characters bound to
+// backward-delete-char replace the character before point with a space.
+// This command affects only emacs mode, and each line starts in insert mode.
 func (rl *Shell) overwriteMode() {
-	// We store the current line as an undo item first, but will not
-	// store any intermediate changes (in the loop below) as undo items.
+	// Characters replaced from now on are undone separately
+	// from those that might have been inserted before.
 	rl.History.Save()
 
-	done := rl.Keymap.PendingCursor()
-	defer done()
-
-	// All replaced characters are stored, to be used with backspace
-	cache := make([]rune, 0)
-
-	// Don't use the delete cache past the end of the line
-	lineStart := rl.line.Len()
-
-	// The replace mode is quite special in that it does escape back
-	// to the main readline loop: it keeps reading characters and inserts
-	// them as long as the escape key is not pressed.
-	for {
-		// We read a character to use first.
-		key, isAbort := rl.Keys.ReadKey()
-		if isAbort {
-			break
-		}
-
-		// If the key is a backspace, we go back one character
-		if string(key) == inputrc.Unescape(string(`\C-?`)) {
-			if rl.cursor.Pos() > lineStart {
-				rl.backwardDeleteChar()
-			} else if rl.cursor.Pos() > 0 {
-				rl.cursor.Dec()
-			}
-
-			// And recover the last replaced character
-			if len(cache) > 0 && rl.cursor.Pos() < lineStart {
-				key = cache[len(cache)-1]
-				cache = cache[:len(cache)-1]
-
-				rl.cursor.ReplaceWith(key)
-			}
-		} else {
-			// If the cursor is at the end of the line,
-			// we insert the character instead of replacing.
-			if rl.line.Len() == rl.cursor.Pos() {
-				rl.cursor.InsertAt(key)
-			} else {
-				cache = append(cache, rl.cursor.Char())
-				rl.cursor.ReplaceWith(key)
-				rl.cursor.Inc()
-			}
-		}
-
-		// Update the line
-		rl.Display.Refresh()
+	overwrite := !rl.Keymap.Overwrite()
+	if rl.Iterations.IsSet() {
+		overwrite = rl.Iterations.Get() > 0
 	}
+
+	rl.Keymap.SetOverwrite(overwrite)
 }
 
 // Delete all spaces and tabs around point.
 func (rl *Shell) deleteHorizontalWhitespace() {
 	rl.History.Save()
 
-	startPos := rl.cursor.Pos()
-
-	rl.cursor.ToFirstNonSpace(false)
+	line := *rl.line
+	bpos, epos := rl.cursor.Pos(), rl.cursor.Pos()
 
-	if rl.cursor.Pos() != startPos {
-		rl.cursor.Inc()
+	for bpos > 0 && (line[bpos-1] == ' ' || line[bpos-1] == '\t') {
+		bpos--
 	}
 
-	bpos := rl.cursor.Pos()
-	rl.cursor.ToFirstNonSpace(true)
-
-	if rl.cursor.Pos() != startPos {
-		rl.cursor.Dec()
+	for epos < len(line) && (line[epos] == ' ' || line[epos] == '\t') {
+		epos++
 	}
 
-	epos := rl.cursor.Pos()
 	rl.line.Cut(bpos, epos)
 	rl.cursor.Set(bpos)
 }
@@ -724,11 +829,8 @@ func (rl *Shell) deleteHorizontalWhitespace() {
 func (rl *Shell) deleteWord() {
 	rl.History.Save()
 
-	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(forward)
-
-	rl.selection.Cut()
+	epos := rl.line.WordEnd(rl.wordStyle(), rl.cursor.Pos())
+	rl.line.Cut(rl.cursor.Pos(), epos)
 }
 
 // Quote the region from the cursor to the mark.
@@ -899,16 +1001,19 @@ func (rl *Shell) killBuffer() {
 	rl.line.Cut(0, rl.line.Len())
 }
 
-// Kill the current word from the cursor point up to the end of it.
+// Kill from point to the end of the current word, or if between
+// words, to the end of the next word. Word boundaries are the
+// same as those used by forward-word.
 func (rl *Shell) killWord() {
 	rl.History.Save()
 
-	bpos := rl.cursor.Pos()
+	vii := rl.Iterations.Get()
+	style := rl.wordStyle()
 
-	rl.cursor.ToFirstNonSpace(true)
-	forward := rl.line.Forward(rl.line.TokenizeSpace, rl.cursor.Pos())
-	rl.cursor.Move(forward - 1)
-	epos := rl.cursor.Pos()
+	bpos, epos := rl.cursor.Pos(), rl.cursor.Pos()
+	for i := 1; i <= vii; i++ {
+		epos = rl.line.WordEnd(style, epos)
+	}
 
 	rl.selection.MarkRange(bpos, epos)
 	rl.Buffers.Write([]rune(rl.selection.Cut())...)
@@ -918,14 +1023,102 @@ func (rl *Shell) killWord() {
 // Kill the word behind point. Word boundaries
 // are the same as those used by backward-word.
 func (rl *Shell) backwardKillWord() {
+	rl.backwardKillWordWith(rl.wordStyle())
+}
+
+// Kill the word behind point, using white space as a word boundary.
+func (rl *Shell) unixWordRubout() {
+	rl.backwardKillWordWith(core.WordStyle{Name: core.WordStyleWhitespace})
+}
+
+// backwardKillWordWith kills from point to the beginning of the current or
+// previous word (or of as many words as the numeric argument).
+func (rl *Shell) backwardKillWordWith(style core.WordStyle) {
 	rl.History.Save()
 	rl.History.SkipSave()
 
-	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(adjust)
+	vii := rl.Iterations.Get()
+
+	bpos := rl.cursor.Pos()
+	for i := 1; i <= vii; i++ {
+		bpos = rl.line.WordStart(style, bpos)
+	}
 
+	rl.selection.MarkRange(bpos, rl.cursor.Pos())
 	rl.Buffers.Write([]rune(rl.selection.Cut())...)
+	rl.cursor.Set(bpos)
+}
+
+// changeWordsCase changes the case of the characters from point to the end of
+// the current word (or of as many words as the numeric argument), and moves
+// point after them. With a negative argument, the previous words are changed,
+// and point is not moved. The change is passed whether the character is the
+// first one of a word.
+func (rl *Shell) changeWordsCase(change func(first bool, char rune) rune) {
+	rl.History.Save()
+
+	vii := rl.Iterations.Get()
+	style := rl.wordStyle()
+	bpos, epos := rl.cursor.Pos(), rl.cursor.Pos()
+
+	for i := 0; i < vii; i++ {
+		epos = rl.line.WordEnd(style, epos)
+	}
+
+	for i := 0; i > vii; i-- {
+		bpos = rl.line.WordStart(style, bpos)
+	}
+
+	line := *rl.line
+
+	for _, word := range rl.line.Words(style) {
+		for pos := max(word[0], bpos); pos < min(word[1], epos); pos++ {
+			line[pos] = change(pos == word[0], line[pos])
+		}
+	}
+
+	if vii > 0 {
+		rl.cursor.Set(epos)
+	}
+}
+
+// autopairs returns the pairs of characters (opening and closing ones)
+// inserted together, or nothing if the autopairs option is off.
+func (rl *Shell) autopairs() string {
+	if !rl.Config.GetBool("autopairs") {
+		return ""
+	}
+
+	return strings.Trim(rl.Config.GetString("autopairs-chars"), "\"")
+}
+
+// wordStyle returns the word style used by the Emacs word commands.
+func (rl *Shell) wordStyle() core.WordStyle {
+	return core.WordStyle{
+		Name:  rl.Config.GetString("word-style"),
+		Chars: strings.Trim(rl.Config.GetString("word-chars"), "\""),
+	}
+}
+
+// Kill the word behind point, using white space and the
+// slash character as the word boundaries.
+func (rl *Shell) unixFilenameRubout() {
+	rl.History.Save()
+
+	line := *rl.line
+	pos := rl.cursor.Pos()
+
+	for pos > 0 && (unicode.IsSpace(line[pos-1]) || line[pos-1] == '/') {
+		pos--
+	}
+
+	for pos > 0 && !unicode.IsSpace(line[pos-1]) && line[pos-1] != '/' {
+		pos--
+	}
+
+	rl.Buffers.Write([]rune(string(line[pos:rl.cursor.Pos()]))...)
+	rl.line.Cut(pos, rl.cursor.Pos())
+	rl.cursor.Set(pos)
 }
 
 // Kill the text between the point and mark (saved cursor
@@ -933,18 +1126,20 @@ func (rl *Shell) backwardKillWord() {
 func (rl *Shell) killRegion() {
 	rl.History.Save()
 
-	if !rl.selection.Active() {
+	if !rl.markRegion() {
 		return
 	}
 
+	cpos := rl.selection.Cursor()
 	rl.Buffers.Write([]rune(rl.selection.Cut())...)
+	rl.cursor.Set(cpos)
 }
 
 // Copy the text in the region to the kill buffer.
 func (rl *Shell) copyRegionAsKill() {
 	rl.History.SkipSave()
 
-	if !rl.selection.Active() {
+	if !rl.markRegion() {
 		return
 	}
 
@@ -952,30 +1147,40 @@ func (rl *Shell) copyRegionAsKill() {
 	rl.selection.Reset()
 }
 
+// markRegion selects the text between the mark and the point, unless another
+// (eg. visual) selection is active. Returns false if there is no mark.
+func (rl *Shell) markRegion() bool {
+	if rl.selection.Active() && !rl.selection.IsRegion() {
+		return true
+	}
+
+	mark := rl.cursor.Mark()
+	if mark < 0 || mark > rl.line.Len() {
+		return false
+	}
+
+	rl.selection.Reset()
+	rl.selection.MarkRange(min(mark, rl.cursor.Pos()), max(mark, rl.cursor.Pos()))
+
+	return true
+}
+
 // Copy the word before point to the kill buffer.
 // The word boundaries are the same as backward-word.
 func (rl *Shell) copyBackwardWord() {
-	rl.History.Save()
-
-	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(adjust)
+	rl.History.SkipSave()
 
-	rl.Buffers.Write([]rune(rl.selection.Text())...)
-	rl.selection.Reset()
+	bpos := rl.line.WordStart(rl.wordStyle(), rl.cursor.Pos())
+	rl.Buffers.Write([]rune(string((*rl.line)[bpos:rl.cursor.Pos()]))...)
 }
 
 // Copy the word following point to the kill buffer.
 // The word boundaries are the same as forward-word.
 func (rl *Shell) copyForwardWord() {
-	rl.History.Save()
-
-	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Forward(rl.line.Tokenize, rl.cursor.Pos())
-	rl.cursor.Move(adjust + 1)
+	rl.History.SkipSave()
 
-	rl.Buffers.Write([]rune(rl.selection.Text())...)
-	rl.selection.Reset()
+	epos := rl.line.WordEnd(rl.wordStyle(), rl.cursor.Pos())
+	rl.Buffers.Write([]rune(string((*rl.line)[rl.cursor.Pos():epos]))...)
 }
 
 // Yank the top of the kill ring into the buffer at point.
@@ -989,15 +1194,42 @@ func (rl *Shell) yank() {
 	}
 }
 
-// Rotate the kill ring, and yank the new top.
-// Only works following yank or yank-pop.
+// Rotate the kill ring, and replace the text just yanked with the new top.
+// Only works following yank or yank-pop, and browses the entire ring
+// (as set with kill-ring-size) without removing any of its entries.
 func (rl *Shell) yankPop() {
+	switch rl.History.Last().Action {
+	case "yank", "yank-pop":
+	default:
+		return
+	}
+
+	// The yanked text must still be right behind the cursor.
+	yanked := rl.Buffers.GetKill()
+	end := rl.cursor.Pos()
+	start := end - len(yanked)
+
+	if len(yanked) == 0 || start < 0 || string((*rl.line)[start:end]) != string(yanked) {
+		return
+	}
+
 	vii := rl.Iterations.Get()
 
+	var buf []rune
 	for i := 1; i <= vii; i++ {
-		buf := rl.Buffers.Pop()
-		rl.cursor.InsertAt(buf...)
+		buf = rl.Buffers.Pop()
 	}
+
+	rl.line.Cut(start, end)
+	rl.cursor.Set(start)
+	rl.cursor.InsertAt(buf...)
+}
+
+// Show the entries of the kill ring in the completion menu,
+// from the most recent one, for selection and insertion.
+func (rl *Shell) listKillRing() {
+	rl.History.SkipSave()
+	rl.startMenuComplete(rl.Buffers.CompleteKillRing)
 }
 
 // Kill the shell word behind point. Word boundaries
@@ -1047,6 +1279,30 @@ func (rl *Shell) shellBackwardKillWord() {
 	rl.selection.Reset()
 }
 
+// Duplicate the word to the left of the cursor,
+// words being delimited by whitespace.
+func (rl *Shell) copyPrevWord() {
+	rl.History.Save()
+
+	line := *rl.line
+	end := rl.cursor.Pos()
+
+	for end > 0 && unicode.IsSpace(line[end-1]) {
+		end--
+	}
+
+	start := end
+
+	for start > 0 && !unicode.IsSpace(line[start-1]) {
+		start--
+	}
+
+	word := []rune(string(line[start:end]))
+
+	rl.line.Insert(rl.cursor.Pos(), word...)
+	rl.cursor.Move(len(word))
+}
+
 // Like copy-prev-word, but the word is found by using shell parsing,
 // whereas copy-prev-word looks for blanks. This makes a difference
 // when the word is quoted and contains spaces.
@@ -1089,6 +1345,16 @@ func (rl *Shell) digitArgument() {
 	rl.Iterations.Add(string(keys))
 }
 
+// Begin a numeric argument, or multiply the current one by four: it is four
+// when executed once, sixteen when executed twice, etc. Digits and a leading
+// minus sign typed afterwards are added to the argument, the first digit
+// replacing the multiplied value. Executing universal-argument again after
+// digits ends the argument, so that digits typed next are inserted.
+func (rl *Shell) universalArgument() {
+	rl.History.SkipSave()
+	rl.Iterations.Universal()
+}
+
 //
 // Macros ----------------------------------------------------------------------
 //
@@ -1136,7 +1402,7 @@ func (rl *Shell) macroToggleRecord() {
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
-	rl.Hint.SetTemporary(color.Dim + "REC (macro arg)")
+	rl.Hint.SetTemporary(rl.styles.Hint + "REC (macro arg)")
 	rl.Display.Refresh()
 
 	key, isAbort := rl.Keys.ReadKey()
@@ -1154,7 +1420,7 @@ func (rl *Shell) macroRun() {
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
-	rl.Hint.SetTemporary(color.Dim + "Run (macro arg)")
+	rl.Hint.SetTemporary(rl.styles.Hint + "Run (macro arg)")
 	rl.Display.Refresh()
 
 	key, isAbort := rl.Keys.ReadKey()
@@ -1165,6 +1431,18 @@ func (rl *Shell) macroRun() {
 	rl.Macros.RunMacro(key)
 }
 
+// If a guided tour is being played, skip to its next step.
+func (rl *Shell) tourNextStep() {
+	rl.History.SkipSave()
+	rl.Macros.NextTourStep()
+}
+
+// If a guided tour is being played, stop it.
+func (rl *Shell) tourStop() {
+	rl.History.SkipSave()
+	rl.Macros.StopTour()
+}
+
 //
 // Miscellaneous ---------------------------------------------------------------
 //
@@ -1172,41 +1450,23 @@ func (rl *Shell) macroRun() {
 // Read in the contents of the inputrc file, and incorporate
 // any bindings or variable assignments found there.
 func (rl *Shell) reReadInitFile() {
-	main := rl.Keymap.Main()
-
-	err := rl.Keymap.ReloadConfig(rl.Opts...)
-	if err != nil {
-		rl.Hint.SetTemporary(color.FgRed + "Inputrc reload error: " + err.Error())
+	if err := rl.ReloadConfig(); err != nil {
+		rl.Hint.SetTemporary(rl.styles.Error + "Inputrc reload error: " + err.Error())
 		return
 	}
 
-	defer rl.Keymap.UpdateCursor()
-
-	// Reload keymap settings and cursor
-	newMain := rl.Keymap.Main()
-
-	if main != newMain {
-		switch newMain {
-		case keymap.Emacs, keymap.EmacsStandard, keymap.EmacsMeta, keymap.EmacsCtrlX:
-			rl.emacsEditingMode()
-		case keymap.Vi, keymap.ViCommand, keymap.ViMove:
-			rl.viCommandMode()
-		case keymap.ViInsert:
-			rl.viInsertMode()
-		}
-	}
-
 	// Notify successfully reloaded
-	rl.Hint.SetTemporary(color.FgGreen + "Inputrc reloaded")
+	rl.Hint.SetTemporary(rl.styles.Success + "Inputrc reloaded")
 }
 
 // Abort the current editing command.
 // If one of the completion or non/incremental-search modes
 // are active, only cancel them and nothing else.
 func (rl *Shell) abort() {
-	// Reset any visual selection and iterations.
+	// Reset any visual selection, iterations and additional cursors.
 	rl.Iterations.Reset()
 	rl.selection.Reset()
+	rl.cursor.ResetCursors()
 
 	// Cancel active completion insertion and/or incremental search.
 	if rl.completer.AutoCompleting() || rl.completer.IsInserting() {
@@ -1234,13 +1494,12 @@ func (rl *Shell) abort() {
 		key := rl.Keys.Caller()
 		if key[0] == rune(inputrc.Unescape(`\C-C`)[0]) {
 			quoted, _ := strutil.Quote(key[0])
-			fmt.Print(string(quoted))
+			fmt.Fprint(rl.term, string(quoted))
 		}
 	}
 
 	// If no line was active,
-	rl.Display.AcceptLine()
-	rl.History.Accept(false, false, ErrInterrupt)
+	rl.terminate(rl.interrupt, ErrInterrupt)
 }
 
 // If the metafied character x is uppercase, run the command
@@ -1294,65 +1553,55 @@ func (rl *Shell) prefixMeta() {
 	rl.Keys.Feed(false, keys...)
 }
 
-// Incrementally undo the last text modification.
-// Note that when invoked from vi command mode, the full
-// prior change made in insert mode is reverted, the changes
-// having been merged when command mode was selected.
-func (rl *Shell) undoLast() {
-	rl.History.Undo()
-}
-
-// Undo all changes made to this line.
-// This is like executing the undo command enough
-// times to return the line to its initial state.
-func (rl *Shell) revertLine() {
-	rl.History.Revert()
-}
-
 // Set the mark to the point. If a numeric argument is
-// supplied, the mark is set to that position.
+// supplied, the mark is set to that position. The region
+// between the mark and the point is highlighted until the
+// line is edited.
 func (rl *Shell) setMark() {
-	switch {
-	case rl.Iterations.IsSet():
-		rl.cursor.SetMark()
-	default:
-		cpos := rl.cursor.Pos()
-		mark := rl.Iterations.Get()
+	rl.History.SkipSave()
 
-		if mark > rl.line.Len()-1 {
+	cpos := rl.cursor.Pos()
+
+	if rl.Iterations.IsSet() {
+		mark := rl.Iterations.Get()
+		if mark < 0 || mark > rl.line.Len() {
+			rl.bell.Ring()
 			return
 		}
 
 		rl.cursor.Set(mark)
-		rl.cursor.SetMark()
-		rl.cursor.Set(cpos)
 	}
+
+	rl.cursor.SetMark()
+	rl.cursor.Set(cpos)
+
+	rl.selection.MarkRegion(rl.cursor.Mark())
 }
 
 // Swap the point with the mark.  The current cursor position
 // is set to the saved position, and the old cursor position
-// is saved as the mark.
+// is saved as the mark. The region is highlighted again.
 func (rl *Shell) exchangePointAndMark() {
-	// Deactivate mark if out of bound
-	if rl.cursor.Mark() > rl.line.Len() {
-		rl.cursor.ResetMark()
-	}
+	rl.History.SkipSave()
+
+	// Without a mark, it is set at the beginning of the line.
+	if rl.cursor.Mark() < 0 || rl.cursor.Mark() > rl.line.Len() {
+		rl.bell.Ring()
 
-	// And set it to start if negative.
-	if rl.cursor.Mark() < 0 {
 		cpos := rl.cursor.Pos()
 		rl.cursor.Set(0)
 		rl.cursor.SetMark()
 		rl.cursor.Set(cpos)
-	} else {
-		mark := rl.cursor.Mark()
 
-		rl.cursor.SetMark()
-		rl.cursor.Set(mark)
-
-		rl.selection.MarkRange(rl.cursor.Mark(), rl.cursor.Pos())
-		rl.selection.Visual(false)
+		return
 	}
+
+	mark := rl.cursor.Mark()
+
+	rl.cursor.SetMark()
+	rl.cursor.Set(mark)
+
+	rl.selection.MarkRegion(rl.cursor.Mark())
 }
 
 // A character is read and point is moved to the next
@@ -1425,35 +1674,56 @@ func (rl *Shell) insertComment() {
 	rl.acceptLineWith(false, false)
 }
 
-// Print all of the functions and their key bindings to the
-// readline output stream.  If a numeric argument is
-// supplied, the output is formatted in such a way that it
-// can be made part of an inputrc file.
+// List all of the functions and their key bindings in the completion menu.
+// If a numeric argument is supplied, the bindings are listed in such a way
+// that they can be made part of an inputrc file. Selected entries are
+// inserted in the line.
 func (rl *Shell) dumpFunctions() {
-	rl.Display.ClearHelpers()
-	fmt.Println()
-
-	defer func() {
-		rl.Prompt.PrimaryPrint()
-		rl.Display.Refresh()
-	}()
-
 	inputrcFormat := rl.Iterations.IsSet()
-	rl.Keymap.PrintBinds(string(rl.Keymap.Main()), inputrcFormat)
+	keymaps := []string{string(rl.Keymap.Main())}
+
+	// In Vim mode, also list the operator-pending and visual keymaps.
+	if !rl.Keymap.IsEmacs() {
+		keymaps = append(keymaps, string(keymap.ViOpp), string(keymap.Visual))
+	}
+
+	var vals []completion.Candidate
+
+	for _, name := range keymaps {
+		commands, binds := rl.Keymap.CommandBinds(name)
+
+		for _, command := range commands {
+			commandBinds := binds[command]
+
+			switch {
+			case len(commandBinds) == 0:
+			case inputrcFormat:
+				for _, bind := range commandBinds {
+					vals = append(vals, completion.Candidate{
+						Tag:   name,
+						Value: fmt.Sprintf("\"%s\": %s", bind, command),
+					})
+				}
+			default:
+				vals = append(vals, completion.Candidate{
+					Tag:     name,
+					Value:   command,
+					Display: command + " can be found on " + quoteBinds(commandBinds),
+				})
+			}
+		}
+	}
+
+	rl.startMenuComplete(func() completion.Values {
+		return rl.dumpCompletions("functions", vals)
+	})
 }
 
-// Print all of the settable variables and their values to
-// the readline output stream.  If a numeric argument is
-// supplied, the output is formatted in such a way that it
-// can be made part of an inputrc file.
+// List all of the settable variables and their values in the completion
+// menu. If a numeric argument is supplied, the variables are listed in such
+// a way that they can be made part of an inputrc file.
 func (rl *Shell) dumpVariables() {
-	rl.Display.ClearHelpers()
-	fmt.Println()
-
-	defer func() {
-		rl.Prompt.PrimaryPrint()
-		rl.Display.Refresh()
-	}()
+	inputrcFormat := rl.Iterations.IsSet()
 
 	// Get all variables and their values, alphabetically sorted.
 	var variables []string
@@ -1464,76 +1734,65 @@ func (rl *Shell) dumpVariables() {
 
 	sort.Strings(variables)
 
-	// Either print in inputrc format, or wordly one.
-	if rl.Iterations.IsSet() {
-		for _, variable := range variables {
-			value := rl.Config.Vars[variable]
-			fmt.Printf("set %s %v\n", variable, value)
-		}
-	} else {
-		for _, variable := range variables {
-			value := rl.Config.Vars[variable]
-			fmt.Printf("%s is set to `%v'\n", variable, value)
+	vals := make([]completion.Candidate, 0, len(variables))
+
+	for _, variable := range variables {
+		value := rl.Config.Vars[variable]
+
+		if inputrcFormat {
+			vals = append(vals, completion.Candidate{Value: fmt.Sprintf("set %s %v", variable, value)})
+		} else {
+			vals = append(vals, completion.Candidate{Value: variable, Display: fmt.Sprintf("%s is set to `%v'", variable, value)})
 		}
 	}
+
+	rl.startMenuComplete(func() completion.Values {
+		return rl.dumpCompletions("variables", vals)
+	})
 }
 
-// Print all of the readline key sequences bound to macros
-// and the strings they output.  If a numeric argument is
-// supplied, the output is formatted in such a way that it
-// can be made part of an inputrc file.
+// List all of the readline key sequences bound to macros and the strings
+// they output in the completion menu. If a numeric argument is supplied,
+// the macros are listed in such a way that they can be made part of an
+// inputrc file.
 func (rl *Shell) dumpMacros() {
-	rl.Display.ClearHelpers()
-	fmt.Println()
-
-	defer func() {
-		rl.Prompt.PrimaryPrint()
-		rl.Display.Refresh()
-	}()
+	inputrcFormat := rl.Iterations.IsSet()
 
-	// We print the macros bound to the current keymap only.
+	// We list the macros bound to the current keymap only.
 	binds := rl.Config.Binds[string(rl.Keymap.Main())]
-	if len(binds) == 0 {
-		return
-	}
 
 	var macroBinds []string
 
 	for keys, bind := range binds {
 		if bind.Macro {
-			macroBinds = append(macroBinds, inputrc.Escape(keys))
+			macroBinds = append(macroBinds, keys)
 		}
 	}
 
 	sort.Strings(macroBinds)
 
-	if rl.Iterations.IsSet() {
-		for _, key := range macroBinds {
-			action := inputrc.Escape(binds[inputrc.Unescape(key)].Action)
-			fmt.Printf("\"%s\": \"%s\"\n", key, action)
-		}
-	} else {
-		for _, key := range macroBinds {
-			action := inputrc.Escape(binds[inputrc.Unescape(key)].Action)
-			fmt.Printf("%s outputs %s\n", key, action)
+	vals := make([]completion.Candidate, 0, len(macroBinds))
+
+	for _, keys := range macroBinds {
+		key, action := inputrc.Escape(keys), inputrc.EscapeMacro(binds[keys].Action)
+
+		if inputrcFormat {
+			vals = append(vals, completion.Candidate{Value: fmt.Sprintf("\"%s\": \"%s\"", key, action)})
+		} else {
+			vals = append(vals, completion.Candidate{Value: key, Display: key + " outputs " + action})
 		}
 	}
+
+	rl.startMenuComplete(func() completion.Values {
+		return rl.dumpCompletions("macros", vals)
+	})
 }
 
 // Invoke an editor on the current command line, and execute the result as shell commands.
 // Readline attempts to invoke $VISUAL, $EDITOR, and emacs as the editor, in that order.
 func (rl *Shell) editAndExecuteCommand() {
-	buffer := *rl.line
-
-	// Edit in editor
-	edited, err := rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
-	if err != nil || (len(edited) == 0 && len(buffer) != 0) {
-		rl.History.SkipSave()
-
-		errStr := strings.ReplaceAll(err.Error(), "\n", "")
-		changeHint := fmt.Sprintf(color.FgRed+"Editor error: %s", errStr)
-		rl.Hint.SetTemporary(changeHint)
-
+	edited, ok := rl.editBuffer()
+	if !ok {
 		return
 	}
 
@@ -1543,24 +1802,20 @@ func (rl *Shell) editAndExecuteCommand() {
 	rl.History.Accept(false, false, nil)
 }
 
+// Invoke an editor on the current command line, opened at the cursor position.
+// The line is left unchanged if the editor exits with a non-zero status.
 func (rl *Shell) editCommandLine() {
-	buffer := *rl.line
 	keymapCur := rl.Keymap.Main()
 
-	// Edit in editor
-	edited, err := rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
-	if err != nil || (len(edited) == 0 && len(buffer) != 0) {
-		rl.History.SkipSave()
-
-		errStr := strings.ReplaceAll(err.Error(), "\n", "")
-		changeHint := fmt.Sprintf(color.FgRed+"Editor error: %s", errStr)
-		rl.Hint.SetTemporary(changeHint)
-
+	edited, ok := rl.editBuffer()
+	if !ok {
 		return
 	}
 
 	// Update our line
+	rl.History.Save()
 	rl.line.Set(edited...)
+	rl.cursor.Set(rl.line.Len())
 
 	// We're done with visual mode when we were in.
 	switch keymapCur {
@@ -1569,9 +1824,50 @@ func (rl *Shell) editCommandLine() {
 	}
 }
 
-// Incrementally redo undone text modifications.
-func (rl *Shell) redo() {
-	rl.History.Redo()
+var errEmptyEdit = errors.New("the edited buffer is empty")
+
+// editBuffer edits the line in the system editor, with the file extension and
+// command template of the editor-file-extension and editor-command options.
+// Returns false if the edit failed (showing the error in the hint area), or
+// was cancelled by the editor exiting with a non-zero status.
+func (rl *Shell) editBuffer() ([]rune, bool) {
+	buffer := *rl.line
+	lines := strings.Split(string(buffer[:rl.cursor.Pos()]), "\n")
+	column := len([]rune(lines[len(lines)-1])) + 1
+
+	edit := editor.Edit{
+		Extension: strings.Trim(rl.Config.GetString("editor-file-extension"), "\""),
+		Command:   strings.Trim(rl.Config.GetString("editor-command"), "\""),
+		Line:      len(lines),
+		Column:    column,
+		Emacs:     rl.Keymap.IsEmacs(),
+		Stdin:     rl.Keys.Reader(),
+		Stdout:    rl.out,
+		Stderr:    rl.out,
+	}
+
+	edited, err := rl.Buffers.EditBuffer(buffer, edit)
+
+	switch {
+	case errors.Is(err, editor.ErrCancelled):
+		rl.History.SkipSave()
+		return nil, false
+
+	case err != nil || (len(edited) == 0 && len(buffer) != 0):
+		rl.History.SkipSave()
+
+		if err == nil {
+			err = errEmptyEdit
+		}
+
+		errStr := strings.ReplaceAll(err.Error(), "\n", "")
+		changeHint := fmt.Sprintf(rl.styles.Error+"Editor error: %s", errStr)
+		rl.Hint.SetTemporary(changeHint)
+
+		return nil, false
+	}
+
+	return edited, true
 }
 
 // Considers the blank word under cursor, and tries a series of regular expressions on it
@@ -1619,3 +1915,12 @@ func (rl *Shell) selectKeywordPrev() {
 	rl.cursor.Set(epos)
 	rl.selection.Visual(false)
 }
+
+// Toggle the low-bandwidth mode, meant for slow terminal links: right prompts,
+// tooltips, autosuggestions and autocompletion are not displayed, redraws are
+// batched when keys are typed faster than they are displayed, and colors are
+// restricted to the 16 base ones.
+func (rl *Shell) lowBandwidthToggle() {
+	rl.History.SkipSave()
+	rl.Config.Set("low-bandwidth", !rl.Config.GetBool("low-bandwidth"))
+}
@@ -0,0 +1,151 @@
+package readline
+
+import (
+	"os"
+	"os/user"
+	"strings"
+	"unicode"
+)
+
+// Expander performs the expansions of the host shell on the input line, for the
+// shell-expand-line, history-expand-line, alias-expand-line and history-and-alias-
+// expand-line commands. The shell cannot perform them itself, since it knows neither
+// the syntax nor the state (variables, aliases, globbing rules) of the host shell.
+// Any method can return the line unchanged if it does not support the expansion,
+// and errors (eg. bad substitutions) are shown in the hint area.
+type Expander interface {
+	// ExpandLine returns the line with all the expansions performed by the shell
+	// before executing it: aliases, history, tildes, parameters and variables,
+	// command substitutions, arithmetic and pathnames (globs).
+	ExpandLine(line string) (string, error)
+
+	// ExpandHistory returns the line with history expansions performed (eg. !!).
+	ExpandHistory(line string) (string, error)
+
+	// ExpandAliases returns the line with its aliases replaced by their values.
+	ExpandAliases(line string) (string, error)
+}
+
+// SetExpander sets the expander used by the line expansion commands, which ring
+// the bell when none is set. Tilde expansion (tilde-expand) does not need one.
+func (rl *Shell) SetExpander(expander Expander) {
+	rl.expander = expander
+}
+
+// Expand the line as the shell does: this performs alias and history
+// expansion as well as all of the shell word expansions.
+func (rl *Shell) shellExpandLine() {
+	rl.expandLine(Expander.ExpandLine)
+}
+
+// Perform history expansion on the current line.
+func (rl *Shell) historyExpandLine() {
+	rl.expandLine(Expander.ExpandHistory)
+}
+
+// Perform alias expansion on the current line.
+func (rl *Shell) aliasExpandLine() {
+	rl.expandLine(Expander.ExpandAliases)
+}
+
+// Perform history and alias expansion on the current line.
+func (rl *Shell) historyAndAliasExpandLine() {
+	rl.expandLine(Expander.ExpandHistory, Expander.ExpandAliases)
+}
+
+// Perform tilde expansion on the current word: a leading tilde
+// is replaced with the home directory, and a tilde followed by
+// a username (up to the first slash) with the home of this user.
+func (rl *Shell) tildeExpand() {
+	rl.History.Save()
+
+	line := *rl.line
+
+	bpos := rl.cursor.Pos()
+	for bpos > 0 && !unicode.IsSpace(line[bpos-1]) {
+		bpos--
+	}
+
+	epos := bpos
+	for epos < len(line) && !unicode.IsSpace(line[epos]) && line[epos] != '/' {
+		epos++
+	}
+
+	if bpos == epos || line[bpos] != '~' {
+		return
+	}
+
+	home := expandTilde(string(line[bpos+1 : epos]))
+	if home == "" {
+		rl.bell.Ring()
+		return
+	}
+
+	rl.line.Cut(bpos, epos)
+	rl.line.Insert(bpos, []rune(home)...)
+
+	if cpos := rl.cursor.Pos(); cpos >= epos {
+		rl.cursor.Set(cpos + len([]rune(home)) - (epos - bpos))
+	} else if cpos > bpos {
+		rl.cursor.Set(bpos + len([]rune(home)))
+	}
+}
+
+// expandLine replaces the line with the result of the expansions (in order) with
+// the expander. The cursor stays at the end of the line if it was there already.
+func (rl *Shell) expandLine(expansions ...func(expander Expander, line string) (string, error)) {
+	if rl.expander == nil {
+		rl.bell.Ring()
+		return
+	}
+
+	rl.History.Save()
+
+	line := string(*rl.line)
+
+	for _, expand := range expansions {
+		expanded, err := expand(rl.expander, line)
+		if err != nil {
+			rl.Hint.SetTemporary(rl.styles.Error + err.Error())
+			rl.bell.Ring()
+
+			return
+		}
+
+		line = expanded
+	}
+
+	if line == string(*rl.line) {
+		return
+	}
+
+	atEnd := rl.cursor.Pos() == rl.line.Len()
+
+	rl.line.Set([]rune(line)...)
+
+	if atEnd {
+		rl.cursor.Set(rl.line.Len())
+	} else {
+		rl.cursor.CheckAppend()
+	}
+}
+
+// expandTilde returns the home directory of a user (of the current
+// one if empty), or an empty string if it cannot be found.
+func expandTilde(username string) string {
+	if username == "" {
+		if home := os.Getenv("HOME"); home != "" {
+			return home
+		}
+
+		home, _ := os.UserHomeDir()
+
+		return home
+	}
+
+	if found, err := user.Lookup(username); err == nil {
+		return strings.TrimSuffix(found.HomeDir, "/")
+	}
+
+	return ""
+}
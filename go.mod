@@ -3,7 +3,6 @@ module github.com/reeflective/readline
 go 1.21
 
 require (
-	golang.org/x/exp v0.0.0-20220827204233-334a2380cb91
 	golang.org/x/sys v0.8.0
 	golang.org/x/term v0.8.0
 )
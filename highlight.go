@@ -0,0 +1,29 @@
+package readline
+
+import "github.com/reeflective/readline/internal/display"
+
+// Token is a region of the input line, from Start to End (excluded, in runes),
+// to display with a style made of SGR sequences (eg. "\x1b[1;32m").
+type Token = display.Token
+
+// Highlighter returns the tokens of the input line to highlight. Since tokens
+// are not embedded in the line, the shell composes them with its own highlighted
+// regions (visual selections, search matches, etc), and computes the width of
+// the line without having to parse escape sequences. Overlapping tokens are
+// allowed, in which case the last one has precedence.
+type Highlighter = display.Highlighter
+
+// HighlighterFunc is a function used as a Highlighter.
+type HighlighterFunc func(line []rune) []Token
+
+// Highlight returns the tokens of the line to highlight.
+func (f HighlighterFunc) Highlight(line []rune) []Token {
+	return f(line)
+}
+
+// ShellHighlighter returns a highlighter for shell command lines, highlighting
+// command names, flags, quoted strings, control and redirection operators, and
+// comments. Lines are split into words and commands with shell syntax.
+func ShellHighlighter() Highlighter {
+	return display.ShellSyntax{}
+}
@@ -2,9 +2,15 @@ package readline
 
 import (
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/history"
+	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
 )
 
@@ -23,11 +29,34 @@ type History = history.Source
 // to the readline instance, with shell.History.Add().
 var NewHistoryFromFile = history.NewSourceFromFile
 
+// TimedHistory is implemented by history sources recording the time at which their
+// lines have been written (as both builtin sources do), which is then displayed
+// by the history-menu command.
+type TimedHistory = history.TimedSource
+
+// ResultHistory is implemented by history sources recording the results of the
+// commands run with their lines (as both builtin sources do), as reported with
+// ReportCommandResult(). Lines whose command failed are then less likely to be
+// autosuggested.
+type ResultHistory = history.ResultSource
+
+// HistoryResult is the result of the command run with a history line.
+type HistoryResult = history.Result
+
 // NewInMemoryHistory creates a new in-memory command history source.
 // The caller should bind the history source returned from this call
 // to the readline instance, with shell.History.Add().
 var NewInMemoryHistory = history.NewInMemoryHistory
 
+// ReportCommandResult reports the exit status and duration of the command run
+// by the caller with the last line returned by Readline(), so that history sources
+// recording them (see ResultHistory) can store them along with the line. This is
+// ignored if the line has not been written to the history (eg. if it is empty or
+// has been read with ReadPassword()), or if another line has been accepted since.
+func (rl *Shell) ReportCommandResult(status int, duration time.Duration) {
+	rl.History.SetResult(history.Result{Status: status, Duration: duration})
+}
+
 // historyCommands returns all history commands.
 // Under each comment are gathered all commands related to the comment's
 // subject. When there are two subgroups separated by an empty line, the
@@ -53,6 +82,10 @@ func (rl *Shell) historyCommands() commands {
 		"insert-last-argument":                   rl.yankLastArg,
 		"yank-nth-arg":                           rl.yankNthArg,
 		"magic-space":                            rl.magicSpace,
+		"shell-expand-line":                      rl.shellExpandLine,
+		"history-expand-line":                    rl.historyExpandLine,
+		"alias-expand-line":                      rl.aliasExpandLine,
+		"history-and-alias-expand-line":          rl.historyAndAliasExpandLine,
 
 		"accept-and-hold":                    rl.acceptAndHold,
 		"accept-and-infer-next-history":      rl.acceptAndInferNextHistory,
@@ -68,11 +101,17 @@ func (rl *Shell) historyCommands() commands {
 		"end-of-line-hist":                   rl.endOfLineHist,
 		"incremental-forward-search-history": rl.incrementalForwardSearchHistory,
 		"incremental-reverse-search-history": rl.incrementalReverseSearchHistory,
+		"history-menu":                       rl.historyMenu,
 		"save-line":                          rl.saveLine,
 		"history-source-next":                rl.historySourceNext,
 		"history-source-prev":                rl.historySourcePrev,
+		"toggle-merged-history":              rl.toggleMergedHistory,
+		"toggle-history-source-tags":         rl.toggleHistorySourceTags,
 		"autosuggest-accept":                 rl.autosuggestAccept,
 		"autosuggest-execute":                rl.autosuggestExecute,
+		"autosuggest-accept-word":            rl.autosuggestAcceptWord,
+		"autosuggest-accept-line-to-cursor":  rl.autosuggestAcceptLineToCursor,
+		"autosuggest-clear":                  rl.autosuggestClear,
 		"autosuggest-enable":                 rl.autosuggestEnable,
 		"autosuggest-disable":                rl.autosuggestDisable,
 		"autosuggest-toggle":                 rl.autosuggestToggle,
@@ -125,9 +164,19 @@ func (rl *Shell) endOfHistory() {
 	rl.History.Walk(-history.Len() + 1)
 }
 
-// Execute the current line, and push the next history event on the buffer stack.
+// Accept the current line for execution, and start the next line with the history
+// line following it: the one after the history line being edited, or after the most
+// recent one identical to the accepted line. With a numeric argument, the history
+// line at this index is used instead. If there is none, the next line is empty.
 func (rl *Shell) acceptLineAndDownHistory() {
-	rl.acceptLineWith(true, false)
+	next := rl.History.Following()
+	if rl.Iterations.IsSet() {
+		next = rl.Iterations.Get()
+	}
+
+	if rl.acceptLineWith(false, false) {
+		rl.History.FetchNext(next)
+	}
 }
 
 // With a numeric argument, fetch that entry from the history
@@ -552,6 +601,28 @@ func (rl *Shell) incrementalReverseSearchHistory() {
 	rl.historyCompletion(forward, filter, regexp)
 }
 
+// Open the completion menu with the most recent lines of the history (at most
+// history-menu-size of them, or all if 0), numbered and with their time if the
+// history source records it. The selected line replaces the input line, and
+// lines can be filtered with menu-incremental-search.
+func (rl *Shell) historyMenu() {
+	rl.History.SkipSave()
+
+	if rl.History.Current() == nil {
+		rl.Hint.SetTemporary(rl.styles.Error + "No command history source" + color.Reset)
+		return
+	}
+
+	rl.startMenuComplete(func() completion.Values {
+		comps := history.CompleteMenu(rl.History, rl.Config.GetInt("history-menu-size"))
+
+		line, _ := rl.completer.Line()
+		comps.Replace, comps.ReplaceStart, comps.ReplaceEnd = true, 0, line.Len()
+
+		return comps
+	})
+}
+
 // Write the current line to the history if it is not empty
 // (without executing it), and clear the line buffer.
 func (rl *Shell) saveLine() {
@@ -573,9 +644,40 @@ func (rl *Shell) historySourcePrev() {
 	rl.History.Cycle(false)
 }
 
-// If a line is currently auto-suggested, make it the buffer.
+// Toggle the completion of the lines of all history sources at once, instead
+// of the active one only. If history completion or incremental search is
+// currently active, its candidates are updated accordingly.
+func (rl *Shell) toggleMergedHistory() {
+	rl.History.SkipSave()
+
+	rl.Config.Set("history-merge-sources", !rl.Config.GetBool("history-merge-sources"))
+	rl.refreshHistoryCompletion()
+}
+
+// Toggle the display of the history source of each line proposed by history
+// completion or incremental search, in a column between its number and itself.
+func (rl *Shell) toggleHistorySourceTags() {
+	rl.History.SkipSave()
+
+	rl.Config.Set("history-source-tags", !rl.Config.GetBool("history-source-tags"))
+	rl.refreshHistoryCompletion()
+}
+
+// refreshHistoryCompletion regenerates the candidates of an autocompletion
+// mode (eg. history completion), if one is active. Incremental search ones
+// are regenerated after each command anyway.
+func (rl *Shell) refreshHistoryCompletion() {
+	if !rl.completer.AutoCompleting() || rl.Keymap.Local() == keymap.Isearch {
+		return
+	}
+
+	rl.completer.GenerateWith(rl.completer.Cached())
+}
+
+// If a line is currently auto-suggested (either from the history or
+// with an inline suggestion from the completer), make it the buffer.
 func (rl *Shell) autosuggestAccept() {
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggested()
 
 	if suggested.Len() <= rl.line.Len() {
 		return
@@ -585,9 +687,54 @@ func (rl *Shell) autosuggestAccept() {
 	rl.cursor.Set(len(suggested))
 }
 
+// If a line is currently auto-suggested, insert its next word (or the next
+// n words with a numeric argument) in the buffer, and move the cursor after it.
+// Unlike forward-word, this works regardless of the autosuggest-motion-accept option.
+func (rl *Shell) autosuggestAcceptWord() {
+	suggested := rl.suggested()
+
+	if suggested.Len() <= rl.line.Len() {
+		return
+	}
+
+	end := rl.line.Len()
+	style := rl.wordStyle()
+	vii := rl.Iterations.Get()
+
+	for i := 1; i <= vii; i++ {
+		end = suggested.WordEnd(style, end)
+	}
+
+	rl.acceptSuggested(suggested, end)
+}
+
+// If a line is currently auto-suggested, insert it in the buffer up to the
+// end of its current line only (the whole suggestion if it has a single line),
+// and move the cursor there.
+func (rl *Shell) autosuggestAcceptLineToCursor() {
+	suggested := rl.suggested()
+
+	if suggested.Len() <= rl.line.Len() {
+		return
+	}
+
+	end := rl.line.Len()
+	for end < suggested.Len() && suggested[end] != '\n' {
+		end++
+	}
+
+	rl.acceptSuggested(suggested, end)
+}
+
+// Hide the current autosuggestion, until the line is changed.
+func (rl *Shell) autosuggestClear() {
+	rl.History.SkipSave()
+	rl.dismissed = string(*rl.line)
+}
+
 // If a line is currently auto-suggested, make it the buffer and execute it.
 func (rl *Shell) autosuggestExecute() {
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggested()
 
 	if suggested.Len() <= rl.line.Len() {
 		return
@@ -628,7 +775,9 @@ func (rl *Shell) autosuggestDisable() {
 // Utils -------------------------------------------------------------------
 //
 
-func (rl *Shell) acceptLineWith(infer, hold bool) {
+// acceptLineWith accepts the line (holding it for the next loop, or inferring the
+// next history line from it), and returns true if it has actually been accepted.
+func (rl *Shell) acceptLineWith(infer, hold bool) bool {
 	// If we are currently using the incremental-search buffer,
 	// we should cancel this mode so as to run the rest of this
 	// function on (with) the input line itself, not the minibuffer.
@@ -644,39 +793,155 @@ func (rl *Shell) acceptLineWith(infer, hold bool) {
 		line, cursor, _ := rl.completer.GetBuffer()
 		rl.History.InsertMatch(line, cursor, true, forward, substring)
 
-		return
+		return false
 	}
 
 	// Use the correct buffer for the rest of the function.
 	rl.line, rl.cursor, rl.selection = rl.completer.GetBuffer()
 
+	// With autopairs, unclosed pairs (eg. a quote or a bracket) are
+	// continued on a new line, as when the caller does not accept it.
+	balanced := completion.AutopairsBalanced(rl.autopairs(), *rl.line)
+
 	// Without multiline support, we always return the line.
-	if rl.AcceptMultiline == nil {
+	if balanced && rl.AcceptMultiline == nil {
+		if !rl.filterAccepted() {
+			return false
+		}
+
 		rl.Macros.StopRecord(rl.Keys.Caller()...)
 
 		rl.Display.AcceptLine()
 		rl.History.Accept(hold, infer, nil)
 
-		return
+		return true
 	}
 
 	// Ask the caller if the line should be accepted
 	// as is, save the command line and accept it.
-	if rl.AcceptMultiline(*rl.line) {
+	if balanced && rl.AcceptMultiline(*rl.line) {
+		if !rl.filterAccepted() {
+			return false
+		}
+
 		rl.Macros.StopRecord(rl.Keys.Caller()...)
 
 		rl.Display.AcceptLine()
 		rl.History.Accept(hold, infer, nil)
 
-		return
+		return true
 	}
 
 	// If not, we should start editing another line,
 	// and insert a newline where our cursor value is.
 	// This has the nice advantage of being able to work
 	// in multiline mode even in the middle of the buffer.
-	rl.line.Insert(rl.cursor.Pos(), '\n')
-	rl.cursor.Inc()
+	rl.insertNewline()
+
+	return false
+}
+
+// insertNewline inserts a newline at the cursor, followed by the indentation
+// of the current line if the smart-indent option is on (with indent-width more
+// spaces if the line ends with an opening bracket before the cursor).
+func (rl *Shell) insertNewline() {
+	indent := []rune{'\n'}
+
+	if rl.Config.GetBool("smart-indent") {
+		indent = append(indent, rl.indentation(rl.cursor.Pos())...)
+	}
+
+	rl.line.Insert(rl.cursor.Pos(), indent...)
+	rl.cursor.Set(rl.cursor.Pos() + len(indent))
+}
+
+// indentation returns the leading blanks of the line in which pos is (up to
+// pos), and indent-width more spaces if the last non-blank character before
+// pos is an opening bracket.
+func (rl *Shell) indentation(pos int) []rune {
+	line := *rl.line
+
+	start := pos
+	for start > 0 && line[start-1] != '\n' {
+		start--
+	}
+
+	end := start
+	for end < pos && (line[end] == ' ' || line[end] == '\t') {
+		end++
+	}
+
+	indent := append([]rune{}, line[start:end]...)
+
+	last := pos
+	for last > end && unicode.IsSpace(line[last-1]) {
+		last--
+	}
+
+	if last > end && strings.ContainsRune("([{", line[last-1]) {
+		indent = append(indent, []rune(strings.Repeat(" ", max(rl.Config.GetInt("indent-width"), 0)))...)
+	}
+
+	return indent
+}
+
+// filterAccepted offers to correct the command word of the line if needed (see
+// SetCorrector), passes the line to the accept filter if there is one, and replaces
+// it with the transformed line. Returns false if the filter rejects the line, in which
+// case its error is shown in the hint area and the user keeps editing the line.
+// With the accept-verify option, the line is also not accepted when the filter changed
+// it (or always, if "always"): it is shown for confirmation, and the next accept-line
+// accepts it as is, unless it has been edited since.
+func (rl *Shell) filterAccepted() bool {
+	line := string(*rl.line)
+
+	if rl.verified != "" && line == rl.verified {
+		rl.verified = ""
+		return true
+	}
+
+	if !rl.correctAccepted() {
+		return false
+	}
+
+	line = string(*rl.line)
+
+	filtered := line
+
+	if rl.accept != nil {
+		var err error
+
+		filtered, err = rl.accept(line)
+		if err != nil {
+			rl.Hint.SetTemporary(rl.styles.Error + err.Error())
+			return false
+		}
+	}
+
+	verify := strings.Trim(rl.Config.GetString("accept-verify"), "\"")
+
+	if verify == "always" || (verify == "changed" && filtered != line) {
+		rl.History.Save()
+		rl.verified = filtered
+		rl.Hint.SetTemporary(rl.styles.Hint + "Press Enter to confirm this line, or edit it")
+	}
+
+	if filtered != line {
+		rl.line.Set([]rune(filtered)...)
+		rl.cursor.Set(rl.line.Len())
+	}
+
+	return rl.verified == ""
+}
+
+// acceptSuggested inserts the suggested line in the buffer up to
+// end (at least one character), and moves the cursor there.
+func (rl *Shell) acceptSuggested(suggested core.Line, end int) {
+	end = min(max(end, rl.line.Len()+1), suggested.Len())
+
+	rl.History.Save()
+	rl.line.Set(suggested[:end]...)
+	rl.cursor.Set(end)
 }
 
 func (rl *Shell) insertAutosuggestPartial(emacs bool) {
@@ -685,11 +950,11 @@ func (rl *Shell) insertAutosuggestPartial(emacs bool) {
 		return
 	}
 
-	if !rl.Config.GetBool("history-autosuggest") {
+	if !rl.motionAccepts() {
 		return
 	}
 
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggested()
 
 	if suggested.Len() > rl.line.Len() {
 		var forward int
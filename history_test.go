@@ -0,0 +1,42 @@
+package readline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShell_AcceptFilter(t *testing.T) {
+	shell := NewShell()
+
+	// Without filter, lines are accepted as is.
+	shell.line.Set([]rune(" ls ")...)
+
+	if !shell.filterAccepted() || string(*shell.line) != " ls " {
+		t.Errorf("filterAccepted() without filter changed the line to %q", string(*shell.line))
+	}
+
+	shell.AcceptFilter(func(line string) (string, error) {
+		if strings.TrimSpace(line) == "" {
+			return line, errors.New("empty command")
+		}
+
+		return strings.TrimSpace(line), nil
+	})
+
+	// Accepted lines are replaced with the filtered one.
+	if !shell.filterAccepted() || string(*shell.line) != "ls" || shell.cursor.Pos() != 2 {
+		t.Errorf("filterAccepted() line = %q, cursor = %d, want %q, 2", string(*shell.line), shell.cursor.Pos(), "ls")
+	}
+
+	// Rejected lines are kept, with the error in the hint area.
+	shell.line.Set([]rune(" ")...)
+
+	if shell.filterAccepted() || string(*shell.line) != " " {
+		t.Errorf("filterAccepted() accepted the line %q", string(*shell.line))
+	}
+
+	if hint := shell.Hint.Text(); !strings.Contains(hint, "empty command") {
+		t.Errorf("hint = %q, want the filter error", hint)
+	}
+}
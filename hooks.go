@@ -0,0 +1,225 @@
+package readline
+
+import "sync"
+
+// Hooks is a registry of functions called at key points of the shell lifecycle,
+// so that applications can react to them (eg. to change the cursor shape in
+// each mode, update status integrations, or collect metrics). Any number of
+// functions can be registered for each event, and are called in order.
+type Hooks struct {
+	preRead    []func()
+	preRender  []func()
+	modeChange []func(main, local string)
+	postAccept []func(line string, err error)
+	suspend    []func()
+	resume     []func()
+	announce   []func(message string)
+	ring       []func()
+	commands   []func(command string, keys []rune, run func())
+
+	main, local string // Last notified keymaps.
+	mutex       sync.RWMutex
+}
+
+// OnPreRead registers a function called each time the shell waits for input keys.
+func (h *Hooks) OnPreRead(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preRead = append(h.preRead, hook)
+}
+
+// OnPreRender registers a function called before each refresh of the display,
+// in which the prompts, input line and helpers are redisplayed. It is called
+// from the goroutine refreshing the display, which might not be the main one.
+func (h *Hooks) OnPreRender(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preRender = append(h.preRender, hook)
+}
+
+// OnModeChange registers a function called with the main keymap (eg. emacs,
+// vi-insert or vi-command) and the local one (eg. isearch, menu-select or
+// vi-opp, or empty) when either changes, and when a Readline call starts
+// with keymaps different from the ones the previous call ended with.
+func (h *Hooks) OnModeChange(hook func(main, local string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.modeChange = append(h.modeChange, hook)
+}
+
+// OnAccept registers a function called when Readline returns, with the line and
+// error it returns: the latter is not nil if the line was not accepted, but the
+// call interrupted (eg. with ErrInterrupt or ErrEOF). The terminal is restored
+// when the function is called, so it can print to it.
+func (h *Hooks) OnAccept(hook func(line string, err error)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.postAccept = append(h.postAccept, hook)
+}
+
+// OnSuspend registers a function called when the user suspends the shell (usually
+// with Ctrl-Z), after the terminal is restored and before the process is stopped,
+// so that the application can save or restore its own state (eg. terminal modes).
+func (h *Hooks) OnSuspend(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.suspend = append(h.suspend, hook)
+}
+
+// OnResume registers a function called when the suspended process is continued,
+// before the terminal is put in raw mode again and the shell redisplayed.
+func (h *Hooks) OnResume(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.resume = append(h.resume, hook)
+}
+
+// OnAnnounce registers a function called with the announcements of the screen-reader
+// mode (mode changes, selected completion candidates, hints), so that applications
+// can pass them to accessibility tools. When none is registered, announcements are
+// printed above the prompt, as plain sequential output read by screen readers.
+func (h *Hooks) OnAnnounce(hook func(message string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.announce = append(h.announce, hook)
+}
+
+// OnBell registers a function called each time the bell rings (eg. when there are no
+// completion matches, or a search or motion fails), so that applications can notify
+// users in their own way. It is called regardless of the bell-style option, which
+// only determines how the terminal is notified (none, visible or audible).
+func (h *Hooks) OnBell(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.ring = append(h.ring, hook)
+}
+
+// OnCommand registers a middleware wrapping the execution of each command run
+// from a key sequence, with the name of the command and the keys it was bound to.
+// The middleware must call run to execute the command (or the next middleware),
+// so it can veto it by not doing so, time it or log it. Middlewares are called in
+// order, the first registered one wrapping all others.
+func (h *Hooks) OnCommand(middleware func(command string, keys []rune, run func())) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.commands = append(h.commands, middleware)
+}
+
+// Clear removes all registered functions.
+func (h *Hooks) Clear() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preRead, h.preRender, h.modeChange, h.postAccept = nil, nil, nil, nil
+	h.suspend, h.resume, h.announce, h.ring = nil, nil, nil, nil
+	h.commands = nil
+}
+
+func (h *Hooks) read() {
+	h.mutex.RLock()
+	hooks := h.preRead
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+func (h *Hooks) render() {
+	h.mutex.RLock()
+	hooks := h.preRender
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// modes notifies the keymaps if they have changed since the last notification.
+func (h *Hooks) modes(main, local string) {
+	h.mutex.Lock()
+	changed := main != h.main || local != h.local
+	h.main, h.local = main, local
+	hooks := h.modeChange
+	h.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+
+	for _, hook := range hooks {
+		hook(main, local)
+	}
+}
+
+func (h *Hooks) accept(line string, err error) {
+	h.mutex.RLock()
+	hooks := h.postAccept
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(line, err)
+	}
+}
+
+// announced passes a message to the announce hooks,
+// and returns false if there are none to pass it to.
+func (h *Hooks) announced(message string) bool {
+	h.mutex.RLock()
+	hooks := h.announce
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(message)
+	}
+
+	return len(hooks) > 0
+}
+
+// command returns the command function wrapped in the command middlewares.
+func (h *Hooks) command(name string, keys []rune, command func()) func() {
+	h.mutex.RLock()
+	middlewares := h.commands
+	h.mutex.RUnlock()
+
+	keys = append([]rune(nil), keys...)
+
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		middleware, run := middlewares[i], command
+		command = func() { middleware(name, keys, run) }
+	}
+
+	return command
+}
+
+func (h *Hooks) bell() {
+	h.mutex.RLock()
+	hooks := h.ring
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+func (h *Hooks) suspended(resumed bool) {
+	h.mutex.RLock()
+	hooks := h.suspend
+	if resumed {
+		hooks = h.resume
+	}
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
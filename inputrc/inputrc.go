@@ -38,7 +38,23 @@ func ParseFile(name string, h Handler, opts ...Option) error {
 
 // UserDefault loads default inputrc settings for the user.
 func UserDefault(u *user.User, cfg *Config, opts ...Option) error {
-	// build possible file list
+	// load first available file
+	for _, name := range UserFiles(u) {
+		buf, err := cfg.ReadFile(name)
+		switch {
+		case err != nil && errors.Is(err, os.ErrNotExist):
+			continue
+		case err != nil:
+			return err
+		}
+		return ParseBytes(buf, cfg, append(opts, WithName(name))...)
+	}
+	return nil
+}
+
+// UserFiles returns the inputrc files possibly loaded by UserDefault, in
+// order of precedence: only the first existing one is loaded.
+func UserFiles(u *user.User) []string {
 	var files []string
 	if name := os.Getenv("INPUTRC"); name != "" {
 		files = append(files, name)
@@ -53,18 +69,7 @@ func UserDefault(u *user.User, cfg *Config, opts ...Option) error {
 	if runtime.GOOS != "windows" {
 		files = append(files, "/etc/inputrc")
 	}
-	// load first available file
-	for _, name := range files {
-		buf, err := cfg.ReadFile(name)
-		switch {
-		case err != nil && errors.Is(err, os.ErrNotExist):
-			continue
-		case err != nil:
-			return err
-		}
-		return ParseBytes(buf, cfg, append(opts, WithName(name))...)
-	}
-	return nil
+	return files
 }
 
 // Unescape unescapes a inputrc string.
@@ -105,6 +105,22 @@ func TestEscape(t *testing.T) {
 	}
 }
 
+func TestUnescape(t *testing.T) {
+	tests := []struct {
+		s, exp string
+	}{
+		{`\M-a`, string(Enmeta('a'))},
+		{`\M-\\`, string(Enmeta('\\'))},
+		{`\M-\"x`, string(Enmeta('"')) + "x"},
+		{`\e\\`, "\x1b\\"},
+	}
+	for i, test := range tests {
+		if s, exp := Unescape(test.s), test.exp; s != exp {
+			t.Errorf("test %d expected %q==%q", i, exp, s)
+		}
+	}
+}
+
 func TestDecode(t *testing.T) {
 	const str = `
 Control-Meta-f: "a"
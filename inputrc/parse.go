@@ -123,7 +123,14 @@ func (p *Parser) readNext(seq []rune, pos, end int) (string, string, token, erro
 		return p.readSymbols(seq, pos+setDirectiveLen, end, tokenSet, true)
 	case seq[pos] == '$':
 		// read construct
-		return p.readSymbols(seq, pos, end, tokenConstruct, false)
+		directive, val, tok, err := p.readSymbols(seq, pos, end, tokenConstruct, false)
+
+		// Conditions are made of the remainder of the line (eg. `$if var == value`).
+		if directive == "$if" {
+			val = strings.TrimSpace(string(seq[pos+len(directive) : end]))
+		}
+
+		return directive, val, tok, err
 	}
 	// read key keySeq
 	var keySeq string
@@ -201,7 +208,7 @@ func (p *Parser) readSymbols(seq []rune, pos, end int, tok token, allowStrings b
 	start = findNonSpace(seq, pos, end)
 	var ok bool
 
-	if c := grab(seq, start, end); allowStrings || c == '"' || c == '\'' {
+	if c := grab(seq, start, end); allowStrings && (c == '"' || c == '\'') {
 		var epos int
 		if epos, ok = findStringEnd(seq, start, end); ok {
 			pos = epos
@@ -264,6 +271,9 @@ func (p *Parser) doSet(handler Handler, name, value string) error {
 			}
 		}
 
+		// Subsequent mode conditions test the new editing mode.
+		p.mode = value
+
 		return handler.Set(name, value)
 	}
 
@@ -308,17 +318,8 @@ func (p *Parser) doSet(handler Handler, name, value string) error {
 func (p *Parser) do(handler Handler, keyword, val string) error {
 	switch keyword {
 	case "$if":
-		var eval bool
-
-		switch {
-		case strings.HasPrefix(val, "mode="):
-			eval = strings.TrimPrefix(val, "mode=") == p.mode
-		case strings.HasPrefix(val, "term="):
-			eval = strings.TrimPrefix(val, "term=") == p.term
-		default:
-			eval = strings.ToLower(val) == p.app
-		}
-
+		// Conditions nested in a false one are always false.
+		eval := p.conds[len(p.conds)-1] && p.eval(handler, val)
 		p.conds = append(p.conds, eval)
 
 		return nil
@@ -333,7 +334,8 @@ func (p *Parser) do(handler Handler, keyword, val string) error {
 			}
 		}
 
-		p.conds[len(p.conds)-1] = !p.conds[len(p.conds)-1]
+		parent := p.conds[len(p.conds)-2]
+		p.conds[len(p.conds)-1] = parent && !p.conds[len(p.conds)-1]
 
 		return nil
 
@@ -385,6 +387,52 @@ func (p *Parser) do(handler Handler, keyword, val string) error {
 	return nil
 }
 
+// eval evaluates the condition of an $if construct, which is either:
+// - mode=emacs/vi, true if this is the current editing mode.
+// - term=name, true if the terminal name, or its portion before the first
+// dash, is the one given (so that term=xterm matches xterm-256color).
+// - A variable comparison (eg. `editing-mode == vi`, `bell-style != none`),
+// booleans being compared to on/off.
+// - The application name, case-insensitive.
+func (p *Parser) eval(handler Handler, cond string) bool {
+	switch {
+	case strings.HasPrefix(cond, "mode="):
+		return strings.TrimPrefix(cond, "mode=") == p.mode
+
+	case strings.HasPrefix(cond, "term="):
+		term := strings.TrimPrefix(cond, "term=")
+		base, _, _ := strings.Cut(p.term, "-")
+
+		return term != "" && (term == p.term || term == base)
+	}
+
+	for _, operator := range []string{"==", "!="} {
+		name, value, found := strings.Cut(cond, operator)
+		if !found {
+			continue
+		}
+
+		name, value = strings.TrimSpace(name), strings.Trim(strings.TrimSpace(value), `"`)
+
+		var current string
+
+		switch val := handler.Get(name).(type) {
+		case nil:
+		case bool:
+			current = "off"
+			if val {
+				current = "on"
+			}
+		default:
+			current = fmt.Sprint(val)
+		}
+
+		return strings.EqualFold(current, value) == (operator == "==")
+	}
+
+	return strings.EqualFold(cond, p.app)
+}
+
 // Option is a parser option.
 type Option func(*Parser)
 
@@ -690,10 +738,14 @@ func unescapeRunes(r []rune, i, end int) string {
 
 				i += 3
 			case char1 == 'M' && char2 == '-': // \M- meta prefix
-				if char3 == 0 {
+				switch {
+				case char3 == 0:
 					seq = append(seq, Esc)
 					i += 2
-				} else {
+				case char3 == '\\' && (char4 == '\\' || char4 == '"' || char4 == '\''): // \M-\\ escaped literal
+					seq = append(seq, Enmeta(char4))
+					i += 4
+				default:
 					seq = append(seq, Enmeta(char3))
 					i += 3
 				}
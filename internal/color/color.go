@@ -84,30 +84,214 @@ func Fmt(color string) string {
 	return SGRStart + color + SGREnd
 }
 
-// Trim accepts a string including arbitrary escaped sequences at arbitrary
-// index positions, and returns the first 'n' printable characters in this
-// string, including all escape codes found between and immediately around
-// those characters (including surrounding 1st and 80th ones).
-func Trim(input string, maxPrintableLength int) string {
-	if len(input) < maxPrintableLength {
-		return input
+var sgr = regexp.MustCompile("\x1b\\[([0-9;]*)m")
+
+// To16 replaces all 256 and true colors used in the SGR sequences
+// of a string with the closest one among the 16 base colors.
+func To16(str string) string {
+	if !strings.Contains(str, "38;") && !strings.Contains(str, "48;") {
+		return str
 	}
 
-	// Find all escape sequences in the input
-	escapeIndices := re.FindAllStringIndex(input, -1)
+	return sgr.ReplaceAllStringFunc(str, func(seq string) string {
+		params := strings.Split(seq[2:len(seq)-1], ";")
+		codes := make([]string, 0, len(params))
+
+		for i := 0; i < len(params); i++ {
+			if (params[i] != "38" && params[i] != "48") || i+1 >= len(params) {
+				codes = append(codes, params[i])
+				continue
+			}
+
+			base := 30
+			if params[i] == "48" {
+				base = 40
+			}
+
+			var red, green, blue int
+
+			switch mode, _ := strconv.Atoi(params[i+1]); {
+			case mode == 5 && i+2 < len(params):
+				num, _ := strconv.Atoi(params[i+2])
+				i += 2
+
+				if num < 16 {
+					codes = append(codes, strconv.Itoa(baseCode(base, num)))
+					continue
+				}
+
+				red, green, blue = rgb256(num)
+
+			case mode == 2 && i+4 < len(params):
+				red, _ = strconv.Atoi(params[i+2])
+				green, _ = strconv.Atoi(params[i+3])
+				blue, _ = strconv.Atoi(params[i+4])
+				i += 4
+
+			default:
+				codes = append(codes, params[i])
+				continue
+			}
+
+			codes = append(codes, strconv.Itoa(baseCode(base, nearest16(red, green, blue))))
+		}
+
+		return SGRStart + strings.Join(codes, ";") + SGREnd
+	})
+}
+
+// To256 replaces all true colors used in the SGR sequences of
+// a string with the closest one among the 256-colors palette.
+func To256(str string) string {
+	if !strings.Contains(str, "38;2;") && !strings.Contains(str, "48;2;") {
+		return str
+	}
+
+	return sgr.ReplaceAllStringFunc(str, func(seq string) string {
+		params := strings.Split(seq[2:len(seq)-1], ";")
+		codes := make([]string, 0, len(params))
+
+		for i := 0; i < len(params); i++ {
+			if (params[i] != "38" && params[i] != "48") || i+1 >= len(params) {
+				codes = append(codes, params[i])
+				continue
+			}
 
-	// Iterate over escape sequences to find the
-	// last escape index within maxPrintableLength
-	for _, indices := range escapeIndices {
-		if indices[0] <= maxPrintableLength {
-			maxPrintableLength += indices[1] - indices[0]
-		} else {
-			break
+			switch {
+			case params[i+1] == "5" && i+2 < len(params):
+				codes = append(codes, params[i:i+3]...)
+				i += 2
+
+			case params[i+1] == "2" && i+4 < len(params):
+				red, _ := strconv.Atoi(params[i+2])
+				green, _ := strconv.Atoi(params[i+3])
+				blue, _ := strconv.Atoi(params[i+4])
+
+				codes = append(codes, params[i], "5", strconv.Itoa(nearest256(red, green, blue)))
+				i += 4
+
+			default:
+				codes = append(codes, params[i])
+			}
 		}
+
+		return SGRStart + strings.Join(codes, ";") + SGREnd
+	})
+}
+
+// baseCode returns the SGR code of one of the 16 base colors,
+// either as a foreground (base 30) or background (base 40).
+func baseCode(base, num int) int {
+	if num < 8 {
+		return base + num
 	}
 
-	// Determine the end index for limiting printable content
-	return input[:maxPrintableLength]
+	return base + 60 + num - 8
+}
+
+// rgb256 returns the RGB values of a color of the 256-colors palette.
+func rgb256(num int) (red, green, blue int) {
+	if num >= 232 {
+		gray := 8 + (num-232)*10
+		return gray, gray, gray
+	}
+
+	levels := []int{0, 95, 135, 175, 215, 255}
+	num -= 16
+
+	return levels[num/36], levels[(num/6)%6], levels[num%6]
+}
+
+// nearest256 returns the index of the color of the 256-colors palette (excluding
+// the 16 base ones) closest to an RGB color: either in the 6x6x6 cube, or a gray.
+func nearest256(red, green, blue int) int {
+	levels := []int{0, 95, 135, 175, 215, 255}
+
+	level := func(value int) int {
+		nearest := 0
+
+		for i, lvl := range levels {
+			if abs(value-lvl) < abs(value-levels[nearest]) {
+				nearest = i
+			}
+		}
+
+		return nearest
+	}
+
+	cube := 16 + 36*level(red) + 6*level(green) + level(blue)
+
+	average := (red + green + blue) / 3
+	gray := 232 + min(max((average-3)/10, 0), 23)
+
+	distance := func(num int) int {
+		r, g, b := rgb256(num)
+		return (r-red)*(r-red) + (g-green)*(g-green) + (b-blue)*(b-blue)
+	}
+
+	if distance(gray) < distance(cube) {
+		return gray
+	}
+
+	return cube
+}
+
+func abs(num int) int {
+	if num < 0 {
+		return -num
+	}
+
+	return num
+}
+
+// nearest16 returns the index of the base color closest to an RGB color.
+func nearest16(red, green, blue int) int {
+	brightest := max(red, green, blue)
+	if brightest < 64 {
+		return 0
+	}
+
+	var num int
+
+	for i, value := range []int{red, green, blue} {
+		if value > brightest/2 {
+			num |= 1 << i
+		}
+	}
+
+	switch {
+	case num == 7 && brightest < 128:
+		return 8
+	case brightest > 200:
+		return num + 8
+	default:
+		return num
+	}
+}
+
+// Truncate returns the input unchanged if it fits in width terminal columns,
+// or else its first printable characters fitting in width-1 columns followed
+// by an ellipsis. Escape sequences are kept, and styles are reset after it.
+func Truncate(input string, width int) string {
+	return string(Text(input).Truncate(width))
+}
+
+// Slice returns the printable characters of the input displayed in the width
+// terminal columns starting at column from, padded with spaces where a wide
+// character is only partially in these columns. All escape sequences are kept,
+// so that the styles in effect before the first returned character still apply.
+func Slice(input string, from, width int) string {
+	return string(Text(input).Slice(from, width))
+}
+
+// Link returns the text as a hyperlink (OSC 8) to the URI: terminals not
+// supporting them display the text only. Any styles of the text are kept.
+func Link(uri, text string) string {
+	if uri == "" {
+		return text
+	}
+
+	return "\x1b]8;;" + uri + "\x1b\\" + text + linkEnd
 }
 
 // UnquoteRC removes the `\e` escape used in readline .inputrc
@@ -191,11 +375,21 @@ func DisableEffects() {
 	BgWhiteBright = ""
 }
 
-const ansi = "[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))"
+// Operating system commands (eg. OSC 8 hyperlinks) are terminated either by BEL or ST.
+const ansi = "\u001B\\][^\u0007\u001B]*(?:\u0007|\u001B\\\\)|[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))"
 
 var re = regexp.MustCompile(ansi)
 
+// linkEnd ends the current hyperlink, and link matches hyperlinks with their URI.
+const linkEnd = "\x1b]8;;\x1b\\"
+
+var link = regexp.MustCompile("\x1b\\]8;[^;\x07\x1b]*;([^\x07\x1b]*)(?:\x07|\x1b\\\\)")
+
 // Strip removes all ANSI escaped color sequences in a string.
 func Strip(str string) string {
+	if strings.IndexByte(str, '\x1b') < 0 && !strings.ContainsRune(str, '\u009b') {
+		return str
+	}
+
 	return re.ReplaceAllString(str, "")
 }
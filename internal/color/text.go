@@ -0,0 +1,141 @@
+package color
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Text is a string which may contain escape sequences (styles, hyperlinks), and
+// whose methods measure, cut and pad it according to its printable characters
+// only, as displayed in a terminal: in columns, wide characters using two of them.
+// Cutting a text keeps all its escape sequences (including those found past the
+// cut), so that its styles still apply to what remains, and are reset as before.
+type Text string
+
+// Width returns the number of terminal columns used by the text.
+func (t Text) Width() int {
+	if printableASCII(string(t)) {
+		return len(t)
+	}
+
+	return uniseg.StringWidth(t.Plain())
+}
+
+// Plain returns the text without its escape sequences.
+func (t Text) Plain() string {
+	return Strip(string(t))
+}
+
+// Trim returns the first printable characters of the text fitting in width
+// terminal columns, or the text itself if it fits.
+func (t Text) Trim(width int) Text {
+	if t.Width() <= width {
+		return t
+	}
+
+	return t.cut(width, "")
+}
+
+// Truncate returns the text unchanged if it fits in width terminal columns,
+// or else its first printable characters fitting in width-1 columns followed
+// by an ellipsis, after which styles are reset.
+func (t Text) Truncate(width int) Text {
+	if width <= 0 {
+		return ""
+	}
+
+	if t.Width() <= width {
+		return t
+	}
+
+	return t.cut(width-1, "…") + Text(Reset)
+}
+
+// Slice returns the printable characters of the text displayed in the width
+// terminal columns starting at column from, padded with spaces where a wide
+// character is only partially in these columns, so that the styles in effect
+// before the first returned character still apply.
+func (t Text) Slice(from, width int) Text {
+	var sliced strings.Builder
+
+	used, remain := 0, string(t)
+
+	for remain != "" {
+		if loc := re.FindStringIndex(remain); loc != nil && loc[0] == 0 {
+			sliced.WriteString(remain[:loc[1]])
+			remain = remain[loc[1]:]
+
+			continue
+		}
+
+		cluster, rest, clusterWidth, _ := uniseg.FirstGraphemeClusterInString(remain, -1)
+		remain = rest
+
+		start, end := used, used+clusterWidth
+		used = end
+
+		switch {
+		case start >= from && end <= from+width:
+			sliced.WriteString(cluster)
+		case end > from && start < from+width:
+			sliced.WriteString(strings.Repeat(" ", min(end, from+width)-max(start, from)))
+		}
+	}
+
+	return Text(sliced.String())
+}
+
+// Pad returns the text followed by as many spaces as
+// needed for it to use at least width terminal columns.
+func (t Text) Pad(width int) Text {
+	return t + Text(strings.Repeat(" ", max(width-t.Width(), 0)))
+}
+
+// PadLeft returns the text preceded by as many spaces as
+// needed for it to use at least width terminal columns.
+func (t Text) PadLeft(width int) Text {
+	return Text(strings.Repeat(" ", max(width-t.Width(), 0))) + t
+}
+
+// cut returns the first printable characters of the text fitting in
+// width columns, followed by the given string if any has been dropped.
+func (t Text) cut(width int, ellipsis string) Text {
+	var cut strings.Builder
+
+	used, remain := 0, string(t)
+
+	for remain != "" {
+		if loc := re.FindStringIndex(remain); loc != nil && loc[0] == 0 {
+			cut.WriteString(remain[:loc[1]])
+			remain = remain[loc[1]:]
+
+			continue
+		}
+
+		cluster, rest, clusterWidth, _ := uniseg.FirstGraphemeClusterInString(remain, -1)
+		remain = rest
+
+		if used+clusterWidth <= width {
+			cut.WriteString(cluster)
+			used += clusterWidth
+		} else if used <= width {
+			cut.WriteString(ellipsis)
+			used = width + 1
+		}
+	}
+
+	return Text(cut.String())
+}
+
+// printableASCII returns true if the string only has printable ASCII characters,
+// each using one column, so that measuring it needs no grapheme segmentation.
+func printableASCII(str string) bool {
+	for i := 0; i < len(str); i++ {
+		if str[i] < ' ' || str[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
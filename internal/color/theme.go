@@ -0,0 +1,262 @@
+package color
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// Theme holds the styles (SGR sequences) of all elements displayed by the shell,
+// except those provided by the application (prompts, highlighters, completions).
+type Theme struct {
+	// Hints and messages
+	Hint    string // Informational hints (usages, arguments, registers, etc).
+	Title   string // Titles of lists in hints (kill ring, registers, etc).
+	Error   string // Error messages.
+	Warning string // Warning messages.
+	Success string // Success messages.
+	Isearch string // Incremental search prompts, and history source names.
+
+	// Input line
+	Autosuggest string // Autosuggested remainder of the line.
+	Selection   string // Visual selections.
+	Surround    string // Characters selected by surround commands.
+	Matcher     string // Matching parenthesis (blink-matching-paren).
+	Comment     string // Commented lines (comment-begin).
+
+	// Completions
+	CompletionTag      string // Titles of completion groups.
+	CompletionPrefix   string // Prefix of candidates (colored-completion-prefix).
+	CompletionSelected string // Currently selected candidate.
+	CompletionMatch    string // Characters matched by incremental or fuzzy searches.
+	DescriptionMatch   string // Descriptions matched by incremental searches.
+	CompletionMore     string // Count of completion rows not displayed.
+
+	// Shell syntax highlighter
+	Command  string // Command names.
+	Flag     string // Command flags.
+	String   string // Quoted strings.
+	Operator string // Control and redirection operators.
+}
+
+// Styles is the theme used by a shell, with its colors converted to the
+// color depth supported by its terminal. Each shell has its own styles,
+// shared by all its components.
+type Styles struct {
+	Theme
+	depth int // Number of colors supported by the terminal.
+}
+
+// NewStyles returns styles using the default (dark) theme, with true colors.
+func NewStyles() *Styles {
+	return &Styles{Theme: Dark(), depth: TrueColor}
+}
+
+// Dark returns the default theme, meant for dark terminal backgrounds.
+func Dark() Theme {
+	return Theme{
+		Hint:    Dim,
+		Title:   Bold + FgBlue,
+		Error:   FgRed,
+		Warning: FgYellow,
+		Success: FgGreen,
+		Isearch: Bold + FgCyan,
+
+		Autosuggest: Dim + fg256("242"),
+		Selection:   BgBlue,
+		Surround:    BgRed,
+		Matcher:     bg256("240"),
+		Comment:     fg256("244"),
+
+		CompletionTag:      Bold + FgYellow,
+		CompletionPrefix:   Bold + FgBlue,
+		CompletionSelected: bg256("255"),
+		CompletionMatch:    bg256("244"),
+		DescriptionMatch:   bg256("238") + Underscore,
+		CompletionMore:     Dim + FgYellow,
+
+		Command:  Bold + FgGreen,
+		Flag:     FgCyan,
+		String:   FgYellow,
+		Operator: FgMagenta,
+	}
+}
+
+// Light returns a theme meant for light terminal backgrounds.
+func Light() Theme {
+	return Theme{
+		Hint:    Dim,
+		Title:   Bold + FgBlue,
+		Error:   fg256("160"),
+		Warning: fg256("166"),
+		Success: fg256("28"),
+		Isearch: Bold + fg256("30"),
+
+		Autosuggest: fg256("247"),
+		Selection:   bg256("153"),
+		Surround:    bg256("217"),
+		Matcher:     bg256("250"),
+		Comment:     fg256("245"),
+
+		CompletionTag:      Bold + fg256("130"),
+		CompletionPrefix:   Bold + FgBlue,
+		CompletionSelected: bg256("250"),
+		CompletionMatch:    bg256("223"),
+		DescriptionMatch:   bg256("223") + Underscore,
+		CompletionMore:     fg256("130"),
+
+		Command:  Bold + fg256("28"),
+		Flag:     fg256("30"),
+		String:   fg256("136"),
+		Operator: fg256("127"),
+	}
+}
+
+// ThemeNamed returns the preset theme with the given name (dark or
+// light), or the default (dark) one if there is no such theme.
+func ThemeNamed(name string) Theme {
+	if name == "light" {
+		return Light()
+	}
+
+	return Dark()
+}
+
+// UseTheme sets the theme used by the shell, converting its colors
+// to the ones supported by the terminal (see UseDepth).
+func (s *Styles) UseTheme(theme Theme) {
+	for _, style := range theme.elements() {
+		*style = s.Degrade(*style)
+	}
+
+	s.Theme = theme
+}
+
+// Set sets the style of a theme element, named in lowercase words separated
+// with dashes (eg. completion-tag), returning false if there is no such one.
+func (t *Theme) Set(name, style string) bool {
+	element, found := t.elements()[name]
+	if found {
+		*element = style
+	}
+
+	return found
+}
+
+// ThemeElements returns the names of all the elements of a theme.
+func ThemeElements() []string {
+	var theme Theme
+
+	names := make([]string, 0, len(theme.elements()))
+	for name := range theme.elements() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (t *Theme) elements() map[string]*string {
+	return map[string]*string{
+		"hint":                &t.Hint,
+		"title":               &t.Title,
+		"error":               &t.Error,
+		"warning":             &t.Warning,
+		"success":             &t.Success,
+		"isearch":             &t.Isearch,
+		"autosuggest":         &t.Autosuggest,
+		"selection":           &t.Selection,
+		"surround":            &t.Surround,
+		"matcher":             &t.Matcher,
+		"comment":             &t.Comment,
+		"completion-tag":      &t.CompletionTag,
+		"completion-prefix":   &t.CompletionPrefix,
+		"completion-selected": &t.CompletionSelected,
+		"completion-match":    &t.CompletionMatch,
+		"description-match":   &t.DescriptionMatch,
+		"completion-more":     &t.CompletionMore,
+		"command":             &t.Command,
+		"flag":                &t.Flag,
+		"string":              &t.String,
+		"operator":            &t.Operator,
+	}
+}
+
+// Color depths (number of colors) supported by terminals.
+const (
+	Colors16  = 16
+	Colors256 = 256
+	TrueColor = 1 << 24
+)
+
+// UseDepth sets the number of colors supported by the terminal: colors
+// of the theme, and those formatted with Fmt or Degrade, are converted
+// to the closest ones supported.
+func (s *Styles) UseDepth(colors int) {
+	s.depth = colors
+}
+
+// DepthNamed returns the color depth with the given name (truecolor,
+// 256 or 16), or the one detected from the environment if it is auto,
+// or any other value.
+func DepthNamed(name string) int {
+	switch name {
+	case "truecolor", "24bit":
+		return TrueColor
+	case "256":
+		return Colors256
+	case "16":
+		return Colors16
+	default:
+		return DetectDepth()
+	}
+}
+
+// DetectDepth returns the color depth of the terminal, from the COLORTERM
+// and TERM environment variables. Since many terminals support true colors
+// without advertising them, only terminals known for supporting fewer ones
+// (eg. TERM=xterm-256color or linux) have a lower depth.
+func DetectDepth() int {
+	if colorterm := os.Getenv("COLORTERM"); colorterm == "truecolor" || colorterm == "24bit" {
+		return TrueColor
+	}
+
+	term := os.Getenv("TERM")
+
+	switch {
+	case strings.Contains(term, "256color"):
+		return Colors256
+	case strings.Contains(term, "16color"), term == "linux", term == "ansi", strings.HasPrefix(term, "vt"):
+		return Colors16
+	default:
+		return TrueColor
+	}
+}
+
+// Fmt formats a color code as an ANSI escaped color sequence,
+// converted to the closest color supported by the terminal.
+func (s *Styles) Fmt(color string) string {
+	return s.Degrade(Fmt(color))
+}
+
+// Degrade converts the colors used in the SGR sequences of
+// a string to the closest ones supported by the terminal.
+func (s *Styles) Degrade(str string) string {
+	switch {
+	case s.depth <= Colors16:
+		return To16(str)
+	case s.depth <= Colors256:
+		return To256(str)
+	default:
+		return str
+	}
+}
+
+func fg256(code string) string {
+	return SGRStart + Fg + code + SGREnd
+}
+
+func bg256(code string) string {
+	return SGRStart + Bg + code + SGREnd
+}
@@ -0,0 +1,182 @@
+package color
+
+import (
+	"testing"
+)
+
+func TestTo256(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		want string
+	}{
+		{name: "True color", str: "\x1b[38;2;255;135;0mx", want: "\x1b[38;5;208mx"},
+		{name: "Gray", str: "\x1b[48;2;128;128;130m", want: "\x1b[48;5;244m"},
+		{name: "Mixed parameters", str: "\x1b[1;48;5;240;38;2;0;0;0m", want: "\x1b[1;48;5;240;38;5;16m"},
+		{name: "256 colors", str: "\x1b[38;05;242m", want: "\x1b[38;05;242m"},
+		{name: "Base colors", str: "\x1b[1;31mx\x1b[0m", want: "\x1b[1;31mx\x1b[0m"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := To256(test.str); got != test.want {
+				t.Errorf("To256(%q) = %q, want %q", test.str, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTo16(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		want string
+	}{
+		{name: "256 colors", str: "\x1b[38;5;208mx", want: "\x1b[93mx"},
+		{name: "256 base colors", str: "\x1b[48;05;4m\x1b[38;5;9m", want: "\x1b[44m\x1b[91m"},
+		{name: "True color", str: "\x1b[1;38;2;215;0;0m", want: "\x1b[1;91m"},
+		{name: "Dark gray", str: "\x1b[38;2;100;100;100m", want: "\x1b[90m"},
+		{name: "Black", str: "\x1b[48;2;10;10;10m", want: "\x1b[40m"},
+		{name: "Base colors", str: "\x1b[1;31mx\x1b[0m", want: "\x1b[1;31mx\x1b[0m"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := To16(test.str); got != test.want {
+				t.Errorf("To16(%q) = %q, want %q", test.str, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDegrade(t *testing.T) {
+	str := "\x1b[38;2;255;135;0m"
+
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{depth: TrueColor, want: str},
+		{depth: Colors256, want: "\x1b[38;5;208m"},
+		{depth: Colors16, want: "\x1b[93m"},
+	}
+
+	for _, test := range tests {
+		styles := NewStyles()
+		styles.UseDepth(test.depth)
+
+		if got := styles.Degrade(str); got != test.want {
+			t.Errorf("Degrade() with %d colors = %q, want %q", test.depth, got, test.want)
+		}
+
+		if got := styles.Fmt("38;2;255;135;0"); got != test.want {
+			t.Errorf("Fmt() with %d colors = %q, want %q", test.depth, got, test.want)
+		}
+	}
+}
+
+func TestDepthNamed(t *testing.T) {
+	t.Setenv("COLORTERM", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	tests := map[string]int{
+		"truecolor": TrueColor,
+		"24bit":     TrueColor,
+		"256":       Colors256,
+		"16":        Colors16,
+		"auto":      Colors256,
+		"":          Colors256,
+	}
+
+	for name, want := range tests {
+		if got := DepthNamed(name); got != want {
+			t.Errorf("DepthNamed(%q) = %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestDetectDepth(t *testing.T) {
+	tests := []struct {
+		colorterm string
+		term      string
+		want      int
+	}{
+		{colorterm: "truecolor", term: "linux", want: TrueColor},
+		{colorterm: "24bit", term: "xterm-256color", want: TrueColor},
+		{term: "xterm-256color", want: Colors256},
+		{term: "screen-256color", want: Colors256},
+		{term: "rxvt-16color", want: Colors16},
+		{term: "linux", want: Colors16},
+		{term: "vt100", want: Colors16},
+		{term: "xterm-kitty", want: TrueColor},
+		{want: TrueColor},
+	}
+
+	for _, test := range tests {
+		t.Setenv("COLORTERM", test.colorterm)
+		t.Setenv("TERM", test.term)
+
+		if got := DetectDepth(); got != test.want {
+			t.Errorf("DetectDepth() with COLORTERM=%q TERM=%q = %d, want %d", test.colorterm, test.term, got, test.want)
+		}
+	}
+}
+
+func TestTheme_Set(t *testing.T) {
+	theme := Dark()
+
+	if !theme.Set("completion-tag", FgRed) || theme.CompletionTag != FgRed {
+		t.Errorf("Set(completion-tag) did not set the style: %q", theme.CompletionTag)
+	}
+
+	if theme.Set("prompt", FgRed) {
+		t.Error("Set(prompt) = true, want false")
+	}
+
+	// All elements can be set.
+	names := ThemeElements()
+	if len(names) != 21 || names[0] != "autosuggest" {
+		t.Errorf("ThemeElements() = %q, want 21 sorted names", names)
+	}
+
+	for _, name := range names {
+		if !theme.Set(name, Reverse) {
+			t.Errorf("Set(%q) = false, want true", name)
+		}
+	}
+
+	for name, style := range theme.elements() {
+		if *style != Reverse {
+			t.Errorf("style of %s = %q, want %q", name, *style, Reverse)
+		}
+	}
+}
+
+func TestThemeNamed(t *testing.T) {
+	if ThemeNamed("light") != Light() {
+		t.Error("ThemeNamed(light) is not the light theme")
+	}
+
+	if ThemeNamed("dark") != Dark() || ThemeNamed("solarized") != Dark() {
+		t.Error("ThemeNamed(dark|solarized) is not the dark theme")
+	}
+}
+
+func TestStyles_UseTheme(t *testing.T) {
+	styles := NewStyles()
+	styles.UseDepth(Colors16)
+
+	// Colors are converted to the depth of the terminal.
+	styles.UseTheme(Light())
+
+	if styles.Error != "\x1b[91m" || styles.Hint != Dim || styles.Title != Bold+FgBlue {
+		t.Errorf("Styles = {Error: %q, Hint: %q, Title: %q}, want 16 colors", styles.Error, styles.Hint, styles.Title)
+	}
+
+	styles.UseDepth(TrueColor)
+	styles.UseTheme(Light())
+
+	if styles.Theme != Light() {
+		t.Errorf("Styles with true colors = %+v, want the light theme", styles.Theme)
+	}
+}
@@ -1,5 +1,12 @@
 package completion
 
+import "github.com/reeflective/readline/internal/ui"
+
+// CursorMarker is a placeholder that can be used in a candidate insertion
+// template: once the template is inserted, the cursor is placed where the
+// marker was found, instead of at the end of the inserted text.
+const CursorMarker = "%|%"
+
 // Completer is a function generating completions.
 // This is generally used so that a given completer function
 // (history, registers, etc) can be cached and reused by the engine.
@@ -13,6 +20,19 @@ type Candidate struct {
 	Style       string // An arbitrary string of color/text effects to use when displaying the completion.
 	Tag         string // All completions with the same tag are grouped together and displayed under the tag heading.
 
+	// Insert, when not empty, is inserted in the line instead of Value (which is
+	// still used for filtering and display). It may span several shell words, and
+	// may contain a CursorMarker, in which case the cursor is placed at its position.
+	Insert string
+
+	// Icon is displayed before the candidate in the menu (eg. a nerd-font glyph), and
+	// Annotation after it, aligned to the right of its column (eg. a file size or a
+	// git status). Both are styled independently of the candidate, with their own style.
+	Icon            string
+	IconStyle       string
+	Annotation      string
+	AnnotationStyle string
+
 	// A list of runes that are automatically trimmed when a space or a non-nil character is
 	// inserted immediately after the completion. This is used for slash-autoremoval in path
 	// completions, comma-separated completions, etc.
@@ -20,6 +40,8 @@ type Candidate struct {
 
 	displayLen int // Real length of the displayed candidate, that is not counting escaped sequences.
 	descLen    int
+	measured   bool // The widths above have been computed.
+	score      int  // Match score against the current prefix, when sorting by score.
 }
 
 // Values is used internally to hold all completion candidates and their associated data.
@@ -29,10 +51,12 @@ type Values struct {
 	NoSpace  SuffixMatcher
 	Usage    string
 	ListLong map[string]bool
-	NoSort   map[string]bool
+	Sort     map[string]SortStrategy
+	SortLess map[string]func(a, b Candidate) bool
 	ListSep  map[string]string
 	Pad      map[string]bool
 	Escapes  map[string]bool
+	Previews map[string]func(value string) *ui.Image
 
 	// Initially this will be set to the part of the current word
 	// from the beginning of the word up to the position of the cursor.
@@ -44,6 +68,26 @@ type Values struct {
 	// It may be altered so that inserted completions don't overwrite
 	// entirely any suffix when completing in the middle of a word.
 	SUFFIX string
+
+	// When Replace is true, inserted candidates replace the line region
+	// between the ReplaceStart and ReplaceEnd offsets, instead of the
+	// current word up to the cursor.
+	Replace      bool
+	ReplaceStart int
+	ReplaceEnd   int
+
+	// ListOnly, when true, lists the candidates without inserting a unique
+	// one automatically: they are only inserted when selected in the menu.
+	ListOnly bool
+
+	// NoFilter, when true, does not filter candidates against the prefix,
+	// which the completer has already matched them against in its own way
+	// (their common prefix is then not inserted either).
+	NoFilter bool
+
+	// Suggest is an optional inline suggestion completing the line,
+	// displayed after it like history autosuggestions.
+	Suggest string
 }
 
 // AddRaw adds completion values in bulk.
@@ -51,7 +95,8 @@ func AddRaw(values []Candidate) Values {
 	return Values{
 		values:   RawValues(values),
 		ListLong: make(map[string]bool),
-		NoSort:   make(map[string]bool),
+		Sort:     make(map[string]SortStrategy),
+		SortLess: make(map[string]func(a, b Candidate) bool),
 		ListSep:  make(map[string]string),
 		Pad:      make(map[string]bool),
 	}
@@ -1,7 +1,6 @@
 package completion
 
 import (
-	"bufio"
 	"fmt"
 	"regexp"
 	"strings"
@@ -15,91 +14,125 @@ import (
 func Display(eng *Engine, maxRows int) {
 	eng.usedY = 0
 
-	defer fmt.Print(term.ClearScreenBelow)
+	defer fmt.Fprint(eng.term, term.ClearScreenBelow)
 
 	// The completion engine might be inactive but still having
 	// a non-empty list of completions. This is on purpose, as
 	// sometimes it's better to keep completions printed for a
 	// little more time. The engine itself is responsible for
 	// deleting those lists when it deems them useless.
-	if eng.Matches() == 0 || eng.skipDisplay {
-		fmt.Print(term.ClearLineAfter)
+	if !Displayed(eng) {
+		fmt.Fprint(eng.term, term.ClearLineAfter)
 		return
 	}
 
-	// The final completions string to print.
-	completions := term.ClearLineAfter
+	// The final completions string to print. Only the rows
+	// fitting in the visible page are laid out and highlighted.
+	var builder strings.Builder
+
+	builder.WriteString(term.ClearLineAfter)
+
+	start, end := eng.visibleRows(maxRows)
+	eng.pageStart = start
+	line := 0
 
 	for _, group := range eng.groups {
-		completions += eng.renderCompletions(group)
+		eng.renderCompletions(&builder, group, &line, start, end)
 	}
 
 	// Crop the completions so that it fits within our terminal
-	completions, eng.usedY = eng.cropCompletions(completions, maxRows)
+	completions, usedY := eng.cropCompletions(builder.String(), start)
+	eng.usedY = usedY
 
 	if completions != "" {
-		fmt.Print(completions)
+		fmt.Fprint(eng.term, completions)
 	}
 }
 
+// Displayed returns true if there are completions printed by Display.
+func Displayed(e *Engine) bool {
+	return e.Matches() > 0 && !e.skipDisplay
+}
+
 // Coordinates returns the number of terminal rows used
 // when displaying the completions with Display().
 func Coordinates(e *Engine) int {
 	return e.usedY
 }
 
-// renderCompletions renders all completions in a given list (with aliases or not).
-// The descriptions list argument is optional.
-func (e *Engine) renderCompletions(grp *group) string {
-	var builder strings.Builder
-
-	if len(grp.rows) == 0 {
-		return ""
+// renderCompletions renders the completions in a given list (with aliases or not)
+// that are comprised in the visible page, delimited by the start/end (excluded)
+// rows. The line argument is the index of the first row (including tag headers)
+// of the group, and is incremented with all the rows used by the group.
+func (e *Engine) renderCompletions(builder *strings.Builder, grp *group, line *int, start, end int) {
+	if len(grp.rows()) == 0 {
+		return
 	}
 
 	if grp.tag != "" {
-		tag := fmt.Sprintf("%s%s%s %s", color.Bold, color.FgYellow, grp.tag, color.Reset)
-		builder.WriteString(tag + term.ClearLineAfter + term.NewlineReturn)
+		if *line >= start && *line < end {
+			tag := fmt.Sprintf("%s%s %s", e.styles.CompletionTag, grp.tag, color.Reset)
+			builder.WriteString(tag + term.ClearLineAfter + term.NewlineReturn)
+		}
+
+		*line++
 	}
 
-	for rowIndex, row := range grp.rows {
+	// Only render the rows of the group comprised in the page.
+	first, last := start-*line, end-*line
+	*line += len(grp.rows())
+
+	if first < 0 {
+		first = 0
+	}
+
+	if last > len(grp.rows()) {
+		last = len(grp.rows())
+	}
+
+	for rowIndex := first; rowIndex < last; rowIndex++ {
 		for columnIndex := range grp.columnsWidth {
-			var value Candidate
+			builder.WriteString(e.renderCell(grp, rowIndex, columnIndex))
+		}
 
-			// If there are aliases, we might have no completions at the current
-			// coordinates, so just print the corresponding padding and return.
-			if len(row) > columnIndex {
-				value = row[columnIndex]
-			}
+		// We're done for this line.
+		builder.WriteString(term.ClearLineAfter + term.NewlineReturn)
+	}
+}
 
-			// Apply all highlightings to the displayed value:
-			// selection, prefixes, styles and other things,
-			padding := grp.getPad(value, columnIndex, false)
-			isSelected := rowIndex == grp.posY && columnIndex == grp.posX && grp.isCurrent
-			display := e.highlightDisplay(grp, value, padding, columnIndex, isSelected)
+// renderCell renders a candidate of a group row (padded to its column
+// width), with its description if the group is not an aliased one.
+func (e *Engine) renderCell(grp *group, rowIndex, columnIndex int) string {
+	row := grp.rows()[rowIndex]
 
-			builder.WriteString(display)
+	var value Candidate
 
-			// Add description if no aliases, or if done with them.
-			onLast := columnIndex == len(grp.columnsWidth)-1
-			if grp.aliased && onLast && value.Description == "" {
-				value = row[0]
-			}
+	// If there are aliases, we might have no completions at the current
+	// coordinates, so just print the corresponding padding and return.
+	if len(row) > columnIndex {
+		value = row[columnIndex]
+	}
 
-			if !grp.aliased || onLast {
-				grp.maxDescAllowed = grp.setMaximumSizes(columnIndex)
+	// Apply all highlightings to the displayed value:
+	// selection, prefixes, styles and other things,
+	padding := grp.getPad(value, columnIndex, false)
+	isSelected := rowIndex == grp.posY && columnIndex == grp.posX && grp.isCurrent
+	display := e.highlightDisplay(grp, value, padding, columnIndex, isSelected)
 
-				descPad := grp.getPad(value, columnIndex, true)
-				desc := e.highlightDesc(grp, value, descPad, rowIndex, columnIndex, isSelected)
-				builder.WriteString(desc)
-			}
-		}
+	// Add description if no aliases, or if done with them.
+	onLast := columnIndex == len(grp.columnsWidth)-1
+	if grp.aliased && onLast && value.Description == "" {
+		value = row[0]
+	}
 
-		// We're done for this line.
-		builder.WriteString(term.ClearLineAfter + term.NewlineReturn)
+	if !grp.aliased || onLast {
+		grp.maxDescAllowed = grp.setMaximumSizes(columnIndex)
+
+		descPad := grp.getPad(value, columnIndex, true)
+		display += e.highlightDesc(grp, value, descPad, rowIndex, columnIndex, isSelected)
 	}
 
-	return builder.String()
+	return display
 }
 
 func (e *Engine) highlightDisplay(grp *group, val Candidate, pad, col int, selected bool) (candidate string) {
@@ -108,37 +141,99 @@ func (e *Engine) highlightDisplay(grp *group, val Candidate, pad, col int, selec
 		return padSpace(pad)
 	}
 
-	reset := color.Fmt(val.Style)
+	reset := e.styles.Fmt(val.Style)
 	candidate, padded := grp.trimDisplay(val, pad, col)
 
-	if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected {
+	if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected && e.isearchField != isearchDescriptions {
 		match := e.IsearchRegex.FindString(candidate)
-		match = color.Fmt(color.Bg+"244") + match + color.Reset + reset
+		match = e.styles.CompletionMatch + match + color.Reset + reset
 		candidate = e.IsearchRegex.ReplaceAllLiteralString(candidate, match)
+	} else if e.fuzzySearching() && e.isearchBuf.Len() > 0 && !selected && e.isearchField != isearchDescriptions {
+		candidate = e.highlightFuzzy(candidate, reset)
 	}
 
 	if selected {
 		// If the comp is currently selected, overwrite any highlighting already applied.
 		userStyle := color.UnquoteRC(e.config.GetString("completion-selection-style"))
-		selectionHighlightStyle := color.Fmt(color.Bg+"255") + userStyle
-		candidate = selectionHighlightStyle + candidate
+		selectionHighlightStyle := e.styles.CompletionSelected + userStyle
+		candidate = selectionHighlightStyle + grp.iconSegment(val, selected) + candidate
 
 		if grp.aliased {
 			candidate += color.Reset
 		}
 	} else {
-		// Highlight the prefix if any and configured for it.
-		if e.config.GetBool("colored-completion-prefix") && e.prefix != "" {
+		// Dim the prefix common to all candidates, or highlight
+		// the current prefix if any and configured for it.
+		if e.config.GetBool("completion-prefix-dim") && e.common != "" && strings.HasPrefix(candidate, e.common) {
+			candidate = color.Dim + e.common + color.DimReset + reset + strings.TrimPrefix(candidate, e.common)
+		} else if e.config.GetBool("colored-completion-prefix") && e.prefix != "" {
 			if prefixMatch, err := regexp.Compile(fmt.Sprintf("^%s", e.prefix)); err == nil {
-				prefixColored := color.Bold + color.FgBlue + e.prefix + color.BoldReset + color.FgDefault + reset
+				prefixColored := e.styles.CompletionPrefix + e.prefix + color.Reset + reset
 				candidate = prefixMatch.ReplaceAllString(candidate, prefixColored)
 			}
 		}
 
-		candidate = reset + candidate + color.Reset
+		candidate = grp.iconSegment(val, selected) + reset + candidate + color.Reset
+	}
+
+	return candidate + grp.annotationSegment(val, padded, selected)
+}
+
+// highlightFuzzy highlights the characters of a candidate matched by the scorer
+// against the isearch buffer, while skipping over any of its escape sequences.
+func (e *Engine) highlightFuzzy(candidate, reset string) string {
+	_, positions := e.scorer().Score(string(*e.isearchBuf), color.Strip(candidate))
+	if len(positions) == 0 {
+		return candidate
+	}
+
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+
+	highlight := e.styles.CompletionMatch
+	runes := []rune(candidate)
+	visible := 0
+
+	var builder strings.Builder
+
+	for i := 0; i < len(runes); i++ {
+		// Copy escape sequences as is.
+		if runes[i] == '\x1b' {
+			end := i + 1
+
+			switch {
+			case end < len(runes) && runes[end] == '[':
+				end++
+
+				for end < len(runes) && (runes[end] < 0x40 || runes[end] > 0x7e) {
+					end++
+				}
+			case end < len(runes) && runes[end] == ']':
+				// Operating system commands (eg. hyperlinks) end with BEL or ST.
+				for end < len(runes) && runes[end] != '\a' && !(runes[end] == '\\' && runes[end-1] == '\x1b') {
+					end++
+				}
+			}
+
+			end = min(end, len(runes)-1)
+			builder.WriteString(string(runes[i : end+1]))
+			i = end
+
+			continue
+		}
+
+		if matched[visible] {
+			builder.WriteString(highlight + string(runes[i]) + color.Reset + reset)
+		} else {
+			builder.WriteRune(runes[i])
+		}
+
+		visible++
 	}
 
-	return candidate + padded
+	return builder.String()
 }
 
 func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, selected bool) (desc string) {
@@ -149,11 +244,12 @@ func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, sel
 	desc, padded := grp.trimDesc(val, pad)
 
 	// If the next row has the same completions, replace the description with our hint.
-	if len(grp.rows) > row+1 && grp.rows[row+1][0].Description == val.Description {
+	if len(grp.rows()) > row+1 && grp.rows()[row+1][0].Description == val.Description {
 		desc = "|"
-	} else if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected {
+	} else if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected && e.isearchField != isearchValues {
+		// Description matches are highlighted differently than values ones.
 		match := e.IsearchRegex.FindString(desc)
-		match = color.Fmt(color.Bg+"244") + match + color.Reset + color.Dim
+		match = e.styles.DescriptionMatch + match + color.Reset + color.Dim
 		desc = e.IsearchRegex.ReplaceAllLiteralString(desc, match)
 	}
 
@@ -161,7 +257,7 @@ func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, sel
 	// Replace all background reset escape sequences in it, to ensure correct display.
 	if row == grp.posY && col == grp.posX && grp.isCurrent && !grp.aliased {
 		userDescStyle := color.UnquoteRC(e.config.GetString("completion-selection-style"))
-		selectionHighlightStyle := color.Fmt(color.Bg+"255") + userDescStyle
+		selectionHighlightStyle := e.styles.CompletionSelected + userDescStyle
 		desc = strings.ReplaceAll(desc, color.BgDefault, userDescStyle)
 		desc = selectionHighlightStyle + desc
 	}
@@ -171,94 +267,39 @@ func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, sel
 	return compDescStyle + desc + color.Reset + padded
 }
 
-// cropCompletions - When the user cycles through a completion list longer
-// than the console MaxTabCompleterRows value, we crop the completions string
-// so that "global" cycling (across all groups) is printed correctly.
-func (e *Engine) cropCompletions(comps string, maxRows int) (cropped string, usedY int) {
+// visibleRows returns the range of completion rows (including group tags) that fit
+// in the available space: when the user cycles through a completion list longer than
+// the console MaxTabCompleterRows value, the page is scrolled so that "global" cycling
+// (across all groups) always shows the selected candidate.
+func (e *Engine) visibleRows(maxRows int) (start, end int) {
 	// Get the current absolute candidate position
 	absPos := e.getAbsPos()
 
-	// Scan the completions for cutting them at newlines
-	scanner := bufio.NewScanner(strings.NewReader(comps))
-
-	// If absPos < MaxTabCompleterRows, cut below MaxTabCompleterRows and return
+	// If absPos < MaxTabCompleterRows, cut below MaxTabCompleterRows.
 	if absPos < maxRows-1 {
-		return e.cutCompletionsBelow(scanner, maxRows)
+		return 0, maxRows - 1
 	}
 
-	// If absolute > MaxTabCompleterRows, cut above and below and return
+	// If absolute > MaxTabCompleterRows, cut above and below.
 	//      -> This includes de facto when we tabCompletionReverse
-	if absPos >= maxRows-1 {
-		return e.cutCompletionsAboveBelow(scanner, maxRows, absPos)
-	}
-
-	return
+	return absPos - maxRows + 2, absPos + 1
 }
 
-func (e *Engine) cutCompletionsBelow(scanner *bufio.Scanner, maxRows int) (string, int) {
-	var count int
-	var cropped string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if count < maxRows-1 {
-			cropped += line + term.NewlineReturn
-			count++
-		} else {
-			break
-		}
-	}
-
-	cropped = strings.TrimSuffix(cropped, term.NewlineReturn)
-
-	// Add hint for remaining completions, if any.
-	_, used := e.completionCount()
-	remain := used - count
-
-	if remain <= 0 {
-		return cropped, count - 1
-	}
-
-	cropped += fmt.Sprintf(term.NewlineReturn+color.Dim+color.FgYellow+" %d more completion rows... (scroll down to show)"+color.Reset, remain)
-
-	return cropped, count
-}
-
-func (e *Engine) cutCompletionsAboveBelow(scanner *bufio.Scanner, maxRows, absPos int) (string, int) {
-	cutAbove := absPos - maxRows + 1
-
-	var cropped string
-	var count int
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if count <= cutAbove {
-			count++
-
-			continue
-		}
-
-		if count > cutAbove && count <= absPos {
-			cropped += line + term.NewlineReturn
-			count++
-		} else {
-			break
-		}
-	}
-
-	cropped = strings.TrimSuffix(cropped, term.NewlineReturn)
-	count -= cutAbove + 1
+// cropCompletions adds a hint for remaining completion rows below the rendered
+// page (which starts at the given row), and returns the number of rows it uses.
+func (e *Engine) cropCompletions(comps string, start int) (cropped string, usedY int) {
+	count := strings.Count(comps, term.NewlineReturn)
+	cropped = strings.TrimSuffix(comps, term.NewlineReturn)
 
 	// Add hint for remaining completions, if any.
 	_, used := e.completionCount()
-	remain := used - (maxRows + cutAbove)
+	remain := used - (start + count)
 
 	if remain <= 0 {
 		return cropped, count - 1
 	}
 
-	cropped += fmt.Sprintf(term.NewlineReturn+color.Dim+color.FgYellow+" %d more completion rows... (scroll down to show)"+color.Reset, remain)
+	cropped += fmt.Sprintf(term.NewlineReturn+e.styles.CompletionMore+" %d more completion rows... (scroll down to show)"+color.Reset, remain)
 
 	return cropped, count
 }
@@ -4,18 +4,25 @@ import (
 	"regexp"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/strutil"
+	"github.com/reeflective/readline/internal/term"
 	"github.com/reeflective/readline/internal/ui"
 )
 
 // Engine is responsible for all completion tasks: generating, computing,
 // displaying and updating completion values and inserted candidates.
 type Engine struct {
+	term          *term.Terminal  // The terminal on which completions are displayed.
 	config        *inputrc.Config // The inputrc contains options relative to completion.
 	cached        Completer       // A cached completer function to use when updating.
 	autoCompleter Completer       // Completer used by things like autocomplete
 	hint          *ui.Hint        // The completions can feed hint/usage messages
+	bell          *ui.Bell        // Rung when there are no matches.
+	score         Scorer          // Matches and ranks candidates (fuzzy completion/isearch, sorting).
+	styles        *color.Styles   // The theme of the shell, used by hints and candidates.
 
 	// Line parameters
 	keys       *core.Keys      // The input keys reader
@@ -31,13 +38,24 @@ type Engine struct {
 	sm          SuffixMatcher // The suffix matcher is kept for removal after actually inserting the candidate.
 	selected    Candidate     // The currently selected item, not yet a real part of the input line.
 	prefix      string        // The current tab completion prefix against which to build candidates
+	common      string        // The longest prefix common to all generated candidates.
 	suffix      string        // The current word suffix
 	inserted    []rune        // The selected candidate (inserted in line) without prefix or suffix.
+	region      []int         // An optional line region (start/end) to be replaced by candidates.
+	escaped     bool          // Escape shell special characters in inserted candidates.
+	listOnly    bool          // Don't insert a unique candidate automatically.
 	usedY       int           // Comprehensive size offset (terminal rows) of the currently built completions.
+	pageStart   int           // The first row (including group tags) of the displayed completions page.
 	auto        bool          // Is the engine autocompleting ?
 	autoForce   bool          // Special autocompletion mode (isearch-style)
 	skipDisplay bool          // Don't display completions if there are some.
 
+	// Inline suggestion
+	suggestion  string // The last suggestion of the completer,
+	suggestLine string // computed for this line,
+	suggestPos  int    // with the cursor at this position.
+	suggested   bool   // A suggestion has been computed (maybe empty).
+
 	// Incremental search
 	IsearchRegex       *regexp.Regexp // Holds the current search regex match
 	isearchBuf         *core.Line     // The isearch minibuffer
@@ -50,15 +68,20 @@ type Engine struct {
 	isearchStartBuf    string         // The buffer before starting isearch
 	isearchStartCursor int            // The cursor position before starting isearch
 	isearchLast        string         // The last non-incremental buffer.
+	searchLast         string         // The last pattern searched in history.
 	isearchModeExit    keymap.Mode    // The main keymap to restore after exiting isearch
+	isearchField       isearchField   // The candidate fields matched by the search.
 }
 
 // NewEngine initializes a new completion engine with the shell operating parameters.
-func NewEngine(h *ui.Hint, km *keymap.Engine, o *inputrc.Config) *Engine {
+func NewEngine(t *term.Terminal, h *ui.Hint, b *ui.Bell, km *keymap.Engine, st *color.Styles, o *inputrc.Config) *Engine {
 	return &Engine{
+		term:   t,
 		config: o,
 		hint:   h,
+		bell:   b,
 		keymap: km,
+		styles: st,
 	}
 }
 
@@ -105,18 +128,28 @@ func (e *Engine) GenerateWith(completer Completer) {
 	e.Generate(e.cached())
 }
 
+// Cached returns the completer last used to generate completions, if any.
+func (e *Engine) Cached() Completer {
+	return e.cached
+}
+
 // SkipDisplay avoids printing completions below the
 // input line, but still enables cycling through them.
 func (e *Engine) SkipDisplay() {
 	e.skipDisplay = true
 }
 
+// ShowDisplay prints completions again after a call to SkipDisplay().
+func (e *Engine) ShowDisplay() {
+	e.skipDisplay = false
+}
+
 // Select moves the completion selector by some X or Y value,
 // and updates the inserted candidate in the input line.
 func (e *Engine) Select(row, column int) {
 	grp := e.currentGroup()
 
-	if grp == nil || len(grp.rows) == 0 {
+	if grp == nil || len(grp.rows()) == 0 {
 		return
 	}
 
@@ -220,6 +253,7 @@ func (e *Engine) Cancel(inserted, cached bool) {
 // All those steps are performed whether or not the engine is active.
 // If revertLine is true, the line will be reverted to its original state.
 func (e *Engine) ResetForce() {
+	e.suggested = false
 	e.Cancel(!e.autoForce, true)
 	e.ClearMenu(true)
 
@@ -274,13 +308,160 @@ func (e *Engine) IsInserting() bool {
 	return e.selected.Value != ""
 }
 
+// Preview returns the preview of the selected candidate, if any,
+// to be displayed in the hint section while it is selected.
+func (e *Engine) Preview() *ui.Image {
+	grp := e.currentGroup()
+	if !e.IsInserting() || grp == nil || grp.preview == nil {
+		return nil
+	}
+
+	return grp.preview(e.selected.Value)
+}
+
 // Matches returns the number of completion candidates
 // matching the current line/settings requirements.
 func (e *Engine) Matches() int {
-	comps, _ := e.completionCount()
+	var comps int
+
+	// Counting candidates does not need laying them out.
+	for _, grp := range e.groups {
+		comps += grp.count()
+	}
+
 	return comps
 }
 
+// Candidates returns all currently generated candidates, in their display order,
+// and the index of the one currently selected/inserted in this list (or -1).
+func (e *Engine) Candidates() (candidates []Candidate, selected int) {
+	selected = -1
+
+	for _, grp := range e.groups {
+		for posY, row := range grp.rows() {
+			for posX, val := range row {
+				if val.Value == "" && val.Display == "" {
+					continue
+				}
+
+				if grp.isCurrent && e.IsInserting() && grp.posY == posY && grp.posX == posX {
+					selected = len(candidates)
+				}
+
+				candidates = append(candidates, val)
+			}
+		}
+	}
+
+	return candidates, selected
+}
+
+// SelectIndex selects the candidate at the given index in the list returned
+// by Candidates(), and inserts it in the line like other selection moves do.
+// Returns false if there is no candidate at this index.
+func (e *Engine) SelectIndex(index int) bool {
+	if index < 0 {
+		return false
+	}
+
+	count := 0
+
+	for _, grp := range e.groups {
+		for posY, row := range grp.rows() {
+			for posX, val := range row {
+				if val.Value == "" && val.Display == "" {
+					continue
+				}
+
+				if count < index {
+					count++
+					continue
+				}
+
+				e.selectCell(grp, posX, posY)
+
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SelectAt selects the candidate displayed at the given row and column
+// (starting at 0) of the completions area, as printed by Display().
+// Returns false if there is no candidate at these coordinates.
+func (e *Engine) SelectAt(row, column int) bool {
+	if row < 0 || column < 0 || e.Matches() == 0 || e.skipDisplay {
+		return false
+	}
+
+	target := e.pageStart + row
+	line := 0
+
+	for _, grp := range e.groups {
+		if len(grp.rows()) == 0 {
+			continue
+		}
+
+		// Group tags are not selectable.
+		if grp.tag != "" {
+			if line == target {
+				return false
+			}
+
+			line++
+		}
+
+		if target >= line+len(grp.rows()) {
+			line += len(grp.rows())
+			continue
+		}
+
+		posY := target - line
+		width := 0
+
+		for posX := range grp.columnsWidth {
+			width += strutil.RealLength(e.renderCell(grp, posY, posX))
+			if column >= width {
+				continue
+			}
+
+			row := grp.rows()[posY]
+			if posX >= len(row) || (row[posX].Value == "" && row[posX].Display == "") {
+				return false
+			}
+
+			e.selectCell(grp, posX, posY)
+
+			return true
+		}
+
+		return false
+	}
+
+	return false
+}
+
+// selectCell makes a group the current one, selects one of its
+// candidates, and inserts it in the line like other selection moves.
+func (e *Engine) selectCell(grp *group, posX, posY int) {
+	e.adjustSelectKeymap()
+
+	if len(e.selected.Value) > 0 {
+		e.cancelCompletedLine()
+	}
+
+	for _, other := range e.groups {
+		other.isCurrent = false
+	}
+
+	grp.isCurrent = true
+	grp.posX, grp.posY = posX, posY
+
+	e.refreshLine()
+}
+
 // Line returns the relevant input line at the time this function is called:
 // if a candidate is currently selected, the line returned is the one containing
 // the candidate. If no candidate is selected, the normal input line is returned.
@@ -316,7 +497,9 @@ func (e *Engine) Autocomplete() {
 	if e.cached != nil {
 		e.prepare(e.cached())
 	} else if e.autoCompleter != nil {
-		e.prepare(e.autoCompleter())
+		comps := e.autoCompleter()
+		e.cacheSuggestion(comps.Suggest)
+		e.prepare(comps)
 	}
 }
 
@@ -326,6 +509,39 @@ func (e *Engine) AutocompleteForce() {
 	e.autoForce = true
 }
 
+// Suggestion returns the inline suggestion supplied by the completer for
+// the current line, if the completion-autosuggest option is enabled, the
+// cursor is at the end of the line and no completion is being inserted.
+// The completer is queried once per line and cursor position, and the
+// suggestion reused on refreshes, unless autocompletion has just done so.
+func (e *Engine) Suggestion() string {
+	if !e.config.GetBool("completion-autosuggest") || e.config.GetBool("low-bandwidth") || e.config.GetBool("screen-reader") {
+		return ""
+	}
+
+	if e.autoCompleter == nil || e.IsInserting() || e.line.Len() == 0 {
+		return ""
+	}
+
+	if e.cursor.Pos() < e.line.Len()-1 {
+		return ""
+	}
+
+	if !e.suggested || e.suggestLine != string(*e.line) || e.suggestPos != e.cursor.Pos() {
+		e.cacheSuggestion(e.autoCompleter().Suggest)
+	}
+
+	return e.suggestion
+}
+
+// cacheSuggestion stores the suggestion of the completer for the current line.
+func (e *Engine) cacheSuggestion(suggestion string) {
+	e.suggestion = suggestion
+	e.suggestLine = string(*e.line)
+	e.suggestPos = e.cursor.Pos()
+	e.suggested = true
+}
+
 // AutoCompleting returns true if the completion engine is an
 // autocompletion mode that has been triggered by a particular
 // command (like history-search-forward/backward).
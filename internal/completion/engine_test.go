@@ -0,0 +1,170 @@
+package completion
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/term"
+	"github.com/reeflective/readline/internal/ui"
+)
+
+const largeSetSize = 100000
+
+// newBenchEngine returns a completion engine with an empty input line,
+// displaying completions on a terminal discarding its output.
+func newBenchEngine(tb testing.TB) *Engine {
+	tb.Helper()
+
+	terminal := &term.Terminal{Output: io.Discard}
+	keys := core.NewKeys(strings.NewReader(""), terminal)
+	line := new(core.Line)
+	cursor := core.NewCursor(line)
+	styles := color.NewStyles()
+	selection := core.NewSelection(line, cursor, styles)
+
+	keymaps, config := keymap.NewEngine(terminal, keys, new(core.Iterations))
+	eng := NewEngine(terminal, ui.NewHint(terminal, styles, config), nil, keymaps, styles, config)
+	Init(eng, keys, line, cursor, selection, nil)
+
+	return eng
+}
+
+// largeValues returns a large set of completions, half of them described.
+func largeValues() Values {
+	vals := make([]Candidate, 0, largeSetSize)
+
+	for i := 0; i < largeSetSize; i++ {
+		cand := Candidate{Value: fmt.Sprintf("candidate-%d", i), Tag: "values"}
+		if i%2 == 0 {
+			cand.Description = fmt.Sprintf("description of candidate %d", i)
+		}
+
+		vals = append(vals, cand)
+	}
+
+	return AddRaw(vals)
+}
+
+func BenchmarkGenerateLarge(b *testing.B) {
+	eng := newBenchEngine(b)
+	vals := largeValues()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		eng.prepare(vals)
+	}
+}
+
+func BenchmarkDisplayLarge(b *testing.B) {
+	eng := newBenchEngine(b)
+	eng.prepare(largeValues())
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Display(eng, 50)
+	}
+}
+
+func BenchmarkDisplayLargeSelected(b *testing.B) {
+	eng := newBenchEngine(b)
+	eng.prepare(largeValues())
+
+	// Select a candidate far down the list.
+	eng.groups[0].isCurrent = true
+	eng.groups[0].posX, eng.groups[0].posY = 0, len(eng.groups[0].rows())/2
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Display(eng, 50)
+	}
+}
+
+func TestEngine_LazyLayout(t *testing.T) {
+	eng := newBenchEngine(t)
+	eng.prepare(largeValues())
+
+	// Candidates are counted without being laid out.
+	if got := eng.Matches(); got != largeSetSize {
+		t.Fatalf("Matches() = %d, want %d", got, largeSetSize)
+	}
+
+	for _, grp := range eng.groups {
+		if grp.grid != nil {
+			t.Fatalf("group %q laid out before being displayed", grp.tag)
+		}
+	}
+
+	Display(eng, 50)
+
+	// Displayed groups are laid out once, with widths computed beforehand.
+	for _, grp := range eng.groups {
+		if len(grp.grid) == 0 || grp.values != nil {
+			t.Errorf("group %q not laid out after being displayed", grp.tag)
+		}
+
+		if cand := grp.grid[0][0]; !cand.measured || cand.displayLen != len(cand.Display) {
+			t.Errorf("candidate %q width = %d, want %d", cand.Display, cand.displayLen, len(cand.Display))
+		}
+	}
+}
+
+func TestEngine_SelectIndex(t *testing.T) {
+	eng := newBenchEngine(t)
+	eng.prepare(AddRaw([]Candidate{{Value: "alpha"}, {Value: "beta"}, {Value: "gamma"}}))
+
+	if cands, selected := eng.Candidates(); len(cands) != 3 || selected != -1 {
+		t.Fatalf("Candidates() = %d candidates, selected %d, want 3, -1", len(cands), selected)
+	}
+
+	if !eng.SelectIndex(1) || eng.SelectIndex(3) || eng.SelectIndex(-1) {
+		t.Error("SelectIndex(1, 3, -1) != true, false, false")
+	}
+
+	cands, selected := eng.Candidates()
+	if selected != 1 || cands[selected].Value != "beta" {
+		t.Errorf("Candidates() selected %d, want 1 (beta)", selected)
+	}
+
+	if line, _ := eng.Line(); string(*line) != "beta" {
+		t.Errorf("Line() = %q, want %q", string(*line), "beta")
+	}
+}
+
+func TestEngine_SelectMultibytePrefix(t *testing.T) {
+	tests := []struct {
+		line   string
+		values []string
+		want   string
+	}{
+		{line: "ls éc", values: []string{"école", "écrit"}, want: "ls école"},
+		{line: "ls 日本", values: []string{"日本語", "日本人"}, want: "ls 日本人"},
+		{line: "été ab", values: []string{"abc", "abd"}, want: "été abc"},
+	}
+
+	for _, test := range tests {
+		eng := newBenchEngine(t)
+		eng.line.Set([]rune(test.line)...)
+		eng.cursor.Set(eng.line.Len())
+
+		cands := make([]Candidate, 0, len(test.values))
+		for _, val := range test.values {
+			cands = append(cands, Candidate{Value: val})
+		}
+
+		// The prefix replaced by the candidate is counted in runes.
+		eng.prepare(AddRaw(cands))
+		eng.SelectIndex(0)
+
+		if line, _ := eng.Line(); string(*line) != test.want {
+			t.Errorf("Line() = %q, want %q", string(*line), test.want)
+		}
+	}
+}
@@ -6,30 +6,34 @@ import (
 	"strconv"
 	"strings"
 
-	"golang.org/x/exp/slices"
-
 	"github.com/reeflective/readline/internal/color"
-	"github.com/reeflective/readline/internal/term"
+	"github.com/reeflective/readline/internal/ui"
 )
 
 // group is used to structure different types of completions with different
 // display types, autosuffix removal matchers, under their tag heading.
 type group struct {
 	tag               string        // Printed on top of the group's completions
-	rows              [][]Candidate // Values are grouped by aliases/rows, with computed paddings.
+	values            RawValues     // Prepared values, not laid out yet.
+	grid              [][]Candidate // Values are grouped by aliases/rows, with computed paddings.
 	noSpace           SuffixMatcher // Suffixes to remove if a space or non-nil character is entered after the completion.
 	columnsWidth      []int         // Computed width for each column of completions, when aliases
 	descriptionsWidth []int         // Computed width for each column of completions, when aliases
 	listSeparator     string        // This is used to separate completion candidates from their descriptions.
 	list              bool          // Force completions to be listed instead of grided
-	noSort            bool          // Don't sort completions
+	sort              SortStrategy  // How to sort completions
 	aliased           bool          // Are their aliased completions
 	preserveEscapes   bool          // Preserve escape sequences in the completion inserted values.
 	isCurrent         bool          // Currently cycling through this group, for highlighting choice
 	longestValue      int           // Used when display is map/list, for determining message width
 	longestDesc       int           // Used to know how much descriptions can use when there are aliases.
+	iconWidth         int           // Width of the icons column, if any candidate has an icon.
 	maxDescAllowed    int           // Maximum ALLOWED description width.
 	termWidth         int           // Term size queried at beginning of computes by the engine.
+	styles            *color.Styles // Icon and annotation styles are converted to the terminal colors.
+
+	less    func(a, b Candidate) bool    // Custom sort function, if any.
+	preview func(value string) *ui.Image // Preview of the selected candidate, if any.
 
 	// Selectors (position/bounds) management
 	posX int
@@ -46,32 +50,62 @@ func (e *Engine) newCompletionGroup(comps Values, tag string, vals RawValues, de
 		posX:         -1,
 		posY:         -1,
 		columnsWidth: []int{0},
-		termWidth:    term.GetWidth(),
+		termWidth:    e.term.GetWidth(),
 		longestDesc:  longest(descriptions, true),
+		styles:       e.styles,
 	}
 
 	// Initialize all options for the group.
 	grp.initOptions(e, &comps, tag, vals)
 
 	// Global actions to take on all values.
-	if !grp.noSort {
-		sort.Stable(vals)
-	}
+	sortValues(vals, grp.sort, grp.less, e.prefix, e.scorer())
 
 	// Initial processing of our assigned values:
 	// Compute color/no-color sizes, some max/min, etc.
-	grp.prepareValues(vals)
+	// The grid is only laid out when needed.
+	grp.values = grp.prepareValues(vals)
+
+	e.groups = append(e.groups, grp)
+}
+
+// rows returns the grid of completions, laying it out first if needed.
+func (g *group) rows() [][]Candidate {
+	g.layout()
+	return g.grid
+}
+
+// count returns the number of candidates in the group, without laying them out.
+func (g *group) count() (comps int) {
+	if g.values != nil {
+		return len(g.values)
+	}
+
+	for _, row := range g.grid {
+		comps += len(row)
+	}
+
+	return comps
+}
+
+// layout generates the full grid of completions, unless already done: a group
+// may have many values and never be displayed nor selected (eg. when asking the
+// user before displaying them), so its grid is only computed once it is needed.
+func (g *group) layout() {
+	vals := g.values
+	if vals == nil {
+		return
+	}
+
+	g.values = nil
 
-	// Generate the full grid of completions.
 	// Special processing is needed when some values
 	// share a common description, they are "aliased".
 	if completionsAreAliases(vals) {
-		grp.initCompletionAliased(vals)
+		g.initCompletionAliased(vals)
 	} else {
-		grp.initCompletionsGrid(vals)
+		g.initCompletionsGrid(vals)
 	}
-
-	e.groups = append(e.groups, grp)
 }
 
 // initOptions checks for global or group-specific options (display, behavior, grouping, etc).
@@ -96,6 +130,12 @@ func (g *group) initOptions(eng *Engine, comps *Values, tag string, vals RawValu
 		g.preserveEscapes = comps.Escapes["*"]
 	}
 
+	// Preview of candidates, for this tag or all of them.
+	g.preview = comps.Previews[tag]
+	if g.preview == nil {
+		g.preview = comps.Previews["*"]
+	}
+
 	// Always list long commands when they have descriptions.
 	if strings.HasSuffix(g.tag, "commands") && len(vals) > 0 && vals[0].Description != "" {
 		g.list = true
@@ -111,10 +151,29 @@ func (g *group) initOptions(eng *Engine, comps *Values, tag string, vals RawValu
 		g.listSeparator = listSep
 	}
 
-	// Override sorting or sort if needed
-	g.noSort = comps.NoSort[tag]
-	if noSort, all := comps.NoSort["*"]; noSort && all && len(comps.NoSort) == 1 {
-		g.noSort = true
+	// Sort strategy: either for this tag, for all tags, or the default one.
+	g.initSort(eng, comps, tag)
+}
+
+// initSort determines the sort strategy of the group, in order of precedence:
+// the one specified for its tag, the one for all tags, or the configured default
+// (fuzzy completion always defaults to sorting candidates by score).
+func (g *group) initSort(eng *Engine, comps *Values, tag string) {
+	g.sort, _ = ParseSortStrategy(eng.config.GetString("completion-sort"))
+	if eng.config.GetBool("completion-fuzzy") {
+		g.sort = SortScore
+	}
+
+	for _, name := range []string{tag, "*"} {
+		if less, found := comps.SortLess[name]; found {
+			g.sort, g.less = SortCustom, less
+			return
+		}
+
+		if strategy, found := comps.Sort[name]; found {
+			g.sort = strategy
+			return
+		}
 	}
 }
 
@@ -136,8 +195,8 @@ func (g *group) initCompletionsGrid(comps RawValues) {
 
 	rowCount := int(math.Ceil(float64(len(comps)) / (float64(maxColumns))))
 
-	g.rows = createGrid(comps, rowCount, maxColumns)
-	g.calculateMaxColumnWidths(g.rows)
+	g.grid = createGrid(comps, rowCount, maxColumns)
+	g.calculateMaxColumnWidths(g.grid)
 }
 
 // initCompletionsGrid arranges completions when some of them share the same description.
@@ -149,7 +208,7 @@ func (g *group) initCompletionAliased(domains []Candidate) {
 	g.calculateMaxColumnWidths(grid)
 	g.wrapExcessAliases(grid, descriptions)
 
-	g.maxY = len(g.rows)
+	g.maxY = len(g.grid)
 	g.maxX = len(g.columnsWidth)
 }
 
@@ -162,7 +221,7 @@ func (g *group) createDescribedRows(values []Candidate) ([][]Candidate, []string
 
 	// Separate duplicates and store them.
 	for i, description := range values {
-		if slices.Contains(uniqueDescriptions, description.Description) {
+		if _, found := descriptionMap[description.Description]; found {
 			descriptionMap[description.Description] = append(descriptionMap[description.Description], values[i])
 		} else {
 			uniqueDescriptions = append(uniqueDescriptions, description.Description)
@@ -188,7 +247,7 @@ func (g *group) wrapExcessAliases(grid [][]Candidate, descriptions []string) {
 
 	for i, width := range g.columnsWidth {
 		if (breakeven + width + 1) > g.termWidth/2 {
-			maxColumns = i
+			maxColumns = max(i, 1)
 			break
 		}
 
@@ -208,21 +267,30 @@ func (g *group) wrapExcessAliases(grid [][]Candidate, descriptions []string) {
 		rows = append(rows, row)
 	}
 
-	g.rows = rows
+	g.grid = rows
 	g.columnsWidth = g.columnsWidth[:maxColumns]
 }
 
 // prepareValues ensures all of them have a display, and starts
 // gathering information on longest/shortest values, etc.
 func (g *group) prepareValues(vals RawValues) RawValues {
+	for _, value := range vals {
+		g.iconWidth = max(g.iconWidth, color.Text(value.Icon).Width())
+	}
+
 	for pos, value := range vals {
 		if value.Display == "" {
 			value.Display = value.Value
 		}
 
-		// Only pass for colors regex should be here.
-		value.displayLen = len(color.Strip(value.Display))
-		value.descLen = len(color.Strip(value.Description))
+		// Widths are computed in columns, regardless of styles,
+		// and include the icon and annotation of the candidate.
+		// They are computed once, and kept when filtering values.
+		if !value.measured {
+			value.displayLen = color.Text(value.Display).Width() + g.segmentsLen(value)
+			value.descLen = color.Text(value.Description).Width()
+			value.measured = true
+		}
 
 		if value.displayLen > g.longestValue {
 			g.longestValue = value.displayLen
@@ -304,7 +372,7 @@ func (g *group) calculateMaxColumnWidths(grid [][]Candidate) {
 	}
 
 	// The group is mostly ready to print and select its values for completion.
-	g.maxY = len(g.rows)
+	g.maxY = len(g.grid)
 	g.maxX = len(values)
 	g.columnsWidth = values
 	g.descriptionsWidth = descriptions
@@ -365,7 +433,7 @@ func (g *group) trimDisplay(comp Candidate, pad, col int) (candidate, padded str
 	val = sanitizer.Replace(val)
 
 	if comp.displayLen > maxDisplayWidth {
-		val = color.Trim(val, maxDisplayWidth-trailingValueLen)
+		val = string(color.Text(val).Trim(maxDisplayWidth - trailingValueLen - g.segmentsLen(comp)))
 		val += "..." // 3 dots + 1 safety space = -3
 
 		return val, " "
@@ -374,6 +442,53 @@ func (g *group) trimDisplay(comp Candidate, pad, col int) (candidate, padded str
 	return val, padSpace(pad)
 }
 
+// segmentsLen returns the width taken by the icon column (if the group has one)
+// and by the annotation of a candidate, including the spaces separating them.
+func (g *group) segmentsLen(comp Candidate) (width int) {
+	if g.iconWidth > 0 {
+		width += g.iconWidth + 1
+	}
+
+	if comp.Annotation != "" {
+		width += color.Text(comp.Annotation).Width() + 1
+	}
+
+	return width
+}
+
+// iconSegment returns the icon of a candidate, padded to the width of
+// the icons column and styled, or nothing if the group has no icons.
+func (g *group) iconSegment(comp Candidate, selected bool) string {
+	if g.iconWidth == 0 {
+		return ""
+	}
+
+	icon := string(color.Text(sanitizer.Replace(comp.Icon)).Pad(g.iconWidth)) + " "
+	if selected || comp.IconStyle == "" {
+		return icon
+	}
+
+	return g.styles.Fmt(comp.IconStyle) + icon + color.Reset
+}
+
+// annotationSegment returns the annotation of a candidate preceded by the padding
+// of the candidate, so that it is aligned to the right of its column (before the
+// spaces separating columns), or the padding alone if there is no annotation.
+func (g *group) annotationSegment(comp Candidate, padded string, selected bool) string {
+	if comp.Annotation == "" {
+		return padded
+	}
+
+	trailing := min(len(padded), 2)
+	annotation := sanitizer.Replace(comp.Annotation)
+
+	if !selected && comp.AnnotationStyle != "" {
+		annotation = g.styles.Fmt(comp.AnnotationStyle) + annotation + color.Reset
+	}
+
+	return " " + padSpace(len(padded)-trailing) + annotation + padSpace(trailing)
+}
+
 func (g *group) trimDesc(val Candidate, pad int) (desc, padded string) {
 	desc = val.Description
 	if desc == "" {
@@ -392,7 +507,7 @@ func (g *group) trimDesc(val Candidate, pad int) (desc, padded string) {
 
 	// Trim the description accounting for escapes.
 	if val.descLen > g.maxDescAllowed && g.maxDescAllowed > 0 {
-		desc = color.Trim(desc, g.maxDescAllowed-trailingDescLen)
+		desc = string(color.Text(desc).Trim(g.maxDescAllowed - trailingDescLen))
 		desc += "..." // 3 dots =  -3
 
 		return g.listSep() + desc, ""
@@ -443,41 +558,44 @@ func (g *group) listSep() string {
 // we ask each of them to filter its own items and return the results to the shell for aggregating them.
 // The rx parameter is passed, as the shell already checked that the search pattern is valid.
 func (g *group) updateIsearch(eng *Engine) {
-	if eng.IsearchRegex == nil {
+	fuzzy := eng.fuzzySearching()
+
+	if eng.IsearchRegex == nil && !fuzzy {
 		return
 	}
 
-	suggs := make([]Candidate, 0)
+	suggs := make(RawValues, 0)
 
-	for i := range g.rows {
-		row := g.rows[i]
+	// Groups are filtered right after being generated,
+	// so their values have not been laid out yet.
+	vals := g.values
+	if vals == nil {
+		for _, row := range g.grid {
+			vals = append(vals, row...)
+		}
+	}
 
-		for _, val := range row {
-			if eng.IsearchRegex.MatchString(val.Value) {
-				suggs = append(suggs, val)
-			} else if val.Description != "" && eng.IsearchRegex.MatchString(val.Description) {
-				suggs = append(suggs, val)
-			}
+	for _, val := range vals {
+		if eng.isearchMatch(&val) {
+			suggs = append(suggs, val)
 		}
 	}
 
+	// Fuzzy matches are ranked by score, but otherwise keep their order.
+	if fuzzy {
+		sort.SliceStable(suggs, func(i, j int) bool {
+			return suggs[i].score > suggs[j].score
+		})
+	}
+
 	// Reset the group parameters
-	g.rows = make([][]Candidate, 0)
+	g.grid = nil
 	g.posX = -1
 	g.posY = -1
 
-	// Initial processing of our assigned values:
-	// Compute color/no-color sizes, some max/min, etc.
-	suggs = g.prepareValues(suggs)
-
-	// Generate the full grid of completions.
-	// Special processing is needed when some values
-	// share a common description, they are "aliased".
-	if completionsAreAliases(suggs) {
-		g.initCompletionAliased(suggs)
-	} else {
-		g.initCompletionsGrid(suggs)
-	}
+	// Initial processing of our assigned values: their widths have
+	// already been computed, and the grid is laid out when needed.
+	g.values = g.prepareValues(suggs)
 }
 
 func (g *group) selected() (comp Candidate) {
@@ -488,10 +606,10 @@ func (g *group) selected() (comp Candidate) {
 	}()
 
 	if g.posY == -1 || g.posX == -1 {
-		return g.rows[0][0]
+		return g.rows()[0][0]
 	}
 
-	return g.rows[g.posY][g.posX]
+	return g.rows()[g.posY][g.posX]
 }
 
 func (g *group) moveSelector(x, y int) (done, next bool) {
@@ -519,7 +637,7 @@ func (g *group) moveSelector(x, y int) (done, next bool) {
 		}
 
 		g.posY--
-		g.posX = len(g.rows[g.posY]) - 1
+		g.posX = len(g.rows()[g.posY]) - 1
 	}
 
 	// 2) If we are reverse-cycling and currently on the first candidate,
@@ -532,7 +650,7 @@ func (g *group) moveSelector(x, y int) (done, next bool) {
 			return true, false
 		}
 
-		g.posY = len(g.rows) - 1
+		g.posY = len(g.rows()) - 1
 		g.posX--
 	}
 
@@ -548,7 +666,7 @@ func (g *group) moveSelector(x, y int) (done, next bool) {
 	}
 
 	// 4) If we are on the last column, go to next row or next group
-	if g.posX > len(g.rows[g.posY])-1 {
+	if g.posX > len(g.rows()[g.posY])-1 {
 		if g.aliased {
 			return g.findFirstCandidate(x, y)
 		}
@@ -570,7 +688,7 @@ func (g *group) moveSelector(x, y int) (done, next bool) {
 // otherwise loop in the direction wished until one is found, or go next/
 // previous column, and so on.
 func (g *group) findFirstCandidate(x, y int) (done, next bool) {
-	for g.posX > len(g.rows[g.posY])-1 {
+	for g.posX > len(g.rows()[g.posY])-1 {
 		g.posY += y
 		g.posY += x
 
@@ -583,7 +701,7 @@ func (g *group) findFirstCandidate(x, y int) (done, next bool) {
 				return true, false
 			}
 
-			g.posY = len(g.rows) - 1
+			g.posY = len(g.rows()) - 1
 			g.posX--
 		}
 
@@ -607,13 +725,13 @@ func (g *group) firstCell() {
 }
 
 func (g *group) lastCell() {
-	g.posY = len(g.rows) - 1
+	g.posY = len(g.rows()) - 1
 	g.posX = len(g.columnsWidth) - 1
 
 	if g.aliased {
 		g.findFirstCandidate(0, -1)
 	} else {
-		g.posX = len(g.rows[g.posY]) - 1
+		g.posX = len(g.rows()[g.posY]) - 1
 	}
 }
 
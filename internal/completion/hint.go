@@ -14,7 +14,7 @@ func (e *Engine) hintCompletions(comps Values) {
 	// and only if we don't have completions.
 	if len(comps.values) == 0 || e.config.GetBool("usage-hint-always") {
 		if comps.Usage != "" {
-			hint += color.Dim + comps.Usage + color.Reset + term.NewlineReturn
+			hint += e.styles.Hint + comps.Usage + color.Reset + term.NewlineReturn
 		}
 	}
 
@@ -25,14 +25,18 @@ func (e *Engine) hintCompletions(comps Values) {
 	messages = strings.TrimSuffix(messages, term.NewlineReturn)
 
 	if messages != "" {
-		hint = hint + color.Dim + messages
+		hint = hint + e.styles.Hint + messages
 	}
 
 	// If we don't have any completions, and no messages, let's say it.
-	if e.Matches() == 0 && hint == color.Dim+term.NewlineReturn && !e.auto {
+	if e.Matches() == 0 && hint == e.styles.Hint+term.NewlineReturn && !e.auto {
 		hint = e.hintNoMatches()
 	}
 
+	if e.Matches() == 0 && !e.auto {
+		e.bell.Ring()
+	}
+
 	hint = strings.TrimSuffix(hint, term.NewlineReturn)
 	if hint == "" {
 		return
@@ -43,7 +47,7 @@ func (e *Engine) hintCompletions(comps Values) {
 }
 
 func (e *Engine) hintNoMatches() string {
-	noMatches := color.Dim + "no matching"
+	noMatches := e.styles.Hint + "no matching"
 
 	var groups []string
 
@@ -1,11 +1,13 @@
 package completion
 
 import (
+	"strings"
 	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/strutil"
 )
 
 // UpdateInserted should be called only once in between the two shell keymaps
@@ -112,18 +114,64 @@ func (e *Engine) acceptCandidate() {
 
 	// Prepare the completion candidate, remove the
 	// prefix part and save its sufffixes for later.
-	completion := e.prepareSuffix()
+	completion, offset := e.prepareSuffix()
 	e.inserted = []rune(completion)
 
-	// Remove the line prefix and insert the candidate.
-	e.cursor.Move(-1 * len(e.prefix))
-	e.line.Cut(e.cursor.Pos(), e.cursor.Pos()+len(e.prefix))
+	// Remove the line prefix (or region) and insert the
+	// candidate, and place the cursor on its marker if any.
+	bpos, epos := e.replaceRegion(e.cursor.Pos())
+	e.line.Cut(bpos, epos)
+	e.cursor.Set(bpos)
 	e.cursor.InsertAt(e.inserted...)
+	e.cursor.Move(-1 * offset)
 
 	// And forget about this inserted completion.
 	e.inserted = make([]rune, 0)
 	e.prefix = ""
 	e.suffix = ""
+	e.region = nil
+	e.escaped = false
+}
+
+// InsertCommonPrefix inserts in the real input line the longest prefix shared by
+// all generated candidates, if it extends the current completion prefix.
+// Returns true if the line has been modified.
+func (e *Engine) InsertCommonPrefix() bool {
+	if len(e.selected.Value) > 0 || e.noCompletions() {
+		return false
+	}
+
+	common := []rune(e.common)
+	if len(common) <= len([]rune(e.prefix)) || !e.extendsPrefix() {
+		return false
+	}
+
+	// Replace the prefix (or region) with the common prefix.
+	bpos, epos := e.replaceRegion(e.cursor.Pos())
+	e.line.Cut(bpos, epos)
+	e.cursor.Set(bpos)
+	e.cursor.InsertAt(common...)
+
+	// The common prefix is now the prefix of all candidates.
+	e.prefix = e.common
+	if e.region != nil {
+		e.region = []int{bpos, e.cursor.Pos()}
+	}
+
+	return true
+}
+
+// extendsPrefix returns true if the common prefix of candidates starts with the
+// prefix being completed (ignoring case if completion does), which is not the
+// case with fuzzy matching, for instance: the prefix would then be lost.
+func (e *Engine) extendsPrefix() bool {
+	common, prefix := e.common, e.prefix
+
+	if e.config.GetBool("completion-ignore-case") {
+		common, prefix = strings.ToLower(common), strings.ToLower(prefix)
+	}
+
+	return strings.HasPrefix(common, prefix)
 }
 
 // insertCandidate inserts a completion candidate into the virtual (completed) line.
@@ -135,13 +183,13 @@ func (e *Engine) insertCandidate() {
 
 	e.selected = grp.selected()
 
-	if len(e.selected.Value) < len(e.prefix) {
+	if len([]rune(e.selected.Value)) < len([]rune(e.prefix)) {
 		return
 	}
 
 	// Prepare the completion candidate, remove the
 	// prefix part and save its sufffixes for later.
-	completion := e.prepareSuffix()
+	completion, offset := e.prepareSuffix()
 	e.inserted = []rune(completion)
 
 	// Copy the current (uncompleted) line/cursor.
@@ -151,26 +199,42 @@ func (e *Engine) insertCandidate() {
 	e.compCursor = core.NewCursor(e.compLine)
 	e.compCursor.Set(e.cursor.Pos())
 
-	// Remove the line prefix and insert the candidate.
-	e.compCursor.Move(-1 * len(e.prefix))
-	e.compLine.Cut(e.compCursor.Pos(), e.compCursor.Pos()+len(e.prefix))
+	// Remove the line prefix (or region) and insert the candidate.
+	bpos, epos := e.replaceRegion(e.cursor.Pos())
+	e.compLine.Cut(bpos, epos)
+	e.compCursor.Set(bpos)
 	e.compCursor.InsertAt(e.inserted...)
+	e.compCursor.Move(-1 * offset)
 }
 
 // prepareSuffix caches any suffix matcher associated with the completion candidate
 // to be inserted/accepted into the input line, and trims it if required at this point.
-func (e *Engine) prepareSuffix() (comp string) {
+// The offset is the number of runes the cursor should be moved back after insertion.
+func (e *Engine) prepareSuffix() (comp string, offset int) {
 	cur := e.currentGroup()
 	if cur == nil {
 		return
 	}
 
-	comp = e.selected.Value
-	prefix := len(e.prefix)
+	comp, offset = insertTemplate(e.selected)
+	prefix := len([]rune(e.prefix))
+
+	// The word being completed had escapes, so must have the candidate.
+	if e.escaped && offset == 0 {
+		comp = strutil.EscapeWord(comp)
+	}
+
+	// The cursor will not be at the end of the inserted
+	// template, so any suffix matcher would be an orphan.
+	if offset > 0 {
+		e.sm = SuffixMatcher{}
+		return comp, offset
+	}
 
 	// When the completion has a size of 1, don't remove anything:
 	// stacked flags, for example, will never be inserted otherwise.
-	if len(comp) > 0 && len(comp[prefix:]) <= 1 {
+	runes := []rune(comp)
+	if len(runes) > 0 && (len(runes) <= prefix || len(runes[prefix:]) <= 1) {
 		return
 	}
 
@@ -178,9 +242,27 @@ func (e *Engine) prepareSuffix() (comp string) {
 	// matcher for later: whatever the decision we take here will be identical
 	// to the one we take while removing suffix in "non-virtual comp" mode.
 	e.sm = cur.noSpace
-	e.sm.pos = e.cursor.Pos() + len(comp) - prefix - 1
+	bpos, _ := e.replaceRegion(e.cursor.Pos())
+	e.sm.pos = bpos + len(runes) - 1
+
+	return comp, 0
+}
+
+// insertTemplate returns the text to insert for a candidate, stripped from
+// its cursor marker (if any), and the offset of this marker from the end.
+func insertTemplate(cand Candidate) (comp string, offset int) {
+	if cand.Insert == "" {
+		return cand.Value, 0
+	}
+
+	before, after, found := strings.Cut(cand.Insert, CursorMarker)
+	if !found {
+		return cand.Insert, 0
+	}
+
+	after = strings.ReplaceAll(after, CursorMarker, "")
 
-	return comp
+	return before + after, len([]rune(after))
 }
 
 func (e *Engine) cancelCompletedLine() {
@@ -8,6 +8,15 @@ import (
 	"github.com/reeflective/readline/internal/keymap"
 )
 
+// isearchField determines which candidate fields are matched by incremental search.
+type isearchField int
+
+const (
+	isearchAll          isearchField = iota // Both values and descriptions.
+	isearchValues                           // Values only.
+	isearchDescriptions                     // Descriptions only.
+)
+
 // IsearchStart starts incremental search (fuzzy-finding)
 // with values matching the isearch minibuffer as a regexp.
 func (e *Engine) IsearchStart(name string, autoinsert, replaceLine bool) {
@@ -28,13 +37,17 @@ func (e *Engine) IsearchStart(name string, autoinsert, replaceLine bool) {
 
 	// Hints
 	e.isearchName = name
-	e.hint.Set(color.Bold + color.FgCyan + e.isearchName + " (isearch): " + color.Reset + string(*e.isearchBuf))
+	e.hint.Set(e.styles.Isearch + e.isearchName + " (isearch): " + color.Reset + string(*e.isearchBuf))
 }
 
 // IsearchStop exists the incremental search mode,
 // and drops the currently used regexp matcher.
 // If revertLine is true, the original line is restored.
 func (e *Engine) IsearchStop(revertLine bool) {
+	if e.isearchReplaceLine && e.isearchBuf != nil && e.isearchBuf.Len() > 0 {
+		e.searchLast = string(*e.isearchBuf)
+	}
+
 	// Reset all buffers and cursors.
 	e.isearchBuf = nil
 	e.IsearchRegex = nil
@@ -49,6 +62,7 @@ func (e *Engine) IsearchStop(revertLine bool) {
 	e.isearchStartBuf = ""
 	e.isearchStartCursor = 0
 	e.isearchReplaceLine = false
+	e.isearchField = isearchAll
 
 	// And clear all related completion keymaps/modes.
 	e.auto = false
@@ -67,7 +81,7 @@ func (e *Engine) GetBuffer() (*core.Line, *core.Cursor, *core.Selection) {
 	searching, _, _ := e.NonIncrementallySearching()
 
 	if e.keymap.Local() == keymap.Isearch || searching {
-		selection := core.NewSelection(e.isearchBuf, e.isearchCur)
+		selection := core.NewSelection(e.isearchBuf, e.isearchCur, e.styles)
 		return e.isearchBuf, e.isearchCur, selection
 	}
 
@@ -105,6 +119,22 @@ func (e *Engine) UpdateIsearch() {
 	}
 }
 
+// IsearchCycleField cycles through the candidate fields matched by incremental search:
+// values and descriptions, values only, or descriptions only, and updates the matches.
+func (e *Engine) IsearchCycleField() {
+	if e.keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	e.isearchField = (e.isearchField + 1) % (isearchDescriptions + 1)
+
+	if len(e.selected.Value) > 0 {
+		e.cancelCompletedLine()
+	}
+
+	e.UpdateIsearch()
+}
+
 // NonIsearchStart starts a non-incremental, fake search mode:
 // it does not produce or tries to match against completions,
 // but uses a minibuffer similarly to incremental search mode.
@@ -130,6 +160,10 @@ func (e *Engine) NonIsearchStart(name string, repeat, forward, substring bool) {
 // NonIsearchStop exits the non-incremental search mode.
 func (e *Engine) NonIsearchStop() {
 	e.isearchLast = string(*e.isearchBuf)
+	if e.isearchLast != "" {
+		e.searchLast = e.isearchLast
+	}
+
 	e.isearchBuf = nil
 	e.IsearchRegex = nil
 	e.isearchCur = nil
@@ -142,6 +176,12 @@ func (e *Engine) NonIsearchStop() {
 	e.hint.Reset()
 }
 
+// LastSearch returns the last pattern searched in history,
+// either incrementally or not, or an empty string if none.
+func (e *Engine) LastSearch() string {
+	return e.searchLast
+}
+
 // NonIncrementallySearching returns true if the completion engine
 // is currently using a minibuffer for non-incremental search mode.
 func (e *Engine) NonIncrementallySearching() (searching, forward, substring bool) {
@@ -160,11 +200,12 @@ func (e *Engine) updateIncrementalSearch() {
 		regexStr = "(?i)" + string(*e.isearchBuf)
 	}
 
+	// Fuzzy searches match candidates with the scorer only.
 	var err error
-	e.IsearchRegex, err = regexp.Compile(regexStr)
-
-	if err != nil {
-		e.hint.Set(color.FgRed + "Failed to compile i-search regexp")
+	if e.config.GetBool("completion-fuzzy") {
+		e.IsearchRegex = nil
+	} else if e.IsearchRegex, err = regexp.Compile(regexStr); err != nil {
+		e.hint.Set(e.styles.Error + "Failed to compile i-search regexp")
 	}
 
 	// Refresh completions with the current minibuffer as a filter.
@@ -176,10 +217,11 @@ func (e *Engine) updateIncrementalSearch() {
 	}
 
 	// Update the hint section.
-	isearchHint := color.Bold + color.FgCyan + e.isearchName + " (inc-search)"
+	isearchHint := e.styles.Isearch + e.isearchName + " (inc-search" + e.isearchFieldHint() + ")"
 
 	if e.Matches() == 0 {
-		isearchHint += color.Reset + color.Bold + color.FgRed + " (no matches)"
+		isearchHint += color.Reset + color.Bold + e.styles.Error + " (no matches)"
+		e.bell.Ring()
 	}
 
 	isearchHint += ": " + color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
@@ -191,6 +233,7 @@ func (e *Engine) updateIncrementalSearch() {
 		// History incremental searches must replace the whole line.
 		if e.isearchReplaceLine {
 			e.prefix = ""
+			e.region = nil
 			e.line.Set()
 			e.cursor.Set(0)
 		}
@@ -204,7 +247,7 @@ func (e *Engine) updateIncrementalSearch() {
 }
 
 func (e *Engine) updateNonIncrementalSearch() {
-	isearchHint := color.Bold + color.FgCyan + e.isearchName +
+	isearchHint := e.styles.Isearch + e.isearchName +
 		" (non-inc-search): " + color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
 	e.hint.Set(isearchHint)
 }
@@ -231,3 +274,52 @@ func (e *Engine) resetIsearchInsertMode() {
 		e.cursor.CheckCommand()
 	}
 }
+
+// fuzzySearching returns true if the engine is in incremental
+// search mode, with candidates being matched by the scorer.
+func (e *Engine) fuzzySearching() bool {
+	return e.isearchBuf != nil && e.keymap.Local() == keymap.Isearch && e.config.GetBool("completion-fuzzy")
+}
+
+// isearchMatch returns true if the candidate value (or description) matches
+// the incremental search, either as a regexp, or with the scorer, in which
+// case the candidate score is updated.
+func (e *Engine) isearchMatch(val *Candidate) bool {
+	values := e.isearchField != isearchDescriptions
+	descriptions := e.isearchField != isearchValues && val.Description != ""
+
+	if !e.fuzzySearching() {
+		return (values && e.IsearchRegex.MatchString(val.Value)) ||
+			(descriptions && e.IsearchRegex.MatchString(val.Description))
+	}
+
+	query := string(*e.isearchBuf)
+
+	if values {
+		val.score, _ = e.scorer().Score(query, color.Strip(val.Value))
+		if val.score >= 0 {
+			return true
+		}
+	}
+
+	if !descriptions {
+		return false
+	}
+
+	val.score, _ = e.scorer().Score(query, color.Strip(val.Description))
+
+	return val.score >= 0
+}
+
+// isearchFieldHint returns the candidate fields matched by
+// the search, for the hint, if not all of them are matched.
+func (e *Engine) isearchFieldHint() string {
+	switch e.isearchField {
+	case isearchValues:
+		return ": values"
+	case isearchDescriptions:
+		return ": descriptions"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,126 @@
+package completion
+
+import (
+	"unicode"
+)
+
+// Scorer matches and ranks candidates against a query. It is used for
+// fuzzy completion and incremental search filtering, sorting candidates
+// by score, and choosing between history autosuggestions.
+type Scorer interface {
+	// Score returns the score of the candidate against the query (higher is
+	// better), and the positions (rune indexes) of the matched characters
+	// in the candidate. A negative score means the candidate does not match.
+	// An empty query matches all candidates.
+	Score(query, candidate string) (score int, positions []int)
+}
+
+// Scoring bonuses and penalties of the default fuzzy scorer.
+const (
+	scoreMatch       = 16 // Each matched character.
+	scoreConsecutive = 8  // A matched character right after the previous one.
+	scoreBoundary    = 8  // A matched character at the start of a word.
+	scoreFirst       = 8  // The first matched character is the first of the candidate.
+	scoreGap         = 1  // Penalty for each unmatched character between two matched ones.
+)
+
+// FuzzyScorer is the default scorer: it matches the query characters as
+// a subsequence of the candidate, case-insensitively unless the query has
+// uppercase characters (smart case), favoring consecutive characters and
+// characters at the start of words. Candidates having the query as prefix
+// therefore have the best (and identical) scores.
+type FuzzyScorer struct{}
+
+// Score implements the Scorer interface.
+func (FuzzyScorer) Score(query, candidate string) (score int, positions []int) {
+	pattern, text := []rune(query), []rune(candidate)
+
+	if len(pattern) == 0 {
+		return 0, nil
+	}
+
+	matchCase := hasUpper(pattern)
+
+	equal := func(p, t rune) bool {
+		if matchCase {
+			return p == t
+		}
+
+		return unicode.ToLower(p) == unicode.ToLower(t)
+	}
+
+	// Find the first position where the whole query is matched.
+	pidx, end := 0, -1
+
+	for i := 0; i < len(text) && end < 0; i++ {
+		if equal(pattern[pidx], text[i]) {
+			pidx++
+		}
+
+		if pidx == len(pattern) {
+			end = i
+		}
+	}
+
+	if end < 0 {
+		return -1, nil
+	}
+
+	// And walk back from there, so as to find the most compact match.
+	positions = make([]int, len(pattern))
+	pidx = len(pattern) - 1
+
+	for i := end; i >= 0 && pidx >= 0; i-- {
+		if equal(pattern[pidx], text[i]) {
+			positions[pidx] = i
+			pidx--
+		}
+	}
+
+	for i, pos := range positions {
+		score += scoreMatch
+
+		switch {
+		case pos == 0:
+			score += scoreFirst + scoreBoundary
+		case isBoundary(text[pos-1], text[pos]):
+			score += scoreBoundary
+		}
+
+		if i == 0 {
+			continue
+		}
+
+		if gap := pos - positions[i-1] - 1; gap == 0 {
+			score += scoreConsecutive
+		} else {
+			score -= gap * scoreGap
+		}
+	}
+
+	return score, positions
+}
+
+// isBoundary returns true if the current character starts a word.
+func isBoundary(prev, cur rune) bool {
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return unicode.IsLetter(cur) || unicode.IsDigit(cur)
+	}
+
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// scorer returns the scorer of the engine, or the default one.
+func (e *Engine) scorer() Scorer {
+	if e.score != nil {
+		return e.score
+	}
+
+	return FuzzyScorer{}
+}
+
+// SetScorer sets the scorer used for fuzzy completion, incremental
+// search and score sorting. If nil, the default fuzzy scorer is used.
+func (e *Engine) SetScorer(scorer Scorer) {
+	e.score = scorer
+}
@@ -0,0 +1,138 @@
+package completion
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SortStrategy determines how the candidates of a group are sorted.
+type SortStrategy int
+
+const (
+	// SortAlpha sorts candidates in case-insensitive alphabetical order.
+	SortAlpha SortStrategy = iota
+	// SortNone keeps candidates in the order they were given.
+	SortNone
+	// SortNatural sorts candidates alphabetically, but compares digit runs numerically.
+	SortNatural
+	// SortLength sorts candidates by length, then alphabetically.
+	SortLength
+	// SortScore sorts candidates by their match score against the prefix (best first).
+	SortScore
+	// SortCustom sorts candidates with a caller-provided less function.
+	SortCustom
+)
+
+// ParseSortStrategy returns the sort strategy corresponding to
+// its configuration name (alpha, none, natural, length, score).
+func ParseSortStrategy(name string) (strategy SortStrategy, valid bool) {
+	switch strings.ToLower(strings.Trim(name, "\"")) {
+	case "alpha", "":
+		return SortAlpha, true
+	case "none":
+		return SortNone, true
+	case "natural":
+		return SortNatural, true
+	case "length":
+		return SortLength, true
+	case "score":
+		return SortScore, true
+	default:
+		return SortAlpha, false
+	}
+}
+
+// sortValues sorts a list of candidates according to a strategy.
+// The less function is only used with the SortCustom strategy,
+// and the scorer ranks candidates against the prefix with SortScore.
+func sortValues(vals RawValues, strategy SortStrategy, less func(a, b Candidate) bool, prefix string, scorer Scorer) {
+	switch strategy {
+	case SortNone:
+		return
+	case SortNatural:
+		sort.SliceStable(vals, func(i, j int) bool {
+			return naturalLess(strings.ToLower(vals[i].Value), strings.ToLower(vals[j].Value))
+		})
+	case SortLength:
+		sort.SliceStable(vals, func(i, j int) bool {
+			if len(vals[i].Value) != len(vals[j].Value) {
+				return len(vals[i].Value) < len(vals[j].Value)
+			}
+
+			return vals.Less(i, j)
+		})
+	case SortScore:
+		for i := range vals {
+			vals[i].score, _ = scorer.Score(prefix, vals[i].Value)
+		}
+
+		sort.SliceStable(vals, func(i, j int) bool {
+			if vals[i].score != vals[j].score {
+				return vals[i].score > vals[j].score
+			}
+
+			if len(vals[i].Value) != len(vals[j].Value) {
+				return len(vals[i].Value) < len(vals[j].Value)
+			}
+
+			return vals.Less(i, j)
+		})
+	case SortCustom:
+		if less == nil {
+			return
+		}
+
+		sort.SliceStable(vals, func(i, j int) bool {
+			return less(vals[i], vals[j])
+		})
+	default:
+		sort.Stable(vals)
+	}
+}
+
+// naturalLess compares two strings, where runs of digits are compared
+// by their numeric value rather than lexicographically (eg. 2 < 10).
+func naturalLess(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	ia, ib := 0, 0
+
+	for ia < len(ra) && ib < len(rb) {
+		ca, cb := ra[ia], rb[ib]
+
+		if !unicode.IsDigit(ca) || !unicode.IsDigit(cb) {
+			if ca != cb {
+				return ca < cb
+			}
+
+			ia++
+			ib++
+
+			continue
+		}
+
+		// Compare both digit runs, ignoring leading zeros.
+		sa, sb := ia, ib
+
+		for ia < len(ra) && unicode.IsDigit(ra[ia]) {
+			ia++
+		}
+
+		for ib < len(rb) && unicode.IsDigit(rb[ib]) {
+			ib++
+		}
+
+		na := strings.TrimLeft(string(ra[sa:ia]), "0")
+		nb := strings.TrimLeft(string(rb[sb:ib]), "0")
+
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+
+		if na != nb {
+			return na < nb
+		}
+	}
+
+	return len(ra)-ia < len(rb)-ib
+}
@@ -1,8 +1,9 @@
 package completion
 
 import (
+	"unicode"
+
 	"github.com/reeflective/readline/internal/core"
-	"github.com/reeflective/readline/internal/strutil"
 )
 
 // CompleteSyntax updates the line with either user-defined syntax completers, or with the builtin ones.
@@ -25,44 +26,106 @@ func (e *Engine) CompleteSyntax(completer func([]rune, int) ([]rune, int)) {
 	e.cursor.Set(newPos)
 }
 
-// AutopairInsertOrJump checks if the character to be inserted in the line is a pair character.
-// If the character is an opening one, its inserted along with its closing equivalent.
-// If it's a closing one and the next character in line is the same, the cursor jumps over it.
-func AutopairInsertOrJump(key rune, line *core.Line, cur *core.Cursor) (skipInsert bool) {
-	matcher, closer := strutil.SurroundType(key)
-
-	if !matcher {
+// AutopairInsertOrJump checks if the character to be inserted in the line is one of
+// the pairs characters, given as consecutive opening and closing characters (eg.
+// "()[]{}"). If it is a closing character already under the cursor, the cursor
+// jumps over it and the character should not be inserted. If it is an opening
+// one, its closing equivalent is inserted after the cursor, unless the previous
+// character is a backslash, or a word character for quotes (eg. an apostrophe).
+func AutopairInsertOrJump(key rune, pairs string, line *core.Line, cur *core.Cursor) (skipInsert bool) {
+	opener, closer, found := matchPair(key, pairs)
+	if !found {
 		return
 	}
 
-	switch {
-	case closer && cur.Char() == key:
-		skipInsert = true
+	if key == closer && cur.Pos() < line.Len() && cur.Char() == key {
 		cur.Inc()
-	case closer && key != '\'' && key != '"':
+		return true
+	}
+
+	if key != opener {
 		return
-	default:
-		_, closeChar := strutil.MatchSurround(key)
-		line.Insert(cur.Pos(), closeChar)
 	}
 
+	if cur.Pos() > 0 {
+		prev := (*line)[cur.Pos()-1]
+
+		if prev == '\\' || (opener == closer && (unicode.IsLetter(prev) || unicode.IsDigit(prev))) {
+			return
+		}
+	}
+
+	line.Insert(cur.Pos(), closer)
+
 	return
 }
 
-// AutopairDelete checks if the character under the cursor is an opening pair
+// AutopairDelete checks if the character before the cursor is an opening pair
 // character which is immediately followed by its closing equivalent. If yes,
 // the closing character is removed.
-func AutopairDelete(line *core.Line, cur *core.Cursor) {
-	if cur.Pos() == 0 {
+func AutopairDelete(pairs string, line *core.Line, cur *core.Cursor) {
+	if cur.Pos() == 0 || cur.Pos() >= line.Len() {
 		return
 	}
 
-	toDelete := (*line)[cur.Pos()-1]
-	isPair, _ := strutil.SurroundType(toDelete)
-	matcher := strutil.IsSurround(toDelete, cur.Char())
+	opener, closer, found := matchPair((*line)[cur.Pos()-1], pairs)
 
 	// Cut the (closing) rune under the cursor.
-	if isPair && matcher {
+	if found && (*line)[cur.Pos()-1] == opener && cur.Char() == closer {
 		line.CutRune(cur.Pos())
 	}
 }
+
+// AutopairsBalanced returns true if all pairs characters in the line are closed.
+// Pairs with identical characters are quotes, in which other pairs are ignored.
+// A backslash escapes the next character, except in single quotes. Unmatched
+// closing characters are ignored, since they cannot be closed on a new line.
+func AutopairsBalanced(pairs string, line []rune) bool {
+	var closers []rune
+
+	var quote rune
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else if char == '\\' && quote != '\'' {
+				i++
+			}
+
+		case char == '\\':
+			i++
+
+		default:
+			opener, closer, found := matchPair(char, pairs)
+
+			switch {
+			case !found:
+			case opener == closer:
+				quote = char
+			case char == opener:
+				closers = append(closers, closer)
+			case len(closers) > 0 && closers[len(closers)-1] == char:
+				closers = closers[:len(closers)-1]
+			}
+		}
+	}
+
+	return quote == 0 && len(closers) == 0
+}
+
+// matchPair returns the pair to which the character belongs, if any.
+func matchPair(char rune, pairs string) (opener, closer rune, found bool) {
+	chars := []rune(pairs)
+
+	for i := 0; i+1 < len(chars); i += 2 {
+		if chars[i] == char || chars[i+1] == char {
+			return chars[i], chars[i+1], true
+		}
+	}
+
+	return 0, 0, false
+}
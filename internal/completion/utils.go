@@ -6,6 +6,7 @@ import (
 
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
 )
 
@@ -25,8 +26,11 @@ var sanitizer = strings.NewReplacer(
 // insertion/abortion on the line.
 func (e *Engine) prepare(completions Values) {
 	e.prefix = ""
+	e.escaped = false
+	e.listOnly = completions.ListOnly
 	e.groups = make([]*group, 0)
 
+	e.setRegion(completions)
 	e.setPrefix(completions)
 	e.setSuffix(completions)
 	e.generate(completions)
@@ -45,45 +49,113 @@ func (e *Engine) generate(completions Values) {
 	}
 
 	// Apply the prefix to the completions, and filter out any
-	// completions that don't match, optionally ignoring case.
-	matchCase := e.config.GetBool("completion-ignore-case")
-	completions.values = completions.values.FilterPrefix(e.prefix, !matchCase)
+	// completions that don't match, optionally ignoring case,
+	// or fuzzy-matching them with the scorer.
+	switch {
+	case completions.NoFilter:
+	case e.config.GetBool("completion-fuzzy"):
+		completions.values = completions.values.FilterScore(e.prefix, e.scorer())
+	default:
+		matchCase := e.config.GetBool("completion-ignore-case")
+		completions.values = completions.values.FilterPrefix(e.prefix, !matchCase)
+	}
 
 	// Classify, group together and initialize completions.
 	completions.values.EachTag(e.generateGroup(completions))
 	e.justifyGroups(completions)
+
+	e.common = commonPrefix(completions.values)
+
+	// Unfiltered candidates might not match the word being completed,
+	// which is then not replaced with their common prefix.
+	if completions.NoFilter {
+		e.common = ""
+	}
 }
 
 func (e *Engine) setPrefix(completions Values) {
-	switch completions.PREFIX {
-	case "":
-		// Select the character just before the cursor.
-		cpos := e.cursor.Pos() - 1
-		if cpos < 0 {
-			cpos = 0
+	switch {
+	case completions.PREFIX == "" && e.region != nil:
+		// The part of the region up to the cursor is the prefix.
+		cpos := e.cursor.Pos()
+		if cpos > e.region[1] {
+			cpos = e.region[1]
 		}
 
-		bpos, _ := e.line.SelectBlankWord(cpos)
-
-		// Safety checks and adjustments.
-		if bpos > cpos {
-			bpos, cpos = cpos, bpos
+		if cpos < e.region[0] {
+			cpos = e.region[0]
 		}
 
-		if cpos < e.line.Len() {
-			cpos++
-		}
+		e.prefix = string((*e.line)[e.region[0]:cpos])
 
-		// You might wonder why we trim spaces here:
-		// in practice we don't really ever want to
-		// consider "how many spaces are somewhere".
-		e.prefix = strings.TrimSpace(string((*e.line)[bpos:cpos]))
+	case completions.PREFIX == "":
+		e.setWordPrefix()
 
 	default:
 		e.prefix = completions.PREFIX
 	}
 }
 
+// setWordPrefix uses the shell word before the cursor as prefix, parsed with
+// shell syntax: if the word has an unclosed quote, only its quoted part is the
+// prefix (and replaced by candidates), and if it has escapes or closed quotes,
+// the unquoted word is the prefix, and inserted candidates are escaped.
+func (e *Engine) setWordPrefix() {
+	cpos := e.cursor.Pos()
+	if cpos > e.line.Len() {
+		cpos = e.line.Len()
+	}
+
+	args := strutil.CommandArgs((*e.line)[:cpos])
+	word := args[len(args)-1]
+	raw := string((*e.line)[word.Start:cpos])
+
+	switch {
+	case word.Quote >= 0:
+		e.prefix = string((*e.line)[word.Quote:cpos])
+		e.region = []int{word.Quote, cpos}
+	case raw != word.Value:
+		e.prefix = word.Value
+		e.region = []int{word.Start, cpos}
+		e.escaped = true
+	default:
+		e.prefix = raw
+	}
+}
+
+// setRegion stores the line region to be replaced by inserted
+// candidates, if the completions specify one and if it is valid.
+func (e *Engine) setRegion(completions Values) {
+	e.region = nil
+
+	if !completions.Replace {
+		return
+	}
+
+	bpos, epos := completions.ReplaceStart, completions.ReplaceEnd
+
+	if bpos > epos {
+		bpos, epos = epos, bpos
+	}
+
+	if bpos < 0 || epos > e.line.Len() {
+		return
+	}
+
+	e.region = []int{bpos, epos}
+}
+
+// replaceRegion returns the boundaries of the line region to be replaced by the
+// inserted candidate: either the region specified by the completions, or the
+// current prefix up to the cursor.
+func (e *Engine) replaceRegion(cpos int) (bpos, epos int) {
+	if e.region != nil {
+		return e.region[0], e.region[1]
+	}
+
+	return cpos - len([]rune(e.prefix)), cpos
+}
+
 func (e *Engine) setSuffix(completions Values) {
 	switch completions.SUFFIX {
 	case "":
@@ -133,7 +205,8 @@ func (e *Engine) generateGroup(comps Values) func(tag string, values RawValues)
 
 // groupNonDescribed separates values based on whether they have descriptions, or are aliases of each other.
 func (e *Engine) groupNonDescribed(comps *Values, values RawValues) (vals, noDescVals RawValues, descs []string) {
-	var descriptions []string
+	descriptions := make([]string, 0, len(values))
+	vals = make(RawValues, 0, len(values))
 
 	prefix := ""
 	if e.prefix != "\"\"" && e.prefix != "''" {
@@ -148,7 +221,7 @@ func (e *Engine) groupNonDescribed(comps *Values, values RawValues) (vals, noDes
 
 		// Currently this is because errors are passed as completions.
 		if strings.HasPrefix(val.Value, prefix+"ERR") && val.Value == prefix+"_" {
-			comps.Messages.Add(color.FgRed + val.Display + val.Description)
+			comps.Messages.Add(e.styles.Error + val.Display + val.Description)
 
 			continue
 		}
@@ -183,7 +256,7 @@ func (e *Engine) currentGroup() (grp *group) {
 	// If there are groups but no current, make first one the king.
 	if len(e.groups) > 0 {
 		for _, g := range e.groups {
-			if len(g.rows) > 0 {
+			if len(g.rows()) > 0 {
 				g.isCurrent = true
 				return g
 			}
@@ -212,7 +285,7 @@ func (e *Engine) cycleNextGroup() {
 
 	for {
 		next := e.currentGroup()
-		if len(next.rows) == 0 {
+		if len(next.rows()) == 0 {
 			e.cycleNextGroup()
 			continue
 		}
@@ -239,7 +312,7 @@ func (e *Engine) cyclePreviousGroup() {
 
 	for {
 		prev := e.currentGroup()
-		if len(prev.rows) == 0 {
+		if len(prev.rows()) == 0 {
 			e.cyclePreviousGroup()
 			continue
 		}
@@ -264,6 +337,8 @@ func (e *Engine) justifyGroups(values Values) {
 		}
 
 		// Skip groups that are aliased or have more than one column
+		group.layout()
+
 		if group.aliased || len(group.columnsWidth) > 1 {
 			continue
 		}
@@ -318,19 +393,18 @@ func (e *Engine) adjustSelectKeymap() {
 func (e *Engine) completionCount() (comps int, used int) {
 	for _, group := range e.groups {
 		// First, agree on the number of comps.
-		for _, row := range group.rows {
-			comps += len(row)
-		}
+		comps += group.count()
+		rows := group.rows()
 
 		// One line for the group name
 		if group.tag != "" {
 			used++
 		}
 
-		if group.maxY > len(group.rows) {
+		if group.maxY > len(rows) {
 			used += group.maxY
 		} else {
-			used += len(group.rows)
+			used += len(rows)
 		}
 	}
 
@@ -338,6 +412,10 @@ func (e *Engine) completionCount() (comps int, used int) {
 }
 
 func (e *Engine) hasUniqueCandidate() bool {
+	if e.listOnly {
+		return false
+	}
+
 	switch len(e.groups) {
 	case 0:
 		return false
@@ -348,18 +426,18 @@ func (e *Engine) hasUniqueCandidate() bool {
 			return false
 		}
 
-		if len(cur.rows) == 1 {
-			return len(cur.rows[0]) == 1
+		if len(cur.rows()) == 1 {
+			return len(cur.rows()[0]) == 1
 		}
 
-		return len(cur.rows) == 1
+		return len(cur.rows()) == 1
 
 	default:
 		var count int
 
 	GROUPS:
 		for _, group := range e.groups {
-			for _, row := range group.rows {
+			for _, row := range group.rows() {
 				count++
 				for range row {
 					count++
@@ -376,7 +454,7 @@ func (e *Engine) hasUniqueCandidate() bool {
 
 func (e *Engine) noCompletions() bool {
 	for _, group := range e.groups {
-		if len(group.rows) > 0 {
+		if len(group.rows()) > 0 {
 			return false
 		}
 	}
@@ -391,6 +469,7 @@ func (e *Engine) resetValues(comps, cached bool) {
 	if comps {
 		e.usedY = 0
 		e.groups = make([]*group, 0)
+		e.common = ""
 	}
 
 	// Drop the completion generation function.
@@ -408,10 +487,43 @@ func (e *Engine) resetValues(comps, cached bool) {
 	}
 }
 
+// commonPrefix returns the longest prefix shared by all candidate values.
+func commonPrefix(values RawValues) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	common := []rune(values[0].Value)
+
+	for _, val := range values[1:] {
+		runes := []rune(val.Value)
+
+		if len(runes) < len(common) {
+			common = common[:len(runes)]
+		}
+
+		for i := range common {
+			if common[i] != runes[i] {
+				common = common[:i]
+				break
+			}
+		}
+
+		if len(common) == 0 {
+			break
+		}
+	}
+
+	return string(common)
+}
+
 func (e *Engine) needsAutoComplete() bool {
 	// Autocomplete is not needed when already completing,
 	// or when the input line is empty (would always trigger)
+	// Neither in low-bandwidth nor screen-reader modes, as it refreshes
+	// the completions on each keystroke.
 	needsComplete := e.config.GetBool("autocomplete") &&
+		!e.config.GetBool("low-bandwidth") && !e.config.GetBool("screen-reader") &&
 		e.keymap.Local() != keymap.MenuSelect &&
 		e.keymap.Local() != keymap.Isearch &&
 		e.line.Len() > 0
@@ -436,13 +548,7 @@ func (e *Engine) getAbsPos() int {
 	var foundCurrent bool
 
 	for _, grp := range e.groups {
-		groupComps := 0
-
-		for _, row := range grp.rows {
-			groupComps += len(row)
-		}
-
-		if groupComps == 0 {
+		if grp.count() == 0 {
 			continue
 		}
 
@@ -457,6 +563,7 @@ func (e *Engine) getAbsPos() int {
 			break
 		}
 
+		grp.layout()
 		prev += grp.maxY
 	}
 
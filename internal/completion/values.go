@@ -45,15 +45,24 @@ func (c *Values) Merge(other Values) {
 // EachTag iterates over each tag and runs a function for each group.
 func (c RawValues) EachTag(tagF func(tag string, values RawValues)) {
 	tags := make([]string, 0)
-	tagGroups := make(map[string]RawValues)
+	counts := make(map[string]int)
 
 	for _, val := range c {
-		if _, exists := tagGroups[val.Tag]; !exists {
-			tagGroups[val.Tag] = make(RawValues, 0)
-
+		if _, exists := counts[val.Tag]; !exists {
 			tags = append(tags, val.Tag)
 		}
 
+		counts[val.Tag]++
+	}
+
+	// Values are copied only once, in groups of the right size.
+	tagGroups := make(map[string]RawValues, len(tags))
+
+	for _, tag := range tags {
+		tagGroups[tag] = make(RawValues, 0, counts[tag])
+	}
+
+	for _, val := range c {
 		tagGroups[val.Tag] = append(tagGroups[val.Tag], val)
 	}
 
@@ -91,6 +100,23 @@ func (c RawValues) FilterPrefix(prefix string, matchCase bool) RawValues {
 	return filtered
 }
 
+// FilterScore filters values matching the prefix with a scorer (eg. fuzzy matching).
+func (c RawValues) FilterScore(prefix string, scorer Scorer) RawValues {
+	if prefix == "" {
+		return c
+	}
+
+	filtered := make(RawValues, 0)
+
+	for _, raw := range c {
+		if score, _ := scorer.Score(prefix, raw.Value); score >= 0 {
+			filtered = append(filtered, raw)
+		}
+	}
+
+	return filtered
+}
+
 func (c RawValues) Len() int { return len(c) }
 
 func (c RawValues) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
@@ -8,9 +8,10 @@ import (
 // Cursor is the cursor position in the current line buffer.
 // Contains methods to set, move, describe and check itself.
 type Cursor struct {
-	pos  int
-	mark int
-	line *Line
+	pos     int
+	mark    int
+	line    *Line
+	cursors []int // Additional cursors, for multiple cursor editing.
 }
 
 // NewCursor is a required constructor for the line cursor,
@@ -47,19 +48,19 @@ func (c *Cursor) Pos() int {
 	return c.pos
 }
 
-// Inc increments the cursor position by 1,
-// if it's not at the end of the line.
+// Inc moves the cursor forward by one character (a grapheme
+// cluster, which may be several runes), if it's not at the end of the line.
 func (c *Cursor) Inc() {
 	if c.pos < c.line.Len() {
-		c.pos++
+		c.pos = c.line.GraphemeEnd(c.pos)
 	}
 }
 
-// Dec decrements the cursor position by 1,
-// if it's not at the beginning of the line.
+// Dec moves the cursor backward by one character (a grapheme
+// cluster, which may be several runes), if it's not at the beginning of the line.
 func (c *Cursor) Dec() {
 	if c.pos > 0 {
-		c.pos--
+		c.pos = c.line.GraphemeStart(c.pos - 1)
 	}
 }
 
@@ -87,7 +88,7 @@ func (c *Cursor) Char() rune {
 	return (*c.line)[c.pos]
 }
 
-// ReplaceWith replaces the rune (unicode point) under the cursor with the provided one.
+// ReplaceWith replaces the character (grapheme cluster) under the cursor with the provided rune.
 // If the cursor is appending to the line, the character is simply added at the end of it.
 func (c *Cursor) ReplaceWith(char rune) {
 	c.CheckAppend()
@@ -96,7 +97,7 @@ func (c *Cursor) ReplaceWith(char rune) {
 	case c.pos == c.line.Len():
 		c.line.Insert(c.line.Len(), char)
 	default:
-		(*c.line)[c.pos] = char
+		c.line.InsertBetween(c.pos, c.line.GraphemeEnd(c.pos), char)
 	}
 }
 
@@ -319,6 +320,8 @@ func (c *Cursor) AtEndOfLine() bool {
 // CheckAppend verifies that the current cursor position is neither negative,
 // nor greater than the length of the input line. If either is true, the
 // cursor will set its value as either 0, or the length of the line.
+// The cursor is also moved back to the beginning of the grapheme cluster
+// it might be in, since it can only be displayed on a whole character.
 func (c *Cursor) CheckAppend() {
 	// Position
 	if c.pos < 0 {
@@ -329,12 +332,14 @@ func (c *Cursor) CheckAppend() {
 		c.pos = c.line.Len()
 	}
 
-	// Mark, invalid position deactivates it.
+	c.pos = c.line.GraphemeStart(c.pos)
+
+	// Mark, invalid position deactivates it (it can be at the end of line).
 	if c.mark < -1 {
 		c.mark = -1
 	}
 
-	if c.mark > c.line.Len()-1 {
+	if c.mark > c.line.Len() {
 		c.mark = -1
 	}
 }
@@ -345,7 +350,7 @@ func (c *Cursor) CheckCommand() {
 	c.CheckAppend()
 
 	if c.pos == c.line.Len() && !c.OnEmptyLine() {
-		c.pos--
+		c.Dec()
 	}
 
 	// The cursor can also not be on a newline sign,
@@ -358,29 +363,51 @@ func (c *Cursor) CheckCommand() {
 // CoordinatesCursor returns the number of real terminal lines above the cursor position
 // (y value), and the number of columns since the beginning of the current line (x value).
 // @indent -    Used to align all lines (except the first) together on a single column.
-func CoordinatesCursor(cur *Cursor, indent int) (x, y int) {
+// @termWidth - The width of the terminal on which the line is displayed.
+func CoordinatesCursor(cur *Cursor, indent, termWidth int) (x, y int) {
+	return CoordinatesCursorIndent(cur, indent, indent, termWidth)
+}
+
+// CoordinatesCursorIndent is like CoordinatesCursor, but the first line starts at the
+// column first, and all other ones at the column next (eg. after a secondary prompt).
+func CoordinatesCursorIndent(cur *Cursor, first, next, termWidth int) (x, y int) {
 	cur.CheckAppend()
 
 	newlines := cur.line.newlines()
 	bpos := 0
 	usedY := 0
+	indent := first
 
 	for pos, newline := range newlines {
+		if pos > 0 {
+			indent = next
+		}
+
 		switch {
 		case newline[0] < cur.pos:
 			// Until we didn't reach the cursor line,
 			// simply care about the line count.
 			line := (*cur.line)[bpos:newline[0]]
 			bpos = newline[0] + 1
-			_, y := strutil.LineSpan(line, pos, indent)
+			_, y := strutil.LineSpan(line, pos, indent, termWidth)
 			usedY += y
 
 		default:
 			// On the cursor line, use both line and column count.
 			line := (*cur.line)[bpos:cur.pos]
-			usedX, y := strutil.LineSpan(line, pos, indent)
+			usedX, y := strutil.LineSpan(line, pos, indent, termWidth)
 			usedY += y
 
+			// A wide character under the cursor not fitting on
+			// the current terminal line is displayed on the next.
+			if cur.pos < newline[0] {
+				char := string((*cur.line)[cur.pos:cur.line.GraphemeEnd(cur.pos)])
+				if usedX+strutil.RealLength(char) > termWidth {
+					usedX = 0
+					usedY++
+				}
+			}
+
 			return usedX, usedY
 		}
 	}
@@ -930,7 +930,7 @@ func TestCursor_CheckAppend(t *testing.T) {
 		},
 		{
 			name:     "Check with negative position",
-			fields:   fields{line: &cursorMultiline, pos: -1, mark: cursorMultiline.Len()},
+			fields:   fields{line: &cursorMultiline, pos: -1, mark: cursorMultiline.Len() + 1},
 			want:     0,
 			wantMark: -1,
 		},
@@ -958,9 +958,6 @@ func TestCursor_CheckAppend(t *testing.T) {
 func TestCursor_Coordinates(t *testing.T) {
 	indent := 2 // Assumes the prompt strings uses two columns
 
-	// Reassign the function for getting the terminal width to a fixed value
-	getTermWidth = func() int { return 80 }
-
 	type fields struct {
 		pos  int
 		mark int
@@ -1005,7 +1002,7 @@ func TestCursor_Coordinates(t *testing.T) {
 				mark: test.fields.mark,
 				line: test.fields.line,
 			}
-			gotX, gotY := CoordinatesCursor(c, indent)
+			gotX, gotY := CoordinatesCursor(c, indent, testTermWidth)
 			if gotX != test.wantX {
 				t.Errorf("Cursor.Coordinates() gotX = %v, want %v", gotX, test.wantX)
 			}
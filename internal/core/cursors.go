@@ -0,0 +1,100 @@
+package core
+
+import "sort"
+
+// AddCursor adds an additional cursor at a position of the line, at which
+// editing commands are also applied when multiple cursor editing is active.
+// A cursor added at the position of the main cursor is only used once the
+// main cursor has moved elsewhere, and a position already used is ignored.
+func (c *Cursor) AddCursor(pos int) {
+	pos = max(min(pos, c.line.Len()), 0)
+
+	if !c.hasCursor(pos) {
+		c.cursors = append(c.cursors, pos)
+		sort.Ints(c.cursors)
+	}
+}
+
+// SetCursors replaces all additional cursors with ones at the given positions.
+func (c *Cursor) SetCursors(positions ...int) {
+	c.cursors = nil
+
+	for _, pos := range positions {
+		switch {
+		case pos < 0:
+			pos = 0
+		case pos > c.line.Len():
+			pos = c.line.Len()
+		}
+
+		if pos == c.pos || c.hasCursor(pos) {
+			continue
+		}
+
+		c.cursors = append(c.cursors, pos)
+	}
+
+	sort.Ints(c.cursors)
+}
+
+// Cursors returns the sorted positions of all additional cursors
+// in the line, excluding those out of the line or at the main cursor.
+func (c *Cursor) Cursors() []int {
+	cursors := make([]int, 0, len(c.cursors))
+
+	for _, pos := range c.cursors {
+		if pos <= c.line.Len() && pos != c.pos {
+			cursors = append(cursors, pos)
+		}
+	}
+
+	return cursors
+}
+
+// ResetCursors removes all additional cursors.
+func (c *Cursor) ResetCursors() {
+	c.cursors = nil
+}
+
+// Apply runs an edit at the main cursor and at all additional ones, starting from
+// the last one in the line, so that the changes made at one cursor do not move those
+// not processed yet. Once done, all cursors are moved by the changes made after them.
+func (c *Cursor) Apply(edit func()) {
+	main := c.pos
+	positions := append(c.Cursors(), main)
+	sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+
+	done := make([]int, 0, len(positions))
+	mainIndex := 0
+
+	for _, pos := range positions {
+		length := c.line.Len()
+
+		c.Set(pos)
+		edit()
+
+		// All cursors already processed are after this one.
+		for i := range done {
+			done[i] += c.line.Len() - length
+		}
+
+		if pos == main {
+			mainIndex = len(done)
+		}
+
+		done = append(done, c.pos)
+	}
+
+	c.Set(done[mainIndex])
+	c.SetCursors(append(done[:mainIndex], done[mainIndex+1:]...)...)
+}
+
+func (c *Cursor) hasCursor(pos int) bool {
+	for _, cursor := range c.cursors {
+		if cursor == pos {
+			return true
+		}
+	}
+
+	return false
+}
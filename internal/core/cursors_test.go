@@ -0,0 +1,153 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCursor_SetCursors(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		pos       int
+		positions []int
+		want      []int
+	}{
+		{
+			name:      "Sorted positions",
+			line:      "one two three",
+			positions: []int{8, 4},
+			want:      []int{4, 8},
+		},
+		{
+			name:      "Duplicates and main cursor",
+			line:      "one two three",
+			pos:       4,
+			positions: []int{8, 4, 8, 0},
+			want:      []int{0, 8},
+		},
+		{
+			name:      "Out of line positions",
+			line:      "one",
+			pos:       1,
+			positions: []int{-2, 10},
+			want:      []int{0, 3},
+		},
+		{
+			name:      "Empty line",
+			line:      "",
+			positions: []int{0, 2},
+			want:      []int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line, cursor := newLine(test.line)
+			cursor.line = &line
+			cursor.pos = test.pos
+
+			cursor.SetCursors(test.positions...)
+
+			if got := cursor.Cursors(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Cursors() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCursor_Cursors(t *testing.T) {
+	line, cursor := newLine("one two three")
+	cursor.line = &line
+	cursor.SetCursors(4, 8, 13)
+
+	// Cursors beyond a shortened line, or joined by
+	// the main one, are not returned anymore.
+	line = line[:6]
+	cursor.pos = 4
+
+	if got, want := cursor.Cursors(), []int{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Cursors() = %v, want %v", got, want)
+	}
+
+	cursor.ResetCursors()
+
+	if got := cursor.Cursors(); len(got) != 0 {
+		t.Errorf("Cursors() after reset = %v, want none", got)
+	}
+}
+
+func TestCursor_Apply(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		pos         int
+		cursors     []int
+		edit        func(line *Line, cursor *Cursor)
+		wantLine    string
+		wantPos     int
+		wantCursors []int
+	}{
+		{
+			name:    "Insert at all cursors",
+			line:    "a b c",
+			pos:     2,
+			cursors: []int{0, 4},
+			edit: func(line *Line, cursor *Cursor) {
+				line.Insert(cursor.Pos(), []rune("xy")...)
+				cursor.Move(2)
+			},
+			wantLine:    "xya xyb xyc",
+			wantPos:     6,
+			wantCursors: []int{2, 10},
+		},
+		{
+			name:    "Delete at all cursors",
+			line:    "aa bb cc",
+			pos:     4,
+			cursors: []int{1, 7},
+			edit: func(line *Line, cursor *Cursor) {
+				line.CutRune(cursor.Pos())
+			},
+			wantLine:    "a b c",
+			wantPos:     3,
+			wantCursors: []int{1, 5},
+		},
+		{
+			name:    "Backward delete merging cursors",
+			line:    "abc",
+			pos:     2,
+			cursors: []int{1},
+			edit: func(line *Line, cursor *Cursor) {
+				line.CutRune(cursor.Pos() - 1)
+				cursor.Dec()
+			},
+			wantLine:    "c",
+			wantPos:     0,
+			wantCursors: []int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line, cursor := newLine(test.line)
+			cursor.line = &line
+			cursor.pos = test.pos
+			cursor.SetCursors(test.cursors...)
+
+			cursor.Apply(func() { test.edit(&line, &cursor) })
+
+			if string(line) != test.wantLine {
+				t.Errorf("Line = %q, want %q", string(line), test.wantLine)
+			}
+
+			if cursor.Pos() != test.wantPos {
+				t.Errorf("Pos() = %d, want %d", cursor.Pos(), test.wantPos)
+			}
+
+			if got := cursor.Cursors(); !reflect.DeepEqual(got, test.wantCursors) {
+				t.Errorf("Cursors() = %v, want %v", got, test.wantCursors)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Graphemes returns the positions in the line at which each grapheme cluster
+// begins: a cluster being what is displayed as a single character (an emoji
+// and its modifiers, ZWJ sequences, a letter followed by combining marks, etc).
+func (l *Line) Graphemes() []int {
+	bounds := make([]int, 0, len(*l))
+
+	rest, state, pos := string(*l), -1, 0
+
+	for len(rest) > 0 {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+
+		bounds = append(bounds, pos)
+		pos += utf8.RuneCountInString(cluster)
+	}
+
+	return bounds
+}
+
+// GraphemeStart returns the position at which the grapheme cluster containing pos
+// begins. If the position is out of the line bounds, it is returned unchanged.
+func (l *Line) GraphemeStart(pos int) int {
+	if pos <= 0 || pos >= l.Len() {
+		return pos
+	}
+
+	// Fast path: ASCII characters are always clusters on their own, except CRLF.
+	prev, char := (*l)[pos-1], (*l)[pos]
+	if prev < utf8.RuneSelf && char < utf8.RuneSelf && (prev != '\r' || char != '\n') {
+		return pos
+	}
+
+	start, _ := l.graphemeAt(pos)
+
+	return start
+}
+
+// GraphemeEnd returns the position right after the grapheme cluster containing pos.
+// If the position is out of the line bounds, it is returned unchanged.
+func (l *Line) GraphemeEnd(pos int) int {
+	if pos < 0 || pos >= l.Len() {
+		return pos
+	}
+
+	// Fast path: ASCII characters are always clusters on their own, except CRLF.
+	char := (*l)[pos]
+	if char < utf8.RuneSelf && char != '\r' && (pos+1 == l.Len() || (*l)[pos+1] < utf8.RuneSelf) {
+		return pos + 1
+	}
+
+	_, end := l.graphemeAt(pos)
+
+	return end
+}
+
+// CutGrapheme deletes the grapheme cluster containing the given position,
+// and returns its runes. If the position is out of bounds, nothing is deleted.
+func (l *Line) CutGrapheme(pos int) []rune {
+	if pos < 0 || pos >= l.Len() {
+		return nil
+	}
+
+	bpos, epos := l.GraphemeStart(pos), l.GraphemeEnd(pos)
+	cut := append([]rune{}, (*l)[bpos:epos]...)
+
+	l.Cut(bpos, epos)
+
+	return cut
+}
+
+// graphemeAt returns the bounds of the grapheme cluster containing pos. Only the
+// runes between the closest cluster bounds known around pos are segmented, so
+// that moving in long lines does not segment them entirely at each keystroke.
+func (l *Line) graphemeAt(pos int) (start, end int) {
+	start, stop := pos, pos+1
+
+	for !l.isClusterBound(start) {
+		start--
+	}
+
+	for !l.isClusterBound(stop) {
+		stop++
+	}
+
+	rest, state := string((*l)[start:stop]), -1
+
+	for len(rest) > 0 {
+		var cluster string
+		cluster, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+
+		end = start + utf8.RuneCountInString(cluster)
+		if end > pos {
+			break
+		}
+
+		start = end
+	}
+
+	return start, end
+}
+
+// isClusterBound returns true if a grapheme cluster is known to begin at pos
+// without segmenting the line: at its bounds, and between two ASCII characters
+// other than CRLF (an ASCII character cannot be a prepended or extending one).
+func (l *Line) isClusterBound(pos int) bool {
+	if pos <= 0 || pos >= l.Len() {
+		return true
+	}
+
+	prev, char := (*l)[pos-1], (*l)[pos]
+
+	return prev < utf8.RuneSelf && char < utf8.RuneSelf && (prev != '\r' || char != '\n')
+}
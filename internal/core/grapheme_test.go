@@ -0,0 +1,113 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLine_Graphemes(t *testing.T) {
+	tests := []struct {
+		name string
+		l    Line
+		want []int
+	}{
+		{
+			name: "ASCII line",
+			l:    Line("git"),
+			want: []int{0, 1, 2},
+		},
+		{
+			name: "Combining marks",
+			l:    Line("cafe\u0301 ok"),
+			want: []int{0, 1, 2, 3, 5, 6, 7},
+		},
+		{
+			name: "ZWJ emoji sequence",
+			l:    Line("a\U0001F469\u200D\U0001F4BBb"),
+			want: []int{0, 1, 4},
+		},
+		{
+			name: "Wide characters",
+			l:    Line("日本語"),
+			want: []int{0, 1, 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.l.Graphemes(); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Line.Graphemes() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLine_GraphemeStartEnd(t *testing.T) {
+	lines := []Line{
+		Line("git"),
+		Line("cafe\u0301 ok"),
+		Line("a\U0001F469\u200D\U0001F4BBb"),
+		Line("日本語 x"),
+		Line("ab\r\ncd"),
+		Line("\U0001F1EB\U0001F1F7\U0001F1E9\U0001F1EA\U0001F1EB"),
+		Line("x \u06001 y"),
+	}
+
+	// Clusters found around each position match those of the whole line.
+	for _, line := range lines {
+		bounds := append(line.Graphemes(), line.Len())
+
+		for cluster := 0; cluster < len(bounds)-1; cluster++ {
+			for pos := bounds[cluster]; pos < bounds[cluster+1]; pos++ {
+				if got := line.GraphemeStart(pos); got != bounds[cluster] {
+					t.Errorf("Line(%q).GraphemeStart(%d) = %d, want %d", string(line), pos, got, bounds[cluster])
+				}
+
+				if got := line.GraphemeEnd(pos); got != bounds[cluster+1] {
+					t.Errorf("Line(%q).GraphemeEnd(%d) = %d, want %d", string(line), pos, got, bounds[cluster+1])
+				}
+			}
+		}
+	}
+}
+
+func TestCursor_IncDec(t *testing.T) {
+	line := Line("a\U0001F469\u200D\U0001F4BBe\u0301")
+	cur := NewCursor(&line)
+
+	var forward []int
+	for i := 0; i < 4; i++ {
+		cur.Inc()
+		forward = append(forward, cur.Pos())
+	}
+
+	if want := []int{1, 4, 6, 6}; !reflect.DeepEqual(forward, want) {
+		t.Errorf("Cursor.Inc() positions = %v, want %v", forward, want)
+	}
+
+	var backward []int
+	for i := 0; i < 4; i++ {
+		cur.Dec()
+		backward = append(backward, cur.Pos())
+	}
+
+	if want := []int{4, 1, 0, 0}; !reflect.DeepEqual(backward, want) {
+		t.Errorf("Cursor.Dec() positions = %v, want %v", backward, want)
+	}
+
+	// Positions inside a cluster are moved to its beginning.
+	cur.Set(2)
+	if cur.Pos() != 1 {
+		t.Errorf("Cursor.Set() in cluster = %v, want %v", cur.Pos(), 1)
+	}
+}
+
+func TestLine_CutGrapheme(t *testing.T) {
+	line := Line("xe\u0301y")
+	want := "e\u0301"
+
+	cut := line.CutGrapheme(2)
+	if string(cut) != want || string(line) != "xy" {
+		t.Errorf("Line.CutGrapheme() = %q (line %q), want %q (line %q)", string(cut), string(line), want, "xy")
+	}
+}
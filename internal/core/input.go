@@ -0,0 +1,201 @@
+package core
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Events ending a wait for input keys.
+type waitEvent int
+
+const (
+	inputReady   waitEvent = iota // Keys can be read.
+	inputTimeout                  // The timeout has expired.
+	inputDone                     // The done channel has been closed.
+	inputWoken                    // The shell has been woken up.
+)
+
+// input is the stream from which the shell reads its keys. Terminal files (and the
+// Windows console) are polled for keys before being read, so that waits for keys
+// can be given up on, but other streams (eg. SSH channels or pipes) cannot: they
+// are read in the background instead, one read at a time and only when keys are
+// needed. The keys read are kept for the next read if the wait has been given up
+// on, so that none is lost, even across several calls to Readline.
+type input struct {
+	reader  io.Reader
+	pending chan inputRead // Result of the background read in progress, if any.
+	ready   *inputRead     // Result of a background read, not consumed yet.
+}
+
+// inputRead is the result of a read on a stream.
+type inputRead struct {
+	keys []byte
+	err  error
+}
+
+// newInput returns the input reading keys from the reader,
+// or from the process standard input if the reader is nil.
+func newInput(reader io.Reader) *input {
+	if reader == nil {
+		reader = processInput()
+	}
+
+	return &input{reader: reader}
+}
+
+// wait waits until keys can be read and returns inputReady, or returns another event
+// if the timeout expires (never if zero or negative) or if the done or wake channels
+// are signaled first (either of them can be nil). A closed done channel always wins.
+func (in *input) wait(timeout time.Duration, done <-chan struct{}, wake chan struct{}) waitEvent {
+	select {
+	case <-done:
+		return inputDone
+	default:
+	}
+
+	if in.pollable() {
+		return in.waitPoll(timeout, done, wake)
+	}
+
+	if in.ready != nil {
+		return inputReady
+	}
+
+	in.readBackground()
+
+	var expired <-chan time.Time
+
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		expired = timer.C
+	}
+
+	select {
+	case read := <-in.pending:
+		in.pending = nil
+		in.ready = &read
+
+		return inputReady
+	case <-expired:
+		return inputTimeout
+	case <-done:
+		return inputDone
+	case <-wake:
+		return inputWoken
+	}
+}
+
+// available returns true if keys can be read without blocking. Streams
+// that cannot be polled only have keys once a background read is done.
+func (in *input) available() bool {
+	if in.pollable() {
+		return in.waitPoll(pollNow, nil, nil) == inputReady
+	}
+
+	if in.ready != nil {
+		return true
+	}
+
+	if in.pending == nil {
+		return false
+	}
+
+	select {
+	case read := <-in.pending:
+		in.pending = nil
+		in.ready = &read
+
+		return true
+	default:
+		return false
+	}
+}
+
+// read reads some keys, waiting for the background read in progress if any.
+func (in *input) read() ([]byte, error) {
+	switch {
+	case in.ready != nil:
+		read := *in.ready
+		in.ready = nil
+
+		return read.keys, read.err
+
+	case in.pending != nil:
+		read := <-in.pending
+		in.pending = nil
+
+		return read.keys, read.err
+
+	default:
+		buf := make([]byte, keyScanBufSize)
+		n, err := in.reader.Read(buf)
+
+		return buf[:n], err
+	}
+}
+
+// readBackground starts reading the stream in the background, unless already done.
+func (in *input) readBackground() {
+	if in.pending != nil {
+		return
+	}
+
+	pending := make(chan inputRead, 1)
+	in.pending = pending
+
+	go func() {
+		buf := make([]byte, keyScanBufSize)
+		n, err := in.reader.Read(buf)
+
+		pending <- inputRead{keys: buf[:n], err: err}
+	}()
+}
+
+// Reader returns a reader of the shell input, for a command run by the shell (eg. the
+// system editor). Terminal files are returned as is. Other streams are wrapped in a
+// reader which must be closed once the command exits: reads then return io.EOF, and
+// the keys they were waiting for are kept for the shell.
+func (k *Keys) Reader() io.Reader {
+	if k.input.pollable() {
+		return k.input.reader
+	}
+
+	return &stoppableReader{input: k.input, stop: make(chan struct{})}
+}
+
+// stoppableReader reads the shell input stream until closed.
+type stoppableReader struct {
+	input *input
+	stop  chan struct{}
+	once  sync.Once
+	keys  []byte
+}
+
+func (r *stoppableReader) Read(buf []byte) (int, error) {
+	if len(r.keys) == 0 {
+		if r.input.wait(0, r.stop, nil) != inputReady {
+			return 0, io.EOF
+		}
+
+		keys, err := r.input.read()
+		if len(keys) == 0 {
+			return 0, err
+		}
+
+		r.keys = keys
+	}
+
+	n := copy(buf, r.keys)
+	r.keys = r.keys[n:]
+
+	return n, nil
+}
+
+// Close stops the reads in progress, and the next ones.
+func (r *stoppableReader) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	return nil
+}
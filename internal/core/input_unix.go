@@ -0,0 +1,126 @@
+//go:build unix
+
+package core
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollNow makes waitPoll return immediately, whether keys are available or not.
+const pollNow time.Duration = -1
+
+// processInput returns the process standard input.
+func processInput() io.Reader {
+	return os.Stdin
+}
+
+// pollable returns true if the input is a file, and can be polled.
+func (in *input) pollable() bool {
+	_, isFile := in.reader.(*os.File)
+	return isFile
+}
+
+// waitPoll polls the input file until keys are available, or the timeout expires
+// (never if zero, see pollNow). The done and wake channels interrupt the poll by
+// writing to a pipe polled along with the input, so that waits are not delayed.
+func (in *input) waitPoll(timeout time.Duration, done <-chan struct{}, wake chan struct{}) (event waitEvent) {
+	file := in.reader.(*os.File)
+	fds := []unix.PollFd{{Fd: int32(file.Fd()), Events: unix.POLLIN}}
+
+	if done != nil || wake != nil {
+		interrupt, stop := interruptPoll(done, wake)
+		defer func() { event = stop(event) }()
+
+		if interrupt >= 0 {
+			fds = append(fds, unix.PollFd{Fd: int32(interrupt), Events: unix.POLLIN})
+		}
+	}
+
+	msec := -1
+
+	switch {
+	case timeout == pollNow:
+		msec = 0
+	case timeout > 0:
+		msec = max(int(timeout.Milliseconds()), 1)
+	}
+
+	for {
+		ready, err := unix.Poll(fds, msec)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+
+		switch {
+		case err != nil, fds[0].Revents != 0:
+			return inputReady
+		case ready == 0:
+			return inputTimeout
+		default:
+			// Interrupted: the event is returned by stop.
+			return inputTimeout
+		}
+	}
+}
+
+// interruptPoll returns the read end of a pipe written to once the done channel is
+// closed or the wake one signaled (-1 if the pipe cannot be created), and a function
+// to call once the poll is done: it returns the event that interrupted it if any, or
+// else the one passed to it. A wake up consumed without interrupting it is signaled again.
+func interruptPoll(done <-chan struct{}, wake chan struct{}) (int, func(event waitEvent) waitEvent) {
+	var pipe [2]int
+
+	if err := unix.Pipe(pipe[:]); err != nil {
+		return -1, func(event waitEvent) waitEvent { return event }
+	}
+
+	events := make(chan waitEvent, 1)
+	stopping := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-done:
+			events <- inputDone
+		case <-wake:
+			events <- inputWoken
+		case <-stopping:
+			return
+		}
+
+		unix.Write(pipe[1], []byte{0})
+	}()
+
+	stop := func(event waitEvent) waitEvent {
+		close(stopping)
+		<-stopped
+
+		unix.Close(pipe[0])
+		unix.Close(pipe[1])
+
+		select {
+		case interrupted := <-events:
+			if event == inputReady && interrupted == inputWoken {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+
+				return event
+			}
+
+			return interrupted
+		default:
+			return event
+		}
+	}
+
+	return pipe[0], stop
+}
@@ -0,0 +1,69 @@
+//go:build windows
+// +build windows
+
+package core
+
+import (
+	"io"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// pollNow makes waitPoll return immediately, whether keys are available or not.
+	pollNow time.Duration = -1
+
+	// eventCheckInterval is how often the done and wake channels
+	// are checked for when waiting for console input events.
+	eventCheckInterval = 50 * time.Millisecond
+)
+
+// processInput returns the console input, translated to ANSI sequences.
+func processInput() io.Reader {
+	return newRawReader()
+}
+
+// pollable returns true if the input is the console, and can be waited for.
+func (in *input) pollable() bool {
+	_, isConsole := in.reader.(*rawReader)
+	return isConsole
+}
+
+// waitPoll waits for console input events until keys are available, or the timeout
+// expires (never if zero, see pollNow). The done and wake channels are checked for
+// between waits, every eventCheckInterval at most.
+func (in *input) waitPoll(timeout time.Duration, done <-chan struct{}, wake chan struct{}) waitEvent {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		select {
+		case <-done:
+			return inputDone
+		case <-wake:
+			return inputWoken
+		default:
+		}
+
+		wait := eventCheckInterval
+
+		switch {
+		case timeout == pollNow:
+			wait = 0
+		case timeout > 0:
+			wait = min(wait, time.Until(deadline))
+		}
+
+		event, err := windows.WaitForSingleObject(windows.Handle(stdin), uint32(max(wait, 0).Milliseconds()))
+		if err != nil || event != uint32(windows.WAIT_TIMEOUT) {
+			return inputReady
+		}
+
+		if timeout == pollNow || (timeout > 0 && !time.Now().Before(deadline)) {
+			return inputTimeout
+		}
+	}
+}
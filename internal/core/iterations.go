@@ -4,15 +4,18 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/reeflective/readline/internal/color"
 )
 
 // Iterations manages iterations for commands.
 type Iterations struct {
-	times   string // Stores iteration value
-	active  bool   // Are we currently setting the iterations.
-	pending bool   // Has the last command been an iteration one (vi-pending style)
+	times     string // Stores iteration value
+	active    bool   // Are we currently setting the iterations.
+	pending   bool   // Has the last command been an iteration one (vi-pending style)
+	universal bool   // Digits and minus signs typed are added (after universal-argument).
+	digits    bool   // Digits have been typed since the last universal-argument.
 }
 
 // Add accepts a string to be converted as an integer representing
@@ -32,7 +35,24 @@ func (i *Iterations) Add(times string) {
 	i.active = true
 	i.pending = true
 
+	// After universal-argument, a minus sign makes the argument -1,
+	// and the first digits typed replace its multiplied value.
+	if i.universal && !i.digits {
+		if times == "-" {
+			i.times = "-"
+			return
+		}
+
+		if i.times != "-" {
+			i.times = ""
+		}
+
+		i.digits = true
+	}
+
 	switch {
+	case times == "-" && strings.HasPrefix(i.times, "-"):
+		i.times = strings.TrimPrefix(i.times, "-")
 	case times == "-":
 		i.times = times + i.times
 	case strings.HasPrefix(times, "-"):
@@ -42,6 +62,46 @@ func (i *Iterations) Add(times string) {
 	}
 }
 
+// Universal multiplies the iterations by four (starting from one), as does
+// the universal-argument command: digits and minus signs typed afterwards
+// are then added to the iterations, the first digit replacing the multiplied
+// value. If digits have been typed since, the numeric argument is ended
+// instead, so that digits typed afterwards are inserted.
+func (i *Iterations) Universal() {
+	i.active = true
+	i.pending = true
+
+	if i.universal && i.digits {
+		i.universal = false
+		return
+	}
+
+	negative := strings.HasPrefix(i.times, "-")
+
+	times, err := strconv.Atoi(strings.TrimPrefix(i.times, "-"))
+	if err != nil || times == 0 {
+		times = 1
+	}
+
+	i.times = strconv.Itoa(times * 4)
+	if negative {
+		i.times = "-" + i.times
+	}
+
+	i.universal = true
+	i.digits = false
+}
+
+// Accepts returns true if a typed key should be added to the iterations rather
+// than inserted: digits and minus signs (before digits) after universal-argument.
+func (i *Iterations) Accepts(key rune) bool {
+	if !i.universal {
+		return false
+	}
+
+	return unicode.IsDigit(key) || (key == '-' && !i.digits)
+}
+
 // Get returns the number of iterations (possibly
 // negative), and resets the iterations to 1.
 func (i *Iterations) Get() int {
@@ -62,6 +122,7 @@ func (i *Iterations) Get() int {
 	}
 
 	i.times = ""
+	i.universal = false
 
 	return times
 }
@@ -82,13 +143,14 @@ func (i *Iterations) Reset() {
 	i.times = ""
 	i.active = false
 	i.pending = false
+	i.universal = false
 }
 
 // ResetPostRunIterations resets the iterations if the last command didn't set them.
 // If the reset operated on active iterations, this function returns true.
-func ResetPostRunIterations(iter *Iterations) (hint string) {
+func ResetPostRunIterations(iter *Iterations, styles *color.Styles) (hint string) {
 	if iter.pending {
-		hint = color.Dim + fmt.Sprintf("(arg: %s)", iter.times)
+		hint = styles.Hint + fmt.Sprintf("(arg: %s)", iter.times)
 	}
 
 	if iter.pending {
@@ -98,6 +160,7 @@ func ResetPostRunIterations(iter *Iterations) (hint string) {
 	}
 
 	iter.active = false
+	iter.universal = false
 
 	return
 }
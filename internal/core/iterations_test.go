@@ -264,9 +264,49 @@ func TestResetPostRunIterations(t *testing.T) {
 			test.args.iter.Add(test.fields.times)
 			test.args.iter.pending = test.fields.pending
 
-			if gotHint := ResetPostRunIterations(test.args.iter); gotHint != test.wantHint {
+			if gotHint := ResetPostRunIterations(test.args.iter, color.NewStyles()); gotHint != test.wantHint {
 				t.Errorf("ResetPostRunIterations() = %v, want %v", gotHint, test.wantHint)
 			}
 		})
 	}
 }
+
+func TestIterations_Universal(t *testing.T) {
+	tests := []struct {
+		name string
+		keys string // u for universal-argument, other keys added if accepted.
+		want int
+		left string // Keys not accepted.
+	}{
+		{name: "Universal argument alone (4)", keys: "u", want: 4},
+		{name: "Repeated universal argument (16)", keys: "uu", want: 16},
+		{name: "Digits after universal argument (12)", keys: "u12", want: 12},
+		{name: "Minus sign after universal argument (-1)", keys: "u-", want: -1},
+		{name: "Negative digits after universal argument (-3)", keys: "u-3", want: -3},
+		{name: "Universal argument ended by another one (3)", keys: "u3u5", want: 3, left: "5"},
+		{name: "Minus sign after digits (2)", keys: "u2-", want: 2, left: "-"},
+		{name: "No universal argument (1)", keys: "5", want: 1, left: "5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			iter := &Iterations{}
+			left := ""
+
+			for _, key := range test.keys {
+				switch {
+				case key == 'u':
+					iter.Universal()
+				case iter.Accepts(key):
+					iter.Add(string(key))
+				default:
+					left += string(key)
+				}
+			}
+
+			if got := iter.Get(); got != test.want || left != test.left {
+				t.Errorf("Iterations.Get() = %d (keys left: %q), want %d (keys left: %q)", got, left, test.want, test.left)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// Key modifiers of the enhanced keyboard protocols,
+// encoded in sequences as 1 + the sum of all modifiers.
+const (
+	modShift = 1 << iota
+	modAlt
+	modCtrl
+	modSuper
+	modHyper
+	modMeta
+	modCapsLock
+	modNumLock
+)
+
+// Key event types of the kitty keyboard protocol
+// (repeat events are handled like press ones).
+const (
+	eventPress   = 1
+	eventRelease = 3
+)
+
+// Unicode private use area, in which the kitty keyboard protocol
+// encodes functional keys without legacy encodings (keypad, media, F13+).
+const (
+	privateUseStart = 57344
+	privateUseEnd   = 63743
+)
+
+// decodeKeyboard decodes the keys sent with the kitty keyboard protocol or with
+// xterm's modifyOtherKeys. Such keys are given their canonical CSI u encoding
+// (ESC [ code ; modifiers u, or ESC [ code ; modifiers : 3 u for releases)
+// if this sequence is bound to a command in any keymap, so that Ctrl-I can be
+// bound independently from Tab, Shift-Enter from Enter, etc.
+// Otherwise, keys are converted back to their legacy encoding, and key release
+// events are dropped. All other keys and sequences are left untouched.
+func decodeKeyboard(buf []byte, bound func(seq string) bool) []byte {
+	keys := make([]byte, 0, len(buf))
+
+	for pos := 0; pos < len(buf); {
+		if buf[pos] != byte(inputrc.Esc) || pos+1 == len(buf) || buf[pos+1] != '[' {
+			keys = append(keys, buf[pos])
+			pos++
+
+			continue
+		}
+
+		// Find the end of the CSI sequence parameters.
+		end := pos + 2
+		for end < len(buf) && strings.IndexByte("0123456789;:", buf[end]) >= 0 {
+			end++
+		}
+
+		if end == len(buf) || buf[end] < '@' || buf[end] > '~' {
+			keys = append(keys, buf[pos])
+			pos++
+
+			continue
+		}
+
+		keys = append(keys, decodeSequence(string(buf[pos+2:end]), buf[end], bound)...)
+		pos = end + 1
+	}
+
+	return keys
+}
+
+// incompleteSequence returns true if the keys end with the beginning of a CSI
+// sequence (ESC [ followed by parameters), the rest of which is not read yet.
+func incompleteSequence(keys []byte) bool {
+	start := bytes.LastIndex(keys, []byte("\x1b["))
+	if start == -1 {
+		return false
+	}
+
+	for _, key := range keys[start+2:] {
+		if key < ' ' || key > '?' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeSequence decodes a single CSI sequence, given its parameters and final byte.
+func decodeSequence(params string, final byte, bound func(seq string) bool) []byte {
+	fields := strings.Split(params, ";")
+
+	switch {
+	// Kitty keyboard protocol: CSI code ; modifiers:event u
+	case final == 'u' && params != "":
+		code, _, _ := strings.Cut(fields[0], ":")
+		mods, event := "1", ""
+
+		if len(fields) > 1 {
+			mods, event, _ = strings.Cut(fields[1], ":")
+		}
+
+		return decodeKey(atoi(code), atoi(mods), atoi(event), bound)
+
+	// ModifyOtherKeys: CSI 27 ; modifiers ; code ~
+	case final == '~' && len(fields) == 3 && fields[0] == "27":
+		return decodeKey(atoi(fields[2]), atoi(fields[1]), eventPress, bound)
+
+	// Legacy functional keys (arrows, etc) with a kitty event type.
+	case len(fields) == 2 && strings.Contains(fields[1], ":"):
+		mods, event, _ := strings.Cut(fields[1], ":")
+		release := "\x1b[" + params + string(final)
+
+		if atoi(event) == eventRelease {
+			if bound(release) {
+				return []byte(release)
+			}
+
+			return nil
+		}
+
+		if atoi(mods) > 1 {
+			return []byte("\x1b[" + fields[0] + ";" + mods + string(final))
+		}
+
+		if fields[0] == "1" {
+			return []byte("\x1b[" + string(final))
+		}
+
+		return []byte("\x1b[" + fields[0] + string(final))
+	}
+
+	return []byte("\x1b[" + params + string(final))
+}
+
+// decodeKey returns the canonical CSI u encoding of a key if it is bound,
+// or its legacy encoding, or nothing for unbound key release events.
+func decodeKey(code, mods, event int, bound func(seq string) bool) []byte {
+	if mods < 1 {
+		mods = 1
+	}
+
+	// Lock modifiers are irrelevant to binds.
+	mods = 1 + (mods-1)&^(modCapsLock|modNumLock)
+
+	var seq string
+
+	switch {
+	case event == eventRelease:
+		seq = "\x1b[" + strconv.Itoa(code) + ";" + strconv.Itoa(mods) + ":3u"
+	case mods > 1:
+		seq = "\x1b[" + strconv.Itoa(code) + ";" + strconv.Itoa(mods) + "u"
+	default:
+		seq = "\x1b[" + strconv.Itoa(code) + "u"
+	}
+
+	if bound(seq) {
+		return []byte(seq)
+	}
+
+	if event == eventRelease {
+		return nil
+	}
+
+	return legacyKey(rune(code), mods-1)
+}
+
+// legacyKey returns the legacy encoding of a key with some
+// modifiers, or nothing if the key cannot be encoded this way.
+func legacyKey(key rune, mods int) []byte {
+	if mods&(modSuper|modHyper|modMeta) != 0 {
+		return nil
+	}
+
+	if key >= privateUseStart && key <= privateUseEnd {
+		return nil
+	}
+
+	var keys []byte
+
+	switch {
+	case key == '\t' && mods&modShift != 0:
+		keys = []byte("\x1b[Z")
+	case mods&modCtrl != 0:
+		keys = []byte(string(controlKey(key)))
+	case mods&modShift != 0:
+		keys = []byte(string(unicode.ToUpper(key)))
+	default:
+		keys = []byte(string(key))
+	}
+
+	if mods&modAlt != 0 {
+		keys = append([]byte{byte(inputrc.Esc)}, keys...)
+	}
+
+	return keys
+}
+
+// controlKey returns the control character for a key, or the key itself.
+func controlKey(key rune) rune {
+	switch {
+	case key >= 'a' && key <= 'z', key >= '@' && key <= '_':
+		return inputrc.Encontrol(key)
+	case key == ' ':
+		return 0
+	case key == '?':
+		return inputrc.Delete
+	default:
+		return key
+	}
+}
+
+// isBound returns true if the sequence is bound in any keymap.
+func (k *Keys) isBound(seq string) bool {
+	for _, binds := range k.cfg.Binds {
+		if _, found := binds[seq]; found {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeProtocol decodes the keys sent with an enhanced keyboard
+// protocol, if one is enabled (see decodeKeyboard for details).
+func (k *Keys) decodeProtocol(keys []byte) []byte {
+	if k.cfg == nil {
+		return keys
+	}
+
+	switch k.cfg.GetString("keyboard-protocol") {
+	case "", "legacy":
+		return keys
+	}
+
+	return decodeKeyboard(keys, k.isBound)
+}
+
+func atoi(str string) int {
+	num, _ := strconv.Atoi(str)
+	return num
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"testing"
+)
+
+func Test_decodeKeyboard(t *testing.T) {
+	binds := map[string]bool{
+		"\x1b[105;5u":  true, // Ctrl-I
+		"\x1b[13;2u":   true, // Shift-Enter
+		"\x1b[97;6u":   true, // Ctrl-Shift-A
+		"\x1b[97;1:3u": true, // Release of a
+		"\x1b[1;1:3A":  true, // Release of Up
+	}
+
+	bound := func(seq string) bool { return binds[seq] }
+
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{name: "Legacy keys", keys: "ab\x1b[A\x1b[1;5C\r", want: "ab\x1b[A\x1b[1;5C\r"},
+		{name: "Bound Ctrl-I", keys: "\x1b[105;5u", want: "\x1b[105;5u"},
+		{name: "Tab", keys: "\t", want: "\t"},
+		{name: "Bound Shift-Enter", keys: "\x1b[13;2u", want: "\x1b[13;2u"},
+		{name: "Unbound Ctrl-Enter", keys: "\x1b[13;5u", want: "\r"},
+		{name: "Unbound Ctrl-M with caps lock", keys: "\x1b[109;69u", want: "\r"},
+		{name: "Bound Ctrl-Shift-A (modifyOtherKeys)", keys: "\x1b[27;6;97~", want: "\x1b[97;6u"},
+		{name: "Unbound Ctrl-Shift-B", keys: "\x1b[98;6u", want: "\x02"},
+		{name: "Unbound Alt-Shift-X", keys: "\x1b[120;4u", want: "\x1bX"},
+		{name: "Unbound Shift-Tab", keys: "\x1b[9;2u", want: "\x1b[Z"},
+		{name: "Unbound Ctrl-Alt-A", keys: "\x1b[97;7u", want: "\x1b\x01"},
+		{name: "Unbound Ctrl-Space", keys: "\x1b[32;5u", want: "\x00"},
+		{name: "Unbound Super-A", keys: "\x1b[97;9u", want: ""},
+		{name: "Unbound Shift-e acute", keys: "\x1b[233;2u", want: "\u00c9"},
+		{name: "Unfinished sequence", keys: "a\x1b[1;", want: "a\x1b[1;"},
+		{name: "Escape", keys: "\x1b[27u", want: "\x1b"},
+		{name: "Bound release", keys: "a\x1b[97;1:3u", want: "a\x1b[97;1:3u"},
+		{name: "Unbound release", keys: "b\x1b[98;1:3u", want: "b"},
+		{name: "Repeat", keys: "\x1b[98;5:2u", want: "\x02"},
+		{name: "Bound functional key release", keys: "\x1b[1;1:3A", want: "\x1b[1;1:3A"},
+		{name: "Unbound functional key release", keys: "\x1b[1;1:3B", want: ""},
+		{name: "Functional key repeat", keys: "\x1b[1;1:2B\x1b[3;5:2~", want: "\x1b[B\x1b[3;5~"},
+		{name: "Unbound private use key", keys: "\x1b[57399u", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(decodeKeyboard([]byte(test.keys), bound)); got != test.want {
+				t.Errorf("decodeKeyboard() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -1,47 +1,74 @@
 package core
 
 import (
-	"errors"
 	"io"
-	"os"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/strutil"
+	"github.com/reeflective/readline/internal/term"
 )
 
 const (
 	keyScanBufSize = 1024
-)
 
-// Stdin is used by the Keys struct to read and write keys.
-// It can be overwritten to use other file descriptors or
-// custom io.Readers, such as the one used on Windows.
-var Stdin io.ReadCloser = os.Stdin
+	// sequenceTimeout is how long the rest of an escape sequence split
+	// across several reads is waited for, before using its keys as is.
+	sequenceTimeout = 50 * time.Millisecond
+)
 
 var rxRcvCursorPos = regexp.MustCompile(`\x1b\[([0-9]+);([0-9]+)R`)
 
 // Keys is used read, manage and use keys input by the shell user.
 type Keys struct {
-	buf       []byte      // Keys read and waiting to be used.
-	matched   []rune      // Keys that have been successfully matched against a bind.
-	macroKeys []rune      // Keys that have been fed by a macro.
-	mustWait  bool        // Keys are in the stack, but we must still read stdin.
-	waiting   bool        // Currently waiting for keys on stdin.
-	reading   bool        // Currently reading keys out of the main loop.
-	keysOnce  chan []byte // Passing keys from the main routine.
-	cursor    chan []byte // Cursor coordinates has been read on stdin.
-	resize    chan bool   // Resize events on Windows are sent on stdin.
+	input     *input          // Stream from which keys are read.
+	term      *term.Terminal  // Terminal to which queries are written.
+	buf       []byte          // Keys read and waiting to be used.
+	matched   []rune          // Keys that have been successfully matched against a bind.
+	macroKeys []rune          // Keys that have been fed by a macro.
+	mustWait  bool            // Keys are in the stack, but we must still read stdin.
+	timeout   time.Duration   // Maximum wait for keys completing a prefix (none if 0).
+	timedOut  bool            // The last wait for keys has timed out.
+	delay     time.Duration   // Maximum wait for any keys (none if 0).
+	delayed   bool            // The last wait for keys has reached the delay.
+	done      <-chan struct{} // Closed when waiting for keys must be aborted.
+	cancelled bool            // The last wait for keys has been aborted.
+	closed    bool            // The input stream has been closed (EOF).
+	wake      chan struct{}   // Signaled to stop waiting for keys, to process other events.
+	woken     bool            // The last wait for keys has been interrupted by a wake up.
+	waiting   bool            // Currently waiting for keys on the input.
+	reading   bool            // Currently reading keys out of the main loop.
+	keysOnce  chan []byte     // Passing keys from the main routine.
+	cursor    chan []byte     // Cursor coordinates has been read on the input.
+	resize    chan bool       // Resize events on Windows are sent on the input.
 
 	cfg   *inputrc.Config // Configuration file used for meta key settings
 	mutex sync.RWMutex    // Concurrency safety
 }
 
-// WaitAvailableKeys waits until an input key is either read from standard input,
+// NewKeys returns the keys read from the input stream (or from the process
+// standard input if nil), answering the queries written to the terminal.
+func NewKeys(in io.Reader, terminal *term.Terminal) *Keys {
+	return &Keys{
+		input: newInput(in),
+		term:  terminal,
+	}
+}
+
+// WaitAvailableKeys waits until an input key is either read from the shell input,
 // or directly returns if the key stack still/already has available keys.
 func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
 	keys.cfg = cfg
+	keys.timedOut = false
+	keys.delayed = false
+	keys.cancelled = false
+	keys.closed = false
+	keys.woken = false
+
+	timeout, delay := keys.timeout, keys.delay
+	keys.timeout, keys.delay = 0, 0
 
 	if len(keys.buf) > 0 && !keys.mustWait {
 		return
@@ -52,6 +79,8 @@ func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
 		return
 	}
 
+	wake := keys.wakeup()
+
 	keys.mutex.Lock()
 	keys.waiting = true
 	keys.cursor = make(chan []byte)
@@ -64,11 +93,40 @@ func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
 	}()
 
 	for {
-		// Start reading from os.Stdin in the background.
-		// We will either read keyBuf from user, or an EOF
-		// send by ourselves, because we pause reading.
-		keyBuf, err := keys.readInputFiltered()
-		if err != nil && errors.Is(err, io.EOF) {
+		// When waiting for keys completing a prefix, give up after
+		// the timeout, so that the shorter bind can be used instead.
+		var wait time.Duration
+		if keys.mustWait {
+			wait = timeout
+		}
+
+		// Otherwise, give up after the delay if there is one.
+		delaying := delay > 0 && (wait <= 0 || delay < wait)
+		if delaying {
+			wait = delay
+		}
+
+		// Stop waiting if the caller does not need keys anymore,
+		// or if the shell has been woken up to process other events.
+		switch keys.input.wait(wait, keys.done, wake) {
+		case inputTimeout:
+			keys.timedOut = !delaying
+			keys.delayed = delaying
+
+			return
+		case inputDone:
+			keys.cancelled = true
+			return
+		case inputWoken:
+			keys.woken = true
+			return
+		}
+
+		// The input is closed (or broken) once
+		// reading it fails without any key.
+		keyBuf, err := keys.readKeys()
+		if err != nil && len(keyBuf) == 0 {
+			keys.closed = true
 			return
 		}
 
@@ -162,6 +220,76 @@ func MatchedPrefix(keys *Keys, prefix ...byte) {
 	keys.matched = []rune(string(prefix))
 }
 
+// WaitTimeout sets the maximum time the next call to WaitAvailableKeys() will wait
+// for keys completing a prefix, after which TimedOut() returns true. It has no effect
+// if no prefix has been matched, or if the timeout is zero or negative.
+func WaitTimeout(keys *Keys, timeout time.Duration) {
+	keys.timeout = timeout
+}
+
+// TimedOut returns true if the last wait for keys completing a prefix has timed out.
+func TimedOut(keys *Keys) bool {
+	return keys.timedOut
+}
+
+// WaitDelay sets the maximum time the next call to WaitAvailableKeys() will wait for
+// input keys, whether a prefix has been matched or not (eg. when playing keys back at
+// some pace), after which Delayed() returns true. It has no effect if zero or negative.
+func WaitDelay(keys *Keys, delay time.Duration) {
+	keys.delay = delay
+}
+
+// Delayed returns true if the last wait for input keys has returned after the delay.
+func Delayed(keys *Keys) bool {
+	return keys.delayed
+}
+
+// WaitDone sets a channel which, once closed, aborts the current and all
+// subsequent waits for input keys (none if nil). Aborted waits return
+// without keys, and the Cancelled function returns true.
+func WaitDone(keys *Keys, done <-chan struct{}) {
+	keys.done = done
+}
+
+// Cancelled returns true if the last wait for input keys
+// has been aborted because the done channel was closed.
+func Cancelled(keys *Keys) bool {
+	return keys.cancelled
+}
+
+// Closed returns true if the last wait for input keys has returned
+// because the input stream has been closed (EOF), or cannot be read.
+func Closed(keys *Keys) bool {
+	return keys.closed
+}
+
+// Wake interrupts the current (or next) wait for input keys, so that the
+// shell can process other events, like text inserted from another goroutine.
+func Wake(keys *Keys) {
+	select {
+	case keys.wakeup() <- struct{}{}:
+	default:
+	}
+}
+
+// Woken returns true if the last wait for input keys
+// has been interrupted by a call to Wake.
+func Woken(keys *Keys) bool {
+	return keys.woken
+}
+
+// wakeup returns the channel used to interrupt waits for input keys.
+func (k *Keys) wakeup() chan struct{} {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.wake == nil {
+		k.wake = make(chan struct{}, 1)
+	}
+
+	return k.wake
+}
+
 // PopForce is used to force-remove a key from the buffer, without marking
 // it as having matched a bind command. This is used, for example, when the
 // escape has been handled specially as a Vim escape.
@@ -194,6 +322,40 @@ func MacroKeys(keys *Keys) []rune {
 	return keys.matched
 }
 
+// HasFedKeys returns true if some keys fed to the stack (by the macro
+// engine, for instance) are still waiting to be dispatched to commands.
+func HasFedKeys(keys *Keys) bool {
+	keys.mutex.RLock()
+	defer keys.mutex.RUnlock()
+
+	return len(keys.macroKeys) > 0
+}
+
+// HasPendingKeys returns true if some keys (either read from the input
+// or fed by the macro engine) can be dispatched without waiting for input.
+func HasPendingKeys(keys *Keys) bool {
+	keys.mutex.RLock()
+	defer keys.mutex.RUnlock()
+
+	return (len(keys.buf) > 0 && !keys.mustWait) || len(keys.macroKeys) > 0
+}
+
+// KeysComing returns true if some keys can be dispatched without waiting,
+// or if input becomes available within the timeout. Inputs that cannot be
+// polled (eg. neither files nor consoles) only have keys coming once they
+// have been read in the background, which is never started here.
+func KeysComing(keys *Keys, timeout time.Duration) bool {
+	if HasPendingKeys(keys) {
+		return true
+	}
+
+	if timeout <= 0 || !keys.input.pollable() {
+		return keys.input.available()
+	}
+
+	return keys.input.wait(timeout, nil, nil) == inputReady
+}
+
 // FlushUsed drops the keys that have matched a given command.
 func FlushUsed(keys *Keys) {
 	keys.mutex.Lock()
@@ -201,9 +363,11 @@ func FlushUsed(keys *Keys) {
 	defer keys.mutex.Unlock()
 }
 
-// ReadKey reads keys from stdin like Read(), but immediately
-// returns them instead of storing them in the stack, along with
-// an indication on whether this key is an escape/abort one.
+// ReadKey reads keys from the input like Read(), but immediately returns the first
+// one instead of storing it in the stack (the other ones are), along with an
+// indication on whether this key is an escape/abort one. The read is also
+// aborted (with a zero key) if the input is closed, or if the done channel
+// passed to WaitDone is closed: Closed or Cancelled then return true.
 func (k *Keys) ReadKey() (key rune, isAbort bool) {
 	k.mutex.RLock()
 	k.keysOnce = make(chan []byte)
@@ -216,17 +380,39 @@ func (k *Keys) ReadKey() (key rune, isAbort bool) {
 		k.mutex.RUnlock()
 	}()
 
+	var buf []byte
+
 	switch {
 	case len(k.macroKeys) > 0:
 		key = k.macroKeys[0]
 		k.macroKeys = k.macroKeys[1:]
 
 	case k.waiting:
-		buf := <-k.keysOnce
-		key = []rune(string(buf))[0]
+		buf = <-k.keysOnce
 	default:
-		buf, _ := k.readInputFiltered()
-		key = []rune(string(buf))[0]
+		for len(buf) == 0 {
+			if k.input.wait(0, k.done, nil) == inputDone {
+				k.cancelled = true
+				return 0, true
+			}
+
+			keys, err := k.readKeys()
+			if err != nil && len(keys) == 0 {
+				k.closed = true
+				return 0, true
+			}
+
+			buf = keys
+		}
+	}
+
+	if len(buf) > 0 {
+		keys := []rune(string(buf))
+		key = keys[0]
+
+		k.mutex.Lock()
+		k.buf = append(k.buf, []byte(string(keys[1:]))...)
+		k.mutex.Unlock()
 	}
 
 	// Always mark those keys as matched, so that
@@ -287,6 +473,23 @@ func (k *Keys) Feed(begin bool, keys ...rune) {
 	}
 }
 
+// readKeys reads keys from the input, and decodes them. If they end with an escape
+// sequence split across several reads (eg. a mouse report or a key encoded with an
+// enhanced keyboard protocol), the rest of it is read before, unless it does not
+// come shortly, in which case the keys are used as they are.
+func (k *Keys) readKeys() ([]byte, error) {
+	keys, err := k.readInputFiltered()
+
+	for err == nil && incompleteSequence(keys) && k.input.wait(sequenceTimeout, k.done, nil) == inputReady {
+		var more []byte
+
+		more, err = k.readInputFiltered()
+		keys = append(keys, more...)
+	}
+
+	return k.decodeProtocol(keys), err
+}
+
 func (k *Keys) extractCursorPos(keys []byte) (cursor, remain []byte) {
 	if !rxRcvCursorPos.Match(keys) {
 		return cursor, keys
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// chunkReader returns its chunks one read at a time, and then io.EOF.
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(buf []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(buf, r.chunks[0])
+	r.chunks = r.chunks[1:]
+
+	return n, nil
+}
+
+// newTestKeys returns keys read from the reader, with a terminal discarding its output.
+func newTestKeys(in io.Reader) *Keys {
+	return NewKeys(in, &term.Terminal{Output: io.Discard})
+}
+
+func TestKeys_SplitSequence(t *testing.T) {
+	kitty := inputrc.NewDefaultConfig()
+	kitty.Set("keyboard-protocol", "kitty")
+
+	tests := []struct {
+		name   string
+		chunks []string
+		cfg    *inputrc.Config
+		want   string
+	}{
+		{name: "Legacy sequence", chunks: []string{"a\x1b[1;", "5A"}, want: "a\x1b[1;5A"},
+		{name: "Kitty sequence", chunks: []string{"\x1b[98;", "6u"}, cfg: kitty, want: "\x02"},
+		{name: "CSI introducer only", chunks: []string{"\x1b[", "A"}, want: "\x1b[A"},
+		{name: "Complete sequences", chunks: []string{"\x1b[A", "b"}, want: "\x1b[A"},
+		{name: "Unfinished sequence", chunks: []string{"\x1b[1;"}, want: "\x1b[1;"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := newTestKeys(&chunkReader{chunks: test.chunks})
+
+			WaitAvailableKeys(keys, test.cfg)
+
+			if got := string(keys.buf); got != test.want {
+				t.Errorf("WaitAvailableKeys() keys = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestKeys_ReadKeyClosed(t *testing.T) {
+	keys := newTestKeys(strings.NewReader("a"))
+
+	if key, isAbort := keys.ReadKey(); key != 'a' || isAbort {
+		t.Fatalf("ReadKey() = %q, %v, want 'a', false", key, isAbort)
+	}
+
+	// A closed input aborts reads, instead of returning empty keys forever.
+	key, isAbort := keys.ReadKey()
+	if key != 0 || !isAbort || !Closed(keys) {
+		t.Errorf("ReadKey() = %q, %v (closed: %v), want 0, true (closed)", key, isAbort, Closed(keys))
+	}
+
+	WaitAvailableKeys(keys, nil)
+
+	if !Closed(keys) {
+		t.Error("WaitAvailableKeys() on closed input: Closed() = false, want true")
+	}
+}
+
+func TestKeys_ReadKeyLeftovers(t *testing.T) {
+	keys := newTestKeys(strings.NewReader("abc"))
+
+	if key, _ := keys.ReadKey(); key != 'a' {
+		t.Fatalf("ReadKey() = %q, want 'a'", key)
+	}
+
+	// Other keys read at once are kept for dispatching.
+	if got := string(keys.buf); got != "bc" {
+		t.Errorf("keys after ReadKey() = %q, want %q", got, "bc")
+	}
+}
+
+func TestKeys_WaitStream(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	keys := newTestKeys(reader)
+
+	// A prefix waits for the keys completing it for the timeout only.
+	MatchedPrefix(keys, '\x18')
+	WaitTimeout(keys, 10*time.Millisecond)
+	WaitAvailableKeys(keys, nil)
+
+	if !TimedOut(keys) {
+		t.Fatal("WaitAvailableKeys() with a prefix: TimedOut() = false, want true")
+	}
+
+	FlushUsed(keys)
+	keys.buf, keys.mustWait = nil, false
+
+	// Wakes interrupt the wait.
+	Wake(keys)
+	WaitAvailableKeys(keys, nil)
+
+	if !Woken(keys) {
+		t.Fatal("WaitAvailableKeys() after Wake(): Woken() = false, want true")
+	}
+
+	// Keys read in the background after a wait has been given up on are not lost.
+	go writer.Write([]byte("b"))
+
+	WaitAvailableKeys(keys, nil)
+
+	if got := string(keys.buf); got != "b" {
+		t.Errorf("WaitAvailableKeys() keys = %q, want %q", got, "b")
+	}
+
+	// Done channels abort the wait.
+	done := make(chan struct{})
+	WaitDone(keys, done)
+	keys.buf = nil
+
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+	WaitAvailableKeys(keys, nil)
+
+	if !Cancelled(keys) {
+		t.Error("WaitAvailableKeys() after done: Cancelled() = false, want true")
+	}
+}
+
+func Test_incompleteSequence(t *testing.T) {
+	tests := []struct {
+		keys string
+		want bool
+	}{
+		{keys: "abc", want: false},
+		{keys: "\x1b", want: false},
+		{keys: "\x1b[", want: true},
+		{keys: "a\x1b[1;5", want: true},
+		{keys: "\x1b[<0;10;", want: true},
+		{keys: "\x1b[1;5A", want: false},
+		{keys: "\x1b[<0;10;5M", want: false},
+		{keys: "\x1b[A\x1b[2", want: true},
+	}
+
+	for _, test := range tests {
+		if got := incompleteSequence([]byte(test.keys)); got != test.want {
+			t.Errorf("incompleteSequence(%q) = %v, want %v", test.keys, got, test.want)
+		}
+	}
+}
+
+func TestKeys_ReadKeyCancelled(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	keys := newTestKeys(reader)
+
+	done := make(chan struct{})
+	WaitDone(keys, done)
+
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+
+	key, isAbort := keys.ReadKey()
+	if key != 0 || !isAbort || !Cancelled(keys) {
+		t.Errorf("ReadKey() = %q, %v (cancelled: %v), want 0, true (cancelled)", key, isAbort, Cancelled(keys))
+	}
+}
+
+func TestKeys_WaitDelay(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	keys := newTestKeys(reader)
+
+	// Delays apply to waits without any prefix.
+	WaitDelay(keys, 10*time.Millisecond)
+	WaitAvailableKeys(keys, nil)
+
+	if !Delayed(keys) || TimedOut(keys) {
+		t.Fatalf("WaitAvailableKeys() with a delay: Delayed() = %v, TimedOut() = %v, want true, false", Delayed(keys), TimedOut(keys))
+	}
+
+	// And only to the next wait.
+	go writer.Write([]byte("a"))
+
+	WaitAvailableKeys(keys, nil)
+
+	if Delayed(keys) || string(keys.buf) != "a" {
+		t.Errorf("WaitAvailableKeys() after a delay = %q (delayed: %v), want %q", keys.buf, Delayed(keys), "a")
+	}
+}
@@ -3,10 +3,7 @@
 package core
 
 import (
-	"errors"
 	"fmt"
-	"io"
-	"os"
 	"strconv"
 )
 
@@ -14,7 +11,7 @@ import (
 // It is safe to call this function even if the shell is reading input.
 func (k *Keys) GetCursorPos() (x, y int) {
 	disable := func() (int, int) {
-		os.Stderr.WriteString("\r\ngetCursorPos() not supported by terminal emulator, disabling....\r\n")
+		fmt.Fprint(k.term, "\r\ngetCursorPos() not supported by terminal emulator, disabling....\r\n")
 		return -1, -1
 	}
 
@@ -23,25 +20,23 @@ func (k *Keys) GetCursorPos() (x, y int) {
 
 	// Echo the query and wait for the main key
 	// reading routine to send us the response back.
-	fmt.Print("\x1b[6n")
+	fmt.Fprint(k.term, "\x1b[6n")
 
 	// In order not to get stuck with an input that might be user-one
 	// (like when the user typed before the shell is fully started, and yet not having
-	// queried cursor yet), we keep reading from stdin until we find the cursor response.
+	// queried cursor yet), we keep reading the input until we find the cursor response.
 	// Everything else is passed back as user input.
 	for {
 		switch {
 		case k.waiting, k.reading:
 			cursor = <-k.cursor
 		default:
-			buf := make([]byte, keyScanBufSize)
-
-			read, err := os.Stdin.Read(buf)
+			read, err := k.input.read()
 			if err != nil {
 				return disable()
 			}
 
-			cursor = buf[:read]
+			cursor = read
 		}
 
 		// We have read (or have been passed) something.
@@ -84,23 +79,17 @@ func (k *Keys) GetCursorPos() (x, y int) {
 }
 
 func (k *Keys) readInputFiltered() (keys []byte, err error) {
-	// Start reading from os.Stdin in the background.
-	// We will either read keys from user, or an EOF
-	// send by ourselves, because we pause reading.
-	buf := make([]byte, keyScanBufSize)
-
-	read, err := Stdin.Read(buf)
-	if err != nil && errors.Is(err, io.EOF) {
-		return
-	}
+	// Read the keys that are available, or wait for them.
+	// The error is returned along with the keys read, if any.
+	read, err := k.input.read()
 
 	// Always attempt to extract cursor position info.
 	// If found, strip it and keep the remaining keys.
-	cursor, keys := k.extractCursorPos(buf[:read])
+	cursor, keys := k.extractCursorPos(read)
 
 	if len(cursor) > 0 {
 		k.cursor <- cursor
 	}
 
-	return keys, nil
+	return keys, err
 }
@@ -4,8 +4,6 @@
 package core
 
 import (
-	"errors"
-	"io"
 	"unsafe"
 
 	"github.com/reeflective/readline/inputrc"
@@ -50,10 +48,6 @@ const (
 	charBackspace = 127
 )
 
-func init() {
-	Stdin = newRawReader()
-}
-
 // GetTerminalResize sends booleans over a channel to notify resize events on Windows.
 // This functions uses the keys reader because on Windows, resize events are sent through
 // stdin, not with syscalls like unix's syscall.SIGWINCH.
@@ -66,18 +60,12 @@ func GetTerminalResize(keys *Keys) <-chan bool {
 // readInputFiltered on Windows needs to check for terminal resize events.
 func (k *Keys) readInputFiltered() (keys []byte, err error) {
 	for {
-		// Start reading from os.Stdin in the background.
-		// We will either read keys from user, or an EOF
-		// send by ourselves, because we pause reading.
-		buf := make([]byte, keyScanBufSize)
-
-		read, err := Stdin.Read(buf)
-		if err != nil && errors.Is(err, io.EOF) {
-			return keys, err
+		// Read the keys that are available, or wait for them.
+		input, err := k.input.read()
+		if err != nil && len(input) == 0 {
+			return nil, err
 		}
 
-		input := buf[:read]
-
 		// On Windows, windows resize events are sent through stdin,
 		// so if one is detected, send it back to the display engine.
 		if len(input) == 1 && input[0] == WINDOWS_RESIZE {
@@ -93,7 +81,7 @@ func (k *Keys) readInputFiltered() (keys []byte, err error) {
 			k.cursor <- cursor
 		}
 
-		return keys, nil
+		return keys, err
 	}
 }
 
@@ -20,12 +20,29 @@ type Tokenizer func(cursorPos int) (split []string, index int, newPos int)
 // Line is an input line buffer.
 // Contains methods to search and modify its contents,
 // split itself with tokenizers, and displaying itself.
+//
+// The line is edited in place: its backing array keeps a gap of free
+// capacity after the last rune, which grows geometrically, so that inserting
+// and deleting text in large buffers only moves the runes after the edit,
+// without reallocating (and converting) the whole buffer on each change.
 type Line []rune
 
+// lineGapMin is the minimum free capacity left after
+// the line contents when its backing array is grown.
+const lineGapMin = 64
+
 // Set replaces the line contents altogether with a new slice of characters.
-// If no characters are passed, the line is thus made empty.
+// If no characters are passed, the line is thus made empty. The characters
+// are copied, so that further edits do not modify the caller's slice.
 func (l *Line) Set(chars ...rune) {
-	*l = chars
+	*l = append((*l)[:0:0], chars...)
+}
+
+// Wipe overwrites the line contents, including its spare
+// capacity (thus any previously cut text), and empties it.
+func (l *Line) Wipe() {
+	clear((*l)[:cap(*l)])
+	*l = (*l)[:0]
 }
 
 // Insert inserts one or more runes at the given position.
@@ -48,17 +65,14 @@ func (l *Line) Insert(pos int, chars ...rune) {
 		return
 	}
 
-	switch {
-	case l.Len() == 0:
-		*l = chars
-	case pos < l.Len():
-		forward := string((*l)[pos:])
-		cut := string(append((*l)[:pos], chars...))
-		cut += forward
-		*l = []rune(cut)
-	case pos == l.Len():
-		*l = append(*l, chars...)
-	}
+	line := l.grow(len(chars))
+	length := len(line)
+
+	line = line[:length+len(chars)]
+	copy(line[pos+len(chars):], line[pos:length])
+	copy(line[pos:], chars)
+
+	*l = line
 }
 
 // InsertBetween inserts one or more runes into the line, between the specified
@@ -71,18 +85,11 @@ func (l *Line) InsertBetween(bpos, epos int, chars ...rune) {
 		return
 	}
 
-	switch {
-	case epos == -1:
-		l.Insert(bpos, chars...)
-	case epos == l.Len():
-		cut := string((*l)[:bpos]) + string(chars)
-		*l = []rune(cut)
-	default:
-		forward := string((*l)[epos:])
-		cut := string(append((*l)[:bpos], chars...))
-		cut += forward
-		*l = []rune(cut)
+	if epos != -1 {
+		l.Cut(bpos, epos)
 	}
+
+	l.Insert(bpos, chars...)
 }
 
 // Cut deletes a slice of runes between a beginning and end position on the line.
@@ -90,20 +97,15 @@ func (l *Line) InsertBetween(bpos, epos int, chars ...rune) {
 // valid indexes in the given range are removed.
 func (l *Line) Cut(bpos, epos int) {
 	bpos, epos, valid := l.checkRange(bpos, epos)
-	if !valid {
+	if !valid || bpos > l.Len() {
 		return
 	}
 
-	switch epos {
-	case -1:
-		cut := string((*l)[:bpos])
-		*l = []rune(cut)
-	default:
-		forward := string((*l)[epos:])
-		cut := string((*l)[:bpos])
-		cut += forward
-		*l = []rune(cut)
+	if epos == -1 {
+		epos = l.Len()
 	}
+
+	*l = append((*l)[:bpos], (*l)[epos:]...)
 }
 
 // CutRune deletes a rune at the given position in the line.
@@ -113,24 +115,18 @@ func (l *Line) CutRune(pos int) {
 		return
 	}
 
-	switch {
-	case pos == 0:
-		*l = (*l)[1:]
-	case pos == l.Len():
-		*l = (*l)[:pos-1]
-	default:
-		forward := string((*l)[pos+1:])
-		cut := string((*l)[:pos])
-		cut += forward
-		*l = []rune(cut)
+	if pos == l.Len() {
+		pos--
 	}
+
+	l.Cut(pos, pos+1)
 }
 
 // Len returns the length of the line, as given by ut8.RuneCount.
 // This should NOT confused with the length of the line in terms of
 // how many terminal columns its printed representation will take.
 func (l *Line) Len() int {
-	return utf8.RuneCountInString(string(*l))
+	return len(*l)
 }
 
 // SelectWord returns the begin and end index positions of a word
@@ -314,11 +310,19 @@ func (l *Line) SurroundQuotes(single bool, pos int) (bpos, epos int) {
 	return bpos, epos
 }
 
-// DisplayLine prints the line to stdout, starting at the current terminal
-// cursor position, assuming it is at the end of the shell prompt string.
+// DisplayLine prints the line to the terminal, starting at its current cursor
+// position, assuming it is at the end of the shell prompt string.
 // Params:
 // @indent -    Used to align all lines (except the first) together on a single column.
-func DisplayLine(l *Line, indent int) {
+func DisplayLine(t *term.Terminal, l *Line, indent int) {
+	DisplayLinePrompt(t, l, indent, "")
+}
+
+// DisplayLinePrompt is like DisplayLine, but prints the secondary prompt at the
+// beginning of the terminal line on which each line (except the first) starts,
+// instead of aligning them with the first one. If empty, lines are aligned.
+func DisplayLinePrompt(t *term.Terminal, l *Line, indent int, secondary string) {
+	termWidth := t.GetWidth()
 	lines := strings.Split(string(*l), "\n")
 
 	if strings.HasSuffix(string(*l), "\n") {
@@ -328,22 +332,28 @@ func DisplayLine(l *Line, indent int) {
 	for num, line := range lines {
 		// Don't let any visual selection go further than length.
 		line += color.BgDefault
+		start := indent
 
 		// Clear everything before each line, except the first.
-		if num > 0 {
-			term.MoveCursorForwards(indent)
+		switch {
+		case num > 0 && secondary != "":
+			start = strutil.RealLength(secondary)
+			line = secondary + line
+		case num > 0:
+			t.MoveCursorForwards(indent)
 			line = term.ClearLineBefore + line
 		}
 
-		// Clear everything after each line, except the last.
+		// Clear everything after each line, except the last, and
+		// unless the line ends on the last column of the terminal.
 		if num < len(lines)-1 {
-			if len(line)+indent < term.GetWidth() {
+			if used := start + strutil.Width(line, start, termWidth); used%termWidth != 0 || used == 0 {
 				line += term.ClearLineAfter
 			}
 			line += term.NewlineReturn
 		}
 
-		fmt.Print(line)
+		fmt.Fprint(t, line)
 	}
 }
 
@@ -352,16 +362,28 @@ func DisplayLine(l *Line, indent int) {
 // take into account an eventual suggestion added to the line before printing.
 // Params:
 // @indent - Coordinates to align all lines (except the first) together on a single column.
+// @termWidth - The width of the terminal on which the line is displayed.
 // Returns:
 // @x - The number of columns, starting from the terminal left, to the end of the last line.
 // @y - The number of actual lines on which the line spans, accounting for line wrap.
-func CoordinatesLine(l *Line, indent int) (x, y int) {
+func CoordinatesLine(l *Line, indent, termWidth int) (x, y int) {
+	return CoordinatesLineIndent(l, indent, indent, termWidth)
+}
+
+// CoordinatesLineIndent is like CoordinatesLine, but the first line starts at the
+// column first, and all other ones at the column next (eg. after a secondary prompt).
+func CoordinatesLineIndent(l *Line, first, next, termWidth int) (x, y int) {
 	line := string(*l)
 	lines := strings.Split(line, "\n")
 	usedY, usedX := 0, 0
+	indent := first
 
 	for i, line := range lines {
-		x, y := strutil.LineSpan([]rune(line), i, indent)
+		if i > 0 {
+			indent = next
+		}
+
+		x, y := strutil.LineSpan([]rune(line), i, indent, termWidth)
 		usedY += y
 		usedX = x
 	}
@@ -450,23 +472,20 @@ func (l *Line) Tokenize(cpos int) ([]string, int, int) {
 
 	cpos = l.checkPosRange(cpos)
 
-	var index, pos int
 	var punc bool
 
-	split := make([]string, 1)
+	starts := []int{0}
 
 	for i, char := range line {
 		switch {
 		case unicode.IsPunct(char):
 			if i > 0 && line[i-1] != char {
-				split = append(split, "")
+				starts = append(starts, i)
 			}
 
-			split[len(split)-1] += string(char)
 			punc = true
 
 		case char == ' ' || char == '\t':
-			split[len(split)-1] += string(char)
 			punc = true
 
 		case char == '\n':
@@ -474,36 +493,21 @@ func (l *Line) Tokenize(cpos int) ([]string, int, int) {
 			// when the last rune of the previous word
 			// is one as well.
 			if i > 0 && line[i-1] == char {
-				split = append(split, "")
+				starts = append(starts, i)
 			}
 
-			split[len(split)-1] += string(char)
 			punc = true
 
 		default:
 			if punc {
-				split = append(split, "")
+				starts = append(starts, i)
 			}
 
-			split[len(split)-1] += string(char)
 			punc = false
 		}
-
-		// Not caught when we are appending to the end
-		// of the line, where rl.pos = linePos + 1, so...
-		if i == cpos {
-			index = len(split) - 1
-			pos = len(split[index]) - 1
-		}
 	}
 
-	// ... so we ajust here for this case.
-	if cpos == len(line) {
-		index = len(split) - 1
-		pos = len(split[index])
-	}
-
-	return split, index, pos
+	return tokens(line, starts, cpos)
 }
 
 // TokenizeSpace splits the line on each WORD (blank word), that is, split on every space.
@@ -516,14 +520,13 @@ func (l *Line) TokenizeSpace(cpos int) ([]string, int, int) {
 
 	cpos = l.checkPosRange(cpos)
 
-	var index, pos int
-	split := make([]string, 1)
 	var newline bool
 
+	starts := []int{0}
+
 	for i, char := range line {
 		switch char {
 		case ' ', '\t':
-			split[len(split)-1] += string(char)
 			newline = false
 
 		case '\n':
@@ -531,36 +534,21 @@ func (l *Line) TokenizeSpace(cpos int) ([]string, int, int) {
 			// when the last rune of the previous word
 			// is one as well.
 			if i > 0 && line[i-1] == char {
-				split = append(split, "")
+				starts = append(starts, i)
 			}
 
-			split[len(split)-1] += string(char)
 			newline = true
 
 		default:
 			if (i > 0 && (line[i-1] == ' ' || line[i-1] == '\t')) || newline {
-				split = append(split, "")
+				starts = append(starts, i)
 			}
 
 			newline = false
-			split[len(split)-1] += string(char)
 		}
-
-		// Not caught when we are appending to the end
-		// of the line, where rl.pos = linePos + 1, so...
-		if i == cpos {
-			index = len(split) - 1
-			pos = len(split[index]) - 1
-		}
-	}
-
-	// ... so we ajust here for this case.
-	if cpos == len(line) {
-		index = len(split) - 1
-		pos = len(split[index])
 	}
 
-	return split, index, pos
+	return tokens(line, starts, cpos)
 }
 
 // TokenizeBlock splits the line into arguments delimited either by
@@ -577,8 +565,49 @@ func (l *Line) TokenizeBlock(cpos int) ([]string, int, int) {
 		cpos--
 	}
 
+	switch line[cpos] {
+	case '(', ')', '{', '[', '}', ']':
+		opener, closer := strutil.MatchSurround(line[cpos])
+		return l.tokenizeBlock(cpos, opener, closer)
+	default:
+		return nil, 0, 0
+	}
+}
+
+// MatchingBracket returns the position of the bracket (including angle brackets)
+// or quote matching the one at pos, or -1 if there is none or if pos is not on one.
+func (l *Line) MatchingBracket(pos int) int {
+	if pos < 0 || pos >= l.Len() {
+		return -1
+	}
+
+	switch char := (*l)[pos]; char {
+	case '(', ')', '{', '[', '}', ']', '<', '>':
+		opener, closer := strutil.MatchSurround(char)
+		split, index, tpos := l.tokenizeBlock(pos, opener, closer)
+
+		switch {
+		case len(split) <= index:
+			return -1
+		case tpos == 0:
+			return pos + utf8.RuneCountInString(split[index])
+		default:
+			return pos - utf8.RuneCountInString(split[index])
+		}
+
+	case '\'', '"':
+		return l.matchingQuote(pos)
+	}
+
+	return -1
+}
+
+// tokenizeBlock splits the line into arguments delimited by the opener and closer
+// brackets, ignoring those in quotes, for the bracket at cpos (see TokenizeBlock).
+func (l *Line) tokenizeBlock(cpos int, opener, closer rune) ([]string, int, int) {
+	line := *l
+
 	var (
-		opener, closer rune
 		split          []string
 		count          int
 		pos            = make(map[int]int)
@@ -586,14 +615,6 @@ func (l *Line) TokenizeBlock(cpos int) ([]string, int, int) {
 		single, double bool
 	)
 
-	switch line[cpos] {
-	case '(', ')', '{', '[', '}', ']':
-		opener, closer = strutil.MatchSurround(line[cpos])
-
-	default:
-		return nil, 0, 0
-	}
-
 	for idx := range line {
 		switch line[idx] {
 		case '\'':
@@ -631,6 +652,69 @@ func (l *Line) TokenizeBlock(cpos int) ([]string, int, int) {
 	return nil, 0, 0
 }
 
+// matchingQuote returns the position of the quote opening or closing the one at
+// pos, or -1: quotes are paired from the start of the line, ignoring the ones in
+// other quotes, and the ones escaped with a backslash (outside single quotes).
+func (l *Line) matchingQuote(pos int) int {
+	var quote rune
+
+	open := -1
+
+	for idx := 0; idx < l.Len(); idx++ {
+		if quote == 0 && idx > pos {
+			break
+		}
+
+		switch char := (*l)[idx]; {
+		case char == '\\' && quote != '\'':
+			idx++
+		case quote == 0 && (char == '\'' || char == '"'):
+			quote, open = char, idx
+		case char == quote:
+			switch pos {
+			case open:
+				return idx
+			case idx:
+				return open
+			}
+
+			quote = 0
+		}
+	}
+
+	return -1
+}
+
+// tokens returns the line split at the given token start positions, the index
+// of the token in which the cursor is, and the cursor position in this token.
+func tokens(line Line, starts []int, cpos int) (split []string, index, pos int) {
+	split = make([]string, len(starts))
+
+	for i, start := range starts {
+		end := len(line)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+
+		split[i] = string(line[start:end])
+
+		if cpos >= start && cpos < end {
+			index = i
+			pos = len(string(line[start:cpos+1])) - 1
+		}
+	}
+
+	// Not caught when we are appending to the end
+	// of the line, where rl.pos = linePos + 1, so
+	// we ajust here for this case.
+	if cpos == len(line) {
+		index = len(split) - 1
+		pos = len(split[index])
+	}
+
+	return split, index, pos
+}
+
 // add a new block token to the list of split tokens.
 func openToken(idx, count, cpos, match int, pos map[int]int, line []rune, split []string) (int, int, []string) {
 	count++
@@ -679,11 +763,17 @@ func closeToken(idx, count, cpos, match int, pos map[int]int, line []rune, split
 
 // newlines gives the indexes of all newline characters in the line.
 func (l *Line) newlines() [][]int {
-	line := string(*l)
-	line += string(inputrc.Newline)
-	nl := regexp.MustCompile(string(inputrc.Newline))
+	newlines := make([][]int, 0)
 
-	return nl.FindAllStringIndex(line, -1)
+	// Positions are rune indexes, not byte ones.
+	for pos, char := range *l {
+		if char == inputrc.Newline {
+			newlines = append(newlines, []int{pos, pos + 1})
+		}
+	}
+
+	// The end of the line counts as a newline.
+	return append(newlines, []int{l.Len(), l.Len() + 1})
 }
 
 // returns bpos, epos ordered and true if either is valid.
@@ -722,3 +812,22 @@ func (l *Line) checkPosRange(pos int) int {
 
 	return pos
 }
+
+// grow returns the line with enough free capacity to insert n runes,
+// reallocating it with a gap proportional to its length if needed.
+func (l *Line) grow(n int) Line {
+	line := *l
+	if len(line)+n <= cap(line) {
+		return line
+	}
+
+	gap := len(line)
+	if gap < lineGapMin {
+		gap = lineGapMin
+	}
+
+	grown := make(Line, len(line), len(line)+n+gap)
+	copy(grown, line)
+
+	return grown
+}
@@ -1,15 +1,15 @@
 package core
 
 import (
+	"io"
 	"reflect"
 	"testing"
 
 	"github.com/reeflective/readline/internal/term"
 )
 
-// getTermWidth is used as a variable so that we can
-// use specific terminal widths in our tests.
-var getTermWidth = term.GetWidth
+// testTermWidth is the terminal width used to compute coordinates in tests.
+const testTermWidth = 80
 
 func TestLine_Insert(t *testing.T) {
 	line := Line("multiple-ambiguous 10.203.23.45")
@@ -1024,6 +1024,38 @@ func TestLine_TokenizeBlock(t *testing.T) {
 	}
 }
 
+func TestLine_MatchingBracket(t *testing.T) {
+	line := Line(`cmd $(echo {a, [b]}) <x> "it's" 'say "hi"' \"q "é(x)"`)
+
+	tests := []struct {
+		name string
+		pos  int
+		want int
+	}{
+		{name: "Parenthesis opener", pos: 5, want: 19},
+		{name: "Parenthesis closer", pos: 19, want: 5},
+		{name: "Nested brace", pos: 11, want: 18},
+		{name: "Nested bracket closer", pos: 17, want: 15},
+		{name: "Angle bracket", pos: 21, want: 23},
+		{name: "Double quote with single quote in it", pos: 25, want: 30},
+		{name: "Single quote in double quotes", pos: 28, want: -1},
+		{name: "Single quote with double quotes in it", pos: 32, want: 41},
+		{name: "Escaped quote", pos: 44, want: -1},
+		{name: "Quote after escaped one", pos: 47, want: 52},
+		{name: "Bracket in quotes", pos: 49, want: -1},
+		{name: "Not a bracket", pos: 0, want: -1},
+		{name: "Out of range", pos: line.Len(), want: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := line.MatchingBracket(test.pos); got != test.want {
+				t.Errorf("Line.MatchingBracket() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
 func TestDisplayLine(t *testing.T) {
 	type args struct {
 		indent int
@@ -1038,7 +1070,7 @@ func TestDisplayLine(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			DisplayLine(tt.l, tt.args.indent)
+			DisplayLine(&term.Terminal{Output: io.Discard}, tt.l, tt.args.indent)
 		})
 	}
 }
@@ -1048,9 +1080,6 @@ func TestCoordinatesLine(t *testing.T) {
 	line := Line("basic -f \"commands.go,line.go\" -cp=/usr --option [value1 value2]")
 	multiline := Line("basic -f \"commands.go \nanother testing\" --alternate \"another\nquote\" -v { expression here } -a [value1 value2]")
 
-	// Reassign the function for getting the terminal width to a fixed value
-	getTermWidth = func() int { return 80 }
-
 	type args struct {
 		indent    int
 		suggested string
@@ -1080,7 +1109,7 @@ func TestCoordinatesLine(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			gotX, gotY := CoordinatesLine(test.l, test.args.indent)
+			gotX, gotY := CoordinatesLine(test.l, test.args.indent, testTermWidth)
 			if gotX != test.wantX {
 				t.Errorf("CoordinatesLine() gotX = %v, want %v", gotX, test.wantX)
 			}
@@ -1090,3 +1119,55 @@ func TestCoordinatesLine(t *testing.T) {
 		})
 	}
 }
+
+// largeLine returns a multiline buffer of about 100KB.
+func largeLine() Line {
+	var line []rune
+
+	for len(line) < 100*1024 {
+		line = append(line, []rune("git commit -m \"update: the line.go file\" --author=user@host.org\n")...)
+	}
+
+	return line
+}
+
+func BenchmarkLine_InsertAtCursor(b *testing.B) {
+	line := largeLine()
+	cur := NewCursor(&line)
+	cur.Set(line.Len() / 2)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cur.InsertAt('a')
+	}
+}
+
+func BenchmarkLine_WordMotions(b *testing.B) {
+	line := largeLine()
+	cur := NewCursor(&line)
+	cur.Set(line.Len() / 2)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cur.Move(line.Forward(line.Tokenize, cur.Pos()))
+		cur.Move(line.Backward(line.Tokenize, cur.Pos()))
+	}
+}
+
+func BenchmarkLine_Redraw(b *testing.B) {
+	line := largeLine()
+	cur := NewCursor(&line)
+	cur.Set(line.Len() / 2)
+
+	terminal := &term.Terminal{Output: io.Discard}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		DisplayLine(terminal, &line, 10)
+		CoordinatesLine(&line, 10, testTermWidth)
+		CoordinatesCursor(cur, 10, testTermWidth)
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+// MarkMatches highlights ranges of the line (eg. the matches of a search or
+// replacement), replacing those already marked. The range at index current
+// is highlighted differently, unless current is negative.
+func (s *Selection) MarkMatches(matches [][2]int, current int) {
+	s.matches = matches
+	s.currentMatch = current
+}
+
+// Matches returns the begin and end positions of all highlighted matches,
+// and the index of the current one (negative if none).
+func (s *Selection) Matches() (matches [][2]int, current int) {
+	return s.matches, s.currentMatch
+}
+
+// ResetMatches removes the highlighting of all matches.
+func (s *Selection) ResetMatches() {
+	s.matches = nil
+	s.currentMatch = 0
+}
+
+// HighlightMatches adds highlighting to the marked matches, with a style
+// for the current one and another for all others. Empty matches are ignored.
+func HighlightMatches(sel *Selection, style, currentStyle string) {
+	for i, match := range sel.matches {
+		if match[0] < 0 || match[1] > sel.line.Len() || match[0] >= match[1] {
+			continue
+		}
+
+		fg := style
+		if i == sel.currentMatch {
+			fg = currentStyle
+		}
+
+		sel.surrounds = append(sel.surrounds, Selection{
+			Type:   "match",
+			active: true,
+			visual: true,
+			bpos:   match[0],
+			epos:   match[1] - 1,
+			fg:     fg,
+			line:   sel.line,
+			cursor: sel.cursor,
+		})
+	}
+}
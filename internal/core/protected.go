@@ -0,0 +1,70 @@
+package core
+
+// Protect marks a range of the line as read-only: the cursor can move
+// through it, but editing commands cannot modify its contents. Text can
+// still be inserted right before or after it, except at the beginning of
+// the line, so that a range there is a prefix. The range moves along with
+// the text when edits are made before it. Invalid ranges are ignored.
+func (s *Selection) Protect(bpos, epos int) {
+	if bpos < 0 || epos > s.line.Len() || bpos >= epos {
+		return
+	}
+
+	s.protected = append(s.protected, [2]int{bpos, epos})
+}
+
+// Protected returns the begin and end positions of all read-only ranges.
+func (s *Selection) Protected() [][2]int {
+	return s.protected
+}
+
+// ResetProtected makes all read-only ranges of the line editable again.
+func (s *Selection) ResetProtected() {
+	s.protected = nil
+}
+
+// CheckProtected compares the line with its contents before an edit, and
+// returns false if the edit has modified any read-only range. Otherwise, the
+// ranges are moved by the number of characters inserted/deleted before them.
+func CheckProtected(sel *Selection, before []rune) bool {
+	if len(sel.protected) == 0 {
+		return true
+	}
+
+	line := *sel.line
+	delta := len(line) - len(before)
+	moved := make([][2]int, 0, len(sel.protected))
+
+	for _, region := range sel.protected {
+		switch {
+		case region[1] <= len(line) && string(line[:region[1]]) == string(before[:region[1]]):
+			// Edited after the region (or inserted at its end).
+			moved = append(moved, region)
+		case region[0] > 0 && region[0]+delta >= 0 && string(line[region[0]+delta:]) == string(before[region[0]:]):
+			// Edited before the region (or inserted at its beginning).
+			moved = append(moved, [2]int{region[0] + delta, region[1] + delta})
+		default:
+			return false
+		}
+	}
+
+	sel.protected = moved
+
+	return true
+}
+
+// HighlightProtected adds highlighting to the read-only ranges of the line.
+func HighlightProtected(sel *Selection, style string) {
+	for _, region := range sel.protected {
+		sel.surrounds = append(sel.surrounds, Selection{
+			Type:   "protected",
+			active: true,
+			visual: true,
+			bpos:   region[0],
+			epos:   region[1] - 1,
+			fg:     style,
+			line:   sel.line,
+			cursor: sel.cursor,
+		})
+	}
+}
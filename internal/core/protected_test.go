@@ -0,0 +1,78 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+func TestCheckProtected(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		region [2]int
+		want   bool
+		moved  [][2]int
+	}{
+		{
+			name:   "Insert after region",
+			before: "git ",
+			after:  "git st",
+			region: [2]int{0, 4},
+			want:   true,
+			moved:  [][2]int{{0, 4}},
+		},
+		{
+			name:   "Delete in region",
+			before: "git st",
+			after:  "gitst",
+			region: [2]int{0, 4},
+			want:   false,
+			moved:  [][2]int{{0, 4}},
+		},
+		{
+			name:   "Insert before prefix",
+			before: "git ",
+			after:  "ggit ",
+			region: [2]int{0, 4},
+			want:   false,
+			moved:  [][2]int{{0, 4}},
+		},
+		{
+			name:   "Insert before region",
+			before: "cat <<EOF\nEOF",
+			after:  "cat <<EOF\nline\nEOF",
+			region: [2]int{10, 13},
+			want:   true,
+			moved:  [][2]int{{15, 18}},
+		},
+		{
+			name:   "Replace region",
+			before: "cat <<EOF\nEOF",
+			after:  "cat <<EOF\nEND",
+			region: [2]int{10, 13},
+			want:   false,
+			moved:  [][2]int{{10, 13}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := Line(test.before)
+			sel := NewSelection(&line, NewCursor(&line), color.NewStyles())
+			sel.Protect(test.region[0], test.region[1])
+
+			line = Line(test.after)
+
+			if got := CheckProtected(sel, []rune(test.before)); got != test.want {
+				t.Errorf("CheckProtected() = %v, want %v", got, test.want)
+			}
+
+			if !reflect.DeepEqual(sel.Protected(), test.moved) {
+				t.Errorf("CheckProtected() regions = %v, want %v", sel.Protected(), test.moved)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ type Selection struct {
 	active     bool   // The selection is running.
 	visual     bool   // The selection is highlighted.
 	visualLine bool   // The selection should span entire lines.
+	region     bool   // The selection is the Emacs region, excluding the cursor.
 	bpos       int    // Beginning index position
 	epos       int    // End index position (can be +1 in visual mode, to encompass cursor pos)
 	kpos       int    // Keyword regexp matchers cycling counter.
@@ -27,20 +28,33 @@ type Selection struct {
 	fg        string      // Foreground color of the highlighted selection.
 	bg        string      // Background color.
 	surrounds []Selection // Surrounds are usually pairs of characters matching each other (quotes/brackets, etc.)
+	protected [][2]int    // Read-only ranges of the line.
+	matches   [][2]int    // Highlighted matches (eg. of a search).
+	last      lastVisual  // The last visual selection, to reselect it.
+
+	currentMatch int // Index of the current match.
 
 	// Core
 	line   *Line
 	cursor *Cursor
+	styles *color.Styles
+}
+
+// lastVisual is a visual selection remembered to be selected again.
+type lastVisual struct {
+	mark, cpos int
+	line, set  bool
 }
 
 // NewSelection is a required constructor to use for initializing
 // a selection, as some numeric values must be negative by default.
-func NewSelection(line *Line, cursor *Cursor) *Selection {
+func NewSelection(line *Line, cursor *Cursor, styles *color.Styles) *Selection {
 	return &Selection{
 		bpos:   -1,
 		epos:   -1,
 		line:   line,
 		cursor: cursor,
+		styles: styles,
 	}
 }
 
@@ -68,7 +82,20 @@ func (s *Selection) MarkRange(bpos, epos int) {
 	s.active = true
 	s.bpos = bpos
 	s.epos = epos
-	s.bg = color.BgBlue
+	s.bg = s.styles.Selection
+}
+
+// MarkRegion starts a highlighted selection between pos and the cursor, which
+// (unlike visual selections) does not encompass the character under the cursor:
+// this is the Emacs region, between the mark and the point.
+func (s *Selection) MarkRegion(pos int) {
+	s.Reset()
+	s.Mark(pos)
+
+	if s.active {
+		s.visual = true
+		s.region = true
+	}
 }
 
 // MarkSurround creates two distinct selections each containing one rune.
@@ -88,9 +115,10 @@ func (s *Selection) MarkSurround(bpos, epos int) {
 			visual: true,
 			bpos:   pos,
 			epos:   pos,
-			bg:     color.BgRed,
+			bg:     s.styles.Surround,
 			line:   s.line,
 			cursor: s.cursor,
+			styles: s.styles,
 		})
 	}
 }
@@ -102,6 +130,57 @@ func (s *Selection) Active() bool {
 	return s.active
 }
 
+// Swap exchanges the anchor of the selection and the cursor, so that the
+// other end of the selection can be moved (as Vim does in visual mode).
+func (s *Selection) Swap() {
+	if !s.active || s.bpos < 0 {
+		return
+	}
+
+	end := s.epos
+	if end == -1 {
+		end = s.cursor.Pos()
+	}
+
+	mark := s.bpos
+	s.bpos, s.epos = end, -1
+	s.cursor.Set(mark)
+}
+
+// Remember stores the anchor of the selection, the cursor position and whether
+// the selection spans entire lines, so that it can be selected again later with
+// Reselect, even after it has been reset.
+func (s *Selection) Remember() {
+	if !s.active || s.bpos < 0 {
+		return
+	}
+
+	cpos := s.epos
+	if cpos == -1 {
+		cpos = s.cursor.Pos()
+	}
+
+	s.last = lastVisual{mark: s.bpos, cpos: cpos, line: s.visualLine, set: true}
+}
+
+// Reselect starts a visual selection with the anchor and cursor position of the
+// last one stored with Remember, adjusted to the line bounds if it has shrunk.
+// It returns false if no selection was remembered, or if the line is empty.
+func (s *Selection) Reselect() bool {
+	if !s.last.set || s.line.Len() == 0 {
+		return false
+	}
+
+	last := s.line.Len() - 1
+
+	s.Reset()
+	s.cursor.Set(min(s.last.cpos, last))
+	s.Mark(min(s.last.mark, last))
+	s.Visual(s.last.line)
+
+	return true
+}
+
 // Visual sets the selection as a visual one (highlighted),
 // which is commonly seen in Vim edition mode.
 // If line is true, the visual is extended to entire lines.
@@ -115,6 +194,16 @@ func (s *Selection) IsVisual() bool {
 	return s.visual
 }
 
+// IsRegion indicates whether the selection is the Emacs region.
+func (s *Selection) IsRegion() bool {
+	return s.active && s.region
+}
+
+// IsVisualLine indicates whether the selection spans entire lines.
+func (s *Selection) IsVisualLine() bool {
+	return s.visual && s.visualLine
+}
+
 // Pos returns the begin and end positions of the selection.
 // If any of these is not set, it is set to the cursor position.
 // This is generally the case with "pending" visual selections.
@@ -135,7 +224,7 @@ func (s *Selection) Pos() (bpos, epos int) {
 		bpos, epos = s.selectToCursor(bpos)
 	}
 
-	if s.visual {
+	if s.visual && !s.region {
 		epos++
 	}
 
@@ -578,20 +667,70 @@ func HighlightMatchers(sel *Selection) {
 			visual: true,
 			bpos:   ppos,
 			epos:   ppos,
-			bg:     color.Fmt("240"),
+			bg:     sel.styles.Matcher,
+			line:   sel.line,
+			cursor: sel.cursor,
+			styles: sel.styles,
+		})
+	}
+}
+
+// HighlightMatchingBracket adds highlighting, with the given style, to the bracket
+// or quote matching the one under the cursor or, if there is none, the one just
+// before it (eg. a closing bracket that has just been inserted).
+func HighlightMatchingBracket(sel *Selection, style string) {
+	cpos := sel.cursor.Pos()
+
+	match := sel.line.MatchingBracket(cpos)
+	if match == -1 {
+		match = sel.line.MatchingBracket(cpos - 1)
+	}
+
+	if match == -1 {
+		return
+	}
+
+	sel.surrounds = append(sel.surrounds, Selection{
+		Type:   "matcher",
+		active: true,
+		visual: true,
+		bpos:   match,
+		epos:   match,
+		fg:     style,
+		line:   sel.line,
+		cursor: sel.cursor,
+	})
+}
+
+// HighlightCursors adds highlighting to the characters
+// under the additional cursors, if there are any.
+func HighlightCursors(sel *Selection) {
+	for _, pos := range sel.cursor.Cursors() {
+		if pos == sel.line.Len() {
+			continue
+		}
+
+		sel.surrounds = append(sel.surrounds, Selection{
+			Type:   "cursor",
+			active: true,
+			visual: true,
+			bpos:   pos,
+			epos:   pos,
+			bg:     color.Reverse,
 			line:   sel.line,
 			cursor: sel.cursor,
 		})
 	}
 }
 
-// ResetMatchers is used by the display engine
-// to reset matching parens highlighting regions.
+// ResetMatchers is used by the display engine to reset matching parens,
+// additional cursors, read-only ranges and matches highlighting regions.
 func ResetMatchers(sel *Selection) {
 	var surrounds []Selection
 
 	for _, surround := range sel.surrounds {
-		if surround.Type == "matcher" {
+		if surround.Type == "matcher" || surround.Type == "cursor" ||
+			surround.Type == "protected" || surround.Type == "match" {
 			continue
 		}
 
@@ -607,6 +746,7 @@ func (s *Selection) Reset() {
 	s.active = false
 	s.visual = false
 	s.visualLine = false
+	s.region = false
 	s.bpos = -1
 	s.epos = -1
 	s.kpos = 0
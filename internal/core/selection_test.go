@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"unicode"
+
+	"github.com/reeflective/readline/internal/color"
 )
 
 type fields struct {
@@ -25,6 +27,7 @@ type fields struct {
 func TestNewSelection(t *testing.T) {
 	line := Line("git command")
 	cursor := NewCursor(&line)
+	styles := color.NewStyles()
 
 	type args struct {
 		line   *Line
@@ -37,13 +40,13 @@ func TestNewSelection(t *testing.T) {
 	}{
 		{
 			args: args{line: &line, cursor: cursor},
-			want: &Selection{bpos: -1, epos: -1, line: &line, cursor: cursor},
+			want: &Selection{bpos: -1, epos: -1, line: &line, cursor: cursor, styles: styles},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewSelection(tt.args.line, tt.args.cursor); !reflect.DeepEqual(got, tt.want) {
+			if got := NewSelection(tt.args.line, tt.args.cursor, styles); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewSelection() = %v, want %v", got, tt.want)
 			}
 		})
@@ -427,6 +430,47 @@ func TestSelection_Visual(t *testing.T) {
 	}
 }
 
+func TestSelection_MarkRegion(t *testing.T) {
+	line, cur := newLine("multiple-ambiguous 10.203.23.45 127.0.0.1")
+
+	tests := []struct {
+		name       string
+		mark       int
+		cursorMove int
+		wantBpos   int
+		wantEpos   int
+	}{
+		{name: "Empty region", mark: 4, cursorMove: 4, wantBpos: 4, wantEpos: 4},
+		{name: "Cursor after mark", mark: 4, cursorMove: 8, wantBpos: 4, wantEpos: 8},
+		{name: "Cursor before mark", mark: 8, cursorMove: 4, wantBpos: 4, wantEpos: 8},
+		{name: "Cursor at end of line", mark: 19, cursorMove: line.Len(), wantBpos: 19, wantEpos: line.Len()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cur.Set(0)
+
+			sel := NewSelection(&line, &cur, color.NewStyles())
+			sel.MarkRegion(test.mark)
+
+			cur.Move(test.cursorMove)
+
+			if !sel.IsRegion() || !sel.IsVisual() {
+				t.Errorf("Selection.IsRegion() = %v, IsVisual() = %v, want true", sel.IsRegion(), sel.IsVisual())
+			}
+
+			gotBpos, gotEpos := sel.Pos()
+			if gotBpos != test.wantBpos || gotEpos != test.wantEpos {
+				t.Errorf("Selection.Pos() = (%v, %v), want (%v, %v)", gotBpos, gotEpos, test.wantBpos, test.wantEpos)
+			}
+
+			if sel.Reset(); sel.IsRegion() {
+				t.Errorf("Selection.IsRegion() = true after Reset(), want false")
+			}
+		})
+	}
+}
+
 func TestSelection_Pos(t *testing.T) {
 	// selection.Pos() is actually used in many/all other tests in this file,
 	// so this test is meant to try wrong values that could only be set internally,
@@ -1366,7 +1410,7 @@ func TestSelection_Cut(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			sel := NewSelection(test.fields.line, test.fields.cursor)
+			sel := NewSelection(test.fields.line, test.fields.cursor, color.NewStyles())
 
 			if test.args.epos == -1 {
 				test.fields.cursor.Set(test.args.bpos)
@@ -1637,5 +1681,6 @@ func newTestSelection(fields fields) *Selection {
 		surrounds: fields.surrounds,
 		line:      fields.line,
 		cursor:    fields.cursor,
+		styles:    color.NewStyles(),
 	}
 }
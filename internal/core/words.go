@@ -0,0 +1,128 @@
+package core
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// Word styles, which define what words are for the Emacs word commands
+// (forward-word, backward-kill-word, transpose-words, etc).
+const (
+	// WordStyleDefault words are sequences of characters that are neither
+	// blanks nor punctuation, or sequences of the same punctuation character.
+	WordStyleDefault = "default"
+	// WordStyleBash words are sequences of letters and digits.
+	WordStyleBash = "bash"
+	// WordStyleNormal words are sequences of letters, digits
+	// and of the characters given with the word style.
+	WordStyleNormal = "normal"
+	// WordStyleShell words are shell arguments (including
+	// quoted strings and substitutions) and operators.
+	WordStyleShell = "shell"
+	// WordStyleWhitespace words are sequences of non-blank characters.
+	WordStyleWhitespace = "whitespace"
+)
+
+// WordStyle is the name of a word style (the default one if unknown),
+// with the characters that are part of words in the normal style,
+// in addition to letters and digits.
+type WordStyle struct {
+	Name  string
+	Chars string
+}
+
+// Words returns the begin and end positions of all words in the line, as defined
+// by the word style. Positions are in runes, and the end ones are excluded.
+func (l *Line) Words(style WordStyle) (words [][2]int) {
+	line := *l
+
+	if style.Name == WordStyleShell {
+		for _, token := range strutil.Tokenize(line) {
+			words = append(words, [2]int{token.Start, token.End})
+		}
+
+		return words
+	}
+
+	start := -1
+
+	for pos, char := range line {
+		if start >= 0 && !sameWord(style, line[start], char) {
+			words = append(words, [2]int{start, pos})
+			start = -1
+		}
+
+		if start < 0 && isWordChar(style, char) {
+			start = pos
+		}
+	}
+
+	if start >= 0 {
+		words = append(words, [2]int{start, len(line)})
+	}
+
+	return words
+}
+
+// WordEnd returns the position of the end of the word in which pos is,
+// or of the next one if pos is not in a word (or at its end). The end
+// of the line is returned if there is no word after pos.
+func (l *Line) WordEnd(style WordStyle, pos int) int {
+	for _, word := range l.Words(style) {
+		if word[1] > pos {
+			return word[1]
+		}
+	}
+
+	return l.Len()
+}
+
+// WordStart returns the position of the beginning of the word in which pos is,
+// or of the previous one if pos is not in a word (or at its beginning). The
+// beginning of the line is returned if there is no word before pos.
+func (l *Line) WordStart(style WordStyle, pos int) int {
+	words := l.Words(style)
+
+	for i := len(words) - 1; i >= 0; i-- {
+		if words[i][0] < pos {
+			return words[i][0]
+		}
+	}
+
+	return 0
+}
+
+// isWordChar returns true if the character can be part of a word.
+func isWordChar(style WordStyle, char rune) bool {
+	switch style.Name {
+	case WordStyleBash:
+		return unicode.IsLetter(char) || unicode.IsDigit(char)
+	case WordStyleNormal:
+		return unicode.IsLetter(char) || unicode.IsDigit(char) || strings.ContainsRune(style.Chars, char)
+	default:
+		return !unicode.IsSpace(char)
+	}
+}
+
+// sameWord returns true if the character is part of the word starting
+// with the first character.
+func sameWord(style WordStyle, first, char rune) bool {
+	if !isWordChar(style, char) {
+		return false
+	}
+
+	switch style.Name {
+	case WordStyleBash, WordStyleNormal, WordStyleWhitespace:
+		return true
+	default:
+		// Punctuation characters are words on their own,
+		// unless the same character is repeated.
+		if unicode.IsPunct(first) || unicode.IsPunct(char) {
+			return char == first
+		}
+
+		return true
+	}
+}
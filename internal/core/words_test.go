@@ -0,0 +1,80 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLine_Words(t *testing.T) {
+	line := Line(`git commit -m "fix: foo_bar" --amend&&ls ../src`)
+
+	tests := []struct {
+		style WordStyle
+		want  []string
+	}{
+		{
+			style: WordStyle{Name: WordStyleDefault},
+			want:  []string{"git", "commit", "-", "m", `"`, "fix", ":", "foo", "_", "bar", `"`, "--", "amend", "&&", "ls", "..", "/", "src"},
+		},
+		{
+			style: WordStyle{Name: WordStyleBash},
+			want:  []string{"git", "commit", "m", "fix", "foo", "bar", "amend", "ls", "src"},
+		},
+		{
+			style: WordStyle{Name: WordStyleNormal, Chars: "_-./"},
+			want:  []string{"git", "commit", "-m", "fix", "foo_bar", "--amend", "ls", "../src"},
+		},
+		{
+			style: WordStyle{Name: WordStyleShell},
+			want:  []string{"git", "commit", "-m", `"fix: foo_bar"`, "--amend", "&&", "ls", "../src"},
+		},
+		{
+			style: WordStyle{Name: WordStyleWhitespace},
+			want:  []string{"git", "commit", "-m", `"fix:`, `foo_bar"`, "--amend&&ls", "../src"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.style.Name, func(t *testing.T) {
+			var got []string
+
+			for _, word := range line.Words(test.style) {
+				got = append(got, string(line[word[0]:word[1]]))
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Line.Words() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLine_WordStartEnd(t *testing.T) {
+	line := Line("foo.bar  baz")
+	style := WordStyle{Name: WordStyleBash}
+
+	tests := []struct {
+		name      string
+		pos       int
+		wantStart int
+		wantEnd   int
+	}{
+		{name: "Beginning of line", pos: 0, wantStart: 0, wantEnd: 3},
+		{name: "In a word", pos: 5, wantStart: 4, wantEnd: 7},
+		{name: "End of a word", pos: 7, wantStart: 4, wantEnd: 12},
+		{name: "Between words", pos: 8, wantStart: 4, wantEnd: 12},
+		{name: "End of line", pos: 12, wantStart: 9, wantEnd: 12},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := line.WordStart(style, test.pos); got != test.wantStart {
+				t.Errorf("Line.WordStart() = %v, want %v", got, test.wantStart)
+			}
+
+			if got := line.WordEnd(style, test.pos); got != test.wantEnd {
+				t.Errorf("Line.WordEnd() = %v, want %v", got, test.wantEnd)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package display
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
@@ -18,22 +23,57 @@ var (
 	halfTerminalHeight     = 2
 )
 
+// Above this round-trip time for the cursor position query, on several
+// consecutive refreshes, the terminal link is considered slow.
+const (
+	slowLinkLatency   = 150 * time.Millisecond
+	slowLinkRefreshes = 3
+)
+
 // Engine handles all display operations: it refreshes the terminal
 // interface and stores the necessary offsets of each components.
 type Engine struct {
 	// Operating parameters
 	highlighter    func(line []rune) string
+	tokens         Highlighter
+	preRefresh     func() // Called before each refresh.
+	status         func() string
+	suggest        func(line *core.Line) core.Line
+	workingDir     func() string // Reported to the terminal before each prompt.
+	theme          *color.Theme  // Set by the application, instead of a preset.
+	commandRan     bool          // A command output start has been marked.
 	startCols      int
+	nextCols       int    // Column at which continuation lines start.
+	secondary      string // Secondary prompt of continuation lines.
+	gutter         int    // Width of the line numbers gutter, if displayed.
+	scroll         int    // First column of the line displayed, in horizontal-scroll-mode.
+	scrollWidth    int    // Width of the line if scrolled horizontally, or 0.
 	startRows      int
 	lineCol        int
 	lineRows       int
 	cursorRow      int
 	cursorCol      int
+	rightRow       int // Line row on which the right prompt is displayed.
+	rightCol       int // Column at which the right prompt line ends.
 	hintRows       int
 	compRows       int
+	statusRows     int
 	primaryPrinted bool
+	slowRefreshes  int    // Consecutive refreshes with a slow terminal round-trip.
+	slowSuggested  bool   // The low-bandwidth mode has been suggested once.
+	masked         bool   // The input line is masked (eg. a password).
+	mask           rune   // Displayed for each masked character, if not 0.
+	imageProtocol  string // Protocol of the image displayed in the hint section, if any.
+
+	// Frames
+	refreshing sync.Mutex   // Refreshes can be triggered by other goroutines.
+	last       frame        // Output of the last refresh.
+	buffer     bytes.Buffer // Output of the current refresh.
+	output     io.Writer    // Terminal output during refreshes.
+	refreshed  time.Time    // Time of the last refresh.
 
 	// UI components
+	term      *term.Terminal
 	keys      *core.Keys
 	line      *core.Line
 	suggested core.Line
@@ -43,77 +83,179 @@ type Engine struct {
 	prompt    *ui.Prompt
 	hint      *ui.Hint
 	completer *completion.Engine
+	styles    *color.Styles
 	opts      *inputrc.Config
 }
 
 // NewEngine is a required constructor for the display engine.
-func NewEngine(k *core.Keys, s *core.Selection, h *history.Sources, p *ui.Prompt, i *ui.Hint, c *completion.Engine, opts *inputrc.Config) *Engine {
+func NewEngine(t *term.Terminal, k *core.Keys, s *core.Selection, h *history.Sources, p *ui.Prompt, i *ui.Hint, c *completion.Engine, st *color.Styles, opts *inputrc.Config) *Engine {
 	return &Engine{
+		term:      t,
 		keys:      k,
 		selection: s,
 		histories: h,
 		prompt:    p,
 		hint:      i,
 		completer: c,
+		styles:    st,
 		opts:      opts,
 	}
 }
 
 // Init computes some base coordinates needed before displaying the line and helpers.
-// The shell syntax highlighter is also provided here, since any consumer library will
-// have bound it after instantiating a new shell instance.
-func Init(e *Engine, highlighter func([]rune) string) {
+// The shell syntax highlighters are also provided here, since any consumer library will
+// have bound them after instantiating a new shell instance. The token highlighter, if
+// not nil, is used instead of the highlighter function.
+func Init(e *Engine, highlighter func([]rune) string, tokens Highlighter) {
+	// The shell syntax highlighter uses the theme of this shell.
+	if _, isShell := tokens.(ShellSyntax); isShell {
+		tokens = ShellSyntax{styles: e.styles}
+	}
+
 	e.highlighter = highlighter
+	e.tokens = tokens
+}
+
+// OnRefresh sets a function called before each refresh of the display.
+func (e *Engine) OnRefresh(hook func()) {
+	e.preRefresh = hook
+}
+
+// StatusLine sets a function returning the status line, displayed
+// below the hints and completions and updated on each refresh.
+func (e *Engine) StatusLine(status func() string) {
+	e.status = status
+}
+
+// Suggester sets the function returning the line completed with its autosuggestion,
+// if any, or the line itself. Nothing is suggested if not set.
+func (e *Engine) Suggester(suggest func(line *core.Line) core.Line) {
+	e.suggest = suggest
+}
+
+// Mask displays the mask in place of each character of the input line (eg. when
+// reading passwords), or nothing if it is 0, in which case the cursor stays at the
+// start of the line. Neither suggestions nor highlighting are displayed until Unmask.
+func (e *Engine) Mask(mask rune) {
+	e.masked = true
+	e.mask = mask
+}
+
+// Unmask displays the input line as is again.
+func (e *Engine) Unmask() {
+	e.masked = false
 }
 
 // Refresh recomputes and redisplays the entire readline interface, except
 // the first lines of the primary prompt when the latter is a multiline one.
+// With the incremental-redisplay option, only the parts of the interface
+// that changed since the last refresh are printed again, if any.
 func (e *Engine) Refresh() {
-	fmt.Print(term.HideCursor)
+	if e.preRefresh != nil {
+		e.preRefresh()
+	}
 
-	// Go back to the first column, and if the primary prompt
-	// was not printed yet, back up to the line's beginning row.
-	term.MoveCursorBackwards(term.GetWidth())
+	e.refreshing.Lock()
+	defer e.refreshing.Unlock()
 
-	if !e.primaryPrinted {
-		term.MoveCursorUp(e.cursorRow)
-	}
+	e.refreshed = time.Now()
+
+	e.applyTheme()
+
+	// Terminals receive all changes at once, which avoids flickering.
+	e.beginFrame()
+	defer e.endFrame()
+
+	last := e.last
+	prompt := render(e.term, func() {
+		e.prompt.LastPrint()
+		e.markPromptEnd()
+	})
 
-	// Print either all or the last line of the prompt.
-	e.prompt.LastPrint()
+	// Print either all or the last line of the prompt, unless it was
+	// not modified, in which case the position of the line is known.
+	redraw := e.primaryPrinted || !last.reusable(prompt, e.term) || !e.opts.GetBool("incremental-redisplay")
 
-	// Get all positions required for the redisplay to come:
-	// prompt end (thus indentation), cursor positions, etc.
-	e.computeCoordinates(true)
+	if redraw {
+		fmt.Fprint(e.term, term.HideCursor)
 
-	// Print the line, right prompt, hints and completions.
-	e.displayLine()
-	e.prompt.RightPrint(e.lineCol, true)
-	e.displayHelpers()
+		// Go back to the first column, and if the primary prompt
+		// was not printed yet, back up to the line's beginning row.
+		e.term.MoveCursorBackwards(e.term.GetWidth())
 
-	// Go back to the start of the line, then to cursor.
-	e.cursorHintToLineStart()
-	e.lineStartToCursorPos()
-	fmt.Print(term.ShowCursor)
+		if !e.primaryPrinted {
+			e.term.MoveCursorUp(e.cursorRow)
+		}
+
+		fmt.Fprint(e.term, prompt)
+
+		// Get all positions required for the redisplay to come:
+		// prompt end (thus indentation), cursor positions, etc.
+		e.computeCoordinates(true)
+	} else {
+		e.computeLayout(true)
+	}
+
+	// Suggest the low-bandwidth mode when the terminal is slow.
+	e.checkLatency()
+
+	// Render the line, right prompt, hints and completions.
+	line := render(e.term, func() {
+		e.displayLine()
+		e.displayRightPrompt(true)
+	})
+	helpers := render(e.term, e.displayHelpers)
+
+	// Print them, and go back to the start of the line, then to cursor.
+	e.displayChanges(last, redraw, line, helpers)
+
+	e.last = frame{
+		valid:     true,
+		width:     e.term.GetWidth(),
+		bottom:    e.startRows + e.lineRows + 1 + e.hintRows + e.compRows + e.statusRows,
+		prompt:    prompt,
+		line:      line,
+		helpers:   helpers,
+		lineRows:  e.lineRows,
+		cursorRow: e.cursorRow,
+		cursorCol: e.cursorCol,
+	}
+}
+
+// RefreshPrompt redraws the entire primary prompt in place, when it spans on
+// several lines, or when some of its segments have been updated. The input
+// line is not redisplayed, and a call to Refresh must follow this one.
+func (e *Engine) RefreshPrompt() {
+	e.CursorToLineStart()
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorUp(e.prompt.PrimaryUsed())
+	fmt.Fprint(e.term, term.ClearScreenBelow)
+
+	e.PrintPrimaryPrompt()
 }
 
 // PrintPrimaryPrompt redraws the primary prompt.
 // There are relatively few cases where you want to use this.
 // It is currently only used when using clear-screen commands.
 func (e *Engine) PrintPrimaryPrompt() {
+	e.invalidate()
+	e.markPromptStart()
 	e.prompt.PrimaryPrint()
 	e.primaryPrinted = true
 }
 
 // ClearHelpers clears the hint and completion sections below the line.
 func (e *Engine) ClearHelpers() {
+	e.invalidate()
+
 	e.CursorBelowLine()
-	fmt.Print(term.ClearScreenBelow)
+	fmt.Fprint(e.term, term.ClearScreenBelow)
+	e.clearImage()
 
-	term.MoveCursorUp(1)
-	term.MoveCursorUp(e.lineRows)
-	term.MoveCursorDown(e.cursorRow)
-	term.MoveCursorForwards(e.cursorCol)
+	e.term.MoveCursorUp(1)
+	e.term.MoveCursorUp(e.lineRows)
+	e.term.MoveCursorDown(e.cursorRow)
+	e.term.MoveCursorForwards(e.cursorCol)
 }
 
 // ResetHelpers cancels all active hints and completions.
@@ -127,48 +269,67 @@ func (e *Engine) ResetHelpers() {
 // hints, completions and some right prompts, the shell will put the
 // display at the start of the line immediately following the line.
 func (e *Engine) AcceptLine() {
+	e.invalidate()
+
 	e.CursorToLineStart()
 
 	e.computeCoordinates(false)
 
 	// Go back to the end of the non-suggested line.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorDown(e.lineRows)
-	term.MoveCursorForwards(e.lineCol)
-	fmt.Print(term.ClearScreenBelow)
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorDown(e.lineRows)
+	e.term.MoveCursorForwards(e.lineCol)
+	fmt.Fprint(e.term, term.ClearScreenBelow)
+	e.clearImage()
 
 	// Reprint the right-side prompt if it's not a tooltip one.
-	e.prompt.RightPrint(e.lineCol, false)
+	e.displayRightPrompt(false)
 
 	// Go below this non-suggested line and clear everything.
-	term.MoveCursorBackwards(term.GetWidth())
-	fmt.Print(term.NewlineReturn)
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	fmt.Fprint(e.term, term.NewlineReturn)
 }
 
-// RefreshTransient goes back to the first line of the input buffer
-// and displays the transient prompt, then redisplays the input line.
-func (e *Engine) RefreshTransient() {
+// RefreshTransient goes back to the first line of the input buffer and displays
+// the transient prompt for the returned line and error, then redisplays the input
+// line, or the line replacing it if the transient prompt provides one.
+func (e *Engine) RefreshTransient(line string, err error) {
 	if !e.opts.GetBool("prompt-transient") {
 		return
 	}
 
-	// Go to the beginning of the primary prompt.
-	e.CursorToLineStart()
-	term.MoveCursorUp(e.prompt.PrimaryUsed())
+	transient, display := e.prompt.TransientFor(line, err)
+	if !display {
+		return
+	}
+
+	e.invalidate()
+
+	// Go back from below the accepted line to its first row:
+	// the transient prompt moves up to the primary prompt start.
+	e.term.MoveCursorUp(e.lineRows + 1)
 
 	// And redisplay the transient/primary/line.
-	e.prompt.TransientPrint()
-	e.displayLine()
-	fmt.Print(term.NewlineReturn)
+	indent := e.prompt.TransientPrint(transient)
+
+	if transient.Line != "" {
+		replaced := core.Line(strutil.FormatTabs(transient.Line))
+		core.DisplayLinePrompt(e.term, &replaced, indent, e.secondary)
+	} else {
+		e.displayLine()
+	}
+
+	fmt.Fprint(e.term, term.NewlineReturn)
 }
 
 // CursorToLineStart moves the cursor just after the primary prompt.
 // This function should only be called when the cursor is on its
 // "cursor" position on the input line.
 func (e *Engine) CursorToLineStart() {
-	term.MoveCursorBackwards(e.cursorCol)
-	term.MoveCursorUp(e.cursorRow)
-	term.MoveCursorForwards(e.startCols)
+	e.invalidate()
+	e.term.MoveCursorBackwards(e.cursorCol)
+	e.term.MoveCursorUp(e.cursorRow)
+	e.term.MoveCursorForwards(e.startCols)
 }
 
 // CursorBelowLine moves the cursor to the leftmost
@@ -176,40 +337,54 @@ func (e *Engine) CursorToLineStart() {
 // This function should only be called when the cursor
 // is on its "cursor" position on the input line.
 func (e *Engine) CursorBelowLine() {
-	term.MoveCursorUp(e.cursorRow)
-	term.MoveCursorDown(e.lineRows)
-	fmt.Print(term.NewlineReturn)
+	e.invalidate()
+	e.term.MoveCursorUp(e.cursorRow)
+	e.term.MoveCursorDown(e.lineRows)
+	fmt.Fprint(e.term, term.NewlineReturn)
 }
 
 // lineStartToCursorPos can be used if the cursor is currently
 // at the very start of the input line, that is just after the
 // last character of the prompt.
 func (e *Engine) lineStartToCursorPos() {
-	term.MoveCursorDown(e.cursorRow)
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorForwards(e.cursorCol)
+	e.term.MoveCursorDown(e.cursorRow)
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorForwards(e.cursorCol)
 }
 
 // cursor is on the line below the last line of input.
 func (e *Engine) cursorHintToLineStart() {
-	term.MoveCursorUp(1)
-	term.MoveCursorUp(e.lineRows - e.cursorRow)
+	e.term.MoveCursorUp(1)
+	e.term.MoveCursorUp(e.lineRows - e.cursorRow)
 	e.CursorToLineStart()
 }
 
 func (e *Engine) computeCoordinates(suggested bool) {
-	// Get the new input line and auto-suggested one.
-	e.line, e.cursor = e.completer.Line()
-	if e.completer.IsInserting() {
-		e.suggested = *e.line
-	} else {
-		e.suggested = e.histories.Suggest(e.line)
+	e.queryLineStart()
+	e.computeLayout(suggested)
+}
+
+// queryLineStart gets the position of the line's beginning by querying
+// the terminal for the cursor position, and measures the round-trip time
+// of the query, as a latency indicator.
+func (e *Engine) queryLineStart() {
+	// The terminal must have received everything printed before.
+	if e.output != nil {
+		e.flushFrame()
+
+		e.term.Output = e.output
+		defer func() { e.term.Output = &e.buffer }()
 	}
 
-	// Get the position of the line's beginning by querying
-	// the terminal for the cursor position.
+	start := time.Now()
 	e.startCols, e.startRows = e.keys.GetCursorPos()
 
+	if time.Since(start) > slowLinkLatency {
+		e.slowRefreshes++
+	} else {
+		e.slowRefreshes = 0
+	}
+
 	if e.startCols > 0 {
 		e.startCols--
 	}
@@ -218,87 +393,306 @@ func (e *Engine) computeCoordinates(suggested bool) {
 	if e.startCols == -1 {
 		e.startCols = e.prompt.LastUsed()
 	}
+}
 
-	e.cursorCol, e.cursorRow = core.CoordinatesCursor(e.cursor, e.startCols)
+// computeLayout computes the coordinates of the input line and of the cursor,
+// from the position of the line's beginning (queried by queryLineStart).
+func (e *Engine) computeLayout(suggested bool) {
+	// Get the new input line and auto-suggested one.
+	e.line, e.cursor = e.completer.Line()
+
+	switch {
+	case e.masked:
+		e.line, e.cursor = e.maskedLine()
+		e.suggested = *e.line
+	case suggested:
+		e.suggested = e.suggestedLine()
+	default:
+		e.suggested = *e.line
+	}
+
+	// Continuation lines are either aligned with the first one,
+	// or start after the secondary prompt, if there is one.
+	e.secondary = e.prompt.SecondaryPrompt()
+	e.nextCols = e.startCols
+
+	if e.secondary != "" {
+		e.nextCols = strutil.RealLength(e.secondary)
+	}
+
+	e.gutter = e.gutterWidth()
+	e.primaryPrinted = false
+
+	// Long lines are either scrolled on a single row, or wrapped.
+	if e.scrollLayout() {
+		return
+	}
+
+	e.cursorCol, e.cursorRow = e.cursorCoordinates(e.cursor)
 
 	// Get the number of rows used by the line, and the end line X pos.
-	if e.opts.GetBool("history-autosuggest") && suggested {
-		e.lineCol, e.lineRows = core.CoordinatesLine(&e.suggested, e.startCols)
+	if suggested {
+		e.lineCol, e.lineRows = core.CoordinatesLineIndent(&e.suggested, e.startCols+e.gutter, e.nextCols+e.gutter, e.term.GetWidth())
 	} else {
-		e.lineCol, e.lineRows = core.CoordinatesLine(e.line, e.startCols)
+		e.lineCol, e.lineRows = core.CoordinatesLineIndent(e.line, e.startCols+e.gutter, e.nextCols+e.gutter, e.term.GetWidth())
 	}
 
-	e.primaryPrinted = false
+	if suggested {
+		e.rightRow, e.rightCol = e.rightPromptPos(&e.suggested)
+	} else {
+		e.rightRow, e.rightCol = e.rightPromptPos(e.line)
+	}
 }
 
-func (e *Engine) displayLine() {
-	var line string
+// rightPromptPos returns the row of the displayed line on which the right prompt
+// is printed, depending on the prompt-right-line option, and the column at which
+// this row ends (the terminal width if the row is part of a wrapped line).
+func (e *Engine) rightPromptPos(line *core.Line) (row, col int) {
+	switch e.opts.GetString("prompt-right-line") {
+	case "first":
+		row = 0
+	case "cursor":
+		row = e.cursorRow
+	default:
+		return e.lineRows, e.lineCol
+	}
 
-	// Apply user-defined highlighter to the input line.
-	if e.highlighter != nil {
-		line = e.highlighter(*e.line)
-	} else {
-		line = string(*e.line)
+	usedY, indent := 0, e.startCols+e.gutter
+
+	for i, line := range strings.Split(string(*line), "\n") {
+		if i > 0 {
+			indent = e.nextCols + e.gutter
+		}
+
+		x, y := strutil.LineSpan([]rune(line), i, indent, e.term.GetWidth())
+		usedY += y
+
+		switch {
+		case usedY == row:
+			return row, x
+		case usedY > row:
+			return row, e.term.GetWidth()
+		}
 	}
 
-	// Highlight matching parenthesis
-	if e.opts.GetBool("blink-matching-paren") {
-		core.HighlightMatchers(e.selection)
-		defer core.ResetMatchers(e.selection)
+	return e.lineRows, e.lineCol
+}
+
+// displayRightPrompt prints the right prompt on the row of the input line set by
+// the prompt-right-line option (the last one by default), after clearing the end
+// of this row. The cursor must be at the end of the line, and is moved back there.
+func (e *Engine) displayRightPrompt(force bool) {
+	if e.rightRow == e.lineRows {
+		e.prompt.RightPrint(e.lineCol, force)
+		return
 	}
 
-	// Apply visual selections highlighting if any
-	line = e.highlightLine([]rune(line), *e.selection)
+	rows := e.lineRows - e.rightRow
+
+	e.term.MoveCursorUp(rows)
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorForwards(e.rightCol)
+
+	// There is no room left on the rows of a wrapped line.
+	if e.rightCol < e.term.GetWidth() {
+		fmt.Fprint(e.term, term.ClearLineAfter)
+		e.prompt.RightPrint(e.rightCol, force)
+	}
+
+	e.term.MoveCursorDown(rows)
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorForwards(e.lineCol)
+}
+
+func (e *Engine) displayLine() {
+	line := string(*e.line)
+
+	if !e.masked {
+		line = e.highlightedLine()
+	}
 
 	// Get the subset of the suggested line to print.
-	if len(e.suggested) > e.line.Len() && e.opts.GetBool("history-autosuggest") {
-		line += color.Dim + color.Fmt(color.Fg+"242") + string(e.suggested[e.line.Len():]) + color.Reset
+	if len(e.suggested) > e.line.Len() {
+		style := color.UnquoteRC(e.opts.GetString("autosuggest-style"))
+		if style == "" {
+			style = e.styles.Autosuggest
+		}
+
+		line += style + string(e.suggested[e.line.Len():]) + color.Reset
 	}
 
+	// Highlighters might use 256 or true colors.
+	line = e.styles.Degrade(line)
+
 	// Format tabs as spaces, for consistent display
-	line = strutil.FormatTabs(line) + term.ClearLineAfter
+	line = e.numberLines(strutil.FormatTabs(line))
+
+	if e.scrollWidth > 0 {
+		line = e.scrolledLine(line)
+	}
+
+	line += term.ClearLineAfter
 
 	// And display the line.
 	e.suggested.Set([]rune(line)...)
-	core.DisplayLine(&e.suggested, e.startCols)
+	core.DisplayLinePrompt(e.term, &e.suggested, e.startCols, e.secondary)
 
 	// Adjust the cursor if the line fits exactly in the terminal width.
 	if e.lineCol == 0 {
-		fmt.Print(term.NewlineReturn)
-		fmt.Print(term.ClearLineAfter)
+		fmt.Fprint(e.term, term.NewlineReturn)
+		fmt.Fprint(e.term, term.ClearLineAfter)
 	}
 }
 
+// highlightedLine returns the input line highlighted with the user-defined
+// highlighter, and with visual selections and other highlighted regions.
+func (e *Engine) highlightedLine() string {
+	// Highlight matching parenthesis, additional cursors, read-only ranges and matches.
+	switch {
+	case e.opts.GetBool("highlight-matching-brackets"):
+		style := color.UnquoteRC(e.opts.GetString("matching-bracket-style"))
+		core.HighlightMatchingBracket(e.selection, style)
+	case e.opts.GetBool("blink-matching-paren"):
+		core.HighlightMatchers(e.selection)
+	}
+
+	core.HighlightCursors(e.selection)
+	core.HighlightProtected(e.selection, e.opts.GetString("protected-region-style"))
+	core.HighlightMatches(e.selection, color.UnquoteRC(e.opts.GetString("search-match-style")),
+		color.UnquoteRC(e.opts.GetString("search-current-match-style")))
+	defer core.ResetMatchers(e.selection)
+
+	// Tokens and selections are composed character by character.
+	if e.tokens != nil {
+		return e.styledLine(*e.line, *e.selection)
+	}
+
+	var line string
+
+	// Apply user-defined highlighter to the input line.
+	if e.highlighter != nil {
+		line = e.highlighter(*e.line)
+	} else {
+		line = string(*e.line)
+	}
+
+	// Apply visual selections highlighting if any
+	return e.highlightLine([]rune(line), *e.selection)
+}
+
+// maskedLine returns a line made of the mask repeated for each character of the
+// input line (or an empty one if the mask is 0), and the cursor in this line.
+func (e *Engine) maskedLine() (*core.Line, *core.Cursor) {
+	line := new(core.Line)
+	cursor := core.NewCursor(line)
+
+	if e.mask == 0 {
+		return line, cursor
+	}
+
+	line.Set([]rune(strings.Repeat(string(e.mask), e.line.Len()))...)
+	cursor.Set(e.cursor.Pos())
+
+	return line, cursor
+}
+
 // displayHelpers renders the hint and completion sections.
 // It assumes that the cursor is on the last line of input,
 // and goes back to this same line after displaying this.
 func (e *Engine) displayHelpers() {
-	fmt.Print(term.NewlineReturn)
+	fmt.Fprint(e.term, term.NewlineReturn)
 
 	// Recompute completions and hints if autocompletion is on.
 	e.completer.Autocomplete()
 
 	// Display hint and completions.
 	ui.DisplayHint(e.hint)
-	e.hintRows = ui.CoordinatesHint(e.hint)
-	completion.Display(e.completer, e.AvailableHelperLines())
+	e.hintRows = ui.CoordinatesHint(e.hint) + e.displayImage()
+	completion.Display(e.completer, e.completionLines())
 	e.compRows = completion.Coordinates(e.completer)
+	e.statusRows = e.displayStatus()
 
 	// Go back to the first line below the input line.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(e.compRows)
-	term.MoveCursorUp(ui.CoordinatesHint(e.hint))
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorUp(e.statusRows)
+	e.term.MoveCursorUp(e.compRows)
+	e.term.MoveCursorUp(e.hintRows)
+}
+
+// displayStatus prints the status line below the hints and completions, cut
+// to the terminal width, and returns the number of rows the cursor moved down.
+func (e *Engine) displayStatus() (rows int) {
+	if e.status == nil {
+		return 0
+	}
+
+	status, _, _ := strings.Cut(e.status(), "\n")
+	if status == "" {
+		return 0
+	}
+
+	status = e.styles.Degrade(status)
+
+	status = string(color.Text(status).Trim(e.term.GetWidth() - 1))
+
+	// Completions leave the cursor on their last row.
+	if completion.Displayed(e.completer) {
+		fmt.Fprint(e.term, term.NewlineReturn)
+		rows++
+	}
+
+	fmt.Fprint(e.term, status+color.Reset+term.ClearLineAfter)
+
+	return rows
 }
 
 // AvailableHelperLines returns the number of lines available below the hint section.
 // It returns half the terminal space if we currently have less than 1/3rd of it below.
 func (e *Engine) AvailableHelperLines() int {
-	termHeight := term.GetLength()
+	termHeight := e.term.GetLength()
 	compLines := termHeight - e.startRows - e.lineRows - e.hintRows
 
+	if e.status != nil {
+		compLines--
+	}
+
 	if compLines < (termHeight / oneThirdTerminalHeight) {
 		compLines = (termHeight / halfTerminalHeight)
 	}
 
 	return compLines
 }
+
+// completionLines returns the number of lines available for the completions
+// (including the one hinting at remaining rows), which is limited by the
+// completion-page-size option if set.
+func (e *Engine) completionLines() int {
+	lines := e.AvailableHelperLines()
+
+	if size := e.opts.GetInt("completion-page-size"); size > 0 {
+		lines = min(lines, size+1)
+	}
+
+	return lines
+}
+
+// suggestedLine returns the line completed with its autosuggestion, if
+// any, unless a completion is being inserted in the line.
+func (e *Engine) suggestedLine() core.Line {
+	if e.completer.IsInserting() || e.suggest == nil {
+		return *e.line
+	}
+
+	return e.suggest(e.line)
+}
+
+// checkLatency suggests (once) the low-bandwidth mode in the hint section,
+// when several consecutive refreshes have shown a slow terminal link.
+func (e *Engine) checkLatency() {
+	if e.slowSuggested || e.slowRefreshes < slowLinkRefreshes || e.opts.GetBool("low-bandwidth") {
+		return
+	}
+
+	e.slowSuggested = true
+	e.hint.SetTemporary(e.styles.Hint + "slow terminal detected: low-bandwidth mode can be enabled with `set low-bandwidth on`" + color.Reset)
+}
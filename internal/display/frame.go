@@ -0,0 +1,147 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/internal/term"
+)
+
+// frame holds what the last refresh printed: the next one compares its
+// own output against it, so as to only print the parts that changed.
+type frame struct {
+	valid     bool
+	width     int
+	bottom    int    // Terminal row below the last one printed.
+	prompt    string // Last line of the primary prompt.
+	line      string // Input line and right prompt.
+	helpers   string // Hints, completions and status line.
+	lineRows  int
+	cursorRow int
+	cursorCol int
+}
+
+// reusable returns true if the display is still the one of the frame,
+// with the same prompt: neither the terminal was resized nor scrolled
+// since, and nothing else was printed (as far as the engine knows).
+func (f frame) reusable(prompt string, t *term.Terminal) bool {
+	return f.valid &&
+		f.prompt == prompt &&
+		f.width == t.GetWidth() &&
+		f.bottom < t.GetLength()
+}
+
+// FrameDelay returns how long to wait before the next refresh, so as not to
+// exceed the maximum frame rate (render-max-fps), or 0 if it can be done now.
+func (e *Engine) FrameDelay() time.Duration {
+	fps := e.opts.GetInt("render-max-fps")
+	if fps <= 0 {
+		return 0
+	}
+
+	e.refreshing.Lock()
+	defer e.refreshing.Unlock()
+
+	return time.Second/time.Duration(fps) - time.Since(e.refreshed)
+}
+
+// invalidate forces the next refresh to redisplay everything, because the
+// cursor was moved, or something was printed, since the last refresh.
+func (e *Engine) invalidate() {
+	e.last.valid = false
+}
+
+// displayChanges prints the input line and the helpers, either entirely
+// (after the prompt) or only if they changed since the last frame (from
+// its cursor position), then moves the cursor to its position in the line.
+func (e *Engine) displayChanges(last frame, redraw bool, line, helpers string) {
+	switch {
+	case redraw:
+		fmt.Fprint(e.term, line+helpers)
+		e.cursorHintToLineStart()
+
+	case line != last.line:
+		fmt.Fprint(e.term, term.HideCursor)
+		e.lastCursorToLineStart(last)
+		fmt.Fprint(e.term, line)
+
+		// Helpers only move if the line uses more or less rows.
+		if helpers != last.helpers || e.lineRows != last.lineRows {
+			fmt.Fprint(e.term, helpers)
+			e.cursorHintToLineStart()
+		} else {
+			e.term.MoveCursorBackwards(e.term.GetWidth())
+			e.term.MoveCursorUp(e.lineRows)
+			e.term.MoveCursorForwards(e.startCols)
+		}
+
+	case helpers != last.helpers:
+		fmt.Fprint(e.term, term.HideCursor)
+		e.lastCursorToLineStart(last)
+		e.term.MoveCursorDown(e.lineRows)
+		e.term.MoveCursorBackwards(e.term.GetWidth())
+		e.term.MoveCursorForwards(e.lineCol)
+		fmt.Fprint(e.term, helpers)
+		e.cursorHintToLineStart()
+
+	case e.cursorRow == last.cursorRow && e.cursorCol == last.cursorCol:
+		return
+
+	default:
+		e.lastCursorToLineStart(last)
+		e.lineStartToCursorPos()
+
+		return
+	}
+
+	e.lineStartToCursorPos()
+	fmt.Fprint(e.term, term.ShowCursor)
+}
+
+// lastCursorToLineStart moves the cursor from its position in the last frame
+// to the start of the input line, which has not moved since this frame.
+func (e *Engine) lastCursorToLineStart(last frame) {
+	e.term.MoveCursorBackwards(e.term.GetWidth())
+	e.term.MoveCursorUp(last.cursorRow)
+	e.term.MoveCursorForwards(e.startCols)
+}
+
+// beginFrame buffers all output until endFrame, so that terminals
+// receive all the changes of a refresh at once, instead of pieces.
+func (e *Engine) beginFrame() {
+	e.output = e.term.Output
+	e.term.Output = &e.buffer
+}
+
+// flushFrame writes the output buffered so far, if any (eg. before
+// querying the terminal, which must have received it first).
+func (e *Engine) flushFrame() {
+	if e.buffer.Len() == 0 {
+		return
+	}
+
+	e.output.Write(e.buffer.Bytes())
+	e.buffer.Reset()
+}
+
+// endFrame writes the buffered output, and stops buffering it.
+func (e *Engine) endFrame() {
+	e.flushFrame()
+	e.term.Output = e.output
+	e.output = nil
+}
+
+// render returns everything printed to the terminal by the function, instead of printing it.
+func render(t *term.Terminal, print func()) string {
+	var buf strings.Builder
+
+	output := t.Output
+	t.Output = &buf
+
+	defer func() { t.Output = output }()
+
+	print()
+
+	return buf.String()
+}
@@ -0,0 +1,48 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// gutterWidth returns the width of the line numbers gutter displayed before each
+// line of a multiline buffer when the line-numbers option is on (the width of the
+// highest line number and a space), or 0 if it is not displayed.
+func (e *Engine) gutterWidth() int {
+	lines := strings.Count(string(e.suggested), "\n") + 1
+
+	if !e.opts.GetBool("line-numbers") || e.masked || lines == 1 {
+		return 0
+	}
+
+	return len(fmt.Sprint(lines)) + 1
+}
+
+// numberLines returns the (formatted) line with the line numbers gutter before
+// each of its lines, if displayed. With the relative-line-numbers option, lines
+// are numbered relatively to the cursor one, on which the line number is shown.
+func (e *Engine) numberLines(line string) string {
+	if e.gutter == 0 {
+		return line
+	}
+
+	current := strings.Count(string((*e.line)[:e.cursor.Pos()]), "\n")
+	relative := e.opts.GetBool("relative-line-numbers")
+	style := color.UnquoteRC(e.opts.GetString("line-numbers-style"))
+
+	lines := strings.Split(line, "\n")
+
+	for num := range lines {
+		number := num + 1
+
+		if relative && num != current {
+			number = max(num-current, current-num)
+		}
+
+		lines[num] = fmt.Sprintf("%s%*d%s %s", style, e.gutter-1, number, color.Reset, lines[num])
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -11,6 +11,94 @@ import (
 	"github.com/reeflective/readline/internal/core"
 )
 
+// Token is a region of the input line, from Start to End (excluded, in runes),
+// to display with a style made of SGR sequences (eg. "\x1b[1;32m").
+type Token struct {
+	Start int
+	End   int
+	Style string
+}
+
+// Highlighter returns the tokens of the input line to highlight, which are
+// displayed along with the visual selections and other highlighted regions.
+// Tokens can overlap, in which case the last one has precedence.
+type Highlighter interface {
+	Highlight(line []rune) []Token
+}
+
+// styledLine renders the line with the styles of the highlighter tokens, and the
+// visual selections and other highlighted regions on top of them: styles are
+// computed for each character, so that they never leak into one another.
+func (e *Engine) styledLine(line []rune, selection core.Selection) string {
+	styles := make([]string, len(line))
+
+	for _, tok := range e.tokens.Highlight(line) {
+		for pos := max(tok.Start, 0); pos < min(tok.End, len(line)); pos++ {
+			styles[pos] = tok.Style
+		}
+	}
+
+	sorted := sortHighlights(selection)
+
+	var (
+		builder strings.Builder
+		current string
+	)
+
+	for pos, char := range line {
+		// Don't style the indentation or prompt of the next line.
+		if char == '\n' {
+			builder.WriteString(color.Reset)
+			builder.WriteRune(char)
+
+			current = ""
+
+			continue
+		}
+
+		if style := styles[pos] + e.regionStyle(sorted, pos); style != current {
+			builder.WriteString(color.Reset + style)
+			current = style
+		}
+
+		builder.WriteRune(char)
+	}
+
+	builder.WriteString(color.Reset)
+
+	return builder.String()
+}
+
+// regionStyle returns the style of the highlighted region at the given
+// position, if any (the one starting last if several overlap there).
+func (e *Engine) regionStyle(sorted []core.Selection, pos int) string {
+	var region *core.Selection
+
+	start := -1
+
+	for i := range sorted {
+		bpos, epos := sorted[i].Pos()
+		if bpos <= pos && pos < epos && bpos >= start {
+			region, start = &sorted[i], bpos
+		}
+	}
+
+	if region == nil {
+		return ""
+	}
+
+	fg, bg := region.Highlights()
+
+	// Update the highlighting with inputrc settings if any.
+	if bg != "" && region.Type != "matcher" {
+		if bg = color.UnquoteRC(e.opts.GetString("active-region-start-color")); bg == "" {
+			bg = color.Reverse
+		}
+	}
+
+	return bg + fg
+}
+
 // highlightLine applies visual/selection highlighting to a line.
 // The provided line might already have been highlighted by a user-provided
 // highlighter: this function accounts for any embedded color sequences.
@@ -35,7 +123,7 @@ func (e *Engine) highlightLine(line []rune, selection core.Selection) string {
 	commentPattern := fmt.Sprintf(`(^|\s)%s.*`, comment)
 
 	if commentsMatch, err := regexp.Compile(commentPattern); err == nil {
-		commentColor := color.SGRStart + color.Fg + "244" + color.SGREnd
+		commentColor := e.styles.Comment
 		highlighted = commentsMatch.ReplaceAllString(highlighted, fmt.Sprintf("%s${0}%s", commentColor, color.Reset))
 	}
 
@@ -203,8 +291,9 @@ func (e *Engine) hlReset(regions []core.Selection, line []rune, pos int) ([]core
 			regions = regions[:i]
 		}
 
+		// Foreground styles may also be bold, dim or underlined.
 		if foreground != "" {
-			line = append(line, []rune(color.FgDefault)...)
+			line = append(line, []rune(color.FgDefault+color.BoldReset+color.UnderscoreReset)...)
 		}
 
 		if background != "" {
@@ -0,0 +1,77 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+	"github.com/reeflective/readline/internal/ui"
+)
+
+// displayImage displays the preview of the selected completion candidate, or
+// else the image of the hint section, below the hint text, and returns the
+// number of rows used. The image replaces the last one displayed, if any.
+func (e *Engine) displayImage() (rows int) {
+	e.clearImage()
+
+	img := e.completer.Preview()
+	if img == nil {
+		img = e.hint.Image()
+	}
+
+	if img == nil || img.Rows <= 0 || img.Cols <= 0 {
+		return 0
+	}
+
+	protocol := term.ImageProtocol(e.opts.GetString("image-protocol"))
+	if protocol == "" || img.Image == nil || e.opts.GetBool("low-bandwidth") {
+		return e.displayImageAlt(img)
+	}
+
+	cols := min(img.Cols, e.term.GetWidth()-1)
+	rows = min(img.Rows, e.term.GetLength()/halfTerminalHeight)
+
+	if rows <= 0 {
+		return 0
+	}
+
+	// Make room for the image first, so that the screen
+	// does not scroll while the terminal is drawing it.
+	fmt.Fprint(e.term, strings.Repeat(term.ClearLineAfter+term.NewlineReturn, rows))
+	e.term.MoveCursorUp(rows)
+
+	fmt.Fprint(e.term, term.SaveCursorPos)
+	fmt.Fprint(e.term, term.ImageSequence(img.Image, cols, rows, protocol))
+	fmt.Fprint(e.term, term.RestoreCursorPos)
+
+	e.term.MoveCursorDown(rows)
+
+	e.imageProtocol = protocol
+
+	return rows
+}
+
+// displayImageAlt displays the alternative text of an image, on a single line.
+func (e *Engine) displayImageAlt(img *ui.Image) (rows int) {
+	alt, _, _ := strings.Cut(img.Alt, "\n")
+	if alt == "" {
+		return 0
+	}
+
+	alt = color.Truncate(e.styles.Degrade(alt), e.term.GetWidth()-1)
+	fmt.Fprint(e.term, alt+color.Reset+term.ClearLineAfter+term.NewlineReturn)
+
+	return 1
+}
+
+// clearImage deletes the last image displayed, when the terminal
+// does not when clearing the screen (eg. with the kitty protocol).
+func (e *Engine) clearImage() {
+	if e.imageProtocol == "" {
+		return
+	}
+
+	fmt.Fprint(e.term, term.ImageClear(e.imageProtocol))
+	e.imageProtocol = ""
+}
@@ -0,0 +1,74 @@
+package display
+
+import (
+	"fmt"
+
+	"github.com/reeflective/readline/internal/term"
+)
+
+// Shell integration sequences (OSC 133, from FinalTerm), marking the prompts,
+// input lines and command outputs, so that terminals can jump between prompts,
+// or select the output of a command.
+const (
+	promptStart  = "\x1b]133;A\a"
+	promptEnd    = "\x1b]133;B\a"
+	commandStart = "\x1b]133;C\a"
+	commandEnd   = "\x1b]133;D%s\a"
+)
+
+// WorkingDirectory sets a function returning the working directory of the
+// application, reported to the terminal (with OSC 7) before each prompt.
+func (e *Engine) WorkingDirectory(dir func() string) {
+	e.workingDir = dir
+}
+
+// CommandStart marks the start of the output of the command accepted by
+// the user (if the error is nil), when the shell-integration option is on.
+func (e *Engine) CommandStart(err error) {
+	if err != nil || !e.opts.GetBool("shell-integration") {
+		return
+	}
+
+	fmt.Fprint(e.term, commandStart)
+	e.commandRan = true
+}
+
+// markPromptStart marks the end of the last command (with its status, if known),
+// reports the working directory, if any, and marks the start of the prompt.
+// Marks are only printed when the shell-integration option is on.
+func (e *Engine) markPromptStart() {
+	integration := e.opts.GetBool("shell-integration")
+
+	if integration && e.commandRan {
+		var status string
+		if code, set := e.prompt.Status(); set {
+			status = fmt.Sprintf(";%d", code)
+		}
+
+		fmt.Fprintf(e.term, commandEnd, status)
+	}
+
+	e.commandRan = false
+
+	if e.workingDir != nil {
+		if dir := e.workingDir(); dir != "" {
+			fmt.Fprint(e.term, reportDirectory(dir))
+		}
+	}
+
+	if integration {
+		fmt.Fprint(e.term, promptStart)
+	}
+}
+
+// markPromptEnd marks the end of the prompt, and the start of the input line.
+func (e *Engine) markPromptEnd() {
+	if e.opts.GetBool("shell-integration") {
+		fmt.Fprint(e.term, promptEnd)
+	}
+}
+
+// reportDirectory returns the OSC 7 sequence reporting the working directory.
+func reportDirectory(dir string) string {
+	return "\x1b]7;" + term.FileURL(dir) + "\x1b\\"
+}
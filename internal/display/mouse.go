@@ -0,0 +1,55 @@
+package display
+
+import (
+	"github.com/reeflective/readline/internal/core"
+)
+
+// LinePos returns the position in the input line of the character displayed
+// at the given terminal coordinates (starting at 1, as in mouse reports).
+// Clicking past the end of a line row returns the end of the row.
+func (e *Engine) LinePos(x, y int) (pos int, found bool) {
+	row := y - e.lineStartRow()
+	if e.startRows < 1 || row < 0 || row > e.lineRows {
+		return 0, false
+	}
+
+	cursor := core.NewCursor(e.line)
+	pos = -1
+
+	for i := 0; i <= e.line.Len(); i++ {
+		cursor.Set(i)
+		cx, cy := e.cursorCoordinates(cursor)
+
+		if cy > row {
+			break
+		}
+
+		if cy == row && (pos == -1 || cx <= x-1) {
+			pos = i
+		}
+	}
+
+	return pos, pos != -1
+}
+
+// CompletionPos returns the row and column (starting at 0) in the completions
+// area of the given terminal coordinates (starting at 1, as in mouse reports).
+func (e *Engine) CompletionPos(x, y int) (row, column int, found bool) {
+	row = y - (e.lineStartRow() + e.lineRows + 1 + e.hintRows)
+	if e.startRows < 1 || row < 0 || row > e.compRows {
+		return 0, 0, false
+	}
+
+	return row, x - 1, true
+}
+
+// lineStartRow returns the terminal row of the first line of input, accounting
+// for the screen having scrolled up when displaying the hints and completions.
+func (e *Engine) lineStartRow() int {
+	bottom := e.startRows + e.lineRows + 1 + e.hintRows + e.compRows + e.statusRows
+	if overflow := bottom - e.term.GetLength(); overflow > 0 {
+		return e.startRows - overflow
+	}
+
+	return e.startRows
+}
@@ -0,0 +1,95 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// scrollLayout computes the coordinates of the line and of the cursor when the line
+// is scrolled horizontally (with horizontal-scroll-mode), that is, when it does not
+// fit in the terminal and has no newlines: it is then displayed on a single row, and
+// scrolled by half of its width when the cursor would go out of it. Returns false
+// if the line is not scrolled, in which case it is wrapped on several rows.
+func (e *Engine) scrollLayout() bool {
+	e.scrollWidth = 0
+
+	if !e.opts.GetBool("horizontal-scroll-mode") || strings.Contains(string(e.suggested), "\n") {
+		return false
+	}
+
+	width := strutil.RealLength(string(e.suggested))
+	columns := e.scrollColumns()
+
+	// The row must leave room to the markers and to the cursor.
+	if width <= columns || columns < 4 {
+		e.scroll = 0
+		return false
+	}
+
+	e.scrollWidth = width
+	cursor := strutil.RealLength(string((*e.line)[:e.cursor.Pos()]))
+
+	if !e.scrollShows(cursor) {
+		e.scroll = max(cursor-columns/2, 0)
+	}
+
+	e.cursorCol, e.cursorRow = e.cursorCoordinates(e.cursor)
+	e.lineCol, e.lineRows = e.startCols+min(width-e.scroll, columns), 0
+	e.rightRow, e.rightCol = 0, e.lineCol
+
+	return true
+}
+
+// scrollColumns returns the number of columns in which a scrolled line is displayed
+// (the last column of the terminal is left empty, for the cursor at the end of it).
+func (e *Engine) scrollColumns() int {
+	return e.term.GetWidth() - e.startCols - 1
+}
+
+// scrollShows returns true if the cursor column in the line is displayed with the
+// current scroll: columns replaced by the scroll markers (< and >) are not.
+func (e *Engine) scrollShows(cursor int) bool {
+	offset := cursor - e.scroll
+	columns := e.scrollColumns()
+
+	switch {
+	case offset < 0 || (e.scroll > 0 && offset < 1):
+		return false
+	case e.scrollWidth > e.scroll+columns:
+		return offset <= columns-2
+	default:
+		return offset <= columns
+	}
+}
+
+// scrolledLine returns the part of the (formatted) line displayed with the current
+// scroll, with a < marker if the line is scrolled, and a > one if it goes further.
+func (e *Engine) scrolledLine(line string) string {
+	from, to := e.scroll, min(e.scroll+e.scrollColumns(), e.scrollWidth)
+	left, right := "", ""
+
+	if from > 0 {
+		left = "<"
+		from++
+	}
+
+	if to < e.scrollWidth {
+		right = color.Reset + ">"
+		to--
+	}
+
+	return left + color.Slice(line, from, to-from) + right
+}
+
+// cursorCoordinates returns the terminal column and the row (from the line start)
+// of a cursor in the line, whether it is scrolled horizontally or wrapped.
+func (e *Engine) cursorCoordinates(cursor *core.Cursor) (x, y int) {
+	if e.scrollWidth > 0 {
+		return e.startCols + strutil.RealLength(string((*e.line)[:cursor.Pos()])) - e.scroll, 0
+	}
+
+	return core.CoordinatesCursorIndent(cursor, e.startCols+e.gutter, e.nextCols+e.gutter, e.term.GetWidth())
+}
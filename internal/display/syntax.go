@@ -0,0 +1,110 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// ShellSyntax highlights shell command lines: command names, flags, quoted
+// strings, control and redirection operators, and comments. Its tokens use
+// the theme of the shell displaying them (see Init), or the default one.
+type ShellSyntax struct {
+	styles *color.Styles
+}
+
+// Highlight returns the tokens of a shell command line to highlight.
+func (s ShellSyntax) Highlight(line []rune) []Token {
+	styles := s.styles
+	if styles == nil {
+		styles = color.NewStyles()
+	}
+
+	var (
+		tokens   []Token
+		command  = true // The next word is a command name.
+		redirect bool   // The next word is a redirection target.
+		comment  int    // End of the last comment.
+		previous int    // End of the last token.
+	)
+
+	for _, tok := range strutil.Tokenize(line) {
+		if tok.Start < comment {
+			continue
+		}
+
+		// Commands also start on new lines.
+		if strings.ContainsRune(string(line[previous:tok.Start]), '\n') {
+			command, redirect = true, false
+		}
+
+		previous = tok.End
+		word := line[tok.Start:tok.End]
+
+		switch {
+		case tok.Kind == strutil.TokenControl:
+			tokens = append(tokens, Token{tok.Start, tok.End, styles.Operator})
+			command, redirect = true, false
+
+			continue
+
+		case tok.Kind == strutil.TokenRedirect:
+			tokens = append(tokens, Token{tok.Start, tok.End, styles.Operator})
+			redirect = true
+
+			continue
+
+		case word[0] == '#':
+			comment = len(line)
+			if end := strings.IndexRune(string(line[tok.Start:]), '\n'); end != -1 {
+				comment = tok.Start + len([]rune(string(line[tok.Start:])[:end]))
+			}
+
+			tokens = append(tokens, Token{tok.Start, comment, styles.Comment})
+
+			continue
+		}
+
+		switch {
+		case redirect:
+			redirect = false
+		case command:
+			tokens = append(tokens, Token{tok.Start, tok.End, styles.Command})
+			command = false
+		case word[0] == '-':
+			tokens = append(tokens, Token{tok.Start, tok.End, styles.Flag})
+		}
+
+		tokens = append(tokens, quotedTokens(word, tok.Start, styles.String)...)
+	}
+
+	return tokens
+}
+
+// quotedTokens returns the quoted strings of a word starting at the
+// given position, including their quotes, until the end of the word
+// if they are not closed.
+func quotedTokens(word []rune, start int, style string) (tokens []Token) {
+	var quote rune
+
+	begin := 0
+
+	for pos := 0; pos < len(word); pos++ {
+		switch char := word[pos]; {
+		case char == '\\' && quote != '\'':
+			pos++
+		case quote == 0 && (char == '\'' || char == '"'):
+			quote, begin = char, pos
+		case char == quote:
+			tokens = append(tokens, Token{start + begin, start + pos + 1, style})
+			quote = 0
+		}
+	}
+
+	if quote != 0 {
+		tokens = append(tokens, Token{start + begin, start + len(word), style})
+	}
+
+	return tokens
+}
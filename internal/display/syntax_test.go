@@ -0,0 +1,61 @@
+package display
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+func TestShellSyntax(t *testing.T) {
+	var (
+		command  = color.Bold + color.FgGreen
+		flag     = color.FgCyan
+		str      = color.FgYellow
+		operator = color.FgMagenta
+		comment  = color.Fmt(color.Fg + "244")
+	)
+
+	tests := []struct {
+		name string
+		line string
+		want []Token
+	}{
+		{
+			name: "Command and flags",
+			line: "ls -la --color dir",
+			want: []Token{{0, 2, command}, {3, 6, flag}, {7, 14, flag}},
+		},
+		{
+			name: "Quoted strings",
+			line: `echo "a b" x'c'`,
+			want: []Token{{0, 4, command}, {5, 10, str}, {12, 15, str}},
+		},
+		{
+			name: "Unclosed quote",
+			line: `echo 'abc`,
+			want: []Token{{0, 4, command}, {5, 9, str}},
+		},
+		{
+			name: "Pipelines and redirections",
+			line: "cat file | grep -v x > out; ls",
+			want: []Token{
+				{0, 3, command}, {9, 10, operator}, {11, 15, command},
+				{16, 18, flag}, {21, 22, operator}, {26, 27, operator}, {28, 30, command},
+			},
+		},
+		{
+			name: "Comments",
+			line: "ls # list | files\nls",
+			want: []Token{{0, 2, command}, {3, 17, comment}, {18, 20, command}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := (ShellSyntax{}).Highlight([]rune(test.line)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Highlight() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package display
+
+import (
+	"github.com/reeflective/readline/internal/color"
+)
+
+// SetTheme sets the theme used by the shell, instead of the preset one named
+// by the theme option. Styles set with theme-<element> options still apply.
+// If nil, the preset theme is used again.
+func (e *Engine) SetTheme(theme *color.Theme) {
+	e.theme = theme
+}
+
+// applyTheme sets the color depth supported by the terminal (16 colors only
+// in low-bandwidth mode), and the theme used by all components of the shell:
+// either the one set by the application or the preset chosen by the theme
+// option, with the styles of its elements overridden by theme-* options.
+func (e *Engine) applyTheme() {
+	depth := color.DepthNamed(e.opts.GetString("color-depth"))
+	if e.opts.GetBool("low-bandwidth") {
+		depth = color.Colors16
+	}
+
+	e.styles.UseDepth(depth)
+
+	theme := color.ThemeNamed(e.opts.GetString("theme"))
+	if e.theme != nil {
+		theme = *e.theme
+	}
+
+	for _, name := range color.ThemeElements() {
+		if style := e.opts.GetString("theme-" + name); style != "" {
+			theme.Set(name, color.UnquoteRC(style))
+		}
+	}
+
+	e.styles.UseTheme(theme)
+}
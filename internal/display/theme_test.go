@@ -0,0 +1,93 @@
+package display
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+)
+
+func TestEngine_ApplyTheme(t *testing.T) {
+	custom := color.Dark()
+	custom.Error = "\x1b[38;2;255;135;0m"
+
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		theme   *color.Theme
+		error   string
+		warning string
+	}{
+		{
+			name:    "Preset",
+			options: map[string]interface{}{"theme": "light", "color-depth": "truecolor"},
+			error:   color.Light().Error,
+			warning: color.Light().Warning,
+		},
+		{
+			name:    "Color depth",
+			options: map[string]interface{}{"theme": "light", "color-depth": "16"},
+			error:   "\x1b[91m",
+			warning: "\x1b[91m",
+		},
+		{
+			name:    "Low bandwidth",
+			options: map[string]interface{}{"theme": "light", "color-depth": "truecolor", "low-bandwidth": true},
+			error:   "\x1b[91m",
+			warning: "\x1b[91m",
+		},
+		{
+			name:    "Element option",
+			options: map[string]interface{}{"theme": "light", "color-depth": "256", "theme-warning": `\e[1;35m`},
+			error:   color.Light().Error,
+			warning: "\x1b[1;35m",
+		},
+		{
+			name:    "Application theme",
+			options: map[string]interface{}{"theme": "light", "color-depth": "256", "theme-warning": `\e[1;35m`},
+			theme:   &custom,
+			error:   "\x1b[38;5;208m",
+			warning: "\x1b[1;35m",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eng := &Engine{styles: color.NewStyles(), opts: inputrc.NewDefaultConfig()}
+
+			for name, value := range test.options {
+				eng.opts.Set(name, value)
+			}
+
+			eng.SetTheme(test.theme)
+			eng.applyTheme()
+
+			if eng.styles.Error != test.error || eng.styles.Warning != test.warning {
+				t.Errorf("Styles = {Error: %q, Warning: %q}, want {Error: %q, Warning: %q}",
+					eng.styles.Error, eng.styles.Warning, test.error, test.warning)
+			}
+		})
+	}
+}
+
+func TestEngine_ApplyThemeShells(t *testing.T) {
+	t.Setenv("COLORTERM", "truecolor")
+
+	light := &Engine{styles: color.NewStyles(), opts: inputrc.NewDefaultConfig()}
+	light.opts.Set("theme", "light")
+	light.opts.Set("color-depth", "16")
+
+	dark := &Engine{styles: color.NewStyles(), opts: inputrc.NewDefaultConfig()}
+
+	// Each shell has its own theme and color depth.
+	light.applyTheme()
+	dark.applyTheme()
+
+	if light.styles.Error != "\x1b[91m" {
+		t.Errorf("light Styles.Error = %q, want %q", light.styles.Error, "\x1b[91m")
+	}
+
+	if dark.styles.Theme != color.Dark() {
+		t.Errorf("dark Styles = %+v, want the dark theme", dark.styles.Theme)
+	}
+}
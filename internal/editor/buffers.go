@@ -8,8 +8,10 @@ import (
 	"sync"
 	"unicode"
 
+	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/term"
 )
 
 var (
@@ -20,26 +22,52 @@ var (
 	alphaRegisters = 52
 )
 
+// Kind is the type of the contents of a register, which determines how they are put in the line.
+type Kind int
+
+const (
+	// CharWise contents are inserted at the cursor position.
+	CharWise Kind = iota
+	// LineWise contents are entire lines, inserted above or below the cursor line.
+	LineWise
+	// BlockWise contents are the lines of a rectangle, each inserted at
+	// the same column in successive lines, starting with the cursor one.
+	BlockWise
+)
+
 // Buffers is a list of registers in which to put yanked/cut contents.
 // These buffers technically are Vim registers with full functionality.
 type Buffers struct {
-	num      map[int][]rune  // numbered registers (0-9)
+	num      map[int][]rune  // numbered registers (0-9), also the kill ring
 	alpha    map[rune][]rune // lettered registers ( a-z )
 	ro       map[rune][]rune // read-only registers ( . % : )
+	clip     map[rune][]rune // clipboard registers ( + * ), last written contents
+	numKinds map[int]Kind    // yank type of numbered registers
+	kinds    map[rune]Kind   // yank type of lettered and clipboard registers
 	waiting  bool            // The user wants to use a still unidentified register
 	selected bool            // We have identified the register, and acting on it.
 	active   rune            // Any of the read/write registers ("/num/alpha)
 	mutex    *sync.Mutex
+	term     *term.Terminal // OSC 52 clipboard sequences are written to it.
+	styles   *color.Styles
+	config   *inputrc.Config
 }
 
 // NewBuffers is a required constructor to set up all the buffers/registers
 // for the shell, because it contains maps that must be correctly initialized.
-func NewBuffers() *Buffers {
+// The configuration selects the system clipboard used by the + and * registers.
+func NewBuffers(t *term.Terminal, styles *color.Styles, config *inputrc.Config) *Buffers {
 	return &Buffers{
-		num:   make(map[int][]rune, numRegisters),
-		alpha: make(map[rune][]rune, alphaRegisters),
-		ro:    map[rune][]rune{},
-		mutex: &sync.Mutex{},
+		num:      make(map[int][]rune, numRegisters),
+		alpha:    make(map[rune][]rune, alphaRegisters),
+		ro:       map[rune][]rune{},
+		clip:     map[rune][]rune{},
+		numKinds: make(map[int]Kind, numRegisters),
+		kinds:    map[rune]Kind{},
+		mutex:    &sync.Mutex{},
+		term:     t,
+		styles:   styles,
+		config:   config,
 	}
 }
 
@@ -73,12 +101,17 @@ func (reg *Buffers) SetActive(register rune) {
 // Get returns the contents of a given register.
 // If the rune is nil (rune(0)), it returns the value of the kill buffer (the " Vim register).
 // If the rune is an alphanumeric comprised in the valid register IDs, their content is returned.
+// The + and * registers return the contents of the system clipboard and primary selection.
 // If the register name is invalid, the function returns an empty rune slice.
 func (reg *Buffers) Get(register rune) []rune {
 	if register == 0 {
 		return reg.GetKill()
 	}
 
+	if isClipboard(register) {
+		return reg.readClipboard(register)
+	}
+
 	num, err := strconv.Atoi(string(register))
 	if err == nil {
 		return reg.num[num]
@@ -107,23 +140,60 @@ func (reg *Buffers) Active() []rune {
 	return reg.Get(reg.active)
 }
 
-// Pop rotates the kill ring and returns the new top.
+// Kind returns the yank type of the contents of a given register (the kill buffer if
+// the rune is nil). Contents of the system clipboard not written by the shell are
+// line-wise if they end with a newline, and character-wise otherwise.
+func (reg *Buffers) Kind(register rune) Kind {
+	switch {
+	case register == 0:
+		return reg.numKinds[0]
+	case isClipboard(register):
+		if buf := reg.readClipboard(register); string(buf) != string(reg.clip[register]) {
+			if len(buf) > 0 && buf[len(buf)-1] == '\n' {
+				return LineWise
+			}
+
+			return CharWise
+		}
+
+		return reg.kinds[register]
+	}
+
+	if num, err := strconv.Atoi(string(register)); err == nil {
+		return reg.numKinds[num]
+	}
+
+	return reg.kinds[register]
+}
+
+// ActiveKind returns the yank type of the active register (or the kill buffer
+// if no register is active), without resetting the active register.
+func (reg *Buffers) ActiveKind() Kind {
+	if !reg.waiting && !reg.selected {
+		return reg.Kind(0)
+	}
+
+	return reg.Kind(reg.active)
+}
+
+// Pop rotates the kill ring and returns the new top: the top entry
+// is moved to the bottom of the ring, and no entry is ever deleted.
 func (reg *Buffers) Pop() []rune {
 	if len(reg.num) == 0 {
 		return nil
 	}
 
-	// Reassign the kill buffer and
-	// pop the first numbered register.
-	buf := string(reg.num[0])
-	delete(reg.num, 0)
+	top, kind := reg.num[0], reg.numKinds[0]
 
-	for i := 0; i < len(reg.num); i++ {
-		reg.num[i] = []rune(string(reg.num[i+1]))
-		delete(reg.num, i+1)
+	for i := 0; i < len(reg.num)-1; i++ {
+		reg.num[i] = reg.num[i+1]
+		reg.numKinds[i] = reg.numKinds[i+1]
 	}
 
-	return []rune(buf)
+	reg.num[len(reg.num)-1] = top
+	reg.numKinds[len(reg.num)-1] = kind
+
+	return reg.num[0]
 }
 
 // GetKill returns the contents of the kill buffer.
@@ -137,7 +207,15 @@ func (reg *Buffers) GetKill() []rune {
 
 // Write writes a slice to the currently active buffer, and/or to the kill one.
 // After the operation, the buffers are reset, eg. none is considered active.
+// The contents are character-wise: use WriteAs to write lines or blocks.
 func (reg *Buffers) Write(content ...rune) {
+	reg.WriteAs(CharWise, content...)
+}
+
+// WriteAs is like Write, but the contents are stored with a given yank type.
+// Line-wise contents always end with a newline, which is added if needed,
+// and block-wise contents are the lines of the block separated by newlines.
+func (reg *Buffers) WriteAs(kind Kind, content ...rune) {
 	buf := string(content)
 
 	defer reg.Reset()
@@ -146,15 +224,19 @@ func (reg *Buffers) Write(content ...rune) {
 		return
 	}
 
+	if kind == LineWise && !strings.HasSuffix(buf, "\n") {
+		buf += "\n"
+	}
+
 	// Either write to the active register, or add to numbered ones.
 	if reg.selected {
-		reg.WriteTo(reg.active, []rune(buf)...)
+		reg.writeTo(reg.active, kind, []rune(buf))
 	} else {
-		reg.writeNum(-1, []rune(buf))
+		reg.writeNum(-1, kind, []rune(buf))
 	}
 }
 
-// WriteTo writes a slice directly to a target register.
+// WriteTo writes a slice directly to a target register, as character-wise contents.
 // If the register name is invalid, nothing is written anywhere.
 func (reg *Buffers) WriteTo(register rune, content ...rune) {
 	buf := string(content)
@@ -163,23 +245,7 @@ func (reg *Buffers) WriteTo(register rune, content ...rune) {
 		return
 	}
 
-	if register == 0 {
-		reg.writeNum(0, []rune(buf))
-		return
-	}
-
-	// If number register.
-	num, err := strconv.Atoi(string(register))
-	if num > 0 && num < 10 && err != nil {
-		reg.writeNum(num, []rune(buf))
-		return
-	}
-
-	// If lettered register.
-	if unicode.IsLetter(register) {
-		reg.writeAlpha(register, []rune(buf))
-		return
-	}
+	reg.writeTo(register, CharWise, []rune(buf))
 }
 
 // IsSelected returns the name of the selected register, and
@@ -195,6 +261,26 @@ func (reg *Buffers) Reset() {
 	reg.selected = false
 }
 
+// Wipe overwrites the contents of all registers, including the
+// clipboard ones (but not the system clipboard), and deletes them.
+func (reg *Buffers) Wipe() {
+	for _, registers := range []map[rune][]rune{reg.alpha, reg.ro, reg.clip} {
+		for name, buf := range registers {
+			clear(buf)
+			delete(registers, name)
+		}
+	}
+
+	for num, buf := range reg.num {
+		clear(buf)
+		delete(reg.num, num)
+	}
+
+	clear(reg.numKinds)
+	clear(reg.kinds)
+	reg.Reset()
+}
+
 // Complete returns the contents of all buffers as a structured list of completions.
 func (reg *Buffers) Complete() completion.Values {
 	vals := make([]completion.Candidate, 0)
@@ -205,11 +291,7 @@ func (reg *Buffers) Complete() completion.Values {
 
 	// Disable sorting, force list long and add hint.
 	comps := completion.AddRaw(vals)
-	if comps.NoSort == nil {
-		comps.NoSort = make(map[string]bool)
-	}
-
-	comps.NoSort["*"] = true
+	comps.Sort["*"] = completion.SortNone
 
 	if comps.ListLong == nil {
 		comps.ListLong = make(map[string]bool)
@@ -218,7 +300,7 @@ func (reg *Buffers) Complete() completion.Values {
 	comps.ListLong["*"] = true
 
 	// Registers Hint
-	hint := color.Bold + color.FgBlue + "(registers)"
+	hint := reg.styles.Title + "(registers)"
 
 	if len(vals) == 0 {
 		hint += " - empty -"
@@ -229,32 +311,66 @@ func (reg *Buffers) Complete() completion.Values {
 	return comps
 }
 
-func (reg *Buffers) writeNum(register int, buf []rune) {
-	// No numbered register above 10
-	if register > numRegisters-1 {
+func (reg *Buffers) writeTo(register rune, kind Kind, buf []rune) {
+	if register == 0 {
+		reg.writeNum(0, kind, buf)
+		return
+	}
+
+	// If clipboard register.
+	if isClipboard(register) {
+		reg.kinds[register] = kind
+		reg.writeClipboard(register, buf)
+
+		return
+	}
+
+	// If number register.
+	num, err := strconv.Atoi(string(register))
+	if num > 0 && num < 10 && err != nil {
+		reg.writeNum(num, kind, buf)
+		return
+	}
+
+	// If lettered register.
+	if unicode.IsLetter(register) {
+		reg.writeAlpha(register, kind, buf)
+		return
+	}
+}
+
+func (reg *Buffers) writeNum(register int, kind Kind, buf []rune) {
+	size := reg.ringSize()
+
+	// No numbered register above the kill ring size
+	if register > size-1 {
 		return
 	}
 
 	// Add to the stack with the specified register
 	if register > 0 {
 		reg.num[register] = buf
+		reg.numKinds[register] = kind
 
 		return
 	}
 
-	// No push to the stack if we are already using 9
-	for i := len(reg.num); i > 0; i-- {
-		if i == numRegisters {
-			i--
-		}
+	// Drop the oldest entries when the ring is full (or was shrunk).
+	for i := len(reg.num) - 1; i >= size-1; i-- {
+		delete(reg.num, i)
+		delete(reg.numKinds, i)
+	}
 
+	for i := len(reg.num); i > 0; i-- {
 		reg.num[i] = append([]rune{}, reg.num[i-1]...)
+		reg.numKinds[i] = reg.numKinds[i-1]
 	}
 
 	reg.num[0] = append([]rune{}, buf...)
+	reg.numKinds[0] = kind
 }
 
-func (reg *Buffers) writeAlpha(register rune, buf []rune) {
+func (reg *Buffers) writeAlpha(register rune, kind Kind, buf []rune) {
 	appendRegs := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	appended := false
 
@@ -265,8 +381,14 @@ func (reg *Buffers) writeAlpha(register rune, buf []rune) {
 
 			if exists {
 				reg.alpha[register] = append(reg.alpha[register], buf...)
+
+				// Appending lines to any contents makes them lines.
+				if kind == LineWise {
+					reg.kinds[register] = kind
+				}
 			} else {
 				reg.alpha[register] = buf
+				reg.kinds[register] = kind
 			}
 
 			appended = true
@@ -275,6 +397,7 @@ func (reg *Buffers) writeAlpha(register rune, buf []rune) {
 
 	if !appended {
 		reg.alpha[register] = buf
+		reg.kinds[register] = kind
 	}
 }
 
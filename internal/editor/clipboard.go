@@ -0,0 +1,171 @@
+package editor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Clipboard registers: + is the system clipboard, and * is the primary
+// selection (on X11 and Wayland, or the clipboard on other systems).
+const (
+	clipboardRegister = '+'
+	selectionRegister = '*'
+)
+
+// Clipboard backends, set with the `clipboard` inputrc option.
+const (
+	clipboardAuto   = "auto"   // OSC 52 over SSH or inside tmux, native tools otherwise.
+	clipboardOSC52  = "osc52"  // OSC 52 terminal sequences only (write-only).
+	clipboardNative = "native" // Clipboard tools (xclip/xsel/wl-copy/pbcopy/Windows).
+	clipboardOff    = "off"    // The clipboard registers are ordinary ones.
+)
+
+// clipboardTool is a command writing its input to a clipboard, or printing its contents.
+type clipboardTool struct {
+	copy  []string
+	paste []string
+}
+
+// clipboardTools returns the native clipboard tools for the
+// current system, for either the clipboard or primary selection.
+func clipboardTools(selection bool) []clipboardTool {
+	switch runtime.GOOS {
+	case "darwin":
+		return []clipboardTool{{copy: []string{"pbcopy"}, paste: []string{"pbpaste"}}}
+	case "windows":
+		return []clipboardTool{{
+			copy:  []string{"clip.exe"},
+			paste: []string{"powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+		}}
+	}
+
+	xclip, xsel, wayland := "clipboard", "--clipboard", []string{}
+	if selection {
+		xclip, xsel, wayland = "primary", "--primary", []string{"--primary"}
+	}
+
+	tools := []clipboardTool{
+		{copy: []string{"xclip", "-selection", xclip}, paste: []string{"xclip", "-selection", xclip, "-o"}},
+		{copy: []string{"xsel", xsel, "--input"}, paste: []string{"xsel", xsel, "--output"}},
+	}
+
+	// Prefer Wayland tools in Wayland sessions.
+	wlTools := clipboardTool{
+		copy:  append([]string{"wl-copy"}, wayland...),
+		paste: append([]string{"wl-paste", "--no-newline"}, wayland...),
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return append([]clipboardTool{wlTools}, tools...)
+	}
+
+	return append(tools, wlTools)
+}
+
+// isClipboard returns true if the register is one of the clipboard ones.
+func isClipboard(register rune) bool {
+	return register == clipboardRegister || register == selectionRegister
+}
+
+// clipboardBackend returns the clipboard backend to use.
+func (reg *Buffers) clipboardBackend() string {
+	backend := clipboardAuto
+	if reg.config != nil {
+		backend = strings.ToLower(reg.config.GetString("clipboard"))
+	}
+
+	switch backend {
+	case clipboardOSC52, clipboardNative, clipboardOff:
+		return backend
+	}
+
+	// Native tools are not reachable from remote sessions, which tmux might be.
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" || os.Getenv("TMUX") != "" {
+		return clipboardOSC52
+	}
+
+	if _, found := nativeTool(false); found {
+		return clipboardNative
+	}
+
+	return clipboardOSC52
+}
+
+// readClipboard returns the contents of the system clipboard (or primary selection).
+// OSC 52 being write-only, the contents last written to the register are used instead.
+func (reg *Buffers) readClipboard(register rune) []rune {
+	if reg.clipboardBackend() == clipboardNative {
+		if tool, found := nativeTool(register == selectionRegister); found {
+			if out, err := exec.Command(tool.paste[0], tool.paste[1:]...).Output(); err == nil {
+				if runtime.GOOS == "windows" {
+					out = bytes.TrimSuffix(out, []byte("\r\n"))
+				}
+
+				return []rune(string(out))
+			}
+		}
+	}
+
+	return reg.clip[register]
+}
+
+// writeClipboard writes to the system clipboard (or primary selection),
+// and keeps the contents in the register for OSC 52 clipboards.
+func (reg *Buffers) writeClipboard(register rune, buf []rune) {
+	reg.clip[register] = buf
+
+	switch reg.clipboardBackend() {
+	case clipboardOff:
+		return
+	case clipboardNative:
+		if tool, found := nativeTool(register == selectionRegister); found {
+			cmd := exec.Command(tool.copy[0], tool.copy[1:]...)
+			cmd.Stdin = bytes.NewBufferString(string(buf))
+
+			if cmd.Run() == nil {
+				return
+			}
+		}
+	}
+
+	fmt.Fprint(reg.term, osc52(register, string(buf)))
+}
+
+// nativeTool returns the first native clipboard tool found in $PATH.
+func nativeTool(selection bool) (clipboardTool, bool) {
+	for _, tool := range clipboardTools(selection) {
+		if _, err := exec.LookPath(tool.copy[0]); err != nil {
+			continue
+		}
+
+		if _, err := exec.LookPath(tool.paste[0]); err != nil {
+			continue
+		}
+
+		return tool, true
+	}
+
+	return clipboardTool{}, false
+}
+
+// osc52 returns the OSC 52 sequence setting the terminal clipboard (or primary
+// selection), wrapped in a passthrough sequence when running inside tmux.
+func osc52(register rune, text string) string {
+	target := "c"
+	if register == selectionRegister {
+		target = "p"
+	}
+
+	seq := "\x1b]52;" + target + ";" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	return seq
+}
@@ -0,0 +1,135 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// newClipboardBuffers returns registers using the given clipboard backend,
+// and the output of their terminal, outside of SSH and tmux sessions.
+func newClipboardBuffers(t *testing.T, backend string) (*Buffers, *bytes.Buffer) {
+	t.Helper()
+
+	for _, env := range []string{"SSH_TTY", "SSH_CONNECTION", "TMUX", "WAYLAND_DISPLAY"} {
+		t.Setenv(env, "")
+	}
+
+	config := inputrc.NewDefaultConfig()
+	config.Set("clipboard", backend)
+
+	out := new(bytes.Buffer)
+
+	return NewBuffers(&term.Terminal{Output: out}, color.NewStyles(), config), out
+}
+
+func TestBuffers_ClipboardOSC52(t *testing.T) {
+	reg, out := newClipboardBuffers(t, "osc52")
+
+	reg.WriteTo('+', []rune("copied")...)
+	reg.WriteTo('*', []rune("selected")...)
+
+	if want := "\x1b]52;c;Y29waWVk\a\x1b]52;p;c2VsZWN0ZWQ=\a"; out.String() != want {
+		t.Errorf("WriteTo() output = %q, want %q", out.String(), want)
+	}
+
+	// OSC 52 is write-only, so the registers keep what was last written to them.
+	if got := string(reg.Get('+')); got != "copied" {
+		t.Errorf("Get('+') = %q, want %q", got, "copied")
+	}
+
+	if got := string(reg.Get('*')); got != "selected" {
+		t.Errorf("Get('*') = %q, want %q", got, "selected")
+	}
+}
+
+func TestBuffers_ClipboardOff(t *testing.T) {
+	reg, out := newClipboardBuffers(t, "off")
+
+	reg.WriteTo('+', []rune("copied")...)
+
+	if out.Len() > 0 {
+		t.Errorf("WriteTo() output = %q, want none", out.String())
+	}
+
+	if got := string(reg.Get('+')); got != "copied" {
+		t.Errorf("Get('+') = %q, want %q", got, "copied")
+	}
+}
+
+func TestBuffers_ClipboardNative(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "freebsd" {
+		t.Skip("fake clipboard tool is an X11 one")
+	}
+
+	// A fake xclip storing the clipboard in a file.
+	dir := t.TempDir()
+	clipboard := filepath.Join(dir, "clipboard")
+	script := "#!/bin/sh\nif [ \"$3\" = -o ]; then cat " + clipboard + "; else cat > " + clipboard + "; fi\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, out := newClipboardBuffers(t, "auto")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	reg.WriteTo('+', []rune("copied")...)
+
+	if data, _ := os.ReadFile(clipboard); string(data) != "copied" || out.Len() > 0 {
+		t.Errorf("WriteTo() clipboard = %q, output = %q, want %q and no output", data, out.String(), "copied")
+	}
+
+	// The clipboard is read again, since it can be changed by other programs.
+	if err := os.WriteFile(clipboard, []byte("changed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(reg.Get('+')); got != "changed" {
+		t.Errorf("Get('+') = %q, want %q", got, "changed")
+	}
+}
+
+func TestBuffers_clipboardBackend(t *testing.T) {
+	reg, _ := newClipboardBuffers(t, "auto")
+	t.Setenv("PATH", t.TempDir())
+
+	// Without native tools, OSC 52 is used.
+	if got := reg.clipboardBackend(); got != clipboardOSC52 {
+		t.Errorf("clipboardBackend() without tools = %q, want %q", got, clipboardOSC52)
+	}
+
+	// Native tools are not used in remote sessions.
+	t.Setenv("SSH_TTY", "/dev/pts/0")
+
+	if got := reg.clipboardBackend(); got != clipboardOSC52 {
+		t.Errorf("clipboardBackend() over SSH = %q, want %q", got, clipboardOSC52)
+	}
+
+	reg.config.Set("clipboard", "Native")
+
+	if got := reg.clipboardBackend(); got != clipboardNative {
+		t.Errorf("clipboardBackend() when set = %q, want %q", got, clipboardNative)
+	}
+}
+
+func Test_osc52(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	if got, want := osc52('*', "a"), "\x1b]52;p;YQ==\a"; got != want {
+		t.Errorf("osc52() = %q, want %q", got, want)
+	}
+
+	// Sequences are passed through tmux to the terminal.
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+
+	if got, want := osc52('+', "a"), "\x1bPtmux;\x1b\x1b]52;c;YQ==\a\x1b\\"; got != want {
+		t.Errorf("osc52() in tmux = %q, want %q", got, want)
+	}
+}
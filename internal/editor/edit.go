@@ -0,0 +1,26 @@
+package editor
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrCancelled indicates that the editor exited with a non-zero status.
+var ErrCancelled = errors.New("editor exited with an error")
+
+// Edit specifies how a buffer is edited in the system editor.
+type Edit struct {
+	Filename  string // Name of the file in the temp directory (random if empty).
+	Extension string // Extension of the file (eg. "sh"), for syntax highlighting.
+	Line      int    // Line of the cursor in the buffer, starting at 1.
+	Column    int    // Column of the cursor in its line, in runes, starting at 1.
+	Command   string // Command template running the editor command in place of {}.
+	Emacs     bool   // Use emacs as the default editor, instead of vi.
+
+	// Streams of the editor, the process ones if nil. Files are passed
+	// to the editor as is, while other inputs are copied to it, and are
+	// closed once it exits if they are closers (to stop reading them).
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,7 +31,7 @@ var (
 	ErrRead = errors.New("failed to read buffer file")
 )
 
-func writeToFile(buf []byte, filename string) (string, error) {
+func writeToFile(buf []byte, filename, extension string) (string, error) {
 	var path string
 
 	// Get the temp directory, or fail.
@@ -58,6 +59,10 @@ func writeToFile(buf []byte, filename string) (string, error) {
 		path = filepath.Join(tmp, filename)
 	}
 
+	if extension != "" && filepath.Ext(path) == "" {
+		path += "." + strings.TrimPrefix(extension, ".")
+	}
+
 	file, err := os.Create(path)
 	if err != nil {
 		return "", fmt.Errorf("%w: %s", ErrCreate, err.Error())
@@ -107,15 +112,13 @@ func readTempFile(name string) ([]byte, error) {
 	return buf, nil
 }
 
-func getSystemEditor(emacsDefault bool) (editor string) {
-	editor = os.Getenv("VISUAL")
-	if editor == "" {
-		return
+func getSystemEditor(emacsDefault bool) string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
 	}
 
-	editor = os.Getenv("EDITOR")
-	if editor == "" {
-		return
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
 	}
 
 	if emacsDefault {
@@ -124,3 +127,38 @@ func getSystemEditor(emacsDefault bool) (editor string) {
 
 	return "vi"
 }
+
+// editorCommand returns the shell command opening the file in the editor, at the
+// cursor position if the editor is known to support it, and run with the command
+// template if any.
+func editorCommand(editor, name string, edit Edit) string {
+	command := editor
+
+	if fields := strings.Fields(editor); len(fields) > 0 && edit.Line > 0 {
+		line, column := strconv.Itoa(edit.Line), strconv.Itoa(edit.Column)
+
+		switch filepath.Base(fields[0]) {
+		case "vim", "nvim", "gvim", "mvim":
+			command += " " + shellQuote("+call cursor("+line+", "+column+")")
+		case "emacs", "emacsclient", "micro", "kak":
+			command += " +" + line + ":" + column
+		case "nano":
+			command += " +" + line + "," + column
+		default:
+			command += " +" + line
+		}
+	}
+
+	command += " " + shellQuote(name)
+
+	if edit.Command != "" {
+		command = strings.ReplaceAll(edit.Command, "{}", command)
+	}
+
+	return command
+}
+
+// shellQuote quotes a word in single quotes for a POSIX shell.
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
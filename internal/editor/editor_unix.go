@@ -5,6 +5,7 @@ package editor
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 )
@@ -12,41 +13,89 @@ import (
 // ErrStart indicates that the command to start the editor failed.
 var ErrStart = errors.New("failed to start editor")
 
-// EditBuffer starts the system editor and opens the given buffer in it.
-// If the filename is specified, the file will be created in the system
-// temp directory under this name.
-// If the filetype is not empty and if the system editor supports it, the
-// file will be opened with the specified filetype passed to the editor.
-func (reg *Buffers) EditBuffer(buf []rune, filename, filetype string, emacs bool) ([]rune, error) {
-	name, err := writeToFile([]byte(string(buf)), filename)
+// EditBuffer starts the system editor ($VISUAL, $EDITOR, or else emacs or vi)
+// and opens the given buffer in it, from a file created in the system temp
+// directory. The editor is opened at the cursor position if it supports it,
+// and its command is run by the shell (sh), with the edit command template if
+// any. If the editor exits with a non-zero status, ErrCancelled is returned.
+func (reg *Buffers) EditBuffer(buf []rune, edit Edit) ([]rune, error) {
+	name, err := writeToFile([]byte(string(buf)), edit.Filename, edit.Extension)
 	if err != nil {
 		return buf, err
 	}
 
-	editor := getSystemEditor(emacs)
+	cmd := exec.Command("sh", "-c", editorCommand(getSystemEditor(edit.Emacs), name, edit))
 
-	args := []string{}
-	if filetype != "" {
-		args = append(args, fmt.Sprintf("-c 'set filetype=%s", filetype))
-	}
-
-	args = append(args, name)
+	cmd.Stdout = stream(edit.Stdout, os.Stdout)
+	cmd.Stderr = stream(edit.Stderr, os.Stderr)
 
-	cmd := exec.Command(editor, args...)
-
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	copied, err := editorInput(cmd, edit.Stdin)
+	if err != nil {
+		os.Remove(name)
+		return buf, fmt.Errorf("%w: %s", ErrStart, err.Error())
+	}
 
 	if err = cmd.Start(); err != nil {
+		copied()
+		os.Remove(name)
+
 		return buf, fmt.Errorf("%w: %s", ErrStart, err.Error())
 	}
 
-	if err = cmd.Wait(); err != nil {
-		return buf, fmt.Errorf("%w: %s", ErrStart, err.Error())
+	err = cmd.Wait()
+	copied()
+
+	if err != nil {
+		os.Remove(name)
+		return buf, ErrCancelled
 	}
 
 	b, err := readTempFile(name)
 
 	return []rune(string(b)), err
 }
+
+// editorInput sets the input of the editor command: files are passed as is, and
+// other readers are copied to the editor. The returned function must be called
+// once the editor exits: it stops reading the input, if it can be closed.
+func editorInput(cmd *exec.Cmd, input io.Reader) (copied func(), err error) {
+	if input == nil {
+		input = os.Stdin
+	}
+
+	if file, isFile := input.(*os.File); isFile {
+		cmd.Stdin = file
+		return func() {}, nil
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		io.Copy(stdin, input)
+		stdin.Close()
+	}()
+
+	return func() {
+		if closer, ok := input.(io.Closer); ok {
+			closer.Close()
+		}
+
+		<-done
+	}, nil
+}
+
+// stream returns the writer, or the default one if nil.
+func stream(writer io.Writer, def *os.File) io.Writer {
+	if writer == nil {
+		return def
+	}
+
+	return writer
+}
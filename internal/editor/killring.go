@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/completion"
+)
+
+// ringSize returns the number of entries kept in the kill ring,
+// as set with the `kill-ring-size` inputrc option.
+func (reg *Buffers) ringSize() int {
+	if reg.config == nil {
+		return numRegisters
+	}
+
+	if size := reg.config.GetInt("kill-ring-size"); size > 0 {
+		return size
+	}
+
+	return numRegisters
+}
+
+// Ring returns the entries of the kill ring, from the most recent one.
+func (reg *Buffers) Ring() [][]rune {
+	ring := make([][]rune, 0, len(reg.num))
+
+	for i := 0; i < len(reg.num); i++ {
+		if buf, found := reg.num[i]; found {
+			ring = append(ring, buf)
+		}
+	}
+
+	return ring
+}
+
+// CompleteKillRing returns the entries of the kill ring as a list of completions,
+// from the most recent one, each prefixed with its index in the ring.
+func (reg *Buffers) CompleteKillRing() completion.Values {
+	ring := reg.Ring()
+	vals := make([]completion.Candidate, 0, len(ring))
+	tag := color.Dim + "kill ring" + color.Reset
+
+	for index, buf := range ring {
+		display := strings.ReplaceAll(string(buf), "\n", ` `)
+
+		vals = append(vals, completion.Candidate{
+			Tag:     tag,
+			Value:   string(buf),
+			Display: fmt.Sprintf("%s%d%s %s", color.Dim, index, color.DimReset, display),
+		})
+	}
+
+	// Keep the ring order and list entries one per line.
+	comps := completion.AddRaw(vals)
+	comps.Sort["*"] = completion.SortNone
+
+	if comps.ListLong == nil {
+		comps.ListLong = make(map[string]bool)
+	}
+
+	comps.ListLong["*"] = true
+
+	hint := reg.styles.Title + "(kill ring)"
+
+	if len(vals) == 0 {
+		hint += " - empty -"
+	}
+
+	comps.Messages.Add(hint)
+
+	return comps
+}
@@ -0,0 +1,64 @@
+package editor
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// ring returns the kill ring entries as strings.
+func ring(reg *Buffers) []string {
+	var entries []string
+
+	for _, buf := range reg.Ring() {
+		entries = append(entries, string(buf))
+	}
+
+	return entries
+}
+
+func TestBuffers_Pop(t *testing.T) {
+	reg := newTestBuffers()
+
+	for _, killed := range []string{"one", "two", "three"} {
+		reg.Write([]rune(killed)...)
+	}
+
+	if want := []string{"three", "two", "one"}; !reflect.DeepEqual(ring(reg), want) {
+		t.Fatalf("Ring() = %q, want %q", ring(reg), want)
+	}
+
+	// The whole ring is browsed, without losing entries.
+	for _, want := range []string{"two", "one", "three"} {
+		if got := string(reg.Pop()); got != want {
+			t.Errorf("Pop() = %q, want %q", got, want)
+		}
+	}
+
+	if want := []string{"three", "two", "one"}; !reflect.DeepEqual(ring(reg), want) {
+		t.Errorf("Ring() after a full rotation = %q, want %q", ring(reg), want)
+	}
+}
+
+func TestBuffers_RingSize(t *testing.T) {
+	config := inputrc.NewDefaultConfig()
+	config.Set("kill-ring-size", 2)
+
+	reg := NewBuffers(&term.Terminal{Output: io.Discard}, color.NewStyles(), config)
+
+	for _, killed := range []string{"one", "two", "three"} {
+		reg.Write([]rune(killed)...)
+	}
+
+	if want := []string{"three", "two"}; !reflect.DeepEqual(ring(reg), want) {
+		t.Errorf("Ring() = %q, want %q", ring(reg), want)
+	}
+
+	if got := string(reg.Pop()); got != "two" {
+		t.Errorf("Pop() = %q, want %q", got, "two")
+	}
+}
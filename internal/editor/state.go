@@ -0,0 +1,72 @@
+package editor
+
+import "strconv"
+
+// State is a serializable snapshot of the contents of all read/write registers.
+type State struct {
+	Num   map[int]string    `json:"num,omitempty"`
+	Alpha map[string]string `json:"alpha,omitempty"`
+	Kinds map[string]Kind   `json:"kinds,omitempty"` // Yank types of non character-wise registers.
+}
+
+// State returns a snapshot of the numbered and lettered registers contents.
+func (reg *Buffers) State() State {
+	state := State{
+		Num:   make(map[int]string, len(reg.num)),
+		Alpha: make(map[string]string, len(reg.alpha)),
+		Kinds: make(map[string]Kind),
+	}
+
+	for num, buf := range reg.num {
+		state.Num[num] = string(buf)
+
+		if kind := reg.numKinds[num]; kind != CharWise {
+			state.Kinds[strconv.Itoa(num)] = kind
+		}
+	}
+
+	for char, buf := range reg.alpha {
+		state.Alpha[string(char)] = string(buf)
+
+		if kind := reg.kinds[char]; kind != CharWise {
+			state.Kinds[string(char)] = kind
+		}
+	}
+
+	return state
+}
+
+// RestoreState replaces the contents of the numbered and lettered registers
+// with those of a snapshot. Invalid register names in the snapshot are ignored.
+func (reg *Buffers) RestoreState(state State) {
+	reg.num = make(map[int][]rune, reg.ringSize())
+	reg.alpha = make(map[rune][]rune, alphaRegisters)
+	reg.numKinds = make(map[int]Kind, reg.ringSize())
+
+	for char := range reg.kinds {
+		if !isClipboard(char) {
+			delete(reg.kinds, char)
+		}
+	}
+
+	for num, buf := range state.Num {
+		if num < 0 || num >= reg.ringSize() {
+			continue
+		}
+
+		reg.num[num] = []rune(buf)
+		reg.numKinds[num] = state.Kinds[strconv.Itoa(num)]
+	}
+
+	for name, buf := range state.Alpha {
+		char := []rune(name)
+		if len(char) != 1 {
+			continue
+		}
+
+		reg.alpha[char[0]] = []rune(buf)
+		reg.kinds[char[0]] = state.Kinds[name]
+	}
+
+	reg.Reset()
+}
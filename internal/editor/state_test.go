@@ -0,0 +1,44 @@
+package editor
+
+import (
+	"io"
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// newTestBuffers returns registers using the default configuration.
+func newTestBuffers() *Buffers {
+	return NewBuffers(&term.Terminal{Output: io.Discard}, color.NewStyles(), inputrc.NewDefaultConfig())
+}
+
+func TestBuffers_State(t *testing.T) {
+	saved := newTestBuffers()
+	saved.Write([]rune("killed")...)
+	saved.WriteTo('a', []rune("alpha")...)
+
+	restored := newTestBuffers()
+	restored.WriteTo('b', []rune("other")...)
+	restored.RestoreState(saved.State())
+
+	if got := string(restored.GetKill()); got != "killed" {
+		t.Errorf("restored kill buffer = %q, want %q", got, "killed")
+	}
+
+	if got := string(restored.Get('a')); got != "alpha" {
+		t.Errorf("restored register a = %q, want %q", got, "alpha")
+	}
+
+	if got := restored.Get('b'); len(got) != 0 {
+		t.Errorf("restored register b = %q, want none", string(got))
+	}
+
+	// Invalid register names are ignored.
+	restored.RestoreState(State{Num: map[int]string{-1: "x", 0: "ok"}, Alpha: map[string]string{"ab": "x"}})
+
+	if got := string(restored.GetKill()); got != "ok" {
+		t.Errorf("restored kill buffer = %q, want %q", got, "ok")
+	}
+}
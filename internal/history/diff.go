@@ -0,0 +1,84 @@
+package history
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// Diff returns a compact, word-level diff between the history entry
+// currently recalled and the input line, if the user has modified it.
+// If no history line is recalled or the line is unchanged, it returns
+// an empty string.
+func (h *Sources) Diff() string {
+	history := h.Current()
+	if h.hpos < 1 || history == nil || h.hpos > history.Len() {
+		return ""
+	}
+
+	original, err := history.GetLine(history.Len() - h.hpos)
+	if err != nil || original == string(*h.line) {
+		return ""
+	}
+
+	changes := h.diffWords(strings.Fields(original), strings.Fields(string(*h.line)))
+	if len(changes) == 0 {
+		return ""
+	}
+
+	return h.styles.Hint + "(edited)" + color.Reset + " " + strings.Join(changes, " ")
+}
+
+// diffWords returns the words removed from (in red, prefixed with -) and added
+// to (in green, prefixed with +) the original line, in the order they appear.
+// Unchanged words are omitted, except for an ellipsis between distant changes.
+func (h *Sources) diffWords(old, new []string) []string {
+	// Longest common subsequence table, from the end of both lists.
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []string
+
+	skipped := false
+
+	mark := func(change string) {
+		if skipped && len(changes) > 0 {
+			changes = append(changes, h.styles.Hint+"…"+color.Reset)
+		}
+
+		skipped = false
+
+		changes = append(changes, change)
+	}
+
+	i, j := 0, 0
+
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			skipped = true
+			i++
+			j++
+		case i < len(old) && (j == len(new) || lcs[i+1][j] >= lcs[i][j+1]):
+			mark(h.styles.Error + "-" + old[i] + color.Reset)
+			i++
+		default:
+			mark(h.styles.Success + "+" + new[j] + color.Reset)
+			j++
+		}
+	}
+
+	return changes
+}
@@ -27,6 +27,7 @@ type Item struct {
 	Index    int
 	DateTime time.Time
 	Block    string
+	Result   *Result
 }
 
 // NewSourceFromFile returns a new history source writing to and reading from a file.
@@ -51,7 +52,16 @@ func openHist(filename string) (list []Item, err error) {
 		var item Item
 
 		err := json.Unmarshal(scanner.Bytes(), &item)
-		if err != nil || len(item.Block) == 0 {
+		if err != nil {
+			continue
+		}
+
+		// Results are written after their lines, with their index.
+		if len(item.Block) == 0 {
+			if item.Result != nil && item.Index >= 0 && item.Index < len(list) {
+				list[item.Index].Result = item.Result
+			}
+
 			continue
 		}
 
@@ -89,20 +99,7 @@ func (h *fileHistory) Write(s string) (int, error) {
 		DateTime: item.DateTime,
 	}
 
-	data, err := json.Marshal(line)
-	if err != nil {
-		return h.Len(), err
-	}
-
-	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
-	if err != nil {
-		return 0, fmt.Errorf("%w: %s", errOpenHistoryFile, err.Error())
-	}
-
-	_, err = f.Write(append(data, '\n'))
-	f.Close()
-
-	return h.Len(), err
+	return h.Len(), h.appendRecord(line)
 }
 
 // GetLine returns a specific line from the history file.
@@ -118,6 +115,74 @@ func (h *fileHistory) GetLine(pos int) (string, error) {
 	return "", errOutOfRangeIndex
 }
 
+// GetTime returns the time at which a line was written to the history file.
+func (h *fileHistory) GetTime(pos int) (time.Time, error) {
+	if pos < 0 {
+		return time.Time{}, errNegativeIndex
+	}
+
+	if pos < len(h.lines) {
+		return h.lines[pos].DateTime, nil
+	}
+
+	return time.Time{}, errOutOfRangeIndex
+}
+
+// SetResult records the result of the command run with a line, by appending
+// it to the history file along with the index of the line.
+func (h *fileHistory) SetResult(pos int, result Result) error {
+	if pos < 0 {
+		return errNegativeIndex
+	}
+
+	if pos >= len(h.lines) {
+		return errOutOfRangeIndex
+	}
+
+	h.lines[pos].Result = &result
+
+	record := struct {
+		Index  int    `json:"index"`
+		Result Result `json:"result"`
+	}{
+		Index:  pos,
+		Result: result,
+	}
+
+	return h.appendRecord(record)
+}
+
+// GetResult returns the result of the command run with a line, if any.
+func (h *fileHistory) GetResult(pos int) (*Result, error) {
+	if pos < 0 {
+		return nil, errNegativeIndex
+	}
+
+	if pos < len(h.lines) {
+		return h.lines[pos].Result, nil
+	}
+
+	return nil, errOutOfRangeIndex
+}
+
+// appendRecord appends a JSON record on its own line to the history file.
+func (h *fileHistory) appendRecord(record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errOpenHistoryFile, err.Error())
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	f.Close()
+
+	return err
+}
+
 // Len returns the number of items in the history file.
 func (h *fileHistory) Len() int {
 	return len(h.lines)
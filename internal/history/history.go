@@ -1,5 +1,7 @@
 package history
 
+import "time"
+
 var defaultSourceName = "default history"
 
 // Source is an interface to allow you to write your own history logging tools.
@@ -24,10 +26,37 @@ type Source interface {
 	Dump() interface{}
 }
 
+// TimedSource is implemented by history sources recording
+// the time at which their lines have been written.
+type TimedSource interface {
+	// GetTime returns the time at which a line has been written.
+	GetTime(int) (time.Time, error)
+}
+
+// Result is the result of the command run with a history line,
+// as reported by the shell caller after running it.
+type Result struct {
+	Status   int           `json:"status"`   // Exit status of the command.
+	Duration time.Duration `json:"duration"` // Time taken to run it.
+}
+
+// ResultSource is implemented by history sources recording the
+// results of the commands run with their lines.
+type ResultSource interface {
+	// SetResult records the result of the command run with a line.
+	SetResult(int, Result) error
+
+	// GetResult returns the result of the command run with a line,
+	// or nil if none has been recorded for it.
+	GetResult(int) (*Result, error)
+}
+
 // memory is an in memory history.
 // One such history is bound to the readline shell by default.
 type memory struct {
-	items []string
+	items   []string
+	times   []time.Time
+	results []*Result
 }
 
 // NewInMemoryHistory creates a new in-memory command history source.
@@ -38,9 +67,41 @@ func NewInMemoryHistory() Source {
 // Write to history.
 func (h *memory) Write(s string) (int, error) {
 	h.items = append(h.items, s)
+	h.times = append(h.times, time.Now())
+	h.results = append(h.results, nil)
+
 	return len(h.items), nil
 }
 
+// GetTime returns the time at which a line was written.
+func (h *memory) GetTime(i int) (time.Time, error) {
+	if i < 0 || i >= len(h.times) {
+		return time.Time{}, errOutOfRangeIndex
+	}
+
+	return h.times[i], nil
+}
+
+// SetResult records the result of the command run with a line.
+func (h *memory) SetResult(i int, result Result) error {
+	if i < 0 || i >= len(h.results) {
+		return errOutOfRangeIndex
+	}
+
+	h.results[i] = &result
+
+	return nil
+}
+
+// GetResult returns the result of the command run with a line, if any.
+func (h *memory) GetResult(i int) (*Result, error) {
+	if i < 0 || i >= len(h.results) {
+		return nil, errOutOfRangeIndex
+	}
+
+	return h.results[i], nil
+}
+
 // GetLine returns a line from history.
 func (h *memory) GetLine(i int) (string, error) {
 	if len(h.items) == 0 {
@@ -3,8 +3,10 @@ package history
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
@@ -19,6 +21,8 @@ type Sources struct {
 	line   *core.Line
 	cursor *core.Cursor
 	hint   *ui.Hint
+	bell   *ui.Bell
+	styles *color.Styles
 	config *inputrc.Config
 
 	// History sources
@@ -27,7 +31,10 @@ type Sources struct {
 	maxEntries int               // Inputrc configured maximum number of entries.
 	sourcePos  int               // The index of the currently used history
 	hpos       int               // Index used for navigating the history lines with arrows/j/k
+	next       int               // Index of the history line to start the next loop with, if any.
 	cpos       int               // A temporary cursor position used when searching/moving around.
+	scorer     completion.Scorer // An optional scorer ranking autosuggestions.
+	secret     bool              // Accepted lines and their changes are not saved.
 
 	// Line changes history
 	skip    bool                            // Skip saving the current line state.
@@ -41,10 +48,11 @@ type Sources struct {
 	acceptHold bool      // Should we reuse the same accepted line on the next loop.
 	acceptLine core.Line // The line to return to the caller.
 	acceptErr  error     // An error to return to the caller.
+	lastLine   string    // The last line accepted, to which results are reported.
 }
 
 // NewSources is a required constructor for the history sources manager type.
-func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, opts *inputrc.Config) *Sources {
+func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, bell *ui.Bell, styles *color.Styles, opts *inputrc.Config) *Sources {
 	sources := &Sources{
 		// History sources
 		list: make(map[string]Source),
@@ -55,7 +63,10 @@ func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, opts *inputrc.
 		cursor: cur,
 		cpos:   -1,
 		hpos:   -1,
+		next:   -1,
 		hint:   hint,
+		bell:   bell,
+		styles: styles,
 		config: opts,
 	}
 
@@ -99,6 +110,8 @@ func Init(hist *Sources) {
 		undoHist := hist.getHistoryLineChanges()
 		undoHist[hist.hpos] = &lineHistory{}
 
+		hist.fetchNext()
+
 		return
 	}
 
@@ -170,11 +183,13 @@ func (h *Sources) Walk(pos int) {
 	history := h.Current()
 
 	if history == nil || history.Len() == 0 {
+		h.bell.Ring()
 		return
 	}
 
 	// Can't go back further than the first line.
 	if h.hpos == history.Len() && pos == 1 {
+		h.bell.Ring()
 		return
 	}
 
@@ -191,6 +206,8 @@ func (h *Sources) Walk(pos int) {
 	switch {
 	case h.hpos < -1:
 		h.hpos = -1
+		h.bell.Ring()
+
 		return
 	case h.hpos == 0:
 		h.restoreLineBuffer()
@@ -207,7 +224,7 @@ func (h *Sources) Walk(pos int) {
 	if hist := h.getLineHistory(); hist != nil && len(hist.items) > 0 {
 		line = hist.items[len(hist.items)-1].line
 	} else if line, err = history.GetLine(history.Len() - h.hpos); err != nil {
-		h.hint.Set(color.FgRed + "history error: " + err.Error())
+		h.hint.Set(h.styles.Error + "history error: " + err.Error())
 		return
 	}
 
@@ -230,13 +247,49 @@ func (h *Sources) Fetch(pos int) {
 
 	line, err := history.GetLine(pos)
 	if err != nil {
-		h.hint.Set(color.FgRed + "history error: " + err.Error())
+		h.hint.Set(h.styles.Error + "history error: " + err.Error())
 		return
 	}
 
 	h.setLineCursorMatch(line)
 }
 
+// Following returns the index of the history line following the one being edited
+// in the active source or, if the line does not come from the history, following
+// the most recent history line identical to it. Returns -1 if there is none.
+func (h *Sources) Following() int {
+	history := h.Current()
+	if history == nil {
+		return -1
+	}
+
+	pos := -1
+
+	if h.hpos > 0 {
+		pos = history.Len() - h.hpos
+	} else {
+		for i := history.Len() - 1; i >= 0; i-- {
+			if line, err := history.GetLine(i); err == nil && line == string(*h.line) {
+				pos = i
+				break
+			}
+		}
+	}
+
+	if pos == -1 || pos+1 >= history.Len() {
+		return -1
+	}
+
+	return pos + 1
+}
+
+// FetchNext makes the next readline loop start with the history line at index pos
+// in the active source (eg. as returned by Following()) instead of an empty one,
+// and history movements are then relative to it. Nothing is done if pos is -1.
+func (h *Sources) FetchNext(pos int) {
+	h.next = pos
+}
+
 // GetLast returns the last saved history line in the active history source.
 func (h *Sources) GetLast() string {
 	history := h.Current()
@@ -288,6 +341,12 @@ func (h *Sources) Current() Source {
 	return h.list[h.names[h.sourcePos]]
 }
 
+// SetSecret stops (or resumes) writing accepted lines to the history sources,
+// and saving the changes of the input line, which thus cannot be undone.
+func (h *Sources) SetSecret(secret bool) {
+	h.secret = secret
+}
+
 // Write writes the accepted input line to all available sources.
 // If infer is true, the next history initialization will automatically insert the next
 // history line event after the first match of the line, which one is then NOT written.
@@ -297,6 +356,10 @@ func (h *Sources) Write(infer bool) {
 		return
 	}
 
+	if h.secret {
+		return
+	}
+
 	line := string(*h.line)
 
 	if len(strings.TrimSpace(line)) == 0 {
@@ -325,7 +388,7 @@ func (h *Sources) Write(infer bool) {
 		// Save the line and notify through hints if an error raised.
 		_, err = history.Write(line)
 		if err != nil {
-			h.hint.Set(color.FgRed + err.Error())
+			h.hint.Set(h.styles.Error + err.Error())
 		}
 	}
 }
@@ -340,6 +403,11 @@ func (h *Sources) Accept(hold, infer bool, err error) {
 	h.acceptHold = hold
 	h.acceptLine = *h.line
 	h.acceptErr = err
+	h.lastLine = ""
+
+	if err == nil && !infer && !h.secret {
+		h.lastLine = string(*h.line)
+	}
 
 	// Write the line to the history sources only when the line is not
 	// returned along with an error (generally, a CtrlC/CtrlD keypress).
@@ -348,6 +416,31 @@ func (h *Sources) Accept(hold, infer bool, err error) {
 	}
 }
 
+// SetResult records the result of the command run with the last accepted line, in
+// the history sources recording results (see ResultSource) whose last line it is.
+func (h *Sources) SetResult(result Result) {
+	line := strings.TrimSpace(h.lastLine)
+	if line == "" {
+		return
+	}
+
+	for _, history := range h.list {
+		results, ok := history.(ResultSource)
+		if !ok || history.Len() == 0 {
+			continue
+		}
+
+		last, err := history.GetLine(history.Len() - 1)
+		if err != nil || strings.TrimSpace(last) != line {
+			continue
+		}
+
+		if err := results.SetResult(history.Len()-1, result); err != nil {
+			h.hint.Set(h.styles.Error + err.Error())
+		}
+	}
+}
+
 // LineAccepted returns true if the user has accepted the line, signaling
 // that the shell must return from its loop. The error can be nil, but may
 // indicate a CtrlC/CtrlD style error.
@@ -389,6 +482,8 @@ func (h *Sources) InsertMatch(line *core.Line, cur *core.Cursor, usePos, fwd, re
 	// history if we are at the end of it.
 	if fwd && h.hpos <= -1 {
 		h.hpos = -1
+		h.bell.Ring()
+
 		return
 	}
 
@@ -397,6 +492,8 @@ func (h *Sources) InsertMatch(line *core.Line, cur *core.Cursor, usePos, fwd, re
 	// If no match was found, return anyway, but if we were going forward
 	// (down to the current input line), reinstore the main line buffer.
 	if !found {
+		h.bell.Ring()
+
 		if fwd {
 			h.hpos = -1
 			h.Undo()
@@ -460,89 +557,206 @@ func (h *Sources) Suggest(line *core.Line) core.Line {
 		return *line
 	}
 
-	suggested, _, found := h.match(line, nil, false, false, false)
-	if !found {
-		return *line
+	// Without a scorer, the most recent matching line is used,
+	// preferably one whose command has not been reported to fail.
+	if h.scorer == nil {
+		return core.Line([]rune(h.suggestRecent(string(*line))))
 	}
 
-	return core.Line([]rune(suggested))
+	return core.Line([]rune(h.suggestScored(string(*line))))
+}
+
+// SetScorer sets the scorer used to choose between all history lines
+// that can be suggested for the current line. If nil, the most recent
+// one is suggested.
+func (h *Sources) SetScorer(scorer completion.Scorer) {
+	h.scorer = scorer
 }
 
 // Complete returns completions with the current history source values.
 // If forward is true, the completions are proposed from the most ancient
 // line in the history source to the most recent. If filter is true,
 // only lines that match the current input line as a prefix are given.
+// If the history-merge-sources option is set, the lines of all sources are
+// proposed at once (ordered by time if all sources record it), and if the
+// history-source-tags one is, the source of each line is displayed after it.
 func Complete(h *Sources, forward, filter bool, maxLines int, regex *regexp.Regexp) completion.Values {
 	if len(h.list) == 0 {
 		return completion.Values{}
 	}
 
-	history := h.Current()
-	if history == nil {
+	if h.Current() == nil {
 		return completion.Values{}
 	}
 
-	h.hint.Set(color.Bold + color.FgCyanBright + h.names[h.sourcePos] + color.Reset)
+	names := h.names[h.sourcePos : h.sourcePos+1]
+	if h.config.GetBool("history-merge-sources") {
+		names = h.names
+	}
 
-	compLines := make([]completion.Candidate, 0)
+	h.hint.Set(h.styles.Isearch + strings.Join(names, ", ") + color.Reset)
 
-	// Set up iteration clauses
-	var (
-		histPos int
-		done    func(i int) bool
-		move    func(inc int) int
-	)
+	var entries []entry
 
-	if forward {
-		histPos = -1
-		done = func(i int) bool { return i < history.Len()-1 && maxLines >= 0 }
-		move = func(pos int) int { return pos + 1 }
-	} else {
-		histPos = history.Len()
-		done = func(i int) bool { return i > 0 && maxLines >= 0 }
-		move = func(pos int) int { return pos - 1 }
+	for _, name := range names {
+		entries = append(entries, h.completeSource(name, forward, filter, maxLines, regex)...)
 	}
 
-	// And generate the completions.
-	for done(histPos) {
-		histPos = move(histPos)
+	if len(names) > 1 {
+		sortEntries(entries, forward)
+	}
 
-		line, err := history.GetLine(histPos)
-		if err != nil {
+	compLines := make([]completion.Candidate, 0, len(entries))
+	tags := h.config.GetBool("history-source-tags")
+
+	var indexWidth, nameWidth int
+
+	for _, name := range names {
+		if history := h.list[name]; history != nil {
+			indexWidth = max(indexWidth, len(strconv.Itoa(history.Len())))
+		}
+
+		nameWidth = max(nameWidth, len(name))
+	}
+
+	for _, entry := range entries {
+		display := strings.ReplaceAll(entry.line, "\n", ` `)
+
+		// Proper pad for indexes, and source names.
+		indexStr := strconv.Itoa(entry.pos)
+		column := indexStr + strings.Repeat(" ", indexWidth-len(indexStr))
+
+		if tags {
+			column += " " + entry.source + strings.Repeat(" ", nameWidth-len(entry.source))
+		}
+
+		display = fmt.Sprintf("%s%s %s%s", color.Dim, column, color.DimReset, display)
+
+		value := completion.Candidate{
+			Display: display,
+			Value:   entry.line,
+		}
+
+		compLines = append(compLines, value)
+	}
+
+	comps := completion.AddRaw(compLines)
+	comps.Sort["*"] = completion.SortNone
+	comps.ListLong["*"] = true
+	comps.PREFIX = string(*h.line)
+
+	return comps
+}
+
+// CompleteMenu returns the last lines of the current history source, from the most
+// recent one and without duplicates, at most maxLines of them (or all if it is
+// not positive). Each line is displayed after its number, and after the time at
+// which it was written if the source records it.
+func CompleteMenu(h *Sources, maxLines int) completion.Values {
+	history := h.Current()
+	if history == nil {
+		return completion.Values{}
+	}
+
+	h.hint.Set(h.styles.Isearch + h.names[h.sourcePos] + color.Reset)
+
+	timed, _ := history.(TimedSource)
+	width := len(strconv.Itoa(history.Len()))
+	seen := make(map[string]bool)
+	lines := make([]completion.Candidate, 0)
+
+	for pos := history.Len() - 1; pos >= 0 && (maxLines <= 0 || len(lines) < maxLines); pos-- {
+		line, err := history.GetLine(pos)
+		if err != nil || strings.TrimSpace(line) == "" || seen[line] {
 			continue
 		}
 
-		if strings.TrimSpace(line) == "" {
+		seen[line] = true
+
+		index := strconv.Itoa(pos)
+		prefix := index + strings.Repeat(" ", width-len(index)) + " "
+
+		if timed != nil {
+			if written, err := timed.GetTime(pos); err == nil && !written.IsZero() {
+				prefix += written.Format(time.Stamp) + " "
+			}
+		}
+
+		lines = append(lines, completion.Candidate{
+			Display: color.Dim + prefix + color.DimReset + strings.ReplaceAll(line, "\n", " "),
+			Value:   line,
+		})
+	}
+
+	comps := completion.AddRaw(lines)
+	comps.Sort["*"] = completion.SortNone
+	comps.ListOnly = true
+	comps.NoFilter = true
+
+	return comps
+}
+
+// CompleteDesignators returns the designators of the history lines matching an event
+// designator word (between bpos and epos in the line), as bash does: !n matches the
+// lines whose number starts with n, !?string the lines containing string, and !string
+// the lines starting with it. Lines are listed once, from the most recent one.
+func CompleteDesignators(h *Sources, word string, bpos, epos int) completion.Values {
+	history := h.Current()
+	if history == nil {
+		return completion.Values{}
+	}
+
+	query := strings.TrimPrefix(word, "!")
+	_, err := strconv.Atoi(query)
+	number := err == nil
+
+	seen := make(map[string]bool)
+	width := len(strconv.Itoa(history.Len()))
+	events := make([]completion.Candidate, 0)
+
+	for pos := history.Len() - 1; pos >= 0; pos-- {
+		line, err := history.GetLine(pos)
+		if err != nil || strings.TrimSpace(line) == "" || seen[line] {
 			continue
 		}
 
-		if filter && !strings.HasPrefix(line, string(*h.line)) {
+		index := strconv.Itoa(pos + 1)
+
+		switch {
+		case number && !strings.HasPrefix(index, query):
 			continue
-		} else if regex != nil && !regex.MatchString(line) {
+		case !number && strings.HasPrefix(query, "?") && !strings.Contains(line, query[1:]):
+			continue
+		case !number && !strings.HasPrefix(query, "?") && !strings.HasPrefix(line, query):
 			continue
 		}
 
+		seen[line] = true
+
 		display := strings.ReplaceAll(line, "\n", ` `)
+		pad := strings.Repeat(" ", width-len(index))
 
-		// Proper pad for indexes
-		indexStr := strconv.Itoa(histPos)
-		pad := strings.Repeat(" ", len(strconv.Itoa(history.Len()))-len(indexStr))
-		display = fmt.Sprintf("%s%s %s%s", color.Dim, indexStr+pad, color.DimReset, display)
+		events = append(events, completion.Candidate{
+			Value:   "!" + index,
+			Display: fmt.Sprintf("%s!%s%s%s %s", color.Dim, index, pad, color.DimReset, display),
+		})
+	}
 
-		value := completion.Candidate{
-			Display: display,
-			Value:   line,
-		}
+	comps := completion.AddRaw(events)
+	comps.Sort["*"] = completion.SortNone
+	comps.ListLong["*"] = true
+	comps.NoFilter = true
+	comps.PREFIX = "!"
+	comps.Replace = true
+	comps.ReplaceStart, comps.ReplaceEnd = bpos, epos
 
-		compLines = append(compLines, value)
+	hint := h.styles.Title + "(history events)"
 
-		maxLines--
+	if len(events) == 0 {
+		hint += " - no match -"
 	}
 
-	comps := completion.AddRaw(compLines)
-	comps.NoSort["*"] = true
-	comps.ListLong["*"] = true
-	comps.PREFIX = string(*h.line)
+	comps.Messages.Add(hint)
 
 	return comps
 }
@@ -623,6 +837,182 @@ func (h *Sources) match(match *core.Line, cur *core.Cursor, usePos, fwd, regex b
 	return "", 0, false
 }
 
+// entry is a history line proposed as a completion.
+type entry struct {
+	source  string    // Name of the history source.
+	pos     int       // Index of the line in its source.
+	line    string    // The line itself.
+	written time.Time // When the line was written, if the source records it.
+}
+
+// completeSource returns the lines of a history source to be proposed as
+// completions, as described by Complete(), at most maxLines+1 of them.
+func (h *Sources) completeSource(name string, forward, filter bool, maxLines int, regex *regexp.Regexp) []entry {
+	history := h.list[name]
+	if history == nil {
+		return nil
+	}
+
+	timed, _ := history.(TimedSource)
+	entries := make([]entry, 0)
+
+	// Set up iteration clauses
+	var (
+		histPos int
+		done    func(i int) bool
+		move    func(inc int) int
+	)
+
+	if forward {
+		histPos = -1
+		done = func(i int) bool { return i < history.Len()-1 && maxLines >= 0 }
+		move = func(pos int) int { return pos + 1 }
+	} else {
+		histPos = history.Len()
+		done = func(i int) bool { return i > 0 && maxLines >= 0 }
+		move = func(pos int) int { return pos - 1 }
+	}
+
+	// And generate the completions.
+	for done(histPos) {
+		histPos = move(histPos)
+
+		line, err := history.GetLine(histPos)
+		if err != nil {
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if filter && !strings.HasPrefix(line, string(*h.line)) {
+			continue
+		} else if regex != nil && !regex.MatchString(line) {
+			continue
+		}
+
+		hist := entry{source: name, pos: histPos, line: line}
+
+		if timed != nil {
+			hist.written, _ = timed.GetTime(histPos)
+		}
+
+		entries = append(entries, hist)
+
+		maxLines--
+	}
+
+	return entries
+}
+
+// sortEntries orders the lines of several history sources by the time
+// they were written (in reverse order if not forward), if all of them
+// record it. Otherwise, the lines are left grouped by source.
+func sortEntries(entries []entry, forward bool) {
+	for _, hist := range entries {
+		if hist.written.IsZero() {
+			return
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if forward {
+			return entries[i].written.Before(entries[j].written)
+		}
+
+		return entries[i].written.After(entries[j].written)
+	})
+}
+
+// suggestScored returns the history line having the best score
+// among all those having the line as prefix, the most recent ones
+// winning ties, or the line itself if none matches. Lines whose
+// command failed are only suggested if no other one matches.
+func (h *Sources) suggestScored(line string) string {
+	history := h.Current()
+	suggested, best, bestFailed := line, -1, true
+
+	for pos := history.Len() - 1; pos >= 0; pos-- {
+		histline, err := history.GetLine(pos)
+		if err != nil || len(histline) < len(line) || !strings.HasPrefix(histline, line) {
+			continue
+		}
+
+		score, _ := h.scorer.Score(line, histline)
+		failed := h.failed(history, pos)
+
+		if (bestFailed && !failed) || (failed == bestFailed && score > best) {
+			suggested, best, bestFailed = histline, score, failed
+		}
+	}
+
+	return suggested
+}
+
+// suggestRecent returns the most recent history line starting with the line,
+// preferably one whose command did not fail, or the line itself if none does.
+func (h *Sources) suggestRecent(line string) string {
+	history := h.Current()
+	suggested, found := line, false
+
+	for pos := history.Len() - 1; pos >= 0; pos-- {
+		histline, err := history.GetLine(pos)
+		if err != nil || len(histline) < len(line) || !strings.HasPrefix(histline, line) {
+			continue
+		}
+
+		if !h.failed(history, pos) {
+			return histline
+		}
+
+		if !found {
+			suggested, found = histline, true
+		}
+	}
+
+	return suggested
+}
+
+// failed returns true if the command run with a history line
+// has been reported to have failed (with a non-zero status).
+func (h *Sources) failed(history Source, pos int) bool {
+	results, ok := history.(ResultSource)
+	if !ok {
+		return false
+	}
+
+	result, err := results.GetResult(pos)
+
+	return err == nil && result != nil && result.Status != 0
+}
+
+// fetchNext starts the line with the history line set with FetchNext(), if any.
+func (h *Sources) fetchNext() {
+	pos := h.next
+	h.next = -1
+
+	history := h.Current()
+	if pos < 0 || history == nil || pos >= history.Len() {
+		return
+	}
+
+	line, err := history.GetLine(pos)
+	if err != nil {
+		h.hint.Set(h.styles.Error + "history error: " + err.Error())
+		return
+	}
+
+	// Save the empty line buffer, which is
+	// restored when moving down past the line.
+	h.skip = false
+	h.Save()
+
+	h.hpos = history.Len() - pos
+	h.line.Set([]rune(line)...)
+	h.cursor.Set(h.line.Len())
+}
+
 // use the "main buffer" and its cursor if no line/cursor has been provided to match against.
 func (h *Sources) getLine(line *core.Line, cur *core.Cursor) (*core.Line, *core.Cursor) {
 	if h.hpos == -1 {
@@ -0,0 +1,91 @@
+package history
+
+// State is a serializable snapshot of the history sources positions
+// and of the undo history of all lines in each history source.
+type State struct {
+	Source string                       `json:"source"`
+	Pos    int                          `json:"pos"`
+	Lines  map[string]map[int]UndoState `json:"lines,omitempty"`
+}
+
+// UndoState is a serializable snapshot of the undo history of a single line.
+type UndoState struct {
+	Pos   int        `json:"pos"`
+	Items []UndoItem `json:"items"`
+}
+
+// UndoItem is a single line and cursor position state in an undo history.
+type UndoItem struct {
+	Line string `json:"line"`
+	Pos  int    `json:"pos"`
+}
+
+// State returns a snapshot of the current history source and position
+// in it, along with the undo history of all lines in all sources.
+func (h *Sources) State() State {
+	state := State{
+		Pos:   h.hpos,
+		Lines: make(map[string]map[int]UndoState, len(h.lines)),
+	}
+
+	if h.sourcePos < len(h.names) {
+		state.Source = h.names[h.sourcePos]
+	}
+
+	for source, lines := range h.lines {
+		undos := make(map[int]UndoState, len(lines))
+
+		for pos, line := range lines {
+			if line == nil {
+				continue
+			}
+
+			undo := UndoState{Pos: line.pos}
+
+			for _, item := range line.items {
+				undo.Items = append(undo.Items, UndoItem{Line: item.line, Pos: item.pos})
+			}
+
+			undos[pos] = undo
+		}
+
+		state.Lines[source] = undos
+	}
+
+	return state
+}
+
+// RestoreState restores the current history source (if it exists) and the
+// position in it, along with the undo history of all lines in all sources.
+// This does not restore the current input line itself.
+func (h *Sources) RestoreState(state State) {
+	for i, name := range h.names {
+		if name == state.Source {
+			h.sourcePos = i
+			break
+		}
+	}
+
+	h.hpos = state.Pos
+	if history := h.Current(); history == nil || h.hpos > history.Len() || h.hpos < -1 {
+		h.hpos = -1
+	}
+
+	h.lines = make(map[string]map[int]*lineHistory, len(state.Lines))
+
+	for source, undos := range state.Lines {
+		lines := make(map[int]*lineHistory, len(undos))
+
+		for pos, undo := range undos {
+			line := &lineHistory{pos: undo.Pos}
+
+			for _, item := range undo.Items {
+				line.items = append(line.items, undoItem{line: item.Line, pos: item.Pos})
+			}
+
+			lines[pos] = line
+		}
+
+		h.lines[source] = lines
+	}
+}
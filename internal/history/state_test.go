@@ -0,0 +1,57 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/ui"
+)
+
+// newTestSources returns history sources using a history with the given lines.
+func newTestSources(hist Source) *Sources {
+	line := new(core.Line)
+	sources := NewSources(line, core.NewCursor(line), new(ui.Hint), nil, color.NewStyles(), inputrc.NewDefaultConfig())
+	sources.Add("local", hist)
+
+	return sources
+}
+
+func TestSources_State(t *testing.T) {
+	hist := NewInMemoryHistory()
+	hist.Write("one")
+	hist.Write("two")
+
+	// Edit the last history line.
+	saved := newTestSources(hist)
+	saved.Walk(1)
+	saved.line.Insert(3, []rune(" x")...)
+	saved.cursor.Set(5)
+	saved.Save()
+
+	restored := newTestSources(hist)
+	restored.RestoreState(saved.State())
+
+	if restored.Name() != "local" || restored.hpos != 1 {
+		t.Fatalf("restored source = %q, position = %d, want %q, 1", restored.Name(), restored.hpos, "local")
+	}
+
+	// The edited line is still found when walking the history.
+	restored.Walk(1)
+	restored.Walk(-1)
+
+	if line := string(*restored.line); line != "two x" {
+		t.Errorf("restored history line = %q, want %q", line, "two x")
+	}
+
+	// Positions out of the history are reset.
+	state := saved.State()
+	state.Pos = 3
+
+	restored.RestoreState(state)
+
+	if restored.hpos != -1 {
+		t.Errorf("restored position = %d, want -1", restored.hpos)
+	}
+}
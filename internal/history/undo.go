@@ -23,7 +23,7 @@ type undoItem struct {
 func (h *Sources) Save() {
 	defer h.Reset()
 
-	if h.skip {
+	if h.skip || h.secret {
 		return
 	}
 
@@ -68,6 +68,21 @@ func (h *Sources) SkipSave() {
 	h.skip = true
 }
 
+// EndGroup saves the current line when the target command is done, even if the
+// command skipped saving it: commands inserting text do, so that their insertions
+// are grouped in a single undo state, until one of them ends the group.
+func (h *Sources) EndGroup() {
+	h.skip = false
+}
+
+// KeepPos will neither save the current line nor reset the position in the undo
+// history when the target command is done, so that commands not changing the line
+// (eg. numeric arguments) can be used between successive undo or redo calls.
+func (h *Sources) KeepPos() {
+	h.skip = true
+	h.undoing = true
+}
+
 // SaveWithCommand is only meant to be called in the main readline loop of the shell,
 // and not from within commands themselves: it does the same job as Save(), but also
 // keeps the command that has just been executed.
@@ -87,6 +102,8 @@ func (h *Sources) Undo() {
 		return
 	}
 
+	h.saveUnsaved(line)
+
 	var undo undoItem
 
 	// When undoing, we loop through preceding undo items
@@ -112,6 +129,40 @@ func (h *Sources) Undo() {
 	h.cursor.Set(undo.pos)
 }
 
+// UndoAll restores the line and cursor position to their first saved state,
+// as if undoing all changes, which can still be redone afterwards.
+func (h *Sources) UndoAll() {
+	h.skip = true
+	h.undoing = true
+
+	line := h.getLineHistory()
+	if line == nil || len(line.items) == 0 {
+		return
+	}
+
+	h.saveUnsaved(line)
+
+	line.pos = len(line.items)
+	undo := line.items[0]
+
+	h.line.Set([]rune(undo.line)...)
+	h.cursor.Set(undo.pos)
+}
+
+// saveUnsaved saves the current line before undoing changes, if it has been
+// changed since the last saved state (eg. with grouped insertions), so that
+// these changes can be redone.
+func (h *Sources) saveUnsaved(line *lineHistory) {
+	if line.pos > 0 || line.items[len(line.items)-1].line == string(*h.line) {
+		return
+	}
+
+	line.items = append(line.items, undoItem{
+		line: string(*h.line),
+		pos:  h.cursor.Pos(),
+	})
+}
+
 // Revert goes back to the initial state of the line, which is what it was
 // like when the shell started reading user input. Note that this state might
 // be a line that was inferred, accept-and-held from the previous readline run.
@@ -10,12 +10,15 @@ var menuselectKeys = map[string]inputrc.Bind{
 	unescape(`\e[Z`):    {Action: "menu-complete-backward"},
 	unescape(`\C-@`):    {Action: "accept-and-menu-complete"},
 	unescape(`\C-F`):    {Action: "menu-incremental-search"},
+	unescape(`\M-s`):    {Action: "menu-incremental-search-field"},
 	unescape(`\e[A`):    {Action: "menu-complete-backward"},
 	unescape(`\e[B`):    {Action: "menu-complete"},
 	unescape(`\e[C`):    {Action: "menu-complete"},
 	unescape(`\e[D`):    {Action: "menu-complete-backward"},
 	unescape(`\e[1;5A`): {Action: "menu-complete-prev-tag"},
 	unescape(`\e[1;5B`): {Action: "menu-complete-next-tag"},
+	unescape(`\M-a`):    {Action: "toggle-merged-history"},
+	unescape(`\M-t`):    {Action: "toggle-history-source-tags"},
 }
 
 // isearchCommands is a subset of commands that are valid in incremental-search mode.
@@ -50,6 +53,8 @@ var isearchCommands = []string{
 	"history-substring-search-backward",
 	"incremental-forward-search-history",
 	"incremental-reverse-search-history",
+	"toggle-merged-history",
+	"toggle-history-source-tags",
 }
 
 // nonIsearchCommands is an even more restricted set of commands
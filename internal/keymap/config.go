@@ -1,11 +1,8 @@
 package keymap
 
 import (
-	"fmt"
 	"os"
 	"os/user"
-	"sort"
-	"strings"
 
 	"github.com/reeflective/readline/inputrc"
 )
@@ -13,17 +10,76 @@ import (
 // readline global options specific to this library.
 var readlineOptions = map[string]interface{}{
 	// General edition
-	"autopairs": false,
+	"autopairs":         false,
+	"autopairs-chars":   `()[]{}""''`,
+	"keyboard-protocol": "legacy",
+	"enable-mouse":      false,
+	"clipboard":         "auto",
+	"kill-ring-size":    10,
+	"word-style":        "default",
+	"word-chars":        "*?_-.[]~=/&;!#$%^(){}<>",
+	"smart-indent":      false,
+	"indent-width":      4,
+	"format-width":      80,
+
+	// External editor
+	"editor-file-extension": "",
+	"editor-command":        "",
+
+	// Accepting lines
+	"accept-verify": "off",
+	"undo-grouping": "insert",
+
+	// Key sequences
+	"keyseq-prefer-exact": false,
 
 	// Completion
 	"autocomplete":               false,
 	"completion-list-separator":  "--",
 	"completion-selection-style": "\x1b[1;30m",
+	"completion-sort":            "alpha",
+	"completion-prefix-dim":      false,
+	"completion-fuzzy":           false,
+	"completion-autosuggest":     false,
+	"completion-page-size":       0,
+	"correct-command":            false,
+	"designator-completion":      false,
 
 	// Prompt & General UI
-	"transient-prompt":    false,
-	"usage-hint-always":   false,
-	"history-autosuggest": false,
+	"transient-prompt":      false,
+	"prompt-right-line":     "last",
+	"prompt-min-line-width": 10,
+	"usage-hint-always":     false,
+	"hint-timeout":          0,
+	"history-autosuggest":   false,
+	"history-menu-size":     20,
+	"history-diff-hint":     false,
+	"low-bandwidth":         false,
+	"screen-reader":         false,
+	"incremental-redisplay": true,
+	"render-max-fps":        0,
+	"shell-integration":     false,
+	"image-protocol":        "auto",
+	"theme":                 "dark",
+	"color-depth":           "auto",
+
+	"autosuggest-strategy":      "completion history",
+	"autosuggest-style":         "",
+	"autosuggest-motion-accept": true,
+
+	"history-merge-sources": false,
+	"history-source-tags":   false,
+
+	"highlight-matching-brackets": false,
+	"matching-bracket-style":      "\x1b[1;4m",
+	"protected-region-style":      "\x1b[2m",
+	"search-highlight":            false,
+	"search-match-style":          "\x1b[4m",
+	"search-current-match-style":  "\x1b[7m",
+
+	"line-numbers":          false,
+	"relative-line-numbers": false,
+	"line-numbers-style":    "\x1b[2m",
 }
 
 // ReloadConfig parses all valid .inputrc configurations and immediately
@@ -61,6 +117,10 @@ func (m *Engine) ReloadConfig(opts ...inputrc.Option) (err error) {
 		return err
 	}
 
+	// Special keys (arrows, Home/End, function keys, etc)
+	// sending other sequences in the current terminal.
+	m.loadTerminfoBinds()
+
 	// Some configuration variables might have an
 	// effect on our various keymaps and bindings.
 	m.overrideBindsSpecial()
@@ -106,15 +166,18 @@ func (m *Engine) loadBuiltinBinds() {
 		m.config.Binds[string(ViInsert)][seq] = bind
 	}
 
-	// Vim local keymaps
-	m.config.Binds[string(Visual)] = visualKeys
-	m.config.Binds[string(ViOpp)] = vioppKeys
-	m.config.Binds[string(MenuSelect)] = menuselectKeys
-	m.config.Binds[string(Isearch)] = menuselectKeys
+	// Vim and completion/search local keymaps, which can be bound
+	// to independently from each other in inputrc files: keys not
+	// bound in the local keymap are passed to the main one.
+	m.config.Binds[string(Visual)] = copyBinds(visualKeys)
+	m.config.Binds[string(ViOpp)] = copyBinds(vioppKeys)
+	m.config.Binds[string(MenuSelect)] = copyBinds(menuselectKeys)
+	m.config.Binds[string(Isearch)] = copyBinds(menuselectKeys)
 
-	// Default TTY binds
+	// Default TTY binds, and mouse reports (SGR-encoded).
 	for _, keymap := range m.config.Binds {
 		keymap[inputrc.Unescape(`\C-C`)] = inputrc.Bind{Action: "abort"}
+		keymap[inputrc.Unescape(`\e[<`)] = inputrc.Bind{Action: "mouse-event"}
 	}
 }
 
@@ -133,45 +196,14 @@ func (m *Engine) overrideBindsSpecial() {
 	}
 }
 
-func printBindsReadable(commands []string, all map[string][]string) {
-	for _, command := range commands {
-		commandBinds := all[command]
-		sort.Strings(commandBinds)
-
-		switch {
-		case len(commandBinds) == 0:
-		case len(commandBinds) > 5:
-			var firstBinds []string
-
-			for i := 0; i < 5; i++ {
-				firstBinds = append(firstBinds, "\""+commandBinds[i]+"\"")
-			}
-
-			bindsStr := strings.Join(firstBinds, ", ")
-			fmt.Printf("%s can be found on %s ...\n", command, bindsStr)
-
-		default:
-			var firstBinds []string
-
-			for _, bind := range commandBinds {
-				firstBinds = append(firstBinds, "\""+bind+"\"")
-			}
+// copyBinds returns a copy of builtin binds, so that binding keys
+// in a keymap does not modify the builtin binds nor other keymaps.
+func copyBinds(binds map[string]inputrc.Bind) map[string]inputrc.Bind {
+	keymap := make(map[string]inputrc.Bind, len(binds))
 
-			bindsStr := strings.Join(firstBinds, ", ")
-			fmt.Printf("%s can be found on %s\n", command, bindsStr)
-		}
+	for seq, bind := range binds {
+		keymap[seq] = bind
 	}
-}
-
-func printBindsInputrc(commands []string, all map[string][]string) {
-	for _, command := range commands {
-		commandBinds := all[command]
-		sort.Strings(commandBinds)
 
-		if len(commandBinds) > 0 {
-			for _, bind := range commandBinds {
-				fmt.Printf("\"%s\": %s\n", bind, command)
-			}
-		}
-	}
+	return keymap
 }
@@ -39,6 +39,10 @@ var cursors = map[CursorStyle]string{
 	cursorUserDefault:       "\x1b[0 q",
 }
 
+// Replace is not a keymap, but the mode of the overwrite-mode and vi-replace
+// commands, which have their own cursor (set with the cursor-replace option).
+const Replace Mode = "replace"
+
 var defaultCursors = map[Mode]CursorStyle{
 	ViInsert:  cursorBlinkingBeam,
 	Vi:        cursorBlinkingBeam,
@@ -46,11 +50,21 @@ var defaultCursors = map[Mode]CursorStyle{
 	ViOpp:     cursorBlinkingUnderline,
 	Visual:    cursorBlock,
 	Emacs:     cursorBlinkingBlock,
+	Replace:   cursorUnderline,
 }
 
-// PrintCursor prints the cursor for the given keymap mode,
-// either default value or the one specified in inputrc file.
+// Cursor color sequences (OSC 12 and 112).
+const (
+	cursorColor      = "\x1b]12;%s\a"
+	cursorResetColor = "\x1b]112\a"
+)
+
+// PrintCursor prints the cursor for the given keymap mode, either default value
+// or the one specified in inputrc file (eg. cursor-vi-insert), and its color if
+// one is specified (eg. cursor-color-vi-insert, as "#ff8700" or any X11 color).
 func (m *Engine) PrintCursor(keymap Mode) {
+	m.printColor(keymap)
+
 	var cursor CursorStyle
 
 	// Check for a configured cursor in .inputrc file.
@@ -58,14 +72,42 @@ func (m *Engine) PrintCursor(keymap Mode) {
 	modeSet := strings.TrimSpace(m.config.GetString(cursorOptname))
 
 	if _, valid := cursors[CursorStyle(modeSet)]; valid {
-		fmt.Print(cursors[CursorStyle(modeSet)])
+		fmt.Fprint(m.term, cursors[CursorStyle(modeSet)])
 		return
 	}
 
 	if defaultCur, valid := defaultCursors[keymap]; valid {
-		fmt.Print(cursors[defaultCur])
+		fmt.Fprint(m.term, cursors[defaultCur])
+		return
+	}
+
+	fmt.Fprint(m.term, cursors[cursor])
+}
+
+// RestoreCursor restores the default cursor style and color of the terminal.
+func (m *Engine) RestoreCursor() {
+	fmt.Fprint(m.term, cursors[cursorUserDefault])
+
+	if m.cursorColor != "" {
+		fmt.Fprint(m.term, cursorResetColor)
+		m.cursorColor = ""
+	}
+}
+
+// printColor sets the color of the cursor for the given keymap mode, or
+// resets it to the terminal default one if none is set for this mode.
+func (m *Engine) printColor(keymap Mode) {
+	color := m.config.GetString(fmt.Sprintf("cursor-color-%s", string(keymap)))
+	color = strings.Trim(strings.TrimSpace(color), "\"")
+
+	switch {
+	case color == m.cursorColor:
 		return
+	case color == "":
+		fmt.Fprint(m.term, cursorResetColor)
+	default:
+		fmt.Fprintf(m.term, cursorColor, color)
 	}
 
-	fmt.Print(cursors[cursor])
+	m.cursorColor = color
 }
@@ -0,0 +1,101 @@
+package keymap
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// newCursorEngine returns a keymap engine writing its cursors to the buffer.
+func newCursorEngine(out *bytes.Buffer) *Engine {
+	terminal := &term.Terminal{Output: out}
+	eng, _ := NewEngine(terminal, core.NewKeys(strings.NewReader(""), terminal), new(core.Iterations))
+	out.Reset()
+
+	return eng
+}
+
+func TestEngine_PrintCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		mode    Mode
+		want    string
+	}{
+		{name: "Default", mode: ViInsert, want: "\x1b[5 q"},
+		{name: "Replace", mode: Replace, want: "\x1b[4 q"},
+		{name: "Configured", options: map[string]interface{}{"cursor-vi-insert": "block"}, mode: ViInsert, want: "\x1b[2 q"},
+		{name: "Invalid", options: map[string]interface{}{"cursor-vi-insert": "round"}, mode: ViInsert, want: "\x1b[5 q"},
+		{name: "Color", options: map[string]interface{}{"cursor-color-emacs": `"#ff8700"`}, mode: Emacs, want: "\x1b]12;#ff8700\a\x1b[1 q"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			eng := newCursorEngine(out)
+
+			for name, value := range test.options {
+				eng.config.Set(name, value)
+			}
+
+			eng.PrintCursor(test.mode)
+
+			if out.String() != test.want {
+				t.Errorf("PrintCursor() = %q, want %q", out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestEngine_CursorColors(t *testing.T) {
+	out := new(bytes.Buffer)
+	eng := newCursorEngine(out)
+	eng.config.Set("cursor-color-vi-insert", "red")
+	eng.config.Set("cursor-color-vi-command", "red")
+
+	// Colors are only printed when they change.
+	eng.PrintCursor(ViInsert)
+	eng.PrintCursor(ViCommand)
+	eng.PrintCursor(Emacs)
+
+	if want := "\x1b]12;red\a\x1b[5 q\x1b[1 q\x1b]112\a\x1b[1 q"; out.String() != want {
+		t.Errorf("PrintCursor() = %q, want %q", out.String(), want)
+	}
+
+	// Restoring the cursor resets its color, if it was changed.
+	eng.PrintCursor(ViInsert)
+	out.Reset()
+	eng.RestoreCursor()
+	eng.RestoreCursor()
+
+	if want := "\x1b[0 q\x1b]112\a\x1b[0 q"; out.String() != want {
+		t.Errorf("RestoreCursor() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestEngine_ReplaceCursor(t *testing.T) {
+	out := new(bytes.Buffer)
+	eng := newCursorEngine(out)
+	eng.SetMain(ViCommand)
+	out.Reset()
+
+	// The replace cursor is used until done, and the main keymap one after.
+	restore := eng.ReplaceCursor()
+	restore()
+
+	if want := "\x1b[4 q\x1b[1 q"; !strings.HasSuffix(out.String(), want) {
+		t.Errorf("ReplaceCursor() = %q, want %q", out.String(), want)
+	}
+
+	// The emacs overwrite mode uses the replace cursor.
+	eng.SetMain(Emacs)
+	out.Reset()
+	eng.SetOverwrite(true)
+
+	if want := "\x1b[4 q"; !strings.HasSuffix(out.String(), want) {
+		t.Errorf("SetOverwrite() = %q, want %q", out.String(), want)
+	}
+}
@@ -3,6 +3,7 @@ package keymap
 import (
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/core"
@@ -25,11 +26,18 @@ func MatchLocal(eng *Engine) (bind inputrc.Bind, command func(), prefix bool) {
 
 	// bind, command, prefix, keys := eng.dispatch(binds)
 	bind, prefix, read, matched := eng.dispatchKeys(binds)
+	bind, prefix = eng.resolvePrefixed(eng.local, bind, prefix)
 
 	if !bind.Macro {
 		command = eng.commands[bind.Action]
 	}
 
+	// Keys bound to commands that do not exist are
+	// not consumed, and fall through to the main keymap.
+	if !prefix && !bind.Macro && command == nil {
+		bind, matched = inputrc.Bind{}, nil
+	}
+
 	if prefix {
 		core.MatchedPrefix(eng.keys, read...)
 	} else {
@@ -62,6 +70,7 @@ func MatchMain(eng *Engine) (bind inputrc.Bind, command func(), prefix bool) {
 
 	// Find the target action, macro or command.
 	bind, prefix, read, _ := eng.dispatchKeys(binds)
+	bind, prefix = eng.resolvePrefixed(eng.main, bind, prefix)
 
 	if !bind.Macro {
 		command = eng.commands[bind.Action]
@@ -167,7 +176,11 @@ func (m *Engine) matchBind(keys []byte, binds map[string]inputrc.Bind) (inputrc.
 	})
 
 	// Iterate over the sorted list of sequences and find all binds
-	// that match the sequence either by prefix or exactly.
+	// that match the sequence either by prefix or exactly. A sequence
+	// matching the keys as is wins over a metafied one (eg. `\e\C-y`
+	// over the self-inserted `\M-\C-y`), which only matches once converted.
+	var exact bool
+
 	for _, sequence := range sequences {
 		seq := strutil.ConvertMeta([]rune(sequence))
 
@@ -175,8 +188,9 @@ func (m *Engine) matchBind(keys []byte, binds map[string]inputrc.Bind) (inputrc.
 			prefixed = append(prefixed, binds[sequence])
 		}
 
-		if string(keys) == seq {
+		if string(keys) == seq && (!exact || sequence == seq) {
 			match = binds[sequence]
+			exact = sequence == seq
 		}
 	}
 
@@ -251,3 +265,38 @@ func (m *Engine) isEscapeKey() bool {
 
 	return true
 }
+
+// resolvePrefixed handles key sequences exactly matching a bind while being the prefix
+// of longer ones: the shorter bind is used if the keymap prefers exact matches, or if
+// no keys completing the longer binds have been read in time (keyseq-timeout).
+// Otherwise, the next wait for such keys is given this timeout.
+func (m *Engine) resolvePrefixed(mode Mode, bind inputrc.Bind, prefix bool) (inputrc.Bind, bool) {
+	if !prefix || m.prefixed.Action == "" {
+		return bind, prefix
+	}
+
+	if preferExact, _ := m.keymapOption(mode, "keyseq-prefer-exact").(bool); preferExact || core.TimedOut(m.keys) {
+		m.active = m.prefixed
+		m.prefixed = inputrc.Bind{}
+
+		return m.active, false
+	}
+
+	// A zero or negative timeout means waiting indefinitely.
+	if timeout, _ := m.keymapOption(mode, "keyseq-timeout").(int); timeout > 0 {
+		core.WaitTimeout(m.keys, time.Duration(timeout)*time.Millisecond)
+	}
+
+	return bind, prefix
+}
+
+// keymapOption returns the value of an option for a keymap, which can
+// be overridden by setting the option suffixed with the keymap name
+// (eg. `set keyseq-timeout-vi-insert 100`).
+func (m *Engine) keymapOption(mode Mode, name string) interface{} {
+	if value := m.config.Get(name + "-" + string(mode)); value != nil {
+		return value
+	}
+
+	return m.config.Get(name)
+}
@@ -0,0 +1,134 @@
+package keymap
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
+)
+
+const (
+	shortSeq = "\x18z"  // Control-x z, bound to the short command.
+	longSeq  = "\x18zz" // Control-x z z, bound to the long command.
+)
+
+// newDispatchEngine returns an emacs keymap engine reading keys from a pipe,
+// with a sequence bound to a command, and also the prefix of another one.
+func newDispatchEngine(t *testing.T, options map[string]interface{}) (*Engine, *io.PipeWriter) {
+	t.Helper()
+
+	reader, writer := io.Pipe()
+	t.Cleanup(func() { writer.Close() })
+
+	terminal := &term.Terminal{Output: io.Discard}
+	keys := core.NewKeys(reader, terminal)
+	eng, config := NewEngine(terminal, keys, new(core.Iterations))
+
+	for name, value := range options {
+		config.Set(name, value)
+	}
+
+	eng.Register(map[string]func(){"short": func() {}, "long": func() {}})
+	config.Binds[string(Emacs)][shortSeq] = inputrc.Bind{Action: "short"}
+	config.Binds[string(Emacs)][longSeq] = inputrc.Bind{Action: "long"}
+
+	return eng, writer
+}
+
+// matchKeys waits for keys (written in the background, if any) and matches them,
+// as the shell does, until a bind is found or the wait for keys is aborted.
+func matchKeys(eng *Engine, writer io.Writer, input string, done <-chan struct{}) (action string, waited time.Duration) {
+	if input != "" {
+		go writer.Write([]byte(input))
+	}
+
+	core.WaitDone(eng.keys, done)
+	start := time.Now()
+
+	for {
+		core.WaitAvailableKeys(eng.keys, eng.config)
+
+		if core.Cancelled(eng.keys) {
+			return "", time.Since(start)
+		}
+
+		bind, _, prefix := MatchMain(eng)
+		if !prefix {
+			return bind.Action, time.Since(start)
+		}
+	}
+}
+
+func TestMatchMain_Prefixed(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		input   string
+		want    string
+		wait    time.Duration // Time waited for keys completing a longer sequence.
+	}{
+		{
+			name:  "Longer sequence",
+			input: longSeq,
+			want:  "long",
+		},
+		{
+			name:    "Timeout",
+			options: map[string]interface{}{"keyseq-timeout": 30},
+			input:   shortSeq,
+			want:    "short",
+			wait:    30 * time.Millisecond,
+		},
+		{
+			name:    "Prefer exact",
+			options: map[string]interface{}{"keyseq-prefer-exact": true},
+			input:   shortSeq,
+			want:    "short",
+		},
+		{
+			name:    "Keymap prefers exact",
+			options: map[string]interface{}{"keyseq-prefer-exact-emacs": true, "keyseq-prefer-exact-vi-insert": false},
+			input:   shortSeq,
+			want:    "short",
+		},
+		{
+			name:    "Keymap timeout",
+			options: map[string]interface{}{"keyseq-timeout": 5000, "keyseq-timeout-emacs": 30},
+			input:   shortSeq,
+			want:    "short",
+			wait:    30 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eng, writer := newDispatchEngine(t, test.options)
+
+			action, waited := matchKeys(eng, writer, test.input, nil)
+			if action != test.want {
+				t.Errorf("MatchMain() = %q, want %q", action, test.want)
+			}
+
+			// The default timeout (500ms) is much longer than those tested.
+			if waited < test.wait || waited > test.wait+250*time.Millisecond {
+				t.Errorf("MatchMain() waited %v, want %v", waited, test.wait)
+			}
+		})
+	}
+}
+
+func TestMatchMain_NoTimeout(t *testing.T) {
+	// Without timeout (for this keymap), keys completing the longer sequence are
+	// waited for indefinitely: the wait only stops when aborted, here after 50ms.
+	eng, writer := newDispatchEngine(t, map[string]interface{}{"keyseq-timeout": 30, "keyseq-timeout-emacs": 0})
+
+	done := make(chan struct{})
+	time.AfterFunc(50*time.Millisecond, func() { close(done) })
+
+	if action, waited := matchKeys(eng, writer, shortSeq, done); action != "" || waited < 50*time.Millisecond {
+		t.Errorf("MatchMain() = %q after %v, want no bind after 50ms", action, waited)
+	}
+}
@@ -10,8 +10,10 @@ var emacsKeys = map[string]inputrc.Bind{
 	unescape(`\C-h`):     {Action: "backward-kill-word"},
 	unescape(`\C-N`):     {Action: "down-line-or-history"},
 	unescape(`\C-P`):     {Action: "up-line-or-history"},
+	unescape(`\C-Z`):     {Action: "terminal-suspend"},
 	unescape(`\C-x\C-b`): {Action: "vi-match"},
 	unescape(`\C-x\C-e`): {Action: "edit-command-line"},
+	unescape(`\C-x\C-g`): {Action: "redo"},
 	unescape(`\C-x\C-n`): {Action: "infer-next-history"},
 	unescape(`\C-x\C-o`): {Action: "overwrite-mode"},
 	unescape(`\C-Xr`):    {Action: "reverse-search-history"},
@@ -19,6 +21,9 @@ var emacsKeys = map[string]inputrc.Bind{
 	unescape(`\C-Xu`):    {Action: "undo"},
 	unescape(`\M-\C-^`):  {Action: "copy-prev-word"},
 	unescape(`\M-'`):     {Action: "quote-line"},
+	unescape(`\M-%`):     {Action: "replace-in-line"},
+	unescape(`\M-/`):     {Action: "dabbrev-expand"},
+	unescape(`\M-s`):     {Action: "correct-command-word"},
 	unescape(`\M-<`):     {Action: "beginning-of-buffer-or-history"},
 	unescape(`\M->`):     {Action: "end-of-buffer-or-history"},
 	unescape(`\M-c`):     {Action: "capitalize-word"},
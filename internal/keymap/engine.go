@@ -5,6 +5,7 @@ import (
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
 )
 
 // Engine is used to manage the main and local keymaps for the shell.
@@ -17,7 +18,10 @@ type Engine struct {
 	skip         bool
 	isCaller     bool
 	nonIncSearch bool
+	overwrite    bool   // Self-insert replaces characters (Emacs overwrite-mode).
+	cursorColor  string // Color of the cursor, if not the default one.
 
+	term       *term.Terminal
 	keys       *core.Keys
 	iterations *core.Iterations
 	config     *inputrc.Config
@@ -26,9 +30,10 @@ type Engine struct {
 
 // NewEngine is a required constructor for the keymap modes manager.
 // It initializes the keymaps to their defaults or configured values.
-func NewEngine(keys *core.Keys, i *core.Iterations, opts ...inputrc.Option) (*Engine, *inputrc.Config) {
+func NewEngine(t *term.Terminal, keys *core.Keys, i *core.Iterations, opts ...inputrc.Option) (*Engine, *inputrc.Config) {
 	modes := &Engine{
 		main:       Emacs,
+		term:       t,
 		keys:       keys,
 		iterations: i,
 		config:     inputrc.NewDefaultConfig(),
@@ -98,7 +103,11 @@ func (m *Engine) UpdateCursor() {
 	// But if not, we check for the global keymap
 	switch m.main {
 	case Emacs, EmacsStandard, EmacsMeta, EmacsCtrlX:
-		m.PrintCursor(Emacs)
+		if m.overwrite {
+			m.PrintCursor(Replace)
+		} else {
+			m.PrintCursor(Emacs)
+		}
 	case ViInsert:
 		m.PrintCursor(ViInsert)
 	case ViCommand, ViMove, Vi:
@@ -116,6 +125,29 @@ func (m *Engine) PendingCursor() (restore func()) {
 	}
 }
 
+// ReplaceCursor changes the cursor to replace mode,
+// and returns a function to call once done with it.
+func (m *Engine) ReplaceCursor() (restore func()) {
+	m.PrintCursor(Replace)
+
+	return func() {
+		m.UpdateCursor()
+	}
+}
+
+// SetOverwrite enables or disables the Emacs overwrite mode, in
+// which self-inserted characters replace those under the cursor.
+func (m *Engine) SetOverwrite(overwrite bool) {
+	m.overwrite = overwrite
+	m.UpdateCursor()
+}
+
+// Overwrite returns true if the overwrite mode is enabled and
+// the main keymap is an Emacs one (it has no effect in Vi modes).
+func (m *Engine) Overwrite() bool {
+	return m.overwrite && m.IsEmacs()
+}
+
 // IsEmacs returns true if the main keymap is one of the emacs modes.
 func (m *Engine) IsEmacs() bool {
 	switch m.main {
@@ -126,43 +158,30 @@ func (m *Engine) IsEmacs() bool {
 	}
 }
 
-// PrintBinds displays a list of currently bound commands (and their sequences)
-// to the screen. If inputrcFormat is true, it displays it formatted such that
-// the output can be reused in an .inputrc file.
-func (m *Engine) PrintBinds(keymap string, inputrcFormat bool) {
-	var commands []string
-
+// CommandBinds returns the sorted names of all commands, and the sorted (and
+// escaped) key sequences bound to each of them in a keymap, if any.
+func (m *Engine) CommandBinds(keymap string) (commands []string, binds map[string][]string) {
 	for command := range m.commands {
 		commands = append(commands, command)
 	}
 
 	sort.Strings(commands)
 
-	binds := m.config.Binds[keymap]
-	if binds == nil {
-		return
-	}
-
-	// Make a list of all sequences bound to each command.
-	allBinds := make(map[string][]string)
-
-	for _, command := range commands {
-		for key, bind := range binds {
-			if bind.Action != command {
-				continue
-			}
+	binds = make(map[string][]string)
 
-			commandBinds := allBinds[command]
-			commandBinds = append(commandBinds, inputrc.Escape(key))
-			allBinds[command] = commandBinds
+	for key, bind := range m.config.Binds[keymap] {
+		if bind.Macro {
+			continue
 		}
+
+		binds[bind.Action] = append(binds[bind.Action], inputrc.Escape(key))
 	}
 
-	if inputrcFormat {
-		printBindsInputrc(commands, allBinds)
-	} else {
-		printBindsReadable(commands, allBinds)
+	for _, commandBinds := range binds {
+		sort.Strings(commandBinds)
 	}
+
+	return commands, binds
 }
 
 // InputIsTerminator returns true when current input keys are one of
@@ -0,0 +1,89 @@
+package keymap
+
+import (
+	"os"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// specialKeys maps terminfo key capabilities to the sequences sent for
+// these keys by most terminals: the first one is the sequence used in the
+// default binds, and the others are common alternatives (xterm application
+// mode, rxvt, linux console, screen/tmux, st), used as a fallback when the
+// terminal has no terminfo entry.
+var specialKeys = map[string][]string{
+	"kcuu1": {`\e[A`, `\eOA`},
+	"kcud1": {`\e[B`, `\eOB`},
+	"kcuf1": {`\e[C`, `\eOC`},
+	"kcub1": {`\e[D`, `\eOD`},
+	"khome": {`\e[H`, `\eOH`, `\e[1~`, `\e[7~`},
+	"kend":  {`\e[F`, `\eOF`, `\e[4~`, `\e[8~`},
+	"kich1": {`\e[2~`},
+	"kdch1": {`\e[3~`},
+	"kpp":   {`\e[5~`},
+	"knp":   {`\e[6~`},
+	"kcbt":  {`\e[Z`},
+	"kf1":   {`\eOP`, `\e[11~`, `\e[[A`},
+	"kf2":   {`\eOQ`, `\e[12~`, `\e[[B`},
+	"kf3":   {`\eOR`, `\e[13~`, `\e[[C`},
+	"kf4":   {`\eOS`, `\e[14~`, `\e[[D`},
+	"kf5":   {`\e[15~`, `\e[[E`},
+	"kf6":   {`\e[17~`},
+	"kf7":   {`\e[18~`},
+	"kf8":   {`\e[19~`},
+	"kf9":   {`\e[20~`},
+	"kf10":  {`\e[21~`},
+	"kf11":  {`\e[23~`},
+	"kf12":  {`\e[24~`},
+}
+
+// loadTerminfoBinds binds, in all keymaps, the sequences sent by special keys
+// in the current terminal (as found in its terminfo entry, or the fallback ones)
+// to the command already bound to any other sequence for the same key.
+// Sequences already bound, by default or in inputrc files, are left untouched.
+func (m *Engine) loadTerminfoBinds() {
+	terminfo := term.TerminfoKeys(os.Getenv("TERM"))
+
+	for capability, escaped := range specialKeys {
+		sequences := make([]string, 0, len(escaped)+1)
+
+		for _, seq := range escaped {
+			sequences = append(sequences, inputrc.Unescape(seq))
+		}
+
+		alternatives := sequences[1:]
+
+		if seq, found := terminfo[capability]; found {
+			sequences = append([]string{seq}, sequences...)
+			alternatives = sequences[:1]
+		}
+
+		for _, binds := range m.config.Binds {
+			bindKeySequences(binds, sequences, alternatives)
+		}
+	}
+}
+
+// bindKeySequences binds all alternative sequences of a key not already
+// bound in the keymap to the command bound to the first bound sequence.
+func bindKeySequences(binds map[string]inputrc.Bind, sequences, alternatives []string) {
+	var bind inputrc.Bind
+
+	for _, seq := range sequences {
+		if found, bound := binds[seq]; bound {
+			bind = found
+			break
+		}
+	}
+
+	if bind.Action == "" {
+		return
+	}
+
+	for _, seq := range alternatives {
+		if _, bound := binds[seq]; !bound {
+			binds[seq] = bind
+		}
+	}
+}
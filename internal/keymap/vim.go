@@ -11,12 +11,13 @@ var viinsKeys = map[string]inputrc.Bind{
 	unescape(`\C-A`):   {Action: "beginning-of-line"},
 	unescape(`\C-B`):   {Action: "backward-char"},
 	unescape(`\C-F`):   {Action: "forward-char"},
-	unescape(`\C-K`):   {Action: "kill-line"},
+	unescape(`\C-K`):   {Action: "insert-digraph"},
 	unescape(`\C-N`):   {Action: "down-line-or-history"},
 	unescape(`\C-O`):   {Action: "operate-and-get-next"},
 	unescape(`\C-Q`):   {Action: "accept-and-infer-next-history"},
 	unescape(`\C-P`):   {Action: "up-line-or-history"},
 	unescape(`\C-_`):   {Action: "undo"},
+	unescape(`\C-Z`):   {Action: "terminal-suspend"},
 	unescape(`\M-q`):   {Action: "macro-toggle-record"},
 	unescape(`\M-r`):   {Action: "vi-registers-complete"},
 	unescape(`\M-[3~`): {Action: "delete-char"},
@@ -36,6 +37,7 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape(`\C-N`):    {Action: "next-history"},
 	unescape(`\C-P`):    {Action: "previous-history"},
 	unescape(`\C-X`):    {Action: "switch-keyword"},
+	unescape(`\C-Z`):    {Action: "terminal-suspend"},
 	unescape(`\M-<`):    {Action: "beginning-of-buffer-or-history"},
 	unescape(`\M->`):    {Action: "end-of-buffer-or-history"},
 	unescape(`\M-'`):    {Action: "quote-line"},
@@ -64,10 +66,17 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape("gE"):      {Action: "vi-backward-end-bigword"},
 	unescape("gu"):      {Action: "vi-down-case"},
 	unescape("gU"):      {Action: "vi-up-case"},
+	unescape("g~"):      {Action: "vi-oper-swap-case"},
+	unescape("g?"):      {Action: "rot13"},
+	unescape("gq"):      {Action: "vi-format"},
+	unescape("g%"):      {Action: "replace-in-line"},
+	unescape("gJ"):      {Action: "vi-join-lines-literal"},
+	unescape("gv"):      {Action: "vi-visual-reselect"},
 	unescape("f"):       {Action: "vi-find-next-char"},
 	unescape("t"):       {Action: "vi-find-next-char-skip"},
 	unescape("i"):       {Action: "vi-insertion-mode"},
 	unescape("I"):       {Action: "vi-insert-beg"},
+	unescape("J"):       {Action: "vi-join-lines"},
 	unescape("h"):       {Action: "vi-backward-char"},
 	unescape("l"):       {Action: "vi-forward-char"},
 	unescape("j"):       {Action: "down-line-or-history"},
@@ -85,6 +94,7 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape("T"):       {Action: "vi-find-prev-char-skip"},
 	unescape("s"):       {Action: "vi-subst"},
 	unescape("u"):       {Action: "vi-undo"},
+	unescape("U"):       {Action: "redo"},
 	unescape("v"):       {Action: "vi-visual-mode"},
 	unescape("V"):       {Action: "vi-visual-line-mode"},
 	unescape("w"):       {Action: "vi-forward-word"},
@@ -110,8 +120,11 @@ var vioppKeys = map[string]inputrc.Bind{
 	unescape("ia"):  {Action: "select-in-shell-word"},
 	unescape("iw"):  {Action: "select-in-word"},
 	unescape("s"):   {Action: "vi-select-surround"},
-	unescape("j"):   {Action: "down-line"},
-	unescape("k"):   {Action: "up-line"},
+	unescape("j"):   {Action: "next-screen-line"},
+	unescape("k"):   {Action: "previous-screen-line"},
+	unescape("u"):   {Action: "vi-down-case"},
+	unescape("U"):   {Action: "vi-up-case"},
+	unescape("~"):   {Action: "vi-oper-swap-case"},
 }
 
 // viinsKeymaps are the default keymaps in Vim Visual mode.
@@ -126,14 +139,21 @@ var visualKeys = map[string]inputrc.Bind{
 	unescape("a"):   {Action: "vi-select-inside"},
 	unescape("c"):   {Action: "vi-change-to"},
 	unescape("d"):   {Action: "vi-delete-to"},
+	unescape("gq"):  {Action: "vi-format"},
+	unescape("gJ"):  {Action: "vi-join-lines-literal"},
+	unescape("g?"):  {Action: "rot13"},
+	unescape("J"):   {Action: "vi-join-lines"},
 	unescape("i"):   {Action: "vi-select-inside"},
 	unescape("j"):   {Action: "next-screen-line"},
 	unescape("k"):   {Action: "previous-screen-line"},
+	unescape("o"):   {Action: "vi-visual-exchange"},
+	unescape("O"):   {Action: "vi-visual-exchange"},
 	unescape("s"):   {Action: "vi-subst"},
 	unescape("S"):   {Action: "vi-add-surround"},
 	unescape("u"):   {Action: "vi-down-case"},
+	unescape("U"):   {Action: "vi-up-case"},
 	unescape("v"):   {Action: "vi-edit-command-line"},
 	unescape("x"):   {Action: "vi-delete-to"},
 	unescape("y"):   {Action: "vi-yank-to"},
-	unescape("~"):   {Action: "vi-swap-case"},
+	unescape("~"):   {Action: "vi-change-case"},
 }
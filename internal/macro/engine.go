@@ -8,6 +8,7 @@ import (
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
 	"github.com/reeflective/readline/internal/ui"
 )
 
@@ -22,30 +23,39 @@ type Engine struct {
 	currentKey rune            // The identifier of the macro being recorded.
 	macros     map[rune]string // All previously recorded macros.
 	started    bool
+	tour       *tour // A guided tour being played, if any.
 
-	keys   *core.Keys // The engine feeds macros directly in the key stack.
-	hint   *ui.Hint   // The engine notifies when macro recording starts/stops.
-	status string     // The hint status displaying the currently recorded macro.
+	term   *term.Terminal // The terminal on which macros are printed.
+	keys   *core.Keys     // The engine feeds macros directly in the key stack.
+	hint   *ui.Hint       // The engine notifies when macro recording starts/stops.
+	styles *color.Styles  // The styles of the hints.
+	status string         // The hint status displaying the currently recorded macro.
 }
 
 // NewEngine is a required constructor to setup a working macro engine.
-func NewEngine(keys *core.Keys, hint *ui.Hint) *Engine {
+func NewEngine(t *term.Terminal, keys *core.Keys, hint *ui.Hint, styles *color.Styles) *Engine {
 	return &Engine{
 		current: make([]rune, 0),
 		macros:  make(map[rune]string),
+		term:    t,
 		keys:    keys,
 		hint:    hint,
+		styles:  styles,
 	}
 }
 
 // RecordKeys is being passed every key read by the shell, and will save
 // those entered while the engine is in record mode. All others are ignored.
 func RecordKeys(eng *Engine) {
+	keys := core.MacroKeys(eng.keys)
+
+	// Keys typed while trying a tour step might complete it.
+	eng.recordTourKeys(keys)
+
 	if !eng.recording {
 		return
 	}
 
-	keys := core.MacroKeys(eng.keys)
 	if len(keys) == 0 {
 		return
 	}
@@ -76,7 +86,7 @@ func (e *Engine) StartRecord(key rune) {
 
 	e.started = true
 	e.recording = true
-	e.status = color.Dim + "Recording macro: " + color.Bold
+	e.status = e.styles.Hint + "Recording macro: " + color.Bold
 	e.hint.Persist(e.status)
 }
 
@@ -152,7 +162,7 @@ func (e *Engine) PrintLastMacro() {
 	// Print the macro and the prompt.
 	// The shell takes care of clearing itself
 	// before printing, and refreshing after.
-	fmt.Printf("\n%s\n", e.macros[e.currentKey])
+	fmt.Fprintf(e.term, "\n%s\n", e.macros[e.currentKey])
 }
 
 // PrintAllMacros dumps all macros to the screen, which one line
@@ -178,7 +188,7 @@ func (e *Engine) PrintAllMacros() {
 			macro = '"'
 		}
 
-		fmt.Printf("\"%s\": %s\n", string(macro), sequence)
+		fmt.Fprintf(e.term, "\"%s\": %s\n", string(macro), sequence)
 	}
 }
 
@@ -0,0 +1,186 @@
+package macro
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+)
+
+// DefaultTourDelay is the delay between each key played back by a tour.
+const DefaultTourDelay = 150 * time.Millisecond
+
+// TourStep is a single step of a guided tour: a hint explaining a binding,
+// and a sequence of keys (in inputrc format, eg. `\C-a`) demonstrating it.
+type TourStep struct {
+	Hint string // Explanation displayed in the hint section during the step.
+	Keys string // Keys played back at human speed, in inputrc format.
+	Try  bool   // If true, pause after playback until the user types the keys.
+}
+
+// tour holds the state of a guided tour being played back.
+type tour struct {
+	steps  []TourStep
+	delay  time.Duration
+	step   int       // Index of the current step.
+	keys   []rune    // The unescaped keys of the current step.
+	played int       // Number of keys of the current step already fed.
+	trying bool      // Waiting for the user to try the binding.
+	tried  []rune    // Keys typed by the user while trying.
+	due    time.Time // Time at which the tour plays on.
+}
+
+// StartTour starts a guided tour: each step displays its hint and feeds its keys
+// to the shell one by one, with the given delay between them (or a default one),
+// optionally pausing until the user has typed the same keys to try the binding.
+func (e *Engine) StartTour(delay time.Duration, steps ...TourStep) {
+	if len(steps) == 0 {
+		return
+	}
+
+	if delay <= 0 {
+		delay = DefaultTourDelay
+	}
+
+	e.tour = &tour{
+		steps: steps,
+		delay: delay,
+	}
+
+	e.startStep()
+}
+
+// StopTour stops the current guided tour, if any.
+func (e *Engine) StopTour() {
+	if e.tour == nil {
+		return
+	}
+
+	e.tour = nil
+	e.hint.ResetPersist()
+}
+
+// NextTourStep skips to the next step of the current tour, if any.
+func (e *Engine) NextTourStep() {
+	if e.tour == nil {
+		return
+	}
+
+	e.tour.step++
+	e.startStep()
+}
+
+// TourActive returns true if a guided tour is currently being played.
+func (e *Engine) TourActive() bool {
+	return e.tour != nil
+}
+
+// PlayTour should be called once per readline loop, before waiting for user
+// input keys: if a tour is being played and no keys are still waiting to be
+// dispatched, it feeds the next key of the step once the tour delay is over.
+// Until then, the shell waits for user input keys for the remaining delay only.
+func PlayTour(eng *Engine) {
+	if eng.tour == nil || eng.tour.trying {
+		return
+	}
+
+	// Let the shell dispatch the keys we already fed.
+	if core.HasFedKeys(eng.keys) {
+		return
+	}
+
+	current := eng.tour
+
+	// Play the next key of the step.
+	if current.played < len(current.keys) {
+		if eng.waitTour() {
+			return
+		}
+
+		eng.keys.Feed(false, current.keys[current.played])
+		current.played++
+		current.due = time.Now().Add(current.delay)
+
+		return
+	}
+
+	// All keys have been played, either wait for the
+	// user to try them, or go to the next step.
+	if current.steps[current.step].Try && len(current.keys) > 0 {
+		current.trying = true
+		current.tried = make([]rune, 0)
+		eng.hint.Persist(eng.tourHint(true))
+
+		return
+	}
+
+	if eng.waitTour() {
+		return
+	}
+
+	eng.NextTourStep()
+}
+
+// waitTour makes the next wait for input keys return once the tour delay is over,
+// so that the keys typed meanwhile (eg. to stop the tour) are still dispatched.
+// Returns true if the delay is not over yet.
+func (e *Engine) waitTour() bool {
+	wait := time.Until(e.tour.due)
+	if wait <= 0 {
+		return false
+	}
+
+	core.WaitDelay(e.keys, wait)
+
+	return true
+}
+
+// recordTourKeys checks if the keys typed by the user while
+// trying a tour step match the ones of the step, and if so,
+// goes to the next step.
+func (e *Engine) recordTourKeys(keys []rune) {
+	if e.tour == nil || !e.tour.trying || len(keys) == 0 {
+		return
+	}
+
+	e.tour.tried = append(e.tour.tried, keys...)
+
+	if strings.HasSuffix(string(e.tour.tried), string(e.tour.keys)) {
+		e.NextTourStep()
+	}
+}
+
+// startStep initializes the current step of the tour,
+// or stops the tour if there are no more steps.
+func (e *Engine) startStep() {
+	if e.tour.step >= len(e.tour.steps) {
+		e.StopTour()
+		return
+	}
+
+	step := e.tour.steps[e.tour.step]
+
+	e.tour.keys = []rune(inputrc.Unescape(step.Keys))
+	e.tour.played = 0
+	e.tour.trying = false
+	e.tour.tried = nil
+	e.tour.due = time.Now().Add(e.tour.delay)
+
+	e.hint.Persist(e.tourHint(false))
+}
+
+// tourHint returns the hint to display for the current step.
+func (e *Engine) tourHint(trying bool) string {
+	step := e.tour.steps[e.tour.step]
+
+	hint := fmt.Sprintf("%sTour (%d/%d):%s %s", e.styles.Hint, e.tour.step+1, len(e.tour.steps), color.Reset, step.Hint)
+
+	if trying {
+		hint += fmt.Sprintf(" %s(your turn: %s)%s", e.styles.Title, step.Keys, color.Reset)
+	}
+
+	return hint
+}
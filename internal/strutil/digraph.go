@@ -0,0 +1,341 @@
+package strutil
+
+// digraphs maps RFC 1345 two-character mnemonics (as used by Vim)
+// to the characters they stand for: accented latin letters (a base
+// letter followed by an accent, eg. e' for é, or u: for ü), greek
+// letters (a letter followed by *), and common symbols.
+var digraphs = map[string]rune{
+	"NS":  0x00A0, // NBSP
+	"!I":  0x00A1, // ¡
+	"Ct":  0x00A2, // ¢
+	"Pd":  0x00A3, // £
+	"Cu":  0x00A4, // ¤
+	"Ye":  0x00A5, // ¥
+	"BB":  0x00A6, // ¦
+	"SE":  0x00A7, // §
+	"':":  0x00A8, // ¨
+	"Co":  0x00A9, // ©
+	"-a":  0x00AA, // ª
+	"<<":  0x00AB, // «
+	"NO":  0x00AC, // ¬
+	"--":  0x00AD, // SHY
+	"Rg":  0x00AE, // ®
+	"'m":  0x00AF, // ¯
+	"DG":  0x00B0, // °
+	"+-":  0x00B1, // ±
+	"2S":  0x00B2, // ²
+	"3S":  0x00B3, // ³
+	"''":  0x00B4, // ´
+	"My":  0x00B5, // µ
+	"PI":  0x00B6, // ¶
+	".M":  0x00B7, // ·
+	"',":  0x00B8, // ¸
+	"1S":  0x00B9, // ¹
+	"-o":  0x00BA, // º
+	">>":  0x00BB, // »
+	"14":  0x00BC, // ¼
+	"12":  0x00BD, // ½
+	"34":  0x00BE, // ¾
+	"?I":  0x00BF, // ¿
+	"A!":  0x00C0, // À
+	"A'":  0x00C1, // Á
+	"A>":  0x00C2, // Â
+	"A?":  0x00C3, // Ã
+	"A:":  0x00C4, // Ä
+	"A0":  0x00C5, // Å
+	"AE":  0x00C6, // Æ
+	"C,":  0x00C7, // Ç
+	"E!":  0x00C8, // È
+	"E'":  0x00C9, // É
+	"E>":  0x00CA, // Ê
+	"E:":  0x00CB, // Ë
+	"I!":  0x00CC, // Ì
+	"I'":  0x00CD, // Í
+	"I>":  0x00CE, // Î
+	"I:":  0x00CF, // Ï
+	"D-":  0x00D0, // Ð
+	"N?":  0x00D1, // Ñ
+	"O!":  0x00D2, // Ò
+	"O'":  0x00D3, // Ó
+	"O>":  0x00D4, // Ô
+	"O?":  0x00D5, // Õ
+	"O:":  0x00D6, // Ö
+	"*X":  0x00D7, // ×
+	"O/":  0x00D8, // Ø
+	"U!":  0x00D9, // Ù
+	"U'":  0x00DA, // Ú
+	"U>":  0x00DB, // Û
+	"U:":  0x00DC, // Ü
+	"Y'":  0x00DD, // Ý
+	"TH":  0x00DE, // Þ
+	"ss":  0x00DF, // ß
+	"a!":  0x00E0, // à
+	"a'":  0x00E1, // á
+	"a>":  0x00E2, // â
+	"a?":  0x00E3, // ã
+	"a:":  0x00E4, // ä
+	"a0":  0x00E5, // å
+	"ae":  0x00E6, // æ
+	"c,":  0x00E7, // ç
+	"e!":  0x00E8, // è
+	"e'":  0x00E9, // é
+	"e>":  0x00EA, // ê
+	"e:":  0x00EB, // ë
+	"i!":  0x00EC, // ì
+	"i'":  0x00ED, // í
+	"i>":  0x00EE, // î
+	"i:":  0x00EF, // ï
+	"d-":  0x00F0, // ð
+	"n?":  0x00F1, // ñ
+	"o!":  0x00F2, // ò
+	"o'":  0x00F3, // ó
+	"o>":  0x00F4, // ô
+	"o?":  0x00F5, // õ
+	"o:":  0x00F6, // ö
+	"-:":  0x00F7, // ÷
+	"o/":  0x00F8, // ø
+	"u!":  0x00F9, // ù
+	"u'":  0x00FA, // ú
+	"u>":  0x00FB, // û
+	"u:":  0x00FC, // ü
+	"y'":  0x00FD, // ý
+	"th":  0x00FE, // þ
+	"y:":  0x00FF, // ÿ
+	"A-":  0x0100, // Ā
+	"a-":  0x0101, // ā
+	"A(":  0x0102, // Ă
+	"a(":  0x0103, // ă
+	"A;":  0x0104, // Ą
+	"a;":  0x0105, // ą
+	"C'":  0x0106, // Ć
+	"c'":  0x0107, // ć
+	"C>":  0x0108, // Ĉ
+	"c>":  0x0109, // ĉ
+	"C.":  0x010A, // Ċ
+	"c.":  0x010B, // ċ
+	"C<":  0x010C, // Č
+	"c<":  0x010D, // č
+	"D<":  0x010E, // Ď
+	"d<":  0x010F, // ď
+	"D/":  0x0110, // Đ
+	"d/":  0x0111, // đ
+	"E-":  0x0112, // Ē
+	"e-":  0x0113, // ē
+	"E(":  0x0114, // Ĕ
+	"e(":  0x0115, // ĕ
+	"E.":  0x0116, // Ė
+	"e.":  0x0117, // ė
+	"E;":  0x0118, // Ę
+	"e;":  0x0119, // ę
+	"E<":  0x011A, // Ě
+	"e<":  0x011B, // ě
+	"G>":  0x011C, // Ĝ
+	"g>":  0x011D, // ĝ
+	"G(":  0x011E, // Ğ
+	"g(":  0x011F, // ğ
+	"G.":  0x0120, // Ġ
+	"g.":  0x0121, // ġ
+	"G,":  0x0122, // Ģ
+	"g,":  0x0123, // ģ
+	"H>":  0x0124, // Ĥ
+	"h>":  0x0125, // ĥ
+	"H/":  0x0126, // Ħ
+	"h/":  0x0127, // ħ
+	"I?":  0x0128, // Ĩ
+	"i?":  0x0129, // ĩ
+	"I-":  0x012A, // Ī
+	"i-":  0x012B, // ī
+	"I(":  0x012C, // Ĭ
+	"i(":  0x012D, // ĭ
+	"I;":  0x012E, // Į
+	"i;":  0x012F, // į
+	"I.":  0x0130, // İ
+	"i.":  0x0131, // ı
+	"IJ":  0x0132, // Ĳ
+	"ij":  0x0133, // ĳ
+	"J>":  0x0134, // Ĵ
+	"j>":  0x0135, // ĵ
+	"K,":  0x0136, // Ķ
+	"k,":  0x0137, // ķ
+	"kk":  0x0138, // ĸ
+	"L'":  0x0139, // Ĺ
+	"l'":  0x013A, // ĺ
+	"L,":  0x013B, // Ļ
+	"l,":  0x013C, // ļ
+	"L<":  0x013D, // Ľ
+	"l<":  0x013E, // ľ
+	"L.":  0x013F, // Ŀ
+	"l.":  0x0140, // ŀ
+	"L/":  0x0141, // Ł
+	"l/":  0x0142, // ł
+	"N'":  0x0143, // Ń
+	"n'":  0x0144, // ń
+	"N,":  0x0145, // Ņ
+	"n,":  0x0146, // ņ
+	"N<":  0x0147, // Ň
+	"n<":  0x0148, // ň
+	"'n":  0x0149, // ŉ
+	"NG":  0x014A, // Ŋ
+	"ng":  0x014B, // ŋ
+	"O-":  0x014C, // Ō
+	"o-":  0x014D, // ō
+	"O(":  0x014E, // Ŏ
+	"o(":  0x014F, // ŏ
+	"O\"": 0x0150, // Ő
+	"o\"": 0x0151, // ő
+	"OE":  0x0152, // Œ
+	"oe":  0x0153, // œ
+	"R'":  0x0154, // Ŕ
+	"r'":  0x0155, // ŕ
+	"R,":  0x0156, // Ŗ
+	"r,":  0x0157, // ŗ
+	"R<":  0x0158, // Ř
+	"r<":  0x0159, // ř
+	"S'":  0x015A, // Ś
+	"s'":  0x015B, // ś
+	"S>":  0x015C, // Ŝ
+	"s>":  0x015D, // ŝ
+	"S,":  0x015E, // Ş
+	"s,":  0x015F, // ş
+	"S<":  0x0160, // Š
+	"s<":  0x0161, // š
+	"T,":  0x0162, // Ţ
+	"t,":  0x0163, // ţ
+	"T<":  0x0164, // Ť
+	"t<":  0x0165, // ť
+	"T/":  0x0166, // Ŧ
+	"t/":  0x0167, // ŧ
+	"U?":  0x0168, // Ũ
+	"u?":  0x0169, // ũ
+	"U-":  0x016A, // Ū
+	"u-":  0x016B, // ū
+	"U(":  0x016C, // Ŭ
+	"u(":  0x016D, // ŭ
+	"U0":  0x016E, // Ů
+	"u0":  0x016F, // ů
+	"U\"": 0x0170, // Ű
+	"u\"": 0x0171, // ű
+	"U;":  0x0172, // Ų
+	"u;":  0x0173, // ų
+	"W>":  0x0174, // Ŵ
+	"w>":  0x0175, // ŵ
+	"Y>":  0x0176, // Ŷ
+	"y>":  0x0177, // ŷ
+	"Y:":  0x0178, // Ÿ
+	"Z'":  0x0179, // Ź
+	"z'":  0x017A, // ź
+	"Z.":  0x017B, // Ż
+	"z.":  0x017C, // ż
+	"Z<":  0x017D, // Ž
+	"z<":  0x017E, // ž
+	"A*":  0x0391, // Α
+	"B*":  0x0392, // Β
+	"G*":  0x0393, // Γ
+	"D*":  0x0394, // Δ
+	"E*":  0x0395, // Ε
+	"Z*":  0x0396, // Ζ
+	"Y*":  0x0397, // Η
+	"H*":  0x0398, // Θ
+	"I*":  0x0399, // Ι
+	"K*":  0x039A, // Κ
+	"L*":  0x039B, // Λ
+	"M*":  0x039C, // Μ
+	"N*":  0x039D, // Ν
+	"C*":  0x039E, // Ξ
+	"O*":  0x039F, // Ο
+	"P*":  0x03A0, // Π
+	"R*":  0x03A1, // Ρ
+	"S*":  0x03A3, // Σ
+	"T*":  0x03A4, // Τ
+	"U*":  0x03A5, // Υ
+	"F*":  0x03A6, // Φ
+	"X*":  0x03A7, // Χ
+	"Q*":  0x03A8, // Ψ
+	"W*":  0x03A9, // Ω
+	"a*":  0x03B1, // α
+	"b*":  0x03B2, // β
+	"g*":  0x03B3, // γ
+	"d*":  0x03B4, // δ
+	"e*":  0x03B5, // ε
+	"z*":  0x03B6, // ζ
+	"y*":  0x03B7, // η
+	"h*":  0x03B8, // θ
+	"i*":  0x03B9, // ι
+	"k*":  0x03BA, // κ
+	"l*":  0x03BB, // λ
+	"m*":  0x03BC, // μ
+	"n*":  0x03BD, // ν
+	"c*":  0x03BE, // ξ
+	"o*":  0x03BF, // ο
+	"p*":  0x03C0, // π
+	"r*":  0x03C1, // ρ
+	"*s":  0x03C2, // ς
+	"s*":  0x03C3, // σ
+	"t*":  0x03C4, // τ
+	"u*":  0x03C5, // υ
+	"f*":  0x03C6, // φ
+	"x*":  0x03C7, // χ
+	"q*":  0x03C8, // ψ
+	"w*":  0x03C9, // ω
+	"-N":  0x2013, // –
+	"-M":  0x2014, // —
+	"'6":  0x2018, // ‘
+	"'9":  0x2019, // ’
+	"\"6": 0x201C, // “
+	"\"9": 0x201D, // ”
+	"/-":  0x2020, // †
+	"/=":  0x2021, // ‡
+	",.":  0x2026, // …
+	"%0":  0x2030, // ‰
+	"Eu":  0x20AC, // €
+	"oC":  0x2103, // ℃
+	"TM":  0x2122, // ™
+	"<-":  0x2190, // ←
+	"-!":  0x2191, // ↑
+	"->":  0x2192, // →
+	"-v":  0x2193, // ↓
+	"<>":  0x2194, // ↔
+	"=>":  0x21D2, // ⇒
+	"==":  0x21D4, // ⇔
+	"FA":  0x2200, // ∀
+	"dP":  0x2202, // ∂
+	"TE":  0x2203, // ∃
+	"/0":  0x2205, // ∅
+	"DE":  0x2206, // ∆
+	"NB":  0x2207, // ∇
+	"(-":  0x2208, // ∈
+	"*P":  0x220F, // ∏
+	"+Z":  0x2211, // ∑
+	"-2":  0x2212, // −
+	"RT":  0x221A, // √
+	"00":  0x221E, // ∞
+	"AN":  0x2227, // ∧
+	"OR":  0x2228, // ∨
+	"(U":  0x2229, // ∩
+	")U":  0x222A, // ∪
+	"In":  0x222B, // ∫
+	"?2":  0x2248, // ≈
+	"!=":  0x2260, // ≠
+	"=3":  0x2261, // ≡
+	"=<":  0x2264, // ≤
+	">=":  0x2265, // ≥
+	"(C":  0x2282, // ⊂
+	")C":  0x2283, // ⊃
+	"Db":  0x25C6, // ◆
+	"*2":  0x2605, // ★
+	"*1":  0x2606, // ☆
+	"OK":  0x2713, // ✓
+	"XX":  0x2717, // ✗
+}
+
+// Digraph returns the character a digraph stands for, trying both
+// orders of its characters (as Vim does), and false if none does.
+func Digraph(first, second rune) (char rune, found bool) {
+	if char, found = digraphs[string([]rune{first, second})]; found {
+		return char, found
+	}
+
+	char, found = digraphs[string([]rune{second, first})]
+
+	return char, found
+}
@@ -0,0 +1,36 @@
+package strutil
+
+// Distance returns the edit distance between two strings: the minimum number
+// of characters to insert, delete or substitute, or of adjacent characters to
+// transpose, to change one into the other (optimal string alignment distance).
+func Distance(first, second string) int {
+	a, b := []rune(first), []rune(second)
+
+	// Only the last three rows of the matrix are needed.
+	prev2, prev, row := make([]int, len(b)+1), make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		row[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				row[j] = min(row[j], prev2[j-2]+1)
+			}
+		}
+
+		prev2, prev, row = prev, row, prev2
+	}
+
+	return prev[len(b)]
+}
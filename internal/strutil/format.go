@@ -0,0 +1,87 @@
+package strutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Format rewraps the paragraphs of a text so that its lines are not longer than
+// width columns (except for words longer than it), breaking them at blanks. The
+// paragraphs are separated by blank lines, or by a change of line prefix: the
+// indentation, followed by the comment string and blanks if the line starts with
+// it. The prefix of the first line of each paragraph is used for all its lines.
+func Format(text string, width int, comment string) string {
+	var (
+		lines     []string
+		prefix    string
+		paragraph []string
+	)
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			lines = append(lines, fill(paragraph, prefix, width)...)
+		}
+
+		paragraph = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		linePrefix := formatPrefix(line, comment)
+		words := strings.Fields(line[len(linePrefix):])
+
+		if len(words) == 0 {
+			flush()
+			lines = append(lines, line)
+
+			continue
+		}
+
+		if len(paragraph) > 0 && strings.TrimSpace(linePrefix) != strings.TrimSpace(prefix) {
+			flush()
+		}
+
+		if len(paragraph) == 0 {
+			prefix = linePrefix
+		}
+
+		paragraph = append(paragraph, words...)
+	}
+
+	flush()
+
+	return strings.Join(lines, "\n")
+}
+
+// formatPrefix returns the indentation of a line, along with the
+// comment string and the blanks following it if the line starts with it.
+func formatPrefix(line, comment string) string {
+	indent := len(line) - len(strings.TrimLeftFunc(line, unicode.IsSpace))
+
+	rest := line[indent:]
+	if comment == "" || !strings.HasPrefix(rest, comment) {
+		return line[:indent]
+	}
+
+	rest = rest[len(comment):]
+	blanks := len(rest) - len(strings.TrimLeftFunc(rest, unicode.IsSpace))
+
+	return line[:indent+len(comment)+blanks]
+}
+
+// fill joins words into lines of at most width columns, each starting with prefix.
+func fill(words []string, prefix string, width int) (lines []string) {
+	line := prefix + words[0]
+
+	for _, word := range words[1:] {
+		if RealLength(line)+1+RealLength(word) > width {
+			lines = append(lines, line)
+			line = prefix + word
+
+			continue
+		}
+
+		line += " " + word
+	}
+
+	return append(lines, line)
+}
@@ -0,0 +1,190 @@
+package strutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// heredoc is a here-document opened by a << (or <<-) redirection.
+type heredoc struct {
+	delimiter string
+	stripTabs bool
+}
+
+// Incomplete returns true if a shell command line is not complete, and should be
+// continued on a new line (as POSIX shells do with their secondary prompt): when
+// it has an unclosed quote, command substitution, parameter expansion or bracket,
+// a here-document without its delimiter line, or when it ends with a backslash
+// or a pipeline/list operator (|, || or &&). Comments are ignored.
+func Incomplete(line []rune) bool {
+	var (
+		closers  []rune // Closing characters of the opened quotes, substitutions and brackets.
+		heredocs []heredoc
+		operator bool // The last token is a |, || or && operator.
+	)
+
+	for pos := 0; pos < len(line); pos++ {
+		char := line[pos]
+
+		var next rune
+		if pos+1 < len(line) {
+			next = line[pos+1]
+		}
+
+		var top rune
+		if len(closers) > 0 {
+			top = closers[len(closers)-1]
+		}
+
+		if !unicode.IsSpace(char) && char != '#' && top == 0 {
+			operator = false
+		}
+
+		switch {
+		// Everything is literal within single quotes.
+		case top == singleChar:
+			if char == singleChar {
+				closers = closers[:len(closers)-1]
+			}
+
+		case char == escapeChar:
+			if pos+1 == len(line) {
+				return true
+			}
+
+			pos++
+
+		case top == doubleChar && char == doubleChar:
+			closers = closers[:len(closers)-1]
+
+		case top == '`' && char == '`':
+			closers = closers[:len(closers)-1]
+
+		case char == '$' && (next == '(' || next == '{'):
+			closers = append(closers, matchingBracket(next))
+			pos++
+
+		case char == '`':
+			closers = append(closers, char)
+
+		// Only substitutions are recognized within double quotes.
+		case top == doubleChar:
+
+		case char == singleChar || char == doubleChar:
+			closers = append(closers, char)
+
+		case char == '(' || char == '{' || char == '[':
+			closers = append(closers, matchingBracket(char))
+
+		case char == top:
+			closers = closers[:len(closers)-1]
+
+		case char == '#' && (pos == 0 || unicode.IsSpace(line[pos-1]) || strings.ContainsRune(operatorChars+"(", line[pos-1])):
+			for pos+1 < len(line) && line[pos+1] != '\n' {
+				pos++
+			}
+
+		// Here-strings (<<<) are not here-documents.
+		case char == '<' && next == '<' && pos+2 < len(line) && line[pos+2] == '<':
+			pos += 2
+
+		case char == '<' && next == '<':
+			doc, end := heredocRedirection(line, pos+2)
+			heredocs = append(heredocs, doc)
+			pos = end - 1
+
+		case char == '|' || (char == '&' && next == '&'):
+			if next == char {
+				pos++
+			}
+
+			operator = top == 0
+
+		// Here-documents start on the line after their redirection.
+		case char == '\n' && len(heredocs) > 0:
+			heredocs, pos = heredocBodies(line, pos, heredocs)
+		}
+	}
+
+	return len(closers) > 0 || len(heredocs) > 0 || operator
+}
+
+// heredocRedirection returns the here-document of a << redirection operator
+// ending at pos, and the position after its (unquoted) delimiter word.
+func heredocRedirection(line []rune, pos int) (doc heredoc, end int) {
+	if pos < len(line) && line[pos] == '-' {
+		doc.stripTabs = true
+		pos++
+	}
+
+	for pos < len(line) && (line[pos] == ' ' || line[pos] == '\t') {
+		pos++
+	}
+
+	var delimiter []rune
+
+	var quote rune
+
+	for ; pos < len(line); pos++ {
+		char := line[pos]
+
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			delimiter = append(delimiter, char)
+		case char == singleChar || char == doubleChar:
+			quote = char
+		case char == escapeChar && pos+1 < len(line):
+			pos++
+			delimiter = append(delimiter, line[pos])
+		case unicode.IsSpace(char) || strings.ContainsRune(operatorChars+"()", char):
+			doc.delimiter = string(delimiter)
+			return doc, pos
+		default:
+			delimiter = append(delimiter, char)
+		}
+	}
+
+	doc.delimiter = string(delimiter)
+
+	return doc, pos
+}
+
+// heredocBodies skips the lines following the newline at pos until the delimiter
+// lines of the pending here-documents, and returns the ones still not terminated
+// along with the position of the newline ending the last delimiter line found.
+func heredocBodies(line []rune, pos int, heredocs []heredoc) ([]heredoc, int) {
+	for len(heredocs) > 0 && pos < len(line) {
+		start := pos + 1
+
+		end := start
+		for end < len(line) && line[end] != '\n' {
+			end++
+		}
+
+		text := string(line[start:end])
+		if heredocs[0].stripTabs {
+			text = strings.TrimLeft(text, "\t")
+		}
+
+		if text == heredocs[0].delimiter {
+			heredocs = heredocs[1:]
+		}
+
+		pos = end
+	}
+
+	return heredocs, pos
+}
+
+func matchingBracket(char rune) rune {
+	switch char {
+	case '(':
+		return ')'
+	case '{':
+		return '}'
+	default:
+		return ']'
+	}
+}
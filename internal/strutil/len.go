@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/reeflective/readline/internal/color"
-	"github.com/reeflective/readline/internal/term"
 	"github.com/rivo/uniseg"
 )
 
@@ -23,15 +22,16 @@ func RealLength(s string) int {
 	return uniseg.StringWidth(tabs)
 }
 
-// LineSpan computes the number of columns and lines that are needed for a given line,
-// accounting for any ANSI escapes/color codes, and tabulations replaced with 4 spaces.
-func LineSpan(line []rune, idx, indent int) (x, y int) {
-	termWidth := term.GetWidth()
-	lineLen := RealLength(string(line))
-	lineLen += indent
+// LineSpan computes the number of columns and lines that are needed for a given line
+// on a terminal of the given width, accounting for any ANSI escapes/color codes, and
+// tabulations replaced with 4 spaces.
+// Wide characters (and grapheme clusters) that do not fit at the end of a terminal
+// line are wrapped as a whole, like terminals do, leaving the last column(s) empty.
+func LineSpan(line []rune, idx, indent, termWidth int) (x, y int) {
+	columns := indent + Width(string(line), indent, termWidth)
 
-	cursorY := lineLen / termWidth
-	cursorX := lineLen % termWidth
+	cursorY := columns / termWidth
+	cursorX := columns % termWidth
 
 	// Empty lines are still considered a line.
 	if idx != 0 {
@@ -40,3 +40,43 @@ func LineSpan(line []rune, idx, indent int) (x, y int) {
 
 	return cursorX, cursorY
 }
+
+// Width returns the number of terminal columns used to render a string
+// starting at the given column, with a given terminal width: this is the
+// real length of the string, plus the columns left empty at the end of
+// terminal lines, when a wide character is wrapped onto the next one.
+func Width(s string, column, termWidth int) int {
+	rest := strings.ReplaceAll(color.Strip(s), "\t", "     ")
+	if isPrintableASCII(rest) {
+		return len(rest)
+	}
+
+	state, width := -1, 0
+
+	for len(rest) > 0 {
+		var cluster int
+		_, rest, cluster, state = uniseg.FirstGraphemeClusterInString(rest, state)
+
+		if termWidth > 0 {
+			if used := (column + width) % termWidth; used+cluster > termWidth {
+				width += termWidth - used
+			}
+		}
+
+		width += cluster
+	}
+
+	return width
+}
+
+// isPrintableASCII returns true if the string only contains printable
+// ASCII characters, each one being rendered on a single column.
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,350 @@
+package strutil
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenKind is the type of a shell command line token.
+type TokenKind int
+
+const (
+	// TokenWord is a command name or argument.
+	TokenWord TokenKind = iota
+	// TokenControl is an operator separating commands (|, ||, &&, ;, &).
+	TokenControl
+	// TokenRedirect is a redirection operator (<, >, >>, 2>, &>, <<, etc).
+	TokenRedirect
+)
+
+// Token is a word or an operator of a shell command line.
+type Token struct {
+	Kind  TokenKind
+	Value string // The token without its quotes and backslash escapes.
+	Start int    // Position of the first rune of the token in the line.
+	End   int    // Position after the last rune of the token in the line.
+	Quote int    // Position after the opening quote if the word has an unclosed one, or -1.
+}
+
+var (
+	operatorChars     = "|&;<>"
+	controlOperators  = []string{"||", "&&", ";;", "|&", "|", "&", ";"}
+	redirectOperators = []string{"&>>", "&>", ">>", ">&", ">|", "<<<", "<<", "<&", "<>", ">", "<"}
+	escapedChars      = " \t\n'\"\\$`|&;<>()*?[]{}#~!"
+)
+
+// Tokenize splits a command line into shell tokens: words are split on blanks
+// outside of quotes, backslash escapes and quotes are removed from their values,
+// command substitutions ($() and backticks) are kept in their word, and control
+// and redirection operators are tokens on their own, even when not surrounded
+// by spaces. Unclosed quotes and substitutions do not produce any errors.
+func Tokenize(line []rune) []Token {
+	tokens, _ := tokenize(line)
+	return tokens
+}
+
+// CommandArgs returns the words of the innermost command at the end of the line:
+// words inside an unclosed command substitution belong to the substituted command,
+// words before the last control operator are dropped, as are redirections and their
+// targets. The last token is always the word at the end of the line (even if it is
+// a redirection target), which is empty if the line ends with a blank or operator.
+func CommandArgs(line []rune) []Token {
+	tokens, substs := tokenize(line)
+
+	// Only consider the innermost unclosed substitution.
+	if len(substs) > 0 {
+		start := substs[len(substs)-1]
+		args := CommandArgs(line[start:])
+
+		for i := range args {
+			args[i].Start += start
+			args[i].End += start
+
+			if args[i].Quote >= 0 {
+				args[i].Quote += start
+			}
+		}
+
+		return args
+	}
+
+	// The word being typed at the end of the line, if any.
+	current := Token{Kind: TokenWord, Start: len(line), End: len(line), Quote: -1}
+
+	if last := len(tokens) - 1; last >= 0 && tokens[last].Kind == TokenWord && tokens[last].End == len(line) {
+		current = tokens[last]
+		tokens = tokens[:last]
+	}
+
+	args := make([]Token, 0, len(tokens)+1)
+	redirect := false
+
+	for _, tok := range tokens {
+		switch {
+		case tok.Kind == TokenControl:
+			args = args[:0]
+			redirect = false
+		case tok.Kind == TokenRedirect:
+			redirect = true
+		case redirect:
+			redirect = false
+		default:
+			args = append(args, tok)
+		}
+	}
+
+	return append(args, current)
+}
+
+// CommandEnd returns the position at which the innermost command including pos
+// ends: at the next control operator outside of quotes and substitutions, at the
+// end of the command substitution in which pos is, or at the end of the line.
+func CommandEnd(line []rune, pos int) int {
+	var (
+		quote  rune
+		closer []rune // Closing characters of the opened substitutions.
+		depth  = -1   // Number of substitutions opened at pos.
+	)
+
+	for i := 0; i < len(line); i++ {
+		if i == pos {
+			depth = len(closer)
+		}
+
+		char := line[i]
+
+		var next rune
+		if i+1 < len(line) {
+			next = line[i+1]
+		}
+
+		switch {
+		case quote == singleChar:
+			if char == singleChar {
+				quote = 0
+			}
+
+		case char == escapeChar:
+			i++
+
+		case char == '$' && next == '(':
+			closer = append(closer, ')')
+			i++
+
+		case len(closer) > 0 && char == closer[len(closer)-1]:
+			if depth == len(closer) {
+				return i
+			}
+
+			closer = closer[:len(closer)-1]
+
+		case char == '`':
+			closer = append(closer, '`')
+
+		case quote == doubleChar:
+			if char == doubleChar {
+				quote = 0
+			}
+
+		case char == singleChar || char == doubleChar:
+			quote = char
+
+		case strings.ContainsRune(operatorChars, char):
+			operator := operatorToken(line, i, i)
+			if operator.Kind == TokenControl && depth == len(closer) {
+				return i
+			}
+
+			i = operator.End - 1
+		}
+	}
+
+	return len(line)
+}
+
+// EscapeWord escapes with backslashes all blanks and shell special characters of a word.
+func EscapeWord(word string) string {
+	var escaped strings.Builder
+
+	for _, char := range word {
+		if strings.ContainsRune(escapedChars, char) {
+			escaped.WriteRune(escapeChar)
+		}
+
+		escaped.WriteRune(char)
+	}
+
+	return escaped.String()
+}
+
+// tokenize splits the line into tokens, and returns the positions at which
+// the contents of all unclosed command substitutions start, outermost first.
+func tokenize(line []rune) (tokens []Token, substs []int) {
+	var (
+		tok    *Token
+		value  []rune
+		quote  rune
+		closer []rune // Closing characters of the opened substitutions.
+	)
+
+	begin := func(pos int) {
+		if tok == nil {
+			tok = &Token{Kind: TokenWord, Start: pos, Quote: -1}
+			value = make([]rune, 0)
+		}
+	}
+
+	end := func(pos int) {
+		if tok == nil {
+			return
+		}
+
+		tok.End = pos
+		tok.Value = string(value)
+		tokens = append(tokens, *tok)
+		tok = nil
+	}
+
+	for pos := 0; pos < len(line); pos++ {
+		char := line[pos]
+
+		var next rune
+		if pos+1 < len(line) {
+			next = line[pos+1]
+		}
+
+		switch {
+		// Everything is literal within single quotes.
+		case quote == singleChar:
+			if char == singleChar {
+				quote, tok.Quote = 0, -1
+			} else {
+				value = append(value, char)
+			}
+
+		case char == escapeChar:
+			begin(pos)
+
+			if pos+1 == len(line) {
+				continue
+			}
+
+			pos++
+
+			if quote == doubleChar && !strings.ContainsRune(doubleEscapeChars, next) {
+				value = append(value, char)
+			}
+
+			if next != '\n' {
+				value = append(value, next)
+			}
+
+		// Command substitutions
+		case char == '$' && next == '(':
+			begin(pos)
+			value = append(value, char, next)
+			closer = append(closer, ')')
+			substs = append(substs, pos+2)
+			pos++
+
+		case len(closer) > 0 && char == closer[len(closer)-1]:
+			value = append(value, char)
+			closer = closer[:len(closer)-1]
+			substs = substs[:len(substs)-1]
+
+		case char == '`':
+			begin(pos)
+			value = append(value, char)
+			closer = append(closer, '`')
+			substs = append(substs, pos+1)
+
+		case len(closer) > 0:
+			value = append(value, char)
+
+		// Double quotes
+		case quote == doubleChar:
+			if char == doubleChar {
+				quote, tok.Quote = 0, -1
+			} else {
+				value = append(value, char)
+			}
+
+		case char == singleChar || char == doubleChar:
+			begin(pos)
+			quote, tok.Quote = char, pos+1
+
+		case unicode.IsSpace(char):
+			end(pos)
+
+		// A word made of digits right before a redirection
+		// is its file descriptor number (like 2>).
+		case strings.ContainsRune(operatorChars, char):
+			start := pos
+
+			if tok != nil && (char == '<' || char == '>') && isDigits(line[tok.Start:pos]) {
+				start, tok = tok.Start, nil
+			} else {
+				end(pos)
+			}
+
+			operator := operatorToken(line, start, pos)
+			tokens = append(tokens, operator)
+			pos = operator.End - 1
+
+		default:
+			begin(pos)
+			value = append(value, char)
+		}
+	}
+
+	end(len(line))
+
+	return tokens, substs
+}
+
+// operatorToken returns the longest control or redirection operator
+// starting at pos, including any file descriptor number starting at start.
+func operatorToken(line []rune, start, pos int) Token {
+	kind := TokenControl
+	operator := string(line[pos])
+
+	for _, op := range redirectOperators {
+		if strings.HasPrefix(string(line[pos:]), op) {
+			kind, operator = TokenRedirect, op
+			break
+		}
+	}
+
+	if kind == TokenControl {
+		for _, op := range controlOperators {
+			if strings.HasPrefix(string(line[pos:]), op) {
+				operator = op
+				break
+			}
+		}
+	}
+
+	end := pos + len([]rune(operator))
+
+	return Token{
+		Kind:  kind,
+		Value: string(line[start:end]),
+		Start: start,
+		End:   end,
+		Quote: -1,
+	}
+}
+
+func isDigits(word []rune) bool {
+	if len(word) == 0 {
+		return false
+	}
+
+	for _, char := range word {
+		if !unicode.IsDigit(char) {
+			return false
+		}
+	}
+
+	return true
+}
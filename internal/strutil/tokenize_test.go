@@ -0,0 +1,167 @@
+package strutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func word(value string, start, end int) Token {
+	return Token{Kind: TokenWord, Value: value, Start: start, End: end, Quote: -1}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []Token
+	}{
+		{
+			name: "Words",
+			line: "ls  -la dir",
+			want: []Token{word("ls", 0, 2), word("-la", 4, 7), word("dir", 8, 11)},
+		},
+		{
+			name: "Quotes and escapes",
+			line: `echo "a b"c 'd\e' f\ g`,
+			want: []Token{word("echo", 0, 4), word("a bc", 5, 11), word(`d\e`, 12, 17), word("f g", 18, 22)},
+		},
+		{
+			name: "Escapes in double quotes",
+			line: `"a\$b\c"`,
+			want: []Token{word(`a$b\c`, 0, 8)},
+		},
+		{
+			name: "Escaped newline",
+			line: "a\\\nb",
+			want: []Token{word("ab", 0, 4)},
+		},
+		{
+			name: "Unclosed quote",
+			line: `echo "ab`,
+			want: []Token{word("echo", 0, 4), {Kind: TokenWord, Value: "ab", Start: 5, End: 8, Quote: 6}},
+		},
+		{
+			name: "Control operators",
+			line: "a|b&&c ;d",
+			want: []Token{
+				word("a", 0, 1), {Kind: TokenControl, Value: "|", Start: 1, End: 2, Quote: -1},
+				word("b", 2, 3), {Kind: TokenControl, Value: "&&", Start: 3, End: 5, Quote: -1},
+				word("c", 5, 6), {Kind: TokenControl, Value: ";", Start: 7, End: 8, Quote: -1},
+				word("d", 8, 9),
+			},
+		},
+		{
+			name: "Redirections",
+			line: "cmd 2>err >>out <in",
+			want: []Token{
+				word("cmd", 0, 3),
+				{Kind: TokenRedirect, Value: "2>", Start: 4, End: 6, Quote: -1}, word("err", 6, 9),
+				{Kind: TokenRedirect, Value: ">>", Start: 10, End: 12, Quote: -1}, word("out", 12, 15),
+				{Kind: TokenRedirect, Value: "<", Start: 16, End: 17, Quote: -1}, word("in", 17, 19),
+			},
+		},
+		{
+			name: "Command substitutions",
+			line: "echo $(ls -a|wc) `pwd` x",
+			want: []Token{word("echo", 0, 4), word("$(ls -a|wc)", 5, 16), word("`pwd`", 17, 22), word("x", 23, 24)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Tokenize([]rune(test.line)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Tokenize(%q) = %+v, want %+v", test.line, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []Token
+	}{
+		{
+			name: "Current word",
+			line: "git commit -m",
+			want: []Token{word("git", 0, 3), word("commit", 4, 10), word("-m", 11, 13)},
+		},
+		{
+			name: "After control operator",
+			line: "ls | grep ",
+			want: []Token{word("grep", 5, 9), word("", 10, 10)},
+		},
+		{
+			name: "Without redirections",
+			line: "cat <in file > ",
+			want: []Token{word("cat", 0, 3), word("file", 8, 12), word("", 15, 15)},
+		},
+		{
+			name: "Redirection target",
+			line: "ls > fi",
+			want: []Token{word("ls", 0, 2), word("fi", 5, 7)},
+		},
+		{
+			name: "Unclosed quote",
+			line: `echo "a b`,
+			want: []Token{word("echo", 0, 4), {Kind: TokenWord, Value: "a b", Start: 5, End: 9, Quote: 6}},
+		},
+		{
+			name: "Unclosed substitution",
+			line: `echo $(git log "--on`,
+			want: []Token{word("git", 7, 10), word("log", 11, 14), {Kind: TokenWord, Value: "--on", Start: 15, End: 20, Quote: 16}},
+		},
+		{
+			name: "Closed substitution",
+			line: "echo $(ls) ",
+			want: []Token{word("echo", 0, 4), word("$(ls)", 5, 10), word("", 11, 11)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CommandArgs([]rune(test.line)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("CommandArgs(%q) = %+v, want %+v", test.line, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCommandEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		pos  int
+		want int
+	}{
+		{name: "Control operator", line: "ls -l | grep x", pos: 2, want: 6},
+		{name: "Last command", line: "ls -l | grep x", pos: 8, want: 14},
+		{name: "Quoted operator", line: `ls "a|b"; x`, pos: 0, want: 8},
+		{name: "Redirection", line: "a 2>&1 && b", pos: 0, want: 7},
+		{name: "Operator in substitution", line: "echo $(ls | wc) x", pos: 7, want: 10},
+		{name: "Substitution end", line: "echo $(ls | wc) x", pos: 12, want: 14},
+		{name: "Around substitution", line: "echo $(ls | wc) x", pos: 0, want: 17},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CommandEnd([]rune(test.line), test.pos); got != test.want {
+				t.Errorf("CommandEnd(%q, %d) = %d, want %d", test.line, test.pos, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeWord(t *testing.T) {
+	word := `a b'c$(d)`
+	want := `a\ b\'c\$\(d\)`
+
+	if got := EscapeWord(word); got != want {
+		t.Errorf("EscapeWord(%q) = %q, want %q", word, got, want)
+	}
+
+	if got := Tokenize([]rune(want)); len(got) != 1 || got[0].Value != word {
+		t.Errorf("Tokenize(EscapeWord(%q)) = %+v, want the word", word, got)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+// +build !unix
+
+package term
+
+// cellSize returns the default size of terminal cells in pixels,
+// since the terminal cannot be queried for it on this system.
+func cellSize() (width, height int) {
+	return defaultCellWidth, defaultCellHeight
+}
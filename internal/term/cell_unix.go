@@ -0,0 +1,19 @@
+//go:build unix
+// +build unix
+
+package term
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// cellSize returns the size of the terminal cells in pixels, computed from the
+// size of the process terminal, or a default size if the latter is unknown.
+func cellSize() (width, height int) {
+	size, err := unix.IoctlGetWinsize(int(stdoutTerm.Fd()), unix.TIOCGWINSZ)
+	if err != nil || size.Xpixel == 0 || size.Ypixel == 0 || size.Col == 0 || size.Row == 0 {
+		return defaultCellWidth, defaultCellHeight
+	}
+
+	return int(size.Xpixel / size.Col), int(size.Ypixel / size.Row)
+}
@@ -0,0 +1,247 @@
+package term
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/png"
+	"os"
+	"strings"
+)
+
+// Image protocols, with which terminals display images in their cells.
+const (
+	ImageKitty = "kitty" // The kitty graphics protocol (kitty, WezTerm, ghostty, etc).
+	ImageSixel = "sixel" // Sixel graphics (xterm -ti vt340, foot, mlterm, etc).
+)
+
+// kittyChunkSize is the maximum size of image data sent in one kitty command,
+// and kittyImageID identifies the image displayed by the shell, to delete it.
+const (
+	kittyChunkSize = 4096
+	kittyImageID   = 7263
+)
+
+// Size of terminal cells in pixels, when it cannot be queried.
+const (
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+)
+
+// ImageProtocol returns the image protocol to use, from the image-protocol
+// option: either "kitty" or "sixel", or "auto" to detect the one supported
+// by the terminal from its environment. Returns an empty string if none is.
+func ImageProtocol(setting string) string {
+	switch setting {
+	case ImageKitty, ImageSixel:
+		return setting
+	case "auto":
+		return detectImageProtocol()
+	default:
+		return ""
+	}
+}
+
+// detectImageProtocol guesses the image protocol supported by the terminal,
+// since querying it would mix its answers with the user input keys.
+func detectImageProtocol() string {
+	name, program := os.Getenv("TERM"), os.Getenv("TERM_PROGRAM")
+
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", name == "xterm-kitty", name == "xterm-ghostty",
+		program == "WezTerm", program == "ghostty":
+		return ImageKitty
+	case strings.Contains(name, "sixel"), name == "foot", strings.HasPrefix(name, "foot-"),
+		strings.HasPrefix(name, "mlterm"), name == "yaft-256color":
+		return ImageSixel
+	default:
+		return ""
+	}
+}
+
+// ImageSequence returns the escape sequence displaying the image with the protocol,
+// scaled to fit in a box of cols x rows terminal cells, whose top-left corner is the
+// cursor position. The cursor position after the image depends on the terminal.
+func ImageSequence(img image.Image, cols, rows int, protocol string) string {
+	if img == nil || cols <= 0 || rows <= 0 {
+		return ""
+	}
+
+	switch protocol {
+	case ImageKitty:
+		return kittyImage(img, cols, rows)
+	case ImageSixel:
+		cellWidth, cellHeight := cellSize()
+		return sixelImage(fitImage(img, cols*cellWidth, rows*cellHeight))
+	default:
+		return ""
+	}
+}
+
+// ImageClear returns the escape sequence deleting the image displayed with the
+// protocol, if clearing the screen does not: kitty images are not text cells.
+func ImageClear(protocol string) string {
+	if protocol != ImageKitty {
+		return ""
+	}
+
+	return fmt.Sprintf("\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", kittyImageID)
+}
+
+// kittyImage sends the image as PNG data, in chunks, scaled by the terminal
+// to the cells box. Responses are suppressed, and the cursor is not moved.
+func kittyImage(img image.Image, cols, rows int) string {
+	var data bytes.Buffer
+	if err := png.Encode(&data, img); err != nil {
+		return ""
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data.Bytes())
+
+	var seq strings.Builder
+
+	for first := true; first || encoded != ""; first = false {
+		chunk := encoded[:min(len(encoded), kittyChunkSize)]
+		encoded = encoded[len(chunk):]
+
+		more := 0
+		if encoded != "" {
+			more = 1
+		}
+
+		if first {
+			fmt.Fprintf(&seq, "\x1b_Ga=T,f=100,q=2,C=1,i=%d,c=%d,r=%d,m=%d;%s\x1b\\", kittyImageID, cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&seq, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	return seq.String()
+}
+
+// fitImage scales the image (nearest neighbor) to fit in the box of pixels,
+// keeping its aspect ratio, and reduces its colors to a 256-color palette.
+func fitImage(img image.Image, width, height int) *image.Paletted {
+	bounds := img.Bounds()
+	scale := min(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()))
+	width = max(int(float64(bounds.Dx())*scale), 1)
+	height = max(int(float64(bounds.Dy())*scale), 1)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/width
+			srcY := bounds.Min.Y + y*bounds.Dy()/height
+			scaled.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+
+	paletted := image.NewPaletted(scaled.Bounds(), palette.Plan9[:transparent])
+	draw.FloydSteinberg.Draw(paletted, paletted.Bounds(), scaled, image.Point{})
+
+	// Transparent pixels are not drawn.
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if _, _, _, alpha := scaled.At(x, y).RGBA(); alpha < 0x8000 {
+				paletted.SetColorIndex(x, y, transparent)
+			}
+		}
+	}
+
+	return paletted
+}
+
+// transparent is the palette index of pixels not drawn: images use
+// the first colors of the Plan 9 palette, which are all the others.
+const transparent = 255
+
+// sixelImage encodes the image with sixels: each band of six pixel rows is sent
+// once for each of its colors, with the columns in which they are set, and runs
+// of identical columns are compressed. The image background is left unchanged.
+func sixelImage(img *image.Paletted) string {
+	var seq strings.Builder
+
+	bounds := img.Bounds()
+	fmt.Fprintf(&seq, "\x1bP0;1q\"1;1;%d;%d", bounds.Dx(), bounds.Dy())
+
+	// Define the colors used, in percents.
+	used := make(map[uint8]bool)
+	for _, index := range img.Pix {
+		used[index] = true
+	}
+
+	for index := range img.Palette {
+		if !used[uint8(index)] {
+			continue
+		}
+
+		red, green, blue, _ := img.Palette[index].RGBA()
+		fmt.Fprintf(&seq, "#%d;2;%d;%d;%d", index, red*100/0xffff, green*100/0xffff, blue*100/0xffff)
+	}
+
+	for top := 0; top < bounds.Dy(); top += 6 {
+		for index := range img.Palette {
+			if !used[uint8(index)] {
+				continue
+			}
+
+			if band, drawn := sixelBand(img, top, uint8(index)); drawn {
+				fmt.Fprintf(&seq, "#%d%s$", index, band)
+			}
+		}
+
+		seq.WriteString("-")
+	}
+
+	seq.WriteString("\x1b\\")
+
+	return seq.String()
+}
+
+// sixelBand returns the sixels of a color in the band starting at the top row,
+// and false if the color is not used in it.
+func sixelBand(img *image.Paletted, top int, index uint8) (band string, drawn bool) {
+	var seq strings.Builder
+
+	width := img.Bounds().Dx()
+	last, count := byte(0), 0
+
+	flush := func() {
+		switch {
+		case count > 3:
+			fmt.Fprintf(&seq, "!%d%c", count, last)
+		case count > 0:
+			seq.WriteString(strings.Repeat(string(last), count))
+		}
+	}
+
+	for x := 0; x < width; x++ {
+		bits := byte(0)
+
+		for bit := 0; bit < 6 && top+bit < img.Bounds().Dy(); bit++ {
+			if img.ColorIndexAt(x, top+bit) == index {
+				bits |= 1 << bit
+			}
+		}
+
+		drawn = drawn || bits != 0
+		char := '?' + bits
+
+		if char == last {
+			count++
+			continue
+		}
+
+		flush()
+
+		last, count = char, 1
+	}
+
+	flush()
+
+	return seq.String(), drawn
+}
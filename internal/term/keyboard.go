@@ -0,0 +1,41 @@
+package term
+
+import (
+	"fmt"
+)
+
+// Keyboard protocols, enhancing the legacy encoding of keys.
+const (
+	kittyKeysPush        = "\x1b[>3u" // Disambiguate escape codes, report event types.
+	kittyKeysPop         = "\x1b[<u"
+	modifyOtherKeysSet   = "\x1b[>4;2m"
+	modifyOtherKeysReset = "\x1b[>4m"
+)
+
+// EnableKeyboardProtocol asks the terminal to encode keys with an enhanced
+// keyboard protocol, either "kitty" (the kitty progressive enhancement one),
+// "modify-other-keys" (xterm), or "auto" for both, only one of which will be
+// honored by terminals supporting it. Other terminals ignore those requests,
+// and keep sending legacy keys. Returns a function restoring the previous
+// keyboard mode (doing nothing for the "legacy" protocol).
+func (t *Terminal) EnableKeyboardProtocol(protocol string) (restore func()) {
+	var enable, disable string
+
+	switch protocol {
+	case "kitty":
+		enable, disable = kittyKeysPush, kittyKeysPop
+	case "modify-other-keys":
+		enable, disable = modifyOtherKeysSet, modifyOtherKeysReset
+	case "auto":
+		enable = kittyKeysPush + modifyOtherKeysSet
+		disable = modifyOtherKeysReset + kittyKeysPop
+	default:
+		return func() {}
+	}
+
+	fmt.Fprint(t.Output, enable)
+
+	return func() {
+		fmt.Fprint(t.Output, disable)
+	}
+}
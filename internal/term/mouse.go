@@ -0,0 +1,26 @@
+package term
+
+import (
+	"fmt"
+)
+
+// Mouse reporting modes.
+const (
+	mouseReportEnable  = "\x1b[?1000h\x1b[?1006h" // Button events, SGR encoding.
+	mouseReportDisable = "\x1b[?1006l\x1b[?1000l"
+)
+
+// EnableMouse asks the terminal to report mouse button and wheel
+// events (SGR-encoded) if enable is true, and returns a function
+// disabling them (doing nothing if enable is false).
+func (t *Terminal) EnableMouse(enable bool) (restore func()) {
+	if !enable {
+		return func() {}
+	}
+
+	fmt.Fprint(t.Output, mouseReportEnable)
+
+	return func() {
+		fmt.Fprint(t.Output, mouseReportDisable)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+// +build !unix
+
+package term
+
+import "errors"
+
+// ErrSuspend is returned when the process cannot be suspended.
+var ErrSuspend = errors.New("cannot suspend: no job control")
+
+// Suspend does nothing, since job control is not available on this system.
+func Suspend() error {
+	return ErrSuspend
+}
@@ -0,0 +1,36 @@
+//go:build unix
+// +build unix
+
+package term
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ErrSuspend is returned when the process cannot be suspended.
+var ErrSuspend = errors.New("cannot suspend: no job control")
+
+// Suspend stops the process group, as the terminal would do when Ctrl-Z is pressed
+// in cooked mode, and returns once the process is continued (eg. with `fg`). Since
+// the stop signal is ignored in orphaned process groups, those are not suspended.
+func Suspend() error {
+	if os.Getppid() == 1 {
+		return ErrSuspend
+	}
+
+	cont := make(chan os.Signal, 1)
+	signal.Notify(cont, syscall.SIGCONT)
+
+	defer signal.Stop(cont)
+
+	if err := syscall.Kill(0, syscall.SIGTSTP); err != nil {
+		return err
+	}
+
+	<-cont
+
+	return nil
+}
@@ -2,6 +2,7 @@ package term
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"golang.org/x/term"
@@ -25,11 +26,42 @@ func init() {
 // fallback terminal width when we can't get it through query.
 var defaultTermWidth = 80
 
-// GetWidth returns the width of Stdout or 80 if the width cannot be established.
-func GetWidth() (termWidth int) {
+// Terminal is the terminal on which a shell is displayed: everything the shell
+// displays is written to its output, and its size is used to lay out the display.
+// Each shell has its own terminal, which is shared by all the shell components,
+// so that several shells can be displayed at once on different terminals.
+type Terminal struct {
+	// Output is the writer to which the shell writes everything it displays.
+	// It is the process standard output by default, but can be any writer,
+	// such as an SSH channel, a PTY or an in-memory buffer in tests.
+	Output io.Writer
+
+	// SizeFunc returns the size of the terminal, when it is not the one
+	// of the process (eg. the size of a remote SSH client). If nil, the
+	// size of the process terminal is used.
+	SizeFunc func() (width, height int, err error)
+}
+
+// NewTerminal returns a terminal writing to the process standard output.
+func NewTerminal() *Terminal {
+	return &Terminal{Output: os.Stdout}
+}
+
+// Write writes to the terminal output.
+func (t *Terminal) Write(buf []byte) (int, error) {
+	return t.Output.Write(buf)
+}
+
+// GetWidth returns the width of the terminal, or 80 if it cannot be established.
+func (t *Terminal) GetWidth() (termWidth int) {
 	var err error
-	fd := int(stdoutTerm.Fd())
-	termWidth, _, err = GetSize(fd)
+
+	if t.SizeFunc != nil {
+		termWidth, _, err = t.SizeFunc()
+	} else {
+		fd := int(stdoutTerm.Fd())
+		termWidth, _, err = GetSize(fd)
+	}
 
 	if err != nil || termWidth == 0 {
 		termWidth = defaultTermWidth
@@ -40,8 +72,15 @@ func GetWidth() (termWidth int) {
 
 // GetLength returns the length of the terminal
 // (Y length), or 80 if it cannot be established.
-func GetLength() int {
-	_, length, err := term.GetSize(0)
+func (t *Terminal) GetLength() int {
+	var length int
+	var err error
+
+	if t.SizeFunc != nil {
+		_, length, err = t.SizeFunc()
+	} else {
+		_, length, err = term.GetSize(0)
+	}
 
 	if err != nil || length == 0 {
 		return defaultTermWidth
@@ -50,7 +89,7 @@ func GetLength() int {
 	return length
 }
 
-func printf(format string, a ...interface{}) {
+func (t *Terminal) printf(format string, a ...interface{}) {
 	s := fmt.Sprintf(format, a...)
-	fmt.Print(s)
+	fmt.Fprint(t.Output, s)
 }
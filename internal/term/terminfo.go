@@ -0,0 +1,174 @@
+package term
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Magic numbers of compiled terminfo entries, with 16-bit or 32-bit numbers.
+const (
+	terminfoMagic   = 0o432
+	terminfoMagic32 = 0o1036
+)
+
+var errTerminfoFormat = errors.New("invalid terminfo entry")
+
+// terminfoKeys are the indexes of the key capabilities in the
+// string capabilities of compiled terminfo entries (see term.h).
+var terminfoKeys = map[string]int{
+	"kbs":   55,
+	"kdch1": 59,
+	"kcud1": 61,
+	"kf1":   66,
+	"kf10":  67,
+	"kf2":   68,
+	"kf3":   69,
+	"kf4":   70,
+	"kf5":   71,
+	"kf6":   72,
+	"kf7":   73,
+	"kf8":   74,
+	"kf9":   75,
+	"khome": 76,
+	"kich1": 77,
+	"kcub1": 79,
+	"knp":   81,
+	"kpp":   82,
+	"kcuf1": 83,
+	"kcuu1": 87,
+	"kcbt":  148,
+	"kend":  164,
+	"kf11":  216,
+	"kf12":  217,
+}
+
+// TerminfoKeys returns the sequences sent by special keys (arrows, Home/End,
+// function keys, keypad, etc) in a terminal, as found in its terminfo entry,
+// mapped to their capability names (kcuu1, khome, kf1, etc).
+// Returns nil if the terminal has no (valid) terminfo entry.
+func TerminfoKeys(name string) map[string]string {
+	data, err := readTerminfo(name)
+	if err != nil {
+		return nil
+	}
+
+	caps, err := parseTerminfo(data)
+	if err != nil {
+		return nil
+	}
+
+	keys := make(map[string]string)
+
+	for key, index := range terminfoKeys {
+		if index < len(caps) && caps[index] != "" {
+			keys[key] = caps[index]
+		}
+	}
+
+	return keys
+}
+
+// readTerminfo reads the compiled terminfo entry of a terminal,
+// searching the directories in the same order as ncurses does.
+func readTerminfo(name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return nil, os.ErrNotExist
+	}
+
+	var dirs []string
+
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+
+	for _, dir := range strings.Split(os.Getenv("TERMINFO_DIRS"), ":") {
+		if dir == "" {
+			dir = "/usr/share/terminfo"
+		}
+
+		dirs = append(dirs, dir)
+	}
+
+	dirs = append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo")
+
+	// Entries are either in a directory named after the first
+	// letter of the terminal name, or its hexadecimal code.
+	for _, dir := range dirs {
+		for _, sub := range []string{name[:1], strings.ToLower(hexByte(name[0]))} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, name))
+			if err == nil {
+				return data, nil
+			}
+		}
+	}
+
+	return nil, os.ErrNotExist
+}
+
+// parseTerminfo returns the string capabilities of a compiled terminfo entry.
+// Absent or cancelled capabilities are empty strings.
+func parseTerminfo(data []byte) ([]string, error) {
+	if len(data) < 12 {
+		return nil, errTerminfoFormat
+	}
+
+	header := make([]int, 6)
+	for i := range header {
+		header[i] = int(int16(binary.LittleEndian.Uint16(data[i*2:])))
+	}
+
+	numSize := 2
+
+	switch header[0] {
+	case terminfoMagic:
+	case terminfoMagic32:
+		numSize = 4
+	default:
+		return nil, errTerminfoFormat
+	}
+
+	namesSize, boolCount, numCount, strCount, tableSize := header[1], header[2], header[3], header[4], header[5]
+
+	// Numbers are aligned on an even byte.
+	offsets := 12 + namesSize + boolCount
+	if offsets%2 != 0 {
+		offsets++
+	}
+
+	offsets += numCount * numSize
+	table := offsets + strCount*2
+
+	if namesSize < 0 || boolCount < 0 || numCount < 0 || strCount < 0 || tableSize < 0 || table+tableSize > len(data) {
+		return nil, errTerminfoFormat
+	}
+
+	caps := make([]string, strCount)
+
+	for i := range caps {
+		offset := int(int16(binary.LittleEndian.Uint16(data[offsets+i*2:])))
+		if offset < 0 || offset >= tableSize {
+			continue
+		}
+
+		str := data[table+offset : table+tableSize]
+		if end := strings.IndexByte(string(str), 0); end >= 0 {
+			str = str[:end]
+		}
+
+		caps[i] = string(str)
+	}
+
+	return caps, nil
+}
+
+func hexByte(char byte) string {
+	const digits = "0123456789ABCDEF"
+	return string([]byte{digits[char>>4], digits[char&0x0f]})
+}
@@ -0,0 +1,27 @@
+package term
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileURL returns the file:// URL of a path on this host, as used by terminals
+// for working directory reports (OSC 7) and hyperlinks (OSC 8). Relative paths
+// are made absolute from the working directory of the process.
+func FileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	host, _ := os.Hostname()
+	file := url.URL{Scheme: "file", Host: host, Path: path}
+
+	return file.String()
+}
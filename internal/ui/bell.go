@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// The visible bell flashes the screen in reverse video for a short time.
+const (
+	audibleBell         = "\a"
+	visibleBellOn       = "\x1b[?5h"
+	visibleBellOff      = "\x1b[?5l"
+	visibleBellDuration = 100 * time.Millisecond
+)
+
+// Bell notifies users that a command failed or could not be performed (eg. no
+// completion matches, a failed search or motion, an undefined key), either by
+// ringing the terminal bell, flashing the screen, or not at all, as per the
+// bell-style option (audible, visible or none).
+type Bell struct {
+	term *term.Terminal
+	opts *inputrc.Config
+	hook func()
+}
+
+// NewBell returns a bell using the bell-style option of the configuration.
+func NewBell(t *term.Terminal, opts *inputrc.Config) *Bell {
+	return &Bell{term: t, opts: opts}
+}
+
+// OnRing sets a function called each time the bell rings, regardless of its style.
+func (b *Bell) OnRing(hook func()) {
+	b.hook = hook
+}
+
+// Ring rings the bell. It does nothing if the bell is nil.
+func (b *Bell) Ring() {
+	if b == nil {
+		return
+	}
+
+	if b.hook != nil {
+		b.hook()
+	}
+
+	switch b.opts.GetString("bell-style") {
+	case "audible":
+		fmt.Fprint(b.term, audibleBell)
+	case "visible":
+		fmt.Fprint(b.term, visibleBellOn)
+		time.Sleep(visibleBellDuration)
+		fmt.Fprint(b.term, visibleBellOff)
+	}
+}
@@ -2,8 +2,11 @@ package ui
 
 import (
 	"fmt"
+	"image"
 	"strings"
+	"time"
 
+	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
@@ -11,13 +14,51 @@ import (
 
 // Hint is in charge of printing the usage messages below the input line.
 // Various other UI components have access to it so that they can feed
-// specialized usage messages to it, like completions.
+// specialized usage messages to it, like completions. Applications have
+// their own message section, which the shell does not reset. All messages
+// can span multiple lines.
 type Hint struct {
 	text       []rune
 	persistent []rune
+	message    message
+	image      *Image
 	cleanup    bool
 	temp       bool
 	set        bool
+	term       *term.Terminal
+	styles     *color.Styles
+	opts       *inputrc.Config
+	expire     func()
+}
+
+// message is the hint message of the application, and when it expires.
+type message struct {
+	text    []rune
+	temp    bool // Expires at the next keypress.
+	shown   bool
+	expires time.Time
+}
+
+// HintLevel is the level of a hint message, which determines its style.
+type HintLevel int
+
+// Hint levels.
+const (
+	HintInfo    HintLevel = iota // Informational hints (the hint theme style).
+	HintWarning                  // Warnings (the warning theme style).
+	HintError                    // Errors (the error theme style).
+)
+
+// NewHint returns a hint section using the styles of the shell,
+// and the hint-timeout option of the configuration.
+func NewHint(t *term.Terminal, styles *color.Styles, opts *inputrc.Config) *Hint {
+	return &Hint{term: t, styles: styles, opts: opts}
+}
+
+// OnExpire sets a function called (from another goroutine) when a hint
+// message expires, so that the display can be refreshed without it.
+func (h *Hint) OnExpire(expire func()) {
+	h.expire = expire
 }
 
 // Set sets the hint message to the given text.
@@ -36,12 +77,69 @@ func (h *Hint) SetTemporary(hint string) {
 	h.temp = true
 }
 
+// SetLevel sets the message of the application, with the style of its level,
+// displayed above other hints. The shell does not reset it, but as per the
+// hint-timeout option, it expires after as many seconds if positive, at the
+// next keypress if negative, or when reset with ResetLevel if zero (the default).
+func (h *Hint) SetLevel(level HintLevel, hint string) {
+	h.message = message{text: []rune(h.levelStyle(level) + hint + color.Reset)}
+
+	timeout := 0
+	if h.opts != nil {
+		timeout = h.opts.GetInt("hint-timeout")
+	}
+
+	switch {
+	case timeout < 0:
+		h.message.temp = true
+	case timeout > 0:
+		delay := time.Duration(timeout) * time.Second
+		h.message.expires = time.Now().Add(delay)
+
+		if h.expire != nil {
+			time.AfterFunc(delay, h.expire)
+		}
+	}
+}
+
+// ResetLevel removes the message of the application.
+func (h *Hint) ResetLevel() {
+	h.cleanup = h.cleanup || len(h.message.text) > 0
+	h.message = message{}
+}
+
 // Persist adds a hint message to be persistently
 // displayed until hint.ResetPersist() is called.
 func (h *Hint) Persist(hint string) {
 	h.persistent = []rune(hint)
 }
 
+// PersistLevel adds a persistent hint message with the style of its level.
+func (h *Hint) PersistLevel(level HintLevel, hint string) {
+	h.Persist(h.levelStyle(level) + hint)
+}
+
+// Image is an image displayed in the hint section, below its text (eg. the preview
+// of a file), with the image protocol of the terminal (see the image-protocol option).
+// It is scaled to fit in a box of Cols x Rows cells, which it uses in the display.
+// Terminals not supporting images display the Alt text instead, if any.
+type Image struct {
+	Image image.Image
+	Cols  int
+	Rows  int
+	Alt   string
+}
+
+// SetImage sets an image displayed below the hint text, until the hint is reset.
+func (h *Hint) SetImage(img *Image) {
+	h.image = img
+}
+
+// Image returns the image displayed below the hint text, if any.
+func (h *Hint) Image() *Image {
+	return h.image
+}
+
 // Text returns the current hint text.
 func (h *Hint) Text() string {
 	return string(h.text)
@@ -58,6 +156,7 @@ func (h *Hint) Len() int {
 // Reset removes the hint message.
 func (h *Hint) Reset() {
 	h.text = make([]rune, 0)
+	h.image = nil
 	h.temp = false
 	h.set = false
 }
@@ -76,9 +175,16 @@ func DisplayHint(hint *Hint) {
 		hint.Reset()
 	}
 
-	if len(hint.text) == 0 && len(hint.persistent) == 0 {
+	msg := &hint.message
+	if (msg.temp && msg.shown) || (!msg.expires.IsZero() && !time.Now().Before(msg.expires)) {
+		hint.ResetLevel()
+	}
+
+	msg.shown = true
+
+	if len(hint.text) == 0 && len(hint.persistent) == 0 && len(msg.text) == 0 {
 		if hint.cleanup {
-			fmt.Print(term.ClearLineAfter)
+			fmt.Fprint(hint.term, term.ClearLineAfter)
 		}
 
 		hint.cleanup = false
@@ -95,17 +201,21 @@ func DisplayHint(hint *Hint) {
 	text += term.ClearLineAfter + color.Reset
 
 	if len(text) > 0 {
-		fmt.Print(text)
+		fmt.Fprint(hint.term, text)
 	}
 }
 
 func (h *Hint) renderHint() (text string) {
+	if len(h.message.text) > 0 {
+		text += hintLines(h.message.text) + term.NewlineReturn
+	}
+
 	if len(h.persistent) > 0 {
-		text += string(h.persistent) + term.NewlineReturn
+		text += hintLines(h.persistent) + term.NewlineReturn
 	}
 
 	if len(h.text) > 0 {
-		text += string(h.text) + term.NewlineReturn
+		text += hintLines(h.text) + term.NewlineReturn
 	}
 
 	if strutil.RealLength(text) == 0 {
@@ -134,7 +244,7 @@ func CoordinatesHint(hint *Hint) int {
 	lines := strings.Split(text, term.ClearLineAfter)
 
 	for i, line := range lines {
-		x, y := strutil.LineSpan([]rune(line), i, 0)
+		x, y := strutil.LineSpan([]rune(line), i, 0, hint.term.GetWidth())
 		if x != 0 {
 			y++
 		}
@@ -144,3 +254,23 @@ func CoordinatesHint(hint *Hint) int {
 
 	return usedY
 }
+
+// hintLines returns a hint message with all its newlines
+// (with or without carriage returns) displayed as such.
+func hintLines(hint []rune) string {
+	text := strings.ReplaceAll(string(hint), term.NewlineReturn, "\n")
+
+	return strings.ReplaceAll(text, "\n", term.NewlineReturn)
+}
+
+// levelStyle returns the theme style of a hint level.
+func (h *Hint) levelStyle(level HintLevel) string {
+	switch level {
+	case HintWarning:
+		return h.styles.Warning
+	case HintError:
+		return h.styles.Error
+	default:
+		return h.styles.Hint
+	}
+}
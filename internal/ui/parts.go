@@ -0,0 +1,117 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// Part is a part of a prompt (eg. a username, a path or a git status), displayed
+// in full when the terminal is wide enough. On narrow terminals, the parts of a
+// prompt line with the lowest priority are dropped first, unless they can be
+// truncated: in this case, they are shortened (with an ellipsis) to fit instead.
+// A part ending with a newline ends a line of the prompt: each line is fitted on
+// its own. Parts should not contain other newlines.
+type Part struct {
+	Text     string // The text of the part, which can contain escape sequences.
+	Priority int    // Parts with lower priorities are dropped first.
+	Truncate bool   // The part is shortened rather than dropped, if it can be.
+}
+
+// PrimaryParts uses a function returning the parts of the primary prompt, which
+// is fitted to the terminal width on each display: the last line of the prompt
+// leaves at least prompt-min-line-width columns to the input line.
+func (p *Prompt) PrimaryParts(parts func() []Part) {
+	p.primaryF = func() string {
+		return fitParts(parts(), p.term.GetWidth(), p.lastLineWidth())
+	}
+}
+
+// RightParts uses a function returning the parts of the right prompt, which is
+// fitted on each display in the columns left after the end of the input line.
+func (p *Prompt) RightParts(parts func() []Part) {
+	p.rightParts = parts
+	p.rightF = nil
+}
+
+// lastLineWidth returns the maximum width of the last line of the primary
+// prompt, leaving room to the input line (at least half of the terminal).
+func (p *Prompt) lastLineWidth() int {
+	width := p.term.GetWidth()
+
+	return max(width-p.opts.GetInt("prompt-min-line-width"), width/2)
+}
+
+// fitParts joins the parts of a prompt, dropping or truncating those of each
+// line so that it fits in width columns, and the last one in lastWidth columns.
+func fitParts(parts []Part, width, lastWidth int) string {
+	var lines []string
+
+	start := 0
+
+	for i, part := range parts {
+		if strings.HasSuffix(part.Text, "\n") {
+			lines = append(lines, fitLine(parts[start:i+1], width))
+			start = i + 1
+		}
+	}
+
+	lines = append(lines, fitLine(parts[start:], lastWidth))
+
+	return strings.Join(lines, "")
+}
+
+// fitLine joins the parts of a prompt line, dropping the ones with the lowest
+// priority (the last ones first, when equal) until they fit in width columns,
+// or truncating the first of them that can be instead. When a single part is
+// left and still does not fit, it is truncated anyway.
+func fitLine(parts []Part, width int) string {
+	texts := make([]string, len(parts))
+	widths := make([]int, len(parts))
+	total := 0
+
+	for i, part := range parts {
+		texts[i] = strings.TrimSuffix(part.Text, "\n")
+		widths[i] = strutil.RealLength(texts[i])
+		total += widths[i]
+	}
+
+	dropped := make([]bool, len(parts))
+	kept := len(parts)
+
+	for total > width && kept > 0 {
+		lowest := -1
+
+		for i, part := range parts {
+			if !dropped[i] && (lowest == -1 || part.Priority <= parts[lowest].Priority) {
+				lowest = i
+			}
+		}
+
+		excess := total - width
+
+		if (parts[lowest].Truncate && widths[lowest]-excess > 1) || kept == 1 {
+			texts[lowest] = color.Truncate(texts[lowest], widths[lowest]-excess)
+			break
+		}
+
+		dropped[lowest] = true
+		total -= widths[lowest]
+		kept--
+	}
+
+	var line strings.Builder
+
+	for i, text := range texts {
+		if !dropped[i] {
+			line.WriteString(text)
+		}
+
+		if strings.HasSuffix(parts[i].Text, "\n") {
+			line.WriteString("\n")
+		}
+	}
+
+	return line.String()
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
@@ -20,27 +23,42 @@ type Prompt struct {
 	primaryCols int
 
 	secondaryF func() string
-	transientF func() string
+	transientF func(line Accepted) Transient
 	rightF     func() string
+	rightParts func() []Part
 	tooltipF   func() string
 
+	// Status of the last command, set by the caller.
+	status    int
+	duration  time.Duration
+	statusSet bool
+
 	// True if some logs have printed asynchronously
 	// since last loop. Check refresh prompt funcs.
 	refreshing bool
 
+	// Segments computed in the background.
+	segments segments
+
 	// Shell parameters
+	term    *term.Terminal
+	keys    *core.Keys
 	line    *core.Line
 	cursor  *core.Cursor
 	keymaps *keymap.Engine
+	styles  *color.Styles
 	opts    *inputrc.Config
 }
 
 // NewPrompt is a required constructor to initialize the prompt system.
-func NewPrompt(line *core.Line, cursor *core.Cursor, keymaps *keymap.Engine, opts *inputrc.Config) *Prompt {
+func NewPrompt(t *term.Terminal, keys *core.Keys, line *core.Line, cursor *core.Cursor, keymaps *keymap.Engine, styles *color.Styles, opts *inputrc.Config) *Prompt {
 	return &Prompt{
+		term:    t,
+		keys:    keys,
 		line:    line,
 		cursor:  cursor,
 		keymaps: keymaps,
+		styles:  styles,
 		opts:    opts,
 	}
 }
@@ -53,35 +71,135 @@ func (p *Prompt) Primary(prompt func() string) {
 // Right uses a function returning the string to use as the right prompt.
 func (p *Prompt) Right(prompt func() string) {
 	p.rightF = prompt
+	p.rightParts = nil
 }
 
-// Secondary uses a function returning the prompt to use as the secondary prompt.
+// Secondary uses a function returning the prompt to use as the secondary prompt,
+// printed at the beginning of each continuation line of a multiline input buffer.
 func (p *Prompt) Secondary(prompt func() string) {
 	p.secondaryF = prompt
 }
 
 // Transient uses a function returning the prompt to use as a transient prompt.
 func (p *Prompt) Transient(prompt func() string) {
+	if prompt == nil {
+		p.transientF = nil
+		return
+	}
+
+	p.transientF = func(Accepted) Transient {
+		return Transient{Prompt: prompt()}
+	}
+}
+
+// Accepted describes a line returned by the shell, for which
+// a transient prompt (and possibly line) is to be displayed.
+type Accepted struct {
+	Line     string        // The line returned by the shell.
+	Err      error         // The error returned with it, if any (eg. ErrInterrupt).
+	Status   int           // Status of the last command, as set with SetStatus.
+	Duration time.Duration // Duration of the last command, as set with SetStatus.
+}
+
+// Transient is the transient prompt to display in place of the primary one
+// (all of its lines), once a line is returned by the shell. If Line is not
+// empty, it is displayed in place of the input line (eg. a multiline input
+// collapsed to a single line). If Skip is true, the prompt and input line
+// are left as they are.
+type Transient struct {
+	Prompt string
+	Line   string
+	Skip   bool
+}
+
+// TransientLine uses a function returning the transient prompt to display for
+// each accepted line, which can also replace the input line, or be skipped.
+func (p *Prompt) TransientLine(prompt func(line Accepted) Transient) {
 	p.transientF = prompt
 }
 
+// SetStatus sets the exit status and the duration of the last command run
+// by the application, to be passed to the transient prompt function along
+// with the next accepted lines.
+func (p *Prompt) SetStatus(status int, duration time.Duration) {
+	p.status = status
+	p.duration = duration
+	p.statusSet = true
+}
+
+// Status returns the exit status of the last command, and
+// false if it has never been set by the caller.
+func (p *Prompt) Status() (status int, set bool) {
+	return p.status, p.statusSet
+}
+
+// wordEndChars end the word under cursor (along with blanks) for tooltips.
+const wordEndChars = "|&;<>)`"
+
 // Tooltip uses a function returning the prompt to use as a tooltip prompt.
+// The function is passed the name of the command under cursor, parsed with
+// shell syntax (in a pipeline or a command substitution, the innermost one).
 func (p *Prompt) Tooltip(prompt func(word string) string) {
 	if prompt == nil {
 		return
 	}
 
+	p.TooltipArgs(func(args []string, _ int) string {
+		return prompt(args[0])
+	})
+}
+
+// TooltipArgs uses a function returning the prompt to use as a tooltip prompt.
+// The function is passed all the words of the command under cursor, parsed with
+// shell syntax as with Tooltip, and the index of the word under cursor in them
+// (which is empty if the cursor is not on a word, but after a blank), so that
+// the tooltip can describe a subcommand or argument as the cursor moves.
+func (p *Prompt) TooltipArgs(prompt func(args []string, current int) string) {
+	if prompt == nil {
+		return
+	}
+
 	// Wrap the user-provided function into a callback using out input line.
 	p.tooltipF = func() string {
-		var tooltipWord string
+		line := *p.line
+
+		// The word under cursor ends at the next blank or operator.
+		cpos := p.cursor.Pos()
+		for cpos < len(line) && !unicode.IsSpace(line[cpos]) && !strings.ContainsRune(wordEndChars, line[cpos]) {
+			cpos++
+		}
 
-		shellWords := strings.Split(string(*p.line), " ")
+		current := len(strutil.CommandArgs(line[:cpos])) - 1
+
+		// And the command under cursor at the next control operator.
+		end := strutil.CommandEnd(line, cpos)
+		words := strutil.CommandArgs(line[:end])
+
+		if last := words[len(words)-1]; len(words)-1 > current && last.Start == last.End {
+			words = words[:len(words)-1]
+		}
 
-		if len(shellWords) > 0 {
-			tooltipWord = shellWords[0]
+		args := make([]string, 0, len(words))
+		for _, word := range words {
+			args = append(args, word.Value)
 		}
 
-		return prompt(tooltipWord)
+		return prompt(args, min(current, len(args)-1))
+	}
+}
+
+// Swap replaces the primary prompt, removes all other ones, and returns
+// a function restoring them all. This is used when reading passwords.
+func (p *Prompt) Swap(primary func() string) (restore func()) {
+	primaryF, secondaryF, transientF := p.primaryF, p.secondaryF, p.transientF
+	rightF, rightParts, tooltipF := p.rightF, p.rightParts, p.tooltipF
+
+	p.primaryF = primary
+	p.secondaryF, p.transientF, p.rightF, p.rightParts, p.tooltipF = nil, nil, nil, nil, nil
+
+	return func() {
+		p.primaryF, p.secondaryF, p.transientF = primaryF, secondaryF, transientF
+		p.rightF, p.rightParts, p.tooltipF = rightF, rightParts, tooltipF
 	}
 }
 
@@ -89,6 +207,7 @@ func (p *Prompt) Tooltip(prompt func(word string) string) {
 // the last line if the primary prompt spans on several lines.
 func (p *Prompt) PrimaryPrint() {
 	p.refreshing = false
+	SegmentsUpdated(p)
 
 	if p.primaryF == nil {
 		return
@@ -103,10 +222,10 @@ func (p *Prompt) PrimaryPrint() {
 
 	// Print the various lines.
 	if prompt != "" {
-		fmt.Print(prompt)
+		fmt.Fprint(p.term, prompt)
 	}
 
-	fmt.Print(lastPrompt)
+	fmt.Fprint(p.term, lastPrompt)
 
 	// And compute coordinates
 	p.primaryRows = strings.Count(prompt, "\n")
@@ -124,6 +243,18 @@ func (p *Prompt) PrimaryUsed() int {
 	return p.primaryRows
 }
 
+// SecondaryPrompt returns the secondary prompt string, printed at the
+// beginning of each continuation line of a multiline input buffer.
+// It is empty if no secondary prompt is set, in which case these lines
+// are aligned with the first one.
+func (p *Prompt) SecondaryPrompt() string {
+	if p.secondaryF == nil {
+		return ""
+	}
+
+	return p.styles.Degrade(p.secondaryF())
+}
+
 // LastPrint prints the last line of the primary prompt, if the latter
 // spans on several lines. If not, this function will actually print
 // the entire primary prompt, and PrimaryPrint() will not print anything.
@@ -144,7 +275,7 @@ func (p *Prompt) LastPrint() {
 
 	prompt := p.formatLastPrompt(lines[len(lines)-1])
 
-	fmt.Print(prompt)
+	fmt.Fprint(p.term, prompt)
 
 	p.primaryCols = strutil.RealLength(prompt)
 	if p.primaryCols > 0 {
@@ -183,7 +314,12 @@ func (p *Prompt) LastUsed() int {
 // a traditional RPROMPT string, or a tooltip prompt if any must be rendered.
 // If force is true, whatever rprompt or tooltip exists will be printed.
 // If false, only the rprompt, if it exists, will be printed.
+// Nothing is printed in low-bandwidth and screen-reader modes.
 func (p *Prompt) RightPrint(startColumn int, force bool) {
+	if p.opts.GetBool("low-bandwidth") || p.opts.GetBool("screen-reader") {
+		return
+	}
+
 	var rprompt string
 
 	if p.tooltipF != nil && force {
@@ -194,30 +330,52 @@ func (p *Prompt) RightPrint(startColumn int, force bool) {
 		rprompt = p.rightF()
 	}
 
+	if rprompt == "" && p.rightParts != nil {
+		rprompt = fitLine(p.rightParts(), p.rightWidth(startColumn))
+	}
+
 	if rprompt == "" {
 		return
 	}
 
 	if prompt, canPrint := p.formatRightPrompt(rprompt, startColumn); canPrint {
-		fmt.Print(prompt)
+		fmt.Fprint(p.term, prompt)
 	} else {
-		fmt.Print(term.ClearLineAfter)
+		fmt.Fprint(p.term, term.ClearLineAfter)
 	}
 }
 
-// TransientPrint prints the transient prompt.
-func (p *Prompt) TransientPrint() {
+// TransientFor returns the transient prompt to display for the line returned
+// by the shell along with an error, and false if there is no transient prompt.
+func (p *Prompt) TransientFor(line string, err error) (Transient, bool) {
 	if p.transientF == nil {
-		return
+		return Transient{}, false
 	}
 
+	transient := p.transientF(Accepted{
+		Line:     line,
+		Err:      err,
+		Status:   p.status,
+		Duration: p.duration,
+	})
+
+	return transient, !transient.Skip
+}
+
+// TransientPrint prints the transient prompt, and returns the number
+// of terminal columns used by its last line.
+func (p *Prompt) TransientPrint(transient Transient) int {
 	// Clean everything below where the prompt will be printed.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(p.primaryRows)
-	fmt.Print(term.ClearScreenBelow)
+	p.term.MoveCursorBackwards(p.term.GetWidth())
+	p.term.MoveCursorUp(p.primaryRows)
+	fmt.Fprint(p.term, term.ClearScreenBelow)
 
 	// And print the prompt
-	fmt.Print(p.transientF())
+	fmt.Fprint(p.term, transient.Prompt)
+
+	lines := strings.Split(transient.Prompt, "\n")
+
+	return strutil.RealLength(lines[len(lines)-1])
 }
 
 // Refreshing returns true if the prompt is currently redisplaying
@@ -226,9 +384,13 @@ func (p *Prompt) Refreshing() bool {
 	return p.refreshing
 }
 
+// formatLastPrompt adds the editing mode to the last line of the primary
+// prompt, if shown, and truncates it if it leaves no room to the input line.
 func (p *Prompt) formatLastPrompt(prompt string) string {
+	prompt = p.styles.Degrade(prompt)
+
 	if !p.opts.GetBool("show-mode-in-prompt") {
-		return prompt
+		return color.Truncate(prompt, p.lastLineWidth())
 	}
 
 	var status string
@@ -254,24 +416,36 @@ func (p *Prompt) formatLastPrompt(prompt string) string {
 	status = end.ReplaceAllString(status, "")
 	status = strings.ReplaceAll(status, "\\e", "\x1b")
 
-	return status + prompt
+	return color.Truncate(status+prompt, p.lastLineWidth())
+}
+
+// rightWidth returns the number of columns available to the right prompt,
+// when the input line ends at the given column.
+func (p *Prompt) rightWidth(startColumn int) int {
+	termWidth := p.term.GetWidth()
+
+	if startColumn == termWidth {
+		return termWidth - 1
+	}
+
+	return termWidth - startColumn - 1
 }
 
 func (p *Prompt) formatRightPrompt(rprompt string, startColumn int) (prompt string, canPrint bool) {
 	// Dimensions
-	termWidth := term.GetWidth()
-	promptLen := strutil.RealLength(rprompt)
-	padLen := termWidth - startColumn - promptLen
+	termWidth := p.term.GetWidth()
+	promptLen := color.Text(rprompt).Width()
+	width := termWidth - startColumn
 
 	// Adjust padding when the last line is as large as terminal.
 	if startColumn == termWidth {
-		padLen = startColumn - promptLen
+		width = startColumn
 	}
 
 	// Check that we have room for a right/tooltip prompt.
 	canPrint = (startColumn+promptLen < termWidth) || startColumn == termWidth
 	if canPrint {
-		prompt = fmt.Sprintf("%s%s", strings.Repeat(" ", padLen), rprompt)
+		prompt = string(color.Text(rprompt).PadLeft(width))
 	}
 
 	return
@@ -281,7 +455,12 @@ func (p *Prompt) formatPrimaryLines(prompt string) (multi, lastPrompt string) {
 	// Get all the lines but the last.
 	lines := strings.Split(prompt, "\n")
 
+	// Lines wider than the terminal would wrap, and use more rows.
 	if len(lines) > 1 {
+		for i, line := range lines[:len(lines)-1] {
+			lines[i] = color.Truncate(line, p.term.GetWidth())
+		}
+
 		multi = strings.Join(lines[:len(lines)-1], "\n") + "\n"
 		lastPrompt = lines[len(lines)-1]
 	} else {
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"sync"
+
+	"github.com/reeflective/readline/internal/core"
+)
+
+// segment is a part of a prompt computed in the background.
+type segment struct {
+	placeholder string
+	value       string
+	computed    bool
+	compute     func() string
+	generation  int // Incremented on each refresh, so that only the last one is used.
+}
+
+// segments stores the asynchronous segments of prompts.
+type segments struct {
+	list    map[string]*segment
+	updated bool // Some segment value has changed since the prompt was printed.
+	mutex   sync.Mutex
+}
+
+// Segment registers a prompt segment (eg. a git status) computed in the background,
+// so that slow prompt data never delays the display of prompts. Prompt functions
+// use the current value of a segment with Async, which returns the placeholder
+// until the compute function has returned. Segments are recomputed each time the
+// shell starts reading a line, and the prompt is redisplayed each time they change.
+func (p *Prompt) Segment(name, placeholder string, compute func() string) {
+	p.segments.mutex.Lock()
+	defer p.segments.mutex.Unlock()
+
+	if p.segments.list == nil {
+		p.segments.list = make(map[string]*segment)
+	}
+
+	p.segments.list[name] = &segment{
+		placeholder: placeholder,
+		compute:     compute,
+	}
+}
+
+// Async returns the last computed value of a prompt segment, or its placeholder
+// if it has not been computed yet, and an empty string if there is no such segment.
+func (p *Prompt) Async(name string) string {
+	p.segments.mutex.Lock()
+	defer p.segments.mutex.Unlock()
+
+	seg, found := p.segments.list[name]
+	if !found {
+		return ""
+	}
+
+	if !seg.computed {
+		return seg.placeholder
+	}
+
+	return seg.value
+}
+
+// RefreshAsync recomputes a prompt segment in the background (eg. after the current
+// directory has changed), and redisplays the prompt with its new value once done.
+// The previous value is displayed until then. It can be called from any goroutine.
+func (p *Prompt) RefreshAsync(name string) {
+	p.segments.mutex.Lock()
+	defer p.segments.mutex.Unlock()
+
+	seg, found := p.segments.list[name]
+	if !found || seg.compute == nil {
+		return
+	}
+
+	seg.generation++
+	generation := seg.generation
+
+	go func() {
+		value := seg.compute()
+
+		p.segments.mutex.Lock()
+
+		// A more recent refresh is running.
+		if generation != seg.generation {
+			p.segments.mutex.Unlock()
+			return
+		}
+
+		changed := !seg.computed || value != seg.value
+		seg.value, seg.computed = value, true
+
+		p.segments.updated = p.segments.updated || changed
+		p.segments.mutex.Unlock()
+
+		// Wake the shell up to redisplay the prompt.
+		if changed {
+			core.Wake(p.keys)
+		}
+	}()
+}
+
+// RefreshSegments recomputes all prompt segments in the background.
+func RefreshSegments(p *Prompt) {
+	p.segments.mutex.Lock()
+	names := make([]string, 0, len(p.segments.list))
+
+	for name := range p.segments.list {
+		names = append(names, name)
+	}
+	p.segments.mutex.Unlock()
+
+	for _, name := range names {
+		p.RefreshAsync(name)
+	}
+}
+
+// SegmentsUpdated returns true if some prompt segments have changed
+// since the last time the prompt was printed, which must thus be.
+func SegmentsUpdated(p *Prompt) bool {
+	p.segments.mutex.Lock()
+	defer p.segments.mutex.Unlock()
+
+	updated := p.segments.updated
+	p.segments.updated = false
+
+	return updated
+}
@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
+)
+
+func newSegmentPrompt() *Prompt {
+	return &Prompt{keys: core.NewKeys(strings.NewReader(""), &term.Terminal{Output: io.Discard})}
+}
+
+// waitUpdated waits for some segment of the prompt to be updated.
+func waitUpdated(t *testing.T, p *Prompt) {
+	t.Helper()
+
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		if SegmentsUpdated(p) {
+			return
+		}
+	}
+
+	t.Fatal("SegmentsUpdated() = false after 1s, want true")
+}
+
+func TestPrompt_Segment(t *testing.T) {
+	prompt := newSegmentPrompt()
+
+	if got := prompt.Async("branch"); got != "" {
+		t.Errorf("Async() without segment = %q, want empty", got)
+	}
+
+	values := make(chan string, 1)
+	computed := make(chan struct{}, 1)
+
+	prompt.Segment("branch", "...", func() string {
+		defer func() { computed <- struct{}{} }()
+		return <-values
+	})
+
+	if got := prompt.Async("branch"); got != "..." {
+		t.Errorf("Async() before computing = %q, want %q", got, "...")
+	}
+
+	values <- "main"
+	RefreshSegments(prompt)
+	waitUpdated(t, prompt)
+
+	if got := prompt.Async("branch"); got != "main" {
+		t.Errorf("Async() after computing = %q, want %q", got, "main")
+	}
+
+	// Unchanged values do not require to redisplay the prompt.
+	values <- "main"
+	prompt.RefreshAsync("branch")
+	<-computed
+	time.Sleep(10 * time.Millisecond)
+
+	if SegmentsUpdated(prompt) {
+		t.Error("SegmentsUpdated() with unchanged value = true, want false")
+	}
+}
+
+func TestPrompt_RefreshAsyncLatest(t *testing.T) {
+	prompt := newSegmentPrompt()
+
+	// Each computation returns the value sent on its own channel.
+	started := make(chan chan string)
+
+	prompt.Segment("branch", "...", func() string {
+		value := make(chan string)
+		started <- value
+
+		return <-value
+	})
+
+	prompt.RefreshAsync("branch")
+	first := <-started
+
+	prompt.RefreshAsync("branch")
+	second := <-started
+
+	// The first refresh returns after the second one,
+	// and its (outdated) value is discarded.
+	second <- "new"
+	waitUpdated(t, prompt)
+
+	first <- "old"
+	time.Sleep(10 * time.Millisecond)
+
+	if got := prompt.Async("branch"); got != "new" {
+		t.Errorf("Async() = %q, want %q", got, "new")
+	}
+
+	if SegmentsUpdated(prompt) {
+		t.Error("SegmentsUpdated() after outdated refresh = true, want false")
+	}
+}
@@ -0,0 +1,62 @@
+package readline
+
+// Behavior is what the shell does when the interrupt (usually Ctrl-C) or the
+// end-of-file (usually Ctrl-D on an empty line) keys are pressed.
+type Behavior int
+
+const (
+	// ReturnError returns the current line with ErrInterrupt or ErrEOF (the default).
+	ReturnError Behavior = iota
+	// ClearLine clears the input line, and keeps reading input.
+	ClearLine
+	// RunCallback calls a function with the current input line: if it returns
+	// an error, the line is returned with it, otherwise the shell keeps reading.
+	RunCallback
+)
+
+// keyBehavior is the behavior of the shell for either the interrupt or EOF keys.
+type keyBehavior struct {
+	behavior Behavior
+	callback func(line string) error
+}
+
+// OnInterrupt sets the behavior of the shell when the interrupt key is pressed,
+// and when it is not cancelling a completion or search. The callback is only
+// used with the RunCallback behavior.
+func (rl *Shell) OnInterrupt(behavior Behavior, callback func(line string) error) {
+	rl.interrupt = keyBehavior{behavior, callback}
+}
+
+// OnEOF sets the behavior of the shell when the end-of-file key is pressed on an
+// empty line. The callback is only used with the RunCallback behavior. The shell
+// always returns ErrEOF when its input stream is closed, whatever the behavior.
+func (rl *Shell) OnEOF(behavior Behavior, callback func(line string) error) {
+	rl.eof = keyBehavior{behavior, callback}
+}
+
+// terminate either returns the error to the caller, or runs the
+// alternate behavior set for the key having produced the error.
+func (rl *Shell) terminate(key keyBehavior, err error) {
+	switch key.behavior {
+	case ClearLine:
+		rl.History.Save()
+
+		start := len([]rune(rl.prefix))
+		rl.line.Cut(start, rl.line.Len())
+		rl.cursor.Set(start)
+
+		return
+
+	case RunCallback:
+		if key.callback == nil {
+			return
+		}
+
+		if err = key.callback(string(*rl.line)); err == nil {
+			return
+		}
+	}
+
+	rl.Display.AcceptLine()
+	rl.History.Accept(false, false, err)
+}
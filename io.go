@@ -0,0 +1,126 @@
+package readline
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// errNoTerminal is returned as the size of streams that are not a terminal,
+// so that the default terminal size is used.
+var errNoTerminal = errors.New("not a terminal")
+
+// Terminal gives control over the terminal of a shell reading its input and writing
+// its output on arbitrary streams (SSH channels, PTYs, in-memory test buffers, etc).
+// Input or output streams passed to NewShellWithIO may implement it: if none does,
+// and if the input is not a terminal file either, the input is assumed to be in raw
+// mode already, and the terminal size is the default one (80 columns).
+type Terminal interface {
+	// MakeRaw puts the terminal in raw mode, and returns
+	// a function restoring its previous state.
+	MakeRaw() (restore func() error, err error)
+
+	// Size returns the current width and height of the terminal.
+	Size() (width, height int, err error)
+}
+
+// NewShellWithIO is like NewShell, but the shell reads its input keys from in,
+// and writes everything it displays to out. If either stream implements the
+// Terminal interface, it is used to switch the terminal in raw mode when
+// reading input, and to query its size. Nil streams are the process ones.
+// Several shells can read input at once, each on its own streams (with the
+// same theme, see SetTheme).
+func NewShellWithIO(in io.Reader, out io.Writer, opts ...inputrc.Option) *Shell {
+	if in == nil {
+		in = os.Stdin
+	}
+
+	if out == nil {
+		out = os.Stdout
+	}
+
+	return newShell(in, out, opts...)
+}
+
+// useTerminal puts the shell terminal in raw mode, and returns
+// a function restoring its previous state.
+func (rl *Shell) useTerminal() (restore func(), err error) {
+	if rl.in == nil {
+		descriptor := int(os.Stdin.Fd())
+
+		state, err := term.MakeRaw(descriptor)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() { term.Restore(descriptor, state) }, nil
+	}
+
+	terminal := rl.terminal()
+	if terminal == nil {
+		return func() {}, nil
+	}
+
+	restoreRaw, err := terminal.MakeRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { restoreRaw() }, nil
+}
+
+// sizeFunc returns the function querying the size of the shell terminal, or
+// nil if it is the process one. Streams that are not terminals have none, so
+// the default size is used.
+func (rl *Shell) sizeFunc() func() (width, height int, err error) {
+	if rl.in == nil {
+		return nil
+	}
+
+	if terminal := rl.terminal(); terminal != nil {
+		return terminal.Size
+	}
+
+	return func() (int, int, error) { return 0, 0, errNoTerminal }
+}
+
+// terminal returns the terminal controlling the shell input and output streams,
+// if one of them implements the Terminal interface or if the input is a terminal.
+func (rl *Shell) terminal() Terminal {
+	if terminal, ok := rl.in.(Terminal); ok {
+		return terminal
+	}
+
+	if terminal, ok := rl.out.(Terminal); ok {
+		return terminal
+	}
+
+	if file, ok := rl.in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		return fileTerminal{file}
+	}
+
+	return nil
+}
+
+// fileTerminal is a terminal file (eg. a PTY), other than the process one.
+type fileTerminal struct {
+	file *os.File
+}
+
+func (t fileTerminal) MakeRaw() (func() error, error) {
+	descriptor := int(t.file.Fd())
+
+	state, err := term.MakeRaw(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error { return term.Restore(descriptor, state) }, nil
+}
+
+func (t fileTerminal) Size() (width, height int, err error) {
+	return term.GetSize(int(t.file.Fd()))
+}
@@ -0,0 +1,109 @@
+package readline
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// PrintAsync prints a formatted message above the prompt, while the user is
+// editing the input line: the prompt, the line and its hints and completions
+// are cleared, the message is printed in their place, and they are displayed
+// again below it. A newline is added to the message if it has none.
+//
+// It is safe to call from any goroutine (eg. to log events happening in the
+// background), and if the shell is not reading input, the message is printed
+// immediately. Unlike Printf, the message is printed by the shell goroutine.
+func (rl *Shell) PrintAsync(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+
+	rl.editMutex.Lock()
+
+	if !rl.reading {
+		rl.editMutex.Unlock()
+		printMessages(rl.output(), msg)
+
+		return
+	}
+
+	rl.messages = append(rl.messages, msg)
+	rl.editMutex.Unlock()
+
+	core.Wake(rl.Keys)
+}
+
+// Logger returns a writer printing everything written to it above the prompt,
+// as PrintAsync does. Each write is printed as a message: it can be used as the
+// output of a log.Logger, for instance, which writes each entry at once.
+func (rl *Shell) Logger() io.Writer {
+	return logger{shell: rl}
+}
+
+type logger struct {
+	shell *Shell
+}
+
+func (l logger) Write(p []byte) (int, error) {
+	l.shell.PrintAsync("%s", p)
+	return len(p), nil
+}
+
+// queueMessages sets whether messages printed with PrintAsync are queued,
+// for the shell to print them above the prompt while it is reading input,
+// or printed immediately, in which case those already queued are printed.
+func (rl *Shell) queueMessages(queue bool) {
+	rl.editMutex.Lock()
+	rl.reading = queue
+	messages := rl.messages
+	rl.messages = nil
+	rl.editMutex.Unlock()
+
+	printMessages(rl.output(), messages...)
+}
+
+// printQueuedMessages clears the prompt, input line and helpers, prints the
+// queued messages in their place, and redisplays the interface below them.
+// The display must be up to date, since its coordinates are used to clear it.
+func (rl *Shell) printQueuedMessages() {
+	rl.editMutex.Lock()
+	messages := rl.messages
+	rl.messages = nil
+	rl.editMutex.Unlock()
+
+	if len(messages) == 0 {
+		return
+	}
+
+	rl.Display.CursorToLineStart()
+	rl.term.MoveCursorBackwards(rl.term.GetWidth())
+	rl.term.MoveCursorUp(rl.Prompt.PrimaryUsed())
+	fmt.Fprint(rl.term, term.ClearScreenBelow)
+
+	printMessages(rl.term, messages...)
+
+	rl.Display.PrintPrimaryPrompt()
+	rl.Display.Refresh()
+}
+
+// output returns the shell output stream, when it is not reading input.
+func (rl *Shell) output() io.Writer {
+	if rl.out != nil {
+		return rl.out
+	}
+
+	return rl.term.Output
+}
+
+// printMessages prints each message on its own lines, which works
+// regardless of the terminal being in raw mode or not.
+func printMessages(out io.Writer, messages ...string) {
+	for _, msg := range messages {
+		msg = strings.TrimSuffix(msg, "\n")
+		msg = strings.ReplaceAll(msg, "\r\n", "\n")
+
+		fmt.Fprint(out, strings.ReplaceAll(msg, "\n", term.NewlineReturn)+term.NewlineReturn)
+	}
+}
@@ -0,0 +1,136 @@
+package readline
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MouseEvent is a mouse button or wheel event reported by the terminal
+// when the enable-mouse option is on, and passed to the MouseHandler.
+type MouseEvent struct {
+	Button  MouseButton
+	X, Y    int  // Terminal column and row of the event, starting at 1.
+	Release bool // The button was released (pressed otherwise).
+	Shift   bool
+	Alt     bool
+	Ctrl    bool
+}
+
+// MouseButton is a mouse button or wheel direction.
+type MouseButton int
+
+// Mouse buttons and wheel directions, as encoded in mouse reports.
+const (
+	MouseLeft      MouseButton = 0
+	MouseMiddle    MouseButton = 1
+	MouseRight     MouseButton = 2
+	MouseWheelUp   MouseButton = 64
+	MouseWheelDown MouseButton = 65
+)
+
+// Modifiers and motion flags of mouse reports.
+const (
+	mouseShift  = 4
+	mouseAlt    = 8
+	mouseCtrl   = 16
+	mouseMotion = 32
+)
+
+// Handle a mouse event reported by the terminal (when enable-mouse is on).
+// Clicks move the cursor in the input line or select completion candidates,
+// and the wheel cycles through candidates, or walks the history otherwise.
+// Events are first passed to the MouseHandler, if any.
+func (rl *Shell) mouseEvent() {
+	rl.History.SkipSave()
+
+	event, valid := rl.readMouseEvent()
+	if !valid {
+		return
+	}
+
+	if rl.MouseHandler != nil && rl.MouseHandler(event) {
+		return
+	}
+
+	if event.Release {
+		return
+	}
+
+	menu := rl.completer.Matches() > 0
+
+	switch event.Button {
+	case MouseLeft:
+		if row, column, found := rl.Display.CompletionPos(event.X, event.Y); found && rl.completer.SelectAt(row, column) {
+			return
+		}
+
+		pos, found := rl.Display.LinePos(event.X, event.Y)
+		if !found {
+			return
+		}
+
+		// Accept any inserted candidate before moving.
+		rl.completer.Reset()
+		rl.line, rl.cursor, rl.selection = rl.completer.GetBuffer()
+		rl.cursor.Set(pos)
+
+	case MouseWheelUp:
+		if menu {
+			rl.completer.Select(-1, 0)
+		} else {
+			rl.upHistory()
+		}
+
+	case MouseWheelDown:
+		if menu {
+			rl.completer.Select(1, 0)
+		} else {
+			rl.downHistory()
+		}
+	}
+}
+
+// readMouseEvent reads the remainder of an SGR mouse report
+// (ESC [ < button ; column ; row M/m), once its prefix is matched.
+func (rl *Shell) readMouseEvent() (event MouseEvent, valid bool) {
+	var params []byte
+
+	for {
+		key, empty := rl.Keys.Pop()
+		if empty {
+			return event, false
+		}
+
+		if key == 'M' || key == 'm' {
+			event.Release = key == 'm'
+			break
+		}
+
+		params = append(params, key)
+	}
+
+	fields := strings.Split(string(params), ";")
+	if len(fields) != 3 {
+		return event, false
+	}
+
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return event, false
+	}
+
+	if event.X, err = strconv.Atoi(fields[1]); err != nil {
+		return event, false
+	}
+
+	if event.Y, err = strconv.Atoi(fields[2]); err != nil {
+		return event, false
+	}
+
+	event.Button = MouseButton(code &^ (mouseShift | mouseAlt | mouseCtrl | mouseMotion))
+	event.Shift = code&mouseShift != 0
+	event.Alt = code&mouseAlt != 0
+	event.Ctrl = code&mouseCtrl != 0
+
+	return event, true
+}
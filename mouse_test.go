@@ -0,0 +1,39 @@
+package readline
+
+import (
+	"testing"
+)
+
+func TestShell_ReadMouseEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  string
+		event MouseEvent
+		valid bool
+	}{
+		{name: "Press", keys: "0;5;1M", event: MouseEvent{Button: MouseLeft, X: 5, Y: 1}, valid: true},
+		{name: "Release", keys: "2;40;6m", event: MouseEvent{Button: MouseRight, X: 40, Y: 6, Release: true}, valid: true},
+		{
+			name:  "Modifiers",
+			keys:  "28;7;2M",
+			event: MouseEvent{Button: MouseLeft, X: 7, Y: 2, Shift: true, Alt: true, Ctrl: true},
+			valid: true,
+		},
+		{name: "Wheel", keys: "65;1;3M", event: MouseEvent{Button: MouseWheelDown, X: 1, Y: 3}, valid: true},
+		{name: "Missing field", keys: "0;5M"},
+		{name: "Invalid field", keys: "0;x;1M"},
+		{name: "Incomplete", keys: "0;5;1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell()
+			shell.Keys.Feed(false, []rune(test.keys)...)
+
+			event, valid := shell.readMouseEvent()
+			if valid != test.valid || (valid && event != test.event) {
+				t.Errorf("readMouseEvent() = %+v, %v, want %+v, %v", event, valid, test.event, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package readline
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// Transform operators replace some text of the line with a transformation of it:
+// in Vim mode, they are operators acting on the text covered by the next motion
+// (eg. `g?iw`), on the current line when repeated (`g?g?`), or on the visual
+// selection. In Emacs mode, they act on the region if any, or else on the
+// text from the cursor to the end of the word.
+
+// AddOperator registers a named command transforming text as an operator, which
+// can then be bound to keys like any other command (eg. "gs" in the vi-command
+// and vi-visual keymaps). The function is passed the text covered by the motion,
+// the selection or the word, and returns the text to replace it with.
+// A command (or operator) of the same name is overridden.
+func (rl *Shell) AddOperator(name string, transform func(text string) string) {
+	rl.Keymap.Register(map[string]func(){
+		name: rl.transformOperator(name, func(text string) (string, error) {
+			return transform(text), nil
+		}),
+	})
+}
+
+// operatorCommands returns the builtin transform operators.
+func (rl *Shell) operatorCommands() commands {
+	transforms := map[string]func(text string) (string, error){
+		"rot13":         rot13,
+		"url-encode":    func(text string) (string, error) { return url.QueryEscape(text), nil },
+		"url-decode":    url.QueryUnescape,
+		"base64-encode": func(text string) (string, error) { return base64.StdEncoding.EncodeToString([]byte(text)), nil },
+		"base64-decode": base64Decode,
+	}
+
+	operators := make(commands, len(transforms))
+
+	for name, transform := range transforms {
+		operators[name] = rl.transformOperator(name, transform)
+	}
+
+	return operators
+}
+
+// transformOperator returns the command of a transform operator.
+func (rl *Shell) transformOperator(name string, transform func(text string) (string, error)) func() {
+	return func() {
+		if !rl.Keymap.IsEmacs() {
+			rl.viOperator(name, func() { rl.transformSelection(transform) })
+			return
+		}
+
+		rl.History.Save()
+
+		if rl.selection.Active() {
+			rl.transformSelection(transform)
+			return
+		}
+
+		cpos := rl.cursor.Pos()
+		epos := rl.line.WordEnd(rl.wordStyle(), cpos)
+
+		rl.selection.MarkRange(cpos, epos)
+
+		if end := rl.transformSelection(transform); end >= 0 {
+			rl.cursor.Set(end)
+		}
+	}
+}
+
+// transformSelection replaces the text of the selection with its transformation,
+// moving the cursor at its beginning, and returns the end of the replaced text.
+// If the transformation fails (eg. decoding invalid text), the bell is rung, the
+// text is left unchanged and -1 is returned. The selection is reset either way.
+func (rl *Shell) transformSelection(transform func(text string) (string, error)) (end int) {
+	bpos, epos := rl.selection.Pos()
+	rl.selection.Reset()
+
+	if bpos == -1 || epos == -1 {
+		return -1
+	}
+
+	text, err := transform(string((*rl.line)[bpos:epos]))
+	if err != nil {
+		rl.bell.Ring()
+		return -1
+	}
+
+	chars := []rune(text)
+
+	rl.line.InsertBetween(bpos, epos, chars...)
+	rl.cursor.Set(bpos)
+
+	return bpos + len(chars)
+}
+
+// rot13 rotates the ASCII letters of the text by 13 positions.
+func rot13(text string) (string, error) {
+	return strings.Map(func(char rune) rune {
+		switch {
+		case char >= 'a' && char <= 'z':
+			return 'a' + (char-'a'+13)%26
+		case char >= 'A' && char <= 'Z':
+			return 'A' + (char-'A'+13)%26
+		default:
+			return char
+		}
+	}, text), nil
+}
+
+// base64Decode decodes standard base64 text, with or without padding.
+func base64Decode(text string) (string, error) {
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "="))
+	if err != nil {
+		return "", err
+	}
+
+	return string(decoded), nil
+}
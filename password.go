@@ -0,0 +1,85 @@
+package readline
+
+import (
+	"github.com/reeflective/readline/internal/editor"
+)
+
+// PasswordOption configures a call to ReadPassword.
+type PasswordOption func(*password)
+
+// password holds the settings of a ReadPassword call.
+type password struct {
+	mask rune
+}
+
+// WithMask displays the mask in place of each character typed (* by default).
+// If the mask is 0, the input is not displayed at all, and the cursor does
+// not move, as with most command-line tools prompting for passwords.
+func WithMask(mask rune) PasswordOption {
+	return func(secret *password) {
+		secret.mask = mask
+	}
+}
+
+// ReadPassword displays the prompt and reads a password, or any secret input.
+// The input is masked, and for the duration of the call, accepted lines are not
+// written to history, and neither suggestions nor completions are proposed.
+// Text cut or yanked is kept in temporary registers (never in the clipboard),
+// which are wiped along with the input line buffer when returning.
+// Other prompts, the immutable prefix and the accept filter are not used.
+func (rl *Shell) ReadPassword(prompt string, opts ...PasswordOption) (string, error) {
+	secret := &password{mask: '*'}
+
+	for _, opt := range opts {
+		opt(secret)
+	}
+
+	// Only display the password prompt and the masked input.
+	defer rl.Prompt.Swap(func() string { return prompt })()
+
+	rl.Display.Mask(secret.mask)
+	defer rl.Display.Unmask()
+
+	// Don't keep the input, nor any of its changes, anywhere.
+	rl.History.SetSecret(true)
+	defer rl.History.SetSecret(false)
+
+	buffers := rl.Buffers
+	rl.Buffers = editor.NewBuffers(rl.term, rl.styles, rl.Config)
+
+	defer func() {
+		rl.line.Wipe()
+		rl.Buffers.Wipe()
+		rl.Buffers = buffers
+	}()
+
+	// No completions, suggestions or other line-dependent features.
+	completer, multiline, prefix, accept, restored := rl.Completer, rl.AcceptMultiline, rl.prefix, rl.accept, rl.restored
+	rl.Completer, rl.AcceptMultiline, rl.prefix, rl.accept, rl.restored = nil, nil, "", nil, nil
+
+	defer func() {
+		rl.Completer, rl.AcceptMultiline, rl.prefix, rl.accept = completer, multiline, prefix, accept
+		rl.restored = restored
+	}()
+
+	defer rl.setOption("clipboard", "off")()
+	defer rl.setOption("autocomplete", false)()
+	defer rl.setOption("correct-command", false)()
+	defer rl.setOption("prompt-transient", false)()
+
+	return rl.Readline()
+}
+
+// setOption sets an inputrc option, and returns a function restoring its previous value.
+func (rl *Shell) setOption(name string, value interface{}) (restore func()) {
+	previous, isSet := rl.Config.Vars[name]
+	rl.Config.Set(name, value)
+
+	return func() {
+		if isSet {
+			rl.Config.Set(name, previous)
+		} else {
+			delete(rl.Config.Vars, name)
+		}
+	}
+}
@@ -19,51 +19,84 @@
 package readline
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/reeflective/readline/inputrc"
-	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/display"
 	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/macro"
-	"github.com/reeflective/readline/internal/term"
+	"github.com/reeflective/readline/internal/ui"
 )
 
-// ErrInterrupt is returned when the interrupt sequence
-// is pressed on the keyboard. The sequence is usually Ctrl-C.
-var ErrInterrupt = errors.New(os.Interrupt.String())
+var (
+	// ErrInterrupt is returned when the interrupt sequence
+	// is pressed on the keyboard. The sequence is usually Ctrl-C.
+	ErrInterrupt = errors.New(os.Interrupt.String())
+
+	// ErrEOF is returned when the end-of-file sequence is pressed on
+	// an empty line (usually Ctrl-D), or when the input is closed.
+	// It is io.EOF, so that both can be checked against.
+	ErrEOF = io.EOF
+)
 
 // Readline displays the readline prompt and reads user input.
 // It can return from the call because of different things:
 //
 //   - When the user accepts the line (generally with Enter).
 //   - If a particular keystroke mapping returns an error.
-//     (Ctrl-C returns ErrInterrupt, Ctrl-D returns io.EOF).
+//     (Ctrl-C returns ErrInterrupt, Ctrl-D returns ErrEOF,
+//     unless other behaviors are set with OnInterrupt/OnEOF).
 //
 // In all cases, the current input line is returned along with any error,
 // and it is up to the caller to decide what to do with the line result.
 // When the error is not nil, the returned line is not written to history.
 func (rl *Shell) Readline() (string, error) {
-	descriptor := int(os.Stdin.Fd())
+	return rl.ReadlineCtx(context.Background())
+}
 
-	state, err := term.MakeRaw(descriptor)
-	if err != nil {
+// ReadlineCtx is like Readline, but stops reading user input when the context
+// is cancelled or its deadline passes: the terminal state is restored and the
+// current input line is returned along with the context error, without being
+// written to history. This can be used for idle timeouts or clean shutdowns.
+// Similarly, ErrEOF is returned if the input stream is closed.
+//
+// The context interrupts the shell whenever it waits for keys, including in
+// commands reading them (eg. quoted-insert or the completion query), on any
+// input stream (on Windows consoles, it is checked every 50 milliseconds).
+// Commands already running, like completers or accept filters, are not
+// interrupted: the shell returns once they are done.
+func (rl *Shell) ReadlineCtx(ctx context.Context) (line string, err error) {
+	defer func() { rl.Hooks.accept(line, err) }()
+
+	rl.queueMessages(true)
+	defer rl.queueMessages(false)
+
+	if err = rl.enterTerminal(); err != nil {
 		return "", err
 	}
-	defer term.Restore(descriptor, state)
+	defer rl.leaveTerminal()
 
 	// Prompts and cursor styles
+	ui.RefreshSegments(rl.Prompt)
 	rl.Display.PrintPrimaryPrompt()
-	defer rl.Display.RefreshTransient()
-	defer fmt.Print(keymap.CursorStyle("default"))
+	defer func() { rl.Display.CommandStart(err) }()
+	defer func() { rl.Display.RefreshTransient(line, err) }()
+	defer rl.Keymap.RestoreCursor()
 
 	rl.init()
 
+	// Abort waiting for keys when the context is done.
+	core.WaitDone(rl.Keys, ctx.Done())
+	defer core.WaitDone(rl.Keys, nil)
+
 	// Terminal resize events
 	resize := display.WatchResize(rl.Display)
 	defer close(resize)
@@ -81,19 +114,66 @@ func (rl *Shell) Readline() (string, error) {
 
 		// Since we always update helpers after being asked to read
 		// for user input again, we do it before actually reading it.
-		rl.Display.Refresh()
+		// Text might have been inserted from another goroutine,
+		// and prompt segments computed in the background.
+		rl.applyEdits()
+
+		if ui.SegmentsUpdated(rl.Prompt) {
+			rl.Display.RefreshPrompt()
+		}
+
+		rl.Hooks.modes(string(rl.Keymap.Main()), string(rl.Keymap.Local()))
+		rl.announce()
+
+		if !rl.coalesceRefresh() {
+			rl.Display.Refresh()
+
+			// Messages from other goroutines are printed above the
+			// prompt, once the display coordinates are up to date.
+			rl.printQueuedMessages()
+		}
+
+		// If a guided tour is being played, feed its next key.
+		macro.PlayTour(rl.Macros)
 
 		// Block and wait for available user input keys.
 		// These might be read on stdin, or already available because
 		// the macro engine has fed some keys in bulk when running one.
+		rl.Hooks.read()
 		core.WaitAvailableKeys(rl.Keys, rl.Config)
 
+		if core.Cancelled(rl.Keys) || core.Closed(rl.Keys) {
+			err := ctx.Err()
+			if core.Closed(rl.Keys) {
+				err = ErrEOF
+			}
+
+			rl.Display.AcceptLine()
+			rl.History.Accept(false, false, err)
+
+			_, line, err := rl.History.LineAccepted()
+
+			return line, err
+		}
+
+		// Woken up to process edits (or to play a tour), not keys.
+		if core.Woken(rl.Keys) || core.Delayed(rl.Keys) {
+			continue
+		}
+
 		// 1 - Local keymap (Completion/Isearch/Vim operator pending).
 		bind, command, prefixed := keymap.MatchLocal(rl.Keymap)
 		if prefixed {
 			continue
 		}
 
+		// Pass the keys to the key hook and tracer once, with the
+		// local bind if there is one, or with the main one below.
+		hooked := bind.Action != ""
+		if hooked && rl.keyEvent(string(rl.Keymap.Local()), bind) {
+			continue
+		}
+
 		accepted, line, err := rl.run(false, bind, command)
 		if accepted {
 			return line, err
@@ -113,6 +193,10 @@ func (rl *Shell) Readline() (string, error) {
 			continue
 		}
 
+		if !hooked && rl.keyEvent(string(rl.Keymap.Main()), bind) {
+			continue
+		}
+
 		accepted, line, err = rl.run(true, bind, command)
 		if accepted {
 			return line, err
@@ -125,6 +209,34 @@ func (rl *Shell) Readline() (string, error) {
 	}
 }
 
+// enterTerminal puts the terminal in raw mode, and enables enhanced
+// keyboard protocols and mouse tracking, if enabled and supported.
+func (rl *Shell) enterTerminal() error {
+	restore, err := rl.useTerminal()
+	if err != nil {
+		return err
+	}
+
+	restoreKeys := rl.term.EnableKeyboardProtocol(rl.Config.GetString("keyboard-protocol"))
+	restoreMouse := rl.term.EnableMouse(rl.Config.GetBool("enable-mouse"))
+
+	rl.leave = func() {
+		restoreMouse()
+		restoreKeys()
+		restore()
+	}
+
+	return nil
+}
+
+// leaveTerminal restores the terminal state changed by enterTerminal.
+func (rl *Shell) leaveTerminal() {
+	if rl.leave != nil {
+		rl.leave()
+		rl.leave = nil
+	}
+}
+
 // init gathers all steps to perform at the beginning of readline loop.
 func (rl *Shell) init() {
 	// Reset core editor components.
@@ -132,20 +244,48 @@ func (rl *Shell) init() {
 	rl.line.Set()
 	rl.cursor.Set(0)
 	rl.cursor.ResetMark()
+	rl.cursor.ResetCursors()
 	rl.selection.Reset()
 	rl.Buffers.Reset()
 	rl.History.Reset()
-	rl.History.Save()
 	rl.Iterations.Reset()
+	rl.Keymap.SetOverwrite(false)
+	rl.verified = ""
+	rl.dismissed = ""
 
 	// Some accept-* commands must fetch a specific
 	// line outright, or keep the accepted one.
 	history.Init(rl.History)
 
+	// The initial state of the line is the first undo state.
+	rl.History.Save()
+
+	// Resume an editing session, if a state was restored.
+	rl.restoreState()
+
+	// Or edit the line preloaded by the caller.
+	rl.applyEdits()
+
+	rl.resetAnnouncements()
+
+	// Insert the read-only prefix, unless already there.
+	rl.selection.ResetProtected()
+
+	if rl.prefix != "" {
+		prefix := []rune(rl.prefix)
+
+		if !strings.HasPrefix(string(*rl.line), rl.prefix) {
+			rl.line.Insert(0, prefix...)
+			rl.cursor.Set(rl.cursor.Pos() + len(prefix))
+		}
+
+		rl.selection.Protect(0, len(prefix))
+	}
+
 	// Reset/initialize user interface components.
 	rl.Hint.Reset()
 	rl.completer.ResetForce()
-	display.Init(rl.Display, rl.SyntaxHighlighter)
+	display.Init(rl.Display, rl.SyntaxHighlighter, rl.Highlighter)
 }
 
 // run wraps the execution of a target command/sequence with various pre/post actions
@@ -169,6 +309,11 @@ func (rl *Shell) run(main bool, bind inputrc.Bind, command func()) (bool, string
 	// so it knows which line and cursor we should work on.
 	rl.line, rl.cursor, rl.selection = rl.completer.GetBuffer()
 
+	// Command middlewares can veto, time or log the command.
+	if command != nil {
+		command = rl.Hooks.command(bind.Action, rl.Keys.Caller(), command)
+	}
+
 	// The command might be nil, because the provided key sequence
 	// did not match any. We regardless execute everything related
 	// to the command, like any pending ones, and cursor checks.
@@ -185,12 +330,15 @@ func (rl *Shell) run(main bool, bind inputrc.Bind, command func()) (bool, string
 	// return the correct input line and cursor.
 	rl.line, rl.cursor, rl.selection = rl.completer.GetBuffer()
 
+	// Highlight the occurrences of the last searched pattern, if required.
+	rl.updateSearchHighlight()
+
 	// History: save the last action to the line history,
 	// and return with the call to the history system that
 	// checks if the line has been accepted (entered), in
 	// which case this will automatically write the history
 	// sources and set up errors/returned line values.
-	rl.History.SaveWithCommand(bind)
+	rl.saveUndo(bind)
 
 	return rl.History.LineAccepted()
 }
@@ -198,7 +346,32 @@ func (rl *Shell) run(main bool, bind inputrc.Bind, command func()) (bool, string
 // Run the dispatched command, any pending operator
 // commands (Vim mode) and some post-run checks.
 func (rl *Shell) execute(command func()) {
-	if command != nil {
+	// Keep the line to restore if read-only ranges are edited,
+	// or to check if it's edited while the region is highlighted.
+	var before []rune
+
+	cpos := rl.cursor.Pos()
+	if len(rl.selection.Protected()) > 0 || rl.selection.IsRegion() {
+		before = append(before, *rl.line...)
+	}
+
+	// A read-only line is restored along with the history position,
+	// but search minibuffers can still be edited.
+	var state history.State
+
+	readOnly := rl.readOnly && !rl.minibuffer()
+	if readOnly {
+		before = append([]rune{}, *rl.line...)
+		state = rl.History.State()
+	}
+
+	region := rl.selection.IsRegion()
+
+	switch {
+	case command == nil:
+	case len(rl.cursor.Cursors()) > 0 && multiCursorCommands[rl.Keymap.ActiveCommand().Action]:
+		rl.runAtCursors(command)
+	default:
 		command()
 	}
 
@@ -215,22 +388,67 @@ func (rl *Shell) execute(command func()) {
 	default:
 		rl.cursor.CheckAppend()
 	}
+
+	switch {
+	case readOnly && string(before) != string(*rl.line):
+		rl.line.Set(before...)
+		rl.cursor.Set(cpos)
+		rl.History.RestoreState(state)
+		rl.bell.Ring()
+	case before != nil && !core.CheckProtected(rl.selection, before):
+		rl.line.Set(before...)
+		rl.cursor.Set(cpos)
+	}
+
+	// The Emacs region is not highlighted anymore once the line is edited.
+	if region && rl.selection.IsRegion() && string(before) != string(*rl.line) {
+		rl.selection.Reset()
+	}
+
+	// Remember the Vim visual selection, to be selected again with gv.
+	if rl.Keymap.Local() == keymap.Visual && rl.selection.Active() {
+		rl.selection.Remember()
+	}
+}
+
+// coalesceRefresh returns true if the display should not be refreshed before
+// dispatching the next keys: in low-bandwidth mode, redraws are batched until
+// all keys already read have been dispatched, and with a maximum frame rate,
+// until keys stop coming faster than it. Since a refresh is never skipped when
+// no more keys are coming, the last frame is always consistent with the line.
+func (rl *Shell) coalesceRefresh() bool {
+	if rl.Config.GetBool("low-bandwidth") && core.HasPendingKeys(rl.Keys) {
+		return true
+	}
+
+	delay := rl.Display.FrameDelay()
+
+	return delay > 0 && core.KeysComing(rl.Keys, delay)
 }
 
-// Some commands show their current status as a hint (iterations/macro).
+// Some commands show their current status as a hint (iterations/macro),
+// and edited history lines can show their changes against the original.
 func (rl *Shell) updatePosRunHints() {
-	hint := core.ResetPostRunIterations(rl.Iterations)
+	hint := core.ResetPostRunIterations(rl.Iterations, rl.styles)
 	register, selected := rl.Buffers.IsSelected()
 
-	if hint == "" && !selected && !rl.Macros.Recording() {
+	if hint == "" && !selected && !rl.Macros.Recording() && !rl.Macros.TourActive() {
 		rl.Hint.ResetPersist()
+
+		// Show what has been changed in a recalled history line.
+		if rl.Config.GetBool("history-diff-hint") {
+			if diff := rl.History.Diff(); diff != "" {
+				rl.Hint.Persist(diff)
+			}
+		}
+
 		return
 	}
 
 	if hint != "" {
 		rl.Hint.Persist(hint)
 	} else if selected {
-		rl.Hint.Persist(color.Dim + fmt.Sprintf("(register: %s)", register))
+		rl.Hint.Persist(rl.styles.Hint + fmt.Sprintf("(register: %s)", register))
 	}
 }
 
@@ -246,4 +464,14 @@ func (rl *Shell) handleUndefined(bind inputrc.Bind, cmd func()) {
 		rl.Hint.Reset()
 		rl.completer.Reset()
 	}
+
+	rl.bell.Ring()
+}
+
+// minibuffer returns true if commands are editing the minibuffer
+// of an incremental or non-incremental search, not the input line.
+func (rl *Shell) minibuffer() bool {
+	searching, _, _ := rl.completer.NonIncrementallySearching()
+
+	return searching || rl.Keymap.Local() == keymap.Isearch
 }
@@ -0,0 +1,121 @@
+package readlinetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShellAcceptMultiline(t *testing.T) {
+	tests := []struct {
+		line   string
+		accept bool
+	}{
+		{line: `echo "a b" 'c' (d) {e} [f]`, accept: true},
+		{line: `echo "a`, accept: false},
+		{line: `echo 'a "b'`, accept: true},
+		{line: `echo "it's"`, accept: true},
+		{line: `echo a\`, accept: false},
+		{line: `echo a\\`, accept: true},
+		{line: `echo $(ls "a)"`, accept: false},
+		{line: "echo `ls", accept: false},
+		{line: "echo ${a", accept: false},
+		{line: "if (a", accept: false},
+		{line: "ls |", accept: false},
+		{line: "ls |\n grep a", accept: true},
+		{line: "ls && \n", accept: false},
+		{line: "sleep 1 &", accept: true},
+		{line: "echo don't # it", accept: false},
+		{line: "echo a # it's", accept: true},
+		{line: "echo a#'", accept: false},
+		{line: "cat <<EOF\nhello", accept: false},
+		{line: "cat <<EOF\nhello\nEOF", accept: true},
+		{line: "cat <<'EOF' | wc\n'\nEOF\n", accept: true},
+		{line: "cat <<-EOF\n\thello\n\tEOF", accept: true},
+		{line: "cat <<A <<B\nA\n", accept: false},
+		{line: "cat <<A <<B\nA\nB", accept: true},
+		{line: "cat <<< word", accept: true},
+	}
+
+	for _, test := range tests {
+		if accept := readline.ShellAcceptMultiline([]rune(test.line)); accept != test.accept {
+			t.Errorf("ShellAcceptMultiline(%q) = %v, want %v", test.line, accept, test.accept)
+		}
+	}
+
+	shell := NewShell(80, 6)
+	shell.AcceptMultiline = readline.ShellAcceptMultiline
+
+	line, _ := shell.Readline(`echo "a`, `\r`, `b"`, `\r`)
+	if line != "echo \"a\nb\"" {
+		t.Errorf("Readline() = %q, want %q", line, "echo \"a\nb\"")
+	}
+}
+
+func TestShell_AcceptVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		verify string
+		keys   []string
+		line   string
+	}{
+		{name: "Off", verify: "off", keys: []string{"ls !!", `\r`}, line: "ls last"},
+		{name: "Changed line", verify: "changed", keys: []string{"ls !!", `\r`, "x", `\r`, `\r`}, line: "ls lastx"},
+		{name: "Changed confirmed", verify: "changed", keys: []string{"ls !!", `\r`, `\r`}, line: "ls last"},
+		{name: "Unchanged line", verify: "changed", keys: []string{"ls", `\r`}, line: "ls"},
+		{name: "Always", verify: "always", keys: []string{"ls", `\r`, "-l", `\r`, `\r`}, line: "ls-l"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+			shell.Config.Set("accept-verify", test.verify)
+			shell.AcceptFilter(func(line string) (string, error) {
+				return strings.ReplaceAll(line, "!!", "last"), nil
+			})
+
+			line, _ := shell.Readline(test.keys...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_OperateAndGetNext(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		next []string // Lines returned by the following calls.
+	}{
+		{name: "Next", keys: []string{`\C-p`, `\C-p`, `\C-o`}, next: []string{"c"}},
+		{name: "Repeat", keys: []string{`\C-p`, `\C-p`, `\C-p`, `\C-o`, `\C-o`}, next: []string{"b", "c"}},
+		{name: "Edited", keys: []string{`\C-p`, `\C-p`, "x", `\C-o`}, next: []string{"c"}},
+		{name: "Matched", keys: []string{"a", `\C-o`}, next: []string{"b"}},
+		{name: "Count", keys: []string{"x", `\M-1`, `\C-o`}, next: []string{"b"}},
+		{name: "Last", keys: []string{`\C-p`, `\C-o`}, next: []string{""}},
+		{name: "New", keys: []string{"x", `\C-o`}, next: []string{""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hist := readline.NewInMemoryHistory()
+			for _, line := range []string{"a", "b", "c"} {
+				hist.Write(line)
+			}
+
+			shell := NewShell(80, 10)
+			shell.History.Add("local", hist)
+
+			shell.Readline(test.keys...)
+
+			for _, next := range test.next {
+				if line, _ := shell.Readline(`\r`); line != next {
+					t.Errorf("Readline() = %q, want %q", line, next)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package readlinetest
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_SetBuffer(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     int
+		wantCursor int
+		line       string
+	}{
+		{name: "Cursor in line", cursor: 1, wantCursor: 1, line: "fxoo"},
+		{name: "Line end", cursor: -1, wantCursor: 3, line: "foox"},
+		{name: "Out of line", cursor: 10, wantCursor: 3, line: "foox"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 6)
+			shell.SetBuffer("foo", test.cursor)
+
+			if line, cursor := shell.Buffer(); line != "foo" || cursor != test.wantCursor {
+				t.Errorf("Buffer() = %q, %d, want %q, %d", line, cursor, "foo", test.wantCursor)
+			}
+
+			if line, _ := shell.Readline("x", `\r`); line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+
+	// Without a preloaded line, the last one is returned.
+	shell := NewShell(40, 6)
+	shell.Readline("abc", `\C-b`, `\C-c`)
+
+	if line, cursor := shell.Buffer(); line != "abc" || cursor != 2 {
+		t.Errorf("Buffer() after Readline() = %q, %d, want %q, %d", line, cursor, "abc", 2)
+	}
+}
+
+func TestShell_InsertText(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(shell *Shell)
+		keys  []string
+		line  string
+	}{
+		{
+			name:  "Before reading",
+			setup: func(shell *Shell) { shell.InsertText("ab"); shell.InsertText("c") },
+			keys:  []string{"x"},
+			line:  "abcx",
+		},
+		{
+			name:  "In preloaded line",
+			setup: func(shell *Shell) { shell.SetBuffer("foo", 1); shell.InsertText("X") },
+			line:  "fXoo",
+		},
+		{
+			name:  "Discarded by preloaded line",
+			setup: func(shell *Shell) { shell.InsertText("X"); shell.SetBuffer("foo", 1) },
+			line:  "foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 6)
+			test.setup(shell)
+
+			if line, _ := shell.Readline(append(test.keys, `\r`)...); line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_InsertTextAsync(t *testing.T) {
+	screen := NewScreen(40, 4)
+	reader, writer := io.Pipe()
+
+	defer writer.Close()
+
+	screen.reply = func(answer string) { go writer.Write([]byte(answer)) }
+
+	shell := readline.NewShellWithIO(reader, screen)
+
+	var frames []string
+
+	// Text inserted from another goroutine while the shell waits for
+	// keys is inserted and displayed right away, and can be undone.
+	shell.Hooks.OnPreRead(func() {
+		frames = append(frames, strings.TrimSpace(screen.Frame().String()))
+
+		switch len(frames) {
+		case 1:
+			go writer.Write([]byte("a"))
+		case 2:
+			go shell.InsertText("bc")
+		case 3:
+			go writer.Write([]byte("\x1f")) // Control-_ (undo)
+		default:
+			go writer.Write([]byte("\r"))
+		}
+	})
+
+	if line, err := shell.Readline(); line != "a" || err != nil {
+		t.Errorf("Readline() = %q, %v, want %q, nil", line, err, "a")
+	}
+
+	if want := []string{"", "a", "abc", "a"}; !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %q, want %q", frames, want)
+	}
+}
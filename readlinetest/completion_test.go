@@ -0,0 +1,312 @@
+package readlinetest
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_Completions(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta", "gamma")
+	}
+
+	shell.Readline(`\e?`, `\C-c`)
+
+	want := ">\nalpha  beta  gamma"
+	if frame := shell.Frames()[0]; frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+func TestShell_CompletionPreview(t *testing.T) {
+	for _, protocol := range []string{"none", "kitty"} {
+		shell := NewShell(40, 10)
+		shell.Prompt.Primary(func() string { return "> " })
+		shell.Config.Set("image-protocol", protocol)
+		shell.Completer = func(line []rune, cursor int) readline.Completions {
+			return readline.CompleteValues("alpha", "beta").PreviewF(func(value string) *readline.Image {
+				img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+				return &readline.Image{Image: img, Cols: 4, Rows: 2, Alt: "preview of " + value}
+			})
+		}
+
+		shell.Readline(`\t`, `\t`, `\C-c`)
+
+		// Images leave the screen text unchanged, or display their alternative text.
+		want := map[string]string{
+			"none":  "> alpha\npreview of alpha",
+			"kitty": "> alpha",
+		}
+
+		if frame := shell.Frames()[0]; frame.String() != want[protocol] {
+			t.Errorf("Frame (%s) = %q, want %q", protocol, frame, want[protocol])
+		}
+	}
+}
+
+func TestShell_DesignatorCompletion(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Prefix", keys: []string{"echo !?stat", `\t`}, line: "echo !1"},
+		{name: "Number", keys: []string{"!3", `\t`}, line: "!3"},
+		{name: "Menu", keys: []string{"!git", `\t`, `\t`}, line: "!1"},
+		{name: "Not a designator", keys: []string{"a!git", `\t`}, line: "a!git"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 10)
+			shell.Config.Set("designator-completion", true)
+
+			hist := readline.NewInMemoryHistory()
+			for _, line := range []string{"git status", "ls", "git log", "ls"} {
+				hist.Write(line)
+			}
+
+			shell.History.Add("local", hist)
+
+			line, _ := shell.Readline(append(test.keys, `\C-e`, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_RegisterNamesCompletion(t *testing.T) {
+	shell := NewShell(40, 10)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Config.Set("designator-completion", true)
+	shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+
+	line, _ := shell.Readline(`\C-xv`, "foo", `\e`, `"`, "a", "yy", `"`, "a", "p", `\r`)
+	if line != "foo\nfoo" {
+		t.Errorf("Readline() = %q, want %q", line, "foo\nfoo")
+	}
+
+	// The registers are listed while reading the register name.
+	listed := false
+
+	for _, frame := range shell.Frames() {
+		listed = listed || strings.Contains(frame.String(), `"a foo`)
+	}
+
+	if !listed {
+		t.Errorf("Register a contents never listed")
+	}
+
+	if frame := shell.Frame().String(); strings.Contains(frame, `"a foo`) {
+		t.Errorf("Frame = %q, want registers not listed anymore", frame)
+	}
+}
+
+func TestShell_CompletionSuggestion(t *testing.T) {
+	shell := NewShell(80, 6)
+	shell.Config.Set("completion-autosuggest", true)
+	shell.Config.Set("autosuggest-strategy", "completion")
+
+	calls := make(map[string]int)
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		calls[string(line)]++
+
+		return readline.CompleteValues().Suggest(strings.Repeat("cd", len(line)%2))
+	}
+
+	// Suggestions are computed once per line, however many times it is refreshed.
+	line, _ := shell.Readline("a", "b", `\C-l`, `\C-l`, "c", `\C-f`, `\r`)
+	if line != "abccd" {
+		t.Errorf("Readline() = %q, want %q", line, "abccd")
+	}
+
+	for _, input := range []string{"a", "ab", "abc"} {
+		if calls[input] != 1 {
+			t.Errorf("completer called %d times for %q, want 1", calls[input], input)
+		}
+	}
+}
+
+func TestShell_CompletionQueryItems(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []string
+		pageSize int
+		want     string
+		hidden   bool
+	}{
+		{name: "Query", keys: []string{`\e?`}, want: "Display all 6 possibilities? (y or n)", hidden: true},
+		{name: "Yes", keys: []string{`\e?`, "y"}, want: "alpha"},
+		{name: "No", keys: []string{`\e?`, "n"}, want: ">", hidden: true},
+		{name: "Page", keys: []string{`\e?`, "y"}, pageSize: 1, want: "1 more completion rows"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 10)
+			shell.Prompt.Primary(func() string { return "> " })
+			shell.Completer = func(line []rune, cursor int) readline.Completions {
+				return readline.CompleteValues("alpha", "beta", "gamma", "delta", "epsilon", "zeta")
+			}
+
+			shell.Config.Set("completion-query-items", 5)
+			shell.Config.Set("completion-page-size", test.pageSize)
+
+			shell.Readline(append(test.keys, `\C-c`)...)
+
+			frame := shell.Frames()[len(test.keys)-1].String()
+			if !strings.Contains(frame, test.want) {
+				t.Errorf("Frame = %q, want %q", frame, test.want)
+			}
+
+			if strings.Contains(frame, "beta") == test.hidden {
+				t.Errorf("Frame = %q, want completions displayed: %v", frame, !test.hidden)
+			}
+		})
+	}
+}
+
+func TestShell_CompleteNowQueryItems(t *testing.T) {
+	shell := NewShell(40, 10)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta", "gamma", "delta", "epsilon", "zeta")
+	}
+
+	shell.Config.Set("completion-query-items", 5)
+
+	// Programmatic completions are displayed without asking the user.
+	shell.Hooks.OnPreRead(func() {
+		if string(*shell.Line()) == "" {
+			shell.CompleteNow()
+		}
+	})
+
+	shell.Readline("x", `\C-c`)
+
+	frame := shell.Frames()[0].String()
+	if strings.Contains(frame, "possibilities") || !strings.Contains(frame, "beta") {
+		t.Errorf("Frame = %q, want completions displayed without query", frame)
+	}
+}
+
+func TestShell_CompleteCommonPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		option string
+		values []string
+		want   string
+	}{
+		{name: "Common prefix", values: []string{"abcd1", "abcd2"}, want: "abcd"},
+		{name: "Ignore case", option: "completion-ignore-case", values: []string{"ABcd1", "ABcd2"}, want: "ABcd"},
+		{name: "Fuzzy matches", option: "completion-fuzzy", values: []string{"xaxb1", "xaxb2"}, want: "xaxb1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 10)
+			shell.Completer = func(line []rune, cursor int) readline.Completions {
+				return readline.CompleteValues(test.values...)
+			}
+
+			if test.option != "" {
+				shell.Config.Set(test.option, true)
+			}
+
+			// The common prefix never replaces a word it does not start with:
+			// the first match is inserted instead, as when there is no prefix.
+			line, _ := shell.Readline("ab", `\t`, `\r`)
+			if line != test.want {
+				t.Errorf("Readline() = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_MenuSelectKeys(t *testing.T) {
+	newShell := func() *Shell {
+		shell := NewShell(40, 10)
+		shell.Prompt.Primary(func() string { return "> " })
+		shell.Completer = func(line []rune, cursor int) readline.Completions {
+			return readline.CompleteValuesDescribed("alpha", "first", "beta", "second")
+		}
+
+		return shell
+	}
+
+	// Emacs commands are not shadowed by the completion menu keys.
+	shell := newShell()
+	if line, _ := shell.Readline("ab ", `\e?`, `\C-t`, `\r`); line != "a b" {
+		t.Errorf("Readline() with transpose-chars in menu = %q, want %q", line, "a b")
+	}
+
+	if frame := shell.Frames()[1].String(); !strings.Contains(frame, "alpha") {
+		t.Errorf("Frame = %q, want the completion menu", frame)
+	}
+
+	// The fields matched by incremental search are cycled with Alt-s.
+	shell = newShell()
+	shell.Readline(`\e?`, `\C-f`, `\M-s`, `\C-g`, `\C-g`, `\C-c`)
+
+	if frame := shell.Frames()[2].String(); !strings.Contains(frame, "inc-search: values") {
+		t.Errorf("Frame = %q, want the searched field in the hint", frame)
+	}
+}
+
+func TestShell_CompletionWidths(t *testing.T) {
+	shell := NewShell(40, 8)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "日本", Description: "wide"},
+			{Value: "abcd", Description: "narrow"},
+			{Value: "é", Description: "\x1b[1maccent\x1b[0m"},
+		})
+	}
+
+	shell.Readline(`\e?`, `\C-c`)
+
+	want := ">\nabcd  -- narrow é  -- accent\n日本  -- wide"
+	if frame := shell.Frames()[0]; frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+func TestShell_CompletionSegments(t *testing.T) {
+	shell := NewShell(40, 8)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("dir/", "notes.txt", "a").
+			IconF(func(value string) (string, string) {
+				if strings.HasSuffix(value, "/") {
+					return "D", "34"
+				}
+
+				return "", ""
+			}).
+			AnnotateF(func(value string) (string, string) {
+				switch value {
+				case "notes.txt":
+					return "4.0K", "2"
+				case "a":
+					return "12B", "2"
+				default:
+					return "", ""
+				}
+			}).
+			DisplayList()
+	}
+
+	shell.Readline(`\e?`, `\C-c`)
+
+	want := ">\n  a          12B\nD dir/\n  notes.txt 4.0K"
+	if frame := shell.Frames()[0]; frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
@@ -0,0 +1,353 @@
+package readlinetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_StatusLine(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.StatusLine(func() string { return "-- " + string(shell.Keymap.Main()) + " --" })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta", "gamma")
+	}
+
+	shell.Readline("x", `\C-h`, `\e?`, `\C-c`)
+
+	// The status line goes below the completions, when there are some.
+	frames := map[int]string{
+		0: "> x\n-- emacs --",
+		2: ">\nalpha  beta  gamma\n-- emacs --",
+	}
+
+	for i, want := range frames {
+		if frame := shell.Frames()[i]; frame.String() != want {
+			t.Errorf("Frame %d = %q, want %q", i, frame, want)
+		}
+	}
+}
+
+func TestShell_Hyperlinks(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValuesDescribed("alpha", "first", "beta", "second").
+			LinkF(func(value string) string { return "https://example.com/" + value })
+	}
+
+	shell.Readline(`\e?`, `\C-c`)
+
+	// Links are not part of the candidates width, and don't break their padding.
+	want := ">\nalpha  -- first\nbeta   -- second"
+	if frame := shell.Frames()[0]; frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+// counter counts the bytes written by a shell to its screen.
+type counter struct {
+	*Screen
+	written int
+}
+
+func (c *counter) Write(out []byte) (int, error) {
+	c.written += len(out)
+	return c.Screen.Write(out)
+}
+
+// newCountingShell returns a shell like NewShell, with a counter of its output.
+func newCountingShell(incremental bool) (*Shell, *counter) {
+	shell := NewShell(40, 10)
+	out := &counter{Screen: shell.Screen}
+
+	shell.Shell = readline.NewShellWithIO(shell.input, out)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Config.Set("incremental-redisplay", incremental)
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta", "gamma")
+	}
+
+	return shell, out
+}
+
+// session is a recorded editing session: typing, moving around, completing.
+var session = []string{
+	"e", "c", "h", "o", " ", "h", "e", "l", "l", "o",
+	`\C-a`, `\C-f`, `\C-f`, `\C-e`, `\C-b`, `\C-b`,
+	`\C-w`, `\C-y`, " ", `\e?`, `\C-g`, `\C-h`, `\C-h`, `\r`,
+}
+
+func TestShell_IncrementalRedisplay(t *testing.T) {
+	full, fullOut := newCountingShell(false)
+	incremental, incrementalOut := newCountingShell(true)
+
+	full.Readline(session...)
+	incremental.Readline(session...)
+
+	if len(full.Frames()) != len(incremental.Frames()) {
+		t.Fatalf("Readline() captured %d frames, want %d", len(incremental.Frames()), len(full.Frames()))
+	}
+
+	// Users must see the same thing, printed with less output.
+	for i, frame := range incremental.Frames() {
+		want := full.Frames()[i]
+
+		if frame.String() != want.String() || frame.Row != want.Row || frame.Col != want.Col {
+			t.Errorf("Frame %d = %q (%d, %d), want %q (%d, %d)",
+				i, frame, frame.Row, frame.Col, want, want.Row, want.Col)
+		}
+	}
+
+	if incrementalOut.written >= fullOut.written {
+		t.Errorf("Incremental redisplay wrote %d bytes, want less than %d", incrementalOut.written, fullOut.written)
+	}
+}
+
+func benchmarkRedisplay(b *testing.B, incremental bool) {
+	b.ReportAllocs()
+
+	var written int
+
+	for i := 0; i < b.N; i++ {
+		shell, out := newCountingShell(incremental)
+		shell.Readline(session...)
+		written += out.written
+	}
+
+	b.ReportMetric(float64(written)/float64(b.N), "bytes/op")
+}
+
+func BenchmarkRedisplay_Full(b *testing.B)        { benchmarkRedisplay(b, false) }
+func BenchmarkRedisplay_Incremental(b *testing.B) { benchmarkRedisplay(b, true) }
+
+func TestShell_RenderMaxFPS(t *testing.T) {
+	unlimited, unlimitedOut := newCountingShell(true)
+	throttled, throttledOut := newCountingShell(true)
+	throttled.Config.Set("render-max-fps", 1)
+
+	// Pasted text is read at once, and only displayed when inserted.
+	unlimited.Readline("hello world", `\C-c`)
+	throttled.Readline("hello world", `\C-c`)
+
+	want := unlimited.Frames()[0]
+	if frame := throttled.Frames()[0]; frame.String() != want.String() || frame.Col != want.Col {
+		t.Errorf("Frame = %q (column %d), want %q (column %d)", frame, frame.Col, want, want.Col)
+	}
+
+	if throttledOut.written >= unlimitedOut.written {
+		t.Errorf("Throttled shell wrote %d bytes, want less than %d", throttledOut.written, unlimitedOut.written)
+	}
+}
+
+func TestShell_PrintAsync(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.PrintAsync("before")
+
+	reads := 0
+	shell.Hooks.OnPreRead(func() {
+		if reads++; reads == 2 {
+			fmt.Fprintf(shell.Logger(), "log %d\n", reads)
+		}
+	})
+
+	line, err := shell.Readline("hi", " there", `\r`)
+	if line != "hi there" || err != nil {
+		t.Fatalf("Readline() = %q, %v, want %q, nil", line, err, "hi there")
+	}
+
+	// Messages are printed above the prompt, which keeps the input line.
+	want := "before\nlog 2\n> hi there"
+	if frame := shell.Frame(); frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+func TestShell_ScreenReader(t *testing.T) {
+	shell := NewShell(40, 10)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Config.Set("screen-reader", true)
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValuesDescribed("alpha", "first", "beta", "second")
+	}
+
+	var announced []string
+	shell.Hooks.OnAnnounce(func(message string) { announced = append(announced, message) })
+
+	shell.Readline(`\t`, `\t`, `\C-c`)
+
+	want := []string{
+		"mode: emacs, menu-select",
+		"candidate: alpha (1 of 2): first",
+		"candidate: beta (2 of 2): second",
+		"mode: emacs",
+	}
+
+	if strings.Join(announced, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Announced %q, want %q", announced, want)
+	}
+}
+
+func TestShell_Bell(t *testing.T) {
+	for _, style := range []string{"none", "visible", "audible"} {
+		shell := NewShell(40, 6)
+		shell.Prompt.Primary(func() string { return "> " })
+		shell.Config.Set("bell-style", style)
+		shell.Completer = func(line []rune, cursor int) readline.Completions {
+			return readline.CompleteValues("alpha", "beta")
+		}
+
+		rings := 0
+		shell.Hooks.OnBell(func() { rings++ })
+
+		line, _ := shell.Readline("x", `\t`, `\e[A`, `\r`)
+		if line != "x" || rings != 2 {
+			t.Errorf("Bell style %s: Readline() = %q with %d rings, want %q with 2 rings", style, line, rings, "x")
+		}
+
+		if frame := shell.Frame(); frame.String() != "> x" {
+			t.Errorf("Bell style %s: Frame = %q, want %q", style, frame, "> x")
+		}
+	}
+}
+
+func TestShell_ScreenReaderOutput(t *testing.T) {
+	shell := NewShell(40, 10)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Config.Set("screen-reader", true)
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	shell.Readline(`\t`, `\C-c`)
+
+	// Without hooks, announcements are printed above the prompt.
+	want := "mode: emacs, menu-select\ncandidate: alpha (1 of 2)\n> alpha"
+	if frame := shell.Frames()[0]; frame.String() != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+func TestShell_LineNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		relative bool
+		keys     []string
+		want     string
+	}{
+		{name: "Single line", keys: []string{"one"}, want: "> one"},
+		{name: "Absolute", keys: []string{"one", `\r`, "two", `\r`, "three"}, want: "> 1 one\n  2 two\n  3 three"},
+		{name: "Relative", relative: true, keys: []string{"one", `\r`, "two", `\r`, "three", `\C-p`}, want: "> 1 one\n  2 two\n  1 three"},
+		{name: "Toggled off", keys: []string{"one", `\r`, "two", `\C-xn`}, want: "> one\n  two"},
+		{name: "Toggled relative", keys: []string{"one", `\r`, "two", `\e1`, `\C-xn`}, want: "> 1 one\n  2 two"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 6)
+			shell.Prompt.Primary(func() string { return "> " })
+			shell.Bind("emacs", `\C-xn`, "toggle-line-numbers")
+			shell.Config.Set("line-numbers", true)
+			shell.Config.Set("relative-line-numbers", test.relative)
+			shell.AcceptMultiline = func(line []rune) bool { return false }
+
+			shell.Readline(append(test.keys, `\C-c`)...)
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-2].String(); frame != test.want {
+				t.Errorf("Frame = %q, want %q", frame, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_HorizontalScroll(t *testing.T) {
+	tests := []struct {
+		name   string
+		scroll bool
+		keys   []string
+		want   string
+		col    int
+	}{
+		{name: "Wrapped", keys: []string{"abcdefghijklmnopqrstuvwxyz0123"}, want: "> abcdefghijklmnopqr\nstuvwxyz0123", col: 12},
+		{name: "Fitting", scroll: true, keys: []string{"abcdefghij"}, want: "> abcdefghij", col: 12},
+		{name: "Scrolled to end", scroll: true, keys: []string{"abcdefghijklmnopqrstuvwxyz0123"}, want: "> <vwxyz0123", col: 12},
+		{name: "Scrolled to start", scroll: true, keys: []string{"abcdefghijklmnopqrstuvwxyz0123", `\C-a`}, want: "> abcdefghijklmnop>", col: 2},
+		{name: "Scrolled in middle", scroll: true, keys: []string{"abcdefghijklmnopqrstuvwxyz0123", `\C-a`, `\e2`, `\e0`, `\C-f`}, want: "> <nopqrstuvwxyz01>", col: 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(20, 6)
+			shell.Prompt.Primary(func() string { return "> " })
+			shell.Config.Set("horizontal-scroll-mode", test.scroll)
+
+			shell.Readline(append(test.keys, `\C-c`)...)
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-2]; frame.String() != test.want || frame.Col != test.col {
+				t.Errorf("Frame = %q (col %d), want %q (col %d)", frame, frame.Col, test.want, test.col)
+			}
+		})
+	}
+}
+
+func TestShell_HintLevels(t *testing.T) {
+	for _, timeout := range []int{0, -1} {
+		shell := NewShell(40, 8)
+		shell.Prompt.Primary(func() string { return "> " })
+		shell.Config.Set("hint-timeout", timeout)
+
+		reads := 0
+		shell.Hooks.OnPreRead(func() {
+			if reads++; reads == 1 {
+				shell.Hint.SetLevel(readline.HintWarning, "first line\nsecond line")
+			}
+		})
+
+		shell.Readline("a", "b", `\C-c`)
+		frames := shell.Frames()
+
+		// Multi-line messages are displayed below the line, and the
+		// shell does not reset them, but they can expire at a keypress.
+		want := "> a\nfirst line\nsecond line"
+		if frame := frames[0].String(); frame != want {
+			t.Errorf("Timeout %d: Frame = %q, want %q", timeout, frame, want)
+		}
+
+		want = "> ab\nfirst line\nsecond line"
+		if timeout < 0 {
+			want = "> ab"
+		}
+
+		if frame := frames[1].String(); frame != want {
+			t.Errorf("Timeout %d: Frame = %q, want %q", timeout, frame, want)
+		}
+	}
+}
+
+func TestShell_LowBandwidthToggle(t *testing.T) {
+	shell := NewShell(40, 4)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Prompt.Right(func() string { return "right" })
+	shell.Bind("emacs", `\C-xl`, "low-bandwidth-toggle")
+
+	shell.Readline("a", `\C-xl`, `\C-c`)
+
+	// Right prompts are not displayed in low-bandwidth mode.
+	if frame := shell.Frames()[0].String(); !strings.Contains(frame, "right") {
+		t.Errorf("Frame = %q, want the right prompt", frame)
+	}
+
+	if frame := shell.Frames()[1].String(); strings.Contains(frame, "right") {
+		t.Errorf("Frame = %q, want no right prompt in low-bandwidth mode", frame)
+	}
+
+	if !shell.Config.GetBool("low-bandwidth") {
+		t.Error("low-bandwidth option not set by low-bandwidth-toggle")
+	}
+}
@@ -0,0 +1,689 @@
+package readlinetest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_MultipleCursors(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Empty line", keys: []string{`\C-xo`, "a"}, line: "a"},
+		{name: "Insert at occurrences", keys: []string{"ab ab ab", `\C-a`, `\C-xo`, "x"}, line: "xab xab xab"},
+		{name: "Delete at occurrences", keys: []string{"ab ab ab", `\C-a`, `\C-xo`, `\C-d`}, line: "b b b"},
+		{name: "Backward delete", keys: []string{"ab ab ab", `\C-a`, `\C-xo`, `\C-f`, `\C-?`}, line: "babab"},
+		{name: "Single undo", keys: []string{"ab ab", `\C-a`, `\C-xo`, "x", `\C-d`, `\C-_`}, line: "xab xab"},
+		{name: "Added cursors", keys: []string{"a b", `\C-a`, `\C-xc`, `\C-f\C-f`, "-"}, line: "-a -b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Bind("emacs", `\C-xo`, "select-all-occurrences")
+			shell.Bind("emacs", `\C-xc`, "add-cursor")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_EmacsCommands(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "alpha.txt"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("READLINE_TEST_VARIABLE", "value")
+
+	tests := []struct {
+		keys []string
+		line string
+	}{
+		{keys: []string{"ls /usr/local/", `\C-x\C-f`}, line: "ls /usr/"},
+		{keys: []string{"HELLO WORLD", `\eb`, `\el`}, line: "HELLO world"},
+		{keys: []string{"hello wORLD", `\C-a`, `\ec`, `\eu`}, line: "Hello WORLD"},
+		{keys: []string{"a   b", `\C-b`, `\C-b`, `\e\\`}, line: "ab"},
+		{keys: []string{"echo one", `\e\C-^`}, line: "echo oneone"},
+		{keys: []string{"cat " + dir + "/al", `\C-x/`}, line: "cat " + dir + "/alpha.txt"},
+		{keys: []string{"echo $READLINE_TEST_VAR", `\e$`}, line: "echo $READLINE_TEST_VARIABLE"},
+	}
+
+	for _, test := range tests {
+		shell := NewShell(80, 6)
+		shell.Bind("emacs", `\C-x\C-f`, "unix-filename-rubout")
+		shell.Bind("emacs", `\C-x/`, "complete-filename")
+
+		line, _ := shell.Readline(append(test.keys, `\r`)...)
+		if line != test.line {
+			t.Errorf("Keys %q: Readline() = %q, want %q", test.keys, line, test.line)
+		}
+	}
+}
+
+func TestShell_UniversalArgument(t *testing.T) {
+	tests := []struct {
+		keys []string
+		line string
+	}{
+		{keys: []string{`\C-u`, "a"}, line: "aaaa"},
+		{keys: []string{`\C-u`, `\C-u`, "a"}, line: strings.Repeat("a", 16)},
+		{keys: []string{`\C-u`, "1", "2", "a"}, line: strings.Repeat("a", 12)},
+		{keys: []string{`\C-u`, "3", `\C-u`, "5"}, line: "555"},
+		{keys: []string{`\e3`, "b"}, line: "bbb"},
+		{keys: []string{"abc", `\C-u`, "2", `\C-b`, "x"}, line: "axbc"},
+	}
+
+	for _, test := range tests {
+		shell := NewShell(80, 6)
+		shell.Bind("emacs", `\C-u`, "universal-argument")
+
+		line, _ := shell.Readline(append(test.keys, `\r`)...)
+		if line != test.line {
+			t.Errorf("Keys %q: Readline() = %q, want %q", test.keys, line, test.line)
+		}
+	}
+
+	// The pending argument is displayed as a hint.
+	shell := NewShell(80, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.Bind("emacs", `\C-u`, "universal-argument")
+	shell.Readline(`\C-u`, `\C-u`, `\C-c`)
+
+	if frame := shell.Frames()[1]; frame.String() != ">\n(arg: 16)" {
+		t.Errorf("Frame = %q, want %q", frame, ">\n(arg: 16)")
+	}
+}
+
+func TestShell_OverwriteMode(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Replace characters", keys: []string{"abcd", `\C-a`, `\C-x\C-o`, "xy"}, line: "xycd"},
+		{name: "Append at end of line", keys: []string{"ab", `\C-b`, `\C-x\C-o`, "xyz"}, line: "axyz"},
+		{name: "Toggle off", keys: []string{"abcd", `\C-a`, `\C-x\C-o`, "x", `\C-x\C-o`, "y"}, line: "xybcd"},
+		{name: "Numeric argument", keys: []string{"abcd", `\C-a`, `\e1`, `\C-x\C-o`, `\e1`, `\C-x\C-o`, "x"}, line: "xbcd"},
+		{name: "Negative numeric argument", keys: []string{"abcd", `\C-a`, `\e-`, `\C-x\C-o`, "x"}, line: "xabcd"},
+		{name: "Repeated character", keys: []string{"abcd", `\C-a`, `\C-x\C-o`, `\e3`, "x"}, line: "xxxd"},
+		{name: "Rubout", keys: []string{"abcd", `\C-b`, `\C-x\C-o`, `\C-?`}, line: "ab d"},
+		{name: "Rubout at end of line", keys: []string{"abcd", `\C-x\C-o`, `\C-?`}, line: "abc"},
+		{name: "Undo replaced characters", keys: []string{"abcd", `\C-a`, `\C-x\C-o`, "xy", `\C-_`}, line: "abcd"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+
+	// Each line starts in insert mode.
+	shell := NewShell(80, 6)
+	shell.Readline(`\C-x\C-o`, `\r`)
+
+	if line, _ := shell.Readline("ab", `\C-a`, "x", `\r`); line != "xab" {
+		t.Errorf("Readline() = %q, want %q", line, "xab")
+	}
+}
+
+func TestShell_Region(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Kill region", keys: []string{"hello world", `\C-a`, `\e `, `\ef`, `\ew`, `\C-e`, `\C-y`}, line: " worldhello"},
+		{name: "Copy region", keys: []string{"hello world", `\C-a`, `\e `, `\ef`, `\C-xw`, `\C-e`, `\C-y`}, line: "hello worldhello"},
+		{name: "Exchange point and mark", keys: []string{"hello world", `\e `, `\C-a`, `\C-x\C-x`, "!"}, line: "hello world!"},
+		{name: "Kill region backward", keys: []string{"hello world", `\e `, `\eb`, `\ew`}, line: "hello "},
+		{name: "Mark at numeric argument", keys: []string{"hello world", `\e3`, `\e `, `\ew`}, line: "hel"},
+		{name: "Exchange without mark", keys: []string{"abc", `\C-x\C-x`, `\ew`}, line: ""},
+		{name: "Kill region without mark", keys: []string{"abc", `\ew`}, line: "abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Bind("emacs", `\C-xw`, "copy-region-as-kill")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+type testExpander struct{}
+
+func (testExpander) ExpandLine(line string) (string, error) {
+	if strings.Contains(line, "$(") {
+		return line, errors.New("bad substitution")
+	}
+
+	line, _ = testExpander{}.ExpandHistory(line)
+	line, _ = testExpander{}.ExpandAliases(line)
+
+	return strings.ReplaceAll(line, "$X", "x"), nil
+}
+
+func (testExpander) ExpandHistory(line string) (string, error) {
+	return strings.ReplaceAll(line, "!!", "last"), nil
+}
+
+func (testExpander) ExpandAliases(line string) (string, error) {
+	if strings.HasPrefix(line, "ll") {
+		return "ls -l" + line[2:], nil
+	}
+
+	return line, nil
+}
+
+func TestShell_Expansions(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+
+	tests := []struct {
+		name     string
+		keys     []string
+		line     string
+		expander bool
+	}{
+		{name: "No expander", keys: []string{"ll $X", `\e\C-e`}, line: "ll $X"},
+		{name: "Shell expansions", keys: []string{"ll $X !!", `\e\C-e`}, line: "ls -l x last", expander: true},
+		{name: "History expansion", keys: []string{"ll !!", `\e^`}, line: "ll last", expander: true},
+		{name: "Alias expansion", keys: []string{"ll !!", `\C-xa`}, line: "ls -l !!", expander: true},
+		{name: "History and alias expansion", keys: []string{"ll !!", `\C-xh`}, line: "ls -l last", expander: true},
+		{name: "Cursor at end of line", keys: []string{"ll", `\e\C-e`, "a"}, line: "ls -la", expander: true},
+		{name: "Expansion error", keys: []string{"ll $(", `\e\C-e`}, line: "ll $(", expander: true},
+		{name: "Tilde expansion", keys: []string{"cd ~/src", `\e&`}, line: "cd /home/test/src"},
+		{name: "Tilde expansion before cursor", keys: []string{"ls ~", `\e&`, "/"}, line: "ls /home/test/"},
+		{name: "Tilde not leading the word", keys: []string{"ls a~", `\e&`}, line: "ls a~"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Bind("emacs", `\C-xa`, "alias-expand-line")
+			shell.Bind("emacs", `\C-xh`, "history-and-alias-expand-line")
+
+			if test.expander {
+				shell.SetExpander(testExpander{})
+			}
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+
+	// Expansion errors are shown in the hint area.
+	shell := NewShell(80, 6)
+	shell.SetExpander(testExpander{})
+	shell.Readline("ll $(", `\e\C-e`, `\C-c`)
+
+	if frame := shell.Frames()[len(shell.Frames())-2].String(); !strings.Contains(frame, "bad substitution") {
+		t.Errorf("Frame = %q, want the expansion error", frame)
+	}
+}
+
+func TestShell_WordStyle(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		keys  []string
+		line  string
+	}{
+		{name: "Default backward kill word", style: "default", keys: []string{"ls ../src-dir", `\e\C-h`}, line: "ls ../src-"},
+		{name: "Bash backward kill word", style: "bash", keys: []string{"ls ../src-dir", `\e\C-h`, `\e\C-h`}, line: "ls ../"},
+		{name: "Whitespace backward kill word", style: "whitespace", keys: []string{"ls ../src-dir", `\e\C-h`}, line: "ls "},
+		{name: "Unix word rubout", style: "bash", keys: []string{"ls ../src-dir", `\C-w`}, line: "ls "},
+		{name: "Normal forward word", style: "normal", keys: []string{"a foo-bar baz", `\C-a`, `\ef`, `\ef`, "!"}, line: "a foo-bar! baz"},
+		{name: "Shell kill word", style: "shell", keys: []string{`echo "a b" c`, `\C-a`, `\ef`, `\ed`}, line: "echo c"},
+		{name: "Bash transpose words", style: "bash", keys: []string{"foo-bar", `\et`}, line: "bar-foo"},
+		{name: "Bash capitalize words", style: "bash", keys: []string{"foo-bar", `\C-a`, `\e2`, `\ec`}, line: "Foo-Bar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Config.Set("word-style", test.style)
+			shell.Config.Set("word-chars", "-")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_Autopairs(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs string
+		keys  []string
+		line  string
+	}{
+		{name: "Insert closer", keys: []string{"echo (a"}, line: "echo (a)"},
+		{name: "Jump over closer", keys: []string{"f(x)", `\C-e`, "!"}, line: "f(x)!"},
+		{name: "Delete pair", keys: []string{"f[", `\C-?`}, line: "f"},
+		{name: "Nested pairs", keys: []string{`{["`}, line: `{[""]}`},
+		{name: "Escaped opener", keys: []string{`\\(`}, line: `\(`},
+		{name: "Configured pairs", pairs: "<>", keys: []string{"a<b", `\C-e`, "(c"}, line: "a<b>(c"},
+		{name: "Unbalanced continues", keys: []string{`echo "a`, `\C-d`, `\r`, `b"`}, line: "echo \"a\nb\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Config.Set("autopairs", true)
+
+			if test.pairs != "" {
+				shell.Config.Set("autopairs-chars", test.pairs)
+			}
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_SmartIndent(t *testing.T) {
+	tests := []struct {
+		name   string
+		indent bool
+		keys   []string
+		line   string
+	}{
+		{name: "No indentation", keys: []string{"  f(", `\r`, "a)"}, line: "  f(\na)"},
+		{name: "Indent after bracket", indent: true, keys: []string{"  f(", `\r`, "a", `\r`, ")"}, line: "  f(\n      a\n      )"},
+		{name: "Open line below", indent: true, keys: []string{"  {", `\C-xv`, `\e`, "o", "a}"}, line: "  {\n      a}"},
+		{name: "Open line above", indent: true, keys: []string{"  x", `\C-xv`, `\e`, "O", "y"}, line: "  y\n  x"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.AcceptMultiline = readline.ShellAcceptMultiline
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.Config.Set("smart-indent", test.indent)
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_EditCommandLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("editing in an editor is not supported on Windows")
+	}
+
+	dir := t.TempDir()
+	args := filepath.Join(dir, "args")
+
+	// The editors write their arguments, and replace the file contents.
+	writeEditor := func(name, script string) string {
+		path := filepath.Join(dir, name)
+		script = "#!/bin/sh\necho \"$PREFIX$*\" > " + args + "\nfor file; do :; done\n" + script + "\n"
+
+		if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		editor    string
+		extension string
+		command   string
+		history   string
+		keys      []string
+		line      string
+		args      string
+	}{
+		{name: "Vim position", editor: writeEditor("vim", `printf 'edited' > "$file"`), keys: []string{"abc", `\C-b`}, line: "edited", args: "+call cursor(1, 3) "},
+		{name: "Multibyte position", editor: writeEditor("vim", `printf 'edited' > "$file"`), history: "échec\nété", keys: []string{`\C-p`, `\C-b`}, line: "edited", args: "+call cursor(2, 3) "},
+		{name: "Emacs position", editor: writeEditor("emacs", `printf 'edited' > "$file"`), keys: []string{"abc", `\C-a`}, line: "edited", args: "+1:1 "},
+		{name: "File extension", editor: writeEditor("ed", `printf 'edited' > "$file"`), extension: "sh", keys: []string{"abc"}, line: "edited", args: "+1 "},
+		{name: "Cancelled", editor: writeEditor("nano", `printf 'edited' > "$file"; exit 1`), keys: []string{"abc"}, line: "abc", args: "+1,4 "},
+		{name: "Command template", editor: writeEditor("kak", `printf 'edited' > "$file"`), command: "PREFIX=split: {}", keys: []string{"abc"}, line: "edited", args: "split:+1:4 "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("VISUAL", test.editor)
+
+			shell := NewShell(80, 6)
+			shell.Config.Set("editor-file-extension", test.extension)
+			shell.Config.Set("editor-command", test.command)
+
+			if test.history != "" {
+				hist := readline.NewInMemoryHistory()
+				hist.Write(test.history)
+				shell.History.Add("local", hist)
+			}
+
+			line, _ := shell.Readline(append(test.keys, `\C-x\C-e`, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			written, _ := os.ReadFile(args)
+			if got := string(written); !strings.HasPrefix(got, test.args) || !strings.HasSuffix(strings.TrimSpace(got), test.extension) {
+				t.Errorf("Editor arguments = %q, want %q and the file (with extension %q)", got, test.args, test.extension)
+			}
+		})
+	}
+}
+
+func TestShell_UndoGrouping(t *testing.T) {
+	tests := []struct {
+		name     string
+		grouping string
+		keys     []string
+		line     string
+	}{
+		{name: "Insert session", grouping: "insert", keys: []string{"foo bar", `\C-_`}, line: ""},
+		{name: "Words", grouping: "word", keys: []string{"foo bar baz", `\C-_`}, line: "foo bar "},
+		{name: "Words with count", grouping: "word", keys: []string{"foo bar baz", `\e2`, `\C-_`}, line: "foo "},
+		{name: "Commands", grouping: "command", keys: []string{"foo", `\C-_`}, line: "fo"},
+		{name: "Redo", grouping: "word", keys: []string{"foo bar", `\C-_`, `\C-x\C-g`}, line: "foo bar"},
+		{name: "Negative undo redoes", grouping: "word", keys: []string{"foo bar", `\e2`, `\C-_`, `\e-`, `\C-_`}, line: "foo "},
+		{name: "Undo all", grouping: "word", keys: []string{"foo bar baz", `\C-xU`}, line: ""},
+		{name: "Redo after undo all", grouping: "word", keys: []string{"foo bar baz", `\C-xU`, `\C-x\C-g`}, line: "foo "},
+		{name: "Vi redo", grouping: "insert", keys: []string{`\C-xv`, "foo", `\e`, "u", "U"}, line: "foo"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+			shell.Config.Set("undo-grouping", test.grouping)
+			shell.Bind("emacs", `\C-xU`, "undo-all")
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_ReadOnly(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+
+	hist := readline.NewInMemoryHistory()
+	hist.Write("old")
+	shell.History.Add("local", hist)
+
+	rings := 0
+	shell.Hooks.OnBell(func() { rings++ })
+
+	shell.SetBuffer("ls -l", 5)
+	shell.SetReadOnly(true)
+
+	// Insertions, kills and history moves are refused, moves are not.
+	line, _ := shell.Readline("x", `\C-a`, `\C-k`, `\e[A`, `\C-f`, `\r`)
+	if line != "ls -l" || rings != 3 {
+		t.Errorf("Readline() = %q with %d rings, want %q with 3 rings", line, rings, "ls -l")
+	}
+
+	frames := shell.Frames()
+	if frame := frames[len(frames)-2]; frame.Col != 3 {
+		t.Errorf("Cursor column = %d, want 3", frame.Col)
+	}
+
+	shell.SetReadOnly(false)
+
+	if line, _ = shell.Readline("ok", `\r`); line != "ok" {
+		t.Errorf("Readline() = %q, want %q", line, "ok")
+	}
+}
+
+func TestShell_RegionAPI(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	var region string
+
+	shell.AddCommand("select-word", func(rl *readline.Shell) { rl.SetRegion(5, 8) })
+	shell.AddCommand("get-region", func(rl *readline.Shell) { region, _, _, _ = rl.Region() })
+	shell.AddCommand("quote-region", func(rl *readline.Shell) { rl.SurroundRegion('"', '"') })
+	shell.AddCommand("upcase-region", func(rl *readline.Shell) {
+		if text, _, _, ok := rl.Region(); ok {
+			rl.ReplaceRegion(strings.ToUpper(text))
+		}
+	})
+
+	shell.Bind("", `\C-xs`, "select-word")
+	shell.Bind("", `\C-xg`, "get-region")
+	shell.Bind("", `\C-xq`, "quote-region")
+	shell.Bind("", `\C-xu`, "upcase-region")
+
+	line, _ := shell.Readline("echo foo bar", `\C-xs`, `\C-xg`, `\C-xu`, `\C-xq`, "!", `\r`)
+	if region != "foo" || line != "echo FOO! bar" {
+		t.Errorf("Readline() = %q with region %q, want %q with region %q", line, region, "echo FOO! bar", "foo")
+	}
+
+	line, _ = shell.Readline("echo foo bar", `\C-xs`, `\C-xq`, `\C-_`, `\C-xs`, `\C-xq`, `\C-xq`, `\r`)
+	if line != `echo "foo" bar` {
+		t.Errorf("Readline() = %q, want %q", line, `echo "foo" bar`)
+	}
+}
+
+func TestShell_TransformOperators(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Rot13 motion", keys: []string{`\C-xv`, "foo bar", `\e`, "0", "g?iw"}, line: "sbb bar"},
+		{name: "Rot13 line", keys: []string{`\C-xv`, "Foo bar", `\e`, "g?g?"}, line: "Sbb one"},
+		{name: "Rot13 visual", keys: []string{`\C-xv`, "foo bar", `\e`, "0", "ve", "g?"}, line: "sbb bar"},
+		{name: "Custom operator", keys: []string{`\C-xv`, "foo bar", `\e`, "0w", "gs$"}, line: "foo BAR"},
+		{name: "Emacs word", keys: []string{"foo bar", `\C-a`, `\C-xs`, "!"}, line: "FOO! bar"},
+		{name: "Emacs region", keys: []string{"a b?", `\C-a`, `\e `, `\C-e`, `\C-xe`}, line: "a+b%3F"},
+		{name: "Decode", keys: []string{"Zm9v", `\e `, `\C-a`, `\C-xd`}, line: "foo"},
+		{name: "Decode error", keys: []string{"Zm9v!", `\e `, `\C-a`, `\C-xd`}, line: "Zm9v!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.AddOperator("upcase", strings.ToUpper)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.Bind("emacs", `\C-xs`, "upcase")
+			shell.Bind("emacs", `\C-xe`, "url-encode")
+			shell.Bind("emacs", `\C-xd`, "base64-decode")
+			shell.Bind("vi-command", "gs", "upcase")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_ReplaceInLine(t *testing.T) {
+	chars := func(text string) []string { return strings.Split(text, "") }
+
+	tests := []struct {
+		name string
+		keys []string
+		line string
+		hint string
+	}{
+		{name: "First", keys: chars("o\rO\r"), line: "fOo bar foo"},
+		{name: "Global", keys: chars("o+\rX/g\r"), line: "fX bar fX"},
+		{name: "Submatches", keys: chars("(f)(o+)\r$2$1/g\r"), line: "oof bar oof"},
+		{name: "Ignore case", keys: chars("BAR\rbaz/i\r"), line: "foo baz foo"},
+		{name: "Literal slash", keys: chars(`bar` + "\r" + `a\/b` + "\r"), line: "foo a/b foo"},
+		{name: "Confirm", keys: chars("foo\rX/gc\rny"), line: "foo bar X"},
+		{name: "Confirm all", keys: chars("o\r0/gc\rna"), line: "fo0 bar f00"},
+		{name: "Confirm quit", keys: chars("o\r0/gc\ryq"), line: "f0o bar foo"},
+		{name: "Abort", keys: []string{"f", `\e`}, line: "foo bar foo"},
+		{name: "Preview", keys: append(chars("o"), `\e`), line: "foo bar foo", hint: "replace: o_ (4 matches)"},
+		{name: "No match", keys: append(chars("z"), `\e`), line: "foo bar foo", hint: "replace: z_ (no matches)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+
+			keys := append([]string{"foo bar foo", `\e%`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if test.hint == "" {
+				return
+			}
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-3].String(); !strings.Contains(frame, test.hint) {
+				t.Errorf("Frame = %q, want hint %q", frame, test.hint)
+			}
+		})
+	}
+}
+
+func TestShell_SearchBuffer(t *testing.T) {
+	chars := func(text string) []string { return strings.Split(text, "") }
+
+	tests := []struct {
+		name string
+		keys []string
+		line string
+		hint string
+	}{
+		{name: "Backward", keys: append([]string{`\C-xr`}, chars("bar\rX")...), line: "foo bar\nfoo Xbar"},
+		{name: "Backward typing", keys: append([]string{`\C-xr`}, chars("b\bfo\rX")...), line: "foo bar\nXfoo bar"},
+		{name: "Forward wraps", keys: append([]string{`\C-xs`}, chars("bar\rX")...), line: "foo Xbar\nfoo bar"},
+		{name: "Abort", keys: append([]string{`\C-xr`}, append(chars("foo"), `\e`, "X")...), line: "foo bar\nfoo barX"},
+		{name: "No match", keys: append([]string{`\C-xs`}, append(chars("baz"), `\e`)...), line: "foo bar\nfoo bar", hint: "search forward: baz_ (no matches)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Bind("emacs", `\C-xs`, "search-buffer-forward")
+			shell.Bind("emacs", `\C-xr`, "search-buffer-backward")
+			shell.SetBuffer("foo bar\nfoo bar", -1)
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if test.hint == "" {
+				return
+			}
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-3].String(); !strings.Contains(frame, test.hint) {
+				t.Errorf("Frame = %q, want hint %q", frame, test.hint)
+			}
+		})
+	}
+}
+
+func TestShell_DabbrevExpand(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Buffer", keys: []string{"fetch fe", `\e/`}, line: "fetch fetch"},
+		{name: "History", keys: []string{"git fe", `\e/`}, line: "git feature-branch"},
+		{name: "Cycle", keys: []string{"fetch fe", `\e/`, `\e/`}, line: "fetch feature-branch"},
+		{name: "Exhausted", keys: []string{"fetch fe", `\e/`, `\e/`, `\e/`}, line: "fetch fe"},
+		{name: "After cursor", keys: []string{"in install.sh", `\C-a`, `\C-f`, `\C-f`, `\e/`}, line: "install.sh install.sh"},
+		{name: "No match", keys: []string{"xyz", `\e/`}, line: "xyz"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+
+			shell.Readline("git checkout feature-branch", `\r`)
+			shell.Readline("make install", `\r`)
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_CorrectCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		correct bool
+		keys    []string
+		line    string
+		hint    string
+	}{
+		{name: "Accept correction", correct: true, keys: []string{"gti status", `\r`, "y"}, line: "git status"},
+		{name: "Accept as is", correct: true, keys: []string{"gti status", `\r`, "n"}, line: "gti status"},
+		{name: "Edit", correct: true, keys: []string{"gti status", `\r`, "e", `\C-a`, `\C-d`, `\r`}, line: "ti status"},
+		{name: "Correct word", correct: true, keys: []string{"gti status", `\r`, `\e`}, hint: "correct gti to git (y, n, e)"},
+		{name: "Known word", correct: true, keys: []string{"ls -l", `\r`}, line: "ls -l"},
+		{name: "Pipeline", keys: []string{"ls | grpe foo", `\C-a`, `\es`, `\r`}, line: "ls | grpe foo"},
+		{name: "On demand", keys: []string{"ls | grpe foo", `\es`, `\r`}, line: "ls | grep foo"},
+		{name: "On demand menu", keys: []string{"lsx -a", `\C-a`, `\es`, `\t`, `\t`, `\r`, `\r`}, line: "lsd -a"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Config.Set("correct-command", test.correct)
+			shell.SetCorrector(func() []string { return []string{"git", "grep", "ls", "lsd", "lsof"} })
+
+			line, _ := shell.Readline(test.keys...)
+			if test.hint == "" && line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if test.hint == "" {
+				return
+			}
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-3].String(); !strings.Contains(frame, test.hint) {
+				t.Errorf("Frame = %q, want hint %q", frame, test.hint)
+			}
+		})
+	}
+}
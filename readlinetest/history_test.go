@@ -0,0 +1,212 @@
+package readlinetest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_AutosuggestStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		keys     []string
+		line     string
+	}{
+		{name: "History", strategy: "completion history", keys: []string{"git s", `\C-f`}, line: "git status"},
+		{name: "Custom", strategy: "greet history", keys: []string{"hello ", `\C-f`}, line: "hello world"},
+		{name: "Custom fallback", strategy: "greet,history", keys: []string{"git s", `\C-f`}, line: "git status"},
+		{name: "Custom first", strategy: "greet history", keys: []string{"git ", `\C-f`}, line: "git world"},
+		{name: "History first", strategy: "history greet", keys: []string{"git ", `\C-f`}, line: "git status"},
+		{name: "Not listed", strategy: "history", keys: []string{"hello ", `\C-f`}, line: "hello "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+			shell.Readline("git status", `\r`)
+
+			shell.Config.Set("history-autosuggest", true)
+			shell.Config.Set("autosuggest-strategy", test.strategy)
+			shell.AddSuggester("greet", func(line string) string {
+				if strings.HasSuffix(line, " ") {
+					return "world"
+				}
+
+				return ""
+			})
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if frame := shell.Frames()[len(test.keys)-2].String(); !strings.Contains(frame, strings.TrimSpace(test.line)) {
+				t.Errorf("Frame = %q, want suggestion %q", frame, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_AutosuggestPartialAccept(t *testing.T) {
+	tests := []struct {
+		name   string
+		motion bool
+		keys   []string
+		line   string
+	}{
+		{name: "Word", keys: []string{"git c", `\C-xw`}, line: "git commit"},
+		{name: "Words", keys: []string{"git c", `\e3`, `\C-xw`}, line: "git commit -m"},
+		{name: "Line", keys: []string{"echo o", `\C-xl`}, line: "echo one"},
+		{name: "Motion", motion: true, keys: []string{"git c", `\C-f`}, line: "git commit -m fix"},
+		{name: "No motion", keys: []string{"git c", `\C-f`, `\C-e`}, line: "git c"},
+		{name: "Clear", motion: true, keys: []string{"git c", `\C-xc`, `\C-f`}, line: "git c"},
+		{name: "Clear until edit", motion: true, keys: []string{"git c", `\C-xc`, "o", `\C-f`}, line: "git commit -m fix"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hist := readline.NewInMemoryHistory()
+			hist.Write("echo one\necho two")
+			hist.Write("git commit -m fix")
+
+			shell := NewShell(80, 6)
+			shell.History.Add("local", hist)
+			shell.Config.Set("history-autosuggest", true)
+			shell.Config.Set("autosuggest-motion-accept", test.motion)
+			shell.Bind("emacs", `\C-xw`, "autosuggest-accept-word")
+			shell.Bind("emacs", `\C-xl`, "autosuggest-accept-line-to-cursor")
+			shell.Bind("emacs", `\C-xc`, "autosuggest-clear")
+
+			line, _ := shell.Readline(append(test.keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_HistoryMenu(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []string
+		line  string
+		frame string
+	}{
+		{name: "Menu", keys: []string{"x", `\C-xh`}, frame: `3 .+ b2 +2 .+ c3 +0 .+ a1`},
+		{name: "Select", keys: []string{"x", `\C-xh`, `\t`, `\t`, `\r`, `\r`}, line: "c3"},
+		{name: "Search", keys: []string{`\C-xh`, `\C-f`, "a", `\t`, `\r`, `\r`}, line: "a1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hist := readline.NewInMemoryHistory()
+			for _, line := range []string{"a1", "b2", "c3", "b2"} {
+				hist.Write(line)
+			}
+
+			shell := NewShell(80, 10)
+			shell.History.Add("local", hist)
+			shell.Config.Set("history-menu-size", 3)
+			shell.Bind("emacs", `\C-xh`, "history-menu")
+
+			line, _ := shell.Readline(test.keys...)
+			if test.frame == "" && line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if test.frame == "" {
+				return
+			}
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-2].String(); !regexp.MustCompile(test.frame).MatchString(frame) {
+				t.Errorf("Frame = %q, want match of %q", frame, test.frame)
+			}
+		})
+	}
+}
+
+func TestShell_HistoryMergedSources(t *testing.T) {
+	tests := []struct {
+		name  string
+		keys  []string
+		line  string
+		frame string
+	}{
+		{name: "Current", keys: []string{`\C-r`, "r", `\r`, `\r`}, line: "rm a"},
+		{name: "Merged", keys: []string{`\C-r`, `\M-a`, "r", `\r`, `\r`}, line: "rm b"},
+		{name: "Tags", keys: []string{`\C-r`, `\M-a`, `\M-t`}, frame: `1 +remote +rm b`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			local := readline.NewInMemoryHistory()
+			local.Write("ls")
+			local.Write("rm a")
+
+			remote := readline.NewInMemoryHistory()
+			remote.Write("cd")
+			remote.Write("rm b")
+
+			shell := NewShell(80, 10)
+			shell.History.Add("local", local)
+			shell.History.Add("remote", remote)
+
+			line, _ := shell.Readline(test.keys...)
+			if test.frame == "" && line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+
+			if test.frame == "" {
+				return
+			}
+
+			frames := shell.Frames()
+			if frame := frames[len(frames)-2].String(); !regexp.MustCompile(test.frame).MatchString(frame) {
+				t.Errorf("Frame = %q, want match of %q", frame, test.frame)
+			}
+		})
+	}
+}
+
+func TestShell_ReportCommandResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		line   string
+	}{
+		{name: "Success", status: 0, line: "git pull"},
+		{name: "Failure", status: 1, line: "git push"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hist := readline.NewInMemoryHistory()
+
+			shell := NewShell(80, 6)
+			shell.History.Add("local", hist)
+
+			shell.Readline("git push", `\r`)
+			shell.ReportCommandResult(0, time.Second)
+			shell.Readline("git pull", `\r`)
+			shell.ReportCommandResult(test.status, time.Second)
+
+			result, err := hist.(readline.ResultHistory).GetResult(hist.Len() - 1)
+			if err != nil || result == nil || result.Status != test.status {
+				t.Errorf("GetResult() = %v, %v, want status %d", result, err, test.status)
+			}
+
+			shell.Config.Set("history-autosuggest", true)
+
+			line, _ := shell.Readline("git p", `\C-f`, `\r`)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package readlinetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_ModeChangeHook(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+
+	var modes []string
+
+	shell.Hooks.OnModeChange(func(main, local string) {
+		modes = append(modes, main+"/"+local)
+	})
+
+	shell.Readline("a", `\C-xv`, `\e`, "d", "w", "v", `\e`, `\r`)
+
+	want := []string{
+		"emacs/",
+		"vi-insert/",
+		"vi-command/",
+		"vi-command/vi-opp",
+		"vi-command/",
+		"vi-command/vi-visual",
+		"vi-command/",
+	}
+
+	if !reflect.DeepEqual(modes, want) {
+		t.Errorf("OnModeChange() modes = %q, want %q", modes, want)
+	}
+
+	// Calls starting in the mode the last one ended with do not notify it.
+	modes = nil
+	shell.Readline(`\r`)
+
+	if len(modes) > 0 {
+		t.Errorf("OnModeChange() modes on same mode = %q, want none", modes)
+	}
+
+	// But calls starting in another mode do.
+	shell.Keymap.SetMain("emacs")
+	shell.Readline(`\r`)
+
+	if want := []string{"emacs/"}; !reflect.DeepEqual(modes, want) {
+		t.Errorf("OnModeChange() modes on next call = %q, want %q", modes, want)
+	}
+}
+
+func TestShell_AcceptHook(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	type accepted struct {
+		line string
+		err  error
+	}
+
+	var lines []accepted
+
+	shell.Hooks.OnAccept(func(line string, err error) {
+		lines = append(lines, accepted{line, err})
+	})
+
+	shell.Readline("ls", `\r`)
+	shell.Readline("ab", `\C-c`)
+
+	if len(lines) != 2 {
+		t.Fatalf("OnAccept() called %d times, want 2", len(lines))
+	}
+
+	if lines[0].line != "ls" || lines[0].err != nil {
+		t.Errorf("OnAccept() = %q, %v, want %q, nil", lines[0].line, lines[0].err, "ls")
+	}
+
+	if lines[1].line != "ab" || !errors.Is(lines[1].err, readline.ErrInterrupt) {
+		t.Errorf("OnAccept() = %q, %v, want %q, %v", lines[1].line, lines[1].err, "ab", readline.ErrInterrupt)
+	}
+}
+
+func TestShell_RenderHook(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	var renders int
+
+	// Hooks are called before rendering, so they can change what is displayed.
+	shell.Hooks.OnPreRender(func() {
+		renders++
+		line := string(*shell.Line())
+		shell.Prompt.Primary(func() string { return "[" + line + "] " })
+	})
+
+	shell.Readline("a", "b", `\C-c`)
+
+	if renders < 3 {
+		t.Errorf("OnPreRender() called %d times, want at least one per key", renders)
+	}
+
+	if frame := shell.Frames()[1].String(); frame != "[ab] ab" {
+		t.Errorf("Frame = %q, want %q", frame, "[ab] ab")
+	}
+}
+
+func TestShell_ClearHooks(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	var called bool
+
+	shell.Hooks.OnPreRead(func() { called = true })
+	shell.Hooks.OnPreRender(func() { called = true })
+	shell.Hooks.OnModeChange(func(string, string) { called = true })
+	shell.Hooks.OnAccept(func(string, error) { called = true })
+	shell.Hooks.OnCommand(func(string, []rune, func()) { called = true })
+	shell.Hooks.Clear()
+
+	if line, _ := shell.Readline("a", `\r`); line != "a" || called {
+		t.Errorf("Readline() = %q (hooks called: %v), want %q without hooks", line, called, "a")
+	}
+}
@@ -0,0 +1,144 @@
+package readlinetest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+// recorder keeps all the output written to the screen.
+type recorder struct {
+	*Screen
+	out bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.out.Write(p)
+	return r.Screen.Write(p)
+}
+
+// integrationSequence matches OSC 133 marks and OSC 7 directory reports.
+var integrationSequence = regexp.MustCompile("\x1b\\](133;[^\a]*\a|7;[^\x1b]*\x1b\\\\)")
+
+// marks returns the shell integration sequences written since the last
+// call, without the repeated ones (the prompt end is marked on each refresh).
+func (r *recorder) marks() []string {
+	var marks []string
+
+	for _, mark := range integrationSequence.FindAllString(r.out.String(), -1) {
+		if len(marks) == 0 || marks[len(marks)-1] != mark {
+			marks = append(marks, mark)
+		}
+	}
+
+	r.out.Reset()
+
+	return marks
+}
+
+// newRecordedShell returns a shell displayed on a recorded screen.
+func newRecordedShell() (*readline.Shell, *script, *recorder) {
+	rec := &recorder{Screen: NewScreen(40, 6)}
+	input := &script{frame: func() {}}
+	rec.reply = input.reply
+
+	return readline.NewShellWithIO(input, rec), input, rec
+}
+
+func TestShell_IntegrationMarks(t *testing.T) {
+	shell, input, rec := newRecordedShell()
+
+	// Nothing is marked by default.
+	input.push("ls\r")
+	shell.Readline()
+
+	if marks := rec.marks(); len(marks) > 0 {
+		t.Errorf("marks without shell-integration = %q, want none", marks)
+	}
+
+	shell.Config.Set("shell-integration", true)
+
+	tests := []struct {
+		name  string
+		setup func()
+		keys  string
+		want  []string
+	}{
+		{
+			name:  "Accepted line",
+			setup: func() {},
+			keys:  "ls\r",
+			want:  []string{"\x1b]133;A\a", "\x1b]133;B\a", "\x1b]133;C\a"},
+		},
+		{
+			name:  "Command status",
+			setup: func() { shell.Prompt.SetStatus(2, 0) },
+			keys:  "\r",
+			want:  []string{"\x1b]133;D;2\a", "\x1b]133;A\a", "\x1b]133;B\a", "\x1b]133;C\a"},
+		},
+		{
+			name:  "Interrupted line",
+			setup: func() {},
+			keys:  "ls\x03",
+			want:  []string{"\x1b]133;D;2\a", "\x1b]133;A\a", "\x1b]133;B\a"},
+		},
+		{
+			name:  "After interrupt",
+			setup: func() {},
+			keys:  "\r",
+			want:  []string{"\x1b]133;A\a", "\x1b]133;B\a", "\x1b]133;C\a"},
+		},
+	}
+
+	for _, test := range tests {
+		test.setup()
+		input.push(test.keys)
+
+		if _, err := shell.Readline(); err != nil && !errors.Is(err, readline.ErrInterrupt) {
+			t.Fatalf("%s: Readline() error = %v", test.name, err)
+		}
+
+		if marks := rec.marks(); !reflect.DeepEqual(marks, test.want) {
+			t.Errorf("%s: marks = %q, want %q", test.name, marks, test.want)
+		}
+	}
+}
+
+func TestShell_WorkingDirectory(t *testing.T) {
+	shell, input, rec := newRecordedShell()
+	host, _ := os.Hostname()
+
+	dir := "/tmp/a b"
+	shell.WorkingDirectory(func() string { return dir })
+
+	// The directory is reported before each prompt, even
+	// without shell-integration, and can be unset.
+	input.push("\r")
+	shell.Readline()
+
+	if marks, want := rec.marks(), "\x1b]7;file://"+host+"/tmp/a%20b\x1b\\"; !reflect.DeepEqual(marks, []string{want}) {
+		t.Errorf("marks = %q, want %q", marks, []string{want})
+	}
+
+	dir = ""
+	input.push("\r")
+	shell.Readline()
+
+	if marks := rec.marks(); len(marks) > 0 {
+		t.Errorf("marks with empty directory = %q, want none", marks)
+	}
+
+	dir = "/tmp"
+	shell.WorkingDirectory(nil)
+	input.push("\r")
+	shell.Readline()
+
+	if marks := rec.marks(); len(marks) > 0 {
+		t.Errorf("marks after unsetting = %q, want none", marks)
+	}
+}
@@ -0,0 +1,82 @@
+package readlinetest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_InterruptBehaviors(t *testing.T) {
+	errQuit := errors.New("quit")
+
+	tests := []struct {
+		name     string
+		behavior readline.Behavior
+		keys     []string
+		line     string
+		err      error
+		called   []string // Lines passed to the callback.
+	}{
+		{name: "Return error", keys: []string{"ab", `\C-c`}, line: "ab", err: readline.ErrInterrupt},
+		{name: "Clear line", behavior: readline.ClearLine, keys: []string{"ab", `\C-c`, "x", `\r`}, line: "x"},
+		{name: "Clear and undo", behavior: readline.ClearLine, keys: []string{"ab", `\C-c`, `\C-_`, `\r`}, line: "ab"},
+		{name: "Callback", behavior: readline.RunCallback, keys: []string{"ab", `\C-c`, "c", `\r`}, line: "abc", called: []string{"ab"}},
+		{name: "Callback error", behavior: readline.RunCallback, keys: []string{"quit", `\C-c`}, line: "quit", err: errQuit, called: []string{"quit"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 6)
+
+			var called []string
+
+			shell.OnInterrupt(test.behavior, func(line string) error {
+				called = append(called, line)
+				if line == "quit" {
+					return errQuit
+				}
+
+				return nil
+			})
+
+			line, err := shell.Readline(test.keys...)
+			if line != test.line || !errors.Is(err, test.err) {
+				t.Errorf("Readline() = %q, %v, want %q, %v", line, err, test.line, test.err)
+			}
+
+			if !reflect.DeepEqual(called, test.called) {
+				t.Errorf("callback lines = %q, want %q", called, test.called)
+			}
+		})
+	}
+}
+
+func TestShell_EOFBehaviors(t *testing.T) {
+	tests := []struct {
+		name     string
+		behavior readline.Behavior
+		keys     []string
+		line     string
+		err      error
+	}{
+		{name: "Return error", keys: []string{`\C-d`, "x", `\r`}, err: readline.ErrEOF},
+		{name: "Not empty", keys: []string{"ab", `\C-a`, `\C-d`, `\r`}, line: "b"},
+		{name: "Clear line", behavior: readline.ClearLine, keys: []string{`\C-d`, "x", `\r`}, line: "x"},
+		{name: "Callback", behavior: readline.RunCallback, keys: []string{`\C-d`, "x", `\r`}, line: "x"},
+		{name: "Closed input", behavior: readline.ClearLine, keys: []string{"ab"}, line: "ab", err: readline.ErrEOF},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(40, 6)
+			shell.OnEOF(test.behavior, func(string) error { return nil })
+
+			line, err := shell.Readline(test.keys...)
+			if line != test.line || !errors.Is(err, test.err) {
+				t.Errorf("Readline() = %q, %v, want %q, %v", line, err, test.line, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package readlinetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_ConcurrentShells(t *testing.T) {
+	widths := []int{12, 40}
+	frames := make([]string, len(widths))
+	lines := make([]string, len(widths))
+
+	// Themes are shared by all shells, and set once before running them.
+	shells := make([]*Shell, len(widths))
+
+	for i, width := range widths {
+		shells[i] = NewShell(width, 6)
+		shells[i].Prompt.Primary(func() string { return "> " })
+		shells[i].Readline(`\r`)
+	}
+
+	var wait sync.WaitGroup
+
+	for i, shell := range shells {
+		wait.Add(1)
+
+		go func(i int, shell *Shell) {
+			defer wait.Done()
+
+			for j := 0; j < 20; j++ {
+				lines[i], _ = shell.Readline(strings.Repeat("x", 20), `\r`)
+			}
+
+			frames[i] = shell.Frame().String()
+		}(i, shell)
+	}
+
+	wait.Wait()
+
+	// Each shell displays the line on its own screen, with its own width.
+	want := []string{
+		"> xxxxxxxxxx\nxxxxxxxxxx",
+		"> xxxxxxxxxxxxxxxxxxxx",
+	}
+
+	for i := range widths {
+		if lines[i] != strings.Repeat("x", 20) {
+			t.Errorf("shell %d: Readline() = %q, want %q", i, lines[i], strings.Repeat("x", 20))
+		}
+
+		if !strings.HasSuffix(frames[i], want[i]) {
+			t.Errorf("shell %d: Frame = %q, want it to end with %q", i, frames[i], want[i])
+		}
+	}
+}
+
+func TestShell_WithIO(t *testing.T) {
+	screen := NewScreen(20, 4)
+	input := &script{}
+	screen.reply = input.reply
+
+	shell := readline.NewShellWithIO(input, screen)
+	shell.Prompt.Primary(func() string { return "$ " })
+
+	input.push("abc\r")
+
+	line, err := shell.Readline()
+	if line != "abc" || err != nil {
+		t.Fatalf("Readline() = %q, %v, want %q, nil", line, err, "abc")
+	}
+
+	if frame := screen.Frame().String(); frame != "$ abc" {
+		t.Errorf("Frame = %q, want %q", frame, "$ abc")
+	}
+}
+
+func TestShell_ReadlineCtx(t *testing.T) {
+	shell := NewShell(40, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Cancel once the keys have been inserted, while the shell waits for more.
+	shell.Hooks.OnPreRead(func() {
+		if string(*shell.Line()) == "abc" {
+			cancel()
+		}
+	})
+
+	line, err := shell.ReadlineCtx(ctx, "abc", `\r`)
+	if line != "abc" || !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadlineCtx() = %q, %v, want %q, %v", line, err, "abc", context.Canceled)
+	}
+
+	// The keys not read yet are kept for the next call.
+	line, err = shell.Readline()
+	if line != "" || err != nil {
+		t.Errorf("Readline() after cancel = %q, %v, want %q, nil", line, err, "")
+	}
+}
+
+func TestShell_ReadlineCtxReadKey(t *testing.T) {
+	shell := NewShell(40, 6)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Commands reading keys are interrupted too.
+	shell.Hooks.OnCommand(func(command string, keys []rune, run func()) {
+		if command == "quoted-insert" {
+			cancel()
+		}
+
+		run()
+	})
+
+	line, err := shell.ReadlineCtx(ctx, "ab", `\C-q`)
+	if line != "ab" || !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadlineCtx() = %q, %v, want %q, %v", line, err, "ab", context.Canceled)
+	}
+}
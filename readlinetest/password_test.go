@@ -0,0 +1,77 @@
+package readlinetest
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// readPassword is like Shell.Readline, but reads a password.
+func readPassword(shell *Shell, prompt string, keys []string, opts ...readline.PasswordOption) (string, error) {
+	shell.frames = nil
+
+	for _, seq := range keys {
+		shell.input.push(inputrc.Unescape(seq))
+	}
+
+	line, err := shell.Shell.ReadPassword(prompt, opts...)
+
+	shell.input.flush()
+	shell.frames = append(shell.frames, shell.Screen.Frame())
+
+	return line, err
+}
+
+func TestShell_ReadPassword(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+	shell.History.Add("local", readline.NewInMemoryHistory())
+
+	var completions int
+
+	shell.Completer = func(line []rune, cursor int) readline.Completions {
+		completions++
+		return readline.CompleteValues("secret")
+	}
+
+	shell.Readline("kept", `\C-a`, `\C-k`, "ls", `\r`)
+
+	line, err := readPassword(shell, "Password: ", []string{"se", `\t`, "cret", `\C-a`, `\C-k`, "pass", `\r`})
+	if line != "pass" || err != nil {
+		t.Fatalf("ReadPassword() = %q, %v, want %q, nil", line, err, "pass")
+	}
+
+	if frame := shell.Frames()[2].String(); frame != "> ls\nPassword: ******" {
+		t.Errorf("Frame = %q, want the masked input", frame)
+	}
+
+	if completions > 0 {
+		t.Errorf("ReadPassword() completed the input %d times, want none", completions)
+	}
+
+	// The password is neither in history nor in the registers,
+	// and the shell settings are restored.
+	line, _ = shell.Readline(`\C-p`, `\C-e`, `\C-y`, `\r`)
+	if line != "lskept" {
+		t.Errorf("Readline() after password = %q, want %q", line, "lskept")
+	}
+
+	if frame := shell.Frames()[0].String(); frame != "> ls\nPassword: ****\n> ls" {
+		t.Errorf("Frame after password = %q, want %q", frame, "> ls\nPassword: ****\n> ls")
+	}
+}
+
+func TestShell_ReadPasswordHidden(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	line, _ := readPassword(shell, "Password: ", []string{"secret", `\r`}, readline.WithMask(0))
+	if line != "secret" {
+		t.Errorf("ReadPassword() = %q, want %q", line, "secret")
+	}
+
+	// Nothing is displayed, and the cursor does not move.
+	if frame := shell.Frames()[0]; frame.String() != "Password:" || frame.Col != 10 {
+		t.Errorf("Frame = %q (column %d), want %q (column 10)", frame.String(), frame.Col, "Password:")
+	}
+}
@@ -0,0 +1,228 @@
+package readlinetest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_PromptSegments(t *testing.T) {
+	screen := NewScreen(40, 4)
+	reader, writer := io.Pipe()
+
+	defer writer.Close()
+
+	screen.reply = func(answer string) { go writer.Write([]byte(answer)) }
+
+	shell := readline.NewShellWithIO(reader, screen)
+
+	branch := make(chan string)
+
+	shell.Prompt.Segment("branch", "...", func() string { return <-branch })
+	shell.Prompt.Primary(func() string { return "[" + shell.Prompt.Async("branch") + "] > " })
+
+	var frames []string
+
+	// The prompt is displayed without waiting for its segments,
+	// and redisplayed once they are computed, without any key.
+	shell.Hooks.OnPreRead(func() {
+		frames = append(frames, strings.TrimSpace(screen.Frame().String()))
+
+		switch len(frames) {
+		case 1:
+			go func() { branch <- "main" }()
+		case 2:
+			go writer.Write([]byte("\r"))
+		}
+	})
+
+	shell.Readline()
+
+	if want := []string{"[...] >", "[main] >"}; !reflect.DeepEqual(frames, want) {
+		t.Errorf("frames = %q, want %q", frames, want)
+	}
+}
+
+func TestShell_TransientLine(t *testing.T) {
+	shell := NewShell(40, 12)
+	shell.Config.Set("prompt-transient", true)
+	shell.Prompt.Primary(func() string { return "long prompt > " })
+	shell.AcceptMultiline = func(line []rune) bool {
+		return !strings.HasSuffix(string(line), "|")
+	}
+
+	shell.Prompt.TransientLine(func(line readline.AcceptedLine) readline.TransientPrompt {
+		switch {
+		case line.Line == "skip":
+			return readline.TransientPrompt{Skip: true}
+		case errors.Is(line.Err, readline.ErrInterrupt):
+			return readline.TransientPrompt{Prompt: "^C "}
+		case line.Status != 0:
+			return readline.TransientPrompt{Prompt: fmt.Sprintf("[%d %s] $ ", line.Status, line.Duration)}
+		}
+
+		return readline.TransientPrompt{Prompt: "$ ", Line: strings.ReplaceAll(line.Line, "\n", " ")}
+	})
+
+	shell.Readline("ls", `\r`)
+	shell.Prompt.SetStatus(2, time.Second)
+	shell.Readline("false", `\r`)
+	shell.Readline("ab", `\C-c`)
+	shell.Readline("skip", `\r`)
+	shell.Prompt.SetStatus(0, 0)
+	shell.Readline("ls |", `\r`, "wc", `\r`)
+
+	// Transient prompts depend on the accepted lines, errors and command
+	// status, can be skipped, and can replace (eg. collapse) input lines.
+	want := `$ ls
+[2 1s] $ false
+^C ab
+long prompt > skip
+$ ls | wc`
+
+	if frame := shell.Frame().String(); frame != want {
+		t.Errorf("Frame = %q, want %q", frame, want)
+	}
+}
+
+func TestShell_RightPromptLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		option string
+		line   string
+		frames []string
+	}{
+		{
+			name:   "Last line",
+			option: "last",
+			line:   "one\ntwo\nthree",
+			frames: []string{"> one\n  two\n  three           RP", "> one\n  two\n  three           RP"},
+		},
+		{
+			name:   "First line",
+			option: "first",
+			line:   "one\ntwo\nthree",
+			frames: []string{"> one             RP\n  two\n  three", "> one             RP\n  two\n  three"},
+		},
+		{
+			name:   "Cursor line",
+			option: "cursor",
+			line:   "one\ntwo\nthree",
+			frames: []string{"> one\n  two             RP\n  three", "> one\n  two\n  three           RP"},
+		},
+		{
+			name:   "Wrapped line",
+			option: "cursor",
+			line:   "one two three four five six\nx",
+			frames: []string{"> one two three four\n five six\n  x", "> one two three four\n five six\n  x               RP"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(20, 8)
+			shell.Config.Set("prompt-right-line", test.option)
+			shell.Prompt.Primary(func() string { return "> " })
+			shell.Prompt.Right(func() string { return "RP" })
+			shell.SetBuffer(test.line, 4)
+
+			// Move to the second line (or row), and then to the next line.
+			shell.Readline(`\C-f`, `\C-e\C-f`, `\C-c`)
+
+			for i, want := range test.frames {
+				if frame := shell.Frames()[i].String(); frame != want {
+					t.Errorf("Frame %d = %q, want %q", i, frame, want)
+				}
+			}
+		})
+	}
+}
+
+func TestShell_SecondaryPrompt(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "$ > " })
+	shell.Prompt.Secondary(func() string { return ". " })
+	shell.AcceptMultiline = func(line []rune) bool {
+		return strings.Count(string(line), "\n") == 2
+	}
+
+	line, err := shell.Readline("one", `\r`, "two", `\r`, "three", `\C-p`, `\r`)
+	if line != "one\ntwo\nthree" || err != nil {
+		t.Fatalf("Readline() = %q, %v, want %q, nil", line, err, "one\ntwo\nthree")
+	}
+
+	want := "$ > one\n. two\n. three"
+	frame := shell.Frames()[5]
+
+	if frame.String() != want || frame.Row != 1 || frame.Col != 4 {
+		t.Errorf("Frame = %q (%d, %d), want %q (1, 4)", frame, frame.Row, frame.Col, want)
+	}
+}
+
+func TestShell_PromptParts(t *testing.T) {
+	shell := NewShell(30, 6)
+	shell.Config.Set("prompt-min-line-width", 10)
+	shell.Prompt.PrimaryParts(func() []readline.PromptPart {
+		return []readline.PromptPart{
+			{Text: "user@host:", Priority: 1},
+			{Text: "/home/user/projects/readline", Priority: 2, Truncate: true},
+			{Text: " > ", Priority: 3},
+		}
+	})
+	shell.Prompt.RightParts(func() []readline.PromptPart {
+		return []readline.PromptPart{
+			{Text: "[main]", Priority: 2},
+			{Text: " 12:00", Priority: 1},
+		}
+	})
+
+	shell.Readline("ls", `\C-c`)
+
+	// The host is dropped first, then the path is truncated.
+	want := "/home/user/proje… > ls  [main]"
+	if frame := shell.Frames()[0]; frame.String() != want || frame.Col != 22 {
+		t.Errorf("Frame = %q (column %d), want %q (column 22)", frame, frame.Col, want)
+	}
+}
+
+func TestShell_TooltipArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		cursor  int
+		args    []string
+		current int
+	}{
+		{line: "git commit -m msg", cursor: 6, args: []string{"git", "commit", "-m", "msg"}, current: 1},
+		{line: "git commit | less -R", cursor: 0, args: []string{"git", "commit"}, current: 0},
+		{line: "git commit | less -R", cursor: 19, args: []string{"less", "-R"}, current: 1},
+		{line: `echo $(git "com mit" -a) done`, cursor: 12, args: []string{"git", "com mit", "-a"}, current: 1},
+		{line: `echo $(git "com mit" -a) done`, cursor: 26, args: []string{"echo", "$(git \"com mit\" -a)", "done"}, current: 2},
+		{line: "ls  -l", cursor: 3, args: []string{"ls", "-l"}, current: 1},
+	}
+
+	for _, test := range tests {
+		shell := NewShell(80, 6)
+
+		var args []string
+
+		current := -1
+
+		shell.Prompt.TooltipArgs(func(words []string, word int) string {
+			args, current = words, word
+			return ""
+		})
+
+		shell.SetBuffer(test.line, test.cursor)
+		shell.Readline(`\C-c`)
+
+		if fmt.Sprintf("%q", args) != fmt.Sprintf("%q", test.args) || current != test.current {
+			t.Errorf("%q at %d: tooltip args = %q, %d, want %q, %d", test.line, test.cursor, args, current, test.args, test.current)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package readlinetest
+
+import (
+	"testing"
+)
+
+func TestShell_RegisterKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		keys []string
+		want string
+	}{
+		{name: "Line below", line: "foo\nbar", keys: []string{"gg", "yy", "j", "p"}, want: "foo\nbar\nfoo"},
+		{name: "Line above", line: "foo\nbar", keys: []string{"yy", "gg", "P"}, want: "bar\nfoo\nbar"},
+		{name: "Visual line", line: "foo\nbar", keys: []string{"gg", "Vy", "p"}, want: "foo\nfoo\nbar"},
+		{name: "Deleted line", line: "foo\nbar", keys: []string{"gg", "dd", "p"}, want: "bar\nfoo"},
+		{name: "Named register", line: "foo\nbar", keys: []string{"gg", `"`, "a", "yy", "j", `"`, "a", "p"}, want: "foo\nbar\nfoo"},
+		{name: "Characters", line: "foo bar\nbaz", keys: []string{"gg", "yw", "P"}, want: "foo foo bar\nbaz"},
+		{name: "Last word", line: "foo bar\nbaz", keys: []string{"gg", "w", "yw", "P"}, want: "foo barbar\nbaz"},
+		{name: "Delete last word", line: "foo\nbar", keys: []string{"gg", "dw"}, want: "\nbar"},
+		{name: "Block before", line: "ab\ncd", keys: []string{"gg", `"`, "b", "P"}, want: "xyab\nzwcd"},
+		{name: "Block after", line: "ab\ncd", keys: []string{"gg", `"`, "b", "p"}, want: "axyb\nczwd"},
+		{name: "Block padded", line: "ab\ncd\ne", keys: []string{"gg", "j", "l", `"`, "b", "p"}, want: "ab\ncdxy\ne zw"},
+		{name: "Block new lines", line: "ab", keys: []string{`"`, "b", "p"}, want: "abxy\n  zw"},
+	}
+
+	// The b register holds a block, as restored from a saved state.
+	state := `{"registers":{"alpha":{"b":"xy\nzw"},"kinds":{"b":2}}}`
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 10)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.SetBuffer(test.line, -1)
+
+			if err := shell.RestoreState([]byte(state)); err != nil {
+				t.Fatalf("RestoreState() = %v", err)
+			}
+
+			keys := append([]string{`\C-xv`, `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.want {
+				t.Errorf("Readline() = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
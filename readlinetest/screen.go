@@ -0,0 +1,321 @@
+package readlinetest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Screen is a minimal virtual terminal: it interprets the text and the escape
+// sequences written by the shell (cursor movements, line and screen clearing),
+// and keeps the resulting grid of characters. Colors and other attributes are
+// discarded, and the screen scrolls up when writing past its last line.
+//
+// Cursor position queries are answered through the reply function, if any.
+type Screen struct {
+	width, height int
+	cells         [][]string // Cells of each line: "" continues a wide character.
+	row, col      int
+	savedRow      int
+	savedCol      int
+	pending       []byte // An incomplete escape sequence or character.
+	reply         func(answer string)
+	mutex         sync.Mutex
+}
+
+// NewScreen returns an empty screen of the given size, in columns and lines.
+func NewScreen(width, height int) *Screen {
+	screen := &Screen{width: width, height: height}
+	screen.cells = make([][]string, height)
+
+	for row := range screen.cells {
+		screen.cells[row] = screen.blankLine()
+	}
+
+	return screen
+}
+
+// Write interprets the output of the shell, and always consumes all of it.
+func (s *Screen) Write(out []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	buf := append(s.pending, out...)
+	s.pending = nil
+
+	for len(buf) > 0 {
+		var done int
+
+		switch buf[0] {
+		case '\x1b':
+			done = s.escape(buf)
+		default:
+			done = s.print(buf)
+		}
+
+		// Keep incomplete sequences for the next write.
+		if done == 0 {
+			s.pending = append([]byte{}, buf...)
+			break
+		}
+
+		buf = buf[done:]
+	}
+
+	return len(out), nil
+}
+
+// MakeRaw does nothing, since the screen input is never line-buffered.
+func (s *Screen) MakeRaw() (restore func() error, err error) {
+	return func() error { return nil }, nil
+}
+
+// Size returns the width and height of the screen.
+func (s *Screen) Size() (width, height int, err error) {
+	return s.width, s.height, nil
+}
+
+// Frame returns the current contents of the screen and cursor position.
+func (s *Screen) Frame() Frame {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	frame := Frame{
+		Lines: make([]string, 0, s.height),
+		Row:   s.row,
+		Col:   min(s.col, s.width-1),
+	}
+
+	for _, cells := range s.cells {
+		frame.Lines = append(frame.Lines, strings.TrimRight(strings.Join(cells, ""), " "))
+	}
+
+	return frame
+}
+
+// print prints the next character, or interprets a control character.
+func (s *Screen) print(buf []byte) int {
+	char, size := utf8.DecodeRune(buf)
+	if char == utf8.RuneError && !utf8.FullRune(buf) {
+		return 0
+	}
+
+	switch char {
+	case '\r':
+		s.col = 0
+	case '\n':
+		s.col = min(s.col, s.width-1)
+		s.lineFeed()
+	case '\b':
+		s.col = max(min(s.col, s.width-1)-1, 0)
+	case '\t':
+		s.col = min((s.col/8+1)*8, s.width-1)
+	default:
+		if unicode.IsControl(char) {
+			break
+		}
+
+		s.put(string(char))
+	}
+
+	return size
+}
+
+// put writes a character at the cursor position, wrapping it on the next
+// line if there is no room left for it on the current one.
+func (s *Screen) put(char string) {
+	width := uniseg.StringWidth(char)
+
+	// Combining characters go in the previous cell.
+	if width == 0 {
+		if col := s.col - 1; col >= 0 {
+			for col > 0 && s.cells[s.row][col] == "" {
+				col--
+			}
+
+			s.cells[s.row][col] += char
+		}
+
+		return
+	}
+
+	if s.col+width > s.width {
+		s.col = 0
+		s.lineFeed()
+	}
+
+	s.cells[s.row][s.col] = char
+	if width > 1 && s.col+1 < s.width {
+		s.cells[s.row][s.col+1] = ""
+	}
+
+	s.col += width
+}
+
+// lineFeed moves the cursor down, scrolling the screen if on the last line.
+func (s *Screen) lineFeed() {
+	if s.row < s.height-1 {
+		s.row++
+		return
+	}
+
+	s.cells = append(s.cells[1:], s.blankLine())
+}
+
+// escape interprets an escape sequence, and returns its length,
+// or 0 if the sequence is not complete yet.
+func (s *Screen) escape(buf []byte) int {
+	if len(buf) < 2 {
+		return 0
+	}
+
+	switch buf[1] {
+	case '[':
+		return s.csi(buf)
+	case ']', 'P', '_', '^':
+		return stringSequence(buf)
+	case '7':
+		s.savedRow, s.savedCol = s.row, s.col
+	case '8':
+		s.row, s.col = s.savedRow, s.savedCol
+	}
+
+	return 2
+}
+
+// csi interprets a control sequence (ESC [ params intermediates final).
+func (s *Screen) csi(buf []byte) int {
+	end := 2
+	for end < len(buf) && (buf[end] < 0x40 || buf[end] > 0x7e) {
+		end++
+	}
+
+	if end == len(buf) {
+		return 0
+	}
+
+	params := string(buf[2:end])
+	final := buf[end]
+
+	// Private, or with intermediate bytes: cursor shapes, modes, keyboard protocols.
+	if strings.ContainsAny(params, "?<=> !\"#$%&'()*+,-./") {
+		return end + 1
+	}
+
+	args := strings.Split(params, ";")
+	arg := func(i, def int) int {
+		if i >= len(args) {
+			return def
+		}
+
+		value, err := strconv.Atoi(args[i])
+		if err != nil || value == 0 {
+			return def
+		}
+
+		return value
+	}
+
+	// Deferred wraps are cancelled by any cursor movement.
+	s.col = min(s.col, s.width-1)
+
+	switch final {
+	case 'A':
+		s.row = max(s.row-arg(0, 1), 0)
+	case 'B':
+		s.row = min(s.row+arg(0, 1), s.height-1)
+	case 'C':
+		s.col = min(s.col+arg(0, 1), s.width-1)
+	case 'D':
+		s.col = max(s.col-arg(0, 1), 0)
+	case 'E':
+		s.row, s.col = min(s.row+arg(0, 1), s.height-1), 0
+	case 'F':
+		s.row, s.col = max(s.row-arg(0, 1), 0), 0
+	case 'G':
+		s.col = min(arg(0, 1), s.width) - 1
+	case 'H', 'f':
+		s.row, s.col = min(arg(0, 1), s.height)-1, min(arg(1, 1), s.width)-1
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	case 'K':
+		s.eraseLine(s.row, arg(0, 0))
+	case 's':
+		s.savedRow, s.savedCol = s.row, s.col
+	case 'u':
+		s.row, s.col = s.savedRow, s.savedCol
+	case 'n':
+		if arg(0, 0) == 6 && s.reply != nil {
+			s.reply(fmt.Sprintf("\x1b[%d;%dR", s.row+1, s.col+1))
+		}
+	}
+
+	return end + 1
+}
+
+// eraseDisplay clears the screen below (0) or above (1) the cursor, or entirely.
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(s.row, 0)
+
+		for row := s.row + 1; row < s.height; row++ {
+			s.cells[row] = s.blankLine()
+		}
+	case 1:
+		s.eraseLine(s.row, 1)
+
+		for row := 0; row < s.row; row++ {
+			s.cells[row] = s.blankLine()
+		}
+	default:
+		for row := range s.cells {
+			s.cells[row] = s.blankLine()
+		}
+	}
+}
+
+// eraseLine clears a line after (0) or before (1) the cursor, or entirely.
+func (s *Screen) eraseLine(row, mode int) {
+	begin, end := 0, s.width
+
+	switch mode {
+	case 0:
+		begin = s.col
+	case 1:
+		end = s.col + 1
+	}
+
+	for col := begin; col < end; col++ {
+		s.cells[row][col] = " "
+	}
+}
+
+func (s *Screen) blankLine() []string {
+	line := make([]string, s.width)
+	for col := range line {
+		line[col] = " "
+	}
+
+	return line
+}
+
+// stringSequence returns the length of an OSC, DCS, APC or PM sequence,
+// terminated by either BEL or ST (ESC \), or 0 if it is not complete yet.
+func stringSequence(buf []byte) int {
+	for i := 2; i < len(buf); i++ {
+		switch {
+		case buf[i] == '\a':
+			return i + 1
+		case buf[i] == '\x1b' && i+1 < len(buf) && buf[i+1] == '\\':
+			return i + 2
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,45 @@
+package readlinetest
+
+import "testing"
+
+func TestScreen_Write(t *testing.T) {
+	tests := []struct {
+		name  string
+		out   string
+		lines string
+		row   int
+		col   int
+	}{
+		{name: "Text", out: "hello\r\nworld", lines: "hello\nworld", row: 1, col: 5},
+		{name: "Colors", out: "\x1b[1;31mred\x1b[0m text", lines: "red text", col: 8},
+		{name: "Wrap", out: "0123456789ab", lines: "0123456789\nab", row: 1, col: 2},
+		{name: "Deferred wrap", out: "0123456789\x1b[2D", lines: "0123456789", col: 7},
+		{name: "Wide wrap", out: "012345678世", lines: "012345678\n世", row: 1, col: 2},
+		{name: "Cursor moves", out: "abc\x1b[2Dx\x1b[1By", lines: "axc\n  y", row: 1, col: 3},
+		{name: "Clear line", out: "abcdef\x1b[3D\x1b[0K", lines: "abc", col: 3},
+		{name: "Clear below", out: "a\r\nb\r\nc\x1b[1A\r\x1b[0J", lines: "a", row: 1},
+		{name: "Scroll", out: "1\r\n2\r\n3\r\n4", lines: "2\n3\n4", row: 2, col: 1},
+		{name: "OSC", out: "\x1b]52;c;aGVsbG8=\atext", lines: "text", col: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			screen := NewScreen(10, 3)
+
+			// Write byte by byte to check incomplete sequences.
+			for i := range []byte(test.out) {
+				screen.Write([]byte{test.out[i]})
+			}
+
+			frame := screen.Frame()
+
+			if frame.String() != test.lines {
+				t.Errorf("Screen = %q, want %q", frame.String(), test.lines)
+			}
+
+			if frame.Row != test.row || frame.Col != test.col {
+				t.Errorf("Cursor = (%d, %d), want (%d, %d)", frame.Row, frame.Col, test.row, test.col)
+			}
+		})
+	}
+}
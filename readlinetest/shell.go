@@ -0,0 +1,196 @@
+// Package readlinetest drives readline shells with scripted keystrokes, without
+// a real terminal. The shell output is rendered on a virtual terminal screen,
+// a frame of which is captured after each scripted key sequence is processed,
+// so that tests can check the returned lines as well as what users would see
+// (prompts, completions, hints, etc), for instance against golden files.
+// Each shell has its own screen, so that tests can run in parallel.
+package readlinetest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// Default size of the virtual terminal screen.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// Frame is a snapshot of the virtual terminal screen:
+// its lines (without trailing spaces) and the cursor position.
+type Frame struct {
+	Lines []string
+	Row   int
+	Col   int
+}
+
+// String returns the lines of the frame, without the trailing empty ones.
+func (f Frame) String() string {
+	lines := f.Lines
+
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Shell is a readline shell reading its input from scripted key sequences,
+// and displaying its output on a virtual terminal screen. The embedded shell
+// can be configured as usual (prompts, completers, inputrc options, etc).
+type Shell struct {
+	*readline.Shell
+	Screen *Screen
+	input  *script
+	frames []Frame
+}
+
+// NewShell returns a shell displayed on a virtual terminal screen of the given
+// size (the default one if zero), and accepts the same options as readline.NewShell.
+func NewShell(width, height int, opts ...inputrc.Option) *Shell {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	if height <= 0 {
+		height = DefaultHeight
+	}
+
+	shell := &Shell{
+		Screen: NewScreen(width, height),
+		input:  &script{},
+	}
+
+	shell.Screen.reply = shell.input.reply
+	shell.input.frame = func() {
+		shell.frames = append(shell.frames, shell.Screen.Frame())
+	}
+
+	shell.Shell = readline.NewShellWithIO(shell.input, shell.Screen, opts...)
+
+	return shell
+}
+
+// Readline runs the shell with the given key sequences as user input, and returns
+// the line and error returned by the shell. Keys are written in inputrc format (eg.
+// `\C-a`, `\e[D` or `\r`), and each sequence is read at once: a frame is captured
+// after the shell has processed it, and a last one when the shell returns.
+//
+// Key sequences not consumed by the shell (eg. after an accepted line) are used
+// as input for the next call. If all keys are consumed before the shell returns,
+// the input is closed, and the shell returns the current line with io.EOF.
+func (s *Shell) Readline(keys ...string) (line string, err error) {
+	return s.ReadlineCtx(context.Background(), keys...)
+}
+
+// ReadlineCtx is like Readline, but the shell stops reading keys when the
+// context is done, as with the ReadlineCtx method of readline shells.
+func (s *Shell) ReadlineCtx(ctx context.Context, keys ...string) (line string, err error) {
+	s.frames = nil
+
+	for _, seq := range keys {
+		s.input.push(inputrc.Unescape(seq))
+	}
+
+	line, err = s.Shell.ReadlineCtx(ctx)
+
+	s.input.flush()
+	s.frames = append(s.frames, s.Screen.Frame())
+
+	return line, err
+}
+
+// Frames returns the frames captured during the last call to Readline.
+func (s *Shell) Frames() []Frame {
+	return s.frames
+}
+
+// Frame returns the current contents of the virtual terminal screen.
+func (s *Shell) Frame() Frame {
+	return s.Screen.Frame()
+}
+
+// script is the shell input: it returns scripted key sequences one at a
+// time, and the answers of the virtual terminal to the shell queries.
+type script struct {
+	keys    []string
+	replies []string
+	read    bool   // A key sequence has been read, and not captured yet.
+	frame   func() // Captures the screen once a key sequence is processed.
+	mutex   sync.Mutex
+}
+
+func (in *script) Read(buf []byte) (int, error) {
+	in.mutex.Lock()
+
+	// Terminal answers are not user input.
+	if len(in.replies) > 0 {
+		defer in.mutex.Unlock()
+
+		n := copy(buf, in.replies[0])
+		in.replies[0] = in.replies[0][n:]
+
+		if in.replies[0] == "" {
+			in.replies = in.replies[1:]
+		}
+
+		return n, nil
+	}
+
+	// The shell is done with the last keys, and waits for more.
+	processed := in.read
+	in.read = false
+	in.mutex.Unlock()
+
+	if processed {
+		in.frame()
+	}
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	if len(in.keys) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(buf, in.keys[0])
+	in.keys[0] = in.keys[0][n:]
+
+	if in.keys[0] == "" {
+		in.keys = in.keys[1:]
+		in.read = true
+	}
+
+	return n, nil
+}
+
+func (in *script) push(keys string) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	if keys != "" {
+		in.keys = append(in.keys, keys)
+	}
+}
+
+func (in *script) reply(answer string) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.replies = append(in.replies, answer)
+}
+
+// flush forgets the state of the last call, and unanswered queries.
+func (in *script) flush() {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.read = false
+	in.replies = nil
+}
@@ -0,0 +1,183 @@
+package readlinetest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_Readline(t *testing.T) {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+
+	line, err := shell.Readline("hello", `\C-a`, "x", `\r`, "next")
+	if line != "xhello" || err != nil {
+		t.Fatalf("Readline() = %q, %v, want %q, nil", line, err, "xhello")
+	}
+
+	frames := []string{"> hello", "> hello", "> xhello", "> xhello"}
+	cols := []int{7, 2, 3, 0}
+
+	if len(shell.Frames()) != len(frames) {
+		t.Fatalf("Readline() captured %d frames, want %d", len(shell.Frames()), len(frames))
+	}
+
+	for i, frame := range shell.Frames() {
+		if frame.String() != frames[i] || frame.Col != cols[i] {
+			t.Errorf("Frame %d = %q (column %d), want %q (column %d)", i, frame, frame.Col, frames[i], cols[i])
+		}
+	}
+
+	// Remaining keys are used by the next call.
+	line, err = shell.Readline()
+	if line != "next" || !errors.Is(err, io.EOF) {
+		t.Errorf("Readline() = %q, %v, want %q, EOF", line, err, "next")
+	}
+}
+
+func TestShell_CommandMiddleware(t *testing.T) {
+	shell := NewShell(40, 6)
+
+	var order, commands []string
+
+	shell.Hooks.OnCommand(func(command string, keys []rune, run func()) {
+		order = append(order, "outer")
+		commands = append(commands, command+" "+string(keys))
+		run()
+	})
+
+	// The inner middleware vetoes killing the line.
+	shell.Hooks.OnCommand(func(command string, keys []rune, run func()) {
+		order = append(order, "inner")
+		if command != "unix-line-discard" {
+			run()
+		}
+	})
+
+	line, _ := shell.Readline("ab", `\C-u`, `\r`)
+	if line != "ab" {
+		t.Errorf("Readline() = %q, want %q", line, "ab")
+	}
+
+	want := []string{"self-insert a", "self-insert b", "unix-line-discard \x15", "accept-line \r"}
+	if fmt.Sprint(commands) != fmt.Sprint(want) {
+		t.Errorf("Commands = %q, want %q", commands, want)
+	}
+
+	if len(order) != 8 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("Middlewares called in order %v, want outer before inner", order)
+	}
+}
+
+func TestShell_ReloadConfig(t *testing.T) {
+	inputrc := filepath.Join(t.TempDir(), "inputrc")
+	t.Setenv("INPUTRC", inputrc)
+
+	writeInputrc := func(contents string, modified time.Time) {
+		if err := os.WriteFile(inputrc, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(inputrc, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeInputrc("set bell-style none\n", time.Now())
+
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+
+	// The input line is kept when re-reading the inputrc file.
+	writeInputrc("set bell-style visible\nset editing-mode vi\n", time.Now().Add(time.Second))
+
+	line, _ := shell.Readline("ab", `\C-x\C-r`, `\r`)
+	if line != "ab" || shell.Config.GetString("bell-style") != "visible" {
+		t.Errorf("Readline() = %q with bell-style %s, want %q with bell-style visible",
+			line, shell.Config.GetString("bell-style"), "ab")
+	}
+
+	if main := shell.Keymap.Main(); main != "vi-insert" {
+		t.Errorf("Main keymap = %s, want vi-insert", main)
+	}
+
+	// Changes are detected and applied on the next call to Readline.
+	stop := shell.WatchConfig(time.Millisecond)
+	defer stop()
+
+	writeInputrc("set bell-style audible\n", time.Now().Add(2*time.Second))
+	time.Sleep(50 * time.Millisecond)
+
+	shell.Readline(`\r`)
+
+	if style := shell.Config.GetString("bell-style"); style != "audible" {
+		t.Errorf("Bell style = %s after the inputrc file changed, want audible", style)
+	}
+}
+
+func TestShell_DumpCommands(t *testing.T) {
+	tests := []struct {
+		keys  string
+		frame string
+	}{
+		{keys: `\C-xm`, frame: ">\n(macros)\n\\C-Xq outputs hello"},
+		{keys: `\e1\C-xm`, frame: ">\n(macros)\n\"\\C-Xq\": \"hello\""},
+		{keys: `\C-xf`, frame: ">\n(functions)\nemacs\nabort can be found on \"\\C-C\", \"\\a\", \"\\e\\a\""},
+	}
+
+	for _, test := range tests {
+		shell := NewShell(60, 5)
+		shell.Prompt.Primary(func() string { return "> " })
+		shell.Bind("emacs", `\C-xm`, "dump-macros")
+		shell.Bind("emacs", `\C-xf`, "dump-functions")
+		shell.Bind("emacs", `\C-xq`, `"hello"`)
+
+		shell.Readline(test.keys, `\C-c`)
+
+		if frame := shell.Frames()[0]; !strings.HasPrefix(frame.String(), test.frame) {
+			t.Errorf("Keys %s: frame = %q, want prefix %q", test.keys, frame, test.frame)
+		}
+	}
+}
+
+func TestShell_TourPlayback(t *testing.T) {
+	screen := NewScreen(40, 4)
+	reader, writer := io.Pipe()
+
+	defer writer.Close()
+
+	// The input is a stream, on which the screen answers the shell queries.
+	screen.reply = func(answer string) { go writer.Write([]byte(answer)) }
+
+	shell := readline.NewShellWithIO(reader, screen)
+	shell.StartTour(time.Millisecond, readline.TourStep{Hint: "insert", Keys: "ab"})
+
+	// Accept the line once the tour has played its keys.
+	shell.Hooks.OnPreRead(func() {
+		if string(*shell.Line()) == "ab" {
+			go writer.Write([]byte("\r"))
+		}
+	})
+
+	if line, err := shell.Readline(); line != "ab" || err != nil {
+		t.Errorf("Readline() = %q, %v, want %q, nil", line, err, "ab")
+	}
+}
+
+func TestShell_TourInterrupt(t *testing.T) {
+	shell := NewShell(40, 4)
+
+	// Keys typed while the tour waits are dispatched without delay.
+	shell.StartTour(time.Hour, readline.TourStep{Hint: "insert", Keys: "ab"})
+
+	if line, _ := shell.Readline("x", `\r`); line != "x" {
+		t.Errorf("Readline() = %q, want %q", line, "x")
+	}
+}
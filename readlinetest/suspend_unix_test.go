@@ -0,0 +1,69 @@
+//go:build unix
+// +build unix
+
+package readlinetest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestShell_Suspend(t *testing.T) {
+	// The shell stops its whole process group, so it runs in a child process.
+	if os.Getenv("READLINETEST_SUSPEND") != "" {
+		suspendShell()
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestShell_Suspend$")
+	cmd.Env = append(os.Environ(), "READLINETEST_SUSPEND=1")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	var out bytes.Buffer
+
+	cmd.Stdout = &out
+
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var status syscall.WaitStatus
+
+	if _, err := syscall.Wait4(cmd.Process.Pid, &status, syscall.WUNTRACED, nil); err != nil || !status.Stopped() {
+		t.Fatalf("shell process not stopped by Ctrl-Z (status: %v, error: %v)", status, err)
+	}
+
+	if err := syscall.Kill(cmd.Process.Pid, syscall.SIGCONT); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("shell process: %v\n%s", err, out.String())
+	}
+
+	// Once continued, the prompt and line are redisplayed below.
+	want := "hooks: [suspend resume]\nline: \"abc\"\nframe: \"> ab\\n> abc\"\n"
+	if !strings.HasPrefix(out.String(), want) {
+		t.Errorf("shell process output = %q, want %q", out.String(), want)
+	}
+}
+
+// suspendShell reads a line suspended with Ctrl-Z, and prints the results.
+func suspendShell() {
+	shell := NewShell(40, 6)
+	shell.Prompt.Primary(func() string { return "> " })
+
+	var hooks []string
+
+	shell.Hooks.OnSuspend(func() { hooks = append(hooks, "suspend") })
+	shell.Hooks.OnResume(func() { hooks = append(hooks, "resume") })
+
+	line, _ := shell.Readline("ab", `\C-z`, "c", `\r`)
+
+	fmt.Printf("hooks: %v\nline: %q\nframe: %q\n", hooks, line, shell.Frame().String())
+}
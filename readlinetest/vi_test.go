@@ -0,0 +1,170 @@
+package readlinetest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+func TestShell_ViVisualReselect(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		line string
+	}{
+		{name: "Exchange", keys: []string{"0wve", "o", "bd"}, line: " baz"},
+		{name: "Exchange twice", keys: []string{"0wve", "oo", "wd"}, line: "foo az"},
+		{name: "Reselect", keys: []string{"0ve", `\e`, "$", "gv", "d"}, line: " bar baz"},
+		{name: "Reselect after delete", keys: []string{"0wve", "d", "0", "gv", "d"}, line: "foo z"},
+		{name: "Nothing to reselect", keys: []string{"0", "gv", "d"}, line: "foo bar baz"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+
+			keys := append([]string{`\C-xv`, "foo bar baz", `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
+
+func TestShell_ViFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		keys []string
+		want string
+	}{
+		{name: "Line", line: "aaa bbb ccc ddd eee", keys: []string{"gq", "gq"}, want: "aaa bbb\nccc ddd\neee"},
+		{name: "Motion", line: "aaa bbb ccc ddd eee", keys: []string{"0", "gq", "w"}, want: "aaa bbb\nccc ddd\neee"},
+		{name: "Visual", line: "aaa\nbbb\nccc\n\nddd", keys: []string{"gg", "Vj", "gq"}, want: "aaa bbb\nccc\n\nddd"},
+		{name: "Paragraphs", line: "aaa\nbbb\n\nccc\nddd", keys: []string{"gg", "Vjjjj", "gq"}, want: "aaa bbb\n\nccc ddd"},
+		{name: "Comment", line: "  # aaa bbb ccc", keys: []string{"gq", "gq"}, want: "  # aaa\n  # bbb\n  # ccc"},
+		{name: "Long word", line: "aaaaaaaaaaaa b", keys: []string{"gq", "gq"}, want: "aaaaaaaaaaaa\nb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 10)
+			shell.Config.Set("format-width", 10)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.SetBuffer(test.line, -1)
+
+			keys := append([]string{`\C-xv`, `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.want {
+				t.Errorf("Readline() = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_ViJoinLines(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		keys []string
+		want string
+	}{
+		{name: "Join", line: "foo\n    bar", keys: []string{"gg", "J"}, want: "foo bar"},
+		{name: "Count", line: "a\nb\nc\nd", keys: []string{"gg", "3J"}, want: "a b c\nd"},
+		{name: "Trailing blank", line: "foo \n  bar", keys: []string{"gg", "J"}, want: "foo bar"},
+		{name: "Parenthesis", line: "(foo\n)", keys: []string{"gg", "J"}, want: "(foo)"},
+		{name: "Empty line", line: "foo\n\nbar", keys: []string{"gg", "J"}, want: "foo\nbar"},
+		{name: "Literal", line: "foo\n  bar", keys: []string{"gg", "gJ"}, want: "foo  bar"},
+		{name: "Visual", line: "a\nb\nc\nd", keys: []string{"gg", "Vjj", "J"}, want: "a b c\nd"},
+		{name: "Last line", line: "foo\nbar", keys: []string{"J", "x"}, want: "foo\nba"},
+		{name: "Cursor", line: "foo\nbar", keys: []string{"gg", "J", "x"}, want: "foobar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 10)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.SetBuffer(test.line, -1)
+
+			keys := append([]string{`\C-xv`, `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.want {
+				t.Errorf("Readline() = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_ViCaseOperators(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"0w", "gUiw"}, want: "foo BAR baz"},
+		{keys: []string{"0w", "gU$"}, want: "foo BAR BAZ"},
+		{keys: []string{"0w", "guiw"}, want: "foo bar baz"},
+		{keys: []string{"0", "g~e"}, want: "FOO Bar baz"},
+		{keys: []string{"0", "gUw"}, want: "FOO Bar baz"},
+		{keys: []string{"gUgU"}, want: "FOO BAR BAZ"},
+		{keys: []string{"gUU"}, want: "FOO BAR BAZ"},
+		{keys: []string{"guu"}, want: "foo bar baz"},
+		{keys: []string{"g~~"}, want: "FOO bAR BAZ"},
+		{keys: []string{"0", "gUu"}, want: "foo Bar baz"},
+		{keys: []string{"0", "vee", "U"}, want: "FOO BAR baz"},
+	}
+
+	for _, test := range tests {
+		t.Run(strings.Join(test.keys, ""), func(t *testing.T) {
+			shell := NewShell(80, 10)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.SetBuffer("foo Bar baz", -1)
+
+			keys := append([]string{`\C-xv`, `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.want {
+				t.Errorf("Readline() = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_SearchHighlight(t *testing.T) {
+	tests := []struct {
+		name      string
+		highlight bool
+		keys      []string
+		line      string
+	}{
+		{name: "Find char", highlight: true, keys: []string{"0", "f", "b", "0", "n", "n", "x"}, line: "foo bar foo ar"},
+		{name: "Backward", highlight: true, keys: []string{"0", "f", "b", "$", "N", "x"}, line: "foo bar foo ar"},
+		{name: "Cleared", highlight: true, keys: []string{"0", "f", "b", `\C-xh`, "0", "n", "x"}, line: "foo ar foo bar"},
+		{name: "History", highlight: true, keys: []string{"0", "d", "$", "?", "o", "o", `\r`, "0", "n", "x"}, line: "fo bar foo bar"},
+		{name: "Disabled", keys: []string{"0", "f", "b", "0", "n", "x"}, line: "oo bar foo bar"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			shell := NewShell(80, 6)
+			shell.History.Add("local", readline.NewInMemoryHistory())
+			shell.Config.Set("search-highlight", test.highlight)
+			shell.Bind("emacs", `\C-xv`, "vi-editing-mode")
+			shell.Bind("vi-command", `\C-xh`, "clear-search-highlight")
+
+			shell.Readline("foo bar foo bar", `\r`)
+
+			keys := append([]string{`\C-xv`, "foo bar foo bar", `\e`}, test.keys...)
+
+			line, _ := shell.Readline(append(keys, `\r`)...)
+			if line != test.line {
+				t.Errorf("Readline() = %q, want %q", line, test.line)
+			}
+		})
+	}
+}
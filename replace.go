@@ -0,0 +1,248 @@
+package readline
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+)
+
+// replaceFlags are the flags of a replace-in-line command.
+type replaceFlags struct {
+	global     bool // Replace all matches, not only the first one.
+	confirm    bool // Ask for confirmation before each replacement.
+	ignoreCase bool // Match the pattern regardless of case.
+}
+
+// Replace the matches of a regular expression in the line. The pattern and the
+// replacement (which can refer to submatches, as in $1) are read in the hint area,
+// in turn, and all matches are highlighted as they are typed. The replacement
+// can end with flags after a slash (a literal one must be escaped): g replaces
+// all matches instead of the first one, c asks to confirm each replacement (y
+// or n, a for all remaining ones, q or escape to stop), and i ignores case.
+func (rl *Shell) replaceInLine() {
+	rl.History.Save()
+
+	done := rl.Keymap.PendingCursor()
+	defer done()
+	defer rl.Hint.Reset()
+	defer rl.selection.ResetMatches()
+
+	pattern, ok := rl.readMinibuffer("replace", func(pattern string) string {
+		return rl.previewMatches(pattern, replaceFlags{global: true})
+	})
+	if !ok || pattern == "" {
+		return
+	}
+
+	input, ok := rl.readMinibuffer("replace "+pattern+" with", func(input string) string {
+		_, flags := parseReplacement(input)
+		return rl.previewMatches(pattern, flags)
+	})
+	if !ok {
+		return
+	}
+
+	replacement, flags := parseReplacement(input)
+
+	matcher, err := compileReplace(pattern, flags)
+	if err != nil {
+		rl.bell.Ring()
+		return
+	}
+
+	rl.replaceMatches(matcher, replacement, flags)
+}
+
+// replaceMatches replaces the matches of the regular expression in the line,
+// asking for confirmation before each replacement if needed. The cursor is
+// left at the beginning of the last replacement.
+func (rl *Shell) replaceMatches(matcher *regexp.Regexp, replacement string, flags replaceFlags) {
+	line := string(*rl.line)
+	matches := findMatches(matcher, line, flags.global)
+
+	if len(matches) == 0 {
+		rl.bell.Ring()
+		return
+	}
+
+	ranges := runeRanges(line, matches)
+	delta, confirm := 0, flags.confirm
+
+	for i, match := range matches {
+		if confirm {
+			answer := rl.confirmReplace(ranges, i, delta)
+			if answer == 'q' {
+				break
+			} else if answer == 'n' {
+				continue
+			}
+
+			confirm = answer != 'a'
+		}
+
+		expanded := []rune(string(matcher.ExpandString(nil, replacement, line, match)))
+		bpos, epos := ranges[i][0]+delta, ranges[i][1]+delta
+
+		rl.line.InsertBetween(bpos, epos, expanded...)
+		rl.cursor.Set(bpos)
+
+		delta += len(expanded) - (epos - bpos)
+	}
+}
+
+// confirmReplace highlights the match to be replaced, and returns the answer
+// read from the user: y, n, a (for all) or q (also returned on escape).
+func (rl *Shell) confirmReplace(ranges [][2]int, current, delta int) rune {
+	shifted := make([][2]int, 0, len(ranges)-current)
+	for _, match := range ranges[current:] {
+		shifted = append(shifted, [2]int{match[0] + delta, match[1] + delta})
+	}
+
+	rl.selection.MarkMatches(shifted, 0)
+	rl.cursor.Set(shifted[0][0])
+
+	for {
+		rl.Hint.Set(rl.styles.Isearch + "replace this match? " + color.Reset + "(y, n, a, q)")
+		rl.Display.Refresh()
+
+		key, isAbort := rl.Keys.ReadKey()
+		if isAbort {
+			return 'q'
+		}
+
+		if answer := unicode.ToLower(key); strings.ContainsRune("ynaq", answer) {
+			return answer
+		}
+	}
+}
+
+// readMinibuffer reads a string typed by the user in the hint area, until the
+// line is accepted (true is returned) or escape is pressed. After each key, the
+// preview function is called with the text, and returns a string to display
+// after it (eg. the number of matches of a pattern).
+func (rl *Shell) readMinibuffer(prompt string, preview func(text string) string) (string, bool) {
+	var text []rune
+
+	for {
+		info := preview(string(text))
+
+		rl.Hint.Set(rl.styles.Isearch + prompt + ": " + color.Reset + color.Bold + string(text) + color.Reset + "_" + info)
+		rl.Display.Refresh()
+
+		key, isAbort := rl.Keys.ReadKey()
+
+		switch {
+		case isAbort:
+			return "", false
+		case key == inputrc.Return || key == inputrc.Newline:
+			return string(text), true
+		case key == inputrc.Backspace || key == inputrc.Delete:
+			if len(text) > 0 {
+				text = text[:len(text)-1]
+			}
+		case key == '\x15': // Ctrl-U
+			text = nil
+		case unicode.IsPrint(key):
+			text = append(text, key)
+		}
+	}
+}
+
+// previewMatches highlights the matches of the pattern in the line,
+// and returns their number (or a compile error) to display as a hint.
+func (rl *Shell) previewMatches(pattern string, flags replaceFlags) string {
+	rl.selection.ResetMatches()
+
+	if pattern == "" {
+		return ""
+	}
+
+	matcher, err := compileReplace(pattern, flags)
+	if err != nil {
+		return rl.styles.Error + " (invalid pattern)" + color.Reset
+	}
+
+	line := string(*rl.line)
+	matches := findMatches(matcher, line, flags.global)
+
+	rl.selection.MarkMatches(runeRanges(line, matches), -1)
+
+	switch len(matches) {
+	case 0:
+		return rl.styles.Error + " (no matches)" + color.Reset
+	case 1:
+		return color.Dim + " (1 match)" + color.Reset
+	default:
+		return color.Dim + " (" + strconv.Itoa(len(matches)) + " matches)" + color.Reset
+	}
+}
+
+// parseReplacement splits the flags (after the last unescaped slash)
+// from a replacement, if they are valid, and unescapes its slashes.
+func parseReplacement(input string) (replacement string, flags replaceFlags) {
+	slash := -1
+
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '/':
+			slash = i
+		}
+	}
+
+	if slash >= 0 && strings.Trim(input[slash+1:], "gci") == "" {
+		flags.global = strings.Contains(input[slash+1:], "g")
+		flags.confirm = strings.Contains(input[slash+1:], "c")
+		flags.ignoreCase = strings.Contains(input[slash+1:], "i")
+		input = input[:slash]
+	}
+
+	return strings.ReplaceAll(input, `\/`, "/"), flags
+}
+
+// compileReplace compiles the pattern of a replacement, ignoring case if needed.
+func compileReplace(pattern string, flags replaceFlags) (*regexp.Regexp, error) {
+	if flags.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// findMatches returns the submatch indexes of all the non-empty
+// matches of the regular expression in the line, or the first one.
+func findMatches(matcher *regexp.Regexp, line string, global bool) (matches [][]int) {
+	for _, match := range matcher.FindAllStringSubmatchIndex(line, -1) {
+		if match[0] == match[1] {
+			continue
+		}
+
+		matches = append(matches, match)
+
+		if !global {
+			break
+		}
+	}
+
+	return matches
+}
+
+// runeRanges converts the byte offsets of matches to rune positions in the line.
+func runeRanges(line string, matches [][]int) [][2]int {
+	ranges := make([][2]int, 0, len(matches))
+
+	for _, match := range matches {
+		bpos := utf8.RuneCountInString(line[:match[0]])
+		epos := bpos + utf8.RuneCountInString(line[match[0]:match[1]])
+
+		ranges = append(ranges, [2]int{bpos, epos})
+	}
+
+	return ranges
+}
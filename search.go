@@ -0,0 +1,208 @@
+package readline
+
+import (
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// When the search-highlight option is on, all occurrences in the line of the
+// last pattern searched (in the history with `/`, `?` or incremental search, or
+// in the line with f/t and the like) are highlighted, and Vim `n`/`N` move the
+// cursor between them before searching the history again.
+
+// searchCommands returns the commands searching the input buffer,
+// or acting on the last search.
+func (rl *Shell) searchCommands() commands {
+	return map[string]func(){
+		"clear-search-highlight": rl.clearSearchHighlight,
+		"search-buffer-forward":  rl.searchBufferForward,
+		"search-buffer-backward": rl.searchBufferBackward,
+	}
+}
+
+// Stop highlighting the matches of the last search in the line,
+// until a new search is performed (as Vim's :nohlsearch).
+func (rl *Shell) clearSearchHighlight() {
+	rl.History.SkipSave()
+	rl.searchHidden = true
+	rl.selection.ResetMatches()
+}
+
+// setSearch sets the pattern whose occurrences are highlighted in the line.
+func (rl *Shell) setSearch(pattern string) {
+	rl.search = pattern
+	rl.searchHidden = false
+}
+
+// updateSearchHighlight highlights the occurrences of the last searched pattern
+// in the line, if the search-highlight option is on. The occurrence under the
+// cursor, if any, is highlighted as the current match.
+func (rl *Shell) updateSearchHighlight() {
+	if last := rl.completer.LastSearch(); last != rl.searchSeen {
+		rl.searchSeen = last
+		rl.setSearch(last)
+	}
+
+	if rl.minibuffer() {
+		return
+	}
+
+	if !rl.Config.GetBool("search-highlight") || rl.search == "" || rl.searchHidden {
+		rl.selection.ResetMatches()
+		return
+	}
+
+	matches := rl.occurrences(rl.search)
+	current := -1
+
+	for i, match := range matches {
+		if rl.cursor.Pos() >= match[0] && rl.cursor.Pos() < match[1] {
+			current = i
+			break
+		}
+	}
+
+	rl.selection.MarkMatches(matches, current)
+}
+
+// searchInLine moves the cursor to the next (or previous) occurrence in the line
+// of the last searched pattern, when highlighted, and returns true if one is found.
+func (rl *Shell) searchInLine(forward bool) bool {
+	if !rl.Config.GetBool("search-highlight") || rl.search == "" {
+		return false
+	}
+
+	matches := rl.occurrences(rl.search)
+
+	next := nextOccurrence(matches, rl.cursor.Pos(), forward, false)
+	if next == -1 {
+		return false
+	}
+
+	rl.cursor.Set(matches[next][0])
+	rl.searchHidden = false
+
+	return true
+}
+
+// Incrementally search forward for a string in the input buffer (all its lines),
+// moving the cursor to the first occurrence of it as it is typed, wrapping around
+// the end of the buffer. Unlike history incremental search, only the current
+// buffer is searched. Escape moves the cursor back to where it was.
+func (rl *Shell) searchBufferForward() {
+	rl.searchBuffer(true)
+}
+
+// Incrementally search backward for a string in the input buffer (all its lines),
+// moving the cursor to the first occurrence of it as it is typed, wrapping around
+// the beginning of the buffer. Unlike history incremental search, only the current
+// buffer is searched. Escape moves the cursor back to where it was.
+func (rl *Shell) searchBufferBackward() {
+	rl.searchBuffer(false)
+}
+
+// searchBuffer reads a pattern in the hint area, moving the cursor to its
+// occurrences in the buffer and highlighting them while it is being typed.
+// Once accepted, the pattern is the last search (see search-highlight).
+func (rl *Shell) searchBuffer(forward bool) {
+	rl.History.SkipSave()
+
+	done := rl.Keymap.PendingCursor()
+	defer done()
+	defer rl.Hint.Reset()
+
+	origin := rl.cursor.Pos()
+
+	prompt := "search backward"
+	if forward {
+		prompt = "search forward"
+	}
+
+	pattern, ok := rl.readMinibuffer(prompt, func(pattern string) string {
+		rl.cursor.Set(origin)
+		rl.selection.ResetMatches()
+
+		if pattern == "" {
+			return ""
+		}
+
+		matches := rl.occurrences(pattern)
+
+		// Forward, an occurrence under the cursor is the first one.
+		pos := origin
+		if forward {
+			pos--
+		}
+
+		current := nextOccurrence(matches, pos, forward, true)
+		if current == -1 {
+			return rl.styles.Error + " (no matches)" + color.Reset
+		}
+
+		rl.cursor.Set(matches[current][0])
+		rl.selection.MarkMatches(matches, current)
+
+		return ""
+	})
+
+	if !ok || pattern == "" {
+		rl.cursor.Set(origin)
+		rl.selection.ResetMatches()
+
+		return
+	}
+
+	rl.setSearch(pattern)
+}
+
+// occurrences returns the positions of all the (non-overlapping)
+// occurrences of a pattern in the line.
+func (rl *Shell) occurrences(pattern string) (matches [][2]int) {
+	length := utf8.RuneCountInString(pattern)
+	line := *rl.line
+
+	for pos := 0; pos+length <= len(line); pos++ {
+		if string(line[pos:pos+length]) == pattern {
+			matches = append(matches, [2]int{pos, pos + length})
+			pos += length - 1
+		}
+	}
+
+	return matches
+}
+
+// nextOccurrence returns the index of the first occurrence starting after pos
+// (or the last one starting before it if backward), or -1 if there is none.
+// If wrap is true, the search wraps around the ends of the line.
+func nextOccurrence(matches [][2]int, pos int, forward, wrap bool) int {
+	if len(matches) == 0 {
+		return -1
+	}
+
+	if forward {
+		for i, match := range matches {
+			if match[0] > pos {
+				return i
+			}
+		}
+
+		if wrap {
+			return 0
+		}
+
+		return -1
+	}
+
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i][0] < pos {
+			return i
+		}
+	}
+
+	if wrap {
+		return len(matches) - 1
+	}
+
+	return -1
+}
@@ -0,0 +1,59 @@
+package readline
+
+// Region returns the text of the active selection (the Emacs region when marked,
+// or the Vim visual selection), and its begin and end positions in the line (the
+// end one being excluded). If no selection is active, ok is false. This is meant
+// for commands registered with AddCommand that operate on selections.
+func (rl *Shell) Region() (text string, bpos, epos int, ok bool) {
+	if !rl.selection.Active() {
+		return "", -1, -1, false
+	}
+
+	bpos, epos = rl.selection.Pos()
+	if bpos == -1 || epos == -1 {
+		return "", -1, -1, false
+	}
+
+	return string((*rl.line)[bpos:epos]), bpos, epos, true
+}
+
+// SetRegion selects the text between bpos and epos (excluded), as the Emacs region
+// does: the mark is set at bpos and the cursor moved to epos, and the selection is
+// highlighted. Positions out of the line are clamped to its bounds, and a begin
+// position greater than the end one selects backwards (with the cursor at bpos).
+func (rl *Shell) SetRegion(bpos, epos int) {
+	bpos, epos = max(min(bpos, rl.line.Len()), 0), max(min(epos, rl.line.Len()), 0)
+
+	rl.cursor.Set(epos)
+	rl.selection.MarkRegion(bpos)
+}
+
+// SurroundRegion surrounds the active selection with a begin and an end character
+// (eg. quotes or brackets), and moves the cursor after the end one. The selection
+// is reset, and nothing is done if none is active.
+func (rl *Shell) SurroundRegion(bchar, echar rune) {
+	text, _, _, ok := rl.Region()
+	if !ok {
+		return
+	}
+
+	rl.ReplaceRegion(string(bchar) + text + string(echar))
+}
+
+// ReplaceRegion replaces the text of the active selection with another one, and
+// moves the cursor at the end of it. The selection is reset, and nothing is done
+// if none is active. As all edits, the replacement can be undone.
+func (rl *Shell) ReplaceRegion(text string) {
+	_, bpos, epos, ok := rl.Region()
+	if !ok {
+		return
+	}
+
+	rl.History.Save()
+
+	chars := []rune(text)
+
+	rl.selection.Reset()
+	rl.line.InsertBetween(bpos, epos, chars...)
+	rl.cursor.Set(bpos + len(chars))
+}
@@ -2,8 +2,13 @@ package readline
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/display"
@@ -34,14 +39,45 @@ type Shell struct {
 	Keymap     *keymap.Engine   // Manages main/local keymaps, binds, stores command functions, etc.
 	History    *history.Sources // History manages all history types/sources (past commands and undo)
 	Macros     *macro.Engine    // Record, use and display macros.
+	Hooks      *Hooks           // Functions called at key points of the shell lifecycle.
 
 	// User interface
-	Config    *inputrc.Config    // Contains all keymaps, binds and per-application settings.
-	Opts      []inputrc.Option   // Inputrc file parsing options (app/term/values, etc).
-	Prompt    *ui.Prompt         // The prompt engine computes and renders prompt strings.
-	Hint      *ui.Hint           // Usage/hints for completion/isearch below the input line.
-	completer *completion.Engine // Completions generation and display.
-	Display   *display.Engine    // Manages display refresh/update/clearing.
+	Config       *inputrc.Config    // Contains all keymaps, binds and per-application settings.
+	Opts         []inputrc.Option   // Inputrc file parsing options (app/term/values, etc).
+	Prompt       *ui.Prompt         // The prompt engine computes and renders prompt strings.
+	Hint         *ui.Hint           // Usage/hints for completion/isearch, and application messages below the input line.
+	completer    *completion.Engine // Completions generation and display.
+	bell         *ui.Bell           // Rung when commands fail (see the bell-style option).
+	term         *term.Terminal     // Output and size of the terminal, shared by all components.
+	styles       *color.Styles      // Theme and color depth of the terminal, shared by all components.
+	Display      *display.Engine    // Manages display refresh/update/clearing.
+	restored     *shellState        // A state to restore when starting to read input.
+	keyHook      func(keys []rune, resolved string) bool
+	keyTrace     io.Writer
+	prefix       string // A read-only prefix inserted at the beginning of the line.
+	readOnly     bool   // The line cannot be edited, see SetReadOnly.
+	accept       func(line string) (string, error)
+	corrector    func() []string
+	suggesters   map[string]func(line string) string
+	dismissed    string       // Line whose autosuggestion was cleared, see autosuggest-clear.
+	verified     string       // Line shown for confirmation, see the accept-verify option.
+	search       string       // Last searched pattern, highlighted with the search-highlight option.
+	searchSeen   string       // Last pattern searched in history, as last seen.
+	searchHidden bool         // The search highlighting was cleared until the next search.
+	dabbrev      dabbrev      // State of successive dabbrev-expand calls.
+	expander     Expander     // Host shell expansions, see SetExpander.
+	interrupt    keyBehavior  // Behavior of the interrupt key.
+	eof          keyBehavior  // Behavior of the end-of-file key.
+	preload      *preloaded   // An input line to edit, set with SetBuffer.
+	inserts      []string     // Text to insert, from InsertText.
+	reload       bool         // Inputrc files changed, see WatchConfig.
+	messages     []string     // Messages to print above the prompt, from PrintAsync.
+	reading      bool         // The shell is reading input, and prints messages itself.
+	announced    announcement // Editing state last announced in screen-reader mode.
+	editMutex    sync.Mutex   // Protects edits set from other goroutines.
+	leave        func()       // Restores the terminal state when leaving the shell.
+	in           io.Reader    // Input stream, if not the process stdin.
+	out          io.Writer    // Output stream, if not the process stdout.
 
 	// User-provided functions
 
@@ -51,68 +87,120 @@ type Shell struct {
 	// This function should return true if the line is deemed complete (thus asking
 	// the shell to return from its Readline() loop), or false if the shell should
 	// keep reading input on a newline (thus, insert a newline and read).
+	// ShellAcceptMultiline can be used for POSIX shell command lines.
 	AcceptMultiline func(line []rune) (accept bool)
 
-	// SyntaxHighlighter is a helper function to provide syntax highlighting.
-	// Once enabled, set to nil to disable again.
+	// Highlighter provides syntax highlighting, as styled regions of the line
+	// (eg. ShellHighlighter()). Once enabled, set to nil to disable again.
+	Highlighter Highlighter
+
+	// SyntaxHighlighter is a helper function to provide syntax highlighting,
+	// returning the line with embedded color sequences. It is not used if a
+	// Highlighter is set. Once enabled, set to nil to disable again.
+	//
+	// Deprecated: use Highlighter, which is composed with visual selections
+	// and other highlighted regions without parsing escape sequences.
 	SyntaxHighlighter func(line []rune) string
 
 	// Completer is a function that produces completions.
 	// It takes the readline line ([]rune) and cursor pos as parameters,
 	// and returns completions with their associated metadata/settings.
 	Completer func(line []rune, cursor int) Completions
+
+	// MouseHandler is passed all mouse events reported by the terminal, when
+	// the enable-mouse option is on. If it returns true, the event is deemed
+	// handled, and the default behavior (moving the cursor, selecting candidates,
+	// walking the history) is not performed.
+	MouseHandler func(event MouseEvent) (handled bool)
 }
 
 // NewShell returns a readline shell instance initialized with a default
 // inputrc configuration and binds, and with an in-memory command history.
 // The constructor accepts an optional list of inputrc configuration options,
-// which are used when parsing/loading and applying any inputrc configuration.
+// which are used when parsing/loading and applying any inputrc configuration,
+// like inputrc.WithApp() to set the application name, so that users can scope
+// their settings and binds to it with `$if <name>` constructs, as in bash.
 func NewShell(opts ...inputrc.Option) *Shell {
-	shell := new(Shell)
+	return newShell(nil, nil, opts...)
+}
+
+// newShell returns a shell reading its input keys from in, and writing everything it
+// displays to out (either of them is the process stream if nil). All the components
+// of the shell use these streams, and the terminal size of the Terminal they might
+// implement, so that several shells can be used at once on different terminals.
+func newShell(in io.Reader, out io.Writer, opts ...inputrc.Option) *Shell {
+	shell := &Shell{in: in, out: out}
+
+	terminal := term.NewTerminal()
+	terminal.SizeFunc = shell.sizeFunc()
+
+	if out != nil {
+		terminal.Output = out
+	}
+
+	// The process stdin might be wrapped (eg. to read Windows console events).
+	if in == os.Stdin {
+		in = nil
+	}
+
+	shell.term = terminal
+	styles := color.NewStyles()
+	shell.styles = styles
 
 	// Core editor
-	keys := new(core.Keys)
+	keys := core.NewKeys(in, terminal)
 	line := new(core.Line)
 	cursor := core.NewCursor(line)
-	selection := core.NewSelection(line, cursor)
+	selection := core.NewSelection(line, cursor, styles)
 	iterations := new(core.Iterations)
 
 	shell.Keys = keys
 	shell.line = line
 	shell.cursor = cursor
 	shell.selection = selection
-	shell.Buffers = editor.NewBuffers()
 	shell.Iterations = iterations
 
 	// Keymaps and commands
-	keymaps, config := keymap.NewEngine(keys, iterations, opts...)
+	keymaps, config := keymap.NewEngine(terminal, keys, iterations, opts...)
 	keymaps.Register(shell.standardCommands())
 	keymaps.Register(shell.viCommands())
 	keymaps.Register(shell.historyCommands())
 	keymaps.Register(shell.completionCommands())
+	keymaps.Register(shell.operatorCommands())
+	keymaps.Register(shell.searchCommands())
 
 	shell.Keymap = keymaps
 	shell.Config = config
 	shell.Opts = opts
+	shell.Buffers = editor.NewBuffers(terminal, styles, config)
 
 	// User interface
-	hint := new(ui.Hint)
-	prompt := ui.NewPrompt(line, cursor, keymaps, config)
-	macros := macro.NewEngine(keys, hint)
-	history := history.NewSources(line, cursor, hint, config)
-	completer := completion.NewEngine(hint, keymaps, config)
+	hint := ui.NewHint(terminal, styles, config)
+	hint.OnExpire(func() { core.Wake(keys) })
+	bell := ui.NewBell(terminal, config)
+	prompt := ui.NewPrompt(terminal, keys, line, cursor, keymaps, styles, config)
+	macros := macro.NewEngine(terminal, keys, hint, styles)
+	history := history.NewSources(line, cursor, hint, bell, styles, config)
+	completer := completion.NewEngine(terminal, hint, bell, keymaps, styles, config)
 	completion.Init(completer, keys, line, cursor, selection, shell.commandCompletion)
 
-	display := display.NewEngine(keys, selection, history, prompt, hint, completer, config)
+	display := display.NewEngine(terminal, keys, selection, history, prompt, hint, completer, styles, config)
 
 	shell.Config = config
 	shell.Hint = hint
+	shell.bell = bell
 	shell.Prompt = prompt
 	shell.completer = completer
 	shell.Macros = macros
 	shell.History = history
 	shell.Display = display
 
+	// Lifecycle hooks
+	shell.Hooks = new(Hooks)
+	display.OnRefresh(shell.Hooks.render)
+	display.Suggester(shell.suggestLine)
+	bell.OnRing(shell.Hooks.bell)
+
 	return shell
 }
 
@@ -144,11 +232,11 @@ func (rl *Shell) Printf(msg string, args ...any) (n int, err error) {
 	// First go back to the last line of the input line,
 	// and clear everything below (hints and completions).
 	rl.Display.CursorBelowLine()
-	term.MoveCursorBackwards(term.GetWidth())
-	fmt.Print(term.ClearScreenBelow)
+	rl.term.MoveCursorBackwards(rl.term.GetWidth())
+	fmt.Fprint(rl.term, term.ClearScreenBelow)
 
 	// Skip a line, and print the formatted message.
-	n, err = fmt.Printf(msg+"\n", args...)
+	n, err = fmt.Fprintf(rl.term, msg+"\n", args...)
 
 	// Redisplay the prompt, input line and active helpers.
 	rl.Prompt.PrimaryPrint()
@@ -163,12 +251,12 @@ func (rl *Shell) PrintTransientf(msg string, args ...any) (n int, err error) {
 	// First go back to the beginning of the line/prompt, and
 	// clear everything below (prompt/line/hints/completions).
 	rl.Display.CursorToLineStart()
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(rl.Prompt.PrimaryUsed())
-	fmt.Print(term.ClearScreenBelow)
+	rl.term.MoveCursorBackwards(rl.term.GetWidth())
+	rl.term.MoveCursorUp(rl.Prompt.PrimaryUsed())
+	fmt.Fprint(rl.term, term.ClearScreenBelow)
 
 	// Print the logged message.
-	n, err = fmt.Printf(msg+"\n", args...)
+	n, err = fmt.Fprintf(rl.term, msg+"\n", args...)
 
 	// Redisplay the prompt, input line and active helpers.
 	rl.Prompt.PrimaryPrint()
@@ -176,3 +264,118 @@ func (rl *Shell) PrintTransientf(msg string, args ...any) (n int, err error) {
 
 	return
 }
+
+// TourStep is a single step of a guided tour: a hint explaining a binding,
+// and a sequence of keys (in inputrc format, eg. `\C-a`) demonstrating it.
+// If Try is true, the tour pauses until the user has typed the same keys.
+type TourStep = macro.TourStep
+
+// StartTour starts a guided tour of the shell ("learn the shell"), in which each step
+// displays its hint and plays its keys back as if typed, with the given delay between
+// each key (a default one is used if zero). The tour is played during Readline() calls,
+// and can be skipped or stopped with the tour-next-step and tour-stop commands.
+func (rl *Shell) StartTour(delay time.Duration, steps ...TourStep) {
+	rl.Macros.StartTour(delay, steps...)
+}
+
+// AcceptedLine describes a line returned by the shell, and the status and duration
+// of the last command run (as set with Prompt.SetStatus). It is passed to the
+// function set with Prompt.TransientLine, which returns the prompt to display.
+type AcceptedLine = ui.Accepted
+
+// TransientPrompt is displayed in place of the primary prompt once a line is
+// returned, when the prompt-transient option is enabled. Its Line, if not empty,
+// replaces the input line, and Skip leaves both the prompt and line as they are.
+type TransientPrompt = ui.Transient
+
+// PromptPart is a part of a prompt set with Prompt.PrimaryParts or Prompt.RightParts:
+// on narrow terminals, the parts with the lowest priority are dropped, or truncated
+// if they can be, so that prompts never wrap and leave room to the input line.
+type PromptPart = ui.Part
+
+// HintLevel is the level of a hint message set with Hint.SetLevel or Hint.PersistLevel
+// (informational, warning or error), which determines its style in the theme.
+type HintLevel = ui.HintLevel
+
+// Hint levels.
+const (
+	HintInfo    = ui.HintInfo
+	HintWarning = ui.HintWarning
+	HintError   = ui.HintError
+)
+
+// Image is an image displayed in the hint section (with Hint.SetImage), or as the
+// preview of completion candidates (with Completions.PreviewF), if the terminal
+// supports an image protocol (kitty or sixel, see the image-protocol option).
+type Image = ui.Image
+
+// Scorer matches and ranks candidates against a query: Score returns the score of
+// the candidate (higher is better, negative if not matching), and the positions of
+// the characters it matched.
+type Scorer = completion.Scorer
+
+// FuzzyScorer is the default scorer, matching queries as (smart case) subsequences
+// of candidates, favoring consecutive matches and matches at the start of words.
+type FuzzyScorer = completion.FuzzyScorer
+
+// SetScorer sets the scorer used for fuzzy completion and incremental search (when
+// the completion-fuzzy option is enabled), for sorting candidates by score, and to
+// choose between history autosuggestions. If nil, the default fuzzy scorer is used,
+// and the most recent history line is suggested.
+func (rl *Shell) SetScorer(scorer Scorer) {
+	rl.completer.SetScorer(scorer)
+	rl.History.SetScorer(scorer)
+}
+
+// SetImmutablePrefix sets a prefix (eg. a command name) inserted at the beginning
+// of the input line in all subsequent calls to Readline: the cursor can move in
+// it, but it cannot be edited. The returned line includes the prefix. An empty
+// prefix removes it. Other ranges of the line can be made read-only with the
+// Protect method of the shell selection.
+func (rl *Shell) SetImmutablePrefix(prefix string) {
+	rl.prefix = prefix
+}
+
+// SetReadOnly makes the input line read-only, or editable again: commands can still
+// move the cursor, search and copy text, but the ones modifying the line (inserting,
+// deleting or pasting text, walking the history) leave it unchanged and ring the bell.
+// This is useful to show a command that the user can only accept or abort. It can
+// be called between calls to Readline, or from commands and hooks while reading.
+func (rl *Shell) SetReadOnly(readOnly bool) {
+	rl.readOnly = readOnly
+}
+
+// ReadOnly returns true if the input line is read-only (see SetReadOnly).
+func (rl *Shell) ReadOnly() bool {
+	return rl.readOnly
+}
+
+// AcceptFilter sets a function called with the line when the user accepts it, before
+// it is returned by Readline and written to history. If the filter returns an error,
+// the line is not accepted, the error is shown in the hint area and the user keeps
+// editing. Otherwise, the returned line (eg. with aliases expanded, or trimmed) is
+// the one returned to the caller and written to history. Set to nil to remove it.
+// With the accept-verify option set to "changed", a line changed by the filter (eg.
+// with history expansions) is shown for confirmation before being accepted, as the
+// histverify option of bash: Enter accepts it, while editing it cancels the accept.
+// With "always", all lines must be confirmed this way.
+func (rl *Shell) AcceptFilter(filter func(line string) (string, error)) {
+	rl.accept = filter
+}
+
+// StatusLine sets a function returning a status line, displayed below the hints
+// and completions, and updated on each refresh: for instance, the current keymap
+// (rl.Keymap.Main()), the selected register (rl.Buffers.IsSelected()), or whether
+// a macro is being recorded (rl.Macros.Recording()). Only the first line of the
+// status is displayed, cut to the terminal width. Set to nil to remove it.
+func (rl *Shell) StatusLine(status func() string) {
+	rl.Display.StatusLine(status)
+}
+
+// WorkingDirectory sets a function returning the working directory of the application,
+// reported to the terminal before each prompt (with an OSC 7 sequence), so that terminals
+// can open new tabs or windows in the same directory. Set to nil to stop reporting it.
+// Prompts and commands are also marked for terminals when shell-integration is on.
+func (rl *Shell) WorkingDirectory(dir func() string) {
+	rl.Display.WorkingDirectory(dir)
+}
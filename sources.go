@@ -0,0 +1,187 @@
+package readline
+
+import (
+	"bufio"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Builtin completion sources, used by the bash commands completing a specific
+// kind of word (complete-filename, complete-command, etc), regardless of the
+// completer of the shell. Candidates are filtered against the current word by
+// the completion engine, as any others.
+
+// filenameCompletion returns the entries of the directory named by the current
+// word (up to its last slash), directories ending with a slash. As in bash, hidden
+// entries are only listed when the word starts with a dot, and a leading tilde
+// stands for the home directory.
+func (rl *Shell) filenameCompletion() Completions {
+	word := rl.currentWord()
+	dir := word[:strings.LastIndex(word, "/")+1]
+
+	entries, err := os.ReadDir(expandHome(dir))
+	if err != nil {
+		return CompleteValues()
+	}
+
+	hidden := strings.HasPrefix(word[len(dir):], ".")
+	files := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") && !hidden {
+			continue
+		}
+
+		name := dir + entry.Name()
+
+		if info, err := os.Stat(expandHome(name)); err == nil && info.IsDir() {
+			name += "/"
+		}
+
+		files = append(files, name)
+	}
+
+	return CompleteValues(files...).Tag("files").NoSpace('/')
+}
+
+// commandNameCompletion returns the names of all executable files in $PATH.
+func (rl *Shell) commandNameCompletion() Completions {
+	seen := make(map[string]bool)
+
+	var commands []string
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if seen[entry.Name()] || !isExecutable(filepath.Join(dir, entry.Name())) {
+				continue
+			}
+
+			seen[entry.Name()] = true
+			commands = append(commands, entry.Name())
+		}
+	}
+
+	return CompleteValues(commands...).Tag("commands")
+}
+
+// variableCompletion returns the names of all environment variables, with a
+// leading dollar sign.
+func (rl *Shell) variableCompletion() Completions {
+	env := os.Environ()
+	variables := make([]string, 0, len(env))
+
+	for _, variable := range env {
+		if name, _, found := strings.Cut(variable, "="); found && name != "" {
+			variables = append(variables, "$"+name)
+		}
+	}
+
+	return CompleteValues(variables...).Tag("variables")
+}
+
+// usernameCompletion returns the names of all users found in /etc/passwd,
+// with a leading tilde. The current user is the only one on Windows.
+func (rl *Shell) usernameCompletion() Completions {
+	var users []string
+
+	for _, fields := range readTable("/etc/passwd", ":") {
+		users = append(users, "~"+fields[0])
+	}
+
+	if current, err := user.Current(); err == nil && len(users) == 0 {
+		users = append(users, "~"+current.Username)
+	}
+
+	return CompleteValues(users...).Tag("users").NoSpace('/')
+}
+
+// hostnameCompletion returns all hostnames found in the file named by the
+// $HOSTFILE variable, or else in /etc/hosts.
+func (rl *Shell) hostnameCompletion() Completions {
+	hostfile := os.Getenv("HOSTFILE")
+	if hostfile == "" {
+		hostfile = "/etc/hosts"
+	}
+
+	seen := make(map[string]bool)
+
+	var hosts []string
+
+	for _, fields := range readTable(hostfile, " \t") {
+		for _, host := range fields[1:] {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	return CompleteValues(hosts...).Tag("hosts")
+}
+
+// currentWord returns the current word up to the cursor, unquoted.
+func (rl *Shell) currentWord() string {
+	line, cursor := rl.completer.Line()
+	args, current := SplitArgs(*line, cursor.Pos())
+
+	return args[current]
+}
+
+// expandHome replaces a leading tilde in path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	return home + path[1:]
+}
+
+// isExecutable returns true if the file exists, is not a directory, and can be
+// executed by someone (on Windows, every file found in $PATH is considered so).
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+
+	return runtime.GOOS == "windows" || info.Mode()&0o111 != 0
+}
+
+// readTable returns the fields (separated by any of the separator characters)
+// of all non-empty and non-comment lines in a file, if it can be read.
+func readTable(path, separators string) (lines [][]string) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return strings.ContainsRune(separators, r)
+		})
+
+		if len(fields) > 0 {
+			lines = append(lines, fields)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,78 @@
+package readline
+
+import (
+	"encoding/json"
+
+	"github.com/reeflective/readline/internal/editor"
+	"github.com/reeflective/readline/internal/history"
+)
+
+// shellState is a serializable snapshot of the shell editing state.
+type shellState struct {
+	Line      string        `json:"line"`
+	Cursor    int           `json:"cursor"`
+	Mark      int           `json:"mark"`
+	Keymap    string        `json:"keymap"`
+	History   history.State `json:"history"`
+	Registers editor.State  `json:"registers"`
+}
+
+// SaveState returns a snapshot of the current editing state of the shell: input
+// buffer, cursor position and mark, main keymap, undo history of all lines, registers
+// and history positions, so that an interrupted editing session can be resumed later.
+func (rl *Shell) SaveState() ([]byte, error) {
+	state := shellState{
+		Line:      string(*rl.line),
+		Cursor:    rl.cursor.Pos(),
+		Mark:      rl.cursor.Mark(),
+		Keymap:    string(rl.Keymap.Main()),
+		History:   rl.History.State(),
+		Registers: rl.Buffers.State(),
+	}
+
+	return json.Marshal(state)
+}
+
+// RestoreState restores an editing state previously returned by SaveState.
+// Registers and keymap are restored immediately, while the input buffer, the
+// cursor and the history positions are restored at the beginning of the next
+// call to Readline(), so that the user resumes editing where they left off.
+func (rl *Shell) RestoreState(data []byte) error {
+	state := new(shellState)
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return err
+	}
+
+	rl.Buffers.RestoreState(state.Registers)
+
+	if state.Keymap != "" {
+		rl.Keymap.SetMain(state.Keymap)
+	}
+
+	rl.restored = state
+
+	return nil
+}
+
+// restoreState applies any pending restored state to the input line,
+// cursor and history. This must be called after the shell is initialized
+// at the beginning of each Readline() call.
+func (rl *Shell) restoreState() {
+	state := rl.restored
+	if state == nil {
+		return
+	}
+
+	rl.restored = nil
+
+	rl.History.RestoreState(state.History)
+	rl.line.Set([]rune(state.Line)...)
+
+	if state.Mark >= 0 && state.Mark <= rl.line.Len() {
+		rl.cursor.Set(state.Mark)
+		rl.cursor.SetMark()
+	}
+
+	rl.cursor.Set(state.Cursor)
+}
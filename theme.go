@@ -0,0 +1,33 @@
+package readline
+
+import "github.com/reeflective/readline/internal/color"
+
+// Theme holds the styles (SGR sequences, eg. "\x1b[1;36m") of all the elements
+// displayed by the shell: hints, error messages, autosuggestions, selections,
+// completion menus and the shell syntax highlighter. Colors can be 16, 256 or
+// true colors: they are converted to the closest ones the terminal supports.
+//
+// In .inputrc files, a preset theme is chosen with `set theme dark|light`, and
+// the style of any element is overridden with `set theme-<element> "\e[1;36m"`,
+// where elements are named in lowercase words separated by dashes (eg. error,
+// completion-tag or command). The terminal color depth is detected from the
+// environment, or set with `set color-depth truecolor|256|16`.
+type Theme = color.Theme
+
+// DarkTheme returns the default theme, meant for dark terminal backgrounds.
+func DarkTheme() Theme {
+	return color.Dark()
+}
+
+// LightTheme returns a theme meant for light terminal backgrounds.
+func LightTheme() Theme {
+	return color.Light()
+}
+
+// SetTheme sets the theme used by the shell, instead of the preset chosen
+// in the inputrc configuration. Styles of theme elements set in the latter
+// still apply. The theme and color depth in use are shared by all shells
+// of the process: shells reading input at once should use the same ones.
+func (rl *Shell) SetTheme(theme Theme) {
+	rl.Display.SetTheme(&theme)
+}
@@ -0,0 +1,116 @@
+package readline
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// undoClass is the way a command saves the line as an undo state once it is done.
+type undoClass int
+
+const (
+	// undoSave commands save the line if they changed it (the default).
+	undoSave undoClass = iota
+	// undoInsert commands insert text, grouped in undo states as set by
+	// the undo-grouping option.
+	undoInsert
+	// undoSkip commands never save the line (eg. undo and redo themselves).
+	undoSkip
+	// undoKeep commands never save the line, nor end a sequence of undo or
+	// redo commands (eg. numeric arguments given to them).
+	undoKeep
+)
+
+// undoClasses are the classes of commands which do not use the default one.
+// Other commands can still skip saving the line (eg. cursor moves), or save it
+// before changing it, so that the states before and after them can be restored.
+var undoClasses = map[string]undoClass{
+	"self-insert":        undoInsert,
+	"quoted-insert":      undoInsert,
+	"tab-insert":         undoInsert,
+	"insert-digraph":     undoInsert,
+	"digit-argument":     undoKeep,
+	"universal-argument": undoKeep,
+	"vi-arg-digit":       undoKeep,
+	"undo":               undoSkip,
+	"vi-undo":            undoSkip,
+	"redo":               undoSkip,
+	"vi-redo":            undoSkip,
+	"undo-all":           undoSkip,
+	"revert-line":        undoSkip,
+}
+
+// saveUndo saves the line as an undo state once a command is done, as
+// dictated by the class of the command, and keeps the command as the last one.
+func (rl *Shell) saveUndo(bind inputrc.Bind) {
+	switch undoClasses[bind.Action] {
+	case undoSkip:
+		rl.History.SkipSave()
+	case undoKeep:
+		rl.History.KeepPos()
+	case undoInsert:
+		if rl.insertEndsUndoGroup() {
+			rl.History.EndGroup()
+		}
+	}
+
+	rl.History.SaveWithCommand(bind)
+}
+
+// insertEndsUndoGroup returns true if the text just inserted ends an undo group,
+// depending on the undo-grouping option: after each insertion ("command"), after
+// each word, when a blank is inserted after it ("word"), or never ("insert", the
+// default), in which case the group ends with the next command saving the line,
+// or when leaving Vi insert mode.
+func (rl *Shell) insertEndsUndoGroup() bool {
+	switch strings.Trim(rl.Config.GetString("undo-grouping"), "\"") {
+	case "command":
+		return true
+	case "word":
+		pos := rl.cursor.Pos()
+		line := *rl.line
+
+		return pos > 1 && unicode.IsSpace(line[pos-1]) && !unicode.IsSpace(line[pos-2])
+	default:
+		return false
+	}
+}
+
+// Incrementally undo the last text modification (or as many as given by the
+// numeric argument, redoing them if negative): inserted text is undone by groups,
+// as set by the undo-grouping option. Note that when invoked from vi command mode,
+// the full prior change made in insert mode is reverted, the changes having been
+// merged when command mode was selected.
+func (rl *Shell) undoLast() {
+	times := rl.Iterations.Get()
+
+	for i := 0; i < times; i++ {
+		rl.History.Undo()
+	}
+
+	for i := 0; i > times; i-- {
+		rl.History.Redo()
+	}
+}
+
+// Redo the last undone changes to the line (or as many as given by the
+// numeric argument).
+func (rl *Shell) redo() {
+	for i := rl.Iterations.Get(); i > 0; i-- {
+		rl.History.Redo()
+	}
+}
+
+// Undo all changes made to this line, which can still be redone.
+func (rl *Shell) undoAll() {
+	rl.History.UndoAll()
+}
+
+// Undo all changes made to this line.
+// This is like executing the undo command enough
+// times to return the line to its initial state.
+func (rl *Shell) revertLine() {
+	rl.History.Revert()
+}
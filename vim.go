@@ -1,9 +1,11 @@
 package readline
 
 import (
+	"strings"
 	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/editor"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
 )
@@ -34,6 +36,8 @@ func (rl *Shell) viCommands() commands {
 		"vi-editing-mode":   rl.viInsertMode,
 
 		"vi-visual-line-mode": rl.viVisualLineMode,
+		"vi-visual-exchange":  rl.viVisualExchange,
+		"vi-visual-reselect":  rl.viVisualReselect,
 
 		// Movement
 		"vi-backward-char":    rl.viBackwardChar,
@@ -48,6 +52,7 @@ func (rl *Shell) viCommands() commands {
 		"vi-end-bigword":      rl.viForwardBlankWordEnd,
 		"vi-match":            rl.viMatchBracket,
 		"vi-column":           rl.viGotoColumn,
+		"vi-goto-column":      rl.viGotoColumn,
 		"vi-end-of-line":      rl.viEndOfLine,
 		"vi-back-to-indent":   rl.viBackToIndent,
 		"vi-first-print":      rl.viFirstPrint,
@@ -73,6 +78,11 @@ func (rl *Shell) viCommands() commands {
 		"vi-open-line-below": rl.viOpenLineBelow,
 		"vi-down-case":       rl.viDownCase,
 		"vi-up-case":         rl.viUpCase,
+		"vi-oper-swap-case":  rl.viOperSwapCase,
+		"vi-format":          rl.viFormat,
+		"vi-join-lines":      rl.viJoinLines,
+
+		"vi-join-lines-literal": rl.viJoinLinesLiteral,
 
 		// Kill and Yanking
 		"vi-kill-eol":         rl.viKillEol,
@@ -201,6 +211,29 @@ func (rl *Shell) viVisualLineMode() {
 	rl.Keymap.PrintCursor(keymap.Visual)
 }
 
+// Exchange the cursor and the other end of the visual selection.
+func (rl *Shell) viVisualExchange() {
+	rl.History.SkipSave()
+	rl.selection.Swap()
+}
+
+// Enter Vim visual mode, selecting again the last visual selection.
+func (rl *Shell) viVisualReselect() {
+	rl.History.SkipSave()
+	rl.Iterations.Reset()
+
+	rl.Hint.Reset()
+	rl.completer.Reset()
+
+	if !rl.selection.Reselect() {
+		rl.bell.Ring()
+		return
+	}
+
+	rl.Keymap.SetLocal(keymap.Visual)
+	rl.Keymap.PrintCursor(keymap.Visual)
+}
+
 // Go to the beginning of the current line, and enter Vim insert mode.
 func (rl *Shell) viInsertBol() {
 	rl.Iterations.Reset()
@@ -238,7 +271,7 @@ func (rl *Shell) viAddEol() {
 // Move forward one character, without changing lines.
 func (rl *Shell) viForwardChar() {
 	// Only exception where we actually don't forward a character.
-	if rl.Config.GetBool("history-autosuggest") && rl.cursor.Pos() == rl.line.Len()-1 {
+	if rl.motionAccepts() && rl.cursor.Pos() == rl.line.Len()-1 {
 		rl.autosuggestAccept()
 		return
 	}
@@ -553,10 +586,11 @@ func (rl *Shell) viChangeTo() {
 		// In visual mode, we have just have a selection to delete.
 		rl.History.Save()
 
+		kind := rl.selectionKind()
 		rl.adjustSelectionPending()
 		cpos := rl.selection.Cursor()
 		cut := rl.selection.Cut()
-		rl.Buffers.Write([]rune(cut)...)
+		rl.Buffers.WriteAs(kind, []rune(cut)...)
 		rl.cursor.Set(cpos)
 
 		rl.viInsertMode()
@@ -593,22 +627,18 @@ func (rl *Shell) viDeleteTo() {
 
 		text := rl.selection.Cut()
 
-		// Get buffer and add newline if there isn't one at the end
-		if len(text) > 0 && rune(text[len(text)-1]) != inputrc.Newline {
-			text += string(inputrc.Newline)
-		}
-
-		rl.Buffers.Write([]rune(text)...)
+		rl.Buffers.WriteAs(editor.LineWise, []rune(text)...)
 		rl.cursor.Set(cpos)
 
 	case rl.selection.Active():
 		// In visual mode, or with a non-empty selection, just cut it.
 		rl.History.Save()
 
+		kind := rl.selectionKind()
 		rl.adjustSelectionPending()
 		cpos := rl.selection.Cursor()
 		cut := rl.selection.Cut()
-		rl.Buffers.Write([]rune(cut)...)
+		rl.Buffers.WriteAs(kind, []rune(cut)...)
 		rl.cursor.Set(cpos)
 
 		rl.viCommandMode()
@@ -641,8 +671,7 @@ func (rl *Shell) viDeleteChar() {
 	vii := rl.Iterations.Get()
 
 	for i := 1; i <= vii; i++ {
-		cutBuf = append(cutBuf, rl.cursor.Char())
-		rl.line.CutRune(rl.cursor.Pos())
+		cutBuf = append(cutBuf, rl.line.CutGrapheme(rl.cursor.Pos())...)
 	}
 
 	rl.Buffers.Write(cutBuf...)
@@ -681,11 +710,62 @@ func (rl *Shell) viBackwardDeleteChar() {
 	}
 }
 
-// Enter overwrite mode.
+// Enter replace mode: typed characters replace those under the cursor
+// (or are appended at the end of the line) until escape is pressed.
 func (rl *Shell) viReplace() {
-	// The the standard emacs replace loop,
-	// which blocks until the ESC is pressed
-	rl.overwriteMode()
+	// We store the current line as an undo item first, but will not
+	// store any intermediate changes (in the loop below) as undo items.
+	rl.History.Save()
+
+	done := rl.Keymap.ReplaceCursor()
+	defer done()
+
+	// All replaced characters are stored, to be used with backspace
+	cache := make([]rune, 0)
+
+	// Don't use the delete cache past the end of the line
+	lineStart := rl.line.Len()
+
+	// The replace mode is quite special in that it does not escape back
+	// to the main readline loop: it keeps reading characters and inserts
+	// them as long as the escape key is not pressed.
+	for {
+		// We read a character to use first.
+		key, isAbort := rl.Keys.ReadKey()
+		if isAbort {
+			break
+		}
+
+		// If the key is a backspace, we go back one character
+		if string(key) == inputrc.Unescape(string(`\C-?`)) {
+			if rl.cursor.Pos() > lineStart {
+				rl.backwardDeleteChar()
+			} else if rl.cursor.Pos() > 0 {
+				rl.cursor.Dec()
+			}
+
+			// And recover the last replaced character
+			if len(cache) > 0 && rl.cursor.Pos() < lineStart {
+				key = cache[len(cache)-1]
+				cache = cache[:len(cache)-1]
+
+				rl.cursor.ReplaceWith(key)
+			}
+		} else {
+			// If the cursor is at the end of the line,
+			// we insert the character instead of replacing.
+			if rl.line.Len() == rl.cursor.Pos() {
+				rl.cursor.InsertAt(key)
+			} else {
+				cache = append(cache, rl.cursor.Char())
+				rl.cursor.ReplaceWith(key)
+				rl.cursor.Inc()
+			}
+		}
+
+		// Update the line
+		rl.Display.Refresh()
+	}
 
 	// And after exiting, move the cursor back
 	rl.cursor.Dec()
@@ -696,27 +776,14 @@ func (rl *Shell) viReplace() {
 func (rl *Shell) viChangeCase() {
 	switch {
 	case rl.selection.Active() && rl.selection.IsVisual():
-		rl.selection.ReplaceWith(func(char rune) rune {
-			if unicode.IsLower(char) {
-				return unicode.ToUpper(char)
-			}
-
-			return unicode.ToLower(char)
-		})
+		rl.selection.ReplaceWith(swapCase)
 
 	default:
 		if rl.line.Len() == 0 || rl.cursor.Pos() == rl.line.Len() {
 			return
 		}
 
-		char := rl.cursor.Char()
-		if unicode.IsLower(char) {
-			char = unicode.ToUpper(char)
-		} else {
-			char = unicode.ToLower(char)
-		}
-
-		rl.cursor.ReplaceWith(char)
+		rl.cursor.ReplaceWith(swapCase(rl.cursor.Char()))
 	}
 }
 
@@ -743,7 +810,7 @@ func (rl *Shell) viSubstitute() {
 			// Delete next characters and enter insert mode.
 			vii := rl.Iterations.Get()
 			for i := 1; i <= vii; i++ {
-				rl.line.CutRune(rl.cursor.Pos())
+				rl.line.CutGrapheme(rl.cursor.Pos())
 			}
 		case 'S':
 			if rl.cursor.OnEmptyLine() {
@@ -755,7 +822,7 @@ func (rl *Shell) viSubstitute() {
 			rl.selection.Visual(true)
 
 			bpos, epos := rl.selection.Pos()
-			rl.Buffers.Write((*rl.line)[bpos:epos]...)
+			rl.Buffers.WriteAs(editor.LineWise, (*rl.line)[bpos:epos]...)
 
 			// If selection has a new line, remove it.
 			if (*rl.line)[epos-1] == '\n' {
@@ -815,6 +882,17 @@ func (rl *Shell) viOpenLineAbove() {
 	}
 	rl.cursor.InsertAt('\n')
 	rl.cursor.Dec()
+
+	// The new line has the indentation of the current one.
+	if rl.Config.GetBool("smart-indent") {
+		indent := rl.cursor.Pos() + 1
+		for indent < rl.line.Len() && ((*rl.line)[indent] == ' ' || (*rl.line)[indent] == '\t') {
+			indent++
+		}
+
+		rl.cursor.InsertAt(rl.indentation(indent)...)
+	}
+
 	rl.viInsertMode()
 }
 
@@ -824,64 +902,70 @@ func (rl *Shell) viOpenLineBelow() {
 	if !rl.cursor.OnEmptyLine() {
 		rl.endOfLine()
 	}
-	rl.cursor.InsertAt('\n')
+	rl.cursor.CheckAppend()
+	rl.insertNewline()
 	rl.viInsertMode()
 }
 
-// Convert the current word to all lowercase and move past it.
-// If in visual mode, operate on the whole selection.
+// Convert the text covered by a motion (eg. `guiw`) to all lowercase, or the
+// entire line when repeated (`guu` or `gugu`). If in visual mode, operate on
+// the whole selection.
 func (rl *Shell) viDownCase() {
-	switch {
-	case rl.Keymap.IsPending():
-		// In vi operator pending mode, it's that we've been called
-		// twice in a row (eg. `uu`), so modify the entire current line.
-		rl.History.Save()
-
-		rl.selection.Mark(rl.cursor.Pos())
-		rl.selection.Visual(true)
-		rl.selection.ReplaceWith(unicode.ToLower)
-		rl.viCommandMode()
+	rl.viOperator("vi-down-case", func() { rl.selection.ReplaceWith(unicode.ToLower) })
+}
 
-	case rl.selection.Active():
-		rl.History.Save()
-		rl.selection.ReplaceWith(unicode.ToLower)
-		rl.viCommandMode()
+// Convert the text covered by a motion (eg. `gU$`) to all uppercase, or the
+// entire line when repeated (`gUU` or `gUgU`). If in visual mode, operate on
+// the whole selection.
+func (rl *Shell) viUpCase() {
+	rl.viOperator("vi-up-case", func() { rl.selection.ReplaceWith(unicode.ToUpper) })
+}
 
-	default:
-		// Else if we are actually starting a yank action.
-		rl.History.SkipSave()
-		rl.Keymap.Pending()
-		rl.selection.Mark(rl.cursor.Pos())
-	}
+// Toggle the case of the text covered by a motion (eg. `g~e`), or of the
+// entire line when repeated (`g~~` or `g~g~`). If in visual mode, operate
+// on the whole selection.
+func (rl *Shell) viOperSwapCase() {
+	rl.viOperator("vi-oper-swap-case", func() { rl.selection.ReplaceWith(swapCase) })
 }
 
-// Convert the current word to all uppercase and move past it.
-// If in visual mode, operate on the whole selection.
-func (rl *Shell) viUpCase() {
+// Rewrap the lines covered by a motion (or the current line when repeated,
+// eg. `gqgq`) so that they fit in format-width columns. If in visual mode,
+// operate on all lines of the selection.
+func (rl *Shell) viFormat() {
 	switch {
 	case rl.Keymap.IsPending():
-		// In vi operator pending mode, it's that we've been called
-		// twice in a row (eg. `uu`), so modify the entire current line.
 		rl.History.Save()
 
 		rl.selection.Mark(rl.cursor.Pos())
 		rl.selection.Visual(true)
-		rl.selection.ReplaceWith(unicode.ToUpper)
+		rl.formatSelection()
 		rl.viCommandMode()
 
 	case rl.selection.Active():
 		rl.History.Save()
-		rl.selection.ReplaceWith(unicode.ToUpper)
+		rl.formatSelection()
 		rl.viCommandMode()
 
 	default:
-		// Else if we are actually starting a yank action.
 		rl.History.SkipSave()
 		rl.Keymap.Pending()
 		rl.selection.Mark(rl.cursor.Pos())
 	}
 }
 
+// Join the current line with the next one (or count-1 next ones, at least one),
+// removing the indentation of the joined lines and separating them with a space,
+// unless the joined line is empty or starts with a closing parenthesis. If in
+// visual mode, join all lines spanned by the selection.
+func (rl *Shell) viJoinLines() {
+	rl.joinLines(true)
+}
+
+// Join lines as vi-join-lines, but without inserting or removing any blank.
+func (rl *Shell) viJoinLinesLiteral() {
+	rl.joinLines(false)
+}
+
 //
 // Killing & Yanking ----------------------------------------------------
 //
@@ -923,8 +1007,7 @@ func (rl *Shell) viRubout() {
 		}
 
 		rl.cursor.Dec()
-		cut = append(cut, rl.cursor.Char())
-		rl.line.CutRune(rl.cursor.Pos())
+		cut = append(cut, rl.line.CutGrapheme(rl.cursor.Pos())...)
 	}
 
 	rl.Buffers.Write(cut...)
@@ -944,21 +1027,18 @@ func (rl *Shell) viYankTo() {
 		rl.selection.Mark(rl.cursor.Pos())
 		rl.selection.Visual(true)
 
-		// Get buffer and add newline if there isn't one at the end
 		text, _, _, _ := rl.selection.Pop()
-		if len(text) > 0 && rune(text[len(text)-1]) != inputrc.Newline {
-			text += string(inputrc.Newline)
-		}
 
-		rl.Buffers.Write([]rune(text)...)
+		rl.Buffers.WriteAs(editor.LineWise, []rune(text)...)
 
 	case rl.selection.Active():
 		// In visual mode, or with a non-empty selection, just yank.
 		rl.History.Save()
+		kind := rl.selectionKind()
 		rl.adjustSelectionPending()
 		text, _, _, cpos := rl.selection.Pop()
 
-		rl.Buffers.Write([]rune(text)...)
+		rl.Buffers.WriteAs(kind, []rune(text)...)
 		rl.cursor.Set(cpos)
 
 		rl.viCommandMode()
@@ -995,7 +1075,7 @@ func (rl *Shell) viYankWholeLine() {
 
 	// Pass the buffer to register.
 	buffer := (*rl.line)[bpos:epos]
-	rl.Buffers.Write(buffer...)
+	rl.Buffers.WriteAs(editor.LineWise, buffer...)
 
 	// Done with any selection.
 	rl.selection.Reset()
@@ -1030,17 +1110,28 @@ func (rl *Shell) viPut() {
 }
 
 // Insert the contents of the kill buffer after the cursor.
+// Line-wise contents are put below the cursor line, and block-wise
+// ones are put as a rectangle, starting after the cursor column.
 func (rl *Shell) viPutAfter() {
 	rl.History.Save()
 
+	kind := rl.Buffers.ActiveKind()
 	buffer := rl.Buffers.Active()
 
 	if len(buffer) == 0 {
 		return
 	}
 
-	// Add newlines when pasting an entire line.
-	if buffer[len(buffer)-1] == '\n' {
+	switch kind {
+	case editor.BlockWise:
+		rl.putBlock(buffer, true)
+		return
+
+	case editor.LineWise:
+		if buffer[len(buffer)-1] != '\n' {
+			buffer = append(buffer, '\n')
+		}
+
 		if !rl.cursor.OnEmptyLine() {
 			rl.cursor.EndOfLineAppend()
 		}
@@ -1060,16 +1151,28 @@ func (rl *Shell) viPutAfter() {
 }
 
 // Insert the contents of the kill buffer before the cursor.
+// Line-wise contents are put above the cursor line, and block-wise
+// ones are put as a rectangle, starting at the cursor column.
 func (rl *Shell) viPutBefore() {
 	rl.History.Save()
 
+	kind := rl.Buffers.ActiveKind()
 	buffer := rl.Buffers.Active()
 
 	if len(buffer) == 0 {
 		return
 	}
 
-	if buffer[len(buffer)-1] == '\n' {
+	switch kind {
+	case editor.BlockWise:
+		rl.putBlock(buffer, false)
+		return
+
+	case editor.LineWise:
+		if buffer[len(buffer)-1] != '\n' {
+			buffer = append(buffer, '\n')
+		}
+
 		rl.cursor.BeginningOfLine()
 
 		if rl.cursor.OnEmptyLine() {
@@ -1088,7 +1191,70 @@ func (rl *Shell) viPutBefore() {
 	rl.cursor.Set(pos)
 }
 
+// putBlock inserts each line of a block-wise register at the same column in
+// successive lines, starting with the cursor one: lines too short are padded
+// with spaces, and new lines are added at the end of the buffer if needed.
+// Block lines are padded to the block width when followed by text.
+func (rl *Shell) putBlock(block []rune, after bool) {
+	rows := strings.Split(strings.TrimSuffix(string(block), "\n"), "\n")
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len([]rune(row)))
+	}
+
+	pos := rl.cursor.Pos()
+	start := pos
+
+	for start > 0 && (*rl.line)[start-1] != '\n' {
+		start--
+	}
+
+	column := pos - start
+	if after && pos < rl.line.Len() && (*rl.line)[pos] != '\n' {
+		column++
+	}
+
+	bpos := start + column
+	vii := rl.Iterations.Get()
+
+	for i, row := range rows {
+		// Go to the beginning of the next line, adding one if needed.
+		if i > 0 {
+			next := start
+			for next < rl.line.Len() && (*rl.line)[next] != '\n' {
+				next++
+			}
+
+			if next == rl.line.Len() {
+				rl.line.Insert(next, '\n')
+			}
+
+			start = next + 1
+		}
+
+		end := start
+		for end < rl.line.Len() && (*rl.line)[end] != '\n' {
+			end++
+		}
+
+		if end-start < column {
+			rl.line.Insert(end, []rune(strings.Repeat(" ", column-(end-start)))...)
+			end = start + column
+		}
+
+		if start+column < end {
+			row += strings.Repeat(" ", width-len([]rune(row)))
+		}
+
+		rl.line.Insert(start+column, []rune(strings.Repeat(row, vii))...)
+	}
+
+	rl.cursor.Set(bpos)
+}
+
 // Specify a buffer to be used in the following command. See the registers section in the Vim page.
+// With the designator-completion option, the registers and their contents are listed meanwhile.
 func (rl *Shell) viSetBuffer() {
 	rl.History.SkipSave()
 
@@ -1099,6 +1265,14 @@ func (rl *Shell) viSetBuffer() {
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
+	// Show the registers and their contents while waiting for it.
+	if rl.Config.GetBool("designator-completion") {
+		rl.completer.GenerateWith(rl.registerCompletion)
+		rl.Display.Refresh()
+
+		defer rl.completer.ClearMenu(true)
+	}
+
 	key, isAbort := rl.Keys.ReadKey()
 	if isAbort {
 		return
@@ -1211,6 +1385,7 @@ func (rl *Shell) viSelectInside() {
 
 	bpos, epos, _, _ := rl.line.FindSurround(rune(char), rl.cursor.Pos())
 	if bpos == -1 && epos == -1 {
+		rl.bell.Ring()
 		return
 	}
 
@@ -1242,6 +1417,7 @@ func (rl *Shell) viSelectSurround() {
 	// Find the corresponding enclosing chars
 	bpos, epos, _, _ := rl.line.FindSurround(char, rl.cursor.Pos())
 	if bpos == -1 || epos == -1 {
+		rl.bell.Ring()
 		return
 	}
 
@@ -1283,7 +1459,8 @@ func (rl *Shell) viSearch() {
 // Search again, through the history for the string of characters
 // between the start of the current line and the point, using the
 // same search string used by the previous search.
-// This is a non-incremental search.
+// This is a non-incremental search. With the search-highlight option,
+// the cursor first moves to the next occurrence of the pattern in the line.
 func (rl *Shell) viSearchAgain() {
 	var forward bool
 	var hint string
@@ -1299,6 +1476,10 @@ func (rl *Shell) viSearchAgain() {
 		hint = " ?"
 	}
 
+	if rl.searchInLine(forward) {
+		return
+	}
+
 	rl.completer.NonIsearchStart(rl.History.Name()+hint, true, forward, true)
 
 	line, cursor, _ := rl.completer.GetBuffer()
@@ -1437,12 +1618,15 @@ func (rl *Shell) viFindChar(forward, skip bool) {
 		return
 	}
 
+	rl.setSearch(string(char))
+
 	times := rl.Iterations.Get()
 
 	for i := 1; i <= times; i++ {
 		pos := rl.line.Find(char, rl.cursor.Pos(), forward)
 
 		if pos == rl.cursor.Pos() || pos == -1 {
+			rl.bell.Ring()
 			break
 		}
 
@@ -1470,6 +1654,10 @@ func (rl *Shell) viSearchBackward() {
 
 // Reuses the last vi-search buffer and finds the previous search match occurrence in the history.
 func (rl *Shell) viSearchAgainForward() {
+	if rl.searchInLine(true) {
+		return
+	}
+
 	rl.completer.NonIsearchStart(rl.History.Name()+" /", true, true, true)
 
 	line, cursor, _ := rl.completer.GetBuffer()
@@ -1480,6 +1668,10 @@ func (rl *Shell) viSearchAgainForward() {
 
 // Reuses the last vi-search buffer and finds the next search match occurrence in the history.
 func (rl *Shell) viSearchAgainBackward() {
+	if rl.searchInLine(false) {
+		return
+	}
+
 	rl.completer.NonIsearchStart(rl.History.Name()+" ?", true, false, true)
 
 	line, cursor, _ := rl.completer.GetBuffer()
@@ -1510,6 +1702,13 @@ func (rl *Shell) adjustSelectionPending() {
 		"vi-match":
 		rl.selection.Visual(false)
 
+	case "vi-forward-word", "vi-next-word", "vi-forward-bigword":
+		// As in Vim, the newline ending the last word of a line is not
+		// part of the selection, which would otherwise be put line-wise.
+		if bpos, epos := rl.selection.Pos(); epos > bpos && epos == rl.cursor.Pos() && (*rl.line)[epos-1] == '\n' {
+			rl.cursor.Set(epos - 1)
+		}
+
 		// Selectors
 	case "select-in-word", "select-a-word",
 		"select-in-blank-word", "select-a-blank-word",
@@ -1522,3 +1721,155 @@ func (rl *Shell) adjustSelectionPending() {
 		rl.selection.Visual(false)
 	}
 }
+
+// formatSelection rewraps all the lines spanned by the selection to format-width
+// columns (the terminal width if zero), keeping comment-begin prefixes, and moves
+// the cursor to the beginning of the last line formatted.
+func (rl *Shell) formatSelection() {
+	bpos, epos := rl.selection.Pos()
+	rl.selection.Reset()
+
+	if bpos == -1 || epos == -1 {
+		return
+	}
+
+	line := *rl.line
+
+	for bpos > 0 && line[bpos-1] != '\n' {
+		bpos--
+	}
+
+	if epos > bpos && line[epos-1] == '\n' {
+		epos--
+	}
+
+	for epos < len(line) && line[epos] != '\n' {
+		epos++
+	}
+
+	width := rl.Config.GetInt("format-width")
+	if width <= 0 {
+		width = rl.term.GetWidth()
+	}
+
+	comment := strings.Trim(rl.Config.GetString("comment-begin"), "\"")
+	text := strutil.Format(string(line[bpos:epos]), width, comment)
+
+	rl.line.InsertBetween(bpos, epos, []rune(text)...)
+	rl.cursor.Set(bpos + len([]rune(text[:strings.LastIndex(text, "\n")+1])))
+}
+
+// joinLines joins the lines as vi-join-lines, collapsing blanks if smart is true,
+// and leaves the cursor at the last join point. The bell is rung if no line follows.
+func (rl *Shell) joinLines(smart bool) {
+	joins := max(rl.Iterations.Get()-1, 1)
+
+	if rl.selection.Active() {
+		bpos, epos := rl.selection.Pos()
+		rl.selection.Reset()
+		defer rl.viCommandMode()
+
+		rl.cursor.Set(bpos)
+		joins = max(strings.Count(string((*rl.line)[bpos:max(epos-1, bpos)]), "\n"), 1)
+	}
+
+	rl.History.Save()
+
+	line := rl.line
+	pos := rl.cursor.Pos()
+	joined := false
+
+	for i := 0; i < joins; i++ {
+		for pos < line.Len() && (*line)[pos] != '\n' {
+			pos++
+		}
+
+		if pos == line.Len() {
+			break
+		}
+
+		joined = true
+
+		if !smart {
+			line.CutRune(pos)
+			continue
+		}
+
+		end := pos + 1
+		for end < line.Len() && (*line)[end] != '\n' && unicode.IsSpace((*line)[end]) {
+			end++
+		}
+
+		line.Cut(pos, end)
+
+		switch {
+		case pos > 0 && unicode.IsSpace((*line)[pos-1]):
+		case pos == line.Len() || (*line)[pos] == '\n' || (*line)[pos] == ')':
+		default:
+			line.Insert(pos, ' ')
+		}
+	}
+
+	if !joined {
+		rl.bell.Ring()
+		return
+	}
+
+	rl.cursor.Set(pos)
+}
+
+// viOperator runs a Vim operator (named after its command) on the text covered
+// by the next motion, on the current line when repeated, or on the visual selection.
+// The operation must act on the active selection, and reset it when done.
+func (rl *Shell) viOperator(name string, operate func()) {
+	switch {
+	case rl.Keymap.IsPending():
+		// In vi operator pending mode, it's that we've been called
+		// twice in a row (eg. `gUU`), so modify the entire current line.
+		rl.Keymap.CancelPending()
+		rl.History.Save()
+
+		rl.selection.Mark(rl.cursor.Pos())
+		rl.selection.Visual(true)
+		operate()
+		rl.viCommandMode()
+
+	case rl.Keymap.Local() == keymap.ViOpp && rl.Keymap.ActiveCommand().Action == name:
+		// Used as the motion of another operator (eg. `gUu`).
+		rl.History.SkipSave()
+		rl.Keymap.CancelPending()
+		rl.selection.Reset()
+		rl.bell.Ring()
+
+	case rl.selection.Active():
+		rl.History.Save()
+		rl.adjustSelectionPending()
+		operate()
+		rl.viCommandMode()
+
+	default:
+		// Else if we are actually starting the operator.
+		rl.History.SkipSave()
+		rl.Keymap.Pending()
+		rl.selection.Mark(rl.cursor.Pos())
+	}
+}
+
+// swapCase returns the uppercase of a lowercase character, or the lowercase of any other.
+func swapCase(char rune) rune {
+	if unicode.IsLower(char) {
+		return unicode.ToUpper(char)
+	}
+
+	return unicode.ToLower(char)
+}
+
+// selectionKind returns the yank type of the current selection,
+// which is line-wise in visual line mode, or character-wise.
+func (rl *Shell) selectionKind() editor.Kind {
+	if rl.selection.IsVisualLine() {
+		return editor.LineWise
+	}
+
+	return editor.CharWise
+}